@@ -1,25 +1,38 @@
 package anna
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"net/url"
+	"os"
+	"path/filepath"
 
+	"runtime"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"encoding/json"
 	"errors"
 	"net/http"
 
 	colly "github.com/gocolly/colly/v2"
+	"github.com/iosifache/annas-mcp/internal/fsutil"
 	"github.com/iosifache/annas-mcp/internal/logger"
 	"go.uber.org/zap"
 )
 
+// Endpoints are relative to the configured mirror, see Configure.
 const (
-	AnnasSearchEndpoint   = "https://annas-archive.org/search?q=%s"
-	AnnasDownloadEndpoint = "https://annas-archive.org/dyn/api/fast_download.json?md5=%s&key=%s"
+	AnnasSearchEndpoint   = "%s/search?q=%s"
+	AnnasDownloadEndpoint = "%s/dyn/api/fast_download.json?md5=%s&key=%s"
 )
 
+// ErrFileTooLarge is returned for downloads above the configured size limit.
+var ErrFileTooLarge = errors.New("file exceeds the maximum download size")
+
 func extractMetaInformation(meta string) (language, format, size string) {
 	// The meta format may be:
 	// - "✅ English [en] · EPUB · 0.7MB · 2015 · ..."
@@ -61,92 +74,592 @@ func extractMetaInformation(meta string) (language, format, size string) {
 	return language, format, size
 }
 
+// fastDownloadMarker prefixes the sources of files offered by the fast
+// download partner servers, as in "🚀/lgli/zlib".
+const fastDownloadMarker = "🚀"
+
+// extractAvailability returns the upstream sources listed in the meta line of
+// a result, and whether the file is offered through fast downloads.
+func extractAvailability(meta string) (sources []string, fast bool) {
+	for _, part := range strings.Split(meta, " · ") {
+		part = strings.TrimSpace(part)
+		if strings.HasPrefix(part, fastDownloadMarker) {
+			fast = true
+			part = strings.TrimPrefix(part, fastDownloadMarker)
+		}
+		if !strings.HasPrefix(part, "/") {
+			continue
+		}
+
+		for _, source := range strings.Split(part, "/") {
+			if source = strings.TrimSpace(source); source != "" && !strings.ContainsAny(source, " \t") {
+				sources = append(sources, source)
+			}
+		}
+	}
+
+	return sources, fast
+}
+
+// setAvailability fills the fields of book derived from its meta line.
+func (b *Book) setAvailability(meta string) {
+	b.Bytes = b.SizeBytes()
+	b.Sources, b.FastDownload = extractAvailability(meta)
+}
+
 func FindBook(query string) ([]*Book, error) {
+	return FindBookFiltered(query, Filters{})
+}
+
+// FindBookFiltered is like FindBook, returning only the results matching
+// filters, such as the books in one of the given languages or formats.
+func FindBookFiltered(query string, filters Filters) ([]*Book, error) {
+	books := make([]*Book, 0)
+	_, err := StreamBooksFiltered(query, filters, 0, func(book *Book) bool {
+		books = append(books, book)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return books, nil
+}
+
+// StreamBooks searches Anna's Archive and passes every result to yield as soon
+// as its row is parsed, so callers can show results before the page is done.
+// Returning false from yield stops the delivery of further results.
+func StreamBooks(query string, yield func(*Book) bool) error {
+	return StreamBooksLimit(query, 0, yield)
+}
+
+// StreamBooksLimit is like StreamBooks but delivers up to limit results,
+// fetching as many result pages as needed. A limit of 0 delivers the first
+// page.
+func StreamBooksLimit(query string, limit int, yield func(*Book) bool) error {
+	_, err := StreamBooksClassified(query, limit, yield)
+	return err
+}
+
+// StreamBooksClassified is like StreamBooksLimit, and also returns the class
+// of the search page when it held no results, such as PageEmpty or
+// PageLayoutChanged. Block and maintenance pages fail the search instead.
+func StreamBooksClassified(query string, limit int, yield func(*Book) bool) (string, error) {
+	return StreamBooksFiltered(query, Filters{}, limit, yield)
+}
+
+// StreamBooksFiltered is like StreamBooksClassified, delivering only the
+// results matching filters.
+func StreamBooksFiltered(query string, filters Filters, limit int, yield func(*Book) bool) (string, error) {
+	return streamSearch(query, filters, limit, func(e *colly.HTMLElement) bool {
+		return yield(parseBook(e))
+	})
+}
+
+// searchPage visits the given page of the search results of query,
+// restricted by filters, and passes the cover link
+// of every result row to onRow until it returns false. A first page without
+// rows is classified; block and maintenance pages fail over to the next
+// mirror, and the class of other pages is returned.
+func searchPage(query string, filters Filters, page int, onRow func(*colly.HTMLElement) bool) (string, error) {
 	l := logger.GetLogger()
 
-	c := colly.NewCollector(
-		colly.Async(true),
-	)
+	var class string
+	err := eachMirror(func(base string) error {
+		var visitErr error
+		var status int
+		var body []byte
+		delivered := 0
+		stopped := false
+		class = ""
+
+		// Synchronous, so yield is always called from the caller's goroutine
+		c := newCollector()
+
+		c.OnHTML("a[href^='/md5/']", func(e *colly.HTMLElement) {
+			// Only process the first link (the cover image link), not the duplicate title link
+			if stopped || e.Attr("class") != "custom-a block mr-2 sm:mr-4 hover:opacity-80" {
+				return
+			}
+
+			delivered++
+			stopped = !onRow(e)
+		})
+
+		c.OnRequest(func(r *colly.Request) {
+			l.Info("Visiting URL", zap.String("url", r.URL.String()))
+		})
 
-	bookList := make([]*colly.HTMLElement, 0)
+		c.OnResponse(func(r *colly.Response) {
+			status, body = r.StatusCode, r.Body
+		})
 
-	c.OnHTML("a[href^='/md5/']", func(e *colly.HTMLElement) {
-		// Only process the first link (the cover image link), not the duplicate title link
-		if e.Attr("class") == "custom-a block mr-2 sm:mr-4 hover:opacity-80" {
-			bookList = append(bookList, e)
+		c.OnError(func(r *colly.Response, err error) {
+			visitErr = err
+			if r != nil {
+				status, body = r.StatusCode, r.Body
+			}
+		})
+
+		fullURL := fmt.Sprintf(AnnasSearchEndpoint, base, url.QueryEscape(query))
+		fullURL += filters.query()
+		if page > 1 {
+			fullURL += fmt.Sprintf("&page=%d", page)
+		}
+		if err := c.Visit(fullURL); err != nil && visitErr == nil {
+			visitErr = err
 		}
-	})
 
-	c.OnRequest(func(r *colly.Request) {
-		l.Info("Visiting URL", zap.String("url", r.URL.String()))
+		// Retrying on another mirror would deliver the same results twice
+		if visitErr != nil && delivered > 0 {
+			return &stopMirrors{err: visitErr}
+		}
+		if delivered == 0 && page == 1 && body != nil {
+			// The page echoes the query, which must not match any marker
+			for _, echo := range []string{query, url.QueryEscape(query)} {
+				if echo != "" {
+					body = bytes.ReplaceAll(bytes.ToLower(body), bytes.ToLower([]byte(echo)), nil)
+				}
+			}
+			class = ClassifyPage(status, body)
+			if class == PageBlocked || class == PageMaintenance {
+				l.Warn("Mirror answered the search with an error page",
+					zap.String("mirror", base),
+					zap.String("class", class),
+					zap.Int("status", status),
+				)
+				return &PageError{Mirror: base, Class: class, Status: status}
+			}
+			if class == PageLayoutChanged && visitErr == nil {
+				l.Warn("Search page holds no recognizable results, the page layout may have changed",
+					zap.String("mirror", base),
+					zap.String("query", query),
+				)
+			}
+		}
+		return visitErr
 	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search: %w", err)
+	}
+
+	return class, nil
+}
 
-	fullURL := fmt.Sprintf(AnnasSearchEndpoint, url.QueryEscape(query))
-	c.Visit(fullURL)
-	c.Wait()
+// parseBook extracts a search result from the cover link of its row.
+func parseBook(e *colly.HTMLElement) *Book {
+	bookInfoDiv := e.DOM.Parent().Find("div.max-w-full")
 
-	bookListParsed := make([]*Book, 0)
-	for _, e := range bookList {
-		bookInfoDiv := e.DOM.Parent().Find("div.max-w-full")
+	title := bookInfoDiv.Find("a[href^='/md5/']").Text()
 
-		title := bookInfoDiv.Find("a[href^='/md5/']").Text()
+	authorsRaw := bookInfoDiv.Find("a[href^='/search'] span.icon-\\[mdi--user-edit\\]").Parent().Text()
+	authors := ParseAuthors(authorsRaw)
 
-		authorsRaw := bookInfoDiv.Find("a[href^='/search'] span.icon-\\[mdi--user-edit\\]").Parent().Text()
-		authors := strings.TrimSpace(authorsRaw)
+	publisherRaw := bookInfoDiv.Find("a[href^='/search'] span.icon-\\[mdi--company\\]").Parent().Text()
+	publisher := strings.TrimSpace(publisherRaw)
 
-		publisherRaw := bookInfoDiv.Find("a[href^='/search'] span.icon-\\[mdi--company\\]").Parent().Text()
-		publisher := strings.TrimSpace(publisherRaw)
+	meta := bookInfoDiv.Find("div.text-gray-800").Text()
 
-		meta := bookInfoDiv.Find("div.text-gray-800").Text()
+	language, format, size := extractMetaInformation(meta)
 
-		language, format, size := extractMetaInformation(meta)
+	link := e.Attr("href")
+	hash := strings.TrimPrefix(link, "/md5/")
 
-		link := e.Attr("href")
-		hash := strings.TrimPrefix(link, "/md5/")
+	book := &Book{
+		Language:  language,
+		Format:    format,
+		Size:      size,
+		Title:     strings.TrimSpace(title),
+		Publisher: publisher,
+		Authors:   authors,
+		URL:       e.Request.AbsoluteURL(link),
+		Hash:      hash,
+	}
+	book.setAvailability(meta)
+
+	return book
+}
 
-		book := &Book{
-			Language:  language,
-			Format:    format,
-			Size:      size,
-			Title:     strings.TrimSpace(title),
-			Publisher: publisher,
-			Authors:   authors,
-			URL:       e.Request.AbsoluteURL(link),
-			Hash:      hash,
+// APIError is an error reported by the fast download API itself, as opposed to
+// a transport failure.
+type APIError struct {
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+// KeyRelated reports whether the error concerns the secret key (invalid key,
+// exhausted quota, missing membership) rather than the requested record or
+// partner server.
+func (e *APIError) KeyRelated() bool {
+	message := strings.ToLower(e.Message)
+	return !strings.Contains(message, "md5") && !strings.Contains(message, "index")
+}
+
+func (b *Book) GetDownloadURL(secretKey string) (string, error) {
+	info, err := b.GetDownloadInfo(secretKey)
+	if err != nil {
+		return "", err
+	}
+
+	return info.URL, nil
+}
+
+// DefaultServer lets the fast download API pick the partner server.
+const DefaultServer = -1
+
+// GetDownloadInfo resolves a fast download link together with the remaining
+// allowance of the key.
+func (b *Book) GetDownloadInfo(secretKey string) (*DownloadInfo, error) {
+	return b.GetDownloadInfoFrom(secretKey, DefaultServer)
+}
+
+// GetDownloadInfoFrom is like GetDownloadInfo but asks for a link on the fast
+// partner server with the given index, or DefaultServer.
+func (b *Book) GetDownloadInfoFrom(secretKey string, server int) (*DownloadInfo, error) {
+	var info *DownloadInfo
+	err := eachMirror(func(base string) error {
+		apiURL := fmt.Sprintf(AnnasDownloadEndpoint, base, url.QueryEscape(b.Hash), url.QueryEscape(secretKey))
+		if server != DefaultServer {
+			apiURL += fmt.Sprintf("&domain_index=%d", server)
+		}
+
+		resp, err := client().Get(apiURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		// Error pages of the mirror or its proxy are not JSON, and are
+		// classified like search pages so breakers and key failover see them
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests:
+			delay, _ := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+			return &RateLimitError{Host: resp.Request.URL.Host, RetryAfter: delay}
+		case resp.StatusCode >= 500:
+			return &PageError{Mirror: base, Class: PageMaintenance, Status: resp.StatusCode}
+		}
+		body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		if err != nil {
+			return err
+		}
+
+		var apiResp fastDownloadResponse
+		if err := json.Unmarshal(body, &apiResp); err != nil {
+			if resp.StatusCode != http.StatusOK {
+				class := PageMaintenance
+				if ClassifyPage(resp.StatusCode, body) == PageBlocked {
+					class = PageBlocked
+				}
+				return &PageError{Mirror: base, Class: class, Status: resp.StatusCode}
+			}
+			return err
+		}
+		if apiResp.DownloadURL == "" {
+			// The mirror answered, so another one would reply the same
+			if apiResp.Error != "" {
+				return &stopMirrors{err: &APIError{Message: apiResp.Error}}
+			}
+			return &stopMirrors{err: errors.New("failed to get download URL")}
 		}
 
-		bookListParsed = append(bookListParsed, book)
+		info = &DownloadInfo{URL: apiResp.DownloadURL, Account: apiResp.AccountInfo, ExpiresAt: LinkExpiry(apiResp.DownloadURL)}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return bookListParsed, nil
+	return info, nil
 }
 
-func (b *Book) GetDownloadURL(secretKey string) (string, error) {
-	apiURL := fmt.Sprintf(AnnasDownloadEndpoint, b.Hash, secretKey)
+// probeHash is a hash no record has, asked for by CheckKey so the fast
+// download API judges the key without handing out a download.
+const probeHash = "00000000000000000000000000000000"
+
+// CheckKey asks the fast download API whether it accepts secretKey, without
+// spending a fast download. It returns an *APIError for an invalid key or one
+// without membership, and other errors when no mirror answered. A key out of
+// fast downloads is accepted.
+func CheckKey(secretKey string) error {
+	_, err := (&Book{Hash: probeHash}).GetDownloadInfo(secretKey)
+	if err == nil {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		message := strings.ToLower(apiErr.Message)
+		if !strings.Contains(message, "key") && !strings.Contains(message, "member") {
+			// Only the unknown record was refused
+			return nil
+		}
+		return apiErr
+	}
+
+	// Transport errors quote the request URL, which holds the key
+	if secretKey == "" {
+		return err
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), url.QueryEscape(secretKey), "REDACTED"))
+}
+
+// Download fetches the book through the fast download API and stores it in
+// folderPath, returning the path of the written file.
+func (b *Book) Download(secretKey, folderPath string) (string, error) {
+	downloadURL, err := b.GetDownloadURL(secretKey)
+	if err != nil {
+		return "", err
+	}
 
-	resp, err := http.Get(apiURL)
+	return b.Fetch(downloadURL, folderPath)
+}
+
+// Fetch stores the file behind an already resolved download URL in folderPath,
+// returning the path of the written file.
+func (b *Book) Fetch(downloadURL, folderPath string) (string, error) {
+	return b.FetchLimited(downloadURL, folderPath, 0)
+}
+
+// FetchLimited is like Fetch but refuses files larger than maxBytes, based on
+// the Content-Length header and on the bytes actually received. A maxBytes of
+// 0 disables the limit.
+func (b *Book) FetchLimited(downloadURL, folderPath string, maxBytes int64) (string, error) {
+	return b.FetchProgress(downloadURL, folderPath, maxBytes, nil)
+}
+
+// FetchProgress is like FetchLimited but calls onProgress while the file is
+// being received, and once more when it was received completely. A nil
+// onProgress reports nothing.
+func (b *Book) FetchProgress(downloadURL, folderPath string, maxBytes int64, onProgress func(Progress)) (string, error) {
+	resp, err := client().Get(downloadURL)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	var apiResp fastDownloadResponse
-	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	if maxBytes > 0 && resp.ContentLength > maxBytes {
+		return "", fmt.Errorf("%w: the server reports %d bytes, the limit is %d", ErrFileTooLarge, resp.ContentLength, maxBytes)
+	}
+
+	if err := os.MkdirAll(folderPath, 0o755); err != nil {
+		return "", err
+	}
+
+	filePath, err := b.Path(folderPath)
+	if err != nil {
+		return "", err
+	}
+
+	// Writing to a fresh temporary file and renaming it means an existing
+	// symlink at filePath is replaced instead of followed
+	out, err := os.CreateTemp(folderPath, ".annas-mcp-download-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(out.Name())
+
+	body := io.Reader(resp.Body)
+	if maxBytes > 0 {
+		// Read one byte past the limit to detect oversized bodies
+		body = io.LimitReader(resp.Body, maxBytes+1)
+	}
+	var progress *progressReader
+	if onProgress != nil {
+		progress = newProgressReader(body, resp.ContentLength, onProgress)
+		body = progress
+	}
+	written, err := io.Copy(out, body)
+	if err != nil {
+		out.Close()
+		return "", err
+	}
+	if maxBytes > 0 && written > maxBytes {
+		out.Close()
+		return "", fmt.Errorf("%w: received more than %d bytes", ErrFileTooLarge, maxBytes)
+	}
+	if progress != nil {
+		progress.finish()
+	}
+	if err := out.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(out.Name(), 0o644); err != nil {
 		return "", err
 	}
-	if apiResp.DownloadURL == "" {
-		if apiResp.Error != "" {
-			return "", errors.New(apiResp.Error)
+	if err := os.Rename(out.Name(), filePath); err != nil {
+		return "", err
+	}
+
+	return filePath, nil
+}
+
+// OpenStream requests the file behind an already resolved download URL without
+// storing it, forwarding rangeHeader so clients can read parts of it. The
+// caller closes the response body.
+func OpenStream(ctx context.Context, downloadURL, rangeHeader string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if rangeHeader != "" {
+		req.Header.Set("Range", rangeHeader)
+	}
+
+	resp, err := client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		resp.Body.Close()
+		return nil, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// maxFilenameLength keeps generated names below the 255 byte limit of common
+// filesystems, leaving room for the extension.
+const maxFilenameLength = 200
+
+// windowsNames applies the naming rules of Windows to the saved files: no
+// reserved device names or characters, and paths within MaxWindowsPath.
+var windowsNames = runtime.GOOS == "windows"
+
+// Filename returns the name under which the book is saved, falling back to
+// the hash when no title is known. Titles come from untrusted pages and
+// clients, so separators, control characters, and leading dots are removed.
+func (b *Book) Filename() string {
+	name := sanitizeFilename(b.Title)
+	if name == "" {
+		name = sanitizeFilename(b.Hash)
+	}
+	if name == "" {
+		name = "download"
+	}
+
+	if format := strings.ToLower(strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
 		}
-		return "", errors.New("failed to get download URL")
+		return -1
+	}, b.Format)); format != "" {
+		name += "." + format
 	}
 
-	return apiResp.DownloadURL, nil
+	return name
+}
+
+// Path returns the path under which the book is saved in folder. On Windows,
+// the name is shortened to keep the path short enough for other programs.
+func (b *Book) Path(folder string) (string, error) {
+	name := b.Filename()
+	if windowsNames {
+		name = fsutil.FitName(folder, name, fsutil.MaxWindowsPath)
+	}
+
+	return fsutil.SafeJoin(folder, name)
+}
+
+// Layouts of the download path selectable with Dir.
+const (
+	// LayoutFlat saves every book directly in the download path.
+	LayoutFlat = "flat"
+	// LayoutKavita gives every book a folder of its own, which Kavita and
+	// Komga take as its series.
+	LayoutKavita = "kavita"
+	// LayoutCalibre groups books by author, with a folder per title, as in a
+	// Calibre library.
+	LayoutCalibre = "calibre"
+)
+
+// Dir returns the folder, relative to the download path, in which the book is
+// saved under layout. It is empty for the flat layout. Its components are
+// sanitized like Filename.
+func (b *Book) Dir(layout string) string {
+	title := sanitizeFilename(b.Title)
+	if title == "" {
+		title = sanitizeFilename(b.Hash)
+	}
+	if title == "" {
+		title = "download"
+	}
+
+	switch layout {
+	case LayoutKavita:
+		return title
+	case LayoutCalibre:
+		author := ""
+		if len(b.Authors) > 0 {
+			author = sanitizeFilename(b.Authors[0])
+		}
+		if author == "" {
+			author = "Unknown"
+		}
+		return filepath.Join(author, title)
+	default:
+		return ""
+	}
+}
+
+func sanitizeFilename(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\':
+			return '_'
+		case r < 0x20 || r == 0x7f:
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.TrimSpace(name), ". ")
+	if windowsNames {
+		name = fsutil.WindowsName(name)
+	}
+
+	if len(name) > maxFilenameLength {
+		name = name[:maxFilenameLength]
+		// Do not cut a multi-byte character in half
+		for !utf8.ValidString(name) {
+			name = name[:len(name)-1]
+		}
+		name = strings.TrimRight(name, ". ")
+	}
+
+	return name
+}
+
+// SizeBytes converts the human-readable size reported by the search page
+// (for example "0.7MB") into bytes. It returns 0 when the size is unknown.
+func (b *Book) SizeBytes() int64 {
+	size, err := fsutil.ParseSize(b.Size)
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+// AuthorLine returns the authors as a single human-readable line.
+func (b *Book) AuthorLine() string {
+	return strings.Join(b.Authors, ", ")
 }
 
 func (b *Book) String() string {
-	return fmt.Sprintf("Title: %s\nAuthors: %s\nPublisher: %s\nLanguage: %s\nFormat: %s\nSize: %s\nURL: %s\nHash: %s",
-		b.Title, b.Authors, b.Publisher, b.Language, b.Format, b.Size, b.URL, b.Hash)
+	text := fmt.Sprintf("Title: %s\nAuthors: %s\nPublisher: %s\nLanguage: %s\nFormat: %s\nSize: %s\nURL: %s\nHash: %s",
+		b.Title, b.AuthorLine(), b.Publisher, b.Language, b.Format, b.Size, b.URL, b.Hash)
+	if len(b.Sources) > 0 {
+		text += fmt.Sprintf("\nSources: %s", strings.Join(b.Sources, ", "))
+	}
+	if b.FastDownload {
+		text += "\nFast download: available"
+	}
+
+	return text
 }
 
 func (b *Book) ToJSON() (string, error) {
@@ -0,0 +1,1000 @@
+package anna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/fsutil"
+)
+
+const searchRow = `<div>
+  <a href="/md5/%[1]s" class="custom-a block mr-2 sm:mr-4 hover:opacity-80"></a>
+  <div class="max-w-full">
+    <a href="/md5/%[1]s">%[2]s</a>
+    <div class="text-gray-800">✅ English [en] · EPUB · 0.7MB · 2015</div>
+  </div>
+</div>`
+
+func newSearchServer(t *testing.T, titles ...string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body>")
+		for i, title := range titles {
+			fmt.Fprintf(w, searchRow, fmt.Sprintf("%032d", i), title)
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestStreamBooks(t *testing.T) {
+	defer Configure(Options{})
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	up := newSearchServer(t, "First", "Second", "Third")
+
+	if err := Configure(Options{Mirrors: []string{down.URL, up.URL}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Mirror Failover", func(t *testing.T) {
+		books, err := FindBook("query")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(books) != 3 || books[0].Title != "First" || books[0].Format != "EPUB" {
+			t.Errorf("Expected 3 books starting with 'First', got %+v", books)
+		}
+	})
+
+	t.Run("Early Stop", func(t *testing.T) {
+		titles := make([]string, 0)
+		err := StreamBooks("query", func(book *Book) bool {
+			titles = append(titles, book.Title)
+			return len(titles) < 2
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(titles) != 2 || titles[1] != "Second" {
+			t.Errorf("Expected [First Second], got %v", titles)
+		}
+	})
+}
+
+func TestStreamBooksLimit(t *testing.T) {
+	defer Configure(Options{})
+
+	// Pages of three results, the second one repeating the last of the first
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		first := map[int]int{0: 0, 1: 0, 2: 2, 3: 5}
+		start, ok := first[page]
+		fmt.Fprint(w, "<html><body>")
+		for i := start; ok && i < start+3; i++ {
+			fmt.Fprintf(w, searchRow, fmt.Sprintf("%032d", i), fmt.Sprintf("Book %d", i))
+		}
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	if err := Configure(Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	titles := make([]string, 0)
+	err := StreamBooksLimit("query", 7, func(book *Book) bool {
+		titles = append(titles, book.Title)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := "Book 0,Book 1,Book 2,Book 3,Book 4,Book 5,Book 6"
+	if got := strings.Join(titles, ","); got != expected {
+		t.Errorf("Expected titles '%s', got '%s'", expected, got)
+	}
+
+	titles = titles[:0]
+	if err := StreamBooksLimit("query", 50, func(book *Book) bool {
+		titles = append(titles, book.Title)
+		return true
+	}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(titles) != 8 {
+		t.Errorf("Expected the 8 results of every page, got %v", titles)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	defer Configure(Options{})
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	defer recordMirrorSuccess(down.URL)
+
+	if err := Configure(Options{Mirrors: []string{down.URL}, BreakerThreshold: 2, BreakerCooldown: time.Hour}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := FindBook("query"); err == nil || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Expected connection error on attempt %d, got %v", i+1, err)
+		}
+	}
+
+	if _, err := FindBook("query"); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("Expected ErrCircuitOpen once the threshold is reached, got %v", err)
+	}
+
+	statuses := MirrorStatuses()
+	if len(statuses) != 1 || statuses[0].State != CircuitOpen || statuses[0].Failures != 2 {
+		t.Errorf("Expected one open circuit with 2 failures, got %+v", statuses)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	defer Configure(Options{})
+
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	up := newSearchServer(t)
+
+	if err := Configure(Options{Mirrors: []string{down.URL, up.URL}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	Probe(context.Background())
+
+	statuses := HealthStatuses()
+	if len(statuses) != 2 || statuses[0].Available || !statuses[1].Available {
+		t.Errorf("Expected the first mirror down and the second up, got %+v", statuses)
+	}
+
+	if ranked := rankMirrors(Mirrors()); ranked[0] != up.URL {
+		t.Errorf("Expected the available mirror to be ranked first, got %v", ranked)
+	}
+}
+
+func TestExtractAvailability(t *testing.T) {
+	sources, fast := extractAvailability("✅ English [en] · EPUB · 0.7MB · 2015 · 📘 Book (non-fiction) · 🚀/lgli/zlib")
+	if len(sources) != 2 || sources[0] != "lgli" || sources[1] != "zlib" {
+		t.Errorf("Expected sources [lgli zlib], got %v", sources)
+	}
+	if !fast {
+		t.Error("Expected the fast download marker to be detected")
+	}
+
+	sources, fast = extractAvailability("✅ English [en] · PDF · 2MB · 2001 · /ia")
+	if len(sources) != 1 || sources[0] != "ia" || fast {
+		t.Errorf("Expected sources [ia] without fast download, got %v, %t", sources, fast)
+	}
+}
+
+func TestFilename(t *testing.T) {
+	cases := []struct {
+		book     Book
+		expected string
+	}{
+		{Book{Title: "Dune", Format: "EPUB"}, "Dune.epub"},
+		{Book{Title: "../../etc/passwd", Format: "pdf"}, "_.._etc_passwd.pdf"},
+		{Book{Title: "..", Hash: "abc", Format: "pdf"}, "abc.pdf"},
+		{Book{Title: "a\\b\x00c", Format: "../sh"}, "a_bc.sh"},
+		{Book{Title: "   ", Hash: "", Format: ""}, "download"},
+	}
+	for _, c := range cases {
+		if got := c.book.Filename(); got != c.expected {
+			t.Errorf("Expected filename '%s' for title '%s', got '%s'", c.expected, c.book.Title, got)
+		}
+	}
+}
+
+func TestWindowsFilename(t *testing.T) {
+	windowsNames = true
+	defer func() { windowsNames = false }()
+
+	for _, c := range []struct {
+		book     Book
+		expected string
+	}{
+		{Book{Title: "Dune: Messiah?", Format: "epub"}, "Dune_ Messiah_.epub"},
+		{Book{Title: "CON", Format: "pdf"}, "CON_.pdf"},
+		{Book{Title: "nul.", Format: "pdf"}, "nul_.pdf"},
+	} {
+		if got := c.book.Filename(); got != c.expected {
+			t.Errorf("Expected filename '%s' for title '%s', got '%s'", c.expected, c.book.Title, got)
+		}
+	}
+
+	book := Book{Title: strings.Repeat("A Very Long Title ", 20), Format: "epub"}
+	folder := filepath.Join(t.TempDir(), strings.Repeat("nested", 10))
+	path, err := book.Path(folder)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(path) > fsutil.MaxWindowsPath || !strings.HasSuffix(path, ".epub") {
+		t.Errorf("Expected the path to be shortened to %d characters, got %d: %s", fsutil.MaxWindowsPath, len(path), path)
+	}
+}
+
+func TestDir(t *testing.T) {
+	cases := []struct {
+		book     Book
+		layout   string
+		expected string
+	}{
+		{Book{Title: "Dune", Authors: []string{"Frank Herbert"}}, LayoutFlat, ""},
+		{Book{Title: "Dune", Authors: []string{"Frank Herbert"}}, LayoutKavita, "Dune"},
+		{Book{Title: "Dune", Authors: []string{"Frank Herbert", "Brian Herbert"}}, LayoutCalibre, filepath.Join("Frank Herbert", "Dune")},
+		{Book{Title: "../x", Authors: []string{".."}}, LayoutCalibre, filepath.Join("Unknown", "_x")},
+	}
+	for _, c := range cases {
+		if got := c.book.Dir(c.layout); got != c.expected {
+			t.Errorf("Expected dir '%s' for layout '%s', got '%s'", c.expected, c.layout, got)
+		}
+	}
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "content")
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	target := filepath.Join(t.TempDir(), "target")
+	os.WriteFile(target, []byte("original"), 0o644)
+	os.Symlink(target, filepath.Join(dir, "Dune.epub"))
+
+	book := &Book{Title: "Dune", Format: "epub"}
+	path, err := book.Fetch(server.URL, dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "content" {
+		t.Errorf("Expected saved content 'content', got '%s'", data)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "original" {
+		t.Errorf("Expected symlink target to be untouched, got '%s'", data)
+	}
+
+	book = &Book{Title: "Large", Format: "epub"}
+	if _, err := book.FetchLimited(server.URL, dir, 3); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("Expected ErrFileTooLarge above the limit, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "Large.epub")); !os.IsNotExist(err) {
+		t.Errorf("Expected no file to be saved above the limit, got %v", err)
+	}
+
+	var last Progress
+	book = &Book{Title: "Tracked", Format: "epub"}
+	if _, err := book.FetchProgress(server.URL, dir, 0, func(p Progress) { last = p }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !last.Done || last.Bytes != 7 || last.Total != 7 {
+		t.Errorf("Expected a final report of 7 of 7 bytes, got %+v", last)
+	}
+	if last.Percent() != 100 || last.ETA() != 0 {
+		t.Errorf("Expected 100%% with no time left, got %.1f%% and %s", last.Percent(), last.ETA())
+	}
+}
+
+func TestNormalizeHash(t *testing.T) {
+	if got, err := NormalizeHash(" D6E1DC51A50726F00EC438AF21952A45 "); err != nil || got != "d6e1dc51a50726f00ec438af21952a45" {
+		t.Errorf("Expected normalized hash, got '%s' (%v)", got, err)
+	}
+
+	for _, hash := range []string{"", "d6e1dc51", "d6e1dc51a50726f00ec438af21952a4g", "../../d6e1dc51a50726f00ec438af219"} {
+		if _, err := NormalizeHash(hash); !errors.Is(err, ErrInvalidHash) {
+			t.Errorf("Expected ErrInvalidHash for '%s', got %v", hash, err)
+		}
+	}
+}
+
+func TestRelaxations(t *testing.T) {
+	cases := map[string][]string{
+		"Dune: Deluxe Edition":  {"Dune", "Dune Deluxe Edition"},
+		"Dune by Frank Herbert": {"Frank Herbert Dune"},
+		"Frank Herbert - Dune":  {"Frank Herbert Dune", "Dune Frank Herbert"},
+		"Dune":                  {},
+	}
+	for query, expected := range cases {
+		got := Relaxations(query)
+		if len(got) != len(expected) {
+			t.Errorf("Expected relaxations %v for '%s', got %v", expected, query, got)
+			continue
+		}
+		for i := range expected {
+			if got[i] != expected[i] {
+				t.Errorf("Expected relaxations %v for '%s', got %v", expected, query, got)
+				break
+			}
+		}
+	}
+}
+
+func TestRankBooks(t *testing.T) {
+	cases := []struct {
+		query    string
+		prefs    Preferences
+		books    []*Book
+		expected []string
+	}{
+		{
+			query: "dune",
+			books: []*Book{
+				{Hash: "guide", Title: "The Dune Encyclopedia", Authors: []string{"Willis E. McNelly"}},
+				{Hash: "messiah", Title: "Dune Messiah", Authors: []string{"Frank Herbert"}},
+				{Hash: "unrelated", Title: "Foundation", Authors: []string{"Isaac Asimov"}},
+				{Hash: "dune", Title: "Dune", Authors: []string{"Frank Herbert"}},
+			},
+			expected: []string{"dune", "guide", "messiah", "unrelated"},
+		},
+		{
+			query: "dostoevsky crime punishmnet",
+			books: []*Book{
+				{Hash: "notes", Title: "Notes from Underground", Authors: []string{"Fyodor Dostoevsky"}},
+				{Hash: "crime", Title: "Преступление и наказание", Authors: []string{"Фёдор Достоевский"}},
+				{Hash: "english", Title: "Crime and Punishment", Authors: []string{"Fyodor Dostoevskiy"}},
+			},
+			expected: []string{"english", "notes", "crime"},
+		},
+		{
+			query: "dune",
+			prefs: Preferences{Formats: []string{"epub", "pdf"}, Languages: []string{"en"}},
+			books: []*Book{
+				{Hash: "pdf", Title: "Dune", Format: "pdf", Language: "English"},
+				{Hash: "german", Title: "Dune", Format: "epub", Language: "German"},
+				{Hash: "messiah", Title: "Dune Messiah", Format: "epub", Language: "English"},
+				{Hash: "epub", Title: "Dune", Format: "EPUB", Language: "English"},
+			},
+			expected: []string{"epub", "pdf", "german", "messiah"},
+		},
+		{
+			query: "the",
+			prefs: Preferences{Languages: []string{"French"}},
+			books: []*Book{
+				{Hash: "english", Title: "B", Language: "English"},
+				{Hash: "french", Title: "A", Language: "French"},
+			},
+			expected: []string{"french", "english"},
+		},
+		{
+			query: "the",
+			books: []*Book{
+				{Hash: "b", Title: "B"},
+				{Hash: "a", Title: "A"},
+			},
+			expected: []string{"b", "a"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.query, func(t *testing.T) {
+			RankBooks(c.query, c.books, c.prefs)
+			for i, book := range c.books {
+				if book.Hash != c.expected[i] {
+					t.Errorf("Expected result %d '%s', got '%s'", i, c.expected[i], book.Hash)
+				}
+			}
+		})
+	}
+}
+
+func TestGetPaper(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scidb/10.1038/nature12373" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `<html><body><main>
+			<div class="text-xl">Nanometre-scale thermometry in a living cell</div>
+			<a href="/md5/D6E1DC51A50726F00EC438AF21952A45">Record</a>
+			<embed src="https://cdn.example.com/papers/nature12373.pdf#view=FitH">
+		</main></body></html>`)
+	}))
+	defer server.Close()
+
+	if err := Configure(Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Failed to configure client: %v", err)
+	}
+	defer Configure(Options{})
+
+	paper, err := GetPaper("https://doi.org/10.1038/nature12373")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if paper.Title != "Nanometre-scale thermometry in a living cell" {
+		t.Errorf("Unexpected title '%s'", paper.Title)
+	}
+	if paper.Hash != "d6e1dc51a50726f00ec438af21952a45" {
+		t.Errorf("Expected normalized record hash, got '%s'", paper.Hash)
+	}
+	if paper.DownloadURL != "https://cdn.example.com/papers/nature12373.pdf#view=FitH" {
+		t.Errorf("Expected embedded PDF link, got '%s'", paper.DownloadURL)
+	}
+
+	if _, err := NormalizeDOI("nature12373"); !errors.Is(err, ErrInvalidDOI) {
+		t.Errorf("Expected ErrInvalidDOI, got %v", err)
+	}
+}
+
+func TestStreamPeriodicals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("content") != ContentMagazine {
+			fmt.Fprint(w, "<html><body></body></html>")
+			return
+		}
+		fmt.Fprint(w, "<html><body>")
+		fmt.Fprintf(w, searchRow, fmt.Sprintf("%032d", 1), "Scientific American Vol. 312 No. 4")
+		fmt.Fprintf(w, searchRow, fmt.Sprintf("%032d", 2), "Wired #27 (2019)")
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	if err := Configure(Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Failed to configure client: %v", err)
+	}
+	defer Configure(Options{})
+
+	var issues []*Periodical
+	err := StreamPeriodicals("science", ContentMagazine, func(p *Periodical) bool {
+		issues = append(issues, p)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(issues) != 2 {
+		t.Fatalf("Expected 2 issues, got %d", len(issues))
+	}
+
+	if issues[0].Volume != "312" || issues[0].Issue != "4" {
+		t.Errorf("Expected volume '312' issue '4', got '%s' '%s'", issues[0].Volume, issues[0].Issue)
+	}
+	if issues[1].Volume != "" || issues[1].Issue != "27" {
+		t.Errorf("Expected no volume and issue '27', got '%s' '%s'", issues[1].Volume, issues[1].Issue)
+	}
+	// The meta line wins over the year in the title
+	if issues[1].Year != "2015" {
+		t.Errorf("Expected year '2015', got '%s'", issues[1].Year)
+	}
+}
+
+func TestFindBookFiltered(t *testing.T) {
+	var langs, exts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		langs = r.URL.Query()["lang"]
+		exts = r.URL.Query()["ext"]
+		fmt.Fprint(w, "<html><body>")
+		fmt.Fprintf(w, searchRow, fmt.Sprintf("%032d", 1), "Der Process")
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	if err := Configure(Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Failed to configure client: %v", err)
+	}
+	defer Configure(Options{})
+
+	books, err := FindBookFiltered("kafka", Filters{Languages: []string{"de", "en"}, Formats: []string{"epub"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(books) != 1 {
+		t.Errorf("Expected 1 book, got %d", len(books))
+	}
+	if got := strings.Join(langs, ","); got != "de,en" {
+		t.Errorf("Expected the lang filters 'de,en', got '%s'", got)
+	}
+	if got := strings.Join(exts, ","); got != "epub" {
+		t.Errorf("Expected the ext filter 'epub', got '%s'", got)
+	}
+
+	if _, err := FindBook("kafka"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(langs) != 0 || len(exts) != 0 {
+		t.Errorf("Expected no filters, got %v and %v", langs, exts)
+	}
+}
+
+func TestSuggestKeepsFilters(t *testing.T) {
+	var queries []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query())
+		fmt.Fprint(w, "<html><body>")
+		fmt.Fprintf(w, searchRow, fmt.Sprintf("%032d", 1), "Der Process")
+		fmt.Fprint(w, "</body></html>")
+	}))
+	defer server.Close()
+
+	if err := Configure(Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Failed to configure client: %v", err)
+	}
+	defer Configure(Options{})
+
+	suggestions := Suggest("Der Process: Roman", Filters{Languages: []string{"de"}, Formats: []string{"epub"}})
+	if len(suggestions) == 0 {
+		t.Fatal("Expected suggestions")
+	}
+	for _, query := range queries {
+		if got := strings.Join(query["lang"], ","); got != "de" {
+			t.Errorf("Expected the lang filter 'de' on %q, got '%s'", query.Get("q"), got)
+		}
+		if got := strings.Join(query["ext"], ","); got != "epub" {
+			t.Errorf("Expected the ext filter 'epub' on %q, got '%s'", query.Get("q"), got)
+		}
+	}
+}
+
+func TestLanguageCode(t *testing.T) {
+	for value, expected := range map[string]string{"EN": "en", " German ": "de", "gsw": "gsw"} {
+		if got, ok := LanguageCode(value); !ok || got != expected {
+			t.Errorf("Expected code '%s' for '%s', got '%s'", expected, value, got)
+		}
+	}
+	for _, value := range []string{"", "e", "en-US", "Klingon"} {
+		if _, ok := LanguageCode(value); ok {
+			t.Errorf("Expected '%s' to be rejected", value)
+		}
+	}
+}
+
+func TestFormatCode(t *testing.T) {
+	for value, expected := range map[string]string{".EPUB": "epub", " pdf": "pdf", "cb7": "cb7"} {
+		if got, ok := FormatCode(value); !ok || got != expected {
+			t.Errorf("Expected extension '%s' for '%s', got '%s'", expected, value, got)
+		}
+	}
+	for _, value := range []string{"", ".", "tar.gz", "epub3-fixed"} {
+		if _, ok := FormatCode(value); ok {
+			t.Errorf("Expected '%s' to be rejected", value)
+		}
+	}
+}
+
+func TestListTorrents(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body>
+			<h3>Libgen.rs Non-Fiction</h3>
+			<div>Books from the Library Genesis non-fiction collection.</div>
+			<table>
+				<tr><th>Torrent</th><th>Size</th><th>Seeders</th></tr>
+				<tr><td><a href="/dyn/small_file/torrents/r_000.torrent">r_000.torrent</a></td><td>706.3GB</td><td>🟢 42 seeders</td></tr>
+			</table>
+			<h3>Z-Library</h3>
+			<table>
+				<tr><td><a href="/dyn/small_file/torrents/zlib_1.torrent">zlib_1.torrent</a></td><td>1.2 TB</td><td>🔴 0 seeders</td></tr>
+			</table>
+		</body></html>`)
+	}))
+	defer server.Close()
+
+	if err := Configure(Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Failed to configure client: %v", err)
+	}
+	defer Configure(Options{})
+
+	torrents, err := ListTorrents("")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(torrents) != 2 {
+		t.Fatalf("Expected 2 torrents, got %d", len(torrents))
+	}
+
+	first := torrents[0]
+	if first.Name != "r_000.torrent" || first.Group != "Libgen.rs Non-Fiction" {
+		t.Errorf("Unexpected torrent '%s' in group '%s'", first.Name, first.Group)
+	}
+	if first.Summary != "Books from the Library Genesis non-fiction collection." {
+		t.Errorf("Unexpected summary '%s'", first.Summary)
+	}
+	if first.Size != "706.3GB" || first.Seeders != 42 {
+		t.Errorf("Expected size '706.3GB' and 42 seeders, got '%s' and %d", first.Size, first.Seeders)
+	}
+	if torrents[1].Summary != "" || torrents[1].Size != "1.2 TB" {
+		t.Errorf("Expected no summary and size '1.2 TB', got '%s' and '%s'", torrents[1].Summary, torrents[1].Size)
+	}
+
+	torrents, err = ListTorrents("z-lib")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(torrents) != 1 || torrents[0].Name != "zlib_1.torrent" {
+		t.Errorf("Expected only the Z-Library torrent, got %v", torrents)
+	}
+}
+
+func TestFixtures(t *testing.T) {
+	defer Configure(Options{})
+
+	server := newSearchServer(t, "Recorded Book")
+	dir := t.TempDir()
+
+	if err := Configure(Options{Mirrors: []string{server.URL}, FixtureDir: dir, FixtureMode: FixtureRecord}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := FindBook("recorded"); err != nil {
+		t.Fatalf("Unexpected error while recording: %v", err)
+	}
+
+	// Replay must not need the server anymore
+	server.Close()
+	if err := Configure(Options{Mirrors: []string{server.URL}, FixtureDir: dir, FixtureMode: FixtureReplay}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	books, err := FindBook("recorded")
+	if err != nil {
+		t.Fatalf("Unexpected error while replaying: %v", err)
+	}
+	if len(books) != 1 || books[0].Title != "Recorded Book" {
+		t.Errorf("Expected the recorded book, got %v", books)
+	}
+
+	if _, err := FindBook("never recorded"); !errors.Is(err, ErrFixtureMissing) {
+		t.Errorf("Expected ErrFixtureMissing, got %v", err)
+	}
+}
+
+func TestParseAuthors(t *testing.T) {
+	cases := map[string][]string{
+		"Frank Herbert":                   {"Frank Herbert"},
+		"Kernighan, Brian W.; Pike, Rob":  {"Kernighan, Brian W.", "Pike, Rob"},
+		"  Gamma   & Helm and Johnson ; ": {"Gamma", "Helm", "Johnson"},
+		"Frank Herbert; frank herbert":    {"Frank Herbert"},
+		"":                                {},
+	}
+	for line, expected := range cases {
+		got := ParseAuthors(line)
+		if strings.Join(got, "|") != strings.Join(expected, "|") {
+			t.Errorf("Expected authors %q for '%s', got %q", expected, line, got)
+		}
+	}
+}
+
+func TestLinkExpiry(t *testing.T) {
+	cases := map[string]string{
+		"https://partner.example/d3/book.epub?expires=1767225600":                        "2026-01-01T00:00:00Z",
+		"https://partner.example/d3/book.epub?token=abc&e=1767225600":                    "2026-01-01T00:00:00Z",
+		"https://bucket.example/book.epub?X-Amz-Date=20260101T000000Z&X-Amz-Expires=600": "2026-01-01T00:10:00Z",
+		"https://partner.example/d3/book.epub":                                           "",
+		"https://partner.example/d3/book.epub?expires=soon":                              "",
+	}
+	for link, expected := range cases {
+		got := ""
+		if expiry := LinkExpiry(link); expiry != nil {
+			got = expiry.Format(time.RFC3339)
+		}
+		if got != expected {
+			t.Errorf("Expected expiry '%s' for '%s', got '%s'", expected, link, got)
+		}
+	}
+}
+
+func TestGetDownloadInfoErrors(t *testing.T) {
+	defer Configure(Options{})
+
+	var status int
+	var page, gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotKey = r.URL.Query().Get("key")
+		if status == http.StatusTooManyRequests {
+			w.Header().Set("Retry-After", "120")
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+	defer recordMirrorSuccess(server.URL)
+
+	if err := Configure(Options{Mirrors: []string{server.URL}, BreakerThreshold: 100}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	book := &Book{Hash: "d6e1dc51a50726f00ec438af21952a45"}
+
+	cases := []struct {
+		name   string
+		status int
+		page   string
+		want   error
+	}{
+		{"Unavailable", http.StatusBadGateway, `<html><body>Bad gateway</body></html>`, ErrMaintenance},
+		{"Blocked", http.StatusForbidden, `<html><head><title>Just a moment...</title></head></html>`, ErrBlocked},
+		// Last, as the client then holds back requests to the host
+		{"RateLimited", http.StatusTooManyRequests, `<html><body>Too many requests</body></html>`, ErrRateLimited},
+	}
+
+	t.Run("APIError", func(t *testing.T) {
+		status, page = http.StatusUnauthorized, `{"download_url": null, "error": "Invalid secret key"}`
+		var apiErr *APIError
+		if _, err := book.GetDownloadInfo("key"); !errors.As(err, &apiErr) || apiErr.Message != "Invalid secret key" {
+			t.Errorf("Expected the API error, got %v", err)
+		}
+	})
+
+	t.Run("EscapedKey", func(t *testing.T) {
+		key := "member+key&md5=other"
+		status, page = http.StatusOK, `{"download_url": "https://example.org/file.pdf"}`
+		info, err := book.GetDownloadInfo(key)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if gotKey != key || info.URL != "https://example.org/file.pdf" {
+			t.Errorf("Expected the key to be sent as is, got '%s'", gotKey)
+		}
+	})
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			status, page = c.status, c.page
+			if _, err := book.GetDownloadInfo("key"); !errors.Is(err, c.want) {
+				t.Errorf("Expected %v, got %v", c.want, err)
+			}
+		})
+	}
+}
+
+func TestUserAgentRotation(t *testing.T) {
+	var agents []string
+	blocked := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.UserAgent())
+		if blocked {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	get := func(transport http.RoundTripper) {
+		resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	t.Run("Fixed", func(t *testing.T) {
+		agents = nil
+		transport, _ := newUserAgentTransport(http.DefaultTransport, "annas-mcp/1.0", []string{"a", "b"}, UserAgentRotationRequest)
+		get(transport)
+		get(transport)
+		if agents[0] != "annas-mcp/1.0" || agents[1] != "annas-mcp/1.0" {
+			t.Errorf("Expected the fixed user agent, got %v", agents)
+		}
+	})
+
+	t.Run("Request", func(t *testing.T) {
+		agents = nil
+		transport, _ := newUserAgentTransport(http.DefaultTransport, "", []string{"a", "b"}, UserAgentRotationRequest)
+		get(transport)
+		get(transport)
+		get(transport)
+		if strings.Join(agents, ",") != "a,b,a" {
+			t.Errorf("Expected user agents 'a,b,a', got '%s'", strings.Join(agents, ","))
+		}
+	})
+
+	t.Run("Mirror", func(t *testing.T) {
+		agents = nil
+		transport, _ := newUserAgentTransport(http.DefaultTransport, "", []string{"a", "b"}, UserAgentRotationMirror)
+		get(transport)
+		get(transport)
+		blocked = true
+		get(transport)
+		blocked = false
+		get(transport)
+		if strings.Join(agents, ",") != "a,a,a,b" {
+			t.Errorf("Expected user agents 'a,a,a,b', got '%s'", strings.Join(agents, ","))
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		if _, err := newUserAgentTransport(http.DefaultTransport, "", nil, "sometimes"); err == nil {
+			t.Error("Expected an error for an invalid rotation")
+		}
+	})
+}
+
+func TestCookieJar(t *testing.T) {
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie("clearance"); err == nil {
+			sent = append(sent, cookie.Value)
+			return
+		}
+		http.SetCookie(w, &http.Cookie{Name: "clearance", Value: "passed", Path: "/", MaxAge: 3600})
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "cookies.json")
+	if err := Configure(Options{Mirrors: []string{server.URL}, CookieFile: path}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer Configure(Options{})
+
+	for range 2 {
+		resp, err := client().Get(server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if len(sent) != 1 || sent[0] != "passed" {
+		t.Errorf("Expected the cookie on the second request, got %v", sent)
+	}
+
+	jar, err := newCookieJar(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	u, _ := url.Parse(server.URL)
+	if cookies := jar.Cookies(u); len(cookies) != 1 || cookies[0].Value != "passed" {
+		t.Errorf("Expected the cookie to be restored from the file, got %v", cookies)
+	}
+}
+
+func TestLocale(t *testing.T) {
+	t.Run("Accept-Language", func(t *testing.T) {
+		for locale, want := range map[string]string{
+			"de-DE":       "de-DE,de;q=0.9,en;q=0.8",
+			"pt_BR.UTF-8": "pt-BR,pt;q=0.9,en;q=0.8",
+			"fr":          "fr,en;q=0.8",
+			"en-us":       "en-US,en;q=0.9",
+		} {
+			language, region, err := parseLocale(locale)
+			if err != nil {
+				t.Fatalf("Unexpected error for '%s': %v", locale, err)
+			}
+			if got := acceptLanguage(language, region); got != want {
+				t.Errorf("Expected Accept-Language '%s' for '%s', got '%s'", want, locale, got)
+			}
+		}
+		if _, _, err := parseLocale("german"); err == nil {
+			t.Error("Expected an error for an invalid locale")
+		}
+	})
+
+	t.Run("Regional Mirrors", func(t *testing.T) {
+		var header string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Get("Accept-Language")
+		}))
+		defer server.Close()
+
+		if err := Configure(Options{Mirrors: []string{"annas-archive.org", "annas-archive.de"}, Locale: "de-DE"}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer Configure(Options{})
+
+		resp, err := client().Get(server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if header != "de-DE,de;q=0.9,en;q=0.8" {
+			t.Errorf("Expected the locale as Accept-Language, got '%s'", header)
+		}
+
+		if ranked := rankMirrors(Mirrors()); ranked[0] != "https://annas-archive.de" {
+			t.Errorf("Expected the German mirror first, got %v", ranked)
+		}
+		if !RegionalHost("de.partner.net", "DE") || !RegionalHost("files.example.co.uk", "GB") || RegionalHost("annas-archive.org", "DE") {
+			t.Error("Expected hosts to be matched by their country domain or leading label")
+		}
+	})
+}
+
+func TestRateLimitRetry(t *testing.T) {
+	var requests int
+	const limited = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= limited {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer server.Close()
+
+	t.Run("Retry", func(t *testing.T) {
+		resp, err := (&http.Client{Transport: newRetryTransport(http.DefaultTransport, time.Second)}).Get(server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || requests != 2 {
+			t.Errorf("Expected status 200 after 2 requests, got %d after %d", resp.StatusCode, requests)
+		}
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		_, err := (&http.Client{Transport: newRetryTransport(http.DefaultTransport, time.Second)}).Get(server.URL)
+		var limitErr *RateLimitError
+		if !errors.Is(err, ErrRateLimited) || !errors.As(err, &limitErr) {
+			t.Fatalf("Expected a rate limit error, got %v", err)
+		}
+		if limitErr.RetryAfter != 2*time.Minute {
+			t.Errorf("Expected to retry in 2m0s, got %s", limitErr.RetryAfter)
+		}
+	})
+
+	t.Run("Retry-After", func(t *testing.T) {
+		now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+		if got, ok := retryAfter("Thu, 01 Jan 2026 12:00:30 GMT", now); !ok || got != 30*time.Second {
+			t.Errorf("Expected 30s, got %s", got)
+		}
+		if _, ok := retryAfter("soon", now); ok {
+			t.Error("Expected an invalid header to be ignored")
+		}
+	})
+}
+
+func TestClassifyPage(t *testing.T) {
+	cases := map[string]struct {
+		status int
+		body   string
+	}{
+		PageEmpty:         {http.StatusOK, `<html><body><div>No files found.</div></body></html>`},
+		PageLayoutChanged: {http.StatusOK, `<html><body><div class="results"><a href="/book/1">Dune</a></div></body></html>`},
+		PageBlocked:       {http.StatusForbidden, `<html><head><title>Just a moment...</title></head></html>`},
+		PageMaintenance:   {http.StatusOK, `<html><body>Anna's Archive is down for maintenance.</body></html>`},
+	}
+	for expected, page := range cases {
+		if got := ClassifyPage(page.status, []byte(page.body)); got != expected {
+			t.Errorf("Expected class '%s', got '%s'", expected, got)
+		}
+	}
+
+	t.Run("Failover", func(t *testing.T) {
+		defer Configure(Options{})
+
+		blocked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `<html><head><title>Attention Required! | Cloudflare</title></head></html>`)
+		}))
+		defer blocked.Close()
+		empty := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<html><body>No files found.</body></html>`)
+		}))
+		defer empty.Close()
+
+		if err := Configure(Options{Mirrors: []string{blocked.URL, empty.URL}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		class, err := StreamBooksClassified("query", 0, func(*Book) bool { return true })
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if class != PageEmpty {
+			t.Errorf("Expected class '%s' from the second mirror, got '%s'", PageEmpty, class)
+		}
+
+		if err := Configure(Options{Mirrors: []string{blocked.URL}}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := StreamBooksClassified("query", 0, func(*Book) bool { return true }); !errors.Is(err, ErrBlocked) {
+			t.Errorf("Expected ErrBlocked, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,30 @@
+package anna
+
+import (
+	"regexp"
+	"strings"
+)
+
+// authorSeparator splits the author line of a record, which lists several
+// authors separated by semicolons, ampersands, or "and". Commas are kept
+// since they separate the last and first names of inverted entries.
+var authorSeparator = regexp.MustCompile(`\s*(?:;|&|\band\b)\s*`)
+
+// ParseAuthors splits an author line into the individual authors, with
+// whitespace collapsed and duplicates dropped.
+func ParseAuthors(line string) []string {
+	authors := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, author := range authorSeparator.Split(line, -1) {
+		author = strings.Trim(strings.Join(strings.Fields(author), " "), ",;")
+		author = strings.TrimSpace(author)
+		key := strings.ToLower(author)
+		if author == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		authors = append(authors, author)
+	}
+
+	return authors
+}
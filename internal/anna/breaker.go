@@ -0,0 +1,126 @@
+package anna
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Circuit states reported by MirrorStatuses.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// Defaults used when Options leaves the breaker settings unset.
+const (
+	defaultBreakerThreshold = 3
+	defaultBreakerCooldown  = time.Minute
+)
+
+// ErrCircuitOpen is returned when every mirror is skipped because its circuit
+// is open.
+var ErrCircuitOpen = errors.New("all mirrors are temporarily disabled after repeated failures")
+
+// MirrorStatus describes the circuit breaker of one mirror.
+type MirrorStatus struct {
+	Mirror    string     `json:"mirror"`
+	State     string     `json:"state"`
+	Failures  int        `json:"failures"`
+	OpenUntil *time.Time `json:"open_until,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	lastError string
+}
+
+// Breakers are keyed by mirror base URL and survive Configure, so a reload
+// does not give a failing mirror a fresh start.
+var (
+	breakerMu        sync.Mutex
+	circuits         = make(map[string]*circuit)
+	breakerThreshold = defaultBreakerThreshold
+	breakerCooldown  = defaultBreakerCooldown
+)
+
+func configureBreaker(threshold int, cooldown time.Duration) {
+	if threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+	breakerThreshold = threshold
+	breakerCooldown = cooldown
+}
+
+// allow reports whether a request may be sent to the mirror. Once the
+// cooldown is over the circuit is half-open and lets requests through again;
+// the next failure opens it right away.
+func allow(base string) bool {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+
+	c, ok := circuits[base]
+	return !ok || !time.Now().Before(c.openUntil)
+}
+
+func recordMirrorSuccess(base string) {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+
+	delete(circuits, base)
+}
+
+func recordMirrorFailure(base string, err error) {
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+
+	c, ok := circuits[base]
+	if !ok {
+		c = &circuit{}
+		circuits[base] = c
+	}
+	c.failures++
+	c.lastError = err.Error()
+	if c.failures >= breakerThreshold {
+		c.openUntil = time.Now().Add(breakerCooldown)
+	}
+}
+
+// MirrorStatuses reports the circuit breaker state of every configured mirror.
+func MirrorStatuses() []MirrorStatus {
+	bases := Mirrors()
+
+	breakerMu.Lock()
+	defer breakerMu.Unlock()
+
+	now := time.Now()
+	statuses := make([]MirrorStatus, 0, len(bases))
+	for _, base := range bases {
+		status := MirrorStatus{Mirror: base, State: CircuitClosed}
+		if c, ok := circuits[base]; ok {
+			status.Failures = c.failures
+			status.LastError = c.lastError
+			if !c.openUntil.IsZero() {
+				if now.Before(c.openUntil) {
+					openUntil := c.openUntil
+					status.State = CircuitOpen
+					status.OpenUntil = &openUntil
+				} else {
+					status.State = CircuitHalfOpen
+				}
+			}
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses
+}
@@ -0,0 +1,91 @@
+package anna
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Classes of search pages without results, telling a real empty result from
+// pages that only look like one.
+const (
+	// PageEmpty is a search page stating that nothing matched.
+	PageEmpty = "empty"
+	// PageLayoutChanged is a page served like search results whose rows the
+	// parser does not recognize, usually after a redesign of the site.
+	PageLayoutChanged = "layout_changed"
+	// PageBlocked is a bot challenge, captcha, or access denied page.
+	PageBlocked = "blocked"
+	// PageMaintenance is a page announcing that the site is down for
+	// maintenance or overloaded.
+	PageMaintenance = "maintenance"
+)
+
+var (
+	// ErrBlocked is returned when a mirror answers searches with a block page.
+	ErrBlocked = errors.New("blocked by a bot challenge")
+	// ErrMaintenance is returned when a mirror answers searches with a
+	// maintenance page.
+	ErrMaintenance = errors.New("down for maintenance")
+)
+
+// PageError is a search page that is not a result page.
+type PageError struct {
+	Mirror string
+	Class  string
+	Status int
+}
+
+func (e *PageError) Error() string {
+	return fmt.Sprintf("%s answered a %s page (status %d)", e.Mirror, e.Class, e.Status)
+}
+
+func (e *PageError) Unwrap() error {
+	if e.Class == PageBlocked {
+		return ErrBlocked
+	}
+	return ErrMaintenance
+}
+
+// Markers of the pages, matched case-insensitively. Block pages are checked
+// first, since challenge pages also mention being temporarily unavailable.
+var (
+	blockedMarkers = [][]byte{
+		[]byte("cf-chl-"), []byte("challenge-platform"), []byte("just a moment..."),
+		[]byte("attention required"), []byte("ddos-guard"), []byte("captcha"),
+		[]byte("access denied"), []byte("you have been blocked"),
+	}
+	maintenanceMarkers = [][]byte{
+		[]byte("maintenance"), []byte("temporarily unavailable"), []byte("be back soon"),
+		[]byte("service unavailable"), []byte("bad gateway"), []byte("overloaded"),
+	}
+	emptyMarkers = [][]byte{
+		[]byte("no files found"), []byte("no results"),
+	}
+)
+
+// ClassifyPage tells why a search page with the given status and body held
+// no results.
+func ClassifyPage(status int, body []byte) string {
+	lower := bytes.ToLower(body)
+	contains := func(markers [][]byte) bool {
+		for _, marker := range markers {
+			if bytes.Contains(lower, marker) {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch {
+	case contains(blockedMarkers), status == http.StatusForbidden:
+		return PageBlocked
+	case contains(maintenanceMarkers), status == http.StatusServiceUnavailable, status == http.StatusBadGateway:
+		return PageMaintenance
+	case contains(emptyMarkers):
+		return PageEmpty
+	}
+
+	return PageLayoutChanged
+}
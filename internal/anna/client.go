@@ -0,0 +1,240 @@
+package anna
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	colly "github.com/gocolly/colly/v2"
+)
+
+// DefaultMirror is the Anna's Archive domain used when no mirrors are configured.
+const DefaultMirror = "annas-archive.org"
+
+// Options controls how the package reaches Anna's Archive.
+type Options struct {
+	// Mirrors lists the domains (or base URLs) to try, in order.
+	Mirrors []string
+	// Proxy is an optional http://, https:// or socks5:// proxy URL. When empty
+	// the standard HTTP_PROXY/HTTPS_PROXY environment variables apply.
+	Proxy string
+
+	// Connection pool settings. Zero values keep the defaults of Go's
+	// http.DefaultTransport; the defaults of the configuration raise
+	// MaxIdleConnsPerHost so that batch downloads from one mirror reuse their
+	// connections.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	MaxConnsPerHost     int
+	IdleConnTimeout     time.Duration
+	DisableKeepAlives   bool
+	DisableHTTP2        bool
+
+	// UserAgent is sent with every request when set. Otherwise, UserAgents
+	// (or DefaultUserAgents when empty) are rotated through as selected by
+	// UserAgentRotation, which defaults to UserAgentRotationOff.
+	UserAgent         string
+	UserAgents        []string
+	UserAgentRotation string
+
+	// Locale, such as de-DE, is sent as Accept-Language, and prefers the
+	// mirrors of its region among equally healthy ones. Empty sends no
+	// language and prefers none.
+	Locale string
+
+	// RateLimitWait is how long a request may wait for a mirror or partner
+	// server that answers 429 before failing with ErrRateLimited. Zero
+	// selects DefaultRateLimitWait.
+	RateLimitWait time.Duration
+
+	// CookieFile persists the cookies set by Anna's Archive, such as session
+	// and clearance cookies, across restarts. Empty keeps them in memory.
+	CookieFile string
+
+	// BreakerThreshold is the number of consecutive failures after which a
+	// mirror is skipped for BreakerCooldown. Zero values select 3 and one minute.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// FixtureDir records upstream responses to disk and replays them, as
+	// selected by FixtureMode. Empty disables fixtures.
+	FixtureDir  string
+	FixtureMode string
+}
+
+var (
+	optionsMu  sync.RWMutex
+	mirrors    = []string{"https://" + DefaultMirror}
+	region     string
+	cookies    = &persistentJar{Jar: mustCookieJar(), saved: make(map[string]savedCookie)}
+	httpClient = &http.Client{Transport: defaultTransport(), Jar: cookies}
+)
+
+// Configure replaces the mirrors, proxy, and transport used by all subsequent
+// requests.
+func Configure(opts Options) error {
+	bases := make([]string, 0, len(opts.Mirrors))
+	for _, mirror := range opts.Mirrors {
+		mirror = strings.TrimRight(strings.TrimSpace(mirror), "/")
+		if mirror == "" {
+			continue
+		}
+		if !strings.Contains(mirror, "://") {
+			mirror = "https://" + mirror
+		}
+		if _, err := url.Parse(mirror); err != nil {
+			return fmt.Errorf("invalid mirror %q: %w", mirror, err)
+		}
+		bases = append(bases, mirror)
+	}
+	if len(bases) == 0 {
+		bases = append(bases, "https://"+DefaultMirror)
+	}
+
+	transport := defaultTransport()
+	if opts.MaxIdleConns > 0 {
+		transport.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = opts.MaxConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	transport.DisableKeepAlives = opts.DisableKeepAlives
+	if opts.DisableHTTP2 {
+		// A non-nil empty map is how net/http is told not to negotiate HTTP/2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	}
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("invalid proxy URL %q", opts.Proxy)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	roundTripper, err := newUserAgentTransport(newRetryTransport(transport, opts.RateLimitWait), opts.UserAgent, opts.UserAgents, opts.UserAgentRotation)
+	if err != nil {
+		return err
+	}
+	var language, localeRegion string
+	if opts.Locale != "" {
+		if language, localeRegion, err = parseLocale(opts.Locale); err != nil {
+			return err
+		}
+		roundTripper = &languageTransport{base: roundTripper, value: acceptLanguage(language, localeRegion)}
+	}
+	if opts.FixtureDir != "" {
+		fixtures, err := newFixtureTransport(opts.FixtureDir, opts.FixtureMode, roundTripper)
+		if err != nil {
+			return err
+		}
+		roundTripper = fixtures
+	}
+
+	// Reloads that keep the cookie file keep the cookies set since
+	optionsMu.RLock()
+	jar := cookies
+	optionsMu.RUnlock()
+	if jar.path != opts.CookieFile {
+		if jar, err = newCookieJar(opts.CookieFile); err != nil {
+			return err
+		}
+	}
+
+	configureBreaker(opts.BreakerThreshold, opts.BreakerCooldown)
+
+	optionsMu.Lock()
+	previous := httpClient
+	mirrors = bases
+	region = localeRegion
+	cookies = jar
+	httpClient = &http.Client{Transport: roundTripper, Jar: jar}
+	optionsMu.Unlock()
+
+	// Requests in flight keep their connections, idle ones are dropped
+	previous.CloseIdleConnections()
+
+	return nil
+}
+
+// Mirrors returns the base URLs of the configured mirrors in the order they
+// are tried.
+func Mirrors() []string {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+
+	return append([]string(nil), mirrors...)
+}
+
+func defaultTransport() *http.Transport {
+	return http.DefaultTransport.(*http.Transport).Clone()
+}
+
+func client() *http.Client {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+
+	return httpClient
+}
+
+func newCollector(options ...colly.CollectorOption) *colly.Collector {
+	c := colly.NewCollector(options...)
+	shared := client()
+	c.WithTransport(shared.Transport)
+	// Cookies are shared by all requests instead of starting over every call
+	c.SetCookieJar(shared.Jar)
+
+	return c
+}
+
+// eachMirror calls fn with the base URL of every mirror, ranked by their
+// health probes, until one succeeds, skipping mirrors whose circuit breaker
+// is open. fn returns a terminal error wrapped in stopMirrors to prevent
+// failover, for instance when the mirror answered but rejected the request.
+func eachMirror(fn func(base string) error) error {
+	var errs []error
+	for _, base := range rankMirrors(Mirrors()) {
+		if !allow(base) {
+			continue
+		}
+
+		err := fn(base)
+		if err == nil {
+			recordMirrorSuccess(base)
+			return nil
+		}
+
+		var stop *stopMirrors
+		if errors.As(err, &stop) {
+			// The mirror answered, so it is healthy even if the request failed
+			recordMirrorSuccess(base)
+			return stop.err
+		}
+		recordMirrorFailure(base, err)
+		errs = append(errs, fmt.Errorf("%s: %w", base, err))
+	}
+
+	if len(errs) == 0 {
+		return ErrCircuitOpen
+	}
+	return errors.Join(errs...)
+}
+
+type stopMirrors struct {
+	err error
+}
+
+func (s *stopMirrors) Error() string {
+	return s.err.Error()
+}
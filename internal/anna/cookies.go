@@ -0,0 +1,177 @@
+package anna
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// savedCookie is a cookie of the cookie file, along with the URL it was set
+// for.
+type savedCookie struct {
+	URL      string    `json:"url"`
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain,omitempty"`
+	Path     string    `json:"path,omitempty"`
+	Expires  time.Time `json:"expires,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+}
+
+// cookieFile is the content of the cookie file.
+type cookieFile struct {
+	Cookies []savedCookie `json:"cookies"`
+}
+
+// persistentJar is a cookie jar that writes the cookies it is given to a file,
+// so session and clearance cookies survive restarts. Without a path, it only
+// keeps them in memory.
+type persistentJar struct {
+	*cookiejar.Jar
+	path string
+
+	mu    sync.Mutex
+	saved map[string]savedCookie
+}
+
+// mustCookieJar returns an empty in-memory cookie jar.
+func mustCookieJar() *cookiejar.Jar {
+	// New never fails without a public suffix list
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
+
+// newCookieJar returns a jar loaded from path, which may not exist yet.
+func newCookieJar(path string) (*persistentJar, error) {
+	j := &persistentJar{Jar: mustCookieJar(), path: path, saved: make(map[string]savedCookie)}
+	if path == "" {
+		return j, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return j, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookie file: %w", err)
+	}
+
+	var file cookieFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse cookie file: %w", err)
+	}
+	now := time.Now()
+	for _, saved := range file.Cookies {
+		u, err := url.Parse(saved.URL)
+		if err != nil || (!saved.Expires.IsZero() && saved.Expires.Before(now)) {
+			continue
+		}
+		j.Jar.SetCookies(u, []*http.Cookie{saved.cookie()})
+		j.saved[saved.key()] = saved
+	}
+
+	return j, nil
+}
+
+// SetCookies stores cookies for u, and saves them to the cookie file.
+func (j *persistentJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	j.Jar.SetCookies(u, cookies)
+	if j.path == "" {
+		return
+	}
+
+	now := time.Now()
+	origin := (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}).String()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, cookie := range cookies {
+		saved := savedCookie{
+			URL:      origin,
+			Name:     cookie.Name,
+			Value:    cookie.Value,
+			Domain:   cookie.Domain,
+			Path:     cookie.Path,
+			Expires:  cookie.Expires,
+			Secure:   cookie.Secure,
+			HttpOnly: cookie.HttpOnly,
+		}
+		if cookie.MaxAge > 0 {
+			saved.Expires = now.Add(time.Duration(cookie.MaxAge) * time.Second)
+		}
+
+		if cookie.MaxAge < 0 || (!saved.Expires.IsZero() && saved.Expires.Before(now)) {
+			delete(j.saved, saved.key())
+		} else {
+			j.saved[saved.key()] = saved
+		}
+	}
+
+	// Failing to persist cookies must not fail the request that set them
+	_ = j.save()
+}
+
+func (j *persistentJar) save() error {
+	file := cookieFile{Cookies: make([]savedCookie, 0, len(j.saved))}
+	for _, saved := range j.saved {
+		file.Cookies = append(file.Cookies, saved)
+	}
+	sort.Slice(file.Cookies, func(a, b int) bool {
+		return file.Cookies[a].key() < file.Cookies[b].key()
+	})
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), ".cookies-*.json")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), j.path)
+}
+
+func (c savedCookie) key() string {
+	domain := c.Domain
+	if domain == "" {
+		domain = c.URL
+	}
+	return domain + "|" + c.Path + "|" + c.Name
+}
+
+func (c savedCookie) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  c.Expires,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+	}
+}
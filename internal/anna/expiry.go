@@ -0,0 +1,38 @@
+package anna
+
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// expiryParams are the query parameters signed links commonly carry their
+// expiry in, as a Unix timestamp.
+var expiryParams = []string{"expires", "Expires", "expiry", "exp", "e"}
+
+// LinkExpiry returns when a signed download link expires, or nil when the
+// link does not say. Unix timestamps in common query parameters and the
+// X-Amz-Date/X-Amz-Expires pair of S3 presigned links are understood.
+func LinkExpiry(link string) *time.Time {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil
+	}
+	query := u.Query()
+
+	for _, param := range expiryParams {
+		if seconds, err := strconv.ParseInt(query.Get(param), 10, 64); err == nil && seconds > 0 {
+			expiry := time.Unix(seconds, 0).UTC()
+			return &expiry
+		}
+	}
+
+	if signed, err := time.Parse("20060102T150405Z", query.Get("X-Amz-Date")); err == nil {
+		if seconds, err := strconv.ParseInt(query.Get("X-Amz-Expires"), 10, 64); err == nil {
+			expiry := signed.Add(time.Duration(seconds) * time.Second)
+			return &expiry
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package anna
+
+import (
+	"net/url"
+	"strings"
+)
+
+// FilterOption is a value accepted by the lang and ext search filters of
+// Anna's Archive, with a human-readable label.
+type FilterOption struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+}
+
+// Formats lists the file formats Anna's Archive can filter on.
+var Formats = []FilterOption{
+	{"pdf", "PDF"},
+	{"epub", "EPUB"},
+	{"mobi", "Mobipocket (MOBI)"},
+	{"azw3", "Kindle (AZW3)"},
+	{"fb2", "FictionBook (FB2)"},
+	{"djvu", "DjVu"},
+	{"cbr", "Comic book archive (CBR)"},
+	{"cbz", "Comic book archive (CBZ)"},
+	{"txt", "Plain text"},
+	{"rtf", "Rich Text Format"},
+	{"doc", "Word document (DOC)"},
+	{"docx", "Word document (DOCX)"},
+	{"lit", "Microsoft Reader (LIT)"},
+	{"zip", "ZIP archive"},
+	{"rar", "RAR archive"},
+}
+
+// Languages lists the most common languages Anna's Archive can filter on, by
+// their ISO 639-1 code.
+var Languages = []FilterOption{
+	{"en", "English"},
+	{"es", "Spanish"},
+	{"fr", "French"},
+	{"de", "German"},
+	{"it", "Italian"},
+	{"pt", "Portuguese"},
+	{"nl", "Dutch"},
+	{"pl", "Polish"},
+	{"ru", "Russian"},
+	{"uk", "Ukrainian"},
+	{"tr", "Turkish"},
+	{"ar", "Arabic"},
+	{"fa", "Persian"},
+	{"he", "Hebrew"},
+	{"hi", "Hindi"},
+	{"bn", "Bengali"},
+	{"id", "Indonesian"},
+	{"vi", "Vietnamese"},
+	{"zh", "Chinese"},
+	{"ja", "Japanese"},
+	{"ko", "Korean"},
+	{"el", "Greek"},
+	{"cs", "Czech"},
+	{"hu", "Hungarian"},
+	{"ro", "Romanian"},
+	{"sv", "Swedish"},
+	{"la", "Latin"},
+}
+
+// IsFormat reports whether value is one of Formats.
+func IsFormat(value string) bool {
+	return hasOption(Formats, value)
+}
+
+// IsLanguage reports whether value is one of Languages.
+func IsLanguage(value string) bool {
+	return hasOption(Languages, value)
+}
+
+// LanguageCode returns the code Anna's Archive filters value on, given as an
+// ISO 639-1 code or as the English name of one of Languages, such as
+// "German". Codes missing from Languages are accepted when they look like
+// ISO 639 codes.
+func LanguageCode(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, option := range Languages {
+		if strings.EqualFold(option.Value, value) || strings.EqualFold(option.Label, value) {
+			return option.Value, true
+		}
+	}
+	if len(value) < 2 || len(value) > 3 {
+		return "", false
+	}
+	for _, r := range value {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return "", false
+		}
+	}
+
+	return strings.ToLower(value), true
+}
+
+// FormatCode returns the extension Anna's Archive filters value on, given as
+// one of Formats with or without its leading dot, such as ".EPUB". Other
+// extensions are accepted when they are short and alphanumeric.
+func FormatCode(value string) (string, bool) {
+	value = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(value), "."))
+	if IsFormat(value) {
+		return value, true
+	}
+	if value == "" || len(value) > 5 {
+		return "", false
+	}
+	for _, r := range value {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return "", false
+		}
+	}
+
+	return value, true
+}
+
+// Filters narrow a search the way the filters of the search page do. The
+// zero value matches every result.
+type Filters struct {
+	// Content is a content type such as ContentMagazine
+	Content string
+	// Languages are the language codes results may be in, see LanguageCode
+	Languages []string
+	// Formats are the file extensions results may have, see FormatCode
+	Formats []string
+}
+
+// query returns the search URL parameters of f, each preceded by "&".
+func (f Filters) query() string {
+	var b strings.Builder
+	if f.Content != "" {
+		b.WriteString("&content=" + url.QueryEscape(f.Content))
+	}
+	for _, language := range f.Languages {
+		b.WriteString("&lang=" + url.QueryEscape(language))
+	}
+	for _, format := range f.Formats {
+		b.WriteString("&ext=" + url.QueryEscape(format))
+	}
+
+	return b.String()
+}
+
+func hasOption(options []FilterOption, value string) bool {
+	for _, option := range options {
+		if strings.EqualFold(option.Value, strings.TrimSpace(value)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,174 @@
+package anna
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// Fixture modes of Options.FixtureMode.
+const (
+	// FixtureAuto replays recorded responses and records the missing ones.
+	FixtureAuto = "auto"
+	// FixtureRecord always asks upstream and overwrites the recordings.
+	FixtureRecord = "record"
+	// FixtureReplay never reaches upstream and fails on missing recordings.
+	FixtureReplay = "replay"
+)
+
+// ErrFixtureMissing is returned in replay mode for requests that were never
+// recorded.
+var ErrFixtureMissing = errors.New("no recorded response")
+
+// redactedParams are dropped from recorded URLs and fixture keys, so
+// fixtures can be shared without leaking secret keys.
+var redactedParams = []string{"key", "secretKey"}
+
+// fixture is the metadata of a recorded response. The body is stored next
+// to it, unencoded, to keep recorded pages readable.
+type fixture struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header"`
+}
+
+// fixtureTransport records upstream responses in a directory and replays
+// them, for deterministic tests and demos without reaching Anna's Archive.
+type fixtureTransport struct {
+	dir  string
+	mode string
+	next http.RoundTripper
+}
+
+func newFixtureTransport(dir, mode string, next http.RoundTripper) (*fixtureTransport, error) {
+	switch mode {
+	case "":
+		mode = FixtureAuto
+	case FixtureAuto, FixtureRecord, FixtureReplay:
+	default:
+		return nil, fmt.Errorf("invalid fixture mode %q", mode)
+	}
+
+	return &fixtureTransport{dir: dir, mode: mode, next: next}, nil
+}
+
+func (t *fixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := fixtureKey(req)
+
+	if t.mode != FixtureRecord {
+		resp, err := t.replay(key, req)
+		if err == nil {
+			return resp, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if t.mode == FixtureReplay {
+			return nil, fmt.Errorf("%w for %s %s in %s", ErrFixtureMissing, req.Method, redactURL(req.URL), t.dir)
+		}
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.record(key, req, resp)
+}
+
+// CloseIdleConnections lets http.Client.CloseIdleConnections reach the
+// wrapped transport.
+func (t *fixtureTransport) CloseIdleConnections() {
+	if closer, ok := t.next.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+func (t *fixtureTransport) replay(key string, req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(filepath.Join(t.dir, key+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var recorded fixture
+	if err := json.Unmarshal(data, &recorded); err != nil {
+		return nil, fmt.Errorf("failed to decode fixture %s: %w", key, err)
+	}
+	body, err := os.ReadFile(filepath.Join(t.dir, key+".body"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// record saves resp and returns an equivalent response whose body reads
+// from the saved copy.
+func (t *fixtureTransport) record(key string, req *http.Request, resp *http.Response) (*http.Response, error) {
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(fixture{
+		Method: req.Method,
+		URL:    redactURL(req.URL),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(t.dir, key+".body"), body, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to record fixture: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(t.dir, key+".json"), data, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to record fixture: %w", err)
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+// fixtureKey names the recording of a request after its method, redacted
+// URL, and range, the only inputs that change upstream responses.
+func fixtureKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.Method + " " + redactURL(req.URL) + " " + req.Header.Get("Range")))
+
+	return req.URL.Hostname() + "-" + hex.EncodeToString(sum[:8])
+}
+
+func redactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	for _, param := range redactedParams {
+		query.Del(param)
+	}
+	redacted.RawQuery = query.Encode()
+
+	return redacted.String()
+}
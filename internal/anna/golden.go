@@ -0,0 +1,137 @@
+package anna
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// Kinds of golden fixtures, named after the parser they exercise.
+const (
+	GoldenSearch = "search"
+	GoldenRecord = "record"
+	GoldenSciDB  = "scidb"
+)
+
+// GoldenManifest is the file listing the golden fixtures of a directory.
+const GoldenManifest = "manifest.json"
+
+// maxFixtureBytes bounds a refreshed fixture page.
+const maxFixtureBytes = 8 << 20
+
+// GoldenFixture is a stored upstream page, parsed by the golden tests and
+// compared with the expected output stored next to it.
+type GoldenFixture struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Query, Hash, or DOI identify the page of search, record, and scidb
+	// fixtures.
+	Query string `json:"query,omitempty"`
+	Hash  string `json:"hash,omitempty"`
+	DOI   string `json:"doi,omitempty"`
+}
+
+// LoadGoldenFixtures reads the manifest of the golden fixtures in dir.
+func LoadGoldenFixtures(dir string) ([]GoldenFixture, error) {
+	data, err := os.ReadFile(filepath.Join(dir, GoldenManifest))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture manifest: %w", err)
+	}
+
+	var manifest struct {
+		Fixtures []GoldenFixture `json:"fixtures"`
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse fixture manifest: %w", err)
+	}
+	for _, fixture := range manifest.Fixtures {
+		if _, err := fixture.URL("https://" + DefaultMirror); err != nil || fixture.Name == "" {
+			return nil, fmt.Errorf("invalid fixture %q of kind %q", fixture.Name, fixture.Kind)
+		}
+	}
+
+	return manifest.Fixtures, nil
+}
+
+// URL returns the address of the fixture's page on the mirror at base.
+func (f GoldenFixture) URL(base string) (string, error) {
+	switch f.Kind {
+	case GoldenSearch:
+		return fmt.Sprintf(AnnasSearchEndpoint, base, url.QueryEscape(f.Query)), nil
+	case GoldenRecord:
+		return fmt.Sprintf(AnnasRecordEndpoint, base, f.Hash), nil
+	case GoldenSciDB:
+		return fmt.Sprintf(AnnasSciDBEndpoint, base, f.DOI), nil
+	}
+
+	return "", fmt.Errorf("unknown fixture kind %q", f.Kind)
+}
+
+// Page returns the path of the stored page of the fixture in dir.
+func (f GoldenFixture) Page(dir string) string {
+	return filepath.Join(dir, f.Kind, f.Name+".html")
+}
+
+// Golden returns the path of the expected output of the fixture in dir.
+func (f GoldenFixture) Golden(dir string) string {
+	return filepath.Join(dir, f.Kind, f.Name+".golden.json")
+}
+
+// RefreshGoldenFixture downloads the page of the fixture from the configured
+// mirrors, sanitizes it, and stores it in dir.
+func RefreshGoldenFixture(dir string, fixture GoldenFixture) error {
+	var page []byte
+	err := eachMirror(func(base string) error {
+		pageURL, err := fixture.URL(base)
+		if err != nil {
+			return &stopMirrors{err: err}
+		}
+
+		resp, err := client().Get(pageURL)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("unexpected status %s", resp.Status)
+		}
+
+		page, err = io.ReadAll(io.LimitReader(resp.Body, maxFixtureBytes))
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to fetch fixture %s/%s: %w", fixture.Kind, fixture.Name, err)
+	}
+
+	path := fixture.Page(dir)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, SanitizeFixture(page), 0o644)
+}
+
+var (
+	fixtureScripts  = regexp.MustCompile(`(?is)<script\b[^>]*>.*?</script>`)
+	fixtureStyles   = regexp.MustCompile(`(?is)<style\b[^>]*>.*?</style>`)
+	fixtureComments = regexp.MustCompile(`(?s)<!--.*?-->`)
+	fixtureSecrets  = regexp.MustCompile(`(?i)\b(key|secretKey|token|session)=[^&"'\s<>]+`)
+	fixtureEmails   = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+)
+
+// SanitizeFixture strips the scripts, styles, and comments of a page, which
+// the parsers ignore but change with every deployment, and redacts secret
+// keys and email addresses, so fixtures can be committed.
+func SanitizeFixture(page []byte) []byte {
+	page = fixtureScripts.ReplaceAll(page, nil)
+	page = fixtureStyles.ReplaceAll(page, nil)
+	page = fixtureComments.ReplaceAll(page, nil)
+	page = fixtureSecrets.ReplaceAll(page, []byte("${1}=REDACTED"))
+	page = fixtureEmails.ReplaceAll(page, []byte("redacted@example.com"))
+
+	return page
+}
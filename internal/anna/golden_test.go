@@ -0,0 +1,125 @@
+package anna
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden outputs of TestGolden")
+
+// goldenDir holds the fixtures of TestGolden, refreshed with the hidden
+// dev refresh-fixtures command.
+var goldenDir = filepath.Join("testdata", "golden")
+
+// goldenMirror replaces the test server in golden outputs, so they do not
+// depend on its port.
+const goldenMirror = "https://annas-archive.org"
+
+// parseGolden runs the parser of the fixture's kind against the mirror
+// serving its page.
+func parseGolden(fixture GoldenFixture) (any, error) {
+	switch fixture.Kind {
+	case GoldenSearch:
+		books := make([]*Book, 0)
+		class, err := StreamBooksClassified(fixture.Query, 0, func(book *Book) bool {
+			books = append(books, book)
+			return true
+		})
+		return map[string]any{"books": books, "classification": class}, err
+	case GoldenRecord:
+		return GetMetadata(fixture.Hash)
+	default:
+		return GetPaper(fixture.DOI)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	fixtures, err := LoadGoldenFixtures(goldenDir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer Configure(Options{})
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.Kind+"/"+fixture.Name, func(t *testing.T) {
+			page, err := os.ReadFile(fixture.Page(goldenDir))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html; charset=utf-8")
+				w.Write(page)
+			}))
+			defer server.Close()
+			if err := Configure(Options{Mirrors: []string{server.URL}}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			parsed, err := parseGolden(fixture)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got, err := json.MarshalIndent(parsed, "", "  ")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			got = append(bytes.ReplaceAll(got, []byte(server.URL), []byte(goldenMirror)), '\n')
+
+			if *updateGolden {
+				if err := os.WriteFile(fixture.Golden(goldenDir), got, 0o644); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				return
+			}
+			want, err := os.ReadFile(fixture.Golden(goldenDir))
+			if err != nil {
+				t.Fatalf("Missing golden output, run go test -run TestGolden -update: %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Parsed output differs from %s, review and run go test -run TestGolden -update:\n%s", fixture.Golden(goldenDir), got)
+			}
+		})
+	}
+}
+
+func TestSanitizeFixture(t *testing.T) {
+	page := []byte(`<html><script>var account = "x";</script><!-- build 123 --><a href="/dyn/api/fast_download.json?md5=abc&key=s3cret">Fast</a><p>reader@example.org</p></html>`)
+	want := `<html><a href="/dyn/api/fast_download.json?md5=abc&key=REDACTED">Fast</a><p>redacted@example.com</p></html>`
+	if got := string(SanitizeFixture(page)); got != want {
+		t.Errorf("Expected '%s', got '%s'", want, got)
+	}
+}
+
+func TestRefreshGoldenFixture(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/md5/6a8ef5e8c4cbd0d1b7d5f2d2f8a6b0e1" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<html><script>track()</script><main>Dune</main></html>`))
+	}))
+	defer server.Close()
+	if err := Configure(Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer Configure(Options{})
+
+	dir := t.TempDir()
+	fixture := GoldenFixture{Kind: GoldenRecord, Name: "dune", Hash: "6a8ef5e8c4cbd0d1b7d5f2d2f8a6b0e1"}
+	if err := RefreshGoldenFixture(dir, fixture); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	page, err := os.ReadFile(fixture.Page(dir))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(page) != "<html><main>Dune</main></html>" {
+		t.Errorf("Expected the sanitized page, got '%s'", page)
+	}
+}
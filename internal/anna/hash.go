@@ -0,0 +1,27 @@
+package anna
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidHash is returned for identifiers that are not MD5 hashes.
+var ErrInvalidHash = errors.New("invalid MD5 hash")
+
+// NormalizeHash validates that hash is an MD5 hash of 32 hexadecimal
+// characters and returns it lowercased, so malformed input is rejected
+// before any upstream request is made.
+func NormalizeHash(hash string) (string, error) {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	if len(hash) != 32 {
+		return "", fmt.Errorf("%w %q: expected 32 hexadecimal characters, got %d", ErrInvalidHash, hash, len(hash))
+	}
+	for _, r := range hash {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return "", fmt.Errorf("%w %q: unexpected character %q", ErrInvalidHash, hash, r)
+		}
+	}
+
+	return hash, nil
+}
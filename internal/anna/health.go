@@ -0,0 +1,210 @@
+package anna
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// probeWindow is the number of recent probes kept per target.
+const probeWindow = 10
+
+// probeTimeout bounds a single probe request.
+const probeTimeout = 10 * time.Second
+
+// Target kinds reported by HealthStatuses.
+const (
+	TargetMirror  = "mirror"
+	TargetPartner = "partner"
+)
+
+// Health summarizes the recent probes of a mirror or partner server.
+type Health struct {
+	Target      string     `json:"target"`
+	Kind        string     `json:"kind"`
+	Available   bool       `json:"available"`
+	LatencyMS   int64      `json:"latency_ms,omitempty"`
+	SuccessRate float64    `json:"success_rate"`
+	Probes      int        `json:"probes"`
+	LastChecked *time.Time `json:"last_checked,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+type probeResult struct {
+	at      time.Time
+	latency time.Duration
+	err     error
+}
+
+var (
+	healthMu sync.Mutex
+	probes   = make(map[string][]probeResult)
+	partners []string
+)
+
+// SetPartners replaces the partner download servers probed next to the
+// mirrors. They are only monitored, never used for mirror selection.
+func SetPartners(urls []string) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	partners = append([]string(nil), urls...)
+}
+
+// Probe checks every mirror and partner server once, concurrently.
+func Probe(ctx context.Context) {
+	healthMu.Lock()
+	targets := append(Mirrors(), partners...)
+	healthMu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			result := probe(ctx, target)
+
+			healthMu.Lock()
+			defer healthMu.Unlock()
+			window := append(probes[target], result)
+			if len(window) > probeWindow {
+				window = window[len(window)-probeWindow:]
+			}
+			probes[target] = window
+		}(target)
+	}
+	wg.Wait()
+}
+
+// StartProber probes all targets every interval until ctx is done.
+func StartProber(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		Probe(ctx)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				Probe(ctx)
+			}
+		}
+	}()
+}
+
+// probe treats any answer below 500 as available, since mirrors may reject
+// a bare request to their root without being down.
+func probe(ctx context.Context, target string) probeResult {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return probeResult{at: start, err: err}
+	}
+
+	resp, err := client().Do(req)
+	if err != nil {
+		return probeResult{at: start, err: err}
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return probeResult{at: start, latency: time.Since(start), err: fmt.Errorf("unexpected status %s", resp.Status)}
+	}
+	return probeResult{at: start, latency: time.Since(start)}
+}
+
+// HealthStatuses reports the rolling probe statistics of every mirror and
+// partner server.
+func HealthStatuses() []Health {
+	bases := Mirrors()
+
+	healthMu.Lock()
+	defer healthMu.Unlock()
+
+	statuses := make([]Health, 0, len(bases)+len(partners))
+	for _, base := range bases {
+		statuses = append(statuses, summarize(base, TargetMirror))
+	}
+	for _, partner := range partners {
+		statuses = append(statuses, summarize(partner, TargetPartner))
+	}
+
+	return statuses
+}
+
+func summarize(target, kind string) Health {
+	health := Health{Target: target, Kind: kind}
+	window := probes[target]
+	if len(window) == 0 {
+		return health
+	}
+
+	var total time.Duration
+	successes := 0
+	for _, result := range window {
+		if result.err == nil {
+			successes++
+			total += result.latency
+		}
+	}
+
+	last := window[len(window)-1]
+	health.Available = last.err == nil
+	health.Probes = len(window)
+	health.SuccessRate = float64(successes) / float64(len(window))
+	health.LastChecked = &last.at
+	if successes > 0 {
+		health.LatencyMS = (total / time.Duration(successes)).Milliseconds()
+	}
+	if last.err != nil {
+		health.LastError = last.err.Error()
+	}
+
+	return health
+}
+
+// rankMirrors orders mirrors by their probes: available ones by latency,
+// then those not probed yet, then unavailable ones. Ties keep the configured
+// order.
+func rankMirrors(bases []string) []string {
+	healthMu.Lock()
+	summaries := make(map[string]Health, len(bases))
+	for _, base := range bases {
+		summaries[base] = summarize(base, TargetMirror)
+	}
+	healthMu.Unlock()
+	local := Region()
+
+	group := func(h Health) int {
+		switch {
+		case h.Probes == 0:
+			return 1
+		case h.Available:
+			return 0
+		default:
+			return 2
+		}
+	}
+
+	ranked := append([]string(nil), bases...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := summaries[ranked[i]], summaries[ranked[j]]
+		if group(a) != group(b) {
+			return group(a) < group(b)
+		}
+		if ra, rb := regionalBase(ranked[i], local), regionalBase(ranked[j], local); ra != rb {
+			return ra
+		}
+		return group(a) == 0 && a.LatencyMS < b.LatencyMS
+	})
+
+	return ranked
+}
@@ -0,0 +1,109 @@
+package anna
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// countryDomains maps the regions whose country code top-level domain
+// differs from their ISO 3166 code.
+var countryDomains = map[string]string{"GB": "uk"}
+
+// parseLocale splits a locale such as "de-DE", "pt_BR.UTF-8", or "fr" into its
+// lower-case language and upper-case region, which may be empty.
+func parseLocale(locale string) (language, region string, err error) {
+	locale, _, _ = strings.Cut(strings.TrimSpace(locale), ".")
+	locale, _, _ = strings.Cut(locale, "@")
+	language, region, _ = strings.Cut(strings.ReplaceAll(locale, "_", "-"), "-")
+
+	valid := func(s string, shortest, longest int) bool {
+		if len(s) < shortest || len(s) > longest {
+			return false
+		}
+		for _, r := range s {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+				return false
+			}
+		}
+		return true
+	}
+	if !valid(language, 2, 3) || (region != "" && !valid(region, 2, 2)) {
+		return "", "", fmt.Errorf("invalid locale %q: expected the form de-DE or de", locale)
+	}
+
+	return strings.ToLower(language), strings.ToUpper(region), nil
+}
+
+// acceptLanguage returns the Accept-Language header of a locale, falling back
+// to English, such as "de-DE,de;q=0.9,en;q=0.8" for de-DE.
+func acceptLanguage(language, region string) string {
+	var values []string
+	if region != "" {
+		values = append(values, language+"-"+region, language+";q=0.9")
+	} else {
+		values = append(values, language)
+	}
+	if language != "en" {
+		values = append(values, "en;q=0.8")
+	}
+
+	return strings.Join(values, ",")
+}
+
+// RegionalHost reports whether host belongs to region, by its country code
+// top-level domain or a leading label such as de.example.org.
+func RegionalHost(host, region string) bool {
+	if region == "" {
+		return false
+	}
+	code := strings.ToLower(region)
+	if domain, ok := countryDomains[strings.ToUpper(region)]; ok {
+		code = domain
+	}
+
+	labels := strings.Split(strings.ToLower(strings.TrimSuffix(host, ".")), ".")
+	if len(labels) < 2 {
+		return false
+	}
+	return labels[len(labels)-1] == code || labels[0] == code || labels[0] == strings.ToLower(region)
+}
+
+// Region returns the region of the configured locale, or "" without one.
+func Region() string {
+	optionsMu.RLock()
+	defer optionsMu.RUnlock()
+
+	return region
+}
+
+// regionalBase reports whether the mirror at base belongs to the region of
+// the configured locale.
+func regionalBase(base, region string) bool {
+	u, err := url.Parse(base)
+	return err == nil && RegionalHost(u.Hostname(), region)
+}
+
+// languageTransport sets the Accept-Language header of upstream requests
+// that do not carry one.
+type languageTransport struct {
+	base  http.RoundTripper
+	value string
+}
+
+func (t *languageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Language") == "" {
+		req = req.Clone(req.Context())
+		req.Header.Set("Accept-Language", t.value)
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport.
+func (t *languageTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,118 @@
+package anna
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	colly "github.com/gocolly/colly/v2"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+const AnnasRecordEndpoint = "%s/md5/%s"
+
+var isbnPattern = regexp.MustCompile(`ISBN-1[03]\s*:?\s*([0-9][0-9Xx-]{8,16})`)
+
+// GetMetadata fetches and parses the record page of the book identified by hash.
+func GetMetadata(hash string) (*Metadata, error) {
+	l := logger.GetLogger()
+
+	var metadata *Metadata
+	err := eachMirror(func(base string) error {
+		c := newCollector()
+
+		metadata = &Metadata{Book: Book{Hash: hash}}
+		found := false
+
+		c.OnHTML("main", func(e *colly.HTMLElement) {
+			found = true
+
+			metadata.Title = strings.TrimSpace(e.DOM.Find("div.text-3xl").First().Text())
+			metadata.Authors = ParseAuthors(e.DOM.Find("a[href^='/search'] span.icon-\\[mdi--user-edit\\]").First().Parent().Text())
+			metadata.Publisher = strings.TrimSpace(e.DOM.Find("a[href^='/search'] span.icon-\\[mdi--company\\]").First().Parent().Text())
+
+			meta := e.DOM.Find("div.text-gray-800").First().Text()
+			metadata.Language, metadata.Format, metadata.Size = extractMetaInformation(meta)
+			metadata.setAvailability(meta)
+
+			metadata.Description = extractDescription(e.DOM.Find("div.js-md5-top-box-description").First().Text())
+			metadata.ISBNs = extractISBNs(e.DOM.Text())
+			metadata.AlternativeTitles = extractAlternativeTitles(e.DOM, metadata.Title)
+		})
+
+		c.OnRequest(func(r *colly.Request) {
+			l.Info("Visiting URL", zap.String("url", r.URL.String()))
+		})
+
+		recordURL := fmt.Sprintf(AnnasRecordEndpoint, base, hash)
+		if err := c.Visit(recordURL); err != nil {
+			return err
+		}
+		if !found {
+			return errors.New("record page could not be parsed")
+		}
+
+		metadata.URL = recordURL
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return metadata, nil
+}
+
+// extractDescription cleans up the description block of a record page,
+// keeping its paragraphs but collapsing the whitespace within them.
+func extractDescription(text string) string {
+	paragraphs := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			paragraphs = append(paragraphs, line)
+		}
+	}
+
+	return strings.Join(paragraphs, "\n")
+}
+
+// extractAlternativeTitles returns the values labelled "Alternative title"
+// on a record page, without duplicates of title.
+func extractAlternativeTitles(page *goquery.Selection, title string) []string {
+	seen := map[string]bool{strings.ToLower(title): true}
+	titles := make([]string, 0)
+	page.Find("div").Each(func(_ int, label *goquery.Selection) {
+		text := strings.ToLower(strings.TrimSpace(label.Text()))
+		if text != "alternative title" && text != "alternative titles" {
+			return
+		}
+
+		alternative := strings.Join(strings.Fields(label.Next().Text()), " ")
+		if alternative == "" || seen[strings.ToLower(alternative)] {
+			return
+		}
+		seen[strings.ToLower(alternative)] = true
+		titles = append(titles, alternative)
+	})
+
+	return titles
+}
+
+// extractISBNs returns the unique, hyphen-free ISBNs mentioned in text.
+func extractISBNs(text string) []string {
+	seen := make(map[string]bool)
+	isbns := make([]string, 0)
+
+	for _, match := range isbnPattern.FindAllStringSubmatch(text, -1) {
+		isbn := strings.ToUpper(strings.ReplaceAll(match[1], "-", ""))
+		if (len(isbn) != 10 && len(isbn) != 13) || seen[isbn] {
+			continue
+		}
+		seen[isbn] = true
+		isbns = append(isbns, isbn)
+	}
+
+	return isbns
+}
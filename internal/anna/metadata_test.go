@@ -0,0 +1,59 @@
+package anna
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestExtractISBNs(t *testing.T) {
+	text := `Codes: ISBN-13 978-0-14-032872-1 ISBN-10 014032872X ISBN-13: 9780140328721 ISBN-13 12345`
+
+	isbns := extractISBNs(text)
+	expected := []string{"9780140328721", "014032872X"}
+	if !reflect.DeepEqual(isbns, expected) {
+		t.Errorf("Expected ISBNs %v, got %v", expected, isbns)
+	}
+}
+
+func TestExtractDescription(t *testing.T) {
+	text := "\n   Set on the desert planet   Arrakis,\n  Dune is the story of Paul.  \n\n   A classic.\n"
+
+	expected := "Set on the desert planet Arrakis,\nDune is the story of Paul.\nA classic."
+	if got := extractDescription(text); got != expected {
+		t.Errorf("Expected description '%s', got '%s'", expected, got)
+	}
+}
+
+func TestExtractAlternativeTitles(t *testing.T) {
+	page := `<main><div class="text-3xl">Crime and Punishment</div>
+<div><div>Alternative title</div><div>Преступление и наказание</div></div>
+<div><div>Alternative title</div><div>crime and punishment</div></div></main>`
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	titles := extractAlternativeTitles(doc.Selection, "Crime and Punishment")
+	expected := []string{"Преступление и наказание"}
+	if !reflect.DeepEqual(titles, expected) {
+		t.Errorf("Expected alternative titles %v, got %v", expected, titles)
+	}
+}
+
+func TestTransliterate(t *testing.T) {
+	cases := map[string]string{
+		"Фёдор Достоевский":   "Fedor Dostoevskiy",
+		"Gödel, Escher, Bach": "Godel, Escher, Bach",
+		"Ἰλιάς":               "Ilias",
+		"Straße":              "Strasse",
+		"三体":                  "三体",
+	}
+	for text, expected := range cases {
+		if got := Transliterate(text); got != expected {
+			t.Errorf("Expected '%s' to be transliterated to '%s', got '%s'", text, expected, got)
+		}
+	}
+}
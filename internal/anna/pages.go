@@ -0,0 +1,100 @@
+package anna
+
+import (
+	colly "github.com/gocolly/colly/v2"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Deep searches fetch the result pages after the first one concurrently, at
+// most searchPageConcurrency at a time and up to maxSearchPages in total.
+const (
+	maxSearchPages        = 10
+	searchPageConcurrency = 3
+)
+
+type pageResult struct {
+	rows []*colly.HTMLElement
+	err  error
+}
+
+// streamSearch passes the result rows of query to onRow like searchPage does,
+// until limit distinct rows were delivered. The first page tells how many
+// results a page holds, so the remaining pages are then fetched at once and
+// delivered in order, skipping rows already seen on an earlier page. Failing
+// pages after the first one end the results instead of failing the search.
+// The class of a first page without results is returned, see searchPage.
+func streamSearch(query string, filters Filters, limit int, onRow func(*colly.HTMLElement) bool) (string, error) {
+	seen := make(map[string]bool)
+	delivered := 0
+	stopped := false
+	deliver := func(e *colly.HTMLElement) bool {
+		href := e.Attr("href")
+		if seen[href] {
+			return true
+		}
+		seen[href] = true
+
+		delivered++
+		stopped = !onRow(e) || (limit > 0 && delivered >= limit)
+		return !stopped
+	}
+
+	class, err := searchPage(query, filters, 1, deliver)
+	if err != nil {
+		return "", err
+	}
+	pageSize := delivered
+	if stopped || limit <= delivered || pageSize == 0 {
+		return class, nil
+	}
+	pages := min(1+(limit-delivered+pageSize-1)/pageSize, maxSearchPages)
+
+	// Pages still queued are skipped once the results are complete
+	done := make(chan struct{})
+	defer close(done)
+	slots := make(chan struct{}, searchPageConcurrency)
+	results := make([]chan pageResult, pages+1)
+	for page := 2; page <= pages; page++ {
+		results[page] = make(chan pageResult, 1)
+		go func(page int) {
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return
+			}
+			defer func() { <-slots }()
+
+			var rows []*colly.HTMLElement
+			_, err := searchPage(query, filters, page, func(e *colly.HTMLElement) bool {
+				rows = append(rows, e)
+				return true
+			})
+			results[page] <- pageResult{rows: rows, err: err}
+		}(page)
+	}
+
+	for page := 2; page <= pages; page++ {
+		result := <-results[page]
+		if result.err != nil {
+			logger.GetLogger().Warn("Failed to fetch search results page",
+				zap.String("query", query),
+				zap.Int("page", page),
+				zap.Error(result.err),
+			)
+			return "", nil
+		}
+		// Past the last page of results
+		if len(result.rows) == 0 {
+			return "", nil
+		}
+
+		for _, row := range result.rows {
+			if !deliver(row) {
+				return "", nil
+			}
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,102 @@
+package anna
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Content types of Anna's Archive search results that are published in
+// issues.
+const (
+	ContentMagazine = "magazine"
+	ContentComic    = "book_comic"
+)
+
+var (
+	volumePattern = regexp.MustCompile(`(?i)\b(?:vol(?:ume)?\.?|v\.)\s*(\d+)`)
+	issuePattern  = regexp.MustCompile(`(?i)(?:\b(?:issue|no\.?|nr\.?|number)\s*|#\s*)(\d+)`)
+	yearPattern   = regexp.MustCompile(`\b(?:1[89]|20)\d{2}\b`)
+)
+
+// Periodical is a magazine or comic search result with the issue details
+// that the book parser ignores.
+type Periodical struct {
+	Book
+	Volume string `json:"volume,omitempty"`
+	Issue  string `json:"issue,omitempty"`
+	Year   string `json:"year,omitempty"`
+}
+
+func (p *Periodical) String() string {
+	var details []string
+	if p.Volume != "" {
+		details = append(details, "Volume: "+p.Volume)
+	}
+	if p.Issue != "" {
+		details = append(details, "Issue: "+p.Issue)
+	}
+	if p.Year != "" {
+		details = append(details, "Year: "+p.Year)
+	}
+	if len(details) == 0 {
+		return p.Book.String()
+	}
+
+	return fmt.Sprintf("%s\n%s", p.Book.String(), strings.Join(details, "\n"))
+}
+
+// StreamPeriodicals searches Anna's Archive for results of the given content
+// type, such as ContentMagazine or ContentComic, and passes them to yield like
+// StreamBooks does.
+func StreamPeriodicals(query, content string, yield func(*Periodical) bool) error {
+	return StreamPeriodicalsFiltered(query, Filters{Content: content}, yield)
+}
+
+// StreamPeriodicalsFiltered is like StreamPeriodicals, with the content type
+// and the other filters of the search given by filters.
+func StreamPeriodicalsFiltered(query string, filters Filters, yield func(*Periodical) bool) error {
+	_, err := streamSearch(query, filters, 0, func(e *colly.HTMLElement) bool {
+		return yield(parsePeriodical(e))
+	})
+	return err
+}
+
+// parsePeriodical extracts a search result and its issue details. Volume and
+// issue numbers are only found in titles, while the year is preferably read
+// from the meta line.
+func parsePeriodical(e *colly.HTMLElement) *Periodical {
+	book := parseBook(e)
+	meta := e.DOM.Parent().Find("div.max-w-full div.text-gray-800").Text()
+
+	return &Periodical{
+		Book:   *book,
+		Volume: firstGroup(volumePattern, book.Title),
+		Issue:  firstGroup(issuePattern, book.Title),
+		Year:   extractYear(meta, book.Title),
+	}
+}
+
+// extractYear returns the publication year from the meta line, falling back
+// to a year mentioned in the title.
+func extractYear(meta, title string) string {
+	for _, part := range strings.Split(meta, " · ") {
+		part = strings.TrimSpace(part)
+		if len(part) == 4 && yearPattern.MatchString(part) {
+			return part
+		}
+	}
+
+	return yearPattern.FindString(title)
+}
+
+func firstGroup(pattern *regexp.Regexp, s string) string {
+	match := pattern.FindStringSubmatch(s)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
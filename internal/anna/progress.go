@@ -0,0 +1,90 @@
+package anna
+
+import (
+	"io"
+	"time"
+)
+
+// progressInterval bounds how often progress is reported while a file is
+// being fetched.
+const progressInterval = 250 * time.Millisecond
+
+// Progress describes how far a download got.
+type Progress struct {
+	Bytes int64 `json:"bytes"`
+	// Total is the size announced by the server, or 0 when unknown.
+	Total   int64         `json:"total,omitempty"`
+	Elapsed time.Duration `json:"-"`
+	Done    bool          `json:"-"`
+}
+
+// Percent returns the completed share of the download, or -1 when the total
+// size is unknown.
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return -1
+	}
+
+	return float64(p.Bytes) * 100 / float64(p.Total)
+}
+
+// BytesPerSecond returns the average throughput so far.
+func (p Progress) BytesPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(p.Bytes) / p.Elapsed.Seconds()
+}
+
+// ETA returns the estimated time left, or -1 when it cannot be estimated.
+func (p Progress) ETA() time.Duration {
+	if p.Total <= 0 {
+		return -1
+	}
+	if p.Bytes >= p.Total {
+		return 0
+	}
+	speed := p.BytesPerSecond()
+	if speed <= 0 {
+		return -1
+	}
+
+	return time.Duration(float64(p.Total-p.Bytes) / speed * float64(time.Second))
+}
+
+// progressReader reports the bytes read through it at most every
+// progressInterval.
+type progressReader struct {
+	r        io.Reader
+	progress Progress
+	start    time.Time
+	last     time.Time
+	report   func(Progress)
+}
+
+func newProgressReader(r io.Reader, total int64, report func(Progress)) *progressReader {
+	now := time.Now()
+
+	return &progressReader{r: r, progress: Progress{Total: max(total, 0)}, start: now, last: now, report: report}
+}
+
+func (p *progressReader) Read(buf []byte) (int, error) {
+	n, err := p.r.Read(buf)
+	p.progress.Bytes += int64(n)
+
+	if now := time.Now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.progress.Elapsed = now.Sub(p.start)
+		p.report(p.progress)
+	}
+
+	return n, err
+}
+
+// finish reports the final progress once the body was read completely.
+func (p *progressReader) finish() {
+	p.progress.Elapsed = time.Since(p.start)
+	p.progress.Done = true
+	p.report(p.progress)
+}
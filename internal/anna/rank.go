@@ -0,0 +1,204 @@
+package anna
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// minTokenSimilarity is the similarity below which a query word is considered
+// missing from a result rather than misspelled.
+const minTokenSimilarity = 0.7
+
+// Preferences are the formats and languages preferred among matching
+// results, most preferred first.
+type Preferences struct {
+	Formats   []string
+	Languages []string
+}
+
+// IsZero reports whether no format or language is preferred.
+func (p Preferences) IsZero() bool {
+	return len(p.Formats) == 0 && len(p.Languages) == 0
+}
+
+// preferenceWeight is the bonus of the most preferred format or language.
+// It decides between equally good matches without outranking a better one.
+const preferenceWeight = 0.05
+
+// score returns the bonus of book, from preferenceWeight for the first
+// preferred format and language each down to 0 for those not preferred.
+func (p Preferences) score(book *Book) float64 {
+	var bonus float64
+	for i, format := range p.Formats {
+		if strings.EqualFold(strings.TrimSpace(format), book.Format) {
+			bonus += preferenceWeight * float64(len(p.Formats)-i) / float64(len(p.Formats))
+			break
+		}
+	}
+	for i, language := range p.Languages {
+		if MatchesLanguage(book.Language, language) {
+			bonus += preferenceWeight * float64(len(p.Languages)-i) / float64(len(p.Languages))
+			break
+		}
+	}
+
+	return bonus
+}
+
+// MatchesLanguage reports whether the language name of a result, such as
+// "English", is the language given by name or ISO 639-1 code.
+func MatchesLanguage(name, language string) bool {
+	language = strings.TrimSpace(language)
+	if name == "" {
+		return false
+	}
+	if strings.EqualFold(name, language) {
+		return true
+	}
+	for _, option := range Languages {
+		if strings.EqualFold(option.Value, language) {
+			return strings.EqualFold(option.Label, name)
+		}
+	}
+
+	return false
+}
+
+// RankBooks reorders books by how closely their title and authors match
+// query, best match first. Words are compared after lowercasing and
+// transliteration, and tolerate typos and truncation, so "dostoevsky crime
+// punishment" ranks "Crime and Punishment" by Fyodor Dostoevskiy first.
+// Between similar matches, the formats and languages of prefs win. Books
+// scoring the same keep their upstream order.
+func RankBooks(query string, books []*Book, prefs Preferences) {
+	words := rankTokens(query)
+	if len(words) == 0 && prefs.IsZero() {
+		return
+	}
+
+	scores := make(map[*Book]float64, len(books))
+	for _, book := range books {
+		scores[book] = prefs.score(book)
+		if len(words) > 0 {
+			scores[book] += matchScore(words, book)
+		}
+	}
+	sort.SliceStable(books, func(i, j int) bool {
+		return scores[books[i]] > scores[books[j]]
+	})
+}
+
+// MatchScore scores how closely the title and authors of book match query,
+// from 0 when they share no word to 1 when the title and authors are exactly
+// the query.
+func MatchScore(query string, book *Book) float64 {
+	words := rankTokens(query)
+	if len(words) == 0 {
+		return 0
+	}
+
+	return matchScore(words, book)
+}
+
+// matchScore scores book against the query words between 0 and 1. Most of
+// the score is how many query words the title or authors contain, the rest
+// how much of the title the query covers, so an exact title outranks a longer
+// one containing it, such as "Dune" and "Dune Messiah" for "dune".
+func matchScore(words []string, book *Book) float64 {
+	title := rankTokens(book.Title)
+	candidates := append(rankTokens(book.AuthorLine()), title...)
+
+	var found float64
+	for _, word := range words {
+		found += bestSimilarity(word, candidates)
+	}
+
+	var covered float64
+	for _, token := range title {
+		covered += bestSimilarity(token, words)
+	}
+	if len(title) > 0 {
+		covered /= float64(len(title))
+	}
+
+	return 0.8*found/float64(len(words)) + 0.2*covered
+}
+
+// bestSimilarity returns the similarity of word to its closest candidate, or
+// 0 when none is close enough.
+func bestSimilarity(word string, candidates []string) float64 {
+	best := 0.0
+	for _, candidate := range candidates {
+		best = max(best, similarity(word, candidate))
+	}
+	if best < minTokenSimilarity {
+		return 0
+	}
+
+	return best
+}
+
+// similarity compares two words between 0 and 1 by their edit distance. A
+// word of at least three letters starting the other, as typed by someone
+// abbreviating, counts as a near match.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+
+	ra, rb := []rune(a), []rune(b)
+	score := 1 - float64(levenshtein(ra, rb))/float64(max(len(ra), len(rb)))
+	if len(ra) >= 3 && strings.HasPrefix(b, a) || len(rb) >= 3 && strings.HasPrefix(a, b) {
+		score = max(score, 0.9)
+	}
+
+	return score
+}
+
+// levenshtein returns the number of rune insertions, deletions, and
+// substitutions turning a into b.
+func levenshtein(a, b []rune) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := range a {
+		current[0] = i + 1
+		for j := range b {
+			cost := 1
+			if a[i] == b[j] {
+				cost = 0
+			}
+			current[j+1] = min(previous[j+1]+1, current[j]+1, previous[j]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// rankTokens splits text into lowercase transliterated words, dropping
+// punctuation and stop words that carry no meaning in a title.
+func rankTokens(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(Transliterate(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	tokens := fields[:0]
+	for _, field := range fields {
+		if !rankStopWords[field] {
+			tokens = append(tokens, field)
+		}
+	}
+
+	return tokens
+}
+
+// rankStopWords are the English articles and conjunctions ignored when
+// ranking.
+var rankStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "of": true, "the": true,
+}
@@ -0,0 +1,193 @@
+package anna
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a mirror or partner server keeps answering
+// 429 Too Many Requests beyond the time a request may wait.
+var ErrRateLimited = errors.New("rate limited by upstream")
+
+// DefaultRateLimitWait is how long a request waits for rate limits to lift
+// when no wait is configured.
+const DefaultRateLimitWait = 30 * time.Second
+
+const (
+	// maxRateLimitRetries bounds the retries of a single request.
+	maxRateLimitRetries = 4
+	// maxBackoff caps the delay between retries without a Retry-After header.
+	maxBackoff = 30 * time.Second
+)
+
+// RateLimitError reports the host that rate limited a request and when it
+// allows the next one.
+type RateLimitError struct {
+	Host       string
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s: %s asks to retry in %s", ErrRateLimited, e.Host, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// hostBackoff is the rate limit state of a host.
+type hostBackoff struct {
+	strikes   int
+	notBefore time.Time
+}
+
+// retryTransport delays and retries requests answered with 429, honoring
+// Retry-After or backing off exponentially per host, as long as the request
+// can wait.
+type retryTransport struct {
+	base http.RoundTripper
+	wait time.Duration
+
+	mu    sync.Mutex
+	hosts map[string]*hostBackoff
+}
+
+func newRetryTransport(base http.RoundTripper, wait time.Duration) *retryTransport {
+	if wait <= 0 {
+		wait = DefaultRateLimitWait
+	}
+
+	return &retryTransport{base: base, wait: wait, hosts: make(map[string]*hostBackoff)}
+}
+
+// RoundTrip sends req, waiting out the rate limits of its host until the
+// deadline of its context or the configured wait.
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	deadline := time.Now().Add(t.wait)
+	if ctxDeadline, ok := req.Context().Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		// Earlier 429s of the host also hold back the requests that follow
+		if delay := t.pause(host); delay > 0 {
+			if err := t.sleep(req, host, delay, deadline); err != nil {
+				return nil, err
+			}
+		}
+
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusTooManyRequests {
+			t.clear(host)
+			return resp, nil
+		}
+
+		delay := t.strike(host, resp.Header.Get("Retry-After"))
+		if !replayable {
+			return resp, nil
+		}
+		if attempt+1 >= maxRateLimitRetries || time.Now().Add(delay).After(deadline) {
+			resp.Body.Close()
+			return nil, &RateLimitError{Host: host, RetryAfter: delay}
+		}
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		resp.Body.Close()
+	}
+}
+
+// pause returns how long requests to host are held back.
+func (t *retryTransport) pause(host string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, ok := t.hosts[host]; ok {
+		return time.Until(state.notBefore)
+	}
+	return 0
+}
+
+// sleep waits delay, unless it goes past deadline or the request is canceled.
+func (t *retryTransport) sleep(req *http.Request, host string, delay time.Duration, deadline time.Time) error {
+	if time.Now().Add(delay).After(deadline) {
+		return &RateLimitError{Host: host, RetryAfter: delay}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// strike records a 429 of host, and returns the delay before the next
+// request: the Retry-After header of the answer, or one that doubles with
+// every consecutive 429.
+func (t *retryTransport) strike(host, header string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.hosts[host]
+	if !ok {
+		state = &hostBackoff{}
+		t.hosts[host] = state
+	}
+	state.strikes++
+
+	delay, ok := retryAfter(header, time.Now())
+	if !ok {
+		delay = min(time.Second<<min(state.strikes-1, 5), maxBackoff)
+	}
+	state.notBefore = time.Now().Add(delay)
+
+	return delay
+}
+
+// clear forgets the rate limits of host once it answers again.
+func (t *retryTransport) clear(host string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.hosts, host)
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport.
+func (t *retryTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP
+// date, into a delay from now. It reports false when the header is missing
+// or invalid.
+func retryAfter(header string, now time.Time) (time.Duration, bool) {
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
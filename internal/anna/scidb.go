@@ -0,0 +1,143 @@
+package anna
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// AnnasSciDBEndpoint is the SciDB page of a paper, relative to the mirror.
+const AnnasSciDBEndpoint = "%s/scidb/%s"
+
+// ErrInvalidDOI is returned for identifiers that are not DOIs.
+var ErrInvalidDOI = errors.New("invalid DOI")
+
+// Paper is a scientific paper found through SciDB.
+type Paper struct {
+	DOI   string `json:"doi"`
+	Title string `json:"title,omitempty"`
+	// Hash is the MD5 of the paper's record, used for fast downloads when no
+	// direct link is offered.
+	Hash string `json:"hash,omitempty"`
+	// DownloadURL is the direct PDF link embedded in the SciDB page, which
+	// needs no secret key.
+	DownloadURL string `json:"download_url,omitempty"`
+	URL         string `json:"url"`
+}
+
+// NormalizeDOI strips resolver prefixes such as "https://doi.org/" or "doi:"
+// and validates that the result looks like a DOI.
+func NormalizeDOI(doi string) (string, error) {
+	doi = strings.TrimSpace(doi)
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		if len(doi) >= len(prefix) && strings.EqualFold(doi[:len(prefix)], prefix) {
+			doi = doi[len(prefix):]
+			break
+		}
+	}
+
+	prefix, suffix, ok := strings.Cut(doi, "/")
+	if !ok || !strings.HasPrefix(prefix, "10.") || len(prefix) < 4 || suffix == "" || strings.ContainsAny(doi, " \t\n?#") {
+		return "", fmt.Errorf("%w %q: expected the form 10.<registrant>/<suffix>", ErrInvalidDOI, doi)
+	}
+
+	return doi, nil
+}
+
+// GetPaper looks up a paper by DOI on SciDB, which resolves papers
+// differently from books: the page embeds the PDF directly and links the
+// record it belongs to.
+func GetPaper(doi string) (*Paper, error) {
+	l := logger.GetLogger()
+
+	doi, err := NormalizeDOI(doi)
+	if err != nil {
+		return nil, err
+	}
+
+	var paper *Paper
+	err = eachMirror(func(base string) error {
+		c := newCollector()
+		paper = &Paper{DOI: doi}
+
+		c.OnHTML("main", func(e *colly.HTMLElement) {
+			if paper.Title == "" {
+				paper.Title = strings.TrimSpace(e.DOM.Find("div.text-xl, div.text-3xl").First().Text())
+			}
+		})
+
+		c.OnHTML("a[href^='/md5/']", func(e *colly.HTMLElement) {
+			if paper.Hash == "" {
+				paper.Hash = strings.TrimPrefix(e.Attr("href"), "/md5/")
+			}
+		})
+
+		// The viewer embeds the PDF, and the download button links it
+		c.OnHTML("embed[src], iframe[src], a[href]", func(e *colly.HTMLElement) {
+			link := e.Attr("src")
+			if link == "" {
+				link = e.Attr("href")
+			}
+			if paper.DownloadURL == "" && isPDFLink(link) {
+				paper.DownloadURL = e.Request.AbsoluteURL(link)
+			}
+		})
+
+		c.OnRequest(func(r *colly.Request) {
+			l.Info("Visiting URL", zap.String("url", r.URL.String()))
+		})
+
+		pageURL := fmt.Sprintf(AnnasSciDBEndpoint, base, doi)
+		if err := c.Visit(pageURL); err != nil {
+			return err
+		}
+
+		paper.URL = pageURL
+		if paper.Hash != "" {
+			if hash, err := NormalizeHash(paper.Hash); err == nil {
+				paper.Hash = hash
+			} else {
+				paper.Hash = ""
+			}
+		}
+		if paper.Hash == "" && paper.DownloadURL == "" {
+			return &stopMirrors{err: fmt.Errorf("paper %s was not found on SciDB", doi)}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return paper, nil
+}
+
+// isPDFLink reports whether link points to a PDF file.
+func isPDFLink(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+
+	return strings.HasSuffix(strings.ToLower(u.Path), ".pdf")
+}
+
+// Book returns the paper as a book, so it can be saved like one.
+func (p *Paper) Book() *Book {
+	title := p.Title
+	if title == "" {
+		title = p.DOI
+	}
+
+	return &Book{
+		Title:  title,
+		Format: "pdf",
+		Hash:   p.Hash,
+		URL:    p.URL,
+	}
+}
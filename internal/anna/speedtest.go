@@ -0,0 +1,58 @@
+package anna
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"time"
+)
+
+// ServerSpeed is the throughput measured on one fast partner server.
+type ServerSpeed struct {
+	Server         int     `json:"server"`
+	Domain         string  `json:"domain,omitempty"`
+	Bytes          int64   `json:"bytes"`
+	Seconds        float64 `json:"seconds"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	Error          string  `json:"error,omitempty"`
+}
+
+func (s *ServerSpeed) String() string {
+	if s.Error != "" {
+		return fmt.Sprintf("Server %d (%s): failed: %s", s.Server, s.Domain, s.Error)
+	}
+
+	return fmt.Sprintf("Server %d (%s): %.2f MB/s (%d bytes in %.2fs)", s.Server, s.Domain, s.BytesPerSecond/(1<<20), s.Bytes, s.Seconds)
+}
+
+// MeasureSpeed downloads the first sample bytes behind an already resolved
+// download URL and reports the throughput.
+func MeasureSpeed(ctx context.Context, server int, downloadURL string, sample int64) ServerSpeed {
+	speed := ServerSpeed{Server: server}
+	if u, err := url.Parse(downloadURL); err == nil {
+		speed.Domain = u.Hostname()
+	}
+
+	start := time.Now()
+	resp, err := OpenStream(ctx, downloadURL, fmt.Sprintf("bytes=0-%d", sample-1))
+	if err != nil {
+		speed.Error = err.Error()
+		return speed
+	}
+	defer resp.Body.Close()
+
+	// Servers ignoring the range still only get to send the sample
+	speed.Bytes, err = io.Copy(io.Discard, io.LimitReader(resp.Body, sample))
+	elapsed := time.Since(start)
+	if err != nil {
+		speed.Error = err.Error()
+		return speed
+	}
+
+	speed.Seconds = elapsed.Seconds()
+	if speed.Seconds > 0 {
+		speed.BytesPerSecond = float64(speed.Bytes) / speed.Seconds
+	}
+	return speed
+}
@@ -1,17 +1,55 @@
 package anna
 
+import "time"
+
 type Book struct {
-	Language  string `json:"language"`
-	Format    string `json:"format"`
-	Size      string `json:"size"`
-	Title     string `json:"title"`
-	Publisher string `json:"publisher"`
-	Authors   string `json:"authors"`
-	URL       string `json:"url"`
-	Hash      string `json:"hash"`
+	Language  string   `json:"language"`
+	Format    string   `json:"format"`
+	Size      string   `json:"size"`
+	Title     string   `json:"title"`
+	Publisher string   `json:"publisher"`
+	Authors   []string `json:"authors"`
+	URL       string   `json:"url"`
+	Hash      string   `json:"hash"`
+
+	// Bytes is Size in bytes, 0 when unknown.
+	Bytes int64 `json:"bytes,omitempty"`
+	// Sources are the upstream collections holding the file, such as lgli,
+	// zlib, or ia. More sources mean more places to fetch it from.
+	Sources []string `json:"sources,omitempty"`
+	// FastDownload reports whether the file is offered through the fast
+	// download partner servers.
+	FastDownload bool `json:"fast_download,omitempty"`
+}
+
+// Metadata is the detailed view of a single record, as shown on its /md5/ page.
+type Metadata struct {
+	Book
+	ISBNs       []string `json:"isbns,omitempty"`
+	Description string   `json:"description,omitempty"`
+	// AlternativeTitles are other titles of the record, such as the title
+	// in its original language.
+	AlternativeTitles []string `json:"alternative_titles,omitempty"`
+	Subjects          []string `json:"subjects,omitempty"`
+	Series            []string `json:"series,omitempty"`
+}
+
+// AccountInfo reports the fast download allowance of the key that was used.
+type AccountInfo struct {
+	DownloadsLeft   int `json:"downloads_left"`
+	DownloadsPerDay int `json:"downloads_per_day"`
+}
+
+// DownloadInfo is the result of resolving a fast download link.
+type DownloadInfo struct {
+	URL     string       `json:"download_url"`
+	Account *AccountInfo `json:"account,omitempty"`
+	// ExpiresAt is when the signed link stops working, if the link says.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 type fastDownloadResponse struct {
-	DownloadURL string `json:"download_url"`
-	Error       string `json:"error"`
+	DownloadURL string       `json:"download_url"`
+	AccountInfo *AccountInfo `json:"account_fast_download_info"`
+	Error       string       `json:"error"`
 }
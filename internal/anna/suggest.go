@@ -0,0 +1,78 @@
+package anna
+
+import (
+	"strings"
+	"unicode"
+)
+
+// maxSuggestionBooks is the number of results kept per suggestion.
+const maxSuggestionBooks = 3
+
+// Suggestion is a relaxed variant of a query that returned results.
+type Suggestion struct {
+	Query string  `json:"query"`
+	Books []*Book `json:"books"`
+}
+
+// Relaxations returns looser variants of query to try when it found nothing:
+// without a subtitle, without punctuation, and with author and title
+// swapped. The query itself is never included.
+func Relaxations(query string) []string {
+	query = strings.TrimSpace(query)
+	variants := make([]string, 0, 4)
+	add := func(variant string) {
+		variant = strings.Join(strings.Fields(variant), " ")
+		if variant == "" || strings.EqualFold(variant, query) {
+			return
+		}
+		for _, existing := range variants {
+			if strings.EqualFold(existing, variant) {
+				return
+			}
+		}
+		variants = append(variants, variant)
+	}
+
+	// "Title: Subtitle", "Title (Series #1)"
+	if i := strings.IndexAny(query, ":(["); i > 0 {
+		add(query[:i])
+	}
+
+	add(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, query))
+
+	// "Title by Author" and "Author - Title"
+	lower := strings.ToLower(query)
+	if i := strings.LastIndex(lower, " by "); i > 0 {
+		add(query[i+4:] + " " + query[:i])
+	} else if left, right, ok := strings.Cut(query, " - "); ok {
+		add(right + " " + left)
+	}
+
+	return variants
+}
+
+// Suggest searches the relaxations of a query that found nothing with
+// filters, keeping the filters, and returns those with results. Failing
+// variants are skipped.
+func Suggest(query string, filters Filters) []Suggestion {
+	suggestions := make([]Suggestion, 0)
+	for _, variant := range Relaxations(query) {
+		books := make([]*Book, 0, maxSuggestionBooks)
+		_, err := StreamBooksFiltered(variant, filters, 0, func(book *Book) bool {
+			books = append(books, book)
+			return len(books) < maxSuggestionBooks
+		})
+		if err != nil || len(books) == 0 {
+			continue
+		}
+
+		suggestions = append(suggestions, Suggestion{Query: variant, Books: books})
+	}
+
+	return suggestions
+}
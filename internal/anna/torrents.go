@@ -0,0 +1,117 @@
+package anna
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// AnnasTorrentsEndpoint lists the released dataset torrents, relative to the
+// mirror.
+const AnnasTorrentsEndpoint = "%s/torrents"
+
+var seedersPattern = regexp.MustCompile(`(?i)(\d+)\s*seed`)
+
+// Torrent is a dataset torrent released by Anna's Archive.
+type Torrent struct {
+	Name string `json:"name"`
+	// Group is the collection the torrent belongs to, and Summary the
+	// description of its contents shown above the group's table.
+	Group   string `json:"group,omitempty"`
+	Summary string `json:"summary,omitempty"`
+	Size    string `json:"size,omitempty"`
+	Seeders int    `json:"seeders"`
+	URL     string `json:"url"`
+}
+
+func (t *Torrent) String() string {
+	return fmt.Sprintf("Name: %s\nGroup: %s\nSize: %s\nSeeders: %d\nURL: %s", t.Name, t.Group, t.Size, t.Seeders, t.URL)
+}
+
+// ListTorrents parses the torrents page. When group is not empty, only the
+// torrents of groups whose name contains it, ignoring case, are returned.
+func ListTorrents(group string) ([]*Torrent, error) {
+	l := logger.GetLogger()
+
+	var torrents []*Torrent
+	err := eachMirror(func(base string) error {
+		c := newCollector()
+		torrents = make([]*Torrent, 0)
+
+		c.OnHTML("tr", func(e *colly.HTMLElement) {
+			link := e.DOM.Find("a[href$='.torrent']").First()
+			href, ok := link.Attr("href")
+			if !ok {
+				return
+			}
+
+			torrent := &Torrent{
+				Name: strings.TrimSpace(link.Text()),
+				URL:  e.Request.AbsoluteURL(href),
+			}
+			torrent.Group, torrent.Summary = torrentGroup(e.DOM)
+			if group != "" && !strings.Contains(strings.ToLower(torrent.Group), strings.ToLower(group)) {
+				return
+			}
+
+			e.DOM.Find("td").Each(func(_ int, cell *goquery.Selection) {
+				text := strings.TrimSpace(cell.Text())
+				if match := seedersPattern.FindStringSubmatch(text); match != nil {
+					torrent.Seeders, _ = strconv.Atoi(match[1])
+				} else if torrent.Size == "" && isSize(text) {
+					torrent.Size = text
+				}
+			})
+
+			torrents = append(torrents, torrent)
+		})
+
+		c.OnRequest(func(r *colly.Request) {
+			l.Info("Visiting URL", zap.String("url", r.URL.String()))
+		})
+
+		return c.Visit(fmt.Sprintf(AnnasTorrentsEndpoint, base))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list torrents: %w", err)
+	}
+
+	return torrents, nil
+}
+
+// torrentGroup returns the heading of the table that holds row, and the
+// description between the heading and the table.
+func torrentGroup(row *goquery.Selection) (group, summary string) {
+	table := row.Closest("table")
+	heading := table.PrevAll().Filter("h2, h3").First()
+	if heading.Length() == 0 {
+		return "", ""
+	}
+
+	group = strings.Join(strings.Fields(heading.Text()), " ")
+	heading.NextUntil("table").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		summary = strings.Join(strings.Fields(s.Text()), " ")
+		return summary == ""
+	})
+
+	return group, summary
+}
+
+// isSize reports whether text is a file size such as "1.2TB" or "706.3 GB".
+func isSize(text string) bool {
+	text = strings.ToUpper(strings.ReplaceAll(text, " ", ""))
+	for _, unit := range []string{"TB", "GB", "MB", "KB"} {
+		if number, ok := strings.CutSuffix(text, unit); ok {
+			_, err := strconv.ParseFloat(number, 64)
+			return err == nil
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package anna
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// romanization maps lowercase Cyrillic and Greek letters, and Latin letters
+// that do not decompose into a base letter and marks, to Latin letters.
+var romanization = map[rune]string{
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh",
+	'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o",
+	'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts",
+	'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu",
+	'я': "ya", 'і': "i", 'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u", 'ђ': "dj", 'ј': "j",
+	'љ': "lj", 'њ': "nj", 'ћ': "c", 'џ': "dz", 'ѓ': "g", 'ќ': "k", 'ѕ': "dz",
+	// Greek
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i", 'θ': "th",
+	'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x", 'ο': "o", 'π': "p",
+	'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'φ': "f", 'χ': "ch", 'ψ': "ps",
+	'ω': "o",
+	// Latin
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'þ': "th", 'ð': "d",
+}
+
+// Transliterate removes diacritics and romanizes Cyrillic and Greek text, so
+// "Достоевский" becomes "Dostoevskiy" and "Gödel" becomes "Godel". Scripts
+// without a rule, such as CJK, are kept unchanged.
+func Transliterate(text string) string {
+	var b strings.Builder
+	for _, r := range norm.NFC.String(text) {
+		if latin, ok := romanize(r); ok {
+			b.WriteString(latin)
+			continue
+		}
+
+		// Drop the marks of decomposable letters, then romanize their base
+		for _, part := range norm.NFD.String(string(r)) {
+			if unicode.Is(unicode.Mn, part) {
+				continue
+			}
+			if latin, ok := romanize(part); ok {
+				b.WriteString(latin)
+			} else {
+				b.WriteRune(part)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// romanize returns the romanization of r, capitalized like r.
+func romanize(r rune) (string, bool) {
+	latin, ok := romanization[unicode.ToLower(r)]
+	if !ok {
+		return "", false
+	}
+	if unicode.IsUpper(r) && latin != "" {
+		latin = strings.ToUpper(latin[:1]) + latin[1:]
+	}
+
+	return latin, true
+}
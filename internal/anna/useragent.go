@@ -0,0 +1,103 @@
+package anna
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// User agent rotation modes of Options.UserAgentRotation.
+const (
+	// UserAgentRotationOff sends the client's default user agent.
+	UserAgentRotationOff = "off"
+	// UserAgentRotationMirror sticks to one user agent per mirror, and moves
+	// on to the next one when the mirror starts blocking requests.
+	UserAgentRotationMirror = "mirror"
+	// UserAgentRotationRequest uses the next user agent for every request.
+	UserAgentRotationRequest = "request"
+)
+
+// DefaultUserAgents are common desktop browsers, rotated through when no list
+// of user agents is configured.
+var DefaultUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.1 Safari/605.1.15",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:133.0) Gecko/20100101 Firefox/133.0",
+	"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36",
+}
+
+// userAgentTransport sets the User-Agent header of upstream requests.
+type userAgentTransport struct {
+	base       http.RoundTripper
+	agents     []string
+	perRequest bool
+
+	mu     sync.Mutex
+	next   int
+	byHost map[string]int
+}
+
+// newUserAgentTransport wraps base to send the fixed user agent, or to rotate
+// through agents as selected by rotation. base is returned as is when neither
+// is configured.
+func newUserAgentTransport(base http.RoundTripper, fixed string, agents []string, rotation string) (http.RoundTripper, error) {
+	switch rotation {
+	case "", UserAgentRotationOff, UserAgentRotationMirror, UserAgentRotationRequest:
+	default:
+		return nil, fmt.Errorf("invalid user agent rotation %q", rotation)
+	}
+
+	switch {
+	case fixed != "":
+		agents = []string{fixed}
+	case rotation == "" || rotation == UserAgentRotationOff:
+		return base, nil
+	case len(agents) == 0:
+		agents = DefaultUserAgents
+	}
+
+	return &userAgentTransport{
+		base:       base,
+		agents:     agents,
+		perRequest: rotation == UserAgentRotationRequest,
+		byHost:     make(map[string]int),
+	}, nil
+}
+
+// RoundTrip sends req with the user agent picked for its host.
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+
+	t.mu.Lock()
+	index, ok := t.byHost[host]
+	if t.perRequest || !ok {
+		// Mirrors get different user agents, so they are not all blocked at once
+		index = t.next % len(t.agents)
+		t.next++
+		t.byHost[host] = index
+	}
+	t.mu.Unlock()
+
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.agents[index])
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil && !t.perRequest && len(t.agents) > 1 &&
+		(resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) {
+		t.mu.Lock()
+		if t.byHost[host] == index {
+			t.byHost[host] = (index + 1) % len(t.agents)
+		}
+		t.mu.Unlock()
+	}
+
+	return resp, err
+}
+
+// CloseIdleConnections closes the idle connections of the wrapped transport.
+func (t *userAgentTransport) CloseIdleConnections() {
+	if closer, ok := t.base.(interface{ CloseIdleConnections() }); ok {
+		closer.CloseIdleConnections()
+	}
+}
@@ -0,0 +1,182 @@
+package aria2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBinary is used when no explicit aria2c binary is configured.
+const DefaultBinary = "aria2c"
+
+// pollInterval is how often the status of an RPC download is checked.
+const pollInterval = time.Second
+
+// Options selects how aria2 is driven.
+type Options struct {
+	// Binary is the aria2c executable run when RPCURL is empty.
+	Binary string
+	// RPCURL is the JSON-RPC endpoint of a running aria2 daemon, for example
+	// "http://localhost:6800/jsonrpc". The daemon must be able to write to the
+	// download directory.
+	RPCURL    string
+	RPCSecret string
+	// Connections is the number of segments fetched in parallel.
+	Connections int
+	// Proxy is used for every protocol when not empty.
+	Proxy string
+}
+
+// Download fetches url into dir/name with a segmented, multi-connection
+// download, overwriting any existing file.
+func Download(ctx context.Context, opts Options, url, dir, name string) error {
+	if opts.RPCURL != "" {
+		return downloadRPC(ctx, opts, url, dir, name)
+	}
+
+	return downloadProcess(ctx, opts, url, dir, name)
+}
+
+func downloadOptions(opts Options, dir, name string) map[string]string {
+	options := map[string]string{
+		"dir":                 dir,
+		"out":                 name,
+		"allow-overwrite":     "true",
+		"auto-file-renaming":  "false",
+		"always-resume":       "false",
+		"remove-control-file": "true",
+	}
+	if opts.Proxy != "" {
+		options["all-proxy"] = opts.Proxy
+	}
+	if opts.Connections > 0 {
+		connections := strconv.Itoa(opts.Connections)
+		options["split"] = connections
+		options["max-connection-per-server"] = connections
+	}
+
+	return options
+}
+
+func downloadProcess(ctx context.Context, opts Options, url, dir, name string) error {
+	binary := opts.Binary
+	if binary == "" {
+		binary = DefaultBinary
+	}
+
+	resolved, err := exec.LookPath(binary)
+	if err != nil {
+		return fmt.Errorf("aria2c binary %q not found: %w", binary, err)
+	}
+
+	args := []string{"--console-log-level=warn", "--summary-interval=0", "--download-result=hide"}
+	for key, value := range downloadOptions(opts, dir, name) {
+		args = append(args, fmt.Sprintf("--%s=%s", key, value))
+	}
+	args = append(args, url)
+
+	output, err := exec.CommandContext(ctx, resolved, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("aria2c download failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      string `json:"id"`
+	Method  string `json:"method"`
+	Params  []any  `json:"params"`
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type status struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"errorMessage"`
+}
+
+// call invokes an aria2 JSON-RPC method and decodes its result into result.
+func call(ctx context.Context, opts Options, method string, result any, params ...any) error {
+	if opts.RPCSecret != "" {
+		params = append([]any{"token:" + opts.RPCSecret}, params...)
+	}
+
+	body, err := json.Marshal(rpcRequest{JSONRPC: "2.0", ID: "annas-mcp", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, opts.RPCURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("aria2 RPC %s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+
+	var decoded rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return fmt.Errorf("failed to decode aria2 RPC %s response: %w", method, err)
+	}
+	if decoded.Error != nil {
+		return fmt.Errorf("aria2 RPC %s failed: %s", method, decoded.Error.Message)
+	}
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(decoded.Result, result)
+}
+
+func downloadRPC(ctx context.Context, opts Options, url, dir, name string) error {
+	var gid string
+	if err := call(ctx, opts, "aria2.addUri", &gid, []string{url}, downloadOptions(opts, dir, name)); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// The daemon outlives the request, so the download is stopped there
+			_ = call(context.Background(), opts, "aria2.forceRemove", nil, gid)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		var current status
+		if err := call(ctx, opts, "aria2.tellStatus", &current, gid, []string{"status", "errorMessage"}); err != nil {
+			return err
+		}
+
+		switch current.Status {
+		case "complete":
+			return nil
+		case "error":
+			return fmt.Errorf("aria2 download failed: %s", current.ErrorMessage)
+		case "removed":
+			return errors.New("aria2 download was removed")
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Outcomes of a download request.
+const (
+	// OutcomeSaved means the file was saved to the download path.
+	OutcomeSaved = "saved"
+	// OutcomeLink means a fast download link was handed to the client.
+	OutcomeLink = "link"
+	// OutcomeFailed means the request failed.
+	OutcomeFailed = "failed"
+)
+
+// Entry is a single download request of the audit log.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Caller  string    `json:"caller"`
+	Source  string    `json:"source"`
+	Hash    string    `json:"md5"`
+	Title   string    `json:"title,omitempty"`
+	Outcome string    `json:"outcome"`
+	Bytes   int64     `json:"bytes,omitempty"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Filter selects entries of the audit log. Zero fields match everything.
+type Filter struct {
+	Caller  string
+	Hash    string
+	Outcome string
+	Since   time.Time
+	// Limit keeps only the most recent entries.
+	Limit int
+}
+
+var mu sync.Mutex
+
+// Append appends entry to the JSON Lines audit log at path, creating it if
+// needed. Existing entries are never rewritten.
+func Append(path string, entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+
+	return nil
+}
+
+// Read returns the entries of the audit log at path that match filter, oldest
+// first. A missing log has no entries.
+func Read(path string, filter Filter) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	defer f.Close()
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log line %d: %w", line, err)
+		}
+		if filter.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+
+	return entries, nil
+}
+
+func (f Filter) matches(entry Entry) bool {
+	return (f.Caller == "" || entry.Caller == f.Caller) &&
+		(f.Hash == "" || entry.Hash == f.Hash) &&
+		(f.Outcome == "" || entry.Outcome == f.Outcome) &&
+		(f.Since.IsZero() || !entry.Time.Before(f.Since))
+}
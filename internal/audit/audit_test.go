@@ -0,0 +1,54 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	now := time.Now().UTC()
+
+	entries := []Entry{
+		{Time: now.Add(-48 * time.Hour), Caller: "family", Source: "download", Hash: "a", Outcome: OutcomeSaved, Bytes: 1024},
+		{Time: now.Add(-time.Hour), Caller: "guests", Source: "indexer", Hash: "b", Outcome: OutcomeFailed, Error: "quota exhausted"},
+		{Time: now, Caller: "family", Source: "download", Hash: "c", Outcome: OutcomeLink},
+	}
+	for _, entry := range entries {
+		if err := Append(path, entry); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	t.Run("All", func(t *testing.T) {
+		got, err := Read(path, Filter{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(got) != 3 || got[0].Bytes != 1024 || got[1].Error != "quota exhausted" {
+			t.Errorf("Expected the appended entries in order, got %+v", got)
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		got, _ := Read(path, Filter{Caller: "family", Since: now.Add(-24 * time.Hour)})
+		if len(got) != 1 || got[0].Hash != "c" {
+			t.Errorf("Expected only entry 'c', got %+v", got)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		got, _ := Read(path, Filter{Limit: 2})
+		if len(got) != 2 || got[0].Hash != "b" {
+			t.Errorf("Expected the 2 most recent entries, got %+v", got)
+		}
+	})
+
+	t.Run("Missing Log", func(t *testing.T) {
+		got, err := Read(filepath.Join(t.TempDir(), "missing.jsonl"), Filter{})
+		if err != nil || len(got) != 0 {
+			t.Errorf("Expected no entries and no error, got %+v and %v", got, err)
+		}
+	})
+}
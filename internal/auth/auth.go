@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/state"
+)
+
+// Scopes granted to API tokens.
+const (
+	// ScopeSearch allows searches and metadata lookups.
+	ScopeSearch = "search"
+	// ScopeDownload allows operations that consume fast downloads.
+	ScopeDownload = "download"
+	// ScopeAdmin allows everything, including server administration.
+	ScopeAdmin = "admin"
+)
+
+// AllScopes is granted to the operator's API key and to unauthenticated local
+// deployments.
+var AllScopes = Scopes{ScopeSearch, ScopeDownload, ScopeAdmin}
+
+// Errors of the token store.
+var (
+	ErrTokenExists   = errors.New("token already exists")
+	ErrTokenNotFound = errors.New("token not found")
+)
+
+// Scopes is the set of permissions of a caller.
+type Scopes []string
+
+// Has reports whether the scopes grant scope. The admin scope grants all.
+func (s Scopes) Has(scope string) bool {
+	return slices.Contains(s, scope) || slices.Contains(s, ScopeAdmin)
+}
+
+// Quota limits the usage of a token per UTC day and calendar month. Limits
+// of 0 are unlimited.
+type Quota struct {
+	SearchesPerDay    int `json:"searches_per_day,omitempty"`
+	SearchesPerMonth  int `json:"searches_per_month,omitempty"`
+	DownloadsPerDay   int `json:"downloads_per_day,omitempty"`
+	DownloadsPerMonth int `json:"downloads_per_month,omitempty"`
+}
+
+// Token is an API token of the tokens file. The secret is stored either as
+// its SHA-256 hash or, for hand-written files, in plain text.
+type Token struct {
+	Name      string    `json:"name"`
+	Token     string    `json:"token,omitempty"`
+	SHA256    string    `json:"sha256,omitempty"`
+	Scopes    Scopes    `json:"scopes"`
+	Quota     *Quota    `json:"quota,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+type file struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// Store holds the API tokens loaded from a tokens file.
+type Store struct {
+	path   string
+	tokens []Token
+}
+
+// ValidScope reports whether scope is one of the known scopes.
+func ValidScope(scope string) bool {
+	return scope == ScopeSearch || scope == ScopeDownload || scope == ScopeAdmin
+}
+
+// Open reads the tokens file at path for editing. Unlike Load, a missing
+// file yields an empty store that is created on the first change.
+func Open(path string) (*Store, error) {
+	if path == "" {
+		return nil, errors.New("tokens file is not configured. Please set ANNAS_TOKENS_FILE")
+	}
+
+	store, err := Load(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Store{path: path}, nil
+	}
+	return store, err
+}
+
+// Load reads the tokens file at path. An empty path yields an empty store.
+func Load(path string) (*Store, error) {
+	if path == "" {
+		return &Store{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tokens file: %w", err)
+	}
+
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse tokens file %s: %w", path, err)
+	}
+
+	for i, token := range f.Tokens {
+		if token.Token == "" && token.SHA256 == "" {
+			return nil, fmt.Errorf("token %q in %s has neither token nor sha256", token.Name, path)
+		}
+		for _, scope := range token.Scopes {
+			if !ValidScope(scope) {
+				return nil, fmt.Errorf("token %q in %s has unknown scope %q", token.Name, path, scope)
+			}
+		}
+		if token.SHA256 == "" {
+			f.Tokens[i].SHA256 = Hash(token.Token)
+		}
+	}
+
+	return &Store{path: path, tokens: f.Tokens}, nil
+}
+
+// Tokens returns the tokens of the store, sorted by name.
+func (s *Store) Tokens() []Token {
+	tokens := slices.Clone(s.tokens)
+	slices.SortFunc(tokens, func(a, b Token) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return tokens
+}
+
+// Create adds a token named name and saves the store. It returns the secret
+// of the new token, which is only stored as its hash.
+func (s *Store) Create(name string, scopes Scopes, quota *Quota) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("token name must not be empty")
+	}
+	if len(scopes) == 0 {
+		return "", errors.New("token needs at least one scope")
+	}
+	for _, scope := range scopes {
+		if !ValidScope(scope) {
+			return "", fmt.Errorf("unknown scope %q, expected %s, %s, or %s", scope, ScopeSearch, ScopeDownload, ScopeAdmin)
+		}
+	}
+	if slices.ContainsFunc(s.tokens, func(token Token) bool { return token.Name == name }) {
+		return "", fmt.Errorf("%w: %s", ErrTokenExists, name)
+	}
+
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	secret := "annas_" + hex.EncodeToString(raw)
+
+	s.tokens = append(s.tokens, Token{
+		Name:      name,
+		SHA256:    Hash(secret),
+		Scopes:    scopes,
+		Quota:     quota,
+		CreatedAt: time.Now().UTC(),
+	})
+	if err := s.save(); err != nil {
+		s.tokens = s.tokens[:len(s.tokens)-1]
+		return "", err
+	}
+
+	return secret, nil
+}
+
+// Revoke removes the token named name and saves the store.
+func (s *Store) Revoke(name string) error {
+	i := slices.IndexFunc(s.tokens, func(token Token) bool { return token.Name == name })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrTokenNotFound, name)
+	}
+
+	s.tokens = slices.Delete(slices.Clone(s.tokens), i, i+1)
+	return s.save()
+}
+
+// save writes the tokens through a temporary file so the server never reads
+// a partial file. Hand-written plain text tokens are kept as they are.
+func (s *Store) save() error {
+	if s.path == "" {
+		return errors.New("tokens file is not configured. Please set ANNAS_TOKENS_FILE")
+	}
+
+	data, err := json.MarshalIndent(file{Tokens: s.tokens}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode tokens: %w", err)
+	}
+
+	return state.WriteFile(s.path, append(data, '\n'))
+}
+
+// Len returns the number of tokens in the store.
+func (s *Store) Len() int {
+	return len(s.tokens)
+}
+
+// Lookup returns the token matching secret.
+func (s *Store) Lookup(secret string) (*Token, bool) {
+	hash := Hash(secret)
+	for i := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(s.tokens[i].SHA256)) == 1 {
+			return &s.tokens[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// Hash returns the hex-encoded SHA-256 of a token secret.
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Caller identifies who is making a request.
+type Caller struct {
+	// Name is the token name, or a placeholder for the operator and local
+	// callers.
+	Name   string
+	Scopes Scopes
+	// Quota is the usage quota of the token, if any.
+	Quota *Quota
+	// Session is the MCP session of the call, if any.
+	Session string
+}
+
+// Callers without a token of the tokens file.
+var (
+	// Operator is a caller that authenticated with the operator's API key.
+	Operator = Caller{Name: "operator", Scopes: AllScopes}
+	// Local is a caller of an unauthenticated deployment, such as stdio
+	// mode or the CLI.
+	Local = Caller{Name: "local", Scopes: AllScopes}
+)
+
+type callerKey struct{}
+
+// WithCaller returns a context carrying the caller of a request.
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFrom returns the caller stored in ctx by WithCaller. Without one, the
+// caller is trusted, as in stdio mode.
+func CallerFrom(ctx context.Context) Caller {
+	if caller, ok := ctx.Value(callerKey{}).(Caller); ok {
+		return caller
+	}
+
+	return Local
+}
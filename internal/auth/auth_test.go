@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens.json")
+	content := `{"tokens": [
+		{"name": "reader", "token": "plain", "scopes": ["search"]},
+		{"name": "family", "sha256": "` + Hash("hashed") + `", "scopes": ["search", "download"]}
+	]}`
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write tokens file: %v", err)
+	}
+
+	store, err := Load(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Plain Token", func(t *testing.T) {
+		token, ok := store.Lookup("plain")
+		if !ok || token.Name != "reader" {
+			t.Fatalf("Expected token 'reader', got %+v", token)
+		}
+		if !token.Scopes.Has(ScopeSearch) || token.Scopes.Has(ScopeDownload) {
+			t.Errorf("Expected only the search scope, got %v", token.Scopes)
+		}
+	})
+
+	t.Run("Hashed Token", func(t *testing.T) {
+		token, ok := store.Lookup("hashed")
+		if !ok || token.Name != "family" || !token.Scopes.Has(ScopeDownload) {
+			t.Errorf("Expected token 'family' with the download scope, got %+v", token)
+		}
+	})
+
+	t.Run("Unknown Token", func(t *testing.T) {
+		if _, ok := store.Lookup("guess"); ok {
+			t.Error("Expected unknown token to be rejected")
+		}
+	})
+
+	t.Run("Unknown Scope", func(t *testing.T) {
+		os.WriteFile(file, []byte(`{"tokens": [{"name": "typo", "token": "x", "scopes": ["downlaod"]}]}`), 0o600)
+		if _, err := Load(file); err == nil {
+			t.Error("Expected error for unknown scope, got nil")
+		}
+	})
+}
+
+func TestCreateRevoke(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens.json")
+
+	store, err := Open(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	secret, err := store.Create("club", Scopes{ScopeSearch}, &Quota{DownloadsPerMonth: 5})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := store.Create("club", Scopes{ScopeSearch}, nil); !errors.Is(err, ErrTokenExists) {
+		t.Errorf("Expected ErrTokenExists, got %v", err)
+	}
+	if _, err := store.Create("typo", Scopes{"downlaod"}, nil); err == nil {
+		t.Error("Expected error for unknown scope, got nil")
+	}
+
+	reloaded, err := Load(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	token, ok := reloaded.Lookup(secret)
+	if !ok || token.Name != "club" || token.Token != "" || token.Quota == nil || token.Quota.DownloadsPerMonth != 5 {
+		t.Fatalf("Expected the hashed token 'club' with its quota, got %+v", token)
+	}
+
+	if err := reloaded.Revoke("club"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := reloaded.Revoke("club"); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("Expected ErrTokenNotFound, got %v", err)
+	}
+	if reloaded, _ := Load(file); reloaded.Len() != 0 {
+		t.Errorf("Expected no tokens after revoking, got %d", reloaded.Len())
+	}
+}
+
+func TestScopesHas(t *testing.T) {
+	if !(Scopes{ScopeAdmin}).Has(ScopeDownload) {
+		t.Error("Expected the admin scope to grant download")
+	}
+	if (Scopes{}).Has(ScopeSearch) {
+		t.Error("Expected empty scopes to grant nothing")
+	}
+}
@@ -0,0 +1,333 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksRefresh is how long fetched signing keys are trusted before they
+	// are fetched again.
+	jwksRefresh = time.Hour
+	// jwksMinRefresh limits how often an unknown key ID triggers a fetch.
+	jwksMinRefresh = time.Minute
+	// clockSkew is the leeway granted to the exp and nbf claims.
+	clockSkew = time.Minute
+)
+
+// OIDCProvider accepts JSON Web Tokens issued by an OpenID Connect provider
+// such as Authentik or Keycloak, verified with the keys the issuer
+// publishes. Scopes are read from the scope claim and the caller is named
+// after the preferred_username, email, or sub claim. The operator's API key
+// keeps granting every scope.
+type OIDCProvider struct {
+	issuer   string
+	audience string
+	apiKey   string
+	client   *http.Client
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewOIDCProvider returns a provider verifying tokens of issuer. When
+// audience is set, tokens must be issued for it.
+func NewOIDCProvider(issuer, audience, apiKey string) *OIDCProvider {
+	return &OIDCProvider{
+		issuer:   strings.TrimRight(issuer, "/"),
+		audience: audience,
+		apiKey:   apiKey,
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (p *OIDCProvider) Name() string {
+	return ProviderOIDC
+}
+
+func (p *OIDCProvider) Authenticate(ctx context.Context, credentials Credentials) (Caller, error) {
+	if credentials.Key == "" {
+		return Caller{}, ErrMissingCredentials
+	}
+	if p.apiKey != "" && subtle.ConstantTimeCompare([]byte(credentials.Key), []byte(p.apiKey)) == 1 {
+		return Operator, nil
+	}
+
+	claims, err := p.verify(ctx, credentials.Key, time.Now())
+	if err != nil {
+		return Caller{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+
+	caller := Caller{Name: claims.name()}
+	for _, scope := range claims.scopes() {
+		if ValidScope(scope) && !slices.Contains(caller.Scopes, scope) {
+			caller.Scopes = append(caller.Scopes, scope)
+		}
+	}
+	return caller, nil
+}
+
+// jwtClaims are the claims of a token that the provider checks.
+type jwtClaims struct {
+	Issuer            string          `json:"iss"`
+	Subject           string          `json:"sub"`
+	Audience          json.RawMessage `json:"aud"`
+	Expiry            *float64        `json:"exp"`
+	NotBefore         *float64        `json:"nbf"`
+	Scope             string          `json:"scope"`
+	Scopes            []string        `json:"scp"`
+	PreferredUsername string          `json:"preferred_username"`
+	Email             string          `json:"email"`
+}
+
+func (c *jwtClaims) name() string {
+	switch {
+	case c.PreferredUsername != "":
+		return c.PreferredUsername
+	case c.Email != "":
+		return c.Email
+	}
+	return c.Subject
+}
+
+func (c *jwtClaims) scopes() []string {
+	return append(strings.Fields(c.Scope), c.Scopes...)
+}
+
+// audiences returns the aud claim, which is either a string or a list.
+func (c *jwtClaims) audiences() []string {
+	var single string
+	if json.Unmarshal(c.Audience, &single) == nil {
+		return []string{single}
+	}
+	var list []string
+	_ = json.Unmarshal(c.Audience, &list)
+	return list
+}
+
+// verify checks the signature and claims of token at now.
+func (p *OIDCProvider) verify(ctx context.Context, token string, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("failed to parse token header: %w", err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token signature: %w", err)
+	}
+
+	key, err := p.key(ctx, header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w", err)
+	}
+	if strings.TrimRight(claims.Issuer, "/") != p.issuer {
+		return nil, fmt.Errorf("token was issued by %q", claims.Issuer)
+	}
+	if p.audience != "" && !slices.Contains(claims.audiences(), p.audience) {
+		return nil, fmt.Errorf("token is not issued for %q", p.audience)
+	}
+	if claims.Expiry == nil || now.Add(-clockSkew).After(time.Unix(int64(*claims.Expiry), 0)) {
+		return nil, errors.New("token expired")
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(int64(*claims.NotBefore), 0)) {
+		return nil, errors.New("token is not valid yet")
+	}
+
+	return &claims, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// verifySignature checks signature of signed with the RS or ES algorithm
+// alg.
+func verifySignature(alg string, key crypto.PublicKey, signed, signature []byte) error {
+	var hash crypto.Hash
+	switch alg[min(2, len(alg)):] {
+	case "256":
+		hash = crypto.SHA256
+	case "384":
+		hash = crypto.SHA384
+	case "512":
+		hash = crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+	digest := hash.New()
+	digest.Write(signed)
+	sum := digest.Sum(nil)
+
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("token algorithm %q does not match an RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(key, hash, sum, signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("token algorithm %q does not match an EC key", alg)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, sum, r, s) {
+			return errors.New("invalid token signature")
+		}
+	default:
+		return fmt.Errorf("unsupported token algorithm %q", alg)
+	}
+
+	return nil
+}
+
+// key returns the signing key with id, fetching the keys of the issuer when
+// they are stale or do not include it.
+func (p *OIDCProvider) key(ctx context.Context, id string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key, ok := p.keys[id]
+	stale := time.Since(p.fetched) > jwksRefresh
+	if ok && !stale {
+		return key, nil
+	}
+	if stale || time.Since(p.fetched) > jwksMinRefresh {
+		keys, err := p.fetchKeys(ctx)
+		if err != nil {
+			if ok {
+				// Keep using known keys while the issuer is unreachable
+				return key, nil
+			}
+			return nil, err
+		}
+		p.keys, p.fetched = keys, time.Now()
+	}
+
+	if key, ok := p.keys[id]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown token key %q", id)
+}
+
+// fetchKeys discovers the JWKS of the issuer and parses its RSA and EC keys.
+func (p *OIDCProvider) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := p.getJSON(ctx, p.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover the OIDC issuer: %w", err)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, errors.New("failed to discover the OIDC issuer: no jwks_uri")
+	}
+
+	var set struct {
+		Keys []struct {
+			KeyType string `json:"kty"`
+			KeyID   string `json:"kid"`
+			Use     string `json:"use"`
+			N       string `json:"n"`
+			E       string `json:"e"`
+			Curve   string `json:"crv"`
+			X       string `json:"x"`
+			Y       string `json:"y"`
+		} `json:"keys"`
+	}
+	if err := p.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch the OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.KeyType {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil || len(e) > 4 {
+				continue
+			}
+			keys[jwk.KeyID] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			var curve elliptic.Curve
+			switch jwk.Curve {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.KeyID] = &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+
+	return keys, nil
+}
+
+func (p *OIDCProvider) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
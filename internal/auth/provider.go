@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+)
+
+// Names of the authentication providers, as selected by configuration.
+const (
+	ProviderNone  = "none"
+	ProviderKeys  = "keys"
+	ProviderBasic = "basic"
+	ProviderOIDC  = "oidc"
+)
+
+// Errors of authentication providers.
+var (
+	// ErrMissingCredentials is returned when a request carries no
+	// credentials the provider accepts.
+	ErrMissingCredentials = errors.New("missing credentials")
+	// ErrInvalidCredentials is returned when the credentials of a request
+	// are wrong.
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Credentials are what a client presented to authenticate.
+type Credentials struct {
+	// Key is a bearer token, X-API-Key header, or apikey query parameter.
+	Key string
+	// Username and Password are the HTTP basic authentication credentials.
+	Username string
+	Password string
+}
+
+// Provider identifies the caller presenting credentials.
+type Provider interface {
+	// Name returns the provider name, one of the Provider constants for the
+	// built-in providers.
+	Name() string
+	// Authenticate returns the caller owning credentials.
+	Authenticate(ctx context.Context, credentials Credentials) (Caller, error)
+}
+
+// NoneProvider trusts every request, for deployments behind an
+// authenticating proxy.
+type NoneProvider struct{}
+
+func (NoneProvider) Name() string {
+	return ProviderNone
+}
+
+func (NoneProvider) Authenticate(context.Context, Credentials) (Caller, error) {
+	return Local, nil
+}
+
+// KeyProvider accepts the operator's API key, granting every scope, and the
+// tokens of a tokens file. When neither is configured, it trusts every
+// request.
+type KeyProvider struct {
+	apiKey string
+	store  func() *Store
+}
+
+// NewKeyProvider returns a provider checking keys against apiKey and the
+// tokens returned by store, which may change between calls.
+func NewKeyProvider(apiKey string, store func() *Store) *KeyProvider {
+	return &KeyProvider{apiKey: apiKey, store: store}
+}
+
+func (p *KeyProvider) Name() string {
+	return ProviderKeys
+}
+
+func (p *KeyProvider) Authenticate(_ context.Context, credentials Credentials) (Caller, error) {
+	return p.lookup(credentials.Key, "")
+}
+
+// Open reports whether the provider trusts every request because neither an
+// API key nor tokens are configured.
+func (p *KeyProvider) Open() bool {
+	store := p.tokens()
+	return p.apiKey == "" && (store == nil || store.Len() == 0)
+}
+
+func (p *KeyProvider) tokens() *Store {
+	if p.store == nil {
+		return nil
+	}
+	return p.store()
+}
+
+// lookup returns the caller owning key. A non-empty name must match the name
+// of the token.
+func (p *KeyProvider) lookup(key, name string) (Caller, error) {
+	if p.Open() {
+		return Local, nil
+	}
+	if key == "" {
+		return Caller{}, ErrMissingCredentials
+	}
+	if p.apiKey != "" && subtle.ConstantTimeCompare([]byte(key), []byte(p.apiKey)) == 1 {
+		return Operator, nil
+	}
+	if store := p.tokens(); store != nil {
+		if token, ok := store.Lookup(key); ok && (name == "" || name == token.Name) {
+			return Caller{Name: token.Name, Scopes: token.Scopes, Quota: token.Quota}, nil
+		}
+	}
+
+	return Caller{}, ErrInvalidCredentials
+}
+
+// BasicProvider accepts HTTP basic authentication with a token name as the
+// username and its secret as the password, or any username with the
+// operator's API key. Clients that cannot send basic credentials, such as
+// indexer clients, may still send keys.
+type BasicProvider struct {
+	keys *KeyProvider
+}
+
+// NewBasicProvider returns a provider checking basic credentials against
+// apiKey and the tokens returned by store.
+func NewBasicProvider(apiKey string, store func() *Store) *BasicProvider {
+	return &BasicProvider{keys: NewKeyProvider(apiKey, store)}
+}
+
+func (p *BasicProvider) Name() string {
+	return ProviderBasic
+}
+
+func (p *BasicProvider) Authenticate(_ context.Context, credentials Credentials) (Caller, error) {
+	if credentials.Password == "" {
+		return p.keys.lookup(credentials.Key, "")
+	}
+
+	return p.keys.lookup(credentials.Password, credentials.Username)
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyProviders(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens.json")
+	os.WriteFile(file, []byte(`{"tokens": [{"name": "family", "token": "family-secret", "scopes": ["search"]}]}`), 0o600)
+	store, err := Load(file)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tokens := func() *Store { return store }
+	ctx := context.Background()
+
+	t.Run("Open", func(t *testing.T) {
+		caller, err := NewKeyProvider("", nil).Authenticate(ctx, Credentials{})
+		if err != nil || caller.Name != Local.Name {
+			t.Errorf("Expected the local caller, got %+v, %v", caller, err)
+		}
+	})
+
+	t.Run("Keys", func(t *testing.T) {
+		provider := NewKeyProvider("operator-key", tokens)
+		if caller, _ := provider.Authenticate(ctx, Credentials{Key: "operator-key"}); caller.Name != Operator.Name {
+			t.Errorf("Expected the operator, got %+v", caller)
+		}
+		if caller, _ := provider.Authenticate(ctx, Credentials{Key: "family-secret"}); caller.Name != "family" {
+			t.Errorf("Expected caller 'family', got %+v", caller)
+		}
+		if _, err := provider.Authenticate(ctx, Credentials{}); !errors.Is(err, ErrMissingCredentials) {
+			t.Errorf("Expected ErrMissingCredentials, got %v", err)
+		}
+		if _, err := provider.Authenticate(ctx, Credentials{Key: "guess"}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+		}
+	})
+
+	t.Run("Basic", func(t *testing.T) {
+		provider := NewBasicProvider("", tokens)
+		if caller, _ := provider.Authenticate(ctx, Credentials{Username: "family", Password: "family-secret"}); caller.Name != "family" {
+			t.Errorf("Expected caller 'family', got %+v", caller)
+		}
+		if _, err := provider.Authenticate(ctx, Credentials{Username: "guests", Password: "family-secret"}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("Expected the username to match the token name, got %v", err)
+		}
+	})
+}
+
+func TestOIDCProvider(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	var issuer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			json.NewEncoder(w).Encode(map[string]string{"jwks_uri": issuer + "/keys"})
+		case "/keys":
+			json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	issuer = server.URL
+
+	sign := func(claims map[string]any) string {
+		header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": "k1", "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+		sum := sha256.Sum256([]byte(signed))
+		signature, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+	}
+	exp := time.Now().Add(time.Hour).Unix()
+	provider := NewOIDCProvider(issuer, "annas-mcp", "")
+	ctx := context.Background()
+
+	t.Run("Valid", func(t *testing.T) {
+		token := sign(map[string]any{"iss": issuer, "aud": "annas-mcp", "exp": exp, "sub": "1", "preferred_username": "alice", "scope": "openid search download"})
+		caller, err := provider.Authenticate(ctx, Credentials{Key: token})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if caller.Name != "alice" || !caller.Scopes.Has(ScopeDownload) || caller.Scopes.Has(ScopeAdmin) {
+			t.Errorf("Expected caller 'alice' with search and download, got %+v", caller)
+		}
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		cases := map[string]string{
+			"Expired":  sign(map[string]any{"iss": issuer, "aud": "annas-mcp", "exp": time.Now().Add(-time.Hour).Unix()}),
+			"Audience": sign(map[string]any{"iss": issuer, "aud": "other", "exp": exp}),
+			"Issuer":   sign(map[string]any{"iss": "https://evil.example.com", "aud": "annas-mcp", "exp": exp}),
+			"Tampered": strings.Replace(sign(map[string]any{"iss": issuer, "aud": "annas-mcp", "exp": exp}), ".", ".e30", 1),
+		}
+		for name, token := range cases {
+			if _, err := provider.Authenticate(ctx, Credentials{Key: token}); !errors.Is(err, ErrInvalidCredentials) {
+				t.Errorf("%s: expected ErrInvalidCredentials, got %v", name, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/state"
+)
+
+// ErrSessionExpired is returned for sessions whose upstream tokens expired
+// and cannot be refreshed.
+var ErrSessionExpired = errors.New("session expired")
+
+// Session is a login through the OAuth authorization code flow. Clients
+// present its secret like an API token.
+type Session struct {
+	// SHA256 is the hash of the session secret, which is not stored.
+	SHA256       string    `json:"sha256"`
+	Name         string    `json:"name"`
+	Scopes       Scopes    `json:"scopes"`
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// Expired reports whether the upstream access token of the session expired
+// at now. Tokens without an expiry never do.
+func (s *Session) Expired(now time.Time) bool {
+	return !s.Expiry.IsZero() && !now.Before(s.Expiry)
+}
+
+// SessionStore holds the OAuth sessions, persisting them to a file when it
+// has one.
+type SessionStore struct {
+	mu       sync.Mutex
+	path     string
+	Sessions []Session `json:"sessions"`
+}
+
+// OpenSessions loads the sessions file at path. A missing file starts empty,
+// and an empty path keeps the sessions in memory only.
+func OpenSessions(path string) (*SessionStore, error) {
+	store := &SessionStore{path: path}
+	if path == "" {
+		return store, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sessions file: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse sessions file %s: %w", path, err)
+	}
+
+	return store, nil
+}
+
+// Create saves session and returns its new secret.
+func (s *SessionStore) Create(session Session) (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	secret := "annas_session_" + hex.EncodeToString(raw)
+
+	session.SHA256 = Hash(secret)
+	session.CreatedAt = time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.Sessions = append(s.Sessions, session)
+	return secret, s.save()
+}
+
+// Lookup returns a copy of the session whose secret is secret.
+func (s *SessionStore) Lookup(secret string) (Session, bool) {
+	hash := Hash(secret)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range s.Sessions {
+		if session.SHA256 == hash {
+			return session, true
+		}
+	}
+	return Session{}, false
+}
+
+// Update replaces the stored session with the same hash as session.
+func (s *SessionStore) Update(session Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.Sessions {
+		if s.Sessions[i].SHA256 == session.SHA256 {
+			s.Sessions[i] = session
+			return s.save()
+		}
+	}
+	return ErrSessionExpired
+}
+
+// Delete removes the session with hash.
+func (s *SessionStore) Delete(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.Sessions {
+		if s.Sessions[i].SHA256 == hash {
+			s.Sessions = append(s.Sessions[:i], s.Sessions[i+1:]...)
+			return s.save()
+		}
+	}
+	return nil
+}
+
+// save writes the sessions through a temporary file so readers never see a
+// partial file.
+func (s *SessionStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode sessions: %w", err)
+	}
+
+	return state.WriteFile(s.path, data)
+}
+
+// SessionProvider accepts the secrets of OAuth sessions and hands any other
+// credentials to the next provider.
+type SessionProvider struct {
+	next     Provider
+	sessions *SessionStore
+	// refresh renews the upstream tokens of an expired session in place.
+	refresh func(context.Context, *Session) error
+}
+
+// NewSessionProvider returns a provider accepting the sessions of store
+// before asking next. Expired sessions are renewed with refresh, when set.
+func NewSessionProvider(next Provider, store *SessionStore, refresh func(context.Context, *Session) error) *SessionProvider {
+	return &SessionProvider{next: next, sessions: store, refresh: refresh}
+}
+
+func (p *SessionProvider) Name() string {
+	return p.next.Name()
+}
+
+func (p *SessionProvider) Authenticate(ctx context.Context, credentials Credentials) (Caller, error) {
+	session, ok := p.sessions.Lookup(credentials.Key)
+	if !ok {
+		return p.next.Authenticate(ctx, credentials)
+	}
+
+	if session.Expired(time.Now()) {
+		if p.refresh == nil || session.RefreshToken == "" {
+			return Caller{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, ErrSessionExpired)
+		}
+		if err := p.refresh(ctx, &session); err != nil {
+			return Caller{}, fmt.Errorf("%w: %w: %w", ErrInvalidCredentials, ErrSessionExpired, err)
+		}
+		if err := p.sessions.Update(session); err != nil {
+			return Caller{}, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+		}
+	}
+
+	return Caller{Name: session.Name, Scopes: session.Scopes}, nil
+}
@@ -0,0 +1,260 @@
+// Package booktext extracts the plain text of saved books, for language
+// checks and for agents that read them. It understands EPUB, FB2, DOCX,
+// HTML, and plain text, and the text layer of simple PDFs.
+package booktext
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// ErrUnsupported is returned for formats text cannot be extracted from.
+var ErrUnsupported = errors.New("text extraction is not supported for this format")
+
+// errFull stops extraction once the limit is reached.
+var errFull = errors.New("text limit reached")
+
+// Supported reports whether text can be extracted from the file at path,
+// judging by its extension.
+func Supported(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".epub", ".fb2", ".docx", ".html", ".htm", ".xhtml", ".txt", ".md", ".pdf":
+		return true
+	default:
+		return false
+	}
+}
+
+// Location marks where a chapter or a page starts in extracted text, at an
+// offset in characters.
+type Location struct {
+	Offset  int    `json:"offset"`
+	Chapter string `json:"chapter,omitempty"`
+	Page    int    `json:"page,omitempty"`
+}
+
+// Document is the text of a book with the locations of its chapters, taken
+// from the headings of EPUB, FB2, and HTML books, and of the pages of PDFs.
+type Document struct {
+	Text      string
+	Locations []Location
+}
+
+// Extract returns the text of the book at path, in reading order, cut after
+// limit characters unless limit is 0. PDFs without a text layer, such as
+// scans, yield no text.
+func Extract(path string, limit int) (string, error) {
+	doc, err := ExtractDocument(path, limit)
+	if err != nil {
+		return "", err
+	}
+
+	return doc.Text, nil
+}
+
+// ExtractDocument is like Extract, but also returns the locations of the
+// chapters and pages of the book.
+func ExtractDocument(path string, limit int) (*Document, error) {
+	text := &builder{limit: limit}
+
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".epub":
+		err = extractEPUB(path, text)
+	case ".docx":
+		err = extractDOCX(path, text)
+	case ".fb2", ".html", ".htm", ".xhtml":
+		err = extractFile(path, text, extractHTML)
+	case ".txt", ".md":
+		err = extractFile(path, text, extractPlain)
+	case ".pdf":
+		err = extractPDF(path, text)
+	default:
+		return nil, ErrUnsupported
+	}
+	if err != nil && !errors.Is(err, errFull) {
+		return nil, err
+	}
+
+	return &Document{Text: strings.TrimSpace(text.String()), Locations: text.locations}, nil
+}
+
+// extractFile opens path and passes it to extract.
+func extractFile(path string, text *builder, extract func(io.Reader, *builder) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return extract(file, text)
+}
+
+// builder collects text with collapsed whitespace, failing with errFull past
+// its limit.
+type builder struct {
+	strings.Builder
+	limit int
+	runes int
+	// space and newline are pending separators, written before the next word
+	space, newline bool
+	locations      []Location
+}
+
+// write appends s, collapsing runs of whitespace into single spaces.
+func (b *builder) write(s string) error {
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			b.space = true
+			continue
+		}
+		if b.Len() > 0 {
+			switch {
+			case b.newline:
+				b.WriteByte('\n')
+				b.runes++
+			case b.space:
+				b.WriteByte(' ')
+				b.runes++
+			}
+		}
+		b.space, b.newline = false, false
+
+		if b.limit > 0 && b.runes >= b.limit {
+			return errFull
+		}
+		b.WriteRune(r)
+		b.runes++
+	}
+
+	return nil
+}
+
+// offset returns the offset of the next word.
+func (b *builder) offset() int {
+	if b.Len() > 0 && (b.space || b.newline) {
+		return b.runes + 1
+	}
+
+	return b.runes
+}
+
+// mark records that location starts at the next word.
+func (b *builder) mark(location Location) {
+	location.Offset = b.offset()
+	b.locations = append(b.locations, location)
+}
+
+// paragraph ends the current paragraph.
+func (b *builder) paragraph() {
+	b.newline = true
+}
+
+// extractPlain reads a plain text file, assuming Latin-1 when it is not UTF-8.
+func extractPlain(r io.Reader, text *builder) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	content := string(data)
+	if !utf8.Valid(data) {
+		runes := make([]rune, len(data))
+		for i, c := range data {
+			runes[i] = rune(c)
+		}
+		content = string(runes)
+	}
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n\n") {
+		if err := text.write(paragraph); err != nil {
+			return err
+		}
+		text.paragraph()
+	}
+
+	return nil
+}
+
+// skippedElements hold no readable text, or only the metadata of FB2 books.
+var skippedElements = map[string]bool{"head": true, "script": true, "style": true, "binary": true, "description": true}
+
+// blockElements end a paragraph, including FB2's <p>, <v>, and <subtitle>.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "h1": true, "h2": true, "h3": true,
+	"h4": true, "h5": true, "h6": true, "blockquote": true, "section": true, "v": true, "subtitle": true,
+}
+
+// headingElements hold the titles of chapters: HTML's top headings and the
+// <title> of FB2 sections.
+var headingElements = map[string]bool{"h1": true, "h2": true, "title": true}
+
+// extractHTML reads the text of an HTML, XHTML, or FB2 document, marking its
+// headings as chapters.
+func extractHTML(r io.Reader, text *builder) error {
+	tokenizer := html.NewTokenizer(r)
+	skipped := 0
+	heading := -1
+	var title strings.Builder
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return err
+			}
+			text.paragraph()
+			return nil
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			if skippedElements[string(name)] {
+				skipped++
+			}
+			if blockElements[string(name)] || headingElements[string(name)] {
+				text.paragraph()
+			}
+			if headingElements[string(name)] && skipped == 0 && heading < 0 {
+				heading = text.offset()
+				title.Reset()
+			}
+			// HTML reads <title> as raw text, but FB2 nests paragraphs in it
+			if string(name) == "title" {
+				tokenizer.NextIsNotRawText()
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			if skippedElements[string(name)] && skipped > 0 {
+				skipped--
+			}
+			if blockElements[string(name)] || headingElements[string(name)] {
+				text.paragraph()
+			}
+			if headingElements[string(name)] && heading >= 0 {
+				if chapter := strings.Join(strings.Fields(title.String()), " "); chapter != "" {
+					text.locations = append(text.locations, Location{Offset: heading, Chapter: chapter})
+				}
+				heading = -1
+			}
+		case html.SelfClosingTagToken:
+			if name, _ := tokenizer.TagName(); blockElements[string(name)] {
+				text.paragraph()
+			}
+		case html.TextToken:
+			if skipped == 0 {
+				content := string(tokenizer.Text())
+				if heading >= 0 {
+					title.WriteString(content + " ")
+				}
+				if err := text.write(content); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package booktext
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeEPUB(t *testing.T, path string) {
+	t.Helper()
+
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	files := []struct{ name, content string }{
+		{"META-INF/container.xml", `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`},
+		{"OEBPS/content.opf", `<package><manifest><item id="a" href="text/b.xhtml"/><item id="b" href="text/a.xhtml"/></manifest><spine><itemref idref="a"/><itemref idref="b"/></spine></package>`},
+		{"OEBPS/text/a.xhtml", `<html><head><title>Skipped</title></head><body><p>Second &amp; last.</p></body></html>`},
+		{"OEBPS/text/b.xhtml", `<html><body><h1>Chapter One</h1><p>First   chapter.</p><script>skipped()</script></body></html>`},
+	}
+	writer := zip.NewWriter(out)
+	for _, file := range files {
+		w, _ := writer.Create(file.name)
+		w.Write([]byte(file.content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("EPUB", func(t *testing.T) {
+		path := filepath.Join(dir, "book.epub")
+		writeEPUB(t, path)
+
+		text, err := Extract(path, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if expected := "Chapter One\nFirst chapter.\nSecond & last."; text != expected {
+			t.Errorf("Expected text in spine order '%s', got '%s'", expected, text)
+		}
+	})
+
+	t.Run("PDF", func(t *testing.T) {
+		var stream bytes.Buffer
+		writer := zlib.NewWriter(&stream)
+		writer.Write([]byte("BT /F1 12 Tf 72 712 Td (Hello) Tj [(Wor) -20 (ld) -300 (\\(again\\))] TJ ET BT (Next line) Tj ET"))
+		writer.Close()
+
+		var data bytes.Buffer
+		data.WriteString("%PDF-1.4\n4 0 obj\n<< /Length 99 /Filter /FlateDecode >>\nstream\n")
+		data.Write(stream.Bytes())
+		data.WriteString("\nendstream\nendobj\n%%EOF\n")
+		path := filepath.Join(dir, "book.pdf")
+		os.WriteFile(path, data.Bytes(), 0o644)
+
+		text, err := Extract(path, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if expected := "Hello World (again)\nNext line"; text != expected {
+			t.Errorf("Expected text '%s', got '%s'", expected, text)
+		}
+	})
+
+	t.Run("Limit", func(t *testing.T) {
+		path := filepath.Join(dir, "book.txt")
+		os.WriteFile(path, []byte("one two\n\nthree four"), 0o644)
+
+		text, err := Extract(path, 9)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if text != "one two\nt" {
+			t.Errorf("Expected text 'one two\\nt', got '%s'", text)
+		}
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		if _, err := Extract(filepath.Join(dir, "book.mobi"), 0); err != ErrUnsupported {
+			t.Errorf("Expected ErrUnsupported, got %v", err)
+		}
+	})
+}
+
+func flate(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var stream bytes.Buffer
+	writer := zlib.NewWriter(&stream)
+	writer.Write([]byte(content))
+	writer.Close()
+
+	return stream.Bytes()
+}
+
+func TestExtractDocument(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Run("Chapters", func(t *testing.T) {
+		path := filepath.Join(dir, "book.fb2")
+		os.WriteFile(path, []byte(`<FictionBook><description><title-info><book-title>Skipped</book-title></title-info></description><body><section><title><p>Part One</p></title><p>Text.</p></section><section><title><p>Part Two</p></title><p>More.</p></section></body></FictionBook>`), 0o644)
+
+		doc, err := ExtractDocument(path, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if expected := "Part One\nText.\nPart Two\nMore."; doc.Text != expected {
+			t.Errorf("Expected text '%s', got '%s'", expected, doc.Text)
+		}
+		expected := []Location{{Offset: 0, Chapter: "Part One"}, {Offset: 15, Chapter: "Part Two"}}
+		if len(doc.Locations) != 2 || doc.Locations[0] != expected[0] || doc.Locations[1] != expected[1] {
+			t.Errorf("Expected locations %+v, got %+v", expected, doc.Locations)
+		}
+	})
+
+	t.Run("Pages", func(t *testing.T) {
+		// The streams are stored in the reverse order of the pages
+		var data bytes.Buffer
+		data.WriteString("%PDF-1.4\n1 0 obj << /Type /Pages /Kids [2 0 R 3 0 R] /Count 2 >> endobj\n")
+		data.WriteString("2 0 obj << /Type /Page /Parent 1 0 R /Contents 5 0 R >> endobj\n")
+		data.WriteString("3 0 obj << /Type /Page /Parent 1 0 R /Contents [4 0 R] >> endobj\n")
+		for _, stream := range []struct {
+			number int
+			text   string
+		}{{4, "Second"}, {5, "First"}} {
+			fmt.Fprintf(&data, "%d 0 obj\n<< /Filter /FlateDecode >>\nstream\n", stream.number)
+			data.Write(flate(t, "BT ("+stream.text+") Tj ET"))
+			data.WriteString("\nendstream\nendobj\n")
+		}
+		data.WriteString("%%EOF\n")
+		path := filepath.Join(dir, "pages.pdf")
+		os.WriteFile(path, data.Bytes(), 0o644)
+
+		doc, err := ExtractDocument(path, 0)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if doc.Text != "First\nSecond" {
+			t.Errorf("Expected text in page order 'First\\nSecond', got '%s'", doc.Text)
+		}
+		expected := []Location{{Offset: 0, Page: 1}, {Offset: 6, Page: 2}}
+		if len(doc.Locations) != 2 || doc.Locations[0] != expected[0] || doc.Locations[1] != expected[1] {
+			t.Errorf("Expected locations %+v, got %+v", expected, doc.Locations)
+		}
+	})
+}
@@ -0,0 +1,169 @@
+package booktext
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+// container is META-INF/container.xml, pointing at the package document.
+type container struct {
+	Rootfiles []struct {
+		Path string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// packageDocument is the OPF file listing the content documents of an EPUB
+// and their reading order.
+type packageDocument struct {
+	Items []struct {
+		ID        string `xml:"id,attr"`
+		Href      string `xml:"href,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"manifest>item"`
+	Spine []struct {
+		IDRef string `xml:"idref,attr"`
+	} `xml:"spine>itemref"`
+}
+
+// extractEPUB reads the content documents of an EPUB in the order of its
+// spine, falling back to their names when it has none.
+func extractEPUB(filePath string, text *builder) error {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open EPUB: %w", err)
+	}
+	defer reader.Close()
+
+	files := make(map[string]*zip.File, len(reader.File))
+	for _, file := range reader.File {
+		files[file.Name] = file
+	}
+
+	for _, name := range readingOrder(files) {
+		file, ok := files[name]
+		if !ok {
+			continue
+		}
+		if err := extractZipped(file, text, extractHTML); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readingOrder returns the names of the content documents of an EPUB.
+func readingOrder(files map[string]*zip.File) []string {
+	var meta container
+	if err := decodeZipped(files["META-INF/container.xml"], &meta); err == nil && len(meta.Rootfiles) > 0 {
+		root := meta.Rootfiles[0].Path
+		var opf packageDocument
+		if err := decodeZipped(files[root], &opf); err == nil && len(opf.Spine) > 0 {
+			hrefs := make(map[string]string, len(opf.Items))
+			for _, item := range opf.Items {
+				hrefs[item.ID] = item.Href
+			}
+
+			order := make([]string, 0, len(opf.Spine))
+			for _, ref := range opf.Spine {
+				if href, ok := hrefs[ref.IDRef]; ok {
+					order = append(order, path.Join(path.Dir(root), href))
+				}
+			}
+			return order
+		}
+	}
+
+	order := make([]string, 0)
+	for name := range files {
+		switch strings.ToLower(path.Ext(name)) {
+		case ".xhtml", ".html", ".htm":
+			order = append(order, name)
+		}
+	}
+	sort.Strings(order)
+
+	return order
+}
+
+// decodeZipped decodes the XML file of an archive into v.
+func decodeZipped(file *zip.File, v any) error {
+	if file == nil {
+		return fmt.Errorf("file missing from the archive")
+	}
+
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return xml.NewDecoder(r).Decode(v)
+}
+
+// extractZipped passes the file of an archive to extract.
+func extractZipped(file *zip.File, text *builder, extract func(io.Reader, *builder) error) error {
+	r, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	return extract(r, text)
+}
+
+// extractDOCX reads the paragraphs of the main document of a DOCX file.
+func extractDOCX(filePath string, text *builder) error {
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open DOCX: %w", err)
+	}
+	defer reader.Close()
+
+	for _, file := range reader.File {
+		if file.Name == "word/document.xml" {
+			return extractZipped(file, text, extractWordXML)
+		}
+	}
+
+	return fmt.Errorf("no word/document.xml in DOCX")
+}
+
+// extractWordXML reads the text runs of a WordprocessingML document.
+func extractWordXML(r io.Reader, text *builder) error {
+	decoder := xml.NewDecoder(r)
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			inText = token.Name.Local == "t"
+			if token.Name.Local == "tab" {
+				text.space = true
+			}
+		case xml.EndElement:
+			inText = false
+			if token.Name.Local == "p" {
+				text.paragraph()
+			}
+		case xml.CharData:
+			if inText {
+				if err := text.write(string(token)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package booktext
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// maxPDFSize caps the PDFs read into memory.
+const maxPDFSize = 512 << 20
+
+// maxStreamSize caps the decompressed size of a single content stream.
+const maxStreamSize = 64 << 20
+
+var (
+	// streamStart matches the object number and dictionary of a stream and
+	// the start of its data.
+	streamStart = regexp.MustCompile(`(?:(\d+)\s+\d+\s+obj\s*)?<<((?:[^<>]|<<[^<>]*>>)*)>>\s*stream\r?\n`)
+	// pageNode matches the objects of the page tree, pages and their parents.
+	pageNode   = regexp.MustCompile(`(\d+)\s+\d+\s+obj\s*<<((?:[^<>]|<<(?:[^<>]|<<[^<>]*>>)*>>)*?/Type\s*/Pages?\b(?:[^<>]|<<(?:[^<>]|<<[^<>]*>>)*>>)*)>>`)
+	pagesType  = regexp.MustCompile(`/Type\s*/Pages\b`)
+	kids       = regexp.MustCompile(`/Kids\s*\[([^\]]*)\]`)
+	contents   = regexp.MustCompile(`/Contents\s*(\[[^\]]*\]|\d+\s+\d+\s+R)`)
+	references = regexp.MustCompile(`(\d+)\s+\d+\s+R`)
+)
+
+// extractPDF reads the strings shown by the text operators of the
+// Flate-encoded content streams of a PDF, page by page when its page tree can
+// be read. Fonts with custom encodings, as used by many producers for
+// subsets, do not map to readable text, so the result is only as good as the
+// PDF.
+func extractPDF(path string, text *builder) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxPDFSize+1))
+	if err != nil {
+		return err
+	}
+	if len(data) > maxPDFSize {
+		return fmt.Errorf("PDF file larger than %d MB", maxPDFSize>>20)
+	}
+
+	// Content streams, by object number and in file order, as the offset of
+	// their data
+	numbered := make(map[int]int)
+	order := make([]int, 0)
+	for _, match := range streamStart.FindAllSubmatchIndex(data, -1) {
+		dict := data[match[4]:match[5]]
+		// Images, fonts, and object streams hold no page text
+		if !bytes.Contains(dict, []byte("/FlateDecode")) || bytes.Contains(dict, []byte("/Subtype")) || bytes.Contains(dict, []byte("/Type")) {
+			continue
+		}
+		order = append(order, match[1])
+		if match[2] >= 0 {
+			number, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+			numbered[number] = match[1]
+		}
+	}
+
+	pages := pageContents(data)
+	found := false
+	for _, page := range pages {
+		for _, number := range page {
+			_, ok := numbered[number]
+			found = found || ok
+		}
+	}
+	if !found {
+		// Without a readable page tree, such as in PDFs keeping their pages
+		// in compressed object streams, file order is the best guess
+		for _, start := range order {
+			if err := showStream(data[start:], text); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for i, page := range pages {
+		text.paragraph()
+		text.mark(Location{Page: i + 1})
+		for _, number := range page {
+			if start, ok := numbered[number]; ok {
+				if err := showStream(data[start:], text); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// showStream writes the text of the Flate-encoded content stream at the start
+// of data. Streams that fail to decompress are skipped.
+func showStream(data []byte, text *builder) error {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	content, _ := io.ReadAll(io.LimitReader(reader, maxStreamSize))
+	reader.Close()
+
+	return showText(content, text)
+}
+
+// pageContents returns the object numbers of the content streams of every
+// page of a PDF, in the order of its page tree.
+func pageContents(data []byte) [][]int {
+	nodes := make(map[int][]byte)
+	children := make(map[int]bool)
+	order := make([]int, 0)
+	for _, match := range pageNode.FindAllSubmatch(data, -1) {
+		number, _ := strconv.Atoi(string(match[1]))
+		nodes[number] = match[2]
+		order = append(order, number)
+		if list := kids.FindSubmatch(match[2]); list != nil {
+			for _, kid := range refs(list[1]) {
+				children[kid] = true
+			}
+		}
+	}
+
+	pages := make([][]int, 0)
+	visited := make(map[int]bool)
+	var walk func(number int)
+	walk = func(number int) {
+		dict, ok := nodes[number]
+		if !ok || visited[number] {
+			return
+		}
+		visited[number] = true
+
+		if !pagesType.Match(dict) {
+			var streams []int
+			if list := contents.FindSubmatch(dict); list != nil {
+				streams = refs(list[1])
+			}
+			pages = append(pages, streams)
+			return
+		}
+		if list := kids.FindSubmatch(dict); list != nil {
+			for _, kid := range refs(list[1]) {
+				walk(kid)
+			}
+		}
+	}
+	// The roots of the page tree are the nodes nobody lists as a kid
+	for _, number := range order {
+		if !children[number] && pagesType.Match(nodes[number]) {
+			walk(number)
+		}
+	}
+
+	return pages
+}
+
+// refs returns the object numbers of the indirect references in list.
+func refs(list []byte) []int {
+	numbers := make([]int, 0)
+	for _, match := range references.FindAllSubmatch(list, -1) {
+		if number, err := strconv.Atoi(string(match[1])); err == nil {
+			numbers = append(numbers, number)
+		}
+	}
+
+	return numbers
+}
+
+// showText writes the operands of the text showing operators of a content
+// stream: the strings before Tj, ', and ", and those of the arrays before TJ.
+// Strings of a text object are joined, and text objects end a line.
+func showText(content []byte, text *builder) error {
+	var pending []string
+	inArray := false
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; {
+		case c == '(':
+			s, end := literalString(content, i)
+			pending = append(pending, s)
+			i = end
+		case c == '[':
+			inArray, pending = true, pending[:0]
+		case c == ']':
+			inArray = false
+		case c == '%':
+			// Comments run to the end of the line
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '/':
+			// Names, such as fonts, are not operators
+			for i+1 < len(content) && !isDelimiter(content[i+1]) {
+				i++
+			}
+		case c == '<':
+			// Hex strings need the font encoding to be read
+			for i < len(content) && content[i] != '>' {
+				i++
+			}
+		case isOperatorStart(c) && !inArray:
+			start := i
+			for i < len(content) && isOperatorStart(content[i]) {
+				i++
+			}
+			operator := string(content[start:i])
+			i--
+
+			switch operator {
+			case "Tj", "TJ", "'", `"`:
+				for _, s := range pending {
+					if err := text.write(s); err != nil {
+						return err
+					}
+				}
+				if operator != "TJ" {
+					text.space = true
+				}
+			case "Td", "TD", "T*":
+				text.space = true
+			case "ET":
+				text.paragraph()
+			case "ID":
+				// Skip the binary data of inline images
+				if end := bytes.Index(content[i:], []byte("EI")); end >= 0 {
+					i += end + 1
+				}
+			}
+			pending = pending[:0]
+		case c == '-' || (c >= '0' && c <= '9'):
+			// Large negative kerning in TJ arrays separates words
+			if inArray {
+				start := i
+				for i+1 < len(content) && (content[i+1] == '.' || (content[i+1] >= '0' && content[i+1] <= '9')) {
+					i++
+				}
+				if c == '-' && i-start >= 3 && len(pending) > 0 {
+					pending[len(pending)-1] += " "
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func isDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte(" \t\r\n\f\x00()<>[]{}/%"), c) >= 0
+}
+
+func isOperatorStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '*' || c == '\'' || c == '"'
+}
+
+// literalString decodes the PDF literal string starting at content[start],
+// returning it as Latin-1 text and the index of its closing parenthesis.
+func literalString(content []byte, start int) (string, int) {
+	var s []rune
+	depth := 0
+	for i := start; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					value := 0
+					for j := 0; j < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; j++ {
+						value = value*8 + int(content[i]-'0')
+						i++
+					}
+					i--
+					s = append(s, rune(value&0xff))
+				} else {
+					s = append(s, rune(e))
+				}
+			}
+		case c == '(':
+			if depth > 0 {
+				s = append(s, '(')
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return string(s), i
+			}
+			s = append(s, ')')
+		default:
+			s = append(s, rune(c))
+		}
+	}
+
+	return string(s), len(content)
+}
@@ -0,0 +1,865 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/container"
+	"github.com/iosifache/annas-mcp/internal/fsutil"
+	"github.com/iosifache/annas-mcp/internal/paths"
+	"github.com/spf13/pflag"
+)
+
+// ConfigFileEnv names the environment variable pointing at the config file
+// when no --config flag is given.
+const ConfigFileEnv = "ANNAS_CONFIG"
+
+// ContainerShutdownGrace is the default shutdown grace, in seconds, when
+// running in a container: it ends before Kubernetes kills a pod, 30 seconds
+// after asking it to stop.
+const ContainerShutdownGrace = 25
+
+// ProfileEnv names the environment variable selecting a profile of the config
+// file when no --profile flag is given.
+const ProfileEnv = "ANNAS_PROFILE"
+
+// ResolveFile returns the config file to load: file, else the one named by
+// ConfigFileEnv, else DefaultFile.
+func ResolveFile(file string) string {
+	if file == "" {
+		file = os.Getenv(ConfigFileEnv)
+	}
+	if file == "" {
+		file = DefaultFile()
+	}
+
+	return file
+}
+
+// DefaultFile returns the config file loaded when none is given, config.json
+// in the config directory of annas-mcp, if it exists.
+func DefaultFile() string {
+	file := paths.File(paths.Config, "config.json")
+	if file == "" {
+		return ""
+	}
+	if _, err := os.Stat(file); err != nil {
+		return ""
+	}
+
+	return file
+}
+
+// Config is the effective configuration of the server and CLI.
+//
+// Every field is resolved from the following layers, later ones winning:
+// defaults < config file < config file profile < environment < flags <
+// per-request values.
+// The layers a field participates in are declared with struct tags:
+//
+//   - json: key in the JSON config file
+//   - env: comma-separated environment variables, highest priority first;
+//     each one may also be provided as a file through its _FILE variant
+//   - flag: command-line flag name
+//   - header: comma-separated HTTP headers accepted per request, preferred
+//     over query parameters since those leak into access logs
+//   - query: comma-separated query parameters accepted per HTTP request; list
+//     fields also accept the dot+bracket style name[0], name[1], ...
+//   - default: value used when no layer sets the field
+//   - secret: masked when the configuration is printed
+//   - title, description: publish a per-request field in the session config
+//     schema, under its first query parameter; required marks it mandatory
+type Config struct {
+	SecretKey      string   `json:"secret_key" env:"ANNAS_SECRET_KEY,secretKey,SECRET_KEY" header:"X-Annas-Secret-Key" query:"secretKey,ANNAS_SECRET_KEY" secret:"true" title:"Anna's Archive API Key" description:"Your Anna's Archive API key for accessing the JSON API. Get one at https://annas-archive.org/faq#api" required:"true"`
+	SecretKeys     []string `json:"secret_keys" env:"ANNAS_SECRET_KEYS" secret:"true"`
+	DownloadPath   string   `json:"download_path" env:"ANNAS_DOWNLOAD_PATH,downloadPath" header:"X-Annas-Download-Path" query:"downloadPath,ANNAS_DOWNLOAD_PATH" default:"/tmp/downloads" title:"Download Path" description:"Path where downloaded documents will be stored"`
+	MinFreeSpaceMB int      `json:"min_free_space_mb" env:"ANNAS_MIN_FREE_SPACE_MB" default:"50"`
+	MaxFileSize    string   `json:"max_file_size" env:"ANNAS_MAX_FILE_SIZE"`
+	AllowedFormats []string `json:"allowed_formats" env:"ANNAS_ALLOWED_FORMATS"`
+	DiskQuota      string   `json:"disk_quota" env:"ANNAS_DISK_QUOTA"`
+	DiskQuotaEvict bool     `json:"disk_quota_evict" env:"ANNAS_DISK_QUOTA_EVICT"`
+	AuditLog       string   `json:"audit_log" env:"ANNAS_AUDIT_LOG"`
+	Mirrors        []string `json:"mirrors" env:"ANNAS_MIRRORS" default:"annas-archive.org"`
+	Proxy          string   `json:"proxy" env:"ANNAS_PROXY"`
+	CookieFile     string   `json:"cookie_file" env:"ANNAS_COOKIE_FILE"`
+	Locale         string   `json:"locale" env:"ANNAS_LOCALE"`
+	PrefetchCount  int      `json:"prefetch_count" env:"ANNAS_PREFETCH_COUNT"`
+	RankResults    bool     `json:"rank_results" env:"ANNAS_RANK_RESULTS" query:"rankResults" title:"Rank Results" description:"Reorder every search by how closely results match the query"`
+
+	// PreferredFormats and PreferredLangs bias the ranking of search results
+	// and the edition picked by download_best_match, most preferred first.
+	PreferredFormats []string `json:"preferred_formats" env:"ANNAS_PREFERRED_FORMATS" query:"preferredFormats" title:"Preferred Formats" description:"File formats to favor, most preferred first, for example epub and pdf"`
+	PreferredLangs   []string `json:"preferred_langs" env:"ANNAS_PREFERRED_LANGS" query:"preferredLangs" title:"Preferred Languages" description:"Languages to favor by ISO 639-1 code or name, most preferred first"`
+
+	// LibraryGCIntervalHours is how often the http mode removes abandoned
+	// temporary files and stale index entries from the download path, 0 to
+	// only do so with the library gc command.
+	LibraryGCIntervalHours int `json:"library_gc_interval_hours" env:"ANNAS_LIBRARY_GC_INTERVAL_HOURS"`
+
+	// MetadataSidecars writes the record of every saved download to a
+	// <file>.json sidecar next to it.
+	MetadataSidecars bool `json:"metadata_sidecars" env:"ANNAS_METADATA_SIDECARS"`
+
+	// LibraryLayout organizes the download path for media servers: flat,
+	// kavita (a folder per book), or calibre (author and title folders).
+	LibraryLayout string `json:"library_layout" env:"ANNAS_LIBRARY_LAYOUT" default:"flat"`
+
+	// ExtractArchives unpacks downloads that are ZIP or RAR containers, such
+	// as CBZ and CBR comics, into a folder next to them. RAR containers need
+	// the unrar binary.
+	ExtractArchives bool   `json:"extract_archives" env:"ANNAS_EXTRACT_ARCHIVES"`
+	UnrarBinary     string `json:"unrar_binary" env:"ANNAS_UNRAR_BINARY"`
+
+	// DetectLanguage samples the text of saved books to detect their language
+	// and flag records claiming another one.
+	DetectLanguage bool `json:"detect_language" env:"ANNAS_DETECT_LANGUAGE" default:"true"`
+
+	// OCR lets extract_text recognize scanned PDFs without a text layer with
+	// the tesseract binary. OCRLanguages are tesseract language codes, such
+	// as eng+deu, and OCRMaxPages bounds the pages recognized per call.
+	OCR             bool   `json:"ocr" env:"ANNAS_OCR"`
+	TesseractBinary string `json:"tesseract_binary" env:"ANNAS_TESSERACT_BINARY"`
+	OCRLanguages    string `json:"ocr_languages" env:"ANNAS_OCR_LANGUAGES"`
+	OCRMaxPages     int    `json:"ocr_max_pages" env:"ANNAS_OCR_MAX_PAGES" default:"20"`
+
+	DownloadsPerHour int `json:"downloads_per_hour" env:"ANNAS_DOWNLOADS_PER_HOUR" query:"downloadsPerHour" title:"Downloads per Hour" description:"Maximum downloads of the session per hour; only limits stricter than the server's apply"`
+	DownloadsPerDay  int `json:"downloads_per_day" env:"ANNAS_DOWNLOADS_PER_DAY" query:"downloadsPerDay" title:"Downloads per Day" description:"Maximum downloads of the session per day; only limits stricter than the server's apply"`
+
+	HTTPMaxIdleConns        int  `json:"http_max_idle_conns" env:"ANNAS_HTTP_MAX_IDLE_CONNS" default:"100"`
+	HTTPMaxIdleConnsPerHost int  `json:"http_max_idle_conns_per_host" env:"ANNAS_HTTP_MAX_IDLE_CONNS_PER_HOST" default:"10"`
+	HTTPMaxConnsPerHost     int  `json:"http_max_conns_per_host" env:"ANNAS_HTTP_MAX_CONNS_PER_HOST"`
+	HTTPIdleTimeoutSeconds  int  `json:"http_idle_timeout_seconds" env:"ANNAS_HTTP_IDLE_TIMEOUT_SECONDS" default:"90"`
+	HTTPKeepAlive           bool `json:"http_keep_alive" env:"ANNAS_HTTP_KEEP_ALIVE" default:"true"`
+	HTTP2                   bool `json:"http2" env:"ANNAS_HTTP2" default:"true"`
+	RateLimitWaitSeconds    int  `json:"rate_limit_wait_seconds" env:"ANNAS_RATE_LIMIT_WAIT_SECONDS" default:"30"`
+
+	// UserAgent is sent upstream as is when set. Otherwise the user agents of
+	// UserAgentsFile, one per line, are rotated as selected by
+	// UserAgentRotation (off, mirror, or request).
+	UserAgent         string `json:"user_agent" env:"ANNAS_USER_AGENT"`
+	UserAgentsFile    string `json:"user_agents_file" env:"ANNAS_USER_AGENTS_FILE"`
+	UserAgentRotation string `json:"user_agent_rotation" env:"ANNAS_USER_AGENT_ROTATION" default:"off"`
+
+	BreakerThreshold       int `json:"breaker_threshold" env:"ANNAS_BREAKER_THRESHOLD" default:"3"`
+	BreakerCooldownSeconds int `json:"breaker_cooldown_seconds" env:"ANNAS_BREAKER_COOLDOWN_SECONDS" default:"60"`
+
+	ProbeIntervalSeconds int      `json:"probe_interval_seconds" env:"ANNAS_PROBE_INTERVAL_SECONDS" default:"300"`
+	PartnerServers       []string `json:"partner_servers" env:"ANNAS_PARTNER_SERVERS"`
+
+	Host      string `json:"host" flag:"host" default:"0.0.0.0"`
+	Port      int    `json:"port" env:"PORT" flag:"port" default:"8080"`
+	Transport string `json:"transport" flag:"transport" default:"streamable"`
+	APIKey    string `json:"api_key" env:"SMITHERY_API_KEY" secret:"true"`
+	ReadOnly  bool   `json:"read_only" env:"ANNAS_READ_ONLY" flag:"read-only" query:"readOnly" title:"Read Only" description:"Only offer lookups, without any download tool"`
+	// PortFallback is what to do when the port is in use: "next" tries the
+	// following ports and "random" binds any free one.
+	PortFallback string `json:"port_fallback" env:"ANNAS_PORT_FALLBACK" flag:"port-fallback"`
+	// GRPC serves the gRPC facade on the HTTP port, over h2c.
+	GRPC bool `json:"grpc" env:"ANNAS_GRPC" flag:"grpc"`
+	// Stateless serves every streamable HTTP request with a temporary
+	// session and keeps nothing in memory between requests, for serverless
+	// platforms.
+	Stateless bool `json:"stateless" env:"ANNAS_STATELESS" flag:"stateless"`
+	// ShutdownGraceSeconds is how long the http mode drains on SIGTERM or
+	// SIGINT, letting in-flight requests and queued downloads finish before
+	// it exits. In containers it defaults to ContainerShutdownGrace instead.
+	ShutdownGraceSeconds int `json:"shutdown_grace_seconds" env:"ANNAS_SHUTDOWN_GRACE_SECONDS" flag:"shutdown-grace" default:"10"`
+
+	TokensFile string `json:"tokens_file" env:"ANNAS_TOKENS_FILE"`
+	// AuthProvider selects how the HTTP server authenticates clients:
+	// "keys" (the API key and tokens), "basic", "oidc", or "none".
+	AuthProvider string `json:"auth_provider" env:"ANNAS_AUTH_PROVIDER" default:"keys"`
+	OIDCIssuer   string `json:"oidc_issuer" env:"ANNAS_OIDC_ISSUER"`
+	OIDCAudience string `json:"oidc_audience" env:"ANNAS_OIDC_AUDIENCE"`
+	// PublicURL is the address clients reach the HTTP server at, when it
+	// differs from the one of incoming requests, e.g. behind a proxy.
+	PublicURL string `json:"public_url" env:"ANNAS_PUBLIC_URL"`
+	// OAuthAuthorizationServers are advertised to MCP clients as issuing
+	// tokens for the HTTP server.
+	OAuthAuthorizationServers []string `json:"oauth_authorization_servers" env:"ANNAS_OAUTH_AUTHORIZATION_SERVERS"`
+
+	// The OAuth client of the browser login at /oauth/login, whose sessions
+	// authenticate like API tokens.
+	OAuthAuthorizeURL string   `json:"oauth_authorize_url" env:"ANNAS_OAUTH_AUTHORIZE_URL"`
+	OAuthTokenURL     string   `json:"oauth_token_url" env:"ANNAS_OAUTH_TOKEN_URL"`
+	OAuthClientID     string   `json:"oauth_client_id" env:"ANNAS_OAUTH_CLIENT_ID"`
+	OAuthClientSecret string   `json:"oauth_client_secret" env:"ANNAS_OAUTH_CLIENT_SECRET" secret:"true"`
+	OAuthScopes       []string `json:"oauth_scopes" env:"ANNAS_OAUTH_SCOPES" default:"search"`
+	OAuthSessionsFile string   `json:"oauth_sessions_file" env:"ANNAS_OAUTH_SESSIONS_FILE"`
+
+	// UsageFile persists the searches and downloads of every token, so
+	// quotas survive restarts.
+	UsageFile string `json:"usage_file" env:"ANNAS_USAGE_FILE"`
+
+	WebhookURL    string `json:"webhook_url" env:"ANNAS_WEBHOOK_URL"`
+	WebhookSecret string `json:"webhook_secret" env:"ANNAS_WEBHOOK_SECRET" secret:"true"`
+	NtfyURL       string `json:"ntfy_url" env:"ANNAS_NTFY_URL"`
+	NtfyToken     string `json:"ntfy_token" env:"ANNAS_NTFY_TOKEN" secret:"true"`
+	PushoverToken string `json:"pushover_token" env:"ANNAS_PUSHOVER_TOKEN" secret:"true"`
+	PushoverUser  string `json:"pushover_user" env:"ANNAS_PUSHOVER_USER" secret:"true"`
+
+	KindleEmail  string `json:"kindle_email" env:"ANNAS_KINDLE_EMAIL"`
+	KindleFormat string `json:"kindle_format" env:"ANNAS_KINDLE_FORMAT"`
+	SMTPHost     string `json:"smtp_host" env:"ANNAS_SMTP_HOST"`
+	SMTPPort     int    `json:"smtp_port" env:"ANNAS_SMTP_PORT" default:"587"`
+	SMTPUsername string `json:"smtp_username" env:"ANNAS_SMTP_USERNAME"`
+	SMTPPassword string `json:"smtp_password" env:"ANNAS_SMTP_PASSWORD" secret:"true"`
+	SMTPFrom     string `json:"smtp_from" env:"ANNAS_SMTP_FROM"`
+
+	RcloneRemote string `json:"rclone_remote" env:"ANNAS_RCLONE_REMOTE"`
+	RcloneBinary string `json:"rclone_binary" env:"ANNAS_RCLONE_BINARY"`
+
+	// Downloader selects how files are fetched: the built-in client, or
+	// aria2c for multi-connection segmented downloads.
+	Downloader       string `json:"downloader" env:"ANNAS_DOWNLOADER" default:"builtin"`
+	Aria2Binary      string `json:"aria2_binary" env:"ANNAS_ARIA2_BINARY"`
+	Aria2RPCURL      string `json:"aria2_rpc_url" env:"ANNAS_ARIA2_RPC_URL"`
+	Aria2RPCSecret   string `json:"aria2_rpc_secret" env:"ANNAS_ARIA2_RPC_SECRET" secret:"true"`
+	Aria2Connections int    `json:"aria2_connections" env:"ANNAS_ARIA2_CONNECTIONS" default:"8"`
+
+	// DownloadServer pins the fast partner server used for downloads, by
+	// index or domain. Empty lets the fast download API pick one.
+	DownloadServer string `json:"download_server" env:"ANNAS_DOWNLOAD_SERVER" flag:"server" header:"X-Annas-Download-Server" query:"downloadServer" title:"Download Server" description:"Fast partner server to download from, by index or domain; picked by the fast download API by default"`
+
+	// OfflineIndex is the local index built from metadata dumps by
+	// "index import" and queried by offline_search, in the data directory
+	// by default.
+	OfflineIndex string `json:"offline_index" env:"ANNAS_OFFLINE_INDEX"`
+
+	// SchedulesFile stores the saved searches run by the HTTP server.
+	SchedulesFile string `json:"schedules_file" env:"ANNAS_SCHEDULES_FILE"`
+
+	HardcoverToken string `json:"hardcover_token" env:"ANNAS_HARDCOVER_TOKEN" secret:"true"`
+
+	// FixtureDir records the responses of Anna's Archive and replays them
+	// offline, as selected by FixtureMode (auto, record, or replay).
+	FixtureDir  string `json:"fixture_dir" env:"ANNAS_FIXTURE_DIR"`
+	FixtureMode string `json:"fixture_mode" env:"ANNAS_FIXTURE_MODE" default:"auto"`
+
+	// UpdateCheck looks up the latest release on GitHub in the background
+	// and warns when a newer version exists.
+	UpdateCheck bool `json:"update_check" env:"ANNAS_UPDATE_CHECK" default:"true"`
+
+	// StatsFile keeps local counts of runs, searches, and downloads per mode
+	// for the stats command, in the state directory when empty and nowhere
+	// when "off". Telemetry, off unless enabled, sends them to
+	// TelemetryURL as an anonymous ping once a day.
+	StatsFile    string `json:"stats_file" env:"ANNAS_STATS_FILE"`
+	Telemetry    bool   `json:"telemetry" env:"ANNAS_TELEMETRY"`
+	TelemetryURL string `json:"telemetry_url" env:"ANNAS_TELEMETRY_URL"`
+}
+
+// Options selects the optional layers used by Load.
+type Options struct {
+	// File is the JSON config file. When empty, ANNAS_CONFIG is consulted.
+	File string
+	// Profile selects an entry of the "profiles" object of the config file
+	// whose keys override the top-level ones. When empty, ANNAS_PROFILE is
+	// consulted.
+	Profile string
+	// Flags holds parsed command-line flags. Only flags set explicitly by the
+	// user override the lower layers.
+	Flags *pflag.FlagSet
+}
+
+// Defaults returns a configuration holding only the default values.
+func Defaults() *Config {
+	cfg := &Config{}
+	for _, f := range fields(cfg) {
+		if f.defaultValue != "" {
+			// Default tags are static and covered by tests, so they always parse
+			_ = setValue(f.value, f.defaultValue)
+		}
+	}
+	if runtime.GOOS == "windows" {
+		if path := windowsDownloadPath(os.Getenv("USERPROFILE")); path != "" {
+			cfg.DownloadPath = path
+		}
+	}
+	cfg.OfflineIndex = paths.File(paths.Data, "offline-index.gob.gz")
+	if container.Detect() != "" {
+		cfg.ShutdownGraceSeconds = ContainerShutdownGrace
+	}
+
+	return cfg
+}
+
+// windowsDownloadPath returns the default download path on Windows, which has
+// no /tmp: a folder in the Downloads of the user profile. It is empty when the
+// profile is unknown, as for some service accounts.
+func windowsDownloadPath(profile string) string {
+	if profile == "" {
+		return ""
+	}
+
+	return strings.TrimRight(profile, `\`) + `\Downloads\annas-mcp`
+}
+
+// Load resolves the configuration from defaults, the config file, the
+// environment, and flags, then validates it.
+func Load(opts Options) (*Config, error) {
+	cfg := Defaults()
+
+	file := ResolveFile(opts.File)
+	profile := opts.Profile
+	if profile == "" {
+		profile = os.Getenv(ProfileEnv)
+	}
+	if file != "" {
+		if err := cfg.loadFile(file, profile); err != nil {
+			return nil, err
+		}
+	} else if profile != "" {
+		return nil, fmt.Errorf("profile %q selected but no config file given", profile)
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if opts.Flags != nil {
+		if err := cfg.loadFlags(opts.Flags); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// WithRequest returns a copy of the configuration with the per-request values
+// of req applied on top.
+func (c *Config) WithRequest(req *http.Request) *Config {
+	clone := *c
+	query := req.URL.Query()
+
+	for _, f := range fields(&clone) {
+		values := make([]string, 0, len(f.header)+len(f.query))
+		for _, name := range f.header {
+			values = append(values, req.Header.Get(name))
+		}
+		for _, name := range f.query {
+			values = append(values, query.Get(name))
+			if f.value.Kind() == reflect.Slice {
+				values = append(values, bracketValues(query, name))
+			}
+		}
+
+		for _, val := range values {
+			if val == "" {
+				continue
+			}
+			// Invalid per-request values are ignored rather than failing the session
+			if err := setValue(f.value, val); err == nil {
+				break
+			}
+		}
+	}
+
+	// Per-request download paths come from untrusted clients, so they are
+	// resolved against the operator's download path and may not leave it
+	if clone.DownloadPath != c.DownloadPath {
+		confined, err := fsutil.Confine(c.DownloadPath, clone.DownloadPath)
+		if err != nil {
+			confined = c.DownloadPath
+		}
+		clone.DownloadPath = confined
+	}
+
+	// A caller bringing their own key must never fail over to the operator's keys
+	if clone.SecretKey != c.SecretKey {
+		clone.SecretKeys = nil
+	}
+
+	// Sessions may restrict themselves further than the operator, never less
+	clone.ReadOnly = clone.ReadOnly || c.ReadOnly
+	clone.DownloadsPerHour = stricterLimit(c.DownloadsPerHour, clone.DownloadsPerHour)
+	clone.DownloadsPerDay = stricterLimit(c.DownloadsPerDay, clone.DownloadsPerDay)
+
+	return &clone
+}
+
+// bracketValues joins the values of the dot+bracket style parameters name[0],
+// name[1], ... of query with commas, in index order.
+func bracketValues(query url.Values, name string) string {
+	parts := make([]string, 0)
+	for i := 0; ; i++ {
+		value, ok := query[fmt.Sprintf("%s[%d]", name, i)]
+		if !ok {
+			return strings.Join(parts, ",")
+		}
+		parts = append(parts, value...)
+	}
+}
+
+// stricterLimit returns the requested limit when it is stricter than the
+// base one, where 0 is unlimited, and the base one otherwise.
+func stricterLimit(base, requested int) int {
+	if requested <= 0 || (base > 0 && requested > base) {
+		return base
+	}
+
+	return requested
+}
+
+// SessionSchema returns the JSON schema properties of the settings HTTP
+// clients may pass per session, as the fields with a title tag, along with
+// the names of the required ones.
+func SessionSchema() (map[string]interface{}, []string) {
+	defaults := Defaults()
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+	for _, f := range fields(defaults) {
+		if f.title == "" || len(f.query) == 0 {
+			continue
+		}
+
+		property := map[string]interface{}{
+			"title":       f.title,
+			"description": f.description,
+		}
+		switch f.value.Kind() {
+		case reflect.Int, reflect.Int64:
+			property["type"] = "integer"
+			property["minimum"] = 0
+		case reflect.Bool:
+			property["type"] = "boolean"
+		case reflect.Slice:
+			property["type"] = "array"
+			property["items"] = map[string]interface{}{"type": "string"}
+		default:
+			property["type"] = "string"
+		}
+		if f.defaultValue != "" {
+			property["default"] = f.value.Interface()
+		}
+		if len(f.header) > 0 {
+			property["x-header"] = f.header[0]
+		}
+
+		name := f.query[0]
+		properties[name] = property
+		if f.required {
+			required = append(required, name)
+		}
+	}
+
+	return properties, required
+}
+
+// Keys returns all configured secret keys in failover order: the primary key
+// first, followed by the additional keys without duplicates.
+func (c *Config) Keys() []string {
+	keys := make([]string, 0, len(c.SecretKeys)+1)
+	seen := make(map[string]bool)
+	for _, key := range append([]string{c.SecretKey}, c.SecretKeys...) {
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// EnvNames returns the environment variables the settings are read from,
+// each followed by its _FILE variant.
+func EnvNames() []string {
+	var names []string
+	for _, f := range fields(&Config{}) {
+		for _, name := range f.env {
+			names = append(names, name, name+"_FILE")
+		}
+	}
+
+	return names
+}
+
+// Validate reports settings that are set but unusable.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Transport != "sse" && c.Transport != "streamable" && c.Transport != "websocket" {
+		errs = append(errs, fmt.Errorf("invalid transport type: %s (must be 'sse', 'streamable', or 'websocket')", c.Transport))
+	}
+	if c.PortFallback != "" && c.PortFallback != "next" && c.PortFallback != "random" {
+		errs = append(errs, fmt.Errorf("invalid port fallback: %s (must be 'next' or 'random')", c.PortFallback))
+	}
+	if c.Stateless && c.Transport != "streamable" {
+		errs = append(errs, fmt.Errorf("stateless mode needs the streamable transport, not %s", c.Transport))
+	}
+	if c.Port <= 0 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("invalid port: %d", c.Port))
+	}
+	if c.SMTPPort <= 0 || c.SMTPPort > 65535 {
+		errs = append(errs, fmt.Errorf("invalid SMTP port: %d", c.SMTPPort))
+	}
+	if c.MinFreeSpaceMB < 0 {
+		errs = append(errs, fmt.Errorf("invalid minimum free space: %d", c.MinFreeSpaceMB))
+	}
+	if c.MaxFileSize != "" {
+		if _, err := fsutil.ParseSize(c.MaxFileSize); err != nil {
+			errs = append(errs, fmt.Errorf("invalid maximum file size: %w", err))
+		}
+	}
+	if c.DiskQuota != "" {
+		if _, err := fsutil.ParseSize(c.DiskQuota); err != nil {
+			errs = append(errs, fmt.Errorf("invalid disk quota: %w", err))
+		}
+	}
+	if c.HTTPMaxIdleConns < 0 || c.HTTPMaxIdleConnsPerHost < 0 || c.HTTPMaxConnsPerHost < 0 || c.HTTPIdleTimeoutSeconds < 0 {
+		errs = append(errs, errors.New("HTTP connection pool settings must not be negative"))
+	}
+	if c.RateLimitWaitSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid rate limit wait: %d (must not be negative)", c.RateLimitWaitSeconds))
+	}
+	if c.BreakerThreshold <= 0 || c.BreakerCooldownSeconds <= 0 {
+		errs = append(errs, errors.New("circuit breaker threshold and cooldown must be positive"))
+	}
+	if c.ShutdownGraceSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid shutdown grace: %d (must not be negative)", c.ShutdownGraceSeconds))
+	}
+	if c.ProbeIntervalSeconds < 0 {
+		errs = append(errs, fmt.Errorf("invalid probe interval: %d", c.ProbeIntervalSeconds))
+	}
+	if c.DownloadsPerHour < 0 || c.DownloadsPerDay < 0 {
+		errs = append(errs, errors.New("download rate limits must not be negative"))
+	}
+	if c.LibraryGCIntervalHours < 0 {
+		errs = append(errs, fmt.Errorf("invalid library GC interval: %d", c.LibraryGCIntervalHours))
+	}
+	if c.PrefetchCount < 0 {
+		errs = append(errs, fmt.Errorf("invalid prefetch count: %d", c.PrefetchCount))
+	}
+	if c.OCRMaxPages < 1 {
+		errs = append(errs, fmt.Errorf("invalid OCR max pages: %d", c.OCRMaxPages))
+	}
+	if c.LibraryLayout != "flat" && c.LibraryLayout != "kavita" && c.LibraryLayout != "calibre" {
+		errs = append(errs, fmt.Errorf("invalid library layout: %s (must be 'flat', 'kavita', or 'calibre')", c.LibraryLayout))
+	}
+	if c.Downloader != "builtin" && c.Downloader != "aria2c" {
+		errs = append(errs, fmt.Errorf("invalid downloader: %s (must be 'builtin' or 'aria2c')", c.Downloader))
+	}
+	if c.UserAgentRotation != "off" && c.UserAgentRotation != "mirror" && c.UserAgentRotation != "request" {
+		errs = append(errs, fmt.Errorf("invalid user agent rotation: %s (must be 'off', 'mirror', or 'request')", c.UserAgentRotation))
+	}
+	if c.FixtureMode != "auto" && c.FixtureMode != "record" && c.FixtureMode != "replay" {
+		errs = append(errs, fmt.Errorf("invalid fixture mode: %s (must be 'auto', 'record', or 'replay')", c.FixtureMode))
+	}
+	if c.Aria2Connections < 1 || c.Aria2Connections > 16 {
+		errs = append(errs, fmt.Errorf("invalid aria2 connections: %d (must be between 1 and 16)", c.Aria2Connections))
+	}
+	if c.DownloadPath == "" {
+		errs = append(errs, errors.New("download path must not be empty"))
+	}
+	if c.Proxy != "" {
+		if u, err := url.Parse(c.Proxy); err != nil || u.Host == "" {
+			errs = append(errs, fmt.Errorf("invalid proxy URL: %s", c.Proxy))
+		}
+	}
+	switch c.AuthProvider {
+	case "keys", "none":
+	case "basic":
+		if c.APIKey == "" && c.TokensFile == "" {
+			errs = append(errs, errors.New("basic authentication needs SMITHERY_API_KEY or ANNAS_TOKENS_FILE"))
+		}
+	case "oidc":
+		if u, err := url.Parse(c.OIDCIssuer); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("OIDC authentication needs the issuer URL in ANNAS_OIDC_ISSUER, got %q", c.OIDCIssuer))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("invalid auth provider: %s (must be 'keys', 'basic', 'oidc', or 'none')", c.AuthProvider))
+	}
+	if c.OAuthAuthorizeURL != "" || c.OAuthTokenURL != "" {
+		for _, endpoint := range []struct{ name, url string }{{"authorize", c.OAuthAuthorizeURL}, {"token", c.OAuthTokenURL}} {
+			if u, err := url.Parse(endpoint.url); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+				errs = append(errs, fmt.Errorf("invalid OAuth %s URL: %q", endpoint.name, endpoint.url))
+			}
+		}
+		if c.OAuthClientID == "" {
+			errs = append(errs, errors.New("OAuth login needs the client ID in ANNAS_OAUTH_CLIENT_ID"))
+		}
+	}
+	if c.PublicURL != "" {
+		if u, err := url.Parse(c.PublicURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("invalid public URL: %s", c.PublicURL))
+		}
+	}
+	if c.Telemetry {
+		if u, err := url.Parse(c.TelemetryURL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("telemetry needs the endpoint URL in ANNAS_TELEMETRY_URL, got %q", c.TelemetryURL))
+		}
+	}
+	for _, server := range c.OAuthAuthorizationServers {
+		if u, err := url.Parse(server); err != nil || u.Host == "" || u.Scheme != "https" {
+			errs = append(errs, fmt.Errorf("invalid OAuth authorization server: %s (must be an https URL)", server))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// MaxFileSizeBytes returns the maximum size of downloaded files, or 0 when
+// downloads are not limited.
+func (c *Config) MaxFileSizeBytes() int64 {
+	size, err := fsutil.ParseSize(c.MaxFileSize)
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+// DiskQuotaBytes returns the maximum total size of the books saved to the
+// download path, or 0 when it is not limited.
+func (c *Config) DiskQuotaBytes() int64 {
+	size, err := fsutil.ParseSize(c.DiskQuota)
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+// Masked returns the configuration keyed by config file names, with secrets
+// replaced so it can be printed or logged safely.
+func (c *Config) Masked() map[string]any {
+	masked := make(map[string]any)
+	for _, f := range fields(c) {
+		value := f.value.Interface()
+		if f.secret {
+			if list, ok := value.([]string); ok {
+				maskedList := make([]string, len(list))
+				for i, item := range list {
+					maskedList[i] = mask(item)
+				}
+				value = maskedList
+			} else {
+				value = mask(f.value.String())
+			}
+		}
+		masked[f.json] = value
+	}
+
+	return masked
+}
+
+// Export returns the settings of the configuration that differ from the
+// defaults, keyed by config file names, so they can be written to a config
+// file. Secrets are left out unless secrets is set.
+func (c *Config) Export(secrets bool) map[string]any {
+	defaults := fields(Defaults())
+
+	exported := make(map[string]any)
+	for i, f := range fields(c) {
+		if f.secret && !secrets {
+			continue
+		}
+		value, fallback := f.value.Interface(), defaults[i].value.Interface()
+		if reflect.DeepEqual(value, fallback) || (f.value.Kind() == reflect.Slice && f.value.Len() == 0 && defaults[i].value.Len() == 0) {
+			continue
+		}
+		exported[f.json] = value
+	}
+
+	return exported
+}
+
+func (c *Config) loadFile(path, profile string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	var profiles map[string]json.RawMessage
+	if raw, ok := file["profiles"]; ok {
+		if err := json.Unmarshal(raw, &profiles); err != nil {
+			return fmt.Errorf("failed to parse profiles of config file %s: %w", path, err)
+		}
+		delete(file, "profiles")
+	}
+
+	base, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	if err := decodeStrict(base, c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if profile == "" {
+		return nil
+	}
+	raw, ok := profiles[profile]
+	if !ok {
+		return fmt.Errorf("profile %q not found in config file %s", profile, path)
+	}
+	if err := decodeStrict(raw, c); err != nil {
+		return fmt.Errorf("failed to parse profile %q of config file %s: %w", profile, path, err)
+	}
+
+	return nil
+}
+
+func decodeStrict(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
+
+func (c *Config) loadEnv() error {
+	for _, f := range fields(c) {
+		for _, name := range f.env {
+			val, err := lookupEnv(name)
+			if err != nil {
+				return err
+			}
+			if val == "" {
+				continue
+			}
+			if err := setValue(f.value, val); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", name, err)
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) loadFlags(flags *pflag.FlagSet) error {
+	for _, f := range fields(c) {
+		if f.flag == "" || !flags.Changed(f.flag) {
+			continue
+		}
+		if err := setValue(f.value, flags.Lookup(f.flag).Value.String()); err != nil {
+			return fmt.Errorf("invalid value for --%s: %w", f.flag, err)
+		}
+	}
+
+	return nil
+}
+
+// lookupEnv returns the value of the environment variable name or, when it is
+// unset, the trimmed contents of the file referenced by name_FILE. This lets
+// containerized deployments mount secrets as files (Docker/Kubernetes secrets).
+func lookupEnv(name string) (string, error) {
+	if value := os.Getenv(name); value != "" {
+		return value, nil
+	}
+
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "********"
+}
+
+type field struct {
+	json         string
+	env          []string
+	header       []string
+	query        []string
+	flag         string
+	defaultValue string
+	secret       bool
+	title        string
+	description  string
+	required     bool
+	value        reflect.Value
+}
+
+func fields(c *Config) []field {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+
+	result := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag
+		result = append(result, field{
+			json:         tag.Get("json"),
+			env:          splitTag(tag.Get("env")),
+			header:       splitTag(tag.Get("header")),
+			query:        splitTag(tag.Get("query")),
+			flag:         tag.Get("flag"),
+			defaultValue: tag.Get("default"),
+			secret:       tag.Get("secret") == "true",
+			title:        tag.Get("title"),
+			description:  tag.Get("description"),
+			required:     tag.Get("required") == "true",
+			value:        v.Field(i),
+		})
+	}
+
+	return result
+}
+
+func splitTag(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+func setValue(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, 64)
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		parts := make([]string, 0)
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		v.Set(reflect.ValueOf(parts))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Kind())
+	}
+
+	return nil
+}
@@ -0,0 +1,272 @@
+package config
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		cfg, err := Load(Options{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.DownloadPath != "/tmp/downloads" || cfg.Port != 8080 || cfg.Transport != "streamable" || cfg.SMTPPort != 587 {
+			t.Errorf("Unexpected defaults: %+v", cfg)
+		}
+	})
+
+	t.Run("Default File", func(t *testing.T) {
+		dir := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", dir)
+		t.Setenv("XDG_DATA_HOME", dir)
+		os.MkdirAll(filepath.Join(dir, "annas-mcp"), 0o755)
+		os.WriteFile(filepath.Join(dir, "annas-mcp", "config.json"), []byte(`{"port": 9000}`), 0o600)
+
+		cfg, err := Load(Options{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.Port != 9000 {
+			t.Errorf("Expected Port 9000 from the config directory, got %d", cfg.Port)
+		}
+		if cfg.OfflineIndex != filepath.Join(dir, "annas-mcp", "offline-index.gob.gz") {
+			t.Errorf("Expected the offline index in the data directory, got '%s'", cfg.OfflineIndex)
+		}
+	})
+
+	t.Run("Layer Order", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		content := `{"secret_key": "fileSecret", "download_path": "filePath", "port": 9000}`
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		os.Setenv("ANNAS_DOWNLOAD_PATH", "envPath")
+		os.Setenv("PORT", "9001")
+		defer os.Unsetenv("ANNAS_DOWNLOAD_PATH")
+		defer os.Unsetenv("PORT")
+
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.Int("port", 8080, "")
+		flags.Parse([]string{"--port", "9002"})
+
+		cfg, err := Load(Options{File: file, Flags: flags})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.SecretKey != "fileSecret" {
+			t.Errorf("Expected SecretKey 'fileSecret' from file, got '%s'", cfg.SecretKey)
+		}
+		if cfg.DownloadPath != "envPath" {
+			t.Errorf("Expected DownloadPath 'envPath' from env, got '%s'", cfg.DownloadPath)
+		}
+		if cfg.Port != 9002 {
+			t.Errorf("Expected Port 9002 from flags, got %d", cfg.Port)
+		}
+
+		req, _ := http.NewRequest("GET", "http://example.com?downloadPath=queryPath", nil)
+		if got := cfg.WithRequest(req).DownloadPath; got != filepath.Join("envPath", "queryPath") {
+			t.Errorf("Expected DownloadPath 'envPath/queryPath' from request, got '%s'", got)
+		}
+		if cfg.DownloadPath != "envPath" {
+			t.Error("WithRequest must not modify the base configuration")
+		}
+	})
+
+	t.Run("Unknown File Key", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		os.WriteFile(file, []byte(`{"secret": "typo"}`), 0o600)
+
+		if _, err := Load(Options{File: file}); err == nil {
+			t.Error("Expected error for unknown config key, got nil")
+		}
+	})
+
+	t.Run("Profile", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "config.json")
+		content := `{
+			"secret_key": "baseSecret",
+			"download_path": "basePath",
+			"profiles": {
+				"work": {"secret_key": "workSecret", "mirrors": ["annas-archive.se"], "proxy": "socks5://127.0.0.1:1080"}
+			}
+		}`
+		if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+
+		cfg, err := Load(Options{File: file})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.SecretKey != "baseSecret" || cfg.Mirrors[0] != "annas-archive.org" {
+			t.Errorf("Expected base values without a profile, got '%s' and %v", cfg.SecretKey, cfg.Mirrors)
+		}
+
+		os.Setenv("ANNAS_PROFILE", "work")
+		defer os.Unsetenv("ANNAS_PROFILE")
+
+		cfg, err = Load(Options{File: file})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if cfg.SecretKey != "workSecret" {
+			t.Errorf("Expected SecretKey 'workSecret' from profile, got '%s'", cfg.SecretKey)
+		}
+		if cfg.DownloadPath != "basePath" {
+			t.Errorf("Expected DownloadPath 'basePath' inherited from base, got '%s'", cfg.DownloadPath)
+		}
+		if len(cfg.Mirrors) != 1 || cfg.Mirrors[0] != "annas-archive.se" || cfg.Proxy != "socks5://127.0.0.1:1080" {
+			t.Errorf("Expected mirrors and proxy from profile, got %v and '%s'", cfg.Mirrors, cfg.Proxy)
+		}
+
+		if _, err := Load(Options{File: file, Profile: "home"}); err == nil {
+			t.Error("Expected error for unknown profile, got nil")
+		}
+	})
+
+	t.Run("Invalid Transport", func(t *testing.T) {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.String("transport", "streamable", "")
+		flags.Parse([]string{"--transport", "carrier-pigeon"})
+
+		if _, err := Load(Options{Flags: flags}); err == nil {
+			t.Error("Expected error for invalid transport, got nil")
+		}
+	})
+
+	t.Run("Stateless SSE", func(t *testing.T) {
+		flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+		flags.String("transport", "streamable", "")
+		flags.Parse([]string{"--transport", "sse"})
+		t.Setenv("ANNAS_STATELESS", "true")
+
+		if _, err := Load(Options{Flags: flags}); err == nil {
+			t.Error("Expected error for stateless mode over SSE, got nil")
+		}
+	})
+}
+
+func TestWithRequestDownloadPath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.Symlink(outside, filepath.Join(root, "link"))
+
+	cfg := Defaults()
+	cfg.DownloadPath = root
+
+	cases := map[string]string{
+		"nested":                   filepath.Join(root, "nested"),
+		filepath.Join(root, "abs"): filepath.Join(root, "abs"),
+		"../escape":                root,
+		outside:                    root,
+		"link/inside-symlink":      root,
+	}
+	for requested, expected := range cases {
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		req.Header.Set("X-Annas-Download-Path", requested)
+		if got := cfg.WithRequest(req).DownloadPath; got != expected {
+			t.Errorf("Expected DownloadPath '%s' for '%s', got '%s'", expected, requested, got)
+		}
+	}
+}
+
+func TestWithRequestSessionSettings(t *testing.T) {
+	cfg := Defaults()
+	cfg.DownloadsPerHour = 10
+
+	req, _ := http.NewRequest("GET", "http://example.com?preferredFormats[0]=epub&preferredFormats[1]=pdf&readOnly=true&downloadsPerHour=50&downloadsPerDay=5", nil)
+	session := cfg.WithRequest(req)
+	if len(session.PreferredFormats) != 2 || session.PreferredFormats[0] != "epub" || session.PreferredFormats[1] != "pdf" {
+		t.Errorf("Expected PreferredFormats [epub pdf], got %v", session.PreferredFormats)
+	}
+	if !session.ReadOnly {
+		t.Error("Expected the session to be read-only")
+	}
+	if session.DownloadsPerHour != 10 || session.DownloadsPerDay != 5 {
+		t.Errorf("Expected the stricter limits 10 and 5, got %d and %d", session.DownloadsPerHour, session.DownloadsPerDay)
+	}
+
+	cfg.ReadOnly = true
+	req, _ = http.NewRequest("GET", "http://example.com?readOnly=false", nil)
+	if !cfg.WithRequest(req).ReadOnly {
+		t.Error("Expected a session not to lift read-only mode")
+	}
+}
+
+func TestSessionSchema(t *testing.T) {
+	properties, required := SessionSchema()
+	if len(required) != 1 || required[0] != "secretKey" {
+		t.Errorf("Expected only secretKey to be required, got %v", required)
+	}
+
+	path, ok := properties["downloadPath"].(map[string]interface{})
+	if !ok || path["default"] != "/tmp/downloads" || path["x-header"] != "X-Annas-Download-Path" {
+		t.Errorf("Expected downloadPath with its default and header, got %v", properties["downloadPath"])
+	}
+	for name, kind := range map[string]string{"preferredFormats": "array", "readOnly": "boolean", "downloadsPerHour": "integer"} {
+		if property, ok := properties[name].(map[string]interface{}); !ok || property["type"] != kind {
+			t.Errorf("Expected %s of type '%s', got %v", name, kind, properties[name])
+		}
+	}
+}
+
+func TestMasked(t *testing.T) {
+	cfg := Defaults()
+	cfg.SecretKey = "supersecret"
+
+	masked := cfg.Masked()
+	if masked["secret_key"] != "********" {
+		t.Errorf("Expected secret_key to be masked, got '%v'", masked["secret_key"])
+	}
+	if masked["download_path"] != "/tmp/downloads" {
+		t.Errorf("Expected download_path to be shown, got '%v'", masked["download_path"])
+	}
+	if masked["api_key"] != "" {
+		t.Errorf("Expected unset api_key to stay empty, got '%v'", masked["api_key"])
+	}
+}
+
+func TestExport(t *testing.T) {
+	cfg := Defaults()
+	cfg.SecretKey = "supersecret"
+	cfg.AuditLog = "/var/log/annas/audit.log"
+
+	exported := cfg.Export(false)
+	if len(exported) != 1 || exported["audit_log"] != "/var/log/annas/audit.log" {
+		t.Errorf("Expected only audit_log to be exported, got %v", exported)
+	}
+	if exported := cfg.Export(true); exported["secret_key"] != "supersecret" {
+		t.Errorf("Expected secret_key 'supersecret' with secrets, got '%v'", exported["secret_key"])
+	}
+}
+
+func TestKeys(t *testing.T) {
+	cfg := Defaults()
+	cfg.SecretKey = "primary"
+	cfg.SecretKeys = []string{"second", "primary", "third"}
+
+	keys := cfg.Keys()
+	if len(keys) != 3 || keys[0] != "primary" || keys[1] != "second" || keys[2] != "third" {
+		t.Errorf("Expected [primary second third], got %v", keys)
+	}
+
+	req, _ := http.NewRequest("GET", "http://example.com?secretKey=tenant", nil)
+	if keys := cfg.WithRequest(req).Keys(); len(keys) != 1 || keys[0] != "tenant" {
+		t.Errorf("Expected a per-request key to replace the operator keys, got %v", keys)
+	}
+}
+
+func TestWindowsDownloadPath(t *testing.T) {
+	if got := windowsDownloadPath(`C:\Users\reader\`); got != `C:\Users\reader\Downloads\annas-mcp` {
+		t.Errorf("Expected the Downloads of the profile, got '%s'", got)
+	}
+	if got := windowsDownloadPath(""); got != "" {
+		t.Errorf("Expected no path without a profile, got '%s'", got)
+	}
+}
@@ -0,0 +1,55 @@
+// Package container detects whether annas-mcp runs in a container, where it
+// is stopped with SIGTERM and killed shortly after, such as a Kubernetes pod
+// during a rolling update.
+package container
+
+import (
+	"os"
+	"strings"
+)
+
+// Runtimes detected by Detect.
+const (
+	Kubernetes = "kubernetes"
+	Docker     = "docker"
+	Podman     = "podman"
+	// Other is a container whose runtime is not recognized
+	Other = "container"
+)
+
+// Detect returns the container runtime annas-mcp runs in, or an empty string
+// outside of containers.
+func Detect() string {
+	return detect(os.Getenv, func(name string) bool {
+		_, err := os.Stat(name)
+		return err == nil
+	}, func(name string) string {
+		data, _ := os.ReadFile(name)
+		return string(data)
+	})
+}
+
+// detect looks at the environment, the marker files runtimes create, and the
+// control groups of the process. Kubernetes wins over the runtime running
+// its pods.
+func detect(getenv func(string) string, exists func(string) bool, read func(string) string) string {
+	if getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return Kubernetes
+	}
+
+	cgroup := read("/proc/self/cgroup")
+	switch {
+	case strings.Contains(cgroup, "kubepods"):
+		return Kubernetes
+	case exists("/.dockerenv") || strings.Contains(cgroup, "docker"):
+		return Docker
+	case exists("/run/.containerenv") || getenv("container") == "podman":
+		return Podman
+	// Set by systemd-nspawn, LXC, and the container images of most
+	// distributions
+	case getenv("container") != "" || strings.Contains(cgroup, "containerd") || strings.Contains(cgroup, "lxc"):
+		return Other
+	}
+
+	return ""
+}
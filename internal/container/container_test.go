@@ -0,0 +1,29 @@
+package container
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name     string
+		env      map[string]string
+		files    map[string]string
+		expected string
+	}{
+		{"host", nil, map[string]string{"/proc/self/cgroup": "0::/user.slice/user-1000.slice/session-2.scope\n"}, ""},
+		{"kubernetes", map[string]string{"KUBERNETES_SERVICE_HOST": "10.96.0.1"}, map[string]string{"/.dockerenv": ""}, Kubernetes},
+		{"pod cgroup", nil, map[string]string{"/proc/self/cgroup": "0::/kubepods/besteffort/pod1234/abcd\n"}, Kubernetes},
+		{"docker", nil, map[string]string{"/.dockerenv": ""}, Docker},
+		{"podman", nil, map[string]string{"/run/.containerenv": ""}, Podman},
+		{"nspawn", map[string]string{"container": "systemd-nspawn"}, nil, Other},
+	}
+	for _, c := range cases {
+		got := detect(
+			func(name string) string { return c.env[name] },
+			func(name string) bool { _, ok := c.files[name]; return ok },
+			func(name string) string { return c.files[name] },
+		)
+		if got != c.expected {
+			t.Errorf("Expected '%s' for the %s case, got '%s'", c.expected, c.name, got)
+		}
+	}
+}
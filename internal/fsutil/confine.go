@@ -0,0 +1,88 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Confine resolves path against root and verifies that the result stays
+// inside root, also after following symlinks. Relative paths are taken
+// relative to root. The returned path is cleaned but not symlink-resolved.
+func Confine(root, path string) (string, error) {
+	root = filepath.Clean(root)
+	if !filepath.IsAbs(path) {
+		// On Windows, "D:books" is relative to the working directory of
+		// drive D: rather than to root
+		if filepath.VolumeName(path) != "" {
+			return "", fmt.Errorf("%s is relative to a drive, not to %s", path, root)
+		}
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+
+	if !within(root, path) {
+		return "", fmt.Errorf("%s is outside of %s", path, root)
+	}
+
+	realRoot, err := resolveExisting(root)
+	if err != nil {
+		return "", err
+	}
+	realPath, err := resolveExisting(path)
+	if err != nil {
+		return "", err
+	}
+	if !within(realRoot, realPath) {
+		return "", fmt.Errorf("%s resolves to %s, outside of %s", path, realPath, realRoot)
+	}
+
+	return path, nil
+}
+
+// SafeJoin joins a single file name to dir, rejecting names that would escape
+// it such as "..", absolute paths, or names containing separators.
+func SafeJoin(dir, name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("invalid file name %q", name)
+	}
+
+	return filepath.Join(dir, name), nil
+}
+
+// within reports whether path is root or lies beneath it. Both must be clean.
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+// resolveExisting follows the symlinks of the longest existing prefix of path
+// and appends the remaining, not yet created, components.
+func resolveExisting(path string) (string, error) {
+	missing := make([]string, 0)
+	current := path
+	for {
+		resolved, err := filepath.EvalSymlinks(current)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				resolved = filepath.Join(resolved, missing[i])
+			}
+			return resolved, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			return path, nil
+		}
+		missing = append(missing, filepath.Base(current))
+		current = parent
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package fsutil
+
+import "syscall"
+
+// FreeSpace returns the number of bytes available to unprivileged users on
+// the filesystem holding path.
+func FreeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
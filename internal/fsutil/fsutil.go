@@ -0,0 +1,31 @@
+package fsutil
+
+import (
+	"fmt"
+	"os"
+)
+
+// EnsureWritableDir creates path if needed and verifies that files can be
+// created in it.
+func EnsureWritableDir(path string) error {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return fmt.Errorf("cannot create directory %s: %w", path, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+
+	probe, err := os.CreateTemp(path, ".annas-mcp-write-test-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", path, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return nil
+}
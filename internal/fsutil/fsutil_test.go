@@ -0,0 +1,125 @@
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestEnsureWritableDir(t *testing.T) {
+	t.Run("Creates Missing Directory", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nested", "downloads")
+		if err := EnsureWritableDir(path); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		entries, _ := os.ReadDir(path)
+		if len(entries) != 0 {
+			t.Errorf("Expected write probe to be removed, found %d entries", len(entries))
+		}
+	})
+
+	t.Run("Rejects File", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+		os.WriteFile(path, []byte("x"), 0o644)
+
+		if err := EnsureWritableDir(path); err == nil {
+			t.Error("Expected error for regular file, got nil")
+		}
+	})
+}
+
+func TestFreeSpace(t *testing.T) {
+	free, err := FreeSpace(t.TempDir())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if free == 0 {
+		t.Error("Expected non-zero free space")
+	}
+}
+
+func TestConfine(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	os.Symlink(outside, filepath.Join(root, "link"))
+
+	if got, err := Confine(root, "a/b"); err != nil || got != filepath.Join(root, "a", "b") {
+		t.Errorf("Expected '%s', got '%s' (%v)", filepath.Join(root, "a", "b"), got, err)
+	}
+
+	for _, path := range []string{"..", "a/../../b", outside, "link", "link/new"} {
+		if _, err := Confine(root, path); err == nil {
+			t.Errorf("Expected error for '%s', got nil", path)
+		}
+	}
+}
+
+func TestSafeJoin(t *testing.T) {
+	if got, err := SafeJoin("/root", "book.epub"); err != nil || got != filepath.Join("/root", "book.epub") {
+		t.Errorf("Expected '/root/book.epub', got '%s' (%v)", got, err)
+	}
+
+	for _, name := range []string{"", ".", "..", "../x", "/etc/passwd", `a\b`} {
+		if _, err := SafeJoin("/root", name); err == nil {
+			t.Errorf("Expected error for '%s', got nil", name)
+		}
+	}
+}
+
+func TestWindowsName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Dune: Messiah":     "Dune_ Messiah",
+		`What? "Why" <Who>`: "What_ _Why_ _Who_",
+		"con":               "con_",
+		"LPT1.notes":        "LPT1_.notes",
+		"Aux ":              "Aux_",
+		"Console":           "Console",
+		"trailing...":       "trailing",
+	} {
+		if got := WindowsName(name); got != want {
+			t.Errorf("Expected '%s' for '%s', got '%s'", want, name, got)
+		}
+	}
+}
+
+func TestFitName(t *testing.T) {
+	dir := `C:\Users\reader\Downloads\annas-mcp`
+	if got := FitName(dir, "Dune.epub", MaxWindowsPath); got != "Dune.epub" {
+		t.Errorf("Expected a short name to be kept, got '%s'", got)
+	}
+
+	long := strings.Repeat("Долгий путь ", 30) + ".epub"
+	got := FitName(dir, long, MaxWindowsPath)
+	if pathLength(dir)+1+pathLength(got) > MaxWindowsPath || !strings.HasSuffix(got, ".epub") || !utf8.ValidString(got) {
+		t.Errorf("Expected the name to fit with its extension, got '%s'", got)
+	}
+
+	if got := FitName(strings.Repeat("x", MaxWindowsPath), "Dune.epub", MaxWindowsPath); got != "Dune.epub" {
+		t.Errorf("Expected a name that cannot fit to be kept, got '%s'", got)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"0.5MB": 512 << 10,
+		"2 gb":  2 << 30,
+		"100KB": 100 << 10,
+		"42":    42,
+	}
+	for size, expected := range cases {
+		if got, err := ParseSize(size); err != nil || got != expected {
+			t.Errorf("Expected %d bytes for '%s', got %d (%v)", expected, size, got, err)
+		}
+	}
+
+	if _, err := ParseSize("lots"); err == nil {
+		t.Error("Expected error for invalid size, got nil")
+	}
+
+	if got := FormatSize(2621440); got != "2.5MB" {
+		t.Errorf("Expected '2.5MB', got '%s'", got)
+	}
+}
@@ -0,0 +1,99 @@
+package fsutil
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// MaxWindowsPath is the longest path, in UTF-16 code units and without the
+// terminating NUL, that most Windows programs can open.
+const MaxWindowsPath = 259
+
+// windowsInvalid are the characters Windows refuses in file names, besides
+// the separators and control characters.
+const windowsInvalid = `<>:"|?*`
+
+// reservedNames are the device names Windows refuses as file names, with any
+// extension and in any case.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"COM¹": true, "COM²": true, "COM³": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+	"LPT¹": true, "LPT²": true, "LPT³": true,
+}
+
+// ReservedName reports whether Windows reserves name, such as "con" or
+// "LPT1.txt", for a device.
+func ReservedName(name string) bool {
+	stem, _, _ := strings.Cut(name, ".")
+	return reservedNames[strings.ToUpper(strings.TrimRight(stem, " "))]
+}
+
+// WindowsName makes name valid as a Windows file name: the characters
+// Windows refuses are replaced with underscores, trailing dots and spaces are
+// removed, and an underscore is added to the stem of reserved device names.
+func WindowsName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(windowsInvalid, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	name = strings.TrimRight(name, ". ")
+
+	if ReservedName(name) {
+		stem, ext, found := strings.Cut(name, ".")
+		name = strings.TrimRight(stem, " ") + "_"
+		if found {
+			name += "." + ext
+		}
+	}
+
+	return name
+}
+
+// FitName shortens name so that dir joined with it is at most max UTF-16 code
+// units long, the unit Windows measures paths in. The stem is cut and the
+// extension kept; names that cannot fit are returned unchanged.
+func FitName(dir, name string, max int) string {
+	available := max - pathLength(dir) - 1
+	if pathLength(name) <= available {
+		return name
+	}
+
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	budget := available - pathLength(ext)
+	if budget < 1 {
+		return name
+	}
+
+	length := 0
+	for i, r := range stem {
+		size := utf16.RuneLen(r)
+		if length+size > budget {
+			stem = stem[:i]
+			break
+		}
+		length += size
+	}
+	stem = strings.TrimRight(stem, ". ")
+	if stem == "" {
+		return name
+	}
+
+	return stem + ext
+}
+
+// pathLength returns the length of s in UTF-16 code units. Invalid bytes
+// decode to U+FFFD, a single unit.
+func pathLength(s string) int {
+	length := 0
+	for _, r := range s {
+		length += utf16.RuneLen(r)
+	}
+
+	return length
+}
@@ -0,0 +1,53 @@
+package fsutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseSize converts a human-readable size such as "0.7MB" or "2 GB" into
+// bytes. Sizes without a unit are taken as bytes.
+func ParseSize(size string) (int64, error) {
+	normalized := strings.TrimSpace(strings.ToUpper(size))
+	multipliers := []struct {
+		suffix string
+		factor float64
+	}{
+		{"GB", 1 << 30},
+		{"MB", 1 << 20},
+		{"KB", 1 << 10},
+		{"B", 1},
+	}
+
+	factor := 1.0
+	for _, m := range multipliers {
+		if strings.HasSuffix(normalized, m.suffix) {
+			normalized = strings.TrimSpace(strings.TrimSuffix(normalized, m.suffix))
+			factor = m.factor
+			break
+		}
+	}
+
+	value, err := strconv.ParseFloat(normalized, 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+
+	return int64(value * factor), nil
+}
+
+// FormatSize renders bytes like the sizes shown by Anna's Archive, for
+// example "2.5MB", so ParseSize reads it back.
+func FormatSize(bytes int64) string {
+	switch {
+	case bytes >= 1<<30:
+		return fmt.Sprintf("%.1fGB", float64(bytes)/(1<<30))
+	case bytes >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(bytes)/(1<<20))
+	case bytes >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(bytes)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", bytes)
+	}
+}
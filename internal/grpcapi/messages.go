@@ -0,0 +1,320 @@
+package grpcapi
+
+import (
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// The messages of proto/annas/v1/annas.proto. They are encoded by hand with
+// protowire, which keeps the facade free of generated code and of the gRPC
+// runtime. Field numbers must match the proto definitions.
+
+type Book struct {
+	Hash         string
+	Title        string
+	Authors      []string
+	Publisher    string
+	Language     string
+	Format       string
+	Size         string
+	URL          string
+	Bytes        int64
+	FastDownload bool
+}
+
+type SearchRequest struct {
+	Query string
+	Limit int32
+}
+
+type SearchResponse struct {
+	Books []*Book
+}
+
+type GetMetadataRequest struct {
+	Hash string
+}
+
+type GetMetadataResponse struct {
+	Book              *Book
+	ISBNs             []string
+	Description       string
+	AlternativeTitles []string
+}
+
+type DownloadRequest struct {
+	Hash   string
+	Title  string
+	Format string
+	Save   bool
+	Server string
+}
+
+type DownloadResponse struct {
+	DownloadURL string
+	ExpiresAt   int64
+	Path        string
+}
+
+// Message is implemented by every message of the service.
+type Message interface {
+	Marshal() []byte
+	Unmarshal(data []byte) error
+}
+
+func appendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+func appendStrings(b []byte, num protowire.Number, values []string) []byte {
+	for _, v := range values {
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendString(b, v)
+	}
+	return b
+}
+
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendMessage(b []byte, num protowire.Number, m Message) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, m.Marshal())
+}
+
+// field is a decoded field value, either a varint or length-delimited bytes.
+type field struct {
+	typ    protowire.Type
+	varint uint64
+	bytes  []byte
+}
+
+func (f field) str() string {
+	if f.typ != protowire.BytesType {
+		return ""
+	}
+	return string(f.bytes)
+}
+
+// eachField calls fn with every field of an encoded message. Fields of other
+// wire types are skipped, so unknown fields do not fail decoding.
+func eachField(data []byte, fn func(num protowire.Number, f field) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		f := field{typ: typ}
+		switch typ {
+		case protowire.VarintType:
+			f.varint, n = protowire.ConsumeVarint(data)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(data)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+
+		if typ == protowire.VarintType || typ == protowire.BytesType {
+			if err := fn(num, f); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func boolVarint(v bool) uint64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+func (m *Book) Marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Hash)
+	b = appendString(b, 2, m.Title)
+	b = appendStrings(b, 3, m.Authors)
+	b = appendString(b, 4, m.Publisher)
+	b = appendString(b, 5, m.Language)
+	b = appendString(b, 6, m.Format)
+	b = appendString(b, 7, m.Size)
+	b = appendString(b, 8, m.URL)
+	b = appendVarint(b, 9, uint64(m.Bytes))
+	b = appendVarint(b, 10, boolVarint(m.FastDownload))
+	return b
+}
+
+func (m *Book) Unmarshal(data []byte) error {
+	return eachField(data, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.Hash = f.str()
+		case 2:
+			m.Title = f.str()
+		case 3:
+			m.Authors = append(m.Authors, f.str())
+		case 4:
+			m.Publisher = f.str()
+		case 5:
+			m.Language = f.str()
+		case 6:
+			m.Format = f.str()
+		case 7:
+			m.Size = f.str()
+		case 8:
+			m.URL = f.str()
+		case 9:
+			m.Bytes = int64(f.varint)
+		case 10:
+			m.FastDownload = f.varint != 0
+		}
+		return nil
+	})
+}
+
+func (m *SearchRequest) Marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Query)
+	b = appendVarint(b, 2, uint64(m.Limit))
+	return b
+}
+
+func (m *SearchRequest) Unmarshal(data []byte) error {
+	return eachField(data, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.Query = f.str()
+		case 2:
+			m.Limit = int32(f.varint)
+		}
+		return nil
+	})
+}
+
+func (m *SearchResponse) Marshal() []byte {
+	var b []byte
+	for _, book := range m.Books {
+		b = appendMessage(b, 1, book)
+	}
+	return b
+}
+
+func (m *SearchResponse) Unmarshal(data []byte) error {
+	return eachField(data, func(num protowire.Number, f field) error {
+		if num == 1 {
+			book := &Book{}
+			if err := book.Unmarshal(f.bytes); err != nil {
+				return err
+			}
+			m.Books = append(m.Books, book)
+		}
+		return nil
+	})
+}
+
+func (m *GetMetadataRequest) Marshal() []byte {
+	return appendString(nil, 1, m.Hash)
+}
+
+func (m *GetMetadataRequest) Unmarshal(data []byte) error {
+	return eachField(data, func(num protowire.Number, f field) error {
+		if num == 1 {
+			m.Hash = f.str()
+		}
+		return nil
+	})
+}
+
+func (m *GetMetadataResponse) Marshal() []byte {
+	var b []byte
+	if m.Book != nil {
+		b = appendMessage(b, 1, m.Book)
+	}
+	b = appendStrings(b, 2, m.ISBNs)
+	b = appendString(b, 3, m.Description)
+	b = appendStrings(b, 4, m.AlternativeTitles)
+	return b
+}
+
+func (m *GetMetadataResponse) Unmarshal(data []byte) error {
+	return eachField(data, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.Book = &Book{}
+			return m.Book.Unmarshal(f.bytes)
+		case 2:
+			m.ISBNs = append(m.ISBNs, f.str())
+		case 3:
+			m.Description = f.str()
+		case 4:
+			m.AlternativeTitles = append(m.AlternativeTitles, f.str())
+		}
+		return nil
+	})
+}
+
+func (m *DownloadRequest) Marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Hash)
+	b = appendString(b, 2, m.Title)
+	b = appendString(b, 3, m.Format)
+	b = appendVarint(b, 4, boolVarint(m.Save))
+	b = appendString(b, 5, m.Server)
+	return b
+}
+
+func (m *DownloadRequest) Unmarshal(data []byte) error {
+	return eachField(data, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.Hash = f.str()
+		case 2:
+			m.Title = f.str()
+		case 3:
+			m.Format = f.str()
+		case 4:
+			m.Save = f.varint != 0
+		case 5:
+			m.Server = f.str()
+		}
+		return nil
+	})
+}
+
+func (m *DownloadResponse) Marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.DownloadURL)
+	b = appendVarint(b, 2, uint64(m.ExpiresAt))
+	b = appendString(b, 3, m.Path)
+	return b
+}
+
+func (m *DownloadResponse) Unmarshal(data []byte) error {
+	return eachField(data, func(num protowire.Number, f field) error {
+		switch num {
+		case 1:
+			m.DownloadURL = f.str()
+		case 2:
+			m.ExpiresAt = int64(f.varint)
+		case 3:
+			m.Path = f.str()
+		}
+		return nil
+	})
+}
@@ -0,0 +1,209 @@
+// Package grpcapi serves the Annas gRPC service defined in
+// proto/annas/v1/annas.proto. It implements the unary calls of the gRPC
+// protocol over HTTP/2 with net/http, so standard gRPC clients can call it.
+package grpcapi
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServicePath prefixes the paths of the methods of the service.
+const ServicePath = "/annas.v1.Annas/"
+
+// maxMessageBytes bounds a request message, like the 4 MiB default of gRPC.
+const maxMessageBytes = 4 << 20
+
+// Status codes of the gRPC protocol used by the service.
+const (
+	CodeOK                 = 0
+	CodeInvalidArgument    = 3
+	CodeNotFound           = 5
+	CodePermissionDenied   = 7
+	CodeResourceExhausted  = 8
+	CodeFailedPrecondition = 9
+	CodeUnimplemented      = 12
+	CodeInternal           = 13
+	CodeUnavailable        = 14
+)
+
+// Status is an error with a gRPC status code.
+type Status struct {
+	Code    int
+	Message string
+}
+
+func (s *Status) Error() string {
+	return fmt.Sprintf("rpc error: code = %d desc = %s", s.Code, s.Message)
+}
+
+// Errorf returns a Status error with the given code.
+func Errorf(code int, format string, args ...any) error {
+	return &Status{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Service implements the Annas service.
+type Service interface {
+	Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error)
+	GetMetadata(ctx context.Context, req *GetMetadataRequest) (*GetMetadataResponse, error)
+	Download(ctx context.Context, req *DownloadRequest) (*DownloadResponse, error)
+}
+
+// Handler serves the methods of svc under ServicePath. Errors that are not a
+// Status are reported as internal errors.
+func Handler(svc Service) http.Handler {
+	methods := map[string]func(ctx context.Context, data []byte) (Message, error){
+		"Search": func(ctx context.Context, data []byte) (Message, error) {
+			req := &SearchRequest{}
+			if err := req.Unmarshal(data); err != nil {
+				return nil, Errorf(CodeInvalidArgument, "invalid request: %v", err)
+			}
+			return svc.Search(ctx, req)
+		},
+		"GetMetadata": func(ctx context.Context, data []byte) (Message, error) {
+			req := &GetMetadataRequest{}
+			if err := req.Unmarshal(data); err != nil {
+				return nil, Errorf(CodeInvalidArgument, "invalid request: %v", err)
+			}
+			return svc.GetMetadata(ctx, req)
+		},
+		"Download": func(ctx context.Context, data []byte) (Message, error) {
+			req := &DownloadRequest{}
+			if err := req.Unmarshal(data); err != nil {
+				return nil, Errorf(CodeInvalidArgument, "invalid request: %v", err)
+			}
+			return svc.Download(ctx, req)
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+		ctx := r.Context()
+		if timeout, ok := parseTimeout(r.Header.Get("Grpc-Timeout")); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		method, ok := methods[strings.TrimPrefix(r.URL.Path, ServicePath)]
+		if !ok {
+			writeStatus(w, Errorf(CodeUnimplemented, "unknown method %s", r.URL.Path))
+			return
+		}
+
+		data, err := readMessage(r.Body)
+		if err != nil {
+			writeStatus(w, err)
+			return
+		}
+
+		resp, err := method(ctx, data)
+		if err != nil {
+			writeStatus(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(frame(resp.Marshal())); err != nil {
+			return
+		}
+		writeStatus(w, nil)
+	})
+}
+
+// readMessage reads the single length-prefixed message of a unary call.
+func readMessage(body io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(body, header[:]); err != nil {
+		return nil, Errorf(CodeInvalidArgument, "missing request message: %v", err)
+	}
+	if header[0] != 0 {
+		return nil, Errorf(CodeUnimplemented, "compressed messages are not supported")
+	}
+
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxMessageBytes {
+		return nil, Errorf(CodeResourceExhausted, "request message of %d bytes exceeds the limit of %d", length, maxMessageBytes)
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(body, data); err != nil {
+		return nil, Errorf(CodeInvalidArgument, "truncated request message: %v", err)
+	}
+
+	return data, nil
+}
+
+// frame prefixes an uncompressed message with its length.
+func frame(data []byte) []byte {
+	framed := make([]byte, 5+len(data))
+	binary.BigEndian.PutUint32(framed[1:], uint32(len(data)))
+	copy(framed[5:], data)
+	return framed
+}
+
+// writeStatus sets the trailers reporting the outcome of a call.
+func writeStatus(w http.ResponseWriter, err error) {
+	status := &Status{Code: CodeOK}
+	if err != nil && !errors.As(err, &status) {
+		status = &Status{Code: CodeInternal, Message: err.Error()}
+	}
+
+	w.Header().Set("Grpc-Status", strconv.Itoa(status.Code))
+	if status.Message != "" {
+		w.Header().Set("Grpc-Message", encodeMessage(status.Message))
+	}
+}
+
+// encodeMessage percent-encodes a status message as the protocol requires.
+func encodeMessage(message string) string {
+	var encoded strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&encoded, "%%%02X", c)
+			continue
+		}
+		encoded.WriteByte(c)
+	}
+	return encoded.String()
+}
+
+// parseTimeout parses the Grpc-Timeout header, such as "30S" or "500m".
+func parseTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 {
+		return 0, false
+	}
+
+	units := map[byte]time.Duration{
+		'H': time.Hour,
+		'M': time.Minute,
+		'S': time.Second,
+		'm': time.Millisecond,
+		'u': time.Microsecond,
+		'n': time.Nanosecond,
+	}
+	unit, ok := units[value[len(value)-1]]
+	if !ok {
+		return 0, false
+	}
+	amount, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || amount < 0 {
+		return 0, false
+	}
+
+	return time.Duration(amount) * unit, true
+}
@@ -0,0 +1,100 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+type fakeService struct{}
+
+func (fakeService) Search(ctx context.Context, req *SearchRequest) (*SearchResponse, error) {
+	if req.Query == "" {
+		return nil, Errorf(CodeInvalidArgument, "empty query")
+	}
+	return &SearchResponse{Books: []*Book{{Hash: "d6e1dc51a50726f00ec438af21952a45", Title: req.Query, Authors: []string{"A", "B"}, Bytes: 42}}}, nil
+}
+
+func (fakeService) GetMetadata(ctx context.Context, req *GetMetadataRequest) (*GetMetadataResponse, error) {
+	return &GetMetadataResponse{}, nil
+}
+
+func (fakeService) Download(ctx context.Context, req *DownloadRequest) (*DownloadResponse, error) {
+	return &DownloadResponse{}, nil
+}
+
+func call(t *testing.T, client *http.Client, url string, req Message) (*http.Response, []byte) {
+	t.Helper()
+
+	httpReq, _ := http.NewRequest(http.MethodPost, url, bytes.NewReader(frame(req.Marshal())))
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// Trailers are only available once the body was read
+	body, _ := io.ReadAll(resp.Body)
+	return resp, body
+}
+
+func TestHandler(t *testing.T) {
+	server := httptest.NewServer(h2c.NewHandler(Handler(fakeService{}), &http2.Server{}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}}
+
+	t.Run("Success", func(t *testing.T) {
+		resp, body := call(t, client, server.URL+ServicePath+"Search", &SearchRequest{Query: "Dune", Limit: 5})
+		if status := resp.Trailer.Get("Grpc-Status"); status != "0" {
+			t.Fatalf("Expected status '0', got '%s' (%s)", status, resp.Trailer.Get("Grpc-Message"))
+		}
+		if len(body) < 5 {
+			t.Fatalf("Expected a framed message, got %d bytes", len(body))
+		}
+
+		got := &SearchResponse{}
+		if err := got.Unmarshal(body[5:]); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(got.Books) != 1 || got.Books[0].Title != "Dune" || len(got.Books[0].Authors) != 2 || got.Books[0].Bytes != 42 {
+			t.Errorf("Expected the book 'Dune' by two authors, got %+v", got.Books)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		resp, _ := call(t, client, server.URL+ServicePath+"Search", &SearchRequest{})
+		status := resp.Header.Get("Grpc-Status")
+		if status == "" {
+			status = resp.Trailer.Get("Grpc-Status")
+		}
+		if status != "3" {
+			t.Errorf("Expected status '3', got '%s'", status)
+		}
+	})
+
+	t.Run("Unknown Method", func(t *testing.T) {
+		resp, _ := call(t, client, server.URL+ServicePath+"Delete", &SearchRequest{})
+		status := resp.Header.Get("Grpc-Status")
+		if status == "" {
+			status = resp.Trailer.Get("Grpc-Status")
+		}
+		if status != "12" {
+			t.Errorf("Expected status '12', got '%s'", status)
+		}
+	})
+}
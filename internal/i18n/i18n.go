@@ -0,0 +1,148 @@
+// Package i18n translates the user-facing output of the CLI. Messages are
+// looked up by their English text in the catalogs under catalogs/, one JSON
+// object per language, and fall back to it when a catalog lacks them.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Default is the language of the messages in the source.
+const Default = "en"
+
+//go:embed catalogs/*.json
+var catalogFiles embed.FS
+
+var (
+	loadOnce sync.Once
+	catalogs map[string]map[string]string
+
+	mu      sync.RWMutex
+	current = Default
+)
+
+func loadCatalogs() {
+	catalogs = make(map[string]map[string]string)
+
+	entries, err := catalogFiles.ReadDir("catalogs")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: failed to list catalogs: %v", err))
+	}
+	for _, entry := range entries {
+		data, err := catalogFiles.ReadFile(path.Join("catalogs", entry.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: failed to read catalog %s: %v", entry.Name(), err))
+		}
+		messages := make(map[string]string)
+		if err := json.Unmarshal(data, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: failed to parse catalog %s: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+}
+
+func catalog(lang string) map[string]string {
+	loadOnce.Do(loadCatalogs)
+	return catalogs[lang]
+}
+
+// Languages returns the supported languages, the default one first.
+func Languages() []string {
+	loadOnce.Do(loadCatalogs)
+
+	languages := make([]string, 0, len(catalogs)+1)
+	for lang := range catalogs {
+		if lang != Default {
+			languages = append(languages, lang)
+		}
+	}
+	slices.Sort(languages)
+
+	return append([]string{Default}, languages...)
+}
+
+// Normalize returns the supported language of a locale or language tag such
+// as "de_DE.UTF-8", "es-MX", or "zh_CN", and whether it is supported. The
+// "C" and "POSIX" locales are English.
+func Normalize(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, ".@"); i >= 0 {
+		tag = tag[:i]
+	}
+	if tag == "c" || tag == "posix" {
+		return Default, true
+	}
+	if i := strings.IndexAny(tag, "_-"); i >= 0 {
+		tag = tag[:i]
+	}
+
+	if tag == Default || catalog(tag) != nil {
+		return tag, true
+	}
+	return "", false
+}
+
+// Detect returns the language of the locale set in the environment, looked up
+// like POSIX does in LC_ALL, LC_MESSAGES, and LANG, or the default language
+// when it is not supported.
+func Detect(getenv func(string) string) string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		if lang, ok := Normalize(value); ok {
+			return lang
+		}
+		return Default
+	}
+
+	return Default
+}
+
+// Use selects the language of the messages.
+func Use(tag string) error {
+	lang, ok := Normalize(tag)
+	if !ok {
+		return fmt.Errorf("unsupported language %q (supported: %s)", tag, strings.Join(Languages(), ", "))
+	}
+
+	mu.Lock()
+	current = lang
+	mu.Unlock()
+
+	return nil
+}
+
+// Language returns the selected language.
+func Language() string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return current
+}
+
+// T returns the translation of message in the selected language.
+func T(message string) string {
+	if translated, ok := catalog(Language())[message]; ok && translated != "" {
+		return translated
+	}
+	return message
+}
+
+// Sprintf formats according to the translation of format. Translations may
+// reorder the arguments with explicit indexes such as %[2]s.
+func Sprintf(format string, args ...any) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// Printf prints to stdout according to the translation of format.
+func Printf(format string, args ...any) {
+	fmt.Print(Sprintf(format, args...))
+}
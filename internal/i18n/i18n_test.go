@@ -0,0 +1,102 @@
+package i18n
+
+import (
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	for tag, want := range map[string]string{
+		"de_DE.UTF-8":  "de",
+		"es-MX":        "es",
+		"zh_CN.GB2312": "zh",
+		"C.UTF-8":      "en",
+		"POSIX":        "en",
+		"en_US":        "en",
+		"fr_FR":        "",
+	} {
+		if got, _ := Normalize(tag); got != want {
+			t.Errorf("Expected '%s' for '%s', got '%s'", want, tag, got)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	env := map[string]string{"LANG": "de_DE.UTF-8"}
+	getenv := func(name string) string { return env[name] }
+	if got := Detect(getenv); got != "de" {
+		t.Errorf("Expected LANG to select 'de', got '%s'", got)
+	}
+
+	// LC_ALL overrides LANG, even with a language that is not supported
+	env["LC_ALL"] = "fr_FR.UTF-8"
+	if got := Detect(getenv); got != Default {
+		t.Errorf("Expected LC_ALL to select the default language, got '%s'", got)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	defer Use(Default)
+
+	if err := Use("es_ES"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := T("Search for books"); got != "Buscar libros" {
+		t.Errorf("Expected the Spanish translation, got '%s'", got)
+	}
+	if got := T("A message missing from the catalogs"); got != "A message missing from the catalogs" {
+		t.Errorf("Expected missing messages to stay in English, got '%s'", got)
+	}
+
+	if err := Use("zh"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := Sprintf("Imported %d records from %s\n", 3, "dump.jsonl"); got != "已从 dump.jsonl 导入 3 条记录\n" {
+		t.Errorf("Expected the arguments to be reordered, got '%s'", got)
+	}
+
+	if err := Use("xx"); err == nil {
+		t.Errorf("Expected an error for an unsupported language")
+	}
+}
+
+var verb = regexp.MustCompile(`%(?:\[(\d+)\])?[-+# 0]*\d*(?:\.\d+)?([a-zA-Z%])`)
+
+// verbs maps the argument index of every verb of format to the verb.
+func verbs(format string) map[int]string {
+	result := make(map[int]string)
+	next := 1
+	for _, match := range verb.FindAllStringSubmatch(format, -1) {
+		if match[2] == "%" {
+			continue
+		}
+		if match[1] != "" {
+			next, _ = strconv.Atoi(match[1])
+		}
+		result[next] = match[2]
+		next++
+	}
+
+	return result
+}
+
+func TestCatalogs(t *testing.T) {
+	english := slices.Sorted(maps.Keys(catalog("de")))
+	for _, lang := range Languages()[1:] {
+		messages := catalog(lang)
+		if keys := slices.Sorted(maps.Keys(messages)); !slices.Equal(keys, english) {
+			t.Errorf("Expected the %s catalog to translate the same messages as the de one", lang)
+		}
+		for message, translated := range messages {
+			if translated == "" {
+				t.Errorf("Expected a %s translation of '%s'", lang, message)
+			}
+			if !maps.Equal(verbs(message), verbs(translated)) {
+				t.Errorf("Expected the %s translation of '%s' to keep its verbs, got '%s'", lang, message, translated)
+			}
+		}
+	}
+}
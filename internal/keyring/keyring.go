@@ -0,0 +1,172 @@
+package keyring
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// cooldown is how long a rejected or exhausted key is skipped before it is
+// tried first again.
+const cooldown = time.Hour
+
+// Usage describes what the server knows about one secret key.
+type Usage struct {
+	Key             string     `json:"key"`
+	Downloads       int        `json:"downloads"`
+	DownloadsLeft   *int       `json:"downloads_left,omitempty"`
+	DownloadsPerDay *int       `json:"downloads_per_day,omitempty"`
+	LastUsed        *time.Time `json:"last_used,omitempty"`
+	LastError       string     `json:"last_error,omitempty"`
+	DisabledUntil   *time.Time `json:"disabled_until,omitempty"`
+}
+
+// Usage is tracked process-wide, keyed by the secret itself, so that every
+// session using the same key shares the same view of its quota.
+var (
+	mu    sync.Mutex
+	usage = make(map[string]*Usage)
+)
+
+// Ring resolves download links with a list of keys, failing over to the next
+// key when one is rejected or out of downloads.
+type Ring struct {
+	keys []string
+}
+
+func New(keys []string) *Ring {
+	return &Ring{keys: keys}
+}
+
+// Resolve returns a fast download link for the book, trying healthy keys in
+// configured order and keys inside their cooldown only as a last resort.
+func (r *Ring) Resolve(book *anna.Book) (*anna.DownloadInfo, error) {
+	return r.ResolveFrom(book, anna.DefaultServer)
+}
+
+// ResolveFrom is like Resolve but asks for a link on the fast partner server
+// with the given index, or anna.DefaultServer.
+func (r *Ring) ResolveFrom(book *anna.Book, server int) (*anna.DownloadInfo, error) {
+	l := logger.GetLogger()
+
+	if len(r.keys) == 0 {
+		return nil, errors.New("no secret key configured")
+	}
+
+	var lastErr error
+	for _, key := range r.ordered() {
+		info, err := book.GetDownloadInfoFrom(key, server)
+		if err == nil {
+			recordSuccess(key, info.Account)
+			return info, nil
+		}
+
+		var apiErr *anna.APIError
+		if !errors.As(err, &apiErr) || !apiErr.KeyRelated() {
+			return nil, err
+		}
+
+		l.Warn("Secret key rejected, failing over to the next key",
+			zap.String("key", Mask(key)),
+			zap.Error(err),
+		)
+		recordFailure(key, err)
+		lastErr = err
+	}
+
+	if len(r.keys) == 1 {
+		return nil, lastErr
+	}
+	return nil, fmt.Errorf("all %d secret keys failed, last error: %w", len(r.keys), lastErr)
+}
+
+// Usage reports the tracked state of the ring's keys, with the keys masked.
+func (r *Ring) Usage() []Usage {
+	mu.Lock()
+	defer mu.Unlock()
+
+	result := make([]Usage, 0, len(r.keys))
+	for _, key := range r.keys {
+		entry := Usage{Key: Mask(key)}
+		if known, ok := usage[key]; ok {
+			entry = *known
+		}
+		result = append(result, entry)
+	}
+
+	return result
+}
+
+func (r *Ring) ordered() []string {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	healthy := make([]string, 0, len(r.keys))
+	cooling := make([]string, 0)
+	for _, key := range r.keys {
+		if u, ok := usage[key]; ok && u.DisabledUntil != nil && now.Before(*u.DisabledUntil) {
+			cooling = append(cooling, key)
+			continue
+		}
+		healthy = append(healthy, key)
+	}
+
+	return append(healthy, cooling...)
+}
+
+func entry(key string) *Usage {
+	u, ok := usage[key]
+	if !ok {
+		u = &Usage{Key: Mask(key)}
+		usage[key] = u
+	}
+	return u
+}
+
+func recordSuccess(key string, account *anna.AccountInfo) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	now := time.Now()
+	u := entry(key)
+	u.Downloads++
+	u.LastUsed = &now
+	u.LastError = ""
+	u.DisabledUntil = nil
+
+	if account != nil {
+		left, perDay := account.DownloadsLeft, account.DownloadsPerDay
+		u.DownloadsLeft = &left
+		u.DownloadsPerDay = &perDay
+
+		// Move on proactively once the allowance is used up
+		if left <= 0 {
+			until := now.Add(cooldown)
+			u.DisabledUntil = &until
+		}
+	}
+}
+
+func recordFailure(key string, err error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	until := time.Now().Add(cooldown)
+	u := entry(key)
+	u.LastError = err.Error()
+	u.DisabledUntil = &until
+}
+
+// Mask hides all but the first characters of a key so it can be shown to users.
+func Mask(key string) string {
+	if len(key) <= 4 {
+		return "****"
+	}
+	return key[:4] + "****"
+}
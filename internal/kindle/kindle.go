@@ -0,0 +1,141 @@
+package kindle
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// MaxAttachmentSize is the largest file Send-to-Kindle accepts by e-mail.
+const MaxAttachmentSize = 50 << 20
+
+// ErrTooLarge is returned by Send for files Kindle would reject.
+var ErrTooLarge = errors.New("file is too large to email to Kindle")
+
+// SMTPConfig holds the outgoing mail settings used for Send-to-Kindle delivery.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// Send emails the file at path to the given Kindle address as an attachment.
+// The sender address must be on the Amazon account's approved list.
+func Send(config SMTPConfig, to, path string) error {
+	if config.Host == "" || config.From == "" {
+		return errors.New("SMTP host and sender address must be configured")
+	}
+	if to == "" {
+		return errors.New("Kindle address must be configured")
+	}
+
+	// Checked before dialing, since servers reject large messages unclearly
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() > MaxAttachmentSize {
+		return fmt.Errorf("%w: %s is %d MB, at most %d MB are accepted", ErrTooLarge, filepath.Base(path), info.Size()>>20, MaxAttachmentSize>>20)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	message, err := buildMessage(config.From, to, filepath.Base(path), data)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+
+	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
+	return smtp.SendMail(addr, auth, config.From, []string{to}, message)
+}
+
+// Convert turns the file into the target format with Calibre's ebook-convert,
+// writing it to dir, and returns the path of the converted file. It is a
+// no-op returning path when format is empty or the file already has the
+// target extension.
+func Convert(path, format, dir string) (string, error) {
+	format = strings.ToLower(strings.TrimPrefix(format, "."))
+	ext := filepath.Ext(path)
+	if format == "" || strings.EqualFold(strings.TrimPrefix(ext, "."), format) {
+		return path, nil
+	}
+
+	binary, err := exec.LookPath("ebook-convert")
+	if err != nil {
+		return "", errors.New("ebook-convert (Calibre) is required for conversion but was not found in PATH")
+	}
+
+	target := filepath.Join(dir, strings.TrimSuffix(filepath.Base(path), ext)+"."+format)
+	if output, err := exec.Command(binary, path, target).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("conversion failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return target, nil
+}
+
+func buildMessage(from, to, filename string, data []byte) ([]byte, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	textPart, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"text/plain; charset=utf-8"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(textPart, "Sent by annas-mcp: %s\r\n", filename)
+
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	attachment, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": filename})},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		attachment.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	attachment.Write([]byte(encoded + "\r\n"))
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", to)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", filename))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", writer.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package kindle
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildMessage(t *testing.T) {
+	data := bytes.Repeat([]byte("Dune, by Frank Herbert. "), 20)
+	raw, err := buildMessage("me@example.org", "reader@kindle.com", "Dune (1965).pdf", data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	message, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	if message.Header.Get("To") != "reader@kindle.com" {
+		t.Errorf("Expected the Kindle address, got %s", message.Header.Get("To"))
+	}
+	mediaType, params, err := mime.ParseMediaType(message.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("Expected a multipart message, got %s and %v", mediaType, err)
+	}
+
+	reader := multipart.NewReader(message.Body, params["boundary"])
+	if _, err := reader.NextPart(); err != nil {
+		t.Fatalf("Expected the text part, got %v", err)
+	}
+	attachment, err := reader.NextPart()
+	if err != nil {
+		t.Fatalf("Expected the attachment, got %v", err)
+	}
+	if attachment.FileName() != "Dune (1965).pdf" {
+		t.Errorf("Expected the file name, got '%s'", attachment.FileName())
+	}
+	if contentType := attachment.Header.Get("Content-Type"); contentType != "application/pdf" {
+		t.Errorf("Expected the PDF type, got '%s'", contentType)
+	}
+
+	encoded, err := io.ReadAll(attachment)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(encoded), "\r\n"), "\r\n")
+	if len(lines) < 2 {
+		t.Fatalf("Expected the attachment to span several lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if len(line) > 76 || (i < len(lines)-1 && len(line) != 76) {
+			t.Errorf("Expected lines of 76 characters, line %d has %d", i, len(line))
+		}
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(lines, ""))
+	if err != nil || !bytes.Equal(decoded, data) {
+		t.Errorf("Expected the attachment to decode to the file, got %v", err)
+	}
+
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("Expected two parts, got %v", err)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dune.epub")
+	for _, format := range []string{"", "epub", ".EPUB"} {
+		converted, err := Convert(path, format, t.TempDir())
+		if err != nil || converted != path {
+			t.Errorf("Expected '%s' to keep the file, got %s and %v", format, converted, err)
+		}
+	}
+}
+
+func TestSendTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "atlas.pdf")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Truncate(MaxAttachmentSize + 1); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	// An unreachable server shows the size is checked first
+	config := SMTPConfig{Host: "192.0.2.1", Port: 25, From: "me@example.org"}
+	if err := Send(config, "reader@kindle.com", path); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+}
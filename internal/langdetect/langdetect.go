@@ -0,0 +1,180 @@
+// Package langdetect guesses the language of a text sample from its script
+// and its most frequent words. It is meant to catch records whose language
+// is wrong, not to tell close dialects apart.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minWords is the number of words of a Latin-script sample needed for a
+// guess, since short samples are dominated by names and numbers.
+const minWords = 50
+
+// minLetters is the number of letters of other scripts needed for a guess.
+const minLetters = 100
+
+// stopwords are frequent words of the Latin-script languages, by ISO 639-1
+// code. Words shared by several languages only add to the noise, so the lists
+// favor the distinctive ones.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "of", "to", "is", "in", "that", "it", "was", "he", "for", "with", "as", "his", "on", "be", "at", "by", "had", "which", "this", "from", "have", "not", "are", "but", "were", "they", "you", "she", "her"},
+	"es": {"el", "los", "las", "del", "que", "y", "en", "por", "con", "una", "para", "es", "lo", "como", "pero", "más", "su", "al", "sus", "le", "ya", "muy", "sin", "sobre", "también", "fue", "había", "esta", "entre", "cuando"},
+	"fr": {"le", "les", "des", "et", "est", "une", "du", "dans", "qui", "que", "pas", "pour", "sur", "au", "avec", "il", "elle", "ce", "sont", "ne", "mais", "nous", "vous", "plus", "être", "aux", "été", "cette", "leur", "était"},
+	"de": {"der", "die", "und", "das", "ist", "nicht", "ein", "eine", "zu", "den", "von", "mit", "sich", "des", "auf", "für", "im", "dem", "auch", "es", "an", "werden", "aus", "er", "sie", "nach", "wird", "bei", "noch", "wie", "war"},
+	"it": {"il", "di", "che", "è", "la", "per", "un", "non", "una", "sono", "gli", "della", "con", "del", "le", "si", "nel", "alla", "ma", "anche", "come", "più", "questo", "dei", "delle", "ha", "era", "loro", "lui", "perché"},
+	"pt": {"o", "os", "do", "da", "dos", "das", "que", "e", "em", "não", "um", "uma", "para", "com", "por", "mais", "ao", "no", "na", "é", "se", "foi", "ele", "ela", "como", "mas", "seu", "sua", "também", "muito"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "op", "te", "zijn", "niet", "met", "voor", "hij", "ik", "maar", "ook", "aan", "er", "om", "dit", "wordt", "bij", "nog", "werd", "naar", "uit", "als", "zij", "kan"},
+	"pl": {"i", "w", "nie", "na", "się", "jest", "z", "do", "że", "to", "o", "jak", "ale", "po", "co", "tak", "za", "od", "jego", "przez", "już", "czy", "tylko", "był", "jej", "bardzo", "może", "które", "który", "ich"},
+	"tr": {"ve", "bir", "bu", "da", "de", "için", "ile", "çok", "ne", "ama", "daha", "gibi", "olan", "sonra", "kadar", "değil", "mi", "ben", "o", "onun", "olarak", "her", "en", "diye", "var", "şey", "bana", "ise", "olduğu", "ya"},
+	"id": {"yang", "dan", "di", "itu", "dengan", "untuk", "tidak", "ini", "dari", "dalam", "akan", "pada", "juga", "saya", "ke", "karena", "tetapi", "oleh", "ada", "mereka", "atau", "bisa", "sudah", "kami", "lebih", "seperti", "telah", "dia", "harus", "kita"},
+	"vi": {"và", "của", "là", "có", "không", "những", "được", "trong", "một", "cho", "với", "người", "này", "các", "đã", "khi", "để", "cũng", "như", "thì", "đó", "ra", "mà", "lại", "sẽ", "từ", "nhưng", "về", "đến", "tôi"},
+	"cs": {"a", "je", "v", "se", "na", "že", "to", "s", "z", "do", "o", "by", "jako", "ale", "jsou", "k", "pro", "tak", "jeho", "jak", "být", "už", "který", "jsem", "které", "když", "byl", "také", "mezi", "bylo"},
+	"hu": {"a", "az", "és", "hogy", "nem", "egy", "is", "van", "meg", "de", "csak", "már", "ez", "mint", "volt", "még", "el", "ha", "azt", "sem", "ki", "vagy", "kell", "lesz", "pedig", "nagyon", "így", "után", "amikor", "minden"},
+	"ro": {"și", "în", "de", "la", "nu", "cu", "o", "pe", "este", "un", "care", "să", "a", "din", "mai", "se", "ce", "lui", "sau", "fost", "dar", "pentru", "sunt", "ca", "acest", "cum", "lor", "foarte", "după", "până"},
+	"sv": {"och", "att", "det", "i", "som", "en", "på", "är", "av", "för", "med", "till", "den", "inte", "har", "de", "om", "ett", "han", "men", "var", "jag", "sig", "från", "så", "kan", "hon", "när", "eller", "skulle"},
+	"la": {"et", "in", "est", "non", "ad", "quod", "cum", "ut", "sed", "qui", "quae", "enim", "esse", "sunt", "etiam", "autem", "per", "ex", "atque", "quam", "nec", "vel", "eius", "hoc", "ab", "ac", "inter", "tamen", "quia", "erat"},
+}
+
+// lookup maps every stopword to the languages listing it.
+var lookup = func() map[string][]string {
+	lookup := make(map[string][]string)
+	for code, words := range stopwords {
+		for _, word := range words {
+			lookup[word] = append(lookup[word], code)
+		}
+	}
+	return lookup
+}()
+
+// Detect returns the ISO 639-1 code of the language of text and a confidence
+// between 0 and 1, or an empty code when the sample is too short or too
+// ambiguous.
+func Detect(text string) (string, float64) {
+	if code, confidence, ok := detectScript(text); ok {
+		return code, confidence
+	}
+
+	return detectWords(text)
+}
+
+// detectScript guesses the languages written in their own script. It reports
+// false when most letters are Latin, or there are too few of them.
+func detectScript(text string) (string, float64, bool) {
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["cyrillic"]++
+			// Letters of Ukrainian missing from Russian
+			if strings.ContainsRune("іїєґІЇЄҐ", r) {
+				counts["uk"]++
+			}
+		case unicode.Is(unicode.Arabic, r):
+			counts["arabic"]++
+			// Letters of Persian missing from Arabic
+			if strings.ContainsRune("پچژگکی", r) {
+				counts["fa"]++
+			}
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		case unicode.Is(unicode.Bengali, r):
+			counts["bn"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		}
+	}
+	if letters < minLetters || counts["latin"]*2 >= letters {
+		return "", 0, false
+	}
+
+	scripts := map[string]int{
+		"cyrillic": counts["cyrillic"],
+		"arabic":   counts["arabic"],
+		"cjk":      counts["han"] + counts["kana"],
+	}
+	for _, code := range []string{"he", "el", "hi", "bn", "ko"} {
+		scripts[code] = counts[code]
+	}
+	best, most := "", 0
+	for script, count := range scripts {
+		if count > most {
+			best, most = script, count
+		}
+	}
+	confidence := float64(most) / float64(letters)
+
+	switch best {
+	case "cyrillic":
+		if counts["uk"]*100 >= most {
+			return "uk", confidence, true
+		}
+		return "ru", confidence, true
+	case "arabic":
+		if counts["fa"]*50 >= most {
+			return "fa", confidence, true
+		}
+		return "ar", confidence, true
+	case "cjk":
+		// Japanese mixes kana into its kanji, Chinese has none
+		if counts["kana"]*10 >= most {
+			return "ja", confidence, true
+		}
+		return "zh", confidence, true
+	case "":
+		return "", 0, false
+	default:
+		return best, confidence, true
+	}
+}
+
+// detectWords guesses a Latin-script language by counting its stopwords.
+func detectWords(text string) (string, float64) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) < minWords {
+		return "", 0
+	}
+
+	hits := make(map[string]int)
+	for _, word := range words {
+		for _, code := range lookup[word] {
+			hits[code]++
+		}
+	}
+
+	best, second := "", 0
+	for code, count := range hits {
+		if count > hits[best] || (count == hits[best] && code < best) {
+			if best != "" {
+				second = max(second, hits[best])
+			}
+			best = code
+		} else {
+			second = max(second, count)
+		}
+	}
+	// Running text is full of stopwords, indexes and tables are not
+	if best == "" || hits[best]*20 < len(words) {
+		return "", 0
+	}
+
+	return best, float64(hits[best]-second) / float64(hits[best])
+}
@@ -0,0 +1,137 @@
+package library
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultGCAge is how long a temporary file must have been left untouched
+// before GC considers it abandoned. Downloads in progress keep writing to
+// theirs, so they are never that old.
+const DefaultGCAge = time.Hour
+
+// tempPrefixes and tempSuffixes name the temporary files written to the
+// download path: downloads in progress, aria2 control files, write probes,
+// and the temporary copies of sidecars, the index, the download queue, and the
+// manifest.
+var (
+	tempPrefixes = []string{".annas-mcp-download-", ".annas-mcp-write-test-", sidecarTempPrefix, IndexFile + ".", QueueFile + ".", ManifestFile + "."}
+	tempSuffixes = []string{".part", ".aria2"}
+)
+
+// extractPrefix names the temporary folders of extractions in progress.
+const extractPrefix = ".annas-mcp-extract-"
+
+// Collected reports what GC removed.
+type Collected struct {
+	// Files are the removed temporary files, relative to the download path.
+	Files []string `json:"files"`
+	// Bytes is the total size of Files.
+	Bytes int64 `json:"bytes"`
+	// Entries are the dropped index entries whose file was gone.
+	Entries []Entry `json:"entries"`
+}
+
+// isTemp reports whether name is a temporary file of a download.
+func isTemp(name string) bool {
+	for _, prefix := range tempPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for _, suffix := range tempSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GC removes the temporary files under root that were last modified more
+// than maxAge before now, left behind by interrupted downloads, and drops the
+// index entries whose file is missing, along with their sidecar. Files that
+// cannot be removed are reported in the error, the others are still collected.
+func GC(root string, maxAge time.Duration, now time.Time) (*Collected, error) {
+	collected := &Collected{Files: make([]string, 0), Entries: make([]Entry, 0)}
+	var errs []error
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if d.IsDir() && (path == root || !strings.HasPrefix(d.Name(), extractPrefix)) {
+			return nil
+		}
+		if !d.IsDir() && !isTemp(d.Name()) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			// Renamed or removed by its download meanwhile
+			return nil
+		}
+		if now.Sub(info.ModTime()) < maxAge {
+			return nil
+		}
+
+		// Temporary folders of extractions are removed whole
+		size := info.Size()
+		if d.IsDir() {
+			size = dirSize(path)
+		}
+		if err := os.RemoveAll(path); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove %s: %w", path, err))
+			return nil
+		}
+		relative, _ := filepath.Rel(root, path)
+		collected.Files = append(collected.Files, relative)
+		collected.Bytes += size
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	err = Update(root, func(index *Index) error {
+		for _, entry := range index.Oldest() {
+			if _, err := os.Stat(filepath.Join(root, entry.File)); errors.Is(err, os.ErrNotExist) {
+				delete(index.Entries, entry.Hash)
+				removeSidecar(root, entry)
+				pruneDirs(root, entry.File)
+				collected.Entries = append(collected.Entries, entry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	return collected, errors.Join(errs...)
+}
+
+// dirSize returns the total size of the files below dir.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			if info, err := d.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+
+	return size
+}
@@ -0,0 +1,64 @@
+package library
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGC(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+	old := now.Add(-2 * DefaultGCAge)
+
+	files := map[string]time.Time{
+		".annas-mcp-download-123":       old,
+		".annas-mcp-download-456":       now,
+		".annas-mcp-download-789.aria2": old,
+		"nested/dune.epub.part":         old,
+		IndexFile + ".42":               old,
+		"dune.epub":                     old,
+	}
+	for name, modTime := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0o755)
+		os.WriteFile(path, []byte("x"), 0o644)
+		os.Chtimes(path, modTime, modTime)
+	}
+	err := Update(root, func(index *Index) error {
+		index.Add(Entry{Hash: "dune", File: "dune.epub"})
+		index.Add(Entry{Hash: "emma", File: "emma.epub"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	collected, err := GC(root, DefaultGCAge, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if len(collected.Files) != 4 || collected.Bytes != 4 {
+		t.Errorf("Expected 4 removed files of 4 bytes, got %+v", collected)
+	}
+	for name := range files {
+		_, err := os.Stat(filepath.Join(root, name))
+		kept := name == ".annas-mcp-download-456" || name == "dune.epub"
+		if kept != (err == nil) {
+			t.Errorf("Expected %s kept %v, got error %v", name, kept, err)
+		}
+	}
+
+	if len(collected.Entries) != 1 || collected.Entries[0].Hash != "emma" {
+		t.Errorf("Expected the entry of the missing emma.epub to be dropped, got %+v", collected.Entries)
+	}
+	index, err := Load(root)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, ok := index.Entries["dune"]; !ok || len(index.Entries) != 1 {
+		t.Errorf("Expected only the dune entry to be kept, got %+v", index.Entries)
+	}
+}
@@ -0,0 +1,211 @@
+package library
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/pdfinfo"
+	"github.com/iosifache/annas-mcp/internal/state"
+)
+
+// IndexFile is the name of the index kept in the download path. Downloaded
+// file names never start with a dot, so it cannot clash with a book.
+const IndexFile = ".annas-library.json"
+
+// QueueFile is the name of the file in the download path keeping the queued
+// downloads the http mode could not finish before it stopped.
+const QueueFile = ".annas-queue.json"
+
+// ErrQuotaExceeded is returned when a download does not fit the disk quota.
+var ErrQuotaExceeded = errors.New("disk quota of the download path exceeded")
+
+// Entry is a book saved to the download path.
+type Entry struct {
+	Hash    string    `json:"md5"`
+	Title   string    `json:"title,omitempty"`
+	Format  string    `json:"format,omitempty"`
+	File    string    `json:"file"`
+	Bytes   int64     `json:"bytes"`
+	SavedAt time.Time `json:"saved_at"`
+
+	// Extracted is the folder the book was extracted to, when it is a ZIP or
+	// RAR container, relative to the download path like File.
+	Extracted      string `json:"extracted,omitempty"`
+	ExtractedFiles int    `json:"extracted_files,omitempty"`
+	ExtractedBytes int64  `json:"extracted_bytes,omitempty"`
+
+	// PDF holds the page count and size of PDF books.
+	PDF *pdfinfo.Info `json:"pdf,omitempty"`
+	// DetectedLanguage is the ISO 639-1 code of the language the content of
+	// the book appears to be in.
+	DetectedLanguage string `json:"detected_language,omitempty"`
+}
+
+// Index lists the books saved to a download path, keyed by hash.
+type Index struct {
+	root    string
+	Entries map[string]Entry `json:"entries"`
+}
+
+// mu serializes updates of all indexes, since several sessions may save to
+// the same download path.
+var mu sync.Mutex
+
+// Update loads the index of root, calls fn with it, and saves the index. It is
+// saved even when fn fails, since files fn removed are gone either way.
+func Update(root string, fn func(*Index) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	index, err := load(root)
+	if err != nil {
+		return err
+	}
+
+	return errors.Join(fn(index), index.save())
+}
+
+// Load returns a snapshot of the index of root. A missing index is empty.
+func Load(root string) (*Index, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return load(root)
+}
+
+func load(root string) (*Index, error) {
+	index := &Index{root: root, Entries: make(map[string]Entry)}
+
+	data, err := os.ReadFile(filepath.Join(root, IndexFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read library index: %w", err)
+	}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse library index: %w", err)
+	}
+	if index.Entries == nil {
+		index.Entries = make(map[string]Entry)
+	}
+
+	return index, nil
+}
+
+// save writes the index through a temporary file so readers never see a
+// partial index.
+func (i *Index) save() error {
+	data, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode library index: %w", err)
+	}
+
+	return state.WriteFile(filepath.Join(i.root, IndexFile), data)
+}
+
+// Add records a saved book, replacing an earlier entry of the same hash.
+func (i *Index) Add(entry Entry) {
+	if entry.SavedAt.IsZero() {
+		entry.SavedAt = time.Now().UTC()
+	}
+	i.Entries[entry.Hash] = entry
+}
+
+// Size returns the total size of the indexed books and their extracted
+// content.
+func (i *Index) Size() int64 {
+	var total int64
+	for _, entry := range i.Entries {
+		total += entry.Bytes + entry.ExtractedBytes
+	}
+
+	return total
+}
+
+// Oldest returns the entries from the least to the most recently saved.
+func (i *Index) Oldest() []Entry {
+	entries := make([]Entry, 0, len(i.Entries))
+	for _, entry := range i.Entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(a, b int) bool {
+		return entries[a].SavedAt.Before(entries[b].SavedAt)
+	})
+
+	return entries
+}
+
+// Remove deletes the file of the entry with hash, its sidecar, and its
+// extracted content, and drops it from the index. Files that are already gone
+// are not an error.
+func (i *Index) Remove(hash string) error {
+	entry, ok := i.Entries[hash]
+	if !ok {
+		return nil
+	}
+
+	if err := os.Remove(filepath.Join(i.root, entry.File)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove %s: %w", entry.File, err)
+	}
+	if entry.Extracted != "" {
+		if err := os.RemoveAll(filepath.Join(i.root, entry.Extracted)); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entry.Extracted, err)
+		}
+	}
+	removeSidecar(i.root, entry)
+	pruneDirs(i.root, entry.File)
+	delete(i.Entries, hash)
+
+	return nil
+}
+
+// pruneDirs removes the folders of file within root that were left empty, as
+// nested layouts give every book folders of its own.
+func pruneDirs(root, file string) {
+	for dir := filepath.Dir(file); dir != "." && dir != string(filepath.Separator); dir = filepath.Dir(dir) {
+		if err := os.Remove(filepath.Join(root, dir)); err != nil {
+			return
+		}
+	}
+}
+
+// Reserve makes room for a file of size bytes under quota. With evict, the
+// least recently saved books other than keep are removed until it fits;
+// otherwise ErrQuotaExceeded is returned. It returns the evicted entries.
+func (i *Index) Reserve(size, quota int64, evict bool, keep string) ([]Entry, error) {
+	evicted := make([]Entry, 0)
+	if quota <= 0 {
+		return evicted, nil
+	}
+	if size > quota {
+		return evicted, fmt.Errorf("%w: %d MB needed, the quota is %d MB", ErrQuotaExceeded, size>>20, quota>>20)
+	}
+
+	for _, entry := range i.Oldest() {
+		if i.Size()+size <= quota {
+			break
+		}
+		if !evict {
+			return evicted, fmt.Errorf("%w: %d of %d MB in use, %d MB needed", ErrQuotaExceeded, i.Size()>>20, quota>>20, size>>20)
+		}
+		if entry.Hash == keep {
+			continue
+		}
+		if err := i.Remove(entry.Hash); err != nil {
+			return evicted, err
+		}
+		evicted = append(evicted, entry)
+	}
+	if i.Size()+size > quota {
+		return evicted, fmt.Errorf("%w: %d of %d MB in use, %d MB needed", ErrQuotaExceeded, i.Size()>>20, quota>>20, size>>20)
+	}
+
+	return evicted, nil
+}
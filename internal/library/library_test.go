@@ -0,0 +1,101 @@
+package library
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReserve(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+
+	err := Update(root, func(index *Index) error {
+		for i, name := range []string{"old.epub", "mid.epub", "new.epub"} {
+			os.WriteFile(filepath.Join(root, name), []byte("x"), 0o644)
+			index.Add(Entry{Hash: name, File: name, Bytes: 40, SavedAt: now.Add(time.Duration(i) * time.Hour)})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Without Eviction", func(t *testing.T) {
+		err := Update(root, func(index *Index) error {
+			_, err := index.Reserve(50, 150, false, "")
+			return err
+		})
+		if !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+		}
+	})
+
+	t.Run("With Eviction", func(t *testing.T) {
+		var evicted []Entry
+		err := Update(root, func(index *Index) error {
+			var err error
+			evicted, err = index.Reserve(50, 150, true, "")
+			return err
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(evicted) != 1 || evicted[0].Hash != "old.epub" {
+			t.Errorf("Expected only the oldest book to be evicted, got %+v", evicted)
+		}
+		if _, err := os.Stat(filepath.Join(root, "old.epub")); !os.IsNotExist(err) {
+			t.Errorf("Expected evicted file to be removed, got %v", err)
+		}
+
+		index, _ := Load(root)
+		if index.Size() != 80 {
+			t.Errorf("Expected 80 bytes left in the index, got %d", index.Size())
+		}
+	})
+}
+
+func TestRemovePrunesFolders(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "Frank Herbert", "Dune"), 0o755)
+	os.MkdirAll(filepath.Join(root, "Frank Herbert", "Children of Dune"), 0o755)
+	os.WriteFile(filepath.Join(root, "Frank Herbert", "Dune", "Dune.epub"), []byte("x"), 0o644)
+
+	err := Update(root, func(index *Index) error {
+		index.Add(Entry{Hash: "dune", File: filepath.Join("Frank Herbert", "Dune", "Dune.epub")})
+		return index.Remove("dune")
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "Frank Herbert", "Dune")); !os.IsNotExist(err) {
+		t.Errorf("Expected the empty book folder to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "Frank Herbert")); err != nil {
+		t.Errorf("Expected the author folder holding another book to stay, got %v", err)
+	}
+}
+
+func TestRemoveExtracted(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "Comic"), 0o755)
+	os.WriteFile(filepath.Join(root, "Comic.cbz"), []byte("x"), 0o644)
+	os.WriteFile(filepath.Join(root, "Comic", "001.jpg"), []byte("x"), 0o644)
+
+	err := Update(root, func(index *Index) error {
+		index.Add(Entry{Hash: "comic", File: "Comic.cbz", Bytes: 1, Extracted: "Comic", ExtractedBytes: 1})
+		if size := index.Size(); size != 2 {
+			t.Errorf("Expected the extracted content to count towards the size 2, got %d", size)
+		}
+		return index.Remove("comic")
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "Comic")); !os.IsNotExist(err) {
+		t.Errorf("Expected the extracted folder to be removed with its book, got %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package library
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SidecarSuffix is appended to the name of a saved book to name its metadata
+// sidecar, so tools that only see the file system can read the record.
+const SidecarSuffix = ".json"
+
+// sidecarTempPrefix names the temporary files sidecars are written to.
+const sidecarTempPrefix = ".annas-mcp-sidecar-"
+
+// WriteSidecar writes metadata as indented JSON next to the book at path,
+// through a temporary file so readers never see a partial sidecar.
+func WriteSidecar(path string, metadata any) error {
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata sidecar: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), sidecarTempPrefix+"*")
+	if err != nil {
+		return fmt.Errorf("failed to write metadata sidecar: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metadata sidecar: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metadata sidecar: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path+SidecarSuffix); err != nil {
+		return fmt.Errorf("failed to write metadata sidecar: %w", err)
+	}
+
+	return nil
+}
+
+// removeSidecar deletes the sidecar of entry, if any. A sidecar left behind
+// only holds metadata, so failures are ignored.
+func removeSidecar(root string, entry Entry) {
+	os.Remove(filepath.Join(root, entry.File+SidecarSuffix))
+}
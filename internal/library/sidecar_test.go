@@ -0,0 +1,38 @@
+package library
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSidecar(t *testing.T) {
+	root := t.TempDir()
+	book := filepath.Join(root, "Dune.epub")
+	os.WriteFile(book, []byte("x"), 0o644)
+
+	if err := WriteSidecar(book, map[string]string{"title": "Dune"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(book + SidecarSuffix)
+	if err != nil {
+		t.Fatalf("Expected a sidecar next to the book: %v", err)
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal(data, &metadata); err != nil || metadata["title"] != "Dune" {
+		t.Errorf("Expected title 'Dune', got '%s'", data)
+	}
+
+	err = Update(root, func(index *Index) error {
+		index.Add(Entry{Hash: "dune", File: "Dune.epub"})
+		return index.Remove("dune")
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(book + SidecarSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the sidecar to be removed with its book, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package library
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ManifestFile is the name of the checksum manifest written by Verify, in the
+// format of sha256sum.
+const ManifestFile = "SHA256SUMS"
+
+// Outcomes of verifying a book of the library.
+const (
+	StatusOK = "ok"
+	// StatusCorrupted files no longer hash to the MD5 they were saved under.
+	StatusCorrupted = "corrupted"
+	// StatusMissing files are indexed but gone from the download path.
+	StatusMissing = "missing"
+	// StatusUnverified files were saved without an MD5 to compare with, such
+	// as papers fetched from a direct link.
+	StatusUnverified = "unverified"
+)
+
+// Check is the outcome of verifying one indexed book.
+type Check struct {
+	Hash   string `json:"md5,omitempty"`
+	File   string `json:"file"`
+	Status string `json:"status"`
+	SHA256 string `json:"sha256,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Verify re-hashes every book indexed in root and compares it with the MD5 it
+// was saved under. The SHA-256 of the books that are intact or could not be
+// verified is written to the manifest at path, ManifestFile in root when path
+// is empty. Checks are returned sorted by file name.
+func Verify(root, path string) ([]Check, error) {
+	index, err := Load(root)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := make([]Check, 0, len(index.Entries))
+	for _, entry := range index.Entries {
+		checks = append(checks, verifyEntry(root, entry))
+	}
+	sort.Slice(checks, func(a, b int) bool {
+		return checks[a].File < checks[b].File
+	})
+
+	if path == "" {
+		path = filepath.Join(root, ManifestFile)
+	}
+	if err := writeManifest(path, checks); err != nil {
+		return checks, err
+	}
+
+	return checks, nil
+}
+
+// verifyEntry hashes the file of entry with MD5 and SHA-256 in a single read.
+func verifyEntry(root string, entry Entry) Check {
+	check := Check{Hash: entry.Hash, File: entry.File}
+
+	file, err := os.Open(filepath.Join(root, entry.File))
+	if errors.Is(err, os.ErrNotExist) {
+		check.Status = StatusMissing
+		return check
+	}
+	if err != nil {
+		check.Status = StatusCorrupted
+		check.Error = err.Error()
+		return check
+	}
+	defer file.Close()
+
+	md5Hash, sha256Hash := md5.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash), file); err != nil {
+		check.Status = StatusCorrupted
+		check.Error = err.Error()
+		return check
+	}
+	check.SHA256 = hex.EncodeToString(sha256Hash.Sum(nil))
+
+	switch sum := hex.EncodeToString(md5Hash.Sum(nil)); {
+	case entry.Hash == "":
+		check.Status = StatusUnverified
+	case strings.EqualFold(sum, entry.Hash):
+		check.Status = StatusOK
+	default:
+		check.Status = StatusCorrupted
+		check.Error = fmt.Sprintf("MD5 is %s", sum)
+	}
+
+	return check
+}
+
+// writeManifest writes the SHA-256 of the intact and unverified books as
+// sha256sum does, through a temporary file so readers never see a partial
+// manifest. Corrupted books are left out, so the manifest never vouches for
+// them.
+func writeManifest(path string, checks []Check) error {
+	var manifest strings.Builder
+	for _, check := range checks {
+		if check.Status == StatusOK || check.Status == StatusUnverified {
+			fmt.Fprintf(&manifest, "%s  %s\n", check.SHA256, check.File)
+		}
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write checksum manifest: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(manifest.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checksum manifest: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checksum manifest: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write checksum manifest: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package library
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	root := t.TempDir()
+
+	sum := func(data string) (string, string) {
+		md5Sum := md5.Sum([]byte(data))
+		sha256Sum := sha256.Sum256([]byte(data))
+		return hex.EncodeToString(md5Sum[:]), hex.EncodeToString(sha256Sum[:])
+	}
+	intactMD5, intactSHA256 := sum("dune")
+	corruptedMD5, _ := sum("emma")
+	_, paperSHA256 := sum("paper")
+
+	os.WriteFile(filepath.Join(root, "dune.epub"), []byte("dune"), 0o644)
+	os.WriteFile(filepath.Join(root, "emma.epub"), []byte("truncated"), 0o644)
+	os.WriteFile(filepath.Join(root, "paper.pdf"), []byte("paper"), 0o644)
+	err := Update(root, func(index *Index) error {
+		index.Add(Entry{Hash: strings.ToUpper(intactMD5), File: "dune.epub"})
+		index.Add(Entry{Hash: corruptedMD5, File: "emma.epub"})
+		index.Add(Entry{Hash: "", File: "paper.pdf"})
+		index.Add(Entry{Hash: "d6e1dc51a50726f00ec438af21952a45", File: "solaris.epub"})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	checks, err := Verify(root, "")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"dune.epub":    StatusOK,
+		"emma.epub":    StatusCorrupted,
+		"paper.pdf":    StatusUnverified,
+		"solaris.epub": StatusMissing,
+	}
+	if len(checks) != len(expected) {
+		t.Fatalf("Expected %d checks, got %+v", len(expected), checks)
+	}
+	for _, check := range checks {
+		if check.Status != expected[check.File] {
+			t.Errorf("Expected status '%s' for %s, got '%s'", expected[check.File], check.File, check.Status)
+		}
+	}
+
+	manifest, err := os.ReadFile(filepath.Join(root, ManifestFile))
+	if err != nil {
+		t.Fatalf("Failed to read manifest: %v", err)
+	}
+	if string(manifest) != intactSHA256+"  dune.epub\n"+paperSHA256+"  paper.pdf\n" {
+		t.Errorf("Unexpected manifest:\n%s", manifest)
+	}
+}
@@ -12,10 +12,10 @@ var logger *zap.Logger
 func init() {
 	var err error
 
-	// Check if we're running the MCP server
+	// Check if we're running the MCP server, over stdio or HTTP
 	isMCPMode := false
 	for _, arg := range os.Args[1:] {
-		if arg == "mcp" {
+		if arg == "mcp" || arg == "http" {
 			isMCPMode = true
 			break
 		}
@@ -0,0 +1,65 @@
+package metrics
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Counter is a monotonically increasing count, safe for concurrent use.
+type Counter struct {
+	value atomic.Int64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+// Value returns the current count.
+func (c *Counter) Value() int64 {
+	return c.value.Load()
+}
+
+// The process-wide counters.
+var (
+	// Searches counts the searches served, online and offline.
+	Searches Counter
+	// Downloads counts the download requests that succeeded, whether the
+	// file was saved or its link handed out.
+	Downloads Counter
+	// CacheHits and CacheMisses count the lookups of the download link cache.
+	CacheHits   Counter
+	CacheMisses Counter
+)
+
+var started = time.Now()
+
+// Snapshot is a point-in-time view of the counters.
+type Snapshot struct {
+	Started            time.Time `json:"started"`
+	UptimeSeconds      int64     `json:"uptime_seconds"`
+	Searches           int64     `json:"searches"`
+	DownloadsCompleted int64     `json:"downloads_completed"`
+	CacheHits          int64     `json:"cache_hits"`
+	CacheMisses        int64     `json:"cache_misses"`
+	// CacheHitRate is the share of link cache lookups that hit, between 0
+	// and 1. It is 0 before the first lookup.
+	CacheHitRate float64 `json:"cache_hit_rate"`
+}
+
+// Take returns the current values of the counters.
+func Take() Snapshot {
+	snapshot := Snapshot{
+		Started:            started,
+		UptimeSeconds:      int64(time.Since(started).Seconds()),
+		Searches:           Searches.Value(),
+		DownloadsCompleted: Downloads.Value(),
+		CacheHits:          CacheHits.Value(),
+		CacheMisses:        CacheMisses.Value(),
+	}
+	if lookups := snapshot.CacheHits + snapshot.CacheMisses; lookups > 0 {
+		snapshot.CacheHitRate = float64(snapshot.CacheHits) / float64(lookups)
+	}
+
+	return snapshot
+}
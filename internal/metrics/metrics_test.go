@@ -0,0 +1,22 @@
+package metrics
+
+import "testing"
+
+func TestTake(t *testing.T) {
+	Searches.Inc()
+	CacheHits.Inc()
+	CacheHits.Inc()
+	CacheHits.Inc()
+	CacheMisses.Inc()
+
+	snapshot := Take()
+	if snapshot.Searches != 1 {
+		t.Errorf("Expected 1 search, got %d", snapshot.Searches)
+	}
+	if snapshot.CacheHitRate != 0.75 {
+		t.Errorf("Expected cache hit rate 0.75, got %f", snapshot.CacheHitRate)
+	}
+	if snapshot.UptimeSeconds < 0 {
+		t.Errorf("Expected a non-negative uptime, got %d", snapshot.UptimeSeconds)
+	}
+}
@@ -0,0 +1,93 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/aria2"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// aria2Timeout bounds a download delegated to aria2.
+const aria2Timeout = 30 * time.Minute
+
+// fetchBook stores the file behind downloadURL in the folder of the library
+// layout within the download path with the configured downloader, returning
+// the path of the written file.
+func fetchBook(env *Env, book *anna.Book, downloadURL string) (string, error) {
+	folder := filepath.Join(env.DownloadPath, book.Dir(env.LibraryLayout))
+	if env.Downloader != "aria2c" {
+		return book.FetchProgress(downloadURL, folder, env.MaxFileSizeBytes(), func(progress anna.Progress) {
+			if fetchProgress != nil {
+				fetchProgress(book, progress)
+			}
+			publishProgress(book, progress)
+		})
+	}
+
+	return fetchWithAria2(env, book, downloadURL, folder)
+}
+
+// fetchWithAria2 is like anna.Book.FetchLimited, but lets aria2 fetch the
+// file. aria2 cannot stop at a size limit, so oversized files are only
+// refused once they are complete.
+func fetchWithAria2(env *Env, book *anna.Book, downloadURL, folder string) (string, error) {
+	l := logger.GetLogger()
+
+	if err := os.MkdirAll(folder, 0o755); err != nil {
+		return "", err
+	}
+	filePath, err := book.Path(folder)
+	if err != nil {
+		return "", err
+	}
+
+	// aria2 overwrites the reserved temporary file, which is then renamed like
+	// the built-in downloader does
+	out, err := os.CreateTemp(folder, ".annas-mcp-download-*")
+	if err != nil {
+		return "", err
+	}
+	out.Close()
+	defer os.Remove(out.Name())
+
+	ctx, cancel := context.WithTimeout(context.Background(), aria2Timeout)
+	defer cancel()
+
+	opts := aria2.Options{
+		Binary:      env.Aria2Binary,
+		RPCURL:      env.Aria2RPCURL,
+		RPCSecret:   env.Aria2RPCSecret,
+		Connections: env.Aria2Connections,
+		Proxy:       env.Proxy,
+	}
+	if err := aria2.Download(ctx, opts, downloadURL, folder, filepath.Base(out.Name())); err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(out.Name())
+	if err != nil {
+		return "", err
+	}
+	if maxBytes := env.MaxFileSizeBytes(); maxBytes > 0 && info.Size() > maxBytes {
+		return "", fmt.Errorf("%w: received %d bytes, the limit is %d", anna.ErrFileTooLarge, info.Size(), maxBytes)
+	}
+	if err := os.Chmod(out.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(out.Name(), filePath); err != nil {
+		return "", err
+	}
+
+	l.Info("Downloaded book with aria2",
+		zap.String("path", filePath),
+		zap.Int64("bytes", info.Size()),
+	)
+
+	return filePath, nil
+}
@@ -0,0 +1,62 @@
+package modes
+
+import (
+	"context"
+	"os"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/audit"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// Sources of download requests in the audit log.
+const (
+	auditSourceDownload    = "download"
+	auditSourceKindle      = "send_to_kindle"
+	auditSourcePaper       = "download_paper"
+	auditSourceRefresh     = "refresh_download_url"
+	auditSourceWantToRead  = "sync_want_to_read"
+	auditSourceBestMatch   = "download_best_match"
+	auditSourceReadingList = "import_reading_list"
+	auditSourceSeries      = "complete_series"
+	auditSourceIndexer     = "indexer"
+	auditSourceCLI         = "cli"
+)
+
+// auditDownload records a download request in the audit log, if one is
+// configured, and counts it in the metrics when it succeeded. A saved file is
+// recorded with its size, a request without a path as a handed out link.
+func auditDownload(ctx context.Context, env *Env, source string, book *anna.Book, path string, err error) {
+	if err == nil {
+		metrics.Downloads.Inc()
+	}
+	if env.AuditLog == "" {
+		return
+	}
+
+	entry := audit.Entry{
+		Caller:  auth.CallerFrom(ctx).Name,
+		Source:  source,
+		Hash:    book.Hash,
+		Title:   book.Title,
+		Outcome: audit.OutcomeLink,
+	}
+	switch {
+	case err != nil:
+		entry.Outcome = audit.OutcomeFailed
+		entry.Error = err.Error()
+	case path != "":
+		entry.Outcome = audit.OutcomeSaved
+		if info, statErr := os.Stat(path); statErr == nil {
+			entry.Bytes = info.Size()
+		}
+	}
+
+	// Failing to audit must not fail the download itself
+	if err := audit.Append(env.AuditLog, entry); err != nil {
+		logger.GetLogger().Error("Failed to write audit log", zap.String("path", env.AuditLog), zap.Error(err))
+	}
+}
@@ -0,0 +1,69 @@
+package modes
+
+import (
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/container"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/version"
+	"go.uber.org/zap"
+)
+
+// Authentication modes of the startup banner.
+const (
+	authModeNone   = "none"
+	authModeAPIKey = "api_key"
+	authModeTokens = "tokens"
+	authModeBoth   = "api_key+tokens"
+)
+
+// authMode describes how the HTTP mode authenticates clients with env: the
+// provider name, or what the key provider checks.
+func authMode(env *Env) string {
+	switch env.AuthProvider {
+	case auth.ProviderNone, auth.ProviderBasic, auth.ProviderOIDC:
+		return env.AuthProvider
+	}
+
+	store := tokenStore.Load()
+	tokens := store != nil && store.Len() > 0
+
+	switch {
+	case env.APIKey != "" && tokens:
+		return authModeBoth
+	case env.APIKey != "":
+		return authModeAPIKey
+	case tokens:
+		return authModeTokens
+	}
+	return authModeNone
+}
+
+// logStartup logs the effective configuration of a starting server in a
+// single line, so operators can confirm what the layered configuration
+// resolved to. Secrets are only reported as counts.
+func logStartup(mode string, env *Env) {
+	fields := []zap.Field{
+		zap.String("mode", mode),
+		zap.String("version", version.GetVersion()),
+		zap.Strings("mirrors", env.Mirrors),
+		zap.String("downloadPath", env.DownloadPath),
+		zap.Bool("readOnly", env.ReadOnly),
+		zap.Int("secretKeys", len(env.Keys())),
+		zap.Int("downloadsPerHour", env.DownloadsPerHour),
+		zap.Int("downloadsPerDay", env.DownloadsPerDay),
+	}
+	if mode == "http" {
+		fields = append(fields,
+			zap.String("transport", env.Transport),
+			zap.String("host", env.Host),
+			zap.Int("port", env.Port),
+			zap.String("auth", authMode(env)),
+			zap.Bool("stateless", env.Stateless),
+			zap.Bool("grpc", env.GRPC),
+			zap.String("container", container.Detect()),
+			zap.Int("shutdownGraceSeconds", env.ShutdownGraceSeconds),
+		)
+	}
+
+	logger.GetLogger().Info("Effective configuration", fields...)
+}
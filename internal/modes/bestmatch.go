@@ -0,0 +1,168 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/notify"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// bestMatchQueries returns the searches to try for a wanted book, the ISBN
+// first since it names a single work, then its title and author.
+func bestMatchQueries(params BestMatchParams) []string {
+	queries := make([]string, 0, 2)
+	if isbn := strings.TrimSpace(params.ISBN); isbn != "" {
+		queries = append(queries, isbn)
+	}
+	if query := strings.TrimSpace(params.Title + " " + params.Author); query != "" {
+		queries = append(queries, query)
+	}
+
+	return queries
+}
+
+// pickEdition returns the result closest to the wanted title and author,
+// favoring the configured formats and languages. Results in the requested
+// format and language win when there are any, and results in formats that are
+// not allowed are never picked.
+func pickEdition(env *Env, params BestMatchParams, books []*anna.Book) *anna.Book {
+	candidates := make([]*anna.Book, 0, len(books))
+	for _, book := range books {
+		if book.Format == "" || len(env.AllowedFormats) == 0 || hasFormat(env.AllowedFormats, book.Format) {
+			candidates = append(candidates, book)
+		}
+	}
+	if params.Format != "" {
+		candidates = narrow(candidates, func(book *anna.Book) bool {
+			return strings.EqualFold(book.Format, strings.TrimSpace(params.Format))
+		})
+	}
+	if params.Language != "" {
+		candidates = narrow(candidates, func(book *anna.Book) bool {
+			return anna.MatchesLanguage(book.Language, params.Language)
+		})
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	anna.RankBooks(strings.TrimSpace(params.Title+" "+params.Author), candidates, preferences(env))
+
+	return candidates[0]
+}
+
+// narrow returns the books for which keep holds, or all books when it holds
+// for none.
+func narrow(books []*anna.Book, keep func(*anna.Book) bool) []*anna.Book {
+	kept := make([]*anna.Book, 0, len(books))
+	for _, book := range books {
+		if keep(book) {
+			kept = append(kept, book)
+		}
+	}
+	if len(kept) == 0 {
+		return books
+	}
+
+	return kept
+}
+
+// findBestMatch searches for the wanted book and picks its best edition.
+func findBestMatch(env *Env, params BestMatchParams) (*anna.Book, error) {
+	queries := bestMatchQueries(params)
+	if len(queries) == 0 {
+		return nil, withCode(codeInvalidArgument, "a title, author, or ISBN is required")
+	}
+
+	for _, query := range queries {
+		books, err := anna.FindBook(query)
+		if err != nil {
+			return nil, err
+		}
+		if book := pickEdition(env, params, books); book != nil {
+			return book, nil
+		}
+	}
+
+	return nil, withCode(codeInvalidArgument, "no book matching %q was found", queries[len(queries)-1])
+}
+
+// NewDownloadBestMatchToolHandler creates a handler for the download_best_match tool that uses the provided environment.
+func NewDownloadBestMatchToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, BestMatchParams) (*mcp.CallToolResult, any, error) {
+	dispatcher := newDispatcher(env)
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, params BestMatchParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Download best match command called",
+			zap.String("title", params.Title),
+			zap.String("author", params.Author),
+			zap.String("isbn", params.ISBN),
+			zap.String("format", params.Format),
+			zap.String("language", params.Language),
+		)
+
+		if len(env.Keys()) == 0 {
+			err := errSecretKeyMissing
+			l.Error("Download best match command failed", zap.Error(err))
+			return nil, nil, err
+		}
+		if err := checkDownloadPath(env); err != nil {
+			l.Error("Download best match command failed", zap.Error(err))
+			return nil, nil, err
+		}
+		if err := chargeUsage(ctx, usage.KindSearch); err != nil {
+			l.Error("Download best match command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		book, err := findBestMatch(env, params)
+		if err != nil {
+			l.Error("Download best match command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		left, err := takeDownload(ctx, env)
+		if err != nil {
+			l.Error("Download best match command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		dispatcher.Publish(downloadEvent(notify.EventDownloadQueued, book))
+
+		path, err := saveBook(env, book)
+		auditDownload(ctx, env, auditSourceBestMatch, book, path, err)
+		if err != nil {
+			l.Error("Download best match command failed",
+				zap.String("bookHash", book.Hash),
+				zap.Error(err),
+			)
+			event := downloadEvent(notify.EventDownloadFailed, book)
+			event.Error = err.Error()
+			dispatcher.Publish(event)
+			return nil, nil, err
+		}
+
+		l.Info("Download best match command completed successfully",
+			zap.String("bookHash", book.Hash),
+			zap.String("path", path),
+		)
+
+		dispatcher.Publish(downloadEvent(notify.EventDownloadCompleted, book))
+
+		note, result := savedDetails(env, book)
+		result["path"] = path
+		result["book"] = book
+
+		text := fmt.Sprintf("Book saved to %s%s\n\n%s", path, note, book.String())
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: withAllowance(text, left)}},
+		}, result, nil
+	}
+}
@@ -0,0 +1,46 @@
+package modes
+
+import (
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/config"
+)
+
+func TestPickEdition(t *testing.T) {
+	books := []*anna.Book{
+		{Hash: "messiah", Title: "Dune Messiah", Authors: []string{"Frank Herbert"}, Format: "epub", Language: "English"},
+		{Hash: "djvu", Title: "Dune", Authors: []string{"Frank Herbert"}, Format: "djvu", Language: "English"},
+		{Hash: "pdf", Title: "Dune", Authors: []string{"Frank Herbert"}, Format: "pdf", Language: "English"},
+		{Hash: "epub", Title: "Dune", Authors: []string{"Frank Herbert"}, Format: "epub", Language: "French"},
+	}
+
+	env := config.Defaults()
+	env.AllowedFormats = []string{"pdf", "epub"}
+	env.PreferredLangs = []string{"en"}
+
+	cases := []struct {
+		name     string
+		params   BestMatchParams
+		expected string
+	}{
+		{"Preferences", BestMatchParams{Title: "Dune", Author: "Frank Herbert"}, "pdf"},
+		{"Requested format", BestMatchParams{Title: "Dune", Format: "epub"}, "epub"},
+		{"Allowed formats", BestMatchParams{Title: "Dune", Format: "djvu"}, "pdf"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			book := pickEdition(env, c.params, append([]*anna.Book(nil), books...))
+			if book == nil || book.Hash != c.expected {
+				t.Errorf("Expected edition '%s', got %v", c.expected, book)
+			}
+		})
+	}
+
+	if book := pickEdition(env, BestMatchParams{Title: "Dune"}, books[1:2]); book != nil {
+		t.Errorf("Expected no edition in an allowed format, got '%s'", book.Hash)
+	}
+	if queries := bestMatchQueries(BestMatchParams{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441013593"}); len(queries) != 2 || queries[0] != "9780441013593" || queries[1] != "Dune Frank Herbert" {
+		t.Errorf("Expected the ISBN searched before the title and author, got %v", queries)
+	}
+}
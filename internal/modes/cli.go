@@ -2,31 +2,123 @@ package modes
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/charmbracelet/fang"
 	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/audit"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/config"
+	"github.com/iosifache/annas-mcp/internal/i18n"
+	"github.com/iosifache/annas-mcp/internal/library"
 	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/notify"
+	"github.com/iosifache/annas-mcp/internal/offline"
+	"github.com/iosifache/annas-mcp/internal/paths"
+	"github.com/iosifache/annas-mcp/internal/service"
+	"github.com/iosifache/annas-mcp/internal/shelves"
+	"github.com/iosifache/annas-mcp/internal/state"
 	"github.com/iosifache/annas-mcp/internal/version"
 	"github.com/joho/godotenv"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"go.uber.org/zap"
 )
 
+// dotEnvCandidates are the dotenv files looked up without an explicit path:
+// .env in the working directory, next to the binary, and in the config
+// directory, since MCP clients often launch the server from an arbitrary
+// working directory.
+func dotEnvCandidates() []string {
+	candidates := []string{".env"}
+	if executable, err := os.Executable(); err == nil {
+		candidates = append(candidates, filepath.Join(filepath.Dir(executable), ".env"))
+	}
+	if file := paths.File(paths.Config, ".env"); file != "" {
+		candidates = append(candidates, file)
+	}
+
+	return candidates
+}
+
+// findDotEnv returns the first existing dotenv candidate, or an empty path.
+func findDotEnv() string {
+	for _, candidate := range dotEnvCandidates() {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+
+	return ""
+}
+
+// loadDotEnv loads the given dotenv file, failing if it cannot be read, or
+// the first of dotEnvCandidates without an explicit path.
+func loadDotEnv(path string) error {
+	l := logger.GetLogger()
+
+	if path != "" {
+		if err := godotenv.Load(path); err != nil {
+			return fmt.Errorf("failed to load env file %s: %w", path, err)
+		}
+		return nil
+	}
+
+	candidate := findDotEnv()
+	if candidate == "" {
+		l.Debug("No .env file found", zap.Strings("candidates", dotEnvCandidates()))
+		return nil
+	}
+	if err := godotenv.Load(candidate); err != nil {
+		l.Warn("Error loading .env file", zap.String("path", candidate), zap.Error(err))
+	}
+
+	return nil
+}
+
+// uiLanguage returns the value of the --lang-ui flag in args, which is needed
+// before the commands and their help are built, so ahead of flag parsing.
+func uiLanguage(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if value, ok := strings.CutPrefix(arg, "--lang-ui="); ok {
+			return value
+		}
+		if arg == "--lang-ui" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+
+	return ""
+}
+
 func StartCLI() {
 	l := logger.GetLogger()
 	defer l.Sync()
 
-	if err := godotenv.Load(); err != nil {
-		l.Warn("Error loading .env file", zap.Error(err))
+	lang := uiLanguage(os.Args[1:])
+	if lang == "" {
+		lang = i18n.Detect(os.Getenv)
 	}
+	// Reported once the flags are parsed, the messages stay in English
+	langErr := i18n.Use(lang)
+	// Taken before the dotenv file fills in the environment
+	exported := exportedEnv()
 
 	rootCmd := &cobra.Command{
 		Use:   "annas-mcp",
-		Short: "Anna's Archive MCP CLI",
-		Long:  "A command-line interface for searching and downloading books from Anna's Archive.",
+		Short: i18n.T("Anna's Archive MCP CLI"),
+		Long:  i18n.T("A command-line interface for searching and downloading books from Anna's Archive."),
 		CompletionOptions: cobra.CompletionOptions{
 			DisableDefaultCmd: true,
 		},
@@ -37,15 +129,123 @@ func StartCLI() {
 	}
 	rootCmd.SetVersionTemplate("{{.Version}}\n")
 
+	// Declared ahead so the root hooks can tell them apart
+	var versionCmd *cobra.Command
+	var statsCmd *cobra.Command
+	var commandEnv *Env
+
+	var configFile string
+	var envFile string
+	var serviceEnvFile string
+	var profile string
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", i18n.T("Path to a JSON config file (defaults to ANNAS_CONFIG)"))
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", i18n.T("Name of the config file profile to use (defaults to ANNAS_PROFILE)"))
+	rootCmd.PersistentFlags().Bool("read-only", false, i18n.T("Only allow searches and metadata lookups (reads from ANNAS_READ_ONLY if set)"))
+	rootCmd.PersistentFlags().StringVar(&envFile, "env-file", "", i18n.T("Path to a dotenv file (defaults to .env in the working directory or next to the binary)"))
+	rootCmd.PersistentFlags().StringVar(&serviceEnvFile, service.EnvFileFlag, "", "Path to the settings exported when installing the service, set by the service")
+	_ = rootCmd.PersistentFlags().MarkHidden(service.EnvFileFlag)
+	rootCmd.PersistentFlags().String("lang-ui", "", i18n.Sprintf("Language of the CLI output: %s (defaults to the LANG locale)", strings.Join(i18n.Languages(), ", ")))
+	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if langErr != nil {
+			return langErr
+		}
+		// Loaded first, since the shell values had precedence over the
+		// dotenv file at install time
+		if serviceEnvFile != "" {
+			if err := loadServiceEnv(serviceEnvFile); err != nil {
+				return err
+			}
+		}
+		if err := loadDotEnv(envFile); err != nil {
+			return err
+		}
+
+		loadOptions = config.Options{File: configFile, Profile: profile, Flags: cmd.Flags()}
+
+		cfg, err := config.Load(loadOptions)
+		if err != nil {
+			return err
+		}
+		// The server modes check on their own schedule, and version --check
+		// checks explicitly
+		if cmd != versionCmd && cmd.Name() != "mcp" && cmd.Name() != "http" {
+			startUpdateCheck(cfg)
+		}
+		commandEnv = cfg
+		return configureClient(cfg)
+	}
+	rootCmd.PersistentPostRun = func(cmd *cobra.Command, args []string) {
+		// The server modes count their own runs, and looking at the stats is
+		// not a use worth counting
+		if commandEnv != nil && cmd != rootCmd && cmd != statsCmd && cmd.Name() != "mcp" && cmd.Name() != "http" {
+			newRun(commandEnv, "cli").flush()
+		}
+		warnIfOutdated()
+	}
+
+	var transliterateSearch bool
+	var rankSearch bool
+	var searchLimit int
+	var searchLanguages []string
+	var searchFormats []string
+
 	searchCmd := &cobra.Command{
 		Use:   "search [term]",
-		Short: "Search for books",
+		Short: i18n.T("Search for books"),
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			searchTerm := args[0]
 			l.Info("Search command called", zap.String("searchTerm", searchTerm))
 
-			books, err := anna.FindBook(searchTerm)
+			if err := validateLimit(searchLimit); err != nil {
+				return err
+			}
+			languages, err := parseLanguages(searchLanguages)
+			if err != nil {
+				return err
+			}
+			formats, err := parseFormats(searchFormats)
+			if err != nil {
+				return err
+			}
+			filters := anna.Filters{Languages: languages, Formats: formats}
+
+			// Print results as they are parsed instead of waiting for the whole
+			// page, unless they have to be ranked first
+			rank := rankSearch
+			var prefs anna.Preferences
+			if env, err := GetEnv(); err == nil {
+				prefs = preferences(env)
+				rank = rank || env.RankResults || !prefs.IsZero()
+			}
+			count := 0
+			seen := make(map[string]bool)
+			var ranked []*anna.Book
+			printBook := func(book *anna.Book) {
+				if count > 0 {
+					fmt.Println()
+				}
+				count++
+				i18n.Printf("Book %d:\n%s\n", count, book.String())
+			}
+			show := func(book *anna.Book) bool {
+				if seen[book.Hash] {
+					return true
+				}
+				seen[book.Hash] = true
+				if rank {
+					ranked = append(ranked, book)
+					return searchLimit == 0 || len(ranked) < searchLimit
+				}
+				printBook(book)
+				return searchLimit == 0 || count < searchLimit
+			}
+			class, err := anna.StreamBooksFiltered(searchTerm, filters, searchLimit, show)
+			if err == nil && transliterateSearch && (searchLimit == 0 || len(seen) < searchLimit) {
+				if variant := anna.Transliterate(searchTerm); variant != searchTerm {
+					_, err = anna.StreamBooksFiltered(variant, filters, searchLimit, show)
+				}
+			}
 			if err != nil {
 				l.Error("Search command failed",
 					zap.String("searchTerm", searchTerm),
@@ -54,59 +254,175 @@ func StartCLI() {
 				return fmt.Errorf("failed to search books: %w", err)
 			}
 
-			if len(books) == 0 {
-				fmt.Println("No books found.")
-				return nil
+			anna.RankBooks(searchTerm, ranked, prefs)
+			for _, book := range ranked {
+				printBook(book)
 			}
 
-			for i, book := range books {
-				fmt.Printf("Book %d:\n%s\n", i+1, book.String())
-				if i < len(books)-1 {
-					fmt.Println()
-				}
+			if count == 0 {
+				fmt.Println(strings.TrimRight(noResultsText(searchTerm, class, anna.Suggest(searchTerm, filters)), "\n"))
+				return nil
 			}
 
 			l.Info("Search command completed successfully",
 				zap.String("searchTerm", searchTerm),
-				zap.Int("resultsCount", len(books)),
+				zap.Int("resultsCount", count),
 			)
 
 			return nil
 		},
 	}
 
+	searchCmd.Flags().BoolVar(&transliterateSearch, "transliterate", false, i18n.T("Also search the term with diacritics removed and Cyrillic or Greek romanized"))
+	searchCmd.Flags().BoolVar(&rankSearch, "rank", false, i18n.T("Print the results ordered by how closely their title and authors match the term, once all are fetched"))
+	searchCmd.Flags().IntVar(&searchLimit, "limit", 0, i18n.T("Maximum number of results, fetched from as many result pages as needed (default: the first page)"))
+	searchCmd.Flags().StringSliceVar(&searchLanguages, "language", nil, i18n.T("Only print results in these language codes, e.g. en,de"))
+	searchCmd.Flags().StringSliceVar(&searchFormats, "format", nil, i18n.T("Only print results in these formats, e.g. epub,pdf"))
+
+	var enrichMetadataFlag bool
+	var descriptionLength int
+
+	metadataCmd := &cobra.Command{
+		Use:   "metadata [hash]",
+		Short: i18n.T("Show the detailed record of a book by its MD5 hash"),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookHash, err := anna.NormalizeHash(args[0])
+			if err != nil {
+				return err
+			}
+			l.Info("Metadata command called", zap.String("bookHash", bookHash))
+
+			metadata, err := fetchMetadata(cmd.Context(), bookHash, enrichMetadataFlag, descriptionLength)
+			if err != nil {
+				l.Error("Metadata command failed",
+					zap.String("bookHash", bookHash),
+					zap.Error(err),
+				)
+				return fmt.Errorf("failed to get metadata: %w", err)
+			}
+
+			data, err := json.MarshalIndent(metadata, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+
+			return nil
+		},
+	}
+	metadataCmd.Flags().BoolVar(&enrichMetadataFlag, "enrich", false, i18n.T("Augment the record with OpenLibrary data looked up by ISBN"))
+	metadataCmd.Flags().IntVar(&descriptionLength, "description-length", defaultDescriptionLength, i18n.T("Truncate the description to this many characters (-1 for the whole text)"))
+
+	var sendKindle bool
+	var saveFile bool
+	var bookTitle string
+	var bookFormat string
+	var downloadProgress string
+
 	downloadCmd := &cobra.Command{
 		Use:   "download [hash]",
-		Short: "Get download URL for a book by its MD5 hash",
-		Long:  "Get the download URL for a book by its MD5 hash. Requires ANNAS_SECRET_KEY environment variable.",
+		Short: i18n.T("Get download URL for a book by its MD5 hash"),
+		Long:  i18n.T("Get the download URL for a book by its MD5 hash. Requires ANNAS_SECRET_KEY environment variable."),
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			bookHash := args[0]
+			bookHash, err := anna.NormalizeHash(args[0])
+			if err != nil {
+				return err
+			}
 
 			l.Info("Download command called",
 				zap.String("bookHash", bookHash),
 			)
 
 			env, err := GetEnv()
+			if env != nil && env.ReadOnly {
+				return errReadOnly
+			}
 			if err != nil {
 				l.Error("Failed to get environment variables", zap.Error(err))
 				return fmt.Errorf("failed to get environment: %w", err)
 			}
 
 			book := &anna.Book{
-				Hash: bookHash,
+				Hash:   bookHash,
+				Title:  bookTitle,
+				Format: bookFormat,
+			}
+
+			progress, err := startProgress(downloadProgress)
+			if err != nil {
+				return err
+			}
+
+			dispatcher := newDispatcher(env)
+			defer dispatcher.Wait()
+
+			dispatcher.Publish(downloadEvent(notify.EventDownloadQueued, book))
+
+			if sendKindle {
+				path, err := sendToKindle(env, book)
+				auditDownload(cmd.Context(), env, auditSourceCLI, book, path, err)
+				if progress != nil {
+					progress.result(book, path, err)
+				}
+				if err != nil {
+					l.Error("Download command failed",
+						zap.String("bookHash", bookHash),
+						zap.Error(err),
+					)
+					event := downloadEvent(notify.EventDownloadFailed, book)
+					event.Error = err.Error()
+					dispatcher.Publish(event)
+					return err
+				}
+
+				i18n.Printf("Sent %s to %s\n", path, env.KindleEmail)
+				dispatcher.Publish(downloadEvent(notify.EventDownloadCompleted, book))
+				return nil
+			}
+
+			if saveFile {
+				path, err := saveBook(env, book)
+				auditDownload(cmd.Context(), env, auditSourceCLI, book, path, err)
+				if progress != nil {
+					progress.result(book, path, err)
+				}
+				if err != nil {
+					l.Error("Download command failed",
+						zap.String("bookHash", bookHash),
+						zap.Error(err),
+					)
+					event := downloadEvent(notify.EventDownloadFailed, book)
+					event.Error = err.Error()
+					dispatcher.Publish(event)
+					return err
+				}
+
+				note, _ := savedDetails(env, book)
+				i18n.Printf("Book saved to %s%s\n", path, note)
+				dispatcher.Publish(downloadEvent(notify.EventDownloadCompleted, book))
+				return nil
 			}
 
-			url, err := book.GetDownloadURL(env.SecretKey)
+			info, err := resolveLink(env, book)
+			auditDownload(cmd.Context(), env, auditSourceCLI, book, "", err)
 			if err != nil {
 				l.Error("Download command failed",
 					zap.String("bookHash", bookHash),
 					zap.Error(err),
 				)
+				event := downloadEvent(notify.EventDownloadFailed, book)
+				event.Error = err.Error()
+				dispatcher.Publish(event)
 				return fmt.Errorf("failed to get download URL: %w", err)
 			}
 
-			fmt.Printf("Download URL: %s\n", url)
+			i18n.Printf("Download URL: %s\n", info.URL)
+
+			event := downloadEvent(notify.EventDownloadCompleted, book)
+			event.URL = info.URL
+			dispatcher.Publish(event)
 
 			l.Info("Download command completed successfully",
 				zap.String("bookHash", bookHash),
@@ -116,10 +432,291 @@ func StartCLI() {
 		},
 	}
 
+	downloadCmd.Flags().BoolVar(&saveFile, "save", false, i18n.T("Save the book to ANNAS_DOWNLOAD_PATH instead of printing a link"))
+	downloadCmd.Flags().BoolVar(&sendKindle, "kindle", false, i18n.T("Download the book and email it to ANNAS_KINDLE_EMAIL"))
+	downloadCmd.Flags().StringVar(&bookTitle, "title", "", i18n.T("Book title, used for the saved filename"))
+	downloadCmd.Flags().StringVar(&bookFormat, "format", "", i18n.T("Book format, used as the saved file extension"))
+	downloadCmd.Flags().String("server", "", i18n.T("Fast partner server to download from, by index or domain (reads from ANNAS_DOWNLOAD_SERVER if set)"))
+	downloadCmd.Flags().StringVar(&downloadProgress, "progress", progressNone, i18n.T("Report the progress of saved downloads on stderr: none or json (NDJSON events)"))
+
+	var bestMatch BestMatchParams
+
+	bestMatchCmd := &cobra.Command{
+		Use:   "best-match [title]",
+		Short: i18n.T("Search a book and save its best edition"),
+		Long:  i18n.T("Search a book by title and author or ISBN, pick the edition closest to the query and ANNAS_PREFERRED_FORMATS/ANNAS_PREFERRED_LANGS, and save it to ANNAS_DOWNLOAD_PATH. Requires ANNAS_SECRET_KEY environment variable."),
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params := bestMatch
+			if len(args) == 1 {
+				params.Title = args[0]
+			}
+
+			l.Info("Download best match command called",
+				zap.String("title", params.Title),
+				zap.String("author", params.Author),
+				zap.String("isbn", params.ISBN),
+			)
+
+			env, err := GetEnv()
+			if env != nil && env.ReadOnly {
+				return errReadOnly
+			}
+			if err != nil {
+				l.Error("Failed to get environment variables", zap.Error(err))
+				return fmt.Errorf("failed to get environment: %w", err)
+			}
+			if len(env.Keys()) == 0 {
+				return errSecretKeyMissing
+			}
+			if err := checkDownloadPath(env); err != nil {
+				return err
+			}
+
+			book, err := findBestMatch(env, params)
+			if err != nil {
+				l.Error("Download best match command failed", zap.Error(err))
+				return err
+			}
+
+			dispatcher := newDispatcher(env)
+			defer dispatcher.Wait()
+
+			dispatcher.Publish(downloadEvent(notify.EventDownloadQueued, book))
+
+			path, err := saveBook(env, book)
+			auditDownload(cmd.Context(), env, auditSourceCLI, book, path, err)
+			if err != nil {
+				l.Error("Download best match command failed",
+					zap.String("bookHash", book.Hash),
+					zap.Error(err),
+				)
+				event := downloadEvent(notify.EventDownloadFailed, book)
+				event.Error = err.Error()
+				dispatcher.Publish(event)
+				return err
+			}
+
+			note, _ := savedDetails(env, book)
+			i18n.Printf("Book saved to %s%s\n\n%s\n", path, note, book.String())
+			dispatcher.Publish(downloadEvent(notify.EventDownloadCompleted, book))
+
+			l.Info("Download best match command completed successfully",
+				zap.String("bookHash", book.Hash),
+				zap.String("path", path),
+			)
+
+			return nil
+		},
+	}
+
+	bestMatchCmd.Flags().StringVar(&bestMatch.Author, "author", "", i18n.T("Author of the wanted book"))
+	bestMatchCmd.Flags().StringVar(&bestMatch.ISBN, "isbn", "", i18n.T("ISBN of the wanted book, searched before the title and author"))
+	bestMatchCmd.Flags().StringVar(&bestMatch.Format, "format", "", i18n.T("Wanted format, falling back to others when no result has it (default: ANNAS_PREFERRED_FORMATS)"))
+	bestMatchCmd.Flags().StringVar(&bestMatch.Language, "language", "", i18n.T("Wanted language as ISO 639-1 code or name, falling back to others when no result has it (default: ANNAS_PREFERRED_LANGS)"))
+
+	var savePaperFile bool
+	var paperProgress string
+
+	paperCmd := &cobra.Command{
+		Use:   "paper [doi]",
+		Short: i18n.T("Get download URL for a scientific paper by its DOI"),
+		Long:  i18n.T("Get the download URL for a scientific paper by its DOI through SciDB. Papers without a direct SciDB link require ANNAS_SECRET_KEY."),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			doi, err := anna.NormalizeDOI(args[0])
+			if err != nil {
+				return err
+			}
+
+			l.Info("Download paper command called", zap.String("doi", doi))
+
+			env, err := GetEnv()
+			if env == nil {
+				l.Error("Failed to get environment variables", zap.Error(err))
+				return fmt.Errorf("failed to get environment: %w", err)
+			}
+			if env.ReadOnly {
+				return errReadOnly
+			}
+
+			progress, err := startProgress(paperProgress)
+			if err != nil {
+				return err
+			}
+
+			paper, err := anna.GetPaper(doi)
+			if err != nil {
+				l.Error("Download paper command failed", zap.String("doi", doi), zap.Error(err))
+				return err
+			}
+			book := paper.Book()
+
+			if savePaperFile {
+				path, err := savePaper(env, paper)
+				auditDownload(cmd.Context(), env, auditSourceCLI, book, path, err)
+				if progress != nil {
+					progress.result(book, path, err)
+				}
+				if err != nil {
+					l.Error("Download paper command failed", zap.String("doi", doi), zap.Error(err))
+					return err
+				}
+
+				note, _ := savedDetails(env, book)
+				i18n.Printf("Paper saved to %s%s\n", path, note)
+				return nil
+			}
+
+			downloadURL, err := resolvePaper(env, paper)
+			auditDownload(cmd.Context(), env, auditSourceCLI, book, "", err)
+			if err != nil {
+				l.Error("Download paper command failed", zap.String("doi", doi), zap.Error(err))
+				return err
+			}
+
+			i18n.Printf("Title: %s\nDownload URL: %s\n", book.Title, downloadURL)
+			l.Info("Download paper command completed successfully", zap.String("doi", doi))
+
+			return nil
+		},
+	}
+
+	paperCmd.Flags().BoolVar(&savePaperFile, "save", false, i18n.T("Save the paper to ANNAS_DOWNLOAD_PATH instead of printing a link"))
+	paperCmd.Flags().StringVar(&paperProgress, "progress", progressNone, i18n.T("Report the progress of saved downloads on stderr: none or json (NDJSON events)"))
+
+	var goodreadsCSV string
+	var useHardcover bool
+	var downloadMatches bool
+
+	wantToReadCmd := &cobra.Command{
+		Use:   "want-to-read",
+		Short: i18n.T("Match a Goodreads or Hardcover want-to-read shelf against Anna's Archive"),
+		Long:  i18n.T("Match a want-to-read shelf (Goodreads CSV export or Hardcover API via ANNAS_HARDCOVER_TOKEN) against Anna's Archive and optionally download the best matches."),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The secret key is only needed when matches are downloaded
+			env, err := GetEnv()
+			if env == nil || (err != nil && downloadMatches) {
+				l.Error("Failed to get environment variables", zap.Error(err))
+				return fmt.Errorf("failed to get environment: %w", err)
+			}
+			if downloadMatches {
+				if env.ReadOnly {
+					return errReadOnly
+				}
+				if err := checkDownloadPath(env); err != nil {
+					return err
+				}
+			}
+
+			source := "goodreads"
+			var csvData io.Reader
+			if useHardcover {
+				source = "hardcover"
+			} else if goodreadsCSV != "" {
+				file, err := os.Open(goodreadsCSV)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				csvData = file
+			}
+
+			entries, err := loadShelf(cmd.Context(), env, source, csvData)
+			if err != nil {
+				return err
+			}
+
+			for _, result := range syncShelf(cmd.Context(), env, entries, downloadMatches) {
+				switch {
+				case result.Error != "":
+					fmt.Printf("✗ %s: %s\n", result.Entry.Title, result.Error)
+				case result.Path != "":
+					fmt.Printf("✓ %s -> %s\n", result.Entry.Title, result.Path)
+				default:
+					fmt.Printf("✓ %s -> %s (%s)\n", result.Entry.Title, result.Match.Hash, result.Match.Format)
+				}
+			}
+
+			return nil
+		},
+	}
+	wantToReadCmd.Flags().StringVar(&goodreadsCSV, "goodreads", "", i18n.T("Path to a Goodreads library export CSV"))
+	wantToReadCmd.Flags().BoolVar(&useHardcover, "hardcover", false, i18n.T("Read the shelf from Hardcover using ANNAS_HARDCOVER_TOKEN"))
+	wantToReadCmd.Flags().BoolVar(&downloadMatches, "download", false, i18n.T("Save the best match of every entry to ANNAS_DOWNLOAD_PATH"))
+
+	var downloadList bool
+	var minConfidence float64
+
+	readingListCmd := &cobra.Command{
+		Use:   "reading-list [file]",
+		Short: i18n.T("Match a CSV or Markdown reading list against Anna's Archive"),
+		Long:  i18n.T("Match every entry of a reading list (a CSV file or Markdown table with title and optional author and ISBN columns, or a Markdown list of \"Title by Author\" items) against Anna's Archive and optionally download the confident matches."),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if minConfidence < 0 || minConfidence > 1 {
+				return fmt.Errorf("invalid --min-confidence %v (must be between 0 and 1)", minConfidence)
+			}
+
+			// The secret key is only needed when matches are downloaded
+			env, err := GetEnv()
+			if env == nil || (err != nil && downloadList) {
+				l.Error("Failed to get environment variables", zap.Error(err))
+				return fmt.Errorf("failed to get environment: %w", err)
+			}
+			if downloadList {
+				if env.ReadOnly {
+					return errReadOnly
+				}
+				if err := checkDownloadPath(env); err != nil {
+					return err
+				}
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			entries, err := shelves.ParseReadingList(file)
+			if err != nil {
+				return err
+			}
+
+			results := matchReadingList(env, entries)
+			if downloadList {
+				queued := queueMatches(cmd.Context(), env, results, minConfidence)
+				paths := saveQueued(cmd.Context(), env, queuedBooks(results, queued), auditSourceReadingList)
+				for i, index := range queued {
+					results[index].Path = paths[i]
+				}
+			}
+
+			for _, result := range results {
+				switch {
+				case result.Error != "":
+					fmt.Printf("✗ %s: %s\n", result.Entry.Title, result.Error)
+				case result.Path != "":
+					fmt.Printf("✓ %s -> %s (%.2f)\n", result.Entry.Title, result.Path, result.Confidence)
+				case result.Queued:
+					i18n.Printf("✗ %s -> %s (%.2f): download failed\n", result.Entry.Title, result.Match.Hash, result.Confidence)
+				default:
+					fmt.Printf("✓ %s -> %s (%s, %.2f)\n", result.Entry.Title, result.Match.Hash, result.Match.Format, result.Confidence)
+				}
+			}
+
+			return nil
+		},
+	}
+	readingListCmd.Flags().BoolVar(&downloadList, "download", false, i18n.T("Save the confident matches to ANNAS_DOWNLOAD_PATH"))
+	readingListCmd.Flags().Float64Var(&minConfidence, "min-confidence", defaultMinConfidence, i18n.T("Confidence between 0 and 1 a match needs to be downloaded"))
+
 	mcpCmd := &cobra.Command{
 		Use:   "mcp",
-		Short: "Start the MCP server (stdio)",
-		Long:  "Start the Model Context Protocol (MCP) server using stdio transport for integration with AI assistants.",
+		Short: i18n.T("Start the MCP server (stdio)"),
+		Long:  i18n.T("Start the Model Context Protocol (MCP) server using stdio transport for integration with AI assistants."),
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Exit CLI mode and start MCP server
@@ -128,46 +725,1187 @@ func StartCLI() {
 		},
 	}
 
-	var httpHost string
-	var httpPort int
-	var httpTransport string
+	defaults := config.Defaults()
 
-	// Get default port from PORT env var (used by Render, Railway, Heroku, etc.)
-	defaultPort := 8080
-	if portStr := os.Getenv("PORT"); portStr != "" {
-		if port, err := strconv.Atoi(portStr); err == nil && port > 0 {
-			defaultPort = port
-		}
-	}
+	var workDir string
 
 	httpCmd := &cobra.Command{
 		Use:   "http",
-		Short: "Start the MCP server with HTTP transport",
-		Long:  "Start the Model Context Protocol (MCP) server using HTTP transport (SSE or Streamable HTTP) for remote access.",
+		Short: i18n.T("Start the MCP server with HTTP transport"),
+		Long:  i18n.T("Start the Model Context Protocol (MCP) server using HTTP transport (SSE, Streamable HTTP, or WebSocket) for remote access."),
 		Args:  cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			config := HTTPServerConfig{
-				Host:          httpHost,
-				Port:          httpPort,
-				TransportType: httpTransport,
+			// Set by the Windows service, which starts in the system folder
+			if workDir != "" {
+				if err := os.Chdir(workDir); err != nil {
+					return err
+				}
+			}
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+			// Read-only deployments never write to the download path
+			if !cfg.ReadOnly {
+				if err := checkDownloadPath(cfg); err != nil {
+					return err
+				}
+			}
+			if err := loadTokens(cfg); err != nil {
+				return err
+			}
+			if err := loadUsage(cfg); err != nil {
+				return err
+			}
+			if err := loadOAuthSessions(cfg); err != nil {
+				return err
+			}
+			logStartup("http", cfg)
+			watchReload(cfg)
+			// Serverless instances are frozen between requests, so they run
+			// no background work
+			if !cfg.Stateless {
+				startProber(cfg)
+				startScheduler(cfg)
+				startUpdateChecks(cfg)
+				if !cfg.ReadOnly {
+					startLibraryGC(cfg)
+				}
+				stopStats := startStats(cfg, "http/"+cfg.Transport)
+				defer stopStats()
+			} else {
+				newRun(cfg, "http/"+cfg.Transport).flush()
+			}
+
+			// Downloads queued before the last shutdown start again
+			queueDir := ""
+			if !cfg.ReadOnly {
+				queueDir = cfg.DownloadPath
+				if err := resumeQueue(cfg); err != nil {
+					l.Warn("Failed to resume the queued downloads", zap.Error(err))
+				}
 			}
-			return StartHTTPServer(config)
+
+			serve := func() error {
+				return StartHTTPServer(HTTPServerConfig{
+					Host:          cfg.Host,
+					Port:          cfg.Port,
+					TransportType: cfg.Transport,
+					ReadOnly:      cfg.ReadOnly,
+					APIKey:        cfg.APIKey,
+					GRPC:          cfg.GRPC,
+					Stateless:     cfg.Stateless,
+					PortFallback:  cfg.PortFallback,
+					Auth:          newAuthProvider(cfg),
+					ShutdownGrace: time.Duration(cfg.ShutdownGraceSeconds) * time.Second,
+					QueueDir:      queueDir,
+				})
+			}
+			stop := func() { stopRequests <- syscall.SIGTERM }
+			if handled, err := service.Run(serve, stop); handled {
+				return err
+			}
+			return serve()
 		},
 	}
 
-	httpCmd.Flags().StringVar(&httpHost, "host", "0.0.0.0", "Host to bind the HTTP server to")
-	httpCmd.Flags().IntVar(&httpPort, "port", defaultPort, "Port to bind the HTTP server to (reads from PORT env var if set)")
-	httpCmd.Flags().StringVar(&httpTransport, "transport", "streamable", "Transport type: 'sse' or 'streamable' (recommended)")
+	httpCmd.Flags().String("host", defaults.Host, i18n.T("Host to bind the HTTP server to"))
+	httpCmd.Flags().Int("port", defaults.Port, i18n.T("Port to bind the HTTP server to (reads from PORT env var if set)"))
+	httpCmd.Flags().String("port-fallback", defaults.PortFallback, i18n.T("When the port is in use, try the 'next' ports or a 'random' free one (reads from ANNAS_PORT_FALLBACK if set)"))
+	httpCmd.Flags().String("transport", defaults.Transport, i18n.T("Transport type: 'sse', 'streamable' (recommended), or 'websocket'"))
+	httpCmd.Flags().Bool("stateless", defaults.Stateless, i18n.T("Keep no sessions or cached links between requests, for serverless platforms (reads from ANNAS_STATELESS if set)"))
+	httpCmd.Flags().Bool("grpc", defaults.GRPC, i18n.T("Also serve the gRPC facade (proto/annas/v1/annas.proto) on the HTTP port (reads from ANNAS_GRPC if set)"))
+	httpCmd.Flags().Int("shutdown-grace", defaults.ShutdownGraceSeconds, i18n.T("Seconds to finish requests and queued downloads after SIGTERM before exiting; 25 in containers (reads from ANNAS_SHUTDOWN_GRACE_SECONDS if set)"))
+	httpCmd.Flags().StringVar(&workDir, service.WorkDirFlag, "", "Working directory of the server, set by the Windows service")
+	_ = httpCmd.Flags().MarkHidden(service.WorkDirFlag)
+
+	var auditCaller, auditHash, auditOutcome string
+	var auditSince time.Duration
+	var auditLimit int
+	var auditJSON bool
+
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: i18n.T("Query the download audit log"),
+		Long:  i18n.T("Print the download requests recorded in the audit log (ANNAS_AUDIT_LOG), most recent last."),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+			if cfg.AuditLog == "" {
+				return fmt.Errorf("audit log is not configured. Please set ANNAS_AUDIT_LOG")
+			}
+
+			filter := audit.Filter{
+				Caller:  auditCaller,
+				Hash:    strings.ToLower(auditHash),
+				Outcome: auditOutcome,
+				Limit:   auditLimit,
+			}
+			if auditSince > 0 {
+				filter.Since = time.Now().Add(-auditSince)
+			}
+
+			entries, err := audit.Read(cfg.AuditLog, filter)
+			if err != nil {
+				return err
+			}
+
+			if auditJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				for _, entry := range entries {
+					if err := encoder.Encode(entry); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+
+			if len(entries) == 0 {
+				fmt.Println(i18n.T("No matching downloads."))
+				return nil
+			}
+			for _, entry := range entries {
+				i18n.Printf("%s %s via %s: %s %s", entry.Time.Local().Format(time.DateTime), entry.Caller, entry.Source, entry.Outcome, entry.Hash)
+				if entry.Title != "" {
+					fmt.Printf(" (%s)", entry.Title)
+				}
+				if entry.Bytes > 0 {
+					i18n.Printf(", %d bytes", entry.Bytes)
+				}
+				if entry.Error != "" {
+					fmt.Printf(": %s", entry.Error)
+				}
+				fmt.Println()
+			}
+
+			return nil
+		},
+	}
+
+	auditCmd.Flags().StringVar(&auditCaller, "caller", "", i18n.T("Only show downloads of this token name"))
+	auditCmd.Flags().StringVar(&auditHash, "md5", "", i18n.T("Only show downloads of this MD5 hash"))
+	auditCmd.Flags().StringVar(&auditOutcome, "outcome", "", i18n.T("Only show downloads with this outcome: saved, link, or failed"))
+	auditCmd.Flags().DurationVar(&auditSince, "since", 0, i18n.T("Only show downloads within this duration, e.g. 24h"))
+	auditCmd.Flags().IntVar(&auditLimit, "limit", 50, i18n.T("Maximum number of downloads to show, 0 for all"))
+	auditCmd.Flags().BoolVar(&auditJSON, "json", false, i18n.T("Print the entries as JSON Lines"))
+
+	var torrentsGroup string
+	var torrentsJSON bool
+
+	torrentsCmd := &cobra.Command{
+		Use:   "torrents",
+		Short: i18n.T("List the dataset torrents released by Anna's Archive"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			l.Info("List torrents command called", zap.String("group", torrentsGroup))
+
+			torrents, err := anna.ListTorrents(torrentsGroup)
+			if err != nil {
+				l.Error("List torrents command failed", zap.Error(err))
+				return err
+			}
+
+			if torrentsJSON {
+				data, err := json.MarshalIndent(torrents, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			fmt.Print(torrentsText(torrents))
+			return nil
+		},
+	}
+	torrentsCmd.Flags().StringVar(&torrentsGroup, "group", "", i18n.T("Only list the torrents of collections whose name contains this text"))
+	torrentsCmd.Flags().BoolVar(&torrentsJSON, "json", false, i18n.T("Print the torrents as JSON"))
+
+	scheduleCmd := &cobra.Command{
+		Use:   "schedule",
+		Short: i18n.T("Manage the searches the HTTP server runs on a schedule"),
+		Long:  i18n.T("Manage the saved searches in ANNAS_SCHEDULES_FILE. The http command runs them on their cron expressions and notifies the configured webhook and push backends of new results."),
+	}
+
+	scheduleEnv := func() (*Env, error) {
+		return config.Load(loadOptions)
+	}
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "add [cron] [query]",
+		Short: i18n.T("Save a search run on a cron expression, e.g. \"0 8 * * *\" or @daily"),
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := scheduleEnv()
+			if err != nil {
+				return err
+			}
+
+			schedule, err := addSchedule(env, args[1], args[0])
+			if err != nil {
+				return err
+			}
+
+			i18n.Printf("Scheduled %q on %q with ID %s\n", schedule.Query, schedule.Cron, schedule.ID)
+			return nil
+		},
+	})
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List the scheduled searches"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := scheduleEnv()
+			if err != nil {
+				return err
+			}
+
+			schedules, err := listSchedules(env)
+			if err != nil {
+				return err
+			}
+
+			fmt.Print(strings.TrimRight(schedulesText(schedules, i18n.Sprintf), "\n") + "\n")
+			return nil
+		},
+	})
+
+	scheduleCmd.AddCommand(&cobra.Command{
+		Use:   "remove [id]",
+		Short: i18n.T("Remove a scheduled search"),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			env, err := scheduleEnv()
+			if err != nil {
+				return err
+			}
+
+			if err := removeSchedule(env, args[0]); err != nil {
+				return err
+			}
+
+			i18n.Printf("Removed schedule %s\n", args[0])
+			return nil
+		},
+	})
+
+	adminCmd := &cobra.Command{
+		Use:   "admin",
+		Short: i18n.T("Administer the HTTP server"),
+	}
+
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: i18n.T("Manage the scoped API tokens of the HTTP server"),
+		Long:  i18n.T("Manage the tokens in ANNAS_TOKENS_FILE. A running http command picks up changes on SIGHUP."),
+	}
+
+	tokenStoreFile := func() (*auth.Store, error) {
+		cfg, err := config.Load(loadOptions)
+		if err != nil {
+			return nil, err
+		}
+		return auth.Open(cfg.TokensFile)
+	}
+
+	var tokenScopes []string
+	var tokenQuota auth.Quota
+
+	tokenCreateCmd := &cobra.Command{
+		Use:   "create [name]",
+		Short: i18n.T("Create a token and print its secret"),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := tokenStoreFile()
+			if err != nil {
+				return err
+			}
+
+			var quota *auth.Quota
+			if tokenQuota != (auth.Quota{}) {
+				quota = &tokenQuota
+			}
+			secret, err := store.Create(args[0], tokenScopes, quota)
+			if err != nil {
+				return err
+			}
+
+			i18n.Printf("Created token %s with scopes %s. Its secret is only shown once:\n%s\n", args[0], strings.Join(tokenScopes, ", "), secret)
+			return nil
+		},
+	}
+	tokenCreateCmd.Flags().StringSliceVar(&tokenScopes, "scopes", []string{auth.ScopeSearch}, i18n.T("Scopes of the token: search, download, or admin"))
+	tokenCreateCmd.Flags().IntVar(&tokenQuota.SearchesPerDay, "searches-per-day", 0, i18n.T("Searches allowed per UTC day, 0 for unlimited"))
+	tokenCreateCmd.Flags().IntVar(&tokenQuota.SearchesPerMonth, "searches-per-month", 0, i18n.T("Searches allowed per month, 0 for unlimited"))
+	tokenCreateCmd.Flags().IntVar(&tokenQuota.DownloadsPerDay, "downloads-per-day", 0, i18n.T("Downloads allowed per UTC day, 0 for unlimited"))
+	tokenCreateCmd.Flags().IntVar(&tokenQuota.DownloadsPerMonth, "downloads-per-month", 0, i18n.T("Downloads allowed per month, 0 for unlimited"))
+
+	tokenCmd.AddCommand(tokenCreateCmd)
+
+	tokenCmd.AddCommand(&cobra.Command{
+		Use:   "revoke [name]",
+		Short: i18n.T("Revoke a token"),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := tokenStoreFile()
+			if err != nil {
+				return err
+			}
+
+			if err := store.Revoke(args[0]); err != nil {
+				return err
+			}
+
+			i18n.Printf("Revoked token %s\n", args[0])
+			return nil
+		},
+	})
+
+	var tokensJSON bool
+
+	tokenListCmd := &cobra.Command{
+		Use:   "list",
+		Short: i18n.T("List the tokens without their secrets"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			store, err := tokenStoreFile()
+			if err != nil {
+				return err
+			}
+
+			tokens := store.Tokens()
+			if tokensJSON {
+				for i := range tokens {
+					tokens[i].Token = ""
+				}
+				data, err := json.MarshalIndent(tokens, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			fmt.Print(strings.TrimRight(tokensText(tokens), "\n") + "\n")
+			return nil
+		},
+	}
+	tokenListCmd.Flags().BoolVar(&tokensJSON, "json", false, i18n.T("Print the tokens as JSON"))
+
+	tokenCmd.AddCommand(tokenListCmd)
+	adminCmd.AddCommand(tokenCmd)
+
+	indexCmd := &cobra.Command{
+		Use:   "index",
+		Short: i18n.T("Manage the offline index of metadata dumps"),
+		Long:  i18n.T("Build and query the local index in ANNAS_OFFLINE_INDEX from the metadata dumps published by Anna's Archive, for searching without rate limits."),
+	}
+
+	var importLanguages, importFormats []string
+	var importLimit int
+
+	indexImportCmd := &cobra.Command{
+		Use:   "import [dump...]",
+		Short: i18n.T("Import JSON Lines metadata dumps, optionally gzip-compressed; - reads stdin"),
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+			if cfg.OfflineIndex == "" {
+				return errOfflineIndexMissing
+			}
+
+			index, err := offline.Open(cfg.OfflineIndex)
+			if err != nil {
+				return err
+			}
+
+			filter := offline.Filter{Languages: importLanguages, Formats: importFormats, Limit: importLimit}
+			for _, dump := range args {
+				l.Info("Index import command called", zap.String("dump", dump))
+
+				r := os.Stdin
+				if dump != "-" {
+					if r, err = os.Open(dump); err != nil {
+						return err
+					}
+				}
+				added, err := index.Import(r, filter)
+				if r != os.Stdin {
+					r.Close()
+				}
+				if err != nil {
+					return fmt.Errorf("failed to import %s: %w", dump, err)
+				}
+				i18n.Printf("Imported %d records from %s\n", added, dump)
+			}
+
+			if err := index.Save(cfg.OfflineIndex); err != nil {
+				return err
+			}
+			i18n.Printf("The offline index now holds %d records\n", index.Len())
+
+			return nil
+		},
+	}
+	indexImportCmd.Flags().StringSliceVar(&importLanguages, "language", nil, i18n.T("Only import records in these language codes, e.g. en,de"))
+	indexImportCmd.Flags().StringSliceVar(&importFormats, "format", nil, i18n.T("Only import records in these formats, e.g. epub,pdf"))
+	indexImportCmd.Flags().IntVar(&importLimit, "limit", 0, i18n.T("Maximum number of records to import from each dump, 0 for all"))
+
+	var offlineLimit int
+
+	indexSearchCmd := &cobra.Command{
+		Use:   "search [term]",
+		Short: i18n.T("Search the offline index"),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+
+			books, err := searchOffline(cfg, args[0], offlineLimit)
+			if err != nil {
+				return err
+			}
+			if len(books) == 0 {
+				i18n.Printf("No books found for %q in the offline index.\n", args[0])
+				return nil
+			}
+			for i, book := range books {
+				if i > 0 {
+					fmt.Println()
+				}
+				i18n.Printf("Book %d:\n%s\n", i+1, book.String())
+			}
+
+			return nil
+		},
+	}
+	indexSearchCmd.Flags().IntVar(&offlineLimit, "limit", offline.DefaultLimit, i18n.T("Maximum number of results"))
+
+	var speedTestSampleKB int
+
+	speedTestCmd := &cobra.Command{
+		Use:   "speedtest [hash]",
+		Short: i18n.T("Measure the throughput of the fast partner servers offering a record"),
+		Long:  i18n.T("Download a sample from every fast partner server offering a record and report the throughput. Requires ANNAS_SECRET_KEY environment variable."),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bookHash, err := anna.NormalizeHash(args[0])
+			if err != nil {
+				return err
+			}
+			l.Info("Speed test command called", zap.String("bookHash", bookHash))
+
+			env, err := GetEnv()
+			if env != nil && env.ReadOnly {
+				return errReadOnly
+			}
+			if err != nil {
+				l.Error("Failed to get environment variables", zap.Error(err))
+				return fmt.Errorf("failed to get environment: %w", err)
+			}
+
+			speeds, err := speedTest(cmd.Context(), env, &anna.Book{Hash: bookHash}, speedTestSampleKB)
+			if err != nil {
+				l.Error("Speed test command failed", zap.String("bookHash", bookHash), zap.Error(err))
+				return err
+			}
+			fmt.Print(speedTestText(speeds))
+
+			return nil
+		},
+	}
+	speedTestCmd.Flags().IntVar(&speedTestSampleKB, "sample-kb", defaultSpeedTestSampleKB, i18n.T("Kilobytes downloaded from every server"))
+
+	var textParams ExtractTextParams
+	var textJSON bool
+
+	extractTextCmd := &cobra.Command{
+		Use:   "extract-text [hash]",
+		Short: i18n.T("Print the text of a saved book"),
+		Long:  i18n.T("Print the text of a book saved to ANNAS_DOWNLOAD_PATH. Scanned PDFs without a text layer are recognized with tesseract when ANNAS_OCR is enabled, for at most ANNAS_OCR_MAX_PAGES pages."),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+
+			textParams.BookHash = args[0]
+			l.Info("Extract text command called", zap.String("bookHash", textParams.BookHash))
+
+			text, err := extractText(cmd.Context(), cfg, textParams)
+			if err != nil {
+				l.Error("Extract text command failed", zap.String("bookHash", textParams.BookHash), zap.Error(err))
+				return err
+			}
+
+			if textJSON {
+				data, err := json.MarshalIndent(text, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			} else {
+				fmt.Println(text.String())
+			}
+
+			l.Info("Extract text command completed successfully",
+				zap.String("bookHash", text.Hash),
+				zap.Bool("ocr", text.OCR),
+			)
+
+			return nil
+		},
+	}
+	extractTextCmd.Flags().IntVar(&textParams.Limit, "limit", defaultTextLimit, i18n.T("Maximum number of characters printed"))
+	extractTextCmd.Flags().IntVar(&textParams.FirstPage, "first-page", 1, i18n.T("First page to OCR for scanned PDFs"))
+	extractTextCmd.Flags().IntVar(&textParams.Pages, "pages", 0, i18n.T("Number of pages to OCR (default: ANNAS_OCR_MAX_PAGES)"))
+	extractTextCmd.Flags().BoolVar(&textJSON, "json", false, i18n.T("Print the text and whether it was recognized with OCR as JSON"))
+
+	var insideLimit int
+	var insideJSON bool
+
+	searchInsideCmd := &cobra.Command{
+		Use:   "search-inside [hash] [phrase]",
+		Short: i18n.T("Search the text of a saved book for a phrase"),
+		Long:  i18n.T("Search the text of a book saved to ANNAS_DOWNLOAD_PATH for a phrase, ignoring case and line breaks, and print the matching passages with their chapter or page."),
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+
+			params := SearchInsideBookParams{BookHash: args[0], Query: strings.Join(args[1:], " "), Limit: insideLimit}
+			l.Info("Search inside book command called",
+				zap.String("bookHash", params.BookHash),
+				zap.String("query", params.Query),
+			)
+
+			matches, err := searchInsideBook(cfg, params)
+			if err != nil {
+				l.Error("Search inside book command failed", zap.String("bookHash", params.BookHash), zap.Error(err))
+				return err
+			}
+
+			if insideJSON {
+				data, err := json.MarshalIndent(matches, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			} else {
+				fmt.Println(matches.String())
+			}
+
+			l.Info("Search inside book command completed successfully",
+				zap.String("bookHash", matches.Hash),
+				zap.Int("matchesCount", matches.Matches),
+			)
+
+			return nil
+		},
+	}
+	searchInsideCmd.Flags().IntVar(&insideLimit, "limit", defaultPassageLimit, i18n.T("Maximum number of passages printed"))
+	searchInsideCmd.Flags().BoolVar(&insideJSON, "json", false, i18n.T("Print the passages and their locations as JSON"))
+
+	indexCmd.AddCommand(indexImportCmd)
+	indexCmd.AddCommand(indexSearchCmd)
+
+	libraryCmd := &cobra.Command{
+		Use:   "library",
+		Short: i18n.T("Maintain the books saved to the download path"),
+		Long:  i18n.T("Maintain the books saved to ANNAS_DOWNLOAD_PATH and recorded in its library index."),
+	}
+
+	var manifestPath string
+	var verifyJSON bool
+
+	libraryVerifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: i18n.T("Re-hash the saved books and write a SHA256SUMS manifest"),
+		Long:  i18n.T("Re-hash every book in the library index, compare it with the MD5 it was saved under, write the SHA-256 of the intact books to a manifest (SHA256SUMS in the download path by default, checkable with sha256sum -c), and report corrupted or missing files."),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+
+			l.Info("Library verify command called", zap.String("path", cfg.DownloadPath))
+
+			checks, err := library.Verify(cfg.DownloadPath, manifestPath)
+			if err != nil {
+				l.Error("Library verify command failed", zap.Error(err))
+				return err
+			}
+
+			if verifyJSON {
+				data, err := json.MarshalIndent(checks, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			} else {
+				for _, check := range checks {
+					if check.Status == library.StatusOK {
+						continue
+					}
+					line := fmt.Sprintf("%s: %s", check.File, check.Status)
+					if check.Error != "" {
+						line += " (" + check.Error + ")"
+					}
+					fmt.Println(line)
+				}
+			}
+
+			failed := 0
+			for _, check := range checks {
+				if check.Status == library.StatusCorrupted || check.Status == library.StatusMissing {
+					failed++
+				}
+			}
+
+			l.Info("Library verify command completed successfully",
+				zap.Int("filesCount", len(checks)),
+				zap.Int("failedCount", failed),
+			)
+
+			if failed > 0 {
+				return fmt.Errorf("%d of %d books are corrupted or missing", failed, len(checks))
+			}
+			if !verifyJSON {
+				i18n.Printf("All %d books are intact.\n", len(checks))
+			}
+
+			return nil
+		},
+	}
+	libraryVerifyCmd.Flags().StringVar(&manifestPath, "manifest", "", i18n.T("Path of the checksum manifest (default: SHA256SUMS in the download path)"))
+	libraryVerifyCmd.Flags().BoolVar(&verifyJSON, "json", false, i18n.T("Print the outcome of every book as JSON"))
+
+	var gcMaxAge time.Duration
+	var gcJSON bool
+
+	libraryGCCmd := &cobra.Command{
+		Use:   "gc",
+		Short: i18n.T("Remove abandoned partial downloads and stale index entries"),
+		Long:  i18n.T("Remove the temporary and .part files of interrupted downloads that were left untouched for --max-age from ANNAS_DOWNLOAD_PATH, and drop the library index entries whose file is missing."),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+			if cfg.ReadOnly {
+				return errReadOnly
+			}
+
+			l.Info("Library gc command called", zap.String("path", cfg.DownloadPath))
+
+			collected, err := collectLibrary(cfg, gcMaxAge)
+			if gcJSON {
+				data, jsonErr := json.MarshalIndent(collected, "", "  ")
+				if jsonErr != nil {
+					return jsonErr
+				}
+				fmt.Println(string(data))
+			} else {
+				for _, file := range collected.Files {
+					i18n.Printf("Removed %s\n", file)
+				}
+				for _, entry := range collected.Entries {
+					i18n.Printf("Dropped index entry of missing %s\n", entry.File)
+				}
+				i18n.Printf("Freed %d MB, dropped %d index entries.\n", collected.Bytes>>20, len(collected.Entries))
+			}
+			if err != nil {
+				l.Error("Library gc command failed", zap.Error(err))
+				return err
+			}
+
+			l.Info("Library gc command completed successfully",
+				zap.Int("filesCount", len(collected.Files)),
+				zap.Int("entriesCount", len(collected.Entries)),
+			)
+
+			return nil
+		},
+	}
+	libraryGCCmd.Flags().DurationVar(&gcMaxAge, "max-age", library.DefaultGCAge, i18n.T("Minimum time since a temporary file was last written before it is removed"))
+	libraryGCCmd.Flags().BoolVar(&gcJSON, "json", false, i18n.T("Print the removed files and dropped entries as JSON"))
+
+	libraryCmd.AddCommand(libraryVerifyCmd)
+	libraryCmd.AddCommand(libraryGCCmd)
+
+	var exportSecrets bool
+
+	exportStateCmd := &cobra.Command{
+		Use:   "export-state [file]",
+		Short: i18n.T("Bundle the server state into a tarball"),
+		Long:  i18n.T("Bundle the library index, the audit log holding the search and download history, the saved searches, the usage counters, and the settings that differ from the defaults into a gzip-compressed tarball (annas-mcp-state.tar.gz by default), to move the server to another machine. Tokens, OAuth sessions, cookies, and secret settings are only bundled with --include-secrets."),
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+
+			path := "annas-mcp-state.tar.gz"
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			l.Info("Export state command called",
+				zap.String("path", path),
+				zap.Bool("secrets", exportSecrets),
+			)
+
+			settings, err := json.MarshalIndent(cfg.Export(exportSecrets), "", "  ")
+			if err != nil {
+				return err
+			}
+
+			file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+			if err != nil {
+				l.Error("Export state command failed", zap.Error(err))
+				return fmt.Errorf("failed to create bundle: %w", err)
+			}
+			manifest, err := state.Export(file, stateItems(cfg), settings, version.GetVersion(), exportSecrets)
+			if closeErr := file.Close(); err == nil && closeErr != nil {
+				err = fmt.Errorf("failed to write bundle: %w", closeErr)
+			}
+			if err != nil {
+				os.Remove(path)
+				l.Error("Export state command failed", zap.Error(err))
+				return err
+			}
+
+			for _, item := range manifest.Items {
+				i18n.Printf("Bundled %s from %s\n", item.Name, item.Path)
+			}
+			i18n.Printf("Wrote %s\n", path)
+
+			l.Info("Export state command completed successfully", zap.Int("filesCount", len(manifest.Items)))
+
+			return nil
+		},
+	}
+	exportStateCmd.Flags().BoolVar(&exportSecrets, "include-secrets", false, i18n.T("Also bundle tokens, OAuth sessions, cookies, and secret settings"))
+
+	var importForce bool
+	var importConfigOut string
+
+	importStateCmd := &cobra.Command{
+		Use:   "import-state <file>",
+		Short: i18n.T("Restore the server state from a tarball"),
+		Long:  i18n.T("Restore the state files of a bundle written by export-state to the paths configured on this machine. Files whose path is not configured here are skipped. The bundled settings are only written with --config-out, and the paths are then taken from that config file. Existing files are kept unless --force is given."),
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			l.Info("Import state command called", zap.String("path", args[0]))
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				l.Error("Import state command failed", zap.Error(err))
+				return fmt.Errorf("failed to open bundle: %w", err)
+			}
+			bundle, err := state.Read(file)
+			file.Close()
+			if err != nil {
+				l.Error("Import state command failed", zap.Error(err))
+				return err
+			}
+
+			options := loadOptions
+			if importConfigOut != "" {
+				if _, err := os.Stat(importConfigOut); err == nil && !importForce {
+					err = fmt.Errorf("%w: %s (pass --force to replace it)", state.ErrExists, importConfigOut)
+					l.Error("Import state command failed", zap.Error(err))
+					return err
+				}
+				if err := state.WriteFile(importConfigOut, bundle.Config); err != nil {
+					l.Error("Import state command failed", zap.Error(err))
+					return err
+				}
+				i18n.Printf("Wrote the bundled settings to %s\n", importConfigOut)
+				options.File = importConfigOut
+			}
+
+			cfg, err := config.Load(options)
+			if err != nil {
+				return err
+			}
+
+			items := stateItems(cfg)
+			restored, err := bundle.Restore(items, importForce)
+			for _, item := range restored {
+				i18n.Printf("Restored %s to %s\n", item.Name, item.Path)
+			}
+			if err != nil {
+				// Drop the settings when nothing was restored, so the import
+				// can be retried without --force
+				if importConfigOut != "" && len(restored) == 0 {
+					os.Remove(importConfigOut)
+				}
+				l.Error("Import state command failed", zap.Error(err))
+				return err
+			}
+			for _, item := range items {
+				if _, ok := bundle.Files[item.Name]; ok && item.Path == "" {
+					i18n.Printf("Skipped %s, as its path is not configured\n", item.Name)
+				}
+			}
+
+			l.Info("Import state command completed successfully", zap.Int("filesCount", len(restored)))
+
+			return nil
+		},
+	}
+	importStateCmd.Flags().BoolVar(&importForce, "force", false, i18n.T("Replace existing files"))
+	importStateCmd.Flags().StringVar(&importConfigOut, "config-out", "", i18n.T("Write the bundled settings to this config file and restore to the paths it configures"))
+
+	var versionJSON bool
+	var versionCheck bool
+
+	versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: i18n.T("Print the version and build information"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := version.GetInfo()
+
+			var latest *version.Release
+			if versionCheck {
+				ctx, cancel := context.WithTimeout(cmd.Context(), updateCheckTimeout)
+				defer cancel()
+
+				release, err := version.CheckForUpdate(ctx)
+				if err != nil {
+					l.Error("Version command failed", zap.Error(err))
+					return err
+				}
+				saveCachedRelease(release)
+				latest = release
+			}
+
+			if versionJSON {
+				var output any = info
+				if latest != nil {
+					output = map[string]interface{}{
+						"info":             info,
+						"latest":           latest,
+						"update_available": version.UpdateAvailable() != nil,
+					}
+				}
+				data, err := json.MarshalIndent(output, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			fmt.Printf("annas-mcp %s\n", info.Version)
+			if info.Commit != "" {
+				i18n.Printf("Commit: %s\n", info.Commit)
+			}
+			if info.BuildDate != "" {
+				i18n.Printf("Built: %s\n", info.BuildDate)
+			}
+			fmt.Printf("Go: %s (%s)\n", info.GoVersion, info.Platform)
+
+			if latest != nil {
+				if version.UpdateAvailable() != nil {
+					i18n.Printf("A newer version is available: %s\n%s\n", latest.Version, latest.URL)
+				} else {
+					i18n.Printf("Up to date (latest release: %s)\n", latest.Version)
+				}
+			}
+
+			return nil
+		},
+	}
+	versionCmd.Flags().BoolVar(&versionJSON, "json", false, i18n.T("Print the build information as JSON"))
+	versionCmd.Flags().BoolVar(&versionCheck, "check", false, i18n.T("Check GitHub for a newer release"))
+
+	var pathsJSON bool
+
+	pathsCmd := &cobra.Command{
+		Use:   "paths",
+		Short: i18n.T("Print where the configuration, caches, indexes, and logs live"),
+		Long:  i18n.T("Print the XDG base directories used by annas-mcp and every file it reads or writes, marking the ones not created yet and the optional ones that are not used."),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+
+			dotEnv := envFile
+			if dotEnv == "" {
+				dotEnv = findDotEnv()
+			}
+			entries := pathEntries(cfg, config.ResolveFile(loadOptions.File), dotEnv)
+
+			if pathsJSON {
+				data, err := json.MarshalIndent(entries, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			fmt.Print(pathsText(entries))
+			return nil
+		},
+	}
+	pathsCmd.Flags().BoolVar(&pathsJSON, "json", false, i18n.T("Print the paths as JSON"))
+
+	serviceCmd := &cobra.Command{
+		Use:   "service",
+		Short: i18n.T("Run the HTTP server as a system service"),
+		Long:  i18n.T("Register the http command with the service manager of the system, so it starts at boot or login and restarts when it fails: a systemd unit on Linux, a launchd agent on macOS, or a Windows service."),
+	}
+
+	serviceInstallCmd := &cobra.Command{
+		Use:   "install [-- http flags]",
+		Short: i18n.T("Install and start the service with the current configuration"),
+		Long:  i18n.T("Install the service running the http command with the config file, dotenv file, and profile of this invocation, from the current working directory, then start it. Flags after -- are passed to the http command, e.g. -- --port 9000. Installing again replaces the service."),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			readOnly, _ := cmd.Flags().GetBool("read-only")
+			exportedFile, err := writeServiceEnv(exported)
+			if err != nil {
+				return fmt.Errorf("failed to save the exported settings: %w", err)
+			}
+			spec, err := serviceSpec(configFile, envFile, exportedFile, profile, readOnly, args)
+			if err != nil {
+				return err
+			}
+
+			// A mistyped flag would only show up as a service failing to start
+			flags := pflag.NewFlagSet("http", pflag.ContinueOnError)
+			flags.SetOutput(io.Discard)
+			flags.AddFlagSet(httpCmd.Flags())
+			flags.AddFlagSet(rootCmd.PersistentFlags())
+			if err := flags.Parse(args); err != nil {
+				return fmt.Errorf("invalid http flags: %w", err)
+			}
+			if flags.NArg() > 0 {
+				return fmt.Errorf("unexpected argument %q: the http command only takes flags", flags.Arg(0))
+			}
+
+			l.Info("Service install command called", zap.String("manager", service.Manager), zap.Strings("args", spec.Args))
+			file, err := service.Install(spec)
+			if err != nil {
+				l.Error("Service install command failed", zap.Error(err))
+				return fmt.Errorf("failed to install the service: %w", err)
+			}
+			l.Info("Service install command completed successfully", zap.String("file", file))
+
+			if file != "" {
+				i18n.Printf("Installed and started the %s service from %s\n", service.Manager, file)
+			} else {
+				i18n.Printf("Installed and started the %s service\n", service.Manager)
+			}
+			if exportedFile != "" {
+				i18n.Printf("Settings exported in the shell are saved to %s: %s\n", exportedFile, strings.Join(exportedNames(exported), ", "))
+			}
+			if service.Manager == "systemd" && os.Geteuid() != 0 {
+				fmt.Println(i18n.T("User services stop when you log out; run 'loginctl enable-linger' to keep it running and start it at boot."))
+			}
+			return nil
+		},
+	}
+
+	serviceUninstallCmd := &cobra.Command{
+		Use:   "uninstall",
+		Short: i18n.T("Stop and remove the service"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			l.Info("Service uninstall command called", zap.String("manager", service.Manager))
+			if err := service.Uninstall(); err != nil {
+				l.Error("Service uninstall command failed", zap.Error(err))
+				return fmt.Errorf("failed to uninstall the service: %w", err)
+			}
+			if _, err := writeServiceEnv(nil); err != nil {
+				l.Warn("Failed to remove the exported settings", zap.Error(err))
+			}
+			l.Info("Service uninstall command completed successfully")
+
+			i18n.Printf("Removed the %s service\n", service.Manager)
+			return nil
+		},
+	}
+
+	var serviceJSON bool
+
+	serviceStatusCmd := &cobra.Command{
+		Use:   "status",
+		Short: i18n.T("Print whether the service is installed and running"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			status, err := service.Query()
+			if err != nil {
+				return fmt.Errorf("failed to query the service: %w", err)
+			}
+
+			if serviceJSON {
+				data, err := json.MarshalIndent(status, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			fmt.Print(serviceText(status))
+			return nil
+		},
+	}
+	serviceStatusCmd.Flags().BoolVar(&serviceJSON, "json", false, i18n.T("Print the status as JSON"))
+
+	serviceCmd.AddCommand(serviceInstallCmd)
+	serviceCmd.AddCommand(serviceUninstallCmd)
+	serviceCmd.AddCommand(serviceStatusCmd)
+
+	var statsJSON bool
+
+	statsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: i18n.T("Print the local usage stats and the telemetry status"),
+		Long:  i18n.T("Print how many times each mode was run, and the searches and downloads made in it, as recorded in the local stats file. Nothing leaves the machine unless telemetry is enabled with ANNAS_TELEMETRY."),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+
+			store := statsStore(cfg)
+			stats, err := store.Load()
+			if err != nil {
+				l.Error("Stats command failed", zap.Error(err))
+				return err
+			}
+
+			if statsJSON {
+				data, err := json.MarshalIndent(stats, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			fmt.Println(statsText(cfg, store.Path(), stats))
+			return nil
+		},
+	}
+	statsCmd.Flags().BoolVar(&statsJSON, "json", false, i18n.T("Print the stats as JSON"))
+
+	dumpConfigCmd := &cobra.Command{
+		Use:   "dump-config",
+		Short: i18n.T("Print the effective configuration with secrets masked"),
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load(loadOptions)
+			if err != nil {
+				return err
+			}
+
+			data, err := json.MarshalIndent(cfg.Masked(), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+
+			return nil
+		},
+	}
+
+	// Development helpers, hidden from the help of end users
+	devCmd := &cobra.Command{
+		Use:    "dev",
+		Short:  "Development helpers",
+		Hidden: true,
+	}
+
+	var fixturesDir string
+	refreshFixturesCmd := &cobra.Command{
+		Use:   "refresh-fixtures [name...]",
+		Short: "Re-download and sanitize the golden parser fixtures",
+		Long:  "Re-download the pages listed in the manifest of the golden parser fixtures, or only the named ones, from the configured mirrors and sanitize them. Review the parser output afterwards with go test ./internal/anna -run TestGolden -update and git diff.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			fixtures, err := anna.LoadGoldenFixtures(fixturesDir)
+			if err != nil {
+				return err
+			}
+
+			refreshed := 0
+			for _, fixture := range fixtures {
+				if len(args) > 0 && !slices.Contains(args, fixture.Name) && !slices.Contains(args, fixture.Kind+"/"+fixture.Name) {
+					continue
+				}
+				if err := anna.RefreshGoldenFixture(fixturesDir, fixture); err != nil {
+					l.Error("Refresh fixtures command failed", zap.String("fixture", fixture.Kind+"/"+fixture.Name), zap.Error(err))
+					return err
+				}
+				fmt.Printf("Refreshed %s\n", fixture.Page(fixturesDir))
+				refreshed++
+			}
+			if refreshed == 0 {
+				return fmt.Errorf("no fixture matches %s", strings.Join(args, ", "))
+			}
+
+			fmt.Println("Update the golden outputs with: go test ./internal/anna -run TestGolden -update")
+			return nil
+		},
+	}
+	refreshFixturesCmd.Flags().StringVar(&fixturesDir, "dir", filepath.Join("internal", "anna", "testdata", "golden"), i18n.T("Directory of the golden fixtures and their manifest"))
+	devCmd.AddCommand(refreshFixturesCmd)
 
 	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(metadataCmd)
 	rootCmd.AddCommand(downloadCmd)
+	rootCmd.AddCommand(bestMatchCmd)
+	rootCmd.AddCommand(paperCmd)
+	rootCmd.AddCommand(wantToReadCmd)
+	rootCmd.AddCommand(readingListCmd)
 	rootCmd.AddCommand(mcpCmd)
 	rootCmd.AddCommand(httpCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(torrentsCmd)
+	rootCmd.AddCommand(scheduleCmd)
+	rootCmd.AddCommand(adminCmd)
+	rootCmd.AddCommand(indexCmd)
+	rootCmd.AddCommand(libraryCmd)
+	rootCmd.AddCommand(extractTextCmd)
+	rootCmd.AddCommand(searchInsideCmd)
+	rootCmd.AddCommand(exportStateCmd)
+	rootCmd.AddCommand(importStateCmd)
+	rootCmd.AddCommand(speedTestCmd)
+	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(pathsCmd)
+	rootCmd.AddCommand(serviceCmd)
+	rootCmd.AddCommand(dumpConfigCmd)
+	rootCmd.AddCommand(devCmd)
 
 	if err := fang.Execute(
 		context.Background(),
 		rootCmd,
 		fang.WithVersion(version.GetVersion()),
+		fang.WithCommit(version.GetInfo().Commit),
 	); err != nil {
 		os.Exit(1)
 	}
@@ -0,0 +1,142 @@
+package modes
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/booktext"
+	"github.com/iosifache/annas-mcp/internal/langdetect"
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/pdfinfo"
+	"go.uber.org/zap"
+)
+
+// languageSample is the number of characters of a saved book read for
+// language detection.
+const languageSample = 60000
+
+// minLanguageConfidence is the confidence below which a detected language is
+// not recorded, since mixed and bilingual texts are not worth flagging.
+const minLanguageConfidence = 0.25
+
+// languageCheck compares the language a record claims with the one its
+// content appears to be in.
+type languageCheck struct {
+	Claimed  string `json:"claimed,omitempty"`
+	Detected string `json:"detected"`
+	Mismatch bool   `json:"mismatch"`
+}
+
+// probeContent reads the page count and size of a saved PDF, and detects the
+// language of books text can be extracted from. Failures only leave the
+// results empty.
+func probeContent(env *Env, path string) (*pdfinfo.Info, string) {
+	l := logger.GetLogger()
+
+	// Page counts tell samples and previews from whole books
+	var pdf *pdfinfo.Info
+	if strings.EqualFold(filepath.Ext(path), ".pdf") {
+		var err error
+		if pdf, err = pdfinfo.Probe(path); err != nil {
+			l.Warn("Failed to probe downloaded PDF", zap.String("path", path), zap.Error(err))
+		}
+	}
+
+	if !env.DetectLanguage || !booktext.Supported(path) {
+		return pdf, ""
+	}
+	text, err := booktext.Extract(path, languageSample)
+	if err != nil {
+		l.Warn("Failed to extract text of downloaded book", zap.String("path", path), zap.Error(err))
+		return pdf, ""
+	}
+	// Front matter such as copyright pages is often in English, whatever the
+	// language of the book
+	if runes := []rune(text); len(runes) == languageSample {
+		text = string(runes[len(runes)/3:])
+	}
+
+	language, confidence := langdetect.Detect(text)
+	if confidence < minLanguageConfidence {
+		return pdf, ""
+	}
+
+	return pdf, language
+}
+
+// savedDetails describes the content of a saved book from its library index
+// entry, as a note for the text of tool results and as entries for their
+// structured content.
+func savedDetails(env *Env, book *anna.Book) (string, map[string]interface{}) {
+	details := make(map[string]interface{})
+	index, err := library.Load(env.DownloadPath)
+	if err != nil {
+		return "", details
+	}
+	entry := index.Entries[book.Hash]
+
+	note := pagesNote(entry.PDF)
+	if entry.PDF != nil {
+		details["pdf"] = entry.PDF
+	}
+
+	if entry.DetectedLanguage != "" {
+		check := checkLanguage(book, entry.DetectedLanguage)
+		details["language"] = check
+		if check.Mismatch {
+			note += fmt.Sprintf("\nWarning: the content appears to be in %s, but the record claims %s", languageName(check.Detected), check.Claimed)
+		}
+	}
+
+	return note, details
+}
+
+// checkLanguage compares the detected language of a book with the one of its
+// record, looking the record up when the book does not name it. Languages
+// that cannot be detected, or records claiming several languages, are never
+// flagged.
+func checkLanguage(book *anna.Book, detected string) languageCheck {
+	claimed := book.Language
+	if claimed == "" {
+		if metadata, err := anna.GetMetadata(book.Hash); err == nil {
+			claimed = metadata.Language
+		}
+	}
+
+	check := languageCheck{Claimed: claimed, Detected: detected}
+	for _, option := range anna.Languages {
+		if strings.EqualFold(option.Label, claimed) {
+			check.Mismatch = !anna.MatchesLanguage(claimed, detected)
+			break
+		}
+	}
+
+	return check
+}
+
+// languageName returns the name of the language of an ISO 639-1 code.
+func languageName(code string) string {
+	for _, option := range anna.Languages {
+		if option.Value == code {
+			return option.Label
+		}
+	}
+
+	return code
+}
+
+// pagesNote describes the pages of a saved PDF for tool results, empty for
+// other books.
+func pagesNote(pdf *pdfinfo.Info) string {
+	switch {
+	case pdf == nil:
+		return ""
+	case pdf.Width == 0:
+		return fmt.Sprintf(" (%d pages)", pdf.Pages)
+	default:
+		return fmt.Sprintf(" (%d pages of %g x %g pt)", pdf.Pages, pdf.Width, pdf.Height)
+	}
+}
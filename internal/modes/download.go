@@ -0,0 +1,490 @@
+package modes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/fsutil"
+	"github.com/iosifache/annas-mcp/internal/keyring"
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"github.com/iosifache/annas-mcp/internal/rclone"
+	"github.com/iosifache/annas-mcp/internal/unpack"
+	"go.uber.org/zap"
+)
+
+// uploadTimeout bounds the post-download rclone step.
+const uploadTimeout = 30 * time.Minute
+
+// checkDownloadPath verifies that the download path exists (creating it if
+// needed), is writable, and has at least the configured amount of free space,
+// so problems surface before anything is fetched.
+func checkDownloadPath(env *Env) error {
+	if err := fsutil.EnsureWritableDir(env.DownloadPath); err != nil {
+		return fmt.Errorf("download path is not usable: %w", err)
+	}
+
+	free, err := fsutil.FreeSpace(env.DownloadPath)
+	if err != nil {
+		return fmt.Errorf("cannot determine free space of %s: %w", env.DownloadPath, err)
+	}
+
+	required := uint64(env.MinFreeSpaceMB) << 20
+	if free < required {
+		return fmt.Errorf("download path %s has only %d MB free, at least %d MB required (ANNAS_MIN_FREE_SPACE_MB)",
+			env.DownloadPath, free>>20, env.MinFreeSpaceMB)
+	}
+
+	return nil
+}
+
+// checkFormat refuses books whose format is not in the configured allowlist.
+// The format claimed by the client is not trusted, the record is looked up
+// and its format and size are kept on the book. Books whose record cannot be
+// fetched are refused too.
+func checkFormat(env *Env, book *anna.Book) error {
+	if len(env.AllowedFormats) == 0 {
+		return nil
+	}
+
+	metadata, err := anna.GetMetadata(book.Hash)
+	if err != nil {
+		return withCode(codeUpstreamDown, "cannot check the format of %s, only %s are allowed (ANNAS_ALLOWED_FORMATS): %w", book.Hash, strings.Join(env.AllowedFormats, ", "), err)
+	}
+	book.Format = metadata.Format
+	if book.Size == "" {
+		book.Size = metadata.Size
+	}
+	if book.Format == "" {
+		return withCode(codeFormatNotAllowed, "cannot determine the format of %s, only %s are allowed (ANNAS_ALLOWED_FORMATS)", book.Hash, strings.Join(env.AllowedFormats, ", "))
+	}
+
+	if hasFormat(env.AllowedFormats, book.Format) {
+		return nil
+	}
+
+	return withCode(codeFormatNotAllowed, "format %s of %s is not allowed, only %s are (ANNAS_ALLOWED_FORMATS)", strings.ToLower(book.Format), book.Hash, strings.Join(env.AllowedFormats, ", "))
+}
+
+// hasFormat reports whether format is one of formats, ignoring case.
+func hasFormat(formats []string, format string) bool {
+	for _, allowed := range formats {
+		if strings.EqualFold(strings.TrimSpace(allowed), format) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkFileSize refuses books whose reported size exceeds the configured
+// maximum before a fast download is spent on them. Books without a known size
+// are looked up, and the limit is enforced again while fetching.
+func checkFileSize(env *Env, book *anna.Book) error {
+	limit := env.MaxFileSizeBytes()
+	if limit == 0 {
+		return nil
+	}
+
+	size := book.SizeBytes()
+	if size == 0 {
+		if metadata, err := anna.GetMetadata(book.Hash); err == nil {
+			size = metadata.SizeBytes()
+		}
+	}
+	if size > limit {
+		return fmt.Errorf("%w: %s is %d MB, the limit is %d MB (ANNAS_MAX_FILE_SIZE)", anna.ErrFileTooLarge, book.Hash, size>>20, limit>>20)
+	}
+
+	return nil
+}
+
+// reserveSpace makes room for size bytes under the disk quota of the download
+// path, evicting the least recently saved books if enabled.
+func reserveSpace(env *Env, size int64) error {
+	quota := env.DiskQuotaBytes()
+	if quota == 0 {
+		return nil
+	}
+
+	return library.Update(env.DownloadPath, func(index *library.Index) error {
+		evicted, err := index.Reserve(size, quota, env.DiskQuotaEvict, "")
+		logEvictions(evicted)
+		return err
+	})
+}
+
+// indexBook records a saved book in the library index and enforces the disk
+// quota with its actual size, removing the book again if it does not fit.
+func indexBook(env *Env, book *anna.Book, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to index downloaded book: %w", err)
+	}
+
+	// Books of nested layouts are indexed by their path within the download
+	// path
+	file, err := filepath.Rel(env.DownloadPath, path)
+	if err != nil {
+		file = filepath.Base(path)
+	}
+
+	pdf, language := probeContent(env, path)
+
+	quota := env.DiskQuotaBytes()
+	err = library.Update(env.DownloadPath, func(index *library.Index) error {
+		index.Add(library.Entry{
+			Hash:             book.Hash,
+			Title:            book.Title,
+			Format:           book.Format,
+			File:             file,
+			Bytes:            info.Size(),
+			PDF:              pdf,
+			DetectedLanguage: language,
+		})
+
+		evicted, err := index.Reserve(0, quota, env.DiskQuotaEvict, book.Hash)
+		logEvictions(evicted)
+		if err != nil {
+			return errors.Join(err, index.Remove(book.Hash))
+		}
+		return nil
+	})
+	if err != nil && quota == 0 {
+		// Without a quota the index is only informational
+		logger.GetLogger().Warn("Failed to update library index", zap.String("path", env.DownloadPath), zap.Error(err))
+		return nil
+	}
+
+	return err
+}
+
+func logEvictions(evicted []library.Entry) {
+	for _, entry := range evicted {
+		logger.GetLogger().Info("Evicted book to stay within the disk quota",
+			zap.String("bookHash", entry.Hash),
+			zap.String("file", entry.File),
+			zap.Int64("bytes", entry.Bytes),
+		)
+	}
+}
+
+// resolveDownload returns a fast download link for the book, preferring a
+// link prefetched after a search.
+func resolveDownload(env *Env, book *anna.Book) (*anna.DownloadInfo, error) {
+	// Prefetched links were resolved without a pinned server
+	if env.DownloadServer != "" {
+		return resolveUncached(env, book)
+	}
+
+	if info := cachedDownload(env, book); info != nil {
+		metrics.CacheHits.Inc()
+		return info, nil
+	}
+	metrics.CacheMisses.Inc()
+
+	return resolveUncached(env, book)
+}
+
+// resolveLink returns a fast download link for a book the client fetches
+// itself, enforcing the format allowlist first.
+func resolveLink(env *Env, book *anna.Book) (*anna.DownloadInfo, error) {
+	if err := checkFormat(env, book); err != nil {
+		return nil, err
+	}
+
+	return resolveDownload(env, book)
+}
+
+// resolveUncached asks the fast download API for a link, failing over between
+// all configured secret keys.
+func resolveUncached(env *Env, book *anna.Book) (*anna.DownloadInfo, error) {
+	ring := keyring.New(env.Keys())
+	if env.DownloadServer == "" {
+		if region := anna.Region(); region != "" {
+			return resolveRegional(ring, book, region)
+		}
+		return ring.Resolve(book)
+	}
+
+	if server, err := strconv.Atoi(env.DownloadServer); err == nil {
+		if server < 0 {
+			return nil, withCode(codeInvalidArgument, "invalid partner server %d: indexes start at 0", server)
+		}
+		return ring.ResolveFrom(book, server)
+	}
+
+	// Domains are matched against the links handed out for every index
+	domain := strings.ToLower(strings.TrimSpace(env.DownloadServer))
+	for server := 0; server < maxPartnerServers; server++ {
+		info, err := ring.ResolveFrom(book, server)
+		if err != nil {
+			if server == 0 {
+				return nil, err
+			}
+			break
+		}
+		if u, err := url.Parse(info.URL); err == nil {
+			if host := strings.ToLower(u.Hostname()); host == domain || strings.HasSuffix(host, "."+domain) {
+				return info, nil
+			}
+		}
+	}
+
+	return nil, withCode(codeInvalidArgument, "no fast partner server on %s offers %s", env.DownloadServer, book.Hash)
+}
+
+// regionalServers remembers the partner server index found in every region,
+// or -1 when none of them is, so later downloads skip the search.
+var regionalServers sync.Map
+
+// resolveRegional prefers a fast partner server of region over the one the
+// API picks, keeping the API's pick when no server is regional.
+func resolveRegional(ring *keyring.Ring, book *anna.Book, region string) (*anna.DownloadInfo, error) {
+	regional := func(info *anna.DownloadInfo) bool {
+		u, err := url.Parse(info.URL)
+		return err == nil && anna.RegionalHost(u.Hostname(), region)
+	}
+
+	if known, ok := regionalServers.Load(region); ok {
+		if server := known.(int); server >= 0 {
+			if info, err := ring.ResolveFrom(book, server); err == nil && regional(info) {
+				return info, nil
+			}
+			regionalServers.Delete(region)
+		} else {
+			return ring.Resolve(book)
+		}
+	}
+
+	info, err := ring.Resolve(book)
+	if err != nil || regional(info) {
+		return info, err
+	}
+	for server := 0; server < maxPartnerServers; server++ {
+		candidate, err := ring.ResolveFrom(book, server)
+		if err != nil {
+			break
+		}
+		if regional(candidate) {
+			regionalServers.Store(region, server)
+			return candidate, nil
+		}
+	}
+	regionalServers.Store(region, -1)
+
+	return info, nil
+}
+
+// linkText formats a resolved link as Markdown, with its expiry when the
+// link states one.
+func linkText(title string, info *anna.DownloadInfo) string {
+	text := fmt.Sprintf("[%s](%s)", title, info.URL)
+	if info.ExpiresAt != nil {
+		text += fmt.Sprintf("\nThe link expires at %s. Use refresh_download_url to get a new one.", info.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+
+	return text
+}
+
+// withServer returns env with the download server pinned to server, or env
+// itself when server is empty.
+func withServer(env *Env, server string) *Env {
+	if server == "" {
+		return env
+	}
+
+	pinned := *env
+	pinned.DownloadServer = server
+	return &pinned
+}
+
+// saveBook downloads the book into the configured download path and runs the
+// post-download steps. It returns the local path of the saved file.
+func saveBook(env *Env, book *anna.Book) (string, error) {
+	if err := checkDownloadPath(env); err != nil {
+		return "", err
+	}
+	if err := checkFormat(env, book); err != nil {
+		return "", err
+	}
+	if err := checkFileSize(env, book); err != nil {
+		return "", err
+	}
+	// Make room for the reported size before a fast download is spent
+	if err := reserveSpace(env, book.SizeBytes()); err != nil {
+		return "", err
+	}
+
+	info, err := resolveDownload(env, book)
+	if err != nil {
+		return "", fmt.Errorf("failed to get download URL: %w", err)
+	}
+
+	return storeBook(env, book, info.URL)
+}
+
+// extractTimeout bounds the extraction of a downloaded container.
+const extractTimeout = 10 * time.Minute
+
+// containerFormats are the formats extracted by extractBook. EPUB and Office
+// documents are ZIP files too, but are read whole.
+var containerFormats = []string{"zip", "cbz", "rar", "cbr"}
+
+// extractBook unpacks a saved book that is a ZIP or RAR container into a
+// folder named after it and records the folder in the library index. The
+// extracted content counts towards the disk quota, and is removed again if it
+// does not fit. Failures are logged, since the book itself is saved.
+func extractBook(env *Env, book *anna.Book, path string) {
+	l := logger.GetLogger()
+
+	if !hasFormat(containerFormats, strings.TrimPrefix(filepath.Ext(path), ".")) {
+		return
+	}
+	// CBR comics are often ZIP files, so the content decides how to extract
+	kind, err := unpack.Detect(path)
+	if err != nil || kind == "" {
+		return
+	}
+
+	dest := strings.TrimSuffix(path, filepath.Ext(path))
+	if dest == path {
+		dest += "_files"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), extractTimeout)
+	defer cancel()
+
+	result, err := unpack.Extract(ctx, kind, path, dest, unpack.Options{UnrarBinary: env.UnrarBinary, MaxBytes: env.MaxFileSizeBytes()})
+	if err != nil {
+		l.Warn("Failed to extract downloaded archive", zap.String("path", path), zap.Error(err))
+		return
+	}
+	folder, err := filepath.Rel(env.DownloadPath, dest)
+	if err != nil {
+		folder = filepath.Base(dest)
+	}
+
+	err = library.Update(env.DownloadPath, func(index *library.Index) error {
+		entry, ok := index.Entries[book.Hash]
+		if !ok {
+			return nil
+		}
+		entry.Extracted, entry.ExtractedFiles, entry.ExtractedBytes = folder, result.Files, result.Bytes
+		index.Entries[book.Hash] = entry
+
+		evicted, err := index.Reserve(0, env.DiskQuotaBytes(), env.DiskQuotaEvict, book.Hash)
+		logEvictions(evicted)
+		if err != nil {
+			entry.Extracted, entry.ExtractedFiles, entry.ExtractedBytes = "", 0, 0
+			index.Entries[book.Hash] = entry
+			return errors.Join(err, os.RemoveAll(dest))
+		}
+		return nil
+	})
+	if err != nil {
+		l.Warn("Failed to record extracted archive", zap.String("path", dest), zap.Error(err))
+		return
+	}
+
+	l.Info("Extracted downloaded archive",
+		zap.String("path", dest),
+		zap.Int("filesCount", result.Files),
+		zap.Int64("bytes", result.Bytes),
+	)
+}
+
+// sidecarRecord is the content of a metadata sidecar.
+type sidecarRecord struct {
+	*anna.Metadata
+	File    string    `json:"file"`
+	SavedAt time.Time `json:"saved_at"`
+}
+
+// writeSidecar writes the record of book to the metadata sidecar of the file
+// at path, falling back to the search result when the record cannot be
+// fetched. Failures are logged, since the book itself is saved. It returns
+// the path of the sidecar, empty when none was written.
+func writeSidecar(book *anna.Book, path string) string {
+	l := logger.GetLogger()
+
+	record := sidecarRecord{Metadata: &anna.Metadata{Book: *book}, File: filepath.Base(path), SavedAt: time.Now().UTC()}
+	if book.Hash != "" {
+		metadata, err := fetchMetadata(context.Background(), book.Hash, false, -1)
+		if err != nil {
+			l.Warn("Failed to fetch the record of a metadata sidecar", zap.String("bookHash", book.Hash), zap.Error(err))
+		} else {
+			record.Metadata = metadata
+		}
+	}
+
+	if err := library.WriteSidecar(path, record); err != nil {
+		l.Warn("Failed to write metadata sidecar", zap.String("path", path), zap.Error(err))
+		return ""
+	}
+
+	return path + library.SidecarSuffix
+}
+
+// storeBook fetches a book from an already resolved download URL into the
+// download path, indexes it, and runs the post-download steps. It returns the
+// local path of the saved file.
+func storeBook(env *Env, book *anna.Book, downloadURL string) (string, error) {
+	l := logger.GetLogger()
+
+	path, err := fetchBook(env, book, downloadURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download book: %w", err)
+	}
+	if err := indexBook(env, book, path); err != nil {
+		return "", err
+	}
+	if env.ExtractArchives {
+		extractBook(env, book, path)
+	}
+	sidecar := ""
+	if env.MetadataSidecars {
+		sidecar = writeSidecar(book, path)
+	}
+
+	if env.RcloneRemote != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+		defer cancel()
+
+		// Mirror the folders of the library layout on the remote
+		remote := env.RcloneRemote
+		if dir := book.Dir(env.LibraryLayout); dir != "" {
+			remote = strings.TrimSuffix(remote, "/")
+			if !strings.HasSuffix(remote, ":") {
+				remote += "/"
+			}
+			remote += filepath.ToSlash(dir)
+		}
+
+		if err := rclone.Copy(ctx, env.RcloneBinary, path, remote); err != nil {
+			return "", fmt.Errorf("downloaded to %s but upload failed: %w", path, err)
+		}
+		if sidecar != "" {
+			if err := rclone.Copy(ctx, env.RcloneBinary, sidecar, remote); err != nil {
+				return "", fmt.Errorf("downloaded to %s but upload of its sidecar failed: %w", path, err)
+			}
+		}
+
+		l.Info("Uploaded book to rclone remote",
+			zap.String("path", path),
+			zap.String("remote", remote),
+		)
+	}
+
+	return path, nil
+}
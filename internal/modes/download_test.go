@@ -0,0 +1,118 @@
+package modes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/config"
+	"github.com/iosifache/annas-mcp/internal/pdfinfo"
+)
+
+func TestDownloadPolicy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><main><div class="text-3xl">Dune</div><div class="text-gray-800">✅ English [en] · DJVU · 300MB · 1965</div></main></body></html>`)
+	}))
+	defer server.Close()
+
+	if err := anna.Configure(anna.Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Failed to configure client: %v", err)
+	}
+	defer anna.Configure(anna.Options{})
+
+	env := config.Defaults()
+	env.AllowedFormats = []string{"pdf", "epub"}
+	env.MaxFileSize = "100MB"
+
+	t.Run("Format", func(t *testing.T) {
+		// The format claimed by the client is replaced by the record's
+		book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Format: "epub"}
+		err := checkFormat(env, book)
+		if err == nil || !strings.Contains(err.Error(), "djvu") {
+			t.Errorf("Expected djvu to be rejected, got %v", err)
+		}
+		if book.Size != "300MB" {
+			t.Errorf("Expected Size '300MB' from the record, got '%s'", book.Size)
+		}
+	})
+
+	t.Run("FormatLookupFails", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer failing.Close()
+		if err := anna.Configure(anna.Options{Mirrors: []string{failing.URL}}); err != nil {
+			t.Fatalf("Failed to configure client: %v", err)
+		}
+		defer anna.Configure(anna.Options{Mirrors: []string{server.URL}})
+
+		// The claimed format is not trusted when the record is unavailable
+		book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Format: "epub"}
+		err := checkFormat(env, book)
+		if err == nil || errorCode(err) != codeUpstreamDown {
+			t.Errorf("Expected %s, got %v", codeUpstreamDown, err)
+		}
+	})
+
+	t.Run("Size", func(t *testing.T) {
+		book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45"}
+		if err := checkFileSize(env, book); !errors.Is(err, anna.ErrFileTooLarge) {
+			t.Errorf("Expected ErrFileTooLarge, got %v", err)
+		}
+	})
+}
+
+func TestIndexBookProbesPDF(t *testing.T) {
+	env := config.Defaults()
+	env.DownloadPath = t.TempDir()
+
+	path := filepath.Join(env.DownloadPath, "Sample.pdf")
+	os.WriteFile(path, []byte("%PDF-1.4\n1 0 obj << /Type /Pages /Kids [2 0 R 3 0 R 4 0 R] /Count 3 /MediaBox [0 0 612 792] >> endobj\n%%EOF\n"), 0o644)
+
+	book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Format: "pdf"}
+	if err := indexBook(env, book, path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	note, details := savedDetails(env, book)
+	if pdf, _ := details["pdf"].(*pdfinfo.Info); pdf == nil || pdf.Pages != 3 {
+		t.Fatalf("Expected 3 pages in the library index, got %+v", details["pdf"])
+	}
+	if note != " (3 pages of 612 x 792 pt)" {
+		t.Errorf("Expected note ' (3 pages of 612 x 792 pt)', got '%s'", note)
+	}
+}
+
+func TestIndexBookFlagsLanguageMismatch(t *testing.T) {
+	env := config.Defaults()
+	env.DownloadPath = t.TempDir()
+
+	path := filepath.Join(env.DownloadPath, "Dune.txt")
+	text := strings.Repeat("Der Wüstenplanet ist die Welt, auf der er mit seinem Vater und den Truppen des Hauses lebt, und sie wird nicht leicht sein. ", 10)
+	os.WriteFile(path, []byte(text), 0o644)
+
+	book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Format: "txt", Language: "English"}
+	if err := indexBook(env, book, path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	note, details := savedDetails(env, book)
+	check, _ := details["language"].(languageCheck)
+	if check.Detected != "de" || !check.Mismatch {
+		t.Fatalf("Expected a German mismatch, got %+v", check)
+	}
+	if note != "\nWarning: the content appears to be in German, but the record claims English" {
+		t.Errorf("Unexpected note '%s'", note)
+	}
+
+	book.Language = "German"
+	if _, details := savedDetails(env, book); details["language"].(languageCheck).Mismatch {
+		t.Errorf("Expected no mismatch for a German record")
+	}
+}
@@ -2,85 +2,145 @@ package modes
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/config"
 	"github.com/iosifache/annas-mcp/internal/logger"
 	"go.uber.org/zap"
 )
 
-type Env struct {
-	SecretKey    string `json:"secret"`
-	DownloadPath string `json:"download_path"`
-}
+// Env is the effective configuration handed to the tool handlers.
+type Env = config.Config
+
+// errReadOnly is returned for operations that read-only deployments reject.
+var errReadOnly = errors.New("downloads are disabled in read-only mode (--read-only or ANNAS_READ_ONLY)")
+
+// loadOptions selects the config file and flags of the running command. It is
+// set once by StartCLI before any command runs.
+var loadOptions config.Options
 
 // LoadEnv resolves the configuration from multiple sources in order of priority:
-// 1. Query Parameters (if req is provided)
-// 2. Standard Environment Variables (ANNAS_SECRET_KEY, ANNAS_DOWNLOAD_PATH)
-// 3. Smithery-style Environment Variables (secretKey, downloadPath)
-// 4. Generic Environment Variable (SECRET_KEY)
+//  1. Request Headers (X-Annas-Secret-Key, X-Annas-Download-Path; if req is provided)
+//  2. Query Parameters (if req is provided)
+//  3. Command-line flags
+//  4. Standard Environment Variables (ANNAS_SECRET_KEY, ANNAS_DOWNLOAD_PATH)
+//     or their _FILE variants (ANNAS_SECRET_KEY_FILE, ...)
+//  5. Smithery-style Environment Variables (secretKey, downloadPath)
+//  6. Generic Environment Variable (SECRET_KEY)
+//  7. The selected profile of the config file (--profile or ANNAS_PROFILE)
+//  8. The JSON config file (--config or ANNAS_CONFIG)
+//  9. Defaults
+//
+// Notification and delivery settings are never read from the request.
+// When only the secret key is missing, the returned configuration is still
+// usable for operations that do not require it.
 func LoadEnv(req *http.Request) (*Env, error) {
 	l := logger.GetLogger()
 
-	var secretKey string
-	var downloadPath string
+	env, err := baseConfig()
+	if err != nil {
+		l.Error("Failed to load configuration", zap.Error(err))
+		return nil, err
+	}
 
-	// 1. Check Query Parameters (if request is provided)
 	if req != nil {
-		query := req.URL.Query()
-		if val := query.Get("secretKey"); val != "" {
-			secretKey = val
-		} else if val := query.Get("ANNAS_SECRET_KEY"); val != "" {
-			secretKey = val
-		}
+		env = env.WithRequest(req)
+	}
 
-		if val := query.Get("downloadPath"); val != "" {
-			downloadPath = val
-		} else if val := query.Get("ANNAS_DOWNLOAD_PATH"); val != "" {
-			downloadPath = val
-		}
+	// Validate required fields
+	if len(env.Keys()) == 0 {
+		err := errors.New("secretKey must be set via X-Annas-Secret-Key header, query param, ANNAS_SECRET_KEY, ANNAS_SECRET_KEY_FILE, ANNAS_SECRET_KEYS, SECRET_KEY, or secretKey env var")
+		l.Error("Environment variables not set", zap.Error(err))
+		return env, err
 	}
 
-	// 2. Check Standard Environment Variables (if not found in query)
-	if secretKey == "" {
-		secretKey = os.Getenv("ANNAS_SECRET_KEY")
+	return env, nil
+}
+
+// sessionConfigSchema returns the JSON schema of the per-session settings,
+// served at /.well-known/mcp-config for Smithery. Its properties are the
+// per-request fields of the config package, so new ones show up in
+// Smithery's UI without touching this schema.
+func sessionConfigSchema() map[string]interface{} {
+	properties, required := config.SessionSchema()
+
+	return map[string]interface{}{
+		"$schema":              "http://json-schema.org/draft-07/schema#",
+		"$id":                  "/.well-known/mcp-config",
+		"title":                "Anna's Archive MCP Configuration",
+		"description":          "Configuration for connecting to Anna's Archive MCP server",
+		"x-query-style":        "dot+bracket",
+		"type":                 "object",
+		"required":             required,
+		"additionalProperties": false,
+		"properties":           properties,
 	}
-	if downloadPath == "" {
-		downloadPath = os.Getenv("ANNAS_DOWNLOAD_PATH")
+}
+
+// GetEnv is a wrapper around LoadEnv(nil) for backwards compatibility and CLI usage
+func GetEnv() (*Env, error) {
+	return LoadEnv(nil)
+}
+
+// configureClient points the Anna's Archive client at the mirrors and proxy
+// of cfg and applies its connection pool settings. These settings are
+// process-wide and never taken from a request.
+func configureClient(cfg *Env) error {
+	agents, err := readUserAgents(cfg.UserAgentsFile)
+	if err != nil {
+		return err
 	}
 
-	// 3. Check Smithery-style Environment Variables (if not found yet)
-	if secretKey == "" {
-		secretKey = os.Getenv("secretKey")
+	opts := anna.Options{
+		Mirrors:             cfg.Mirrors,
+		Proxy:               cfg.Proxy,
+		CookieFile:          cfg.CookieFile,
+		Locale:              cfg.Locale,
+		MaxIdleConns:        cfg.HTTPMaxIdleConns,
+		MaxIdleConnsPerHost: cfg.HTTPMaxIdleConnsPerHost,
+		MaxConnsPerHost:     cfg.HTTPMaxConnsPerHost,
+		IdleConnTimeout:     time.Duration(cfg.HTTPIdleTimeoutSeconds) * time.Second,
+		DisableKeepAlives:   !cfg.HTTPKeepAlive,
+		DisableHTTP2:        !cfg.HTTP2,
+		RateLimitWait:       time.Duration(cfg.RateLimitWaitSeconds) * time.Second,
+		UserAgent:           cfg.UserAgent,
+		UserAgents:          agents,
+		UserAgentRotation:   cfg.UserAgentRotation,
+		BreakerThreshold:    cfg.BreakerThreshold,
+		BreakerCooldown:     time.Duration(cfg.BreakerCooldownSeconds) * time.Second,
+		FixtureDir:          cfg.FixtureDir,
+		FixtureMode:         cfg.FixtureMode,
 	}
-	if downloadPath == "" {
-		downloadPath = os.Getenv("downloadPath")
+	if err := anna.Configure(opts); err != nil {
+		return fmt.Errorf("failed to configure client: %w", err)
 	}
+	anna.SetPartners(cfg.PartnerServers)
 
-	// 4. Check Generic Environment Variable (if not found yet)
-	if secretKey == "" {
-		secretKey = os.Getenv("SECRET_KEY")
-	}
+	return nil
+}
 
-	// Validate required fields
-	if secretKey == "" {
-		err := errors.New("secretKey must be set via query param, ANNAS_SECRET_KEY, SECRET_KEY, or secretKey env var")
-		l.Error("Environment variables not set", zap.Error(err))
-		return nil, err
+// readUserAgents returns the user agents listed in path, one per line,
+// skipping blank lines and # comments. An empty path lists none.
+func readUserAgents(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
 	}
 
-	// Set default download path if not provided
-	if downloadPath == "" {
-		downloadPath = "/tmp/downloads"
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read user agents file: %w", err)
 	}
 
-	return &Env{
-		SecretKey:    secretKey,
-		DownloadPath: downloadPath,
-	}, nil
-}
-
-// GetEnv is a wrapper around LoadEnv(nil) for backwards compatibility and CLI usage
-func GetEnv() (*Env, error) {
-	return LoadEnv(nil)
+	var agents []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			agents = append(agents, line)
+		}
+	}
+	return agents, nil
 }
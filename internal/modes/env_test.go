@@ -3,7 +3,10 @@ package modes
 import (
 	"net/http"
 	"os"
+	"path/filepath"
 	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/config"
 )
 
 func TestLoadEnv(t *testing.T) {
@@ -17,8 +20,26 @@ func TestLoadEnv(t *testing.T) {
 		if env.SecretKey != "querySecret" {
 			t.Errorf("Expected SecretKey 'querySecret', got '%s'", env.SecretKey)
 		}
-		if env.DownloadPath != "queryPath" {
-			t.Errorf("Expected DownloadPath 'queryPath', got '%s'", env.DownloadPath)
+		// Per-request paths are resolved inside the configured download path
+		if env.DownloadPath != "/tmp/downloads/queryPath" {
+			t.Errorf("Expected DownloadPath '/tmp/downloads/queryPath', got '%s'", env.DownloadPath)
+		}
+	})
+
+	// Test case 1b: Headers take precedence over Query Parameters
+	t.Run("Priority 0: Request Headers", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "http://example.com?secretKey=querySecret&downloadPath=queryPath", nil)
+		req.Header.Set("X-Annas-Secret-Key", "headerSecret")
+		env, err := LoadEnv(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if env.SecretKey != "headerSecret" {
+			t.Errorf("Expected SecretKey 'headerSecret', got '%s'", env.SecretKey)
+		}
+		// Fields without a header fall back to the query parameter
+		if env.DownloadPath != "/tmp/downloads/queryPath" {
+			t.Errorf("Expected DownloadPath '/tmp/downloads/queryPath', got '%s'", env.DownloadPath)
 		}
 	})
 
@@ -101,7 +122,40 @@ func TestLoadEnv(t *testing.T) {
 		}
 	})
 
-	// Test case 5: Missing Secret Key
+	// Test case 5: Secret read from a _FILE variant
+	t.Run("Secret File", func(t *testing.T) {
+		os.Unsetenv("ANNAS_SECRET_KEY")
+		os.Unsetenv("secretKey")
+
+		path := filepath.Join(t.TempDir(), "secret")
+		if err := os.WriteFile(path, []byte("fileSecret\n"), 0o600); err != nil {
+			t.Fatalf("Failed to write secret file: %v", err)
+		}
+		os.Setenv("ANNAS_SECRET_KEY_FILE", path)
+		defer os.Unsetenv("ANNAS_SECRET_KEY_FILE")
+
+		req, _ := http.NewRequest("GET", "http://example.com", nil)
+		env, err := LoadEnv(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if env.SecretKey != "fileSecret" {
+			t.Errorf("Expected SecretKey 'fileSecret', got '%s'", env.SecretKey)
+		}
+
+		// The plain variable still takes precedence over the file
+		os.Setenv("ANNAS_SECRET_KEY", "stdSecret")
+		defer os.Unsetenv("ANNAS_SECRET_KEY")
+		env, err = LoadEnv(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if env.SecretKey != "stdSecret" {
+			t.Errorf("Expected SecretKey 'stdSecret', got '%s'", env.SecretKey)
+		}
+	})
+
+	// Test case 6: Missing Secret Key
 	t.Run("Missing Secret Key", func(t *testing.T) {
 		os.Unsetenv("ANNAS_SECRET_KEY")
 		os.Unsetenv("secretKey")
@@ -116,3 +170,46 @@ func TestLoadEnv(t *testing.T) {
 		}
 	})
 }
+
+func TestReloadConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	os.WriteFile(file, []byte(`{"secret_key": "oldSecret", "port": 9000}`), 0o600)
+
+	loadOptions.File = file
+	defer func() {
+		loadOptions.File = ""
+		activeConfig.Store(nil)
+	}()
+
+	env, err := GetEnv()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	activeConfig.Store(env)
+
+	os.WriteFile(file, []byte(`{"secret_key": "newSecret", "port": 9001, "mirrors": ["annas-archive.se"]}`), 0o600)
+	if env, _ := GetEnv(); env.SecretKey != "oldSecret" {
+		t.Errorf("Expected SecretKey 'oldSecret' before reload, got '%s'", env.SecretKey)
+	}
+
+	if err := reloadConfig(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer configureClient(config.Defaults())
+
+	env, _ = GetEnv()
+	if env.SecretKey != "newSecret" {
+		t.Errorf("Expected SecretKey 'newSecret' after reload, got '%s'", env.SecretKey)
+	}
+	if env.Port != 9000 {
+		t.Errorf("Expected Port 9000 to survive the reload, got %d", env.Port)
+	}
+
+	os.WriteFile(file, []byte(`{"secret_key": `), 0o600)
+	if err := reloadConfig(); err == nil {
+		t.Error("Expected error for invalid config file, got nil")
+	}
+	if env, _ := GetEnv(); env.SecretKey != "newSecret" {
+		t.Errorf("Expected previous configuration to be kept, got '%s'", env.SecretKey)
+	}
+}
@@ -0,0 +1,102 @@
+package modes
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/kindle"
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/scheduler"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// Machine-readable error codes of tool results, so agents can decide whether
+// to retry, fall back, or give up.
+const (
+	codeInvalidHash      = "INVALID_HASH"
+	codeInvalidArgument  = "INVALID_ARGUMENT"
+	codeQuotaExceeded    = "QUOTA_EXCEEDED"
+	codeRateLimited      = "RATE_LIMITED"
+	codeUpstreamDown     = "UPSTREAM_DOWN"
+	codeNotConfigured    = "NOT_CONFIGURED"
+	codeForbidden        = "FORBIDDEN"
+	codeFileTooLarge     = "FILE_TOO_LARGE"
+	codeFormatNotAllowed = "FORMAT_NOT_ALLOWED"
+	codeInternal         = "INTERNAL"
+)
+
+// codedError attaches an error code to errors that cannot be classified by
+// their type.
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *codedError) Unwrap() error {
+	return e.err
+}
+
+// withCode returns an error with the given code and message.
+func withCode(code, format string, args ...any) error {
+	return &codedError{code: code, err: fmt.Errorf(format, args...)}
+}
+
+// errSecretKeyMissing is returned by tools that need a fast download key.
+var errSecretKeyMissing = withCode(codeNotConfigured, "secret key is not configured. Please set ANNAS_SECRET_KEY, secretKey, or pass it via query parameters")
+
+// errorCode classifies err into one of the tool result error codes.
+func errorCode(err error) string {
+	var coded *codedError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+
+	var apiErr *anna.APIError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, anna.ErrInvalidHash):
+		return codeInvalidHash
+	case errors.Is(err, anna.ErrInvalidDOI), errors.Is(err, scheduler.ErrInvalidCron), errors.Is(err, scheduler.ErrNotFound):
+		return codeInvalidArgument
+	case errors.Is(err, errRateLimited), errors.Is(err, library.ErrQuotaExceeded), errors.Is(err, usage.ErrQuotaExceeded):
+		return codeQuotaExceeded
+	case errors.Is(err, anna.ErrFileTooLarge), errors.Is(err, kindle.ErrTooLarge):
+		return codeFileTooLarge
+	case errors.Is(err, anna.ErrRateLimited):
+		return codeRateLimited
+	case errors.As(err, &apiErr) && apiErr.KeyRelated():
+		if strings.Contains(strings.ToLower(apiErr.Message), "invalid") {
+			return codeNotConfigured
+		}
+		return codeQuotaExceeded
+	case errors.Is(err, anna.ErrCircuitOpen), errors.Is(err, anna.ErrBlocked), errors.Is(err, anna.ErrMaintenance), errors.As(err, &netErr):
+		return codeUpstreamDown
+	}
+
+	return codeInternal
+}
+
+// errorResult turns err into an error tool result carrying both the code and
+// a human-readable message.
+func errorResult(err error) *mcp.CallToolResult {
+	code := errorCode(err)
+
+	return &mcp.CallToolResult{
+		IsError: true,
+		Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("%s: %s", code, err)}},
+		StructuredContent: map[string]interface{}{
+			"error": map[string]string{
+				"code":    code,
+				"message": err.Error(),
+			},
+		},
+	}
+}
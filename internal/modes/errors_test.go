@@ -0,0 +1,49 @@
+package modes
+
+import (
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestErrorCode(t *testing.T) {
+	_, invalidHash := validateHash("nope")
+
+	cases := map[string]error{
+		codeInvalidHash:   invalidHash,
+		codeQuotaExceeded: fmt.Errorf("failed to save: %w", library.ErrQuotaExceeded),
+		codeUpstreamDown:  anna.ErrCircuitOpen,
+		codeRateLimited:   &url.Error{Op: "Get", URL: "https://annas-archive.org", Err: &anna.RateLimitError{Host: "annas-archive.org"}},
+		codeNotConfigured: errSecretKeyMissing,
+		codeInternal:      fmt.Errorf("something else"),
+	}
+	for expected, err := range cases {
+		if got := errorCode(err); got != expected {
+			t.Errorf("Expected code '%s' for '%v', got '%s'", expected, err, got)
+		}
+	}
+
+	apiErr := &anna.APIError{Message: "No downloads left"}
+	if got := errorCode(apiErr); got != codeQuotaExceeded {
+		t.Errorf("Expected code '%s' for an exhausted key, got '%s'", codeQuotaExceeded, got)
+	}
+}
+
+func TestErrorResult(t *testing.T) {
+	result := errorResult(errSecretKeyMissing)
+	if !result.IsError {
+		t.Error("Expected an error result")
+	}
+
+	structured := result.StructuredContent.(map[string]interface{})["error"].(map[string]string)
+	if structured["code"] != codeNotConfigured || structured["message"] != errSecretKeyMissing.Error() {
+		t.Errorf("Expected structured code and message, got %v", structured)
+	}
+	if text := result.Content[0].(*mcp.TextContent).Text; text != codeNotConfigured+": "+errSecretKeyMissing.Error() {
+		t.Errorf("Unexpected text '%s'", text)
+	}
+}
@@ -0,0 +1,85 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/notify"
+	"go.uber.org/zap"
+)
+
+// eventBroker receives the events of every download of the process, for the
+// subscribers of /events.
+var eventBroker = notify.NewBroker()
+
+// eventsBuffer is how many events a slow /events client may lag behind
+// before it misses some.
+const eventsBuffer = 64
+
+// eventsKeepAlive is how often idle /events streams get a comment, so that
+// proxies do not time them out.
+const eventsKeepAlive = 15 * time.Second
+
+// publishProgress reports how far the download of book got to the /events
+// subscribers. Progress is too frequent for the notification backends.
+func publishProgress(book *anna.Book, progress anna.Progress) {
+	event := downloadEvent(notify.EventDownloadProgress, book)
+	event.Timestamp = time.Now().UTC()
+	event.Bytes = progress.Bytes
+	event.Total = progress.Total
+
+	// The broker never fails
+	_ = eventBroker.Notify(context.Background(), event)
+}
+
+// eventsHandler streams download events as server-sent events, one JSON
+// object per event named after its type. Events cover every caller, so they
+// are reserved to operators.
+func eventsHandler(l *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := checkScope(auth.CallerFrom(r.Context()).Scopes, auth.ScopeAdmin); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		events, cancel := eventBroker.Subscribe(eventsBuffer)
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, ": connected\n\n")
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": ping\n\n")
+			case event := <-events:
+				data, err := json.Marshal(event)
+				if err != nil {
+					l.Error("Failed to encode event", zap.Error(err))
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			}
+			flusher.Flush()
+		}
+	})
+}
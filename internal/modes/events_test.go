@@ -0,0 +1,73 @@
+package modes
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/logger"
+)
+
+func TestEventsHandler(t *testing.T) {
+	handler := eventsHandler(logger.GetLogger())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller := auth.Local
+		if r.URL.Query().Has("guest") {
+			caller = auth.Caller{Name: "guests", Scopes: auth.Scopes{auth.ScopeSearch}}
+		}
+		handler.ServeHTTP(w, r.WithContext(auth.WithCaller(r.Context(), caller)))
+	}))
+	defer server.Close()
+
+	t.Run("Forbidden", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "?guest")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status 403 without the admin scope, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("Progress", func(t *testing.T) {
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+		if got := resp.Header.Get("Content-Type"); got != "text/event-stream" {
+			t.Errorf("Expected content type 'text/event-stream', got '%s'", got)
+		}
+
+		// The handler subscribes before it sends the first comment
+		lines := bufio.NewScanner(resp.Body)
+		lines.Scan()
+		publishProgress(&anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45"}, anna.Progress{Bytes: 5, Total: 10})
+
+		deadline := time.AfterFunc(5*time.Second, func() { resp.Body.Close() })
+		defer deadline.Stop()
+		var event, data string
+		for lines.Scan() && data == "" {
+			line := lines.Text()
+			if value, ok := strings.CutPrefix(line, "event: "); ok {
+				event = value
+			}
+			if value, ok := strings.CutPrefix(line, "data: "); ok {
+				data = value
+			}
+		}
+
+		if event != "download.progress" {
+			t.Errorf("Expected event 'download.progress', got '%s'", event)
+		}
+		if !strings.Contains(data, `"bytes":5`) || !strings.Contains(data, `"total":10`) {
+			t.Errorf("Expected the progress in the event data, got '%s'", data)
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package modes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/booktext"
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/ocr"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// defaultTextLimit bounds the characters returned by extract_text, so a
+// whole book does not flood the context of the client.
+const defaultTextLimit = 100000
+
+// ocrTimeout bounds the OCR of one extract_text call.
+const ocrTimeout = 10 * time.Minute
+
+// extractedText is the text of a saved book.
+type extractedText struct {
+	Hash      string `json:"md5"`
+	File      string `json:"file"`
+	Text      string `json:"text"`
+	Truncated bool   `json:"truncated"`
+	// OCR is set when the text was recognized from the page images of a
+	// scanned PDF, whose pages are listed.
+	OCR   bool  `json:"ocr"`
+	Pages []int `json:"pages,omitempty"`
+}
+
+// savedBook returns the library index entry and the path of a book saved to
+// the download path.
+func savedBook(env *Env, hash string) (library.Entry, string, error) {
+	index, err := library.Load(env.DownloadPath)
+	if err != nil {
+		return library.Entry{}, "", fmt.Errorf("failed to load library index: %w", err)
+	}
+	entry, ok := index.Entries[hash]
+	if !ok {
+		return library.Entry{}, "", withCode(codeInvalidArgument, "%s is not saved to %s, download it with save first", hash, env.DownloadPath)
+	}
+
+	return entry, filepath.Join(env.DownloadPath, entry.File), nil
+}
+
+// extractText reads the text of a book saved to the download path. Scanned
+// PDFs without a text layer are recognized with tesseract when OCR is
+// enabled, for at most ANNAS_OCR_MAX_PAGES pages starting at the first
+// requested one.
+func extractText(ctx context.Context, env *Env, params ExtractTextParams) (*extractedText, error) {
+	hash, err := validateHash(params.BookHash)
+	if err != nil {
+		return nil, err
+	}
+	if params.Limit < 0 || params.FirstPage < 0 || params.Pages < 0 {
+		return nil, withCode(codeInvalidArgument, "limit, first page, and pages must not be negative")
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultTextLimit
+	}
+
+	entry, path, err := savedBook(env, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := booktext.Extract(path, limit)
+	if errors.Is(err, booktext.ErrUnsupported) {
+		return nil, withCode(codeInvalidArgument, "cannot extract the text of %s", entry.File)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text of %s: %w", entry.File, err)
+	}
+	result := &extractedText{Hash: hash, File: entry.File, Text: text, Truncated: len([]rune(text)) >= limit}
+	if text != "" || !strings.EqualFold(filepath.Ext(path), ".pdf") {
+		return result, nil
+	}
+
+	if !env.OCR {
+		return nil, withCode(codeNotConfigured, "%s has no text layer, set ANNAS_OCR=true to recognize it with tesseract", entry.File)
+	}
+	count := params.Pages
+	if count == 0 || count > env.OCRMaxPages {
+		count = env.OCRMaxPages
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ocrTimeout)
+	defer cancel()
+	pages, err := ocr.PDF(ctx, path, params.FirstPage, count, ocr.Options{Binary: env.TesseractBinary, Languages: env.OCRLanguages})
+	if err != nil {
+		return nil, fmt.Errorf("failed to OCR %s: %w", entry.File, err)
+	}
+
+	texts := make([]string, len(pages))
+	for i, page := range pages {
+		texts[i] = page.Text
+		result.Pages = append(result.Pages, page.Number)
+	}
+	result.OCR = true
+	result.Text = strings.Join(texts, "\n\n")
+	if runes := []rune(result.Text); len(runes) > limit {
+		result.Text, result.Truncated = string(runes[:limit]), true
+	}
+
+	return result, nil
+}
+
+// String renders extracted text for tool results, noting OCR and truncation.
+func (t *extractedText) String() string {
+	text := t.Text
+	if t.OCR {
+		text = fmt.Sprintf("OCR of pages %d-%d of %s:\n\n%s", t.Pages[0], t.Pages[len(t.Pages)-1], t.File, text)
+	}
+	if t.Truncated {
+		text += "\n\n[truncated]"
+	}
+
+	return text
+}
+
+// NewExtractTextToolHandler creates a handler for the extract_text tool that uses the provided environment.
+func NewExtractTextToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, ExtractTextParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params ExtractTextParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Extract text command called", zap.String("bookHash", params.BookHash))
+
+		text, err := extractText(ctx, env, params)
+		if err != nil {
+			l.Error("Extract text command failed", zap.String("bookHash", params.BookHash), zap.Error(err))
+			return nil, nil, err
+		}
+
+		l.Info("Extract text command completed successfully",
+			zap.String("bookHash", text.Hash),
+			zap.Bool("ocr", text.OCR),
+		)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: text.String()}},
+		}, text, nil
+	}
+}
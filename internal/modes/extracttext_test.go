@@ -0,0 +1,53 @@
+package modes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/config"
+)
+
+func TestExtractText(t *testing.T) {
+	env := config.Defaults()
+	env.DownloadPath = t.TempDir()
+	env.DetectLanguage = false
+
+	path := filepath.Join(env.DownloadPath, "Dune.txt")
+	os.WriteFile(path, []byte("A beginning is the time for taking the most delicate care."), 0o644)
+	book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Format: "txt"}
+	if err := indexBook(env, book, path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	text, err := extractText(context.Background(), env, ExtractTextParams{BookHash: book.Hash, Limit: 11})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if text.Text != "A beginning" || !text.Truncated || text.OCR {
+		t.Errorf("Expected truncated text 'A beginning', got %+v", text)
+	}
+
+	t.Run("scanned PDF without OCR", func(t *testing.T) {
+		path := filepath.Join(env.DownloadPath, "Scan.pdf")
+		os.WriteFile(path, []byte("%PDF-1.4\n1 0 obj << /Type /Pages /Count 1 >> endobj\n%%EOF\n"), 0o644)
+		scan := &anna.Book{Hash: "0123456789abcdef0123456789abcdef", Format: "pdf"}
+		if err := indexBook(env, scan, path); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		_, err := extractText(context.Background(), env, ExtractTextParams{BookHash: scan.Hash})
+		if code := errorCode(err); code != codeNotConfigured {
+			t.Errorf("Expected code '%s', got '%s' (%v)", codeNotConfigured, code, err)
+		}
+	})
+
+	t.Run("unsaved book", func(t *testing.T) {
+		_, err := extractText(context.Background(), env, ExtractTextParams{BookHash: "ffffffffffffffffffffffffffffffff"})
+		if code := errorCode(err); code != codeInvalidArgument {
+			t.Errorf("Expected code '%s', got '%s'", codeInvalidArgument, code)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package modes
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/scheduler"
+	"go.uber.org/zap"
+)
+
+type feedRSS struct {
+	XMLName xml.Name    `xml:"rss"`
+	Version string      `xml:"version,attr"`
+	Channel feedChannel `xml:"channel"`
+}
+
+type feedChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Items         []feedItem `xml:"item"`
+}
+
+type feedItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	Description string   `xml:"description"`
+	GUID        feedGUID `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type feedGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// feedHandler serves /feeds/{id}.xml, an RSS feed of the new results of a
+// scheduled search. Feed readers rarely send headers, so the API key or token
+// may also be passed as the apikey query parameter, like for the indexer API.
+func feedHandler(provider auth.Provider, l *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller, err := provider.Authenticate(r.Context(), requestCredentials(r, true))
+		if err != nil {
+			challenge(w, r, provider)
+			http.Error(w, "Unauthorized: Missing or invalid API key", http.StatusUnauthorized)
+			return
+		}
+		if err := checkScope(caller.Scopes, auth.ScopeSearch); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		id, ok := strings.CutSuffix(r.PathValue("feed"), ".xml")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		env, err := baseConfig()
+		if err != nil {
+			l.Error("Failed to load configuration", zap.Error(err))
+			http.Error(w, "failed to load configuration", http.StatusInternalServerError)
+			return
+		}
+		schedules, err := listSchedules(env)
+		if err != nil {
+			l.Error("Failed to load schedules", zap.Error(err))
+			http.NotFound(w, r)
+			return
+		}
+
+		for _, schedule := range schedules {
+			if schedule.ID == id {
+				writeFeed(w, l, scheduleFeed(schedule))
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}
+}
+
+// scheduleFeed lists the new results of a schedule, newest first.
+func scheduleFeed(schedule scheduler.Schedule) feedRSS {
+	items := make([]feedItem, 0, len(schedule.Results))
+	for i := len(schedule.Results) - 1; i >= 0; i-- {
+		result := schedule.Results[i]
+		book := &anna.Book{Title: result.Title, Authors: anna.ParseAuthors(result.Authors), Format: result.Format}
+		items = append(items, feedItem{
+			Title:       newznabTitle(book),
+			Link:        result.URL,
+			Description: fmt.Sprintf("New result for %q: %s", schedule.Query, result.Hash),
+			GUID:        feedGUID{Value: schedule.ID + ":" + result.Hash},
+			PubDate:     result.FoundAt.Format(time.RFC1123Z),
+		})
+	}
+
+	channel := feedChannel{
+		Title:       fmt.Sprintf("Anna's Archive: %s", schedule.Query),
+		Link:        fmt.Sprintf(anna.AnnasSearchEndpoint, anna.Mirrors()[0], url.QueryEscape(schedule.Query)),
+		Description: fmt.Sprintf("New results for the scheduled search %q (%s)", schedule.Query, schedule.Cron),
+		Items:       items,
+	}
+	if !schedule.LastRun.IsZero() {
+		channel.LastBuildDate = schedule.LastRun.Format(time.RFC1123Z)
+	}
+
+	return feedRSS{Version: "2.0", Channel: channel}
+}
+
+func writeFeed(w http.ResponseWriter, l *zap.Logger, feed feedRSS) {
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(feed); err != nil {
+		l.Error("Failed to encode feed", zap.Error(err))
+	}
+}
@@ -0,0 +1,79 @@
+package modes
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/scheduler"
+)
+
+func TestFeedHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	t.Setenv("ANNAS_SCHEDULES_FILE", path)
+
+	var schedule scheduler.Schedule
+	err := scheduler.Update(path, func(store *scheduler.Store) error {
+		var err error
+		schedule, err = store.Add("dune", "@daily")
+		found := time.Date(2026, time.March, 6, 8, 0, 0, 0, time.UTC)
+		store.Schedules[0].AddResults(
+			scheduler.Result{Hash: "a", Title: "Dune", Authors: "Frank Herbert", Format: "epub", FoundAt: found},
+			scheduler.Result{Hash: "b", Title: "Dune Messiah", FoundAt: found.Add(24 * time.Hour)},
+		)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/feeds/{feed}", feedHandler(auth.NewKeyProvider("secret", nil), logger.GetLogger()))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	t.Run("Feed", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/feeds/" + schedule.ID + ".xml?apikey=secret")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer resp.Body.Close()
+
+		var feed feedRSS
+		if err := xml.NewDecoder(resp.Body).Decode(&feed); err != nil {
+			t.Fatalf("Failed to decode feed: %v", err)
+		}
+		if len(feed.Channel.Items) != 2 {
+			t.Fatalf("Expected 2 items, got %d", len(feed.Channel.Items))
+		}
+		if feed.Channel.Items[0].Title != "Dune Messiah" {
+			t.Errorf("Expected newest item first, got '%s'", feed.Channel.Items[0].Title)
+		}
+		if feed.Channel.Items[1].Title != "Frank Herbert - Dune [EPUB]" {
+			t.Errorf("Unexpected item title '%s'", feed.Channel.Items[1].Title)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		cases := map[string]int{
+			"/feeds/" + schedule.ID + ".xml":                http.StatusUnauthorized,
+			"/feeds/unknown.xml?apikey=secret":              http.StatusNotFound,
+			"/feeds/" + schedule.ID + ".json?apikey=secret": http.StatusNotFound,
+		}
+		for path, expected := range cases {
+			resp, err := http.Get(server.URL + path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != expected {
+				t.Errorf("Expected status %d for %s, got %d", expected, path, resp.StatusCode)
+			}
+		}
+	})
+}
@@ -0,0 +1,33 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ListFiltersToolHandler returns the common values of the formats and
+// languages arguments of the search tools, so clients can validate them
+// before searching.
+func ListFiltersToolHandler(ctx context.Context, req *mcp.CallToolRequest, params ListFiltersParams) (*mcp.CallToolResult, any, error) {
+	l := logger.GetLogger()
+	l.Info("List formats and languages command called")
+
+	var text strings.Builder
+	text.WriteString("Formats:\n")
+	for _, format := range anna.Formats {
+		fmt.Fprintf(&text, "- %s: %s\n", format.Value, format.Label)
+	}
+	text.WriteString("\nLanguages:\n")
+	for _, language := range anna.Languages {
+		fmt.Fprintf(&text, "- %s: %s\n", language.Value, language.Label)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text.String()}},
+	}, map[string]interface{}{"formats": anna.Formats, "languages": anna.Languages}, nil
+}
@@ -0,0 +1,49 @@
+package modes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestListFilters(t *testing.T) {
+	result, out, err := ListFiltersToolHandler(context.Background(), nil, ListFiltersParams{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	text := result.Content[0].(*mcp.TextContent).Text
+	listed := out.(map[string]interface{})
+
+	cases := []struct {
+		name    string
+		options []anna.FilterOption
+		line    string
+		parse   func([]string) ([]string, error)
+	}{
+		{"formats", anna.Formats, "- epub: EPUB", parseFormats},
+		{"formats", anna.Formats, "- djvu: DjVu", parseFormats},
+		{"languages", anna.Languages, "- en: English", parseLanguages},
+		{"languages", anna.Languages, "- zh: Chinese", parseLanguages},
+	}
+	for _, c := range cases {
+		t.Run(c.line, func(t *testing.T) {
+			if !strings.Contains(text, c.line+"\n") {
+				t.Errorf("Expected '%s' in the listing", c.line)
+			}
+			if options, ok := listed[c.name].([]anna.FilterOption); !ok || len(options) != len(c.options) {
+				t.Errorf("Expected the %d %s in the structured content, got %v", len(c.options), c.name, listed[c.name])
+			}
+
+			// Every listed value is accepted by the search arguments as is
+			for _, option := range c.options {
+				codes, err := c.parse([]string{option.Value})
+				if err != nil || len(codes) != 1 || codes[0] != option.Value {
+					t.Errorf("Expected %s '%s' to be accepted, got %v and %v", c.name, option.Value, codes, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package modes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/grpcapi"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"go.uber.org/zap"
+)
+
+// grpcService implements the gRPC facade with the environment of the call,
+// on top of the same client and download pipeline as the MCP tools.
+type grpcService struct {
+	env *Env
+}
+
+// grpcHandler serves the gRPC facade, loading the environment of every call
+// from its headers like MCP sessions do.
+func grpcHandler(l *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		env, err := LoadEnv(r)
+		if err != nil {
+			l.Error("Failed to load environment", zap.Error(err))
+		}
+		if env == nil {
+			env = &Env{}
+		}
+
+		grpcapi.Handler(&grpcService{env: env}).ServeHTTP(w, r)
+	})
+}
+
+// grpcCodes maps the error codes of tool results to gRPC status codes.
+var grpcCodes = map[string]int{
+	codeInvalidHash:      grpcapi.CodeInvalidArgument,
+	codeInvalidArgument:  grpcapi.CodeInvalidArgument,
+	codeQuotaExceeded:    grpcapi.CodeResourceExhausted,
+	codeUpstreamDown:     grpcapi.CodeUnavailable,
+	codeNotConfigured:    grpcapi.CodeFailedPrecondition,
+	codeForbidden:        grpcapi.CodePermissionDenied,
+	codeFileTooLarge:     grpcapi.CodeFailedPrecondition,
+	codeFormatNotAllowed: grpcapi.CodeFailedPrecondition,
+	codeInternal:         grpcapi.CodeInternal,
+}
+
+// grpcError converts err into a gRPC status, keeping the message of the tool
+// error code.
+func grpcError(err error) error {
+	code := errorCode(err)
+	return grpcapi.Errorf(grpcCodes[code], "%s: %s", code, err)
+}
+
+func grpcBook(book *anna.Book) *grpcapi.Book {
+	return &grpcapi.Book{
+		Hash:         book.Hash,
+		Title:        book.Title,
+		Authors:      book.Authors,
+		Publisher:    book.Publisher,
+		Language:     book.Language,
+		Format:       book.Format,
+		Size:         book.Size,
+		URL:          book.URL,
+		Bytes:        book.Bytes,
+		FastDownload: book.FastDownload,
+	}
+}
+
+func (s *grpcService) Search(ctx context.Context, req *grpcapi.SearchRequest) (*grpcapi.SearchResponse, error) {
+	l := logger.GetLogger()
+
+	l.Info("gRPC search called", zap.String("searchTerm", req.Query), zap.Int32("limit", req.Limit))
+
+	if err := checkScope(auth.CallerFrom(ctx).Scopes, auth.ScopeSearch); err != nil {
+		return nil, grpcError(err)
+	}
+	if err := validateLimit(int(req.Limit)); err != nil {
+		return nil, grpcError(err)
+	}
+	if err := chargeUsage(ctx, usage.KindSearch); err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &grpcapi.SearchResponse{}
+	err := anna.StreamBooksLimit(req.Query, int(req.Limit), func(book *anna.Book) bool {
+		resp.Books = append(resp.Books, grpcBook(book))
+		return ctx.Err() == nil && (req.Limit == 0 || len(resp.Books) < int(req.Limit))
+	})
+	if err != nil {
+		l.Error("gRPC search failed", zap.String("searchTerm", req.Query), zap.Error(err))
+		return nil, grpcError(err)
+	}
+	metrics.Searches.Inc()
+
+	return resp, nil
+}
+
+func (s *grpcService) GetMetadata(ctx context.Context, req *grpcapi.GetMetadataRequest) (*grpcapi.GetMetadataResponse, error) {
+	l := logger.GetLogger()
+
+	l.Info("gRPC get metadata called", zap.String("bookHash", req.Hash))
+
+	if err := checkScope(auth.CallerFrom(ctx).Scopes, auth.ScopeSearch); err != nil {
+		return nil, grpcError(err)
+	}
+	hash, err := validateHash(req.Hash)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	metadata, err := fetchMetadata(ctx, hash, false, -1)
+	if err != nil {
+		l.Error("gRPC get metadata failed", zap.String("bookHash", hash), zap.Error(err))
+		return nil, grpcError(err)
+	}
+
+	return &grpcapi.GetMetadataResponse{
+		Book:              grpcBook(&metadata.Book),
+		ISBNs:             metadata.ISBNs,
+		Description:       metadata.Description,
+		AlternativeTitles: metadata.AlternativeTitles,
+	}, nil
+}
+
+// Download runs the download tool, so rate limits, the audit log, and
+// notifications apply to gRPC calls as well.
+func (s *grpcService) Download(ctx context.Context, req *grpcapi.DownloadRequest) (*grpcapi.DownloadResponse, error) {
+	caller := auth.CallerFrom(ctx)
+	if err := checkScope(caller.Scopes, auth.ScopeDownload); err != nil {
+		return nil, grpcError(err)
+	}
+	if s.env.ReadOnly {
+		return nil, grpcapi.Errorf(grpcapi.CodePermissionDenied, "%s", errReadOnly)
+	}
+
+	_, out, err := NewDownloadToolHandler(s.env)(ctx, nil, DownloadParams{
+		BookHash: req.Hash,
+		Title:    req.Title,
+		Format:   req.Format,
+		Save:     req.Save,
+		Server:   req.Server,
+	})
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &grpcapi.DownloadResponse{}
+	result, _ := out.(map[string]interface{})
+	if path, ok := result["path"].(string); ok {
+		resp.Path = path
+	}
+	if info, ok := result["download"].(*anna.DownloadInfo); ok {
+		resp.DownloadURL = info.URL
+		if info.ExpiresAt != nil {
+			resp.ExpiresAt = info.ExpiresAt.Unix()
+		}
+	}
+	if resp.Path == "" && resp.DownloadURL == "" {
+		return nil, grpcError(errors.New("the download returned neither a link nor a file"))
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,19 @@
+package modes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/grpcapi"
+)
+
+func TestGRPCReadOnly(t *testing.T) {
+	service := &grpcService{env: &Env{ReadOnly: true, DownloadPath: t.TempDir()}}
+
+	_, err := service.Download(context.Background(), &grpcapi.DownloadRequest{Hash: "d6e1dc51a50726f00ec438af21952a45", Title: "Dune", Format: "epub"})
+	var status *grpcapi.Status
+	if !errors.As(err, &status) || status.Code != grpcapi.CodePermissionDenied {
+		t.Errorf("Expected downloads to be denied in read-only mode, got %v", err)
+	}
+}
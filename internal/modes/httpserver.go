@@ -2,29 +2,52 @@ package modes
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/grpcapi"
 	"github.com/iosifache/annas-mcp/internal/logger"
 	"github.com/iosifache/annas-mcp/internal/version"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 // HTTPServerConfig holds configuration for the HTTP MCP server
 type HTTPServerConfig struct {
 	Host          string
 	Port          int
-	TransportType string // "sse" or "streamable"
+	TransportType string // "sse", "streamable", or "websocket"
+	ReadOnly      bool
+	APIKey        string // Required from clients when set
+	GRPC          bool   // Serve the gRPC facade on the same port
+	Stateless     bool   // Keep no sessions, for serverless platforms
+	PortFallback  string // "next" or "random" to fall back from a busy port
+	// ShutdownGrace is how long the server drains on SIGTERM or SIGINT, and
+	// QueueDir where it keeps the queued downloads it could not start.
+	ShutdownGrace time.Duration
+	QueueDir      string
+	// Auth authenticates clients. Without one, clients authenticate with
+	// APIKey or a token of the token store.
+	Auth auth.Provider
 }
 
-// StartHTTPServer starts the MCP server with HTTP transport (SSE or Streamable)
+// StartHTTPServer starts the MCP server with HTTP transport (SSE, Streamable, or WebSocket)
 func StartHTTPServer(config HTTPServerConfig) error {
 	l := logger.GetLogger()
 	defer l.Sync()
 
+	provider := config.Auth
+	if provider == nil {
+		provider = auth.NewKeyProvider(config.APIKey, tokenStore.Load)
+	}
+
 	serverVersion := version.GetVersion()
 	l.Info("Starting MCP HTTP server",
 		zap.String("name", "annas-mcp"),
@@ -32,6 +55,7 @@ func StartHTTPServer(config HTTPServerConfig) error {
 		zap.String("host", config.Host),
 		zap.Int("port", config.Port),
 		zap.String("transport", config.TransportType),
+		zap.Bool("stateless", config.Stateless),
 	)
 
 	// Server factory used by both transports
@@ -42,13 +66,36 @@ func StartHTTPServer(config HTTPServerConfig) error {
 		}
 		if env == nil {
 			env = &Env{} // Empty env to avoid panic
+		} else if !env.ReadOnly {
+			if err := checkDownloadPath(env); err != nil {
+				// Downloads re-check the path and report the error to the client
+				l.Error("Invalid download path for session", zap.String("path", env.DownloadPath), zap.Error(err))
+			}
+		}
+		caller := auth.CallerFrom(r.Context())
+		// Calls layer the opening request on the configuration in effect, so
+		// reloads reach running sessions
+		server := createMCPServer(func() *Env {
+			if current, err := baseConfig(); err == nil {
+				return current.WithRequest(r)
+			}
+			return env
+		}, requestCaller(provider, r))
+		if !config.Stateless {
+			trackSessions(server, caller)
 		}
-		return createMCPServer(env)
+		return server
 	}
 
 	// Create handlers for both transports
 	sseHandler := mcp.NewSSEHandler(serverFactory, nil)
-	streamableHandler := mcp.NewStreamableHTTPHandler(serverFactory, nil)
+	// Stateless requests may each reach another instance, so they get a
+	// temporary session and a plain JSON response instead of a stream
+	streamableHandler := mcp.NewStreamableHTTPHandler(serverFactory, &mcp.StreamableHTTPOptions{
+		Stateless:    config.Stateless,
+		JSONResponse: config.Stateless,
+	})
+	wsHandler := websocketHandler(serverFactory, l)
 
 	// Determine the primary handler based on transport type (for /mcp endpoint)
 	var primaryHandler http.Handler
@@ -57,18 +104,34 @@ func StartHTTPServer(config HTTPServerConfig) error {
 		primaryHandler = sseHandler
 	case "streamable":
 		primaryHandler = streamableHandler
+	case "websocket":
+		primaryHandler = wsHandler
 	default:
-		return fmt.Errorf("invalid transport type: %s (must be 'sse' or 'streamable')", config.TransportType)
+		return fmt.Errorf("invalid transport type: %s (must be 'sse', 'streamable', or 'websocket')", config.TransportType)
 	}
 
 	// Set up HTTP server with CORS and API key authentication
 	mux := http.NewServeMux()
 
 	// Mount the primary handler at /mcp (for backward compatibility and flag respect)
-	mux.Handle("/mcp", corsMiddleware(apiKeyMiddleware(recoveryMiddleware(primaryHandler, l), l)))
+	mux.Handle("/mcp", corsMiddleware(authMiddleware(recoveryMiddleware(primaryHandler, l), provider, l)))
+
+	// SSE, WebSocket, and event streams are long-lived connections to one
+	// instance, which stateless deployments do not offer
+	if !config.Stateless {
+		// Mount SSE handler explicitly at /sse (always available as fallback)
+		mux.Handle("/sse", corsMiddleware(authMiddleware(recoveryMiddleware(sseHandler, l), provider, l)))
+
+		// Mount WebSocket handler explicitly at /ws (always available for gateways)
+		mux.Handle("/ws", authMiddleware(recoveryMiddleware(wsHandler, l), provider, l))
 
-	// Mount SSE handler explicitly at /sse (always available as fallback)
-	mux.Handle("/sse", corsMiddleware(apiKeyMiddleware(recoveryMiddleware(sseHandler, l), l)))
+		// Add a stream of download events for dashboards
+		mux.Handle("/events", corsMiddleware(authMiddleware(recoveryMiddleware(eventsHandler(l), l), provider, l)))
+
+		// Add listing and force-closing of the open MCP sessions
+		mux.Handle("/admin/sessions", corsMiddleware(authMiddleware(recoveryMiddleware(sessionsHandler(l), l), provider, l)))
+		mux.Handle("/admin/sessions/{id}", corsMiddleware(authMiddleware(recoveryMiddleware(sessionsHandler(l), l), provider, l)))
+	}
 
 	// Add .well-known/mcp-config endpoint for Smithery
 	mux.HandleFunc("/.well-known/mcp-config", func(w http.ResponseWriter, r *http.Request) {
@@ -82,32 +145,8 @@ func StartHTTPServer(config HTTPServerConfig) error {
 			return
 		}
 
-		configSchema := map[string]interface{}{
-			"$schema":             "http://json-schema.org/draft-07/schema#",
-			"$id":                 "/.well-known/mcp-config",
-			"title":               "Anna's Archive MCP Configuration",
-			"description":         "Configuration for connecting to Anna's Archive MCP server",
-			"x-query-style":       "dot+bracket",
-			"type":                "object",
-			"required":            []string{"secretKey"},
-			"additionalProperties": false,
-			"properties": map[string]interface{}{
-				"secretKey": map[string]interface{}{
-					"type":        "string",
-					"title":       "Anna's Archive API Key",
-					"description": "Your Anna's Archive API key for accessing the JSON API. Get one at https://annas-archive.org/faq#api",
-				},
-				"downloadPath": map[string]interface{}{
-					"type":        "string",
-					"title":       "Download Path",
-					"description": "Path where downloaded documents will be stored",
-					"default":     "/tmp/downloads",
-				},
-			},
-		}
-
 		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(configSchema); err != nil {
+		if err := json.NewEncoder(w).Encode(sessionConfigSchema()); err != nil {
 			l.Error("Failed to encode config schema", zap.Error(err))
 		}
 	})
@@ -125,36 +164,22 @@ func StartHTTPServer(config HTTPServerConfig) error {
 			return
 		}
 
-		serverCard := map[string]interface{}{
-			"$schema":         "https://static.modelcontextprotocol.io/schemas/mcp-server-card/v1.json",
-			"version":         "1.0",
-			"protocolVersion": "2024-11-05",
-			"serverInfo": map[string]interface{}{
-				"name":    "annas-mcp",
-				"title":   "Anna's Archive MCP Server",
-				"version": version.GetVersion(),
-			},
-			"description": "Search and download documents from Anna's Archive",
-			"transport": map[string]interface{}{
-				"type":     config.TransportType,
-				"endpoint": "/mcp",
-			},
-			"capabilities": map[string]interface{}{
-				"tools": []map[string]interface{}{
-					{
-						"name":        "search",
-						"description": "Search books on Anna's Archive",
-					},
-					{
-						"name":        "download",
-						"description": "Download a book by its MD5 hash",
-					},
-				},
-			},
+		env, err := baseConfig()
+		if err != nil {
+			l.Error("Failed to load environment", zap.Error(err))
+			env = &Env{}
+		}
+		env.APIKey, env.ReadOnly, env.Stateless = config.APIKey, config.ReadOnly, config.Stateless
+
+		card, err := serverCard(r.Context(), config, env)
+		if err != nil {
+			l.Error("Failed to build server card", zap.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
 		}
 
 		w.WriteHeader(http.StatusOK)
-		if err := json.NewEncoder(w).Encode(serverCard); err != nil {
+		if err := json.NewEncoder(w).Encode(card); err != nil {
 			l.Error("Failed to encode server card", zap.Error(err))
 		}
 	}
@@ -163,24 +188,84 @@ func StartHTTPServer(config HTTPServerConfig) error {
 	mux.HandleFunc("/.well-known/mcp-server-card.json", serverCardHandler)
 	mux.HandleFunc("/.well-known/mcp/server-card.json", serverCardHandler)
 
-	// Add a health check endpoint
+	// Add the browser login through the configured OAuth client
+	mux.Handle("/oauth/login", recoveryMiddleware(oauthLoginHandler(l), l))
+	mux.Handle("/oauth/callback", recoveryMiddleware(oauthCallbackHandler(l), l))
+
+	// Add OAuth protected resource metadata for MCP authorization discovery,
+	// also at the path suffixed with the MCP endpoint (RFC 9728)
+	mux.HandleFunc(protectedResourcePath, protectedResourceHandler(l))
+	mux.HandleFunc(protectedResourcePath+"/mcp", protectedResourceHandler(l))
+
+	// Add the usage of the caller's token
+	mux.Handle("/usage", corsMiddleware(authMiddleware(recoveryMiddleware(usageHandler(l), l), provider, l)))
+
+	// Add validation of candidate configurations for hosting UIs
+	mux.Handle("/config/validate", corsMiddleware(authMiddleware(recoveryMiddleware(validateHandler(l), l), provider, l)))
+
+	// Add a Range-capable proxy for reading books without saving them
+	mux.Handle("/stream/{md5}", corsMiddleware(authMiddleware(recoveryMiddleware(streamHandler(l), l), provider, l)))
+
+	// Add RSS feeds of the new results of scheduled searches
+	mux.Handle("/feeds/{feed}", recoveryMiddleware(feedHandler(provider, l), l))
+
+	// Add a Newznab-compatible indexer API for Readarr/LazyLibrarian
+	mux.Handle("/api", recoveryMiddleware(indexerHandler(provider, l), l))
+
+	// Add a health check endpoint. It answers 200 even when upstream mirrors
+	// are failing, since restarting the server does not help, and 503 while
+	// the server drains before stopping.
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte("OK"))
+		mirrors := anna.MirrorStatuses()
+		status := healthStatus(mirrors)
+		code := http.StatusOK
+		if draining.Load() {
+			status = "draining"
+			code = http.StatusServiceUnavailable
+		}
+
+		health := map[string]interface{}{
+			"status":  status,
+			"mirrors": mirrors,
+		}
+		// Deep checks add the background probe statistics
+		if r.URL.Query().Get("deep") == "true" {
+			health["probes"] = anna.HealthStatuses()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(code)
+		if err := json.NewEncoder(w).Encode(health); err != nil {
+			l.Error("Failed to encode health status", zap.Error(err))
+		}
 	})
 
-	addr := fmt.Sprintf("%s:%d", config.Host, config.Port)
+	// Add the gRPC facade. gRPC needs HTTP/2, which plain-text clients
+	// negotiate with prior knowledge (h2c).
+	var handler http.Handler = mux
+	if config.GRPC {
+		mux.Handle(grpcapi.ServicePath, authMiddleware(recoveryMiddleware(grpcHandler(l), l), provider, l))
+		handler = h2c.NewHandler(mux, &http2.Server{})
+	}
+
+	listener, err := listen(config.Host, config.Port, config.PortFallback)
+	if err != nil {
+		l.Error("MCP HTTP server failed", zap.Error(err))
+		return fmt.Errorf("failed to listen on %s:%d: %w", config.Host, config.Port, err)
+	}
+	addr := listener.Addr().String()
 	l.Info("MCP HTTP server listening",
 		zap.String("address", addr),
 		zap.String("endpoint", "/mcp"),
 	)
+	announceListener(listener)
 
 	server := &http.Server{
 		Addr:    addr,
-		Handler: mux,
+		Handler: handler,
 	}
 
-	if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+	if err := serve(server, listener, config.ShutdownGrace, config.QueueDir); err != nil && err != http.ErrServerClosed {
 		l.Fatal("MCP HTTP server failed", zap.Error(err))
 		return err
 	}
@@ -205,13 +290,31 @@ func recoveryMiddleware(next http.Handler, l *zap.Logger) http.Handler {
 	})
 }
 
+// headerKey extracts the Bearer token or X-API-Key from the request headers.
+func headerKey(r *http.Request) string {
+	authHeader := r.Header.Get("Authorization")
+	apiKeyHeader := r.Header.Get("X-API-Key")
+
+	if authHeader != "" {
+		// Check for Bearer token format
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) == 2 && parts[0] == "Bearer" {
+			return parts[1]
+		}
+	} else if apiKeyHeader != "" {
+		return apiKeyHeader
+	}
+
+	return ""
+}
+
 // corsMiddleware adds CORS headers to allow cross-origin requests
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Authorization, X-API-Key, Mcp-Session-Id")
-		w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, Authorization, X-API-Key, X-Annas-Secret-Key, X-Annas-Download-Path, Mcp-Session-Id, Range")
+		w.Header().Set("Access-Control-Expose-Headers", "Mcp-Session-Id, Content-Range, Content-Length, Accept-Ranges, WWW-Authenticate")
 		w.Header().Set("Access-Control-Max-Age", "3600")
 
 		if r.Method == "OPTIONS" {
@@ -223,47 +326,46 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
-// apiKeyMiddleware verifies API keys from Smithery or other clients
-func apiKeyMiddleware(next http.Handler, l *zap.Logger) http.Handler {
+// authMiddleware verifies the credentials of requests with provider and
+// attaches the caller they identify to the request context
+func authMiddleware(next http.Handler, provider auth.Provider, l *zap.Logger) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip API key check if not configured (for local development)
-		smitheryAPIKey := os.Getenv("SMITHERY_API_KEY")
-		if smitheryAPIKey == "" {
-			l.Debug("API key authentication not configured, allowing all requests")
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Extract Bearer token or X-API-Key from headers
-		authHeader := r.Header.Get("Authorization")
-		apiKeyHeader := r.Header.Get("X-API-Key")
-
-		var providedKey string
-
-		if authHeader != "" {
-			// Check for Bearer token format
-			parts := strings.SplitN(authHeader, " ", 2)
-			if len(parts) == 2 && parts[0] == "Bearer" {
-				providedKey = parts[1]
+		caller, err := provider.Authenticate(r.Context(), requestCredentials(r, false))
+		if err != nil {
+			challenge(w, r, provider)
+			if errors.Is(err, auth.ErrMissingCredentials) {
+				l.Warn("Missing credentials in Authorization or X-API-Key header", zap.String("provider", provider.Name()))
+				http.Error(w, "Unauthorized: Missing API key", http.StatusUnauthorized)
+				return
 			}
-		} else if apiKeyHeader != "" {
-			providedKey = apiKeyHeader
-		}
-
-		if providedKey == "" {
-			l.Warn("Missing API key in Authorization or X-API-Key header")
-			http.Error(w, "Unauthorized: Missing API key", http.StatusUnauthorized)
-			return
-		}
-
-		// Verify the API key matches
-		if providedKey != smitheryAPIKey {
-			l.Warn("Invalid API key provided")
+			l.Warn("Invalid credentials provided", zap.String("provider", provider.Name()), zap.Error(err))
 			http.Error(w, "Unauthorized: Invalid API key", http.StatusUnauthorized)
 			return
 		}
 
-		l.Debug("API key verified successfully")
-		next.ServeHTTP(w, r)
+		l.Debug("API key verified successfully", zap.String("caller", caller.Name), zap.Strings("scopes", caller.Scopes))
+		next.ServeHTTP(w, r.WithContext(auth.WithCaller(r.Context(), caller)))
 	})
 }
+
+// healthStatus summarizes the circuit breakers of the mirrors. A
+// configuration without mirrors is reported on its own rather than as every
+// mirror being down.
+func healthStatus(mirrors []anna.MirrorStatus) string {
+	if len(mirrors) == 0 {
+		return "no_mirrors"
+	}
+	open := 0
+	for _, mirror := range mirrors {
+		if mirror.State == anna.CircuitOpen {
+			open++
+		}
+	}
+	switch {
+	case open == len(mirrors):
+		return "unavailable"
+	case open > 0:
+		return "degraded"
+	}
+	return "ok"
+}
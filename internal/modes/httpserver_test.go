@@ -0,0 +1,30 @@
+package modes
+
+import (
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+)
+
+func TestHealthStatus(t *testing.T) {
+	closed := anna.MirrorStatus{Mirror: "https://a.example", State: anna.CircuitClosed}
+	open := anna.MirrorStatus{Mirror: "https://b.example", State: anna.CircuitOpen}
+
+	cases := []struct {
+		name    string
+		mirrors []anna.MirrorStatus
+		want    string
+	}{
+		{"NoMirrors", nil, "no_mirrors"},
+		{"AllClosed", []anna.MirrorStatus{closed}, "ok"},
+		{"SomeOpen", []anna.MirrorStatus{closed, open}, "degraded"},
+		{"AllOpen", []anna.MirrorStatus{open}, "unavailable"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := healthStatus(c.mirrors); got != c.want {
+				t.Errorf("Expected '%s', got '%s'", c.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package modes
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"github.com/iosifache/annas-mcp/internal/version"
+	"go.uber.org/zap"
+)
+
+// Newznab book categories used by Readarr and LazyLibrarian.
+const (
+	newznabCategoryBooks     = 7000
+	newznabCategoryMagazines = 7010
+	newznabCategoryEbooks    = 7020
+	newznabCategoryComics    = 7030
+)
+
+type newznabCaps struct {
+	XMLName    xml.Name `xml:"caps"`
+	Server     newznabServer
+	Limits     newznabLimits
+	Searching  newznabSearching
+	Categories newznabCategories
+}
+
+type newznabServer struct {
+	XMLName xml.Name `xml:"server"`
+	Title   string   `xml:"title,attr"`
+	Version string   `xml:"version,attr"`
+}
+
+type newznabLimits struct {
+	XMLName xml.Name `xml:"limits"`
+	Max     int      `xml:"max,attr"`
+	Default int      `xml:"default,attr"`
+}
+
+type newznabSearching struct {
+	XMLName    xml.Name          `xml:"searching"`
+	Search     newznabSearchType `xml:"search"`
+	BookSearch newznabSearchType `xml:"book-search"`
+}
+
+type newznabSearchType struct {
+	Available       string `xml:"available,attr"`
+	SupportedParams string `xml:"supportedParams,attr"`
+}
+
+type newznabCategories struct {
+	XMLName    xml.Name          `xml:"categories"`
+	Categories []newznabCategory `xml:"category"`
+}
+
+type newznabCategory struct {
+	ID      int                  `xml:"id,attr"`
+	Name    string               `xml:"name,attr"`
+	Subcats []newznabSubcategory `xml:"subcat"`
+}
+
+type newznabSubcategory struct {
+	ID   int    `xml:"id,attr"`
+	Name string `xml:"name,attr"`
+}
+
+type newznabRSS struct {
+	XMLName      xml.Name       `xml:"rss"`
+	Version      string         `xml:"version,attr"`
+	NewznabSpace string         `xml:"xmlns:newznab,attr"`
+	Channel      newznabChannel `xml:"channel"`
+}
+
+type newznabChannel struct {
+	Title       string        `xml:"title"`
+	Description string        `xml:"description"`
+	Items       []newznabItem `xml:"item"`
+}
+
+type newznabItem struct {
+	Title     string           `xml:"title"`
+	GUID      string           `xml:"guid"`
+	Link      string           `xml:"link"`
+	Comments  string           `xml:"comments"`
+	PubDate   string           `xml:"pubDate"`
+	Size      int64            `xml:"size"`
+	Enclosure newznabEnclosure `xml:"enclosure"`
+	Attrs     []newznabAttr    `xml:"newznab:attr"`
+}
+
+type newznabEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+type newznabAttr struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type newznabError struct {
+	XMLName     xml.Name `xml:"error"`
+	Code        int      `xml:"code,attr"`
+	Description string   `xml:"description,attr"`
+}
+
+// indexerHandler serves a Newznab-compatible API so book automation tools can
+// use the server as an indexer. Clients authenticate with the apikey query
+// parameter, which is checked by provider. Grabs require the download scope.
+func indexerHandler(provider auth.Provider, l *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		caller, err := provider.Authenticate(r.Context(), auth.Credentials{Key: query.Get("apikey")})
+		if err != nil {
+			writeNewznabError(w, l, 100, "Incorrect user credentials")
+			return
+		}
+
+		switch query.Get("t") {
+		case "caps":
+			writeXML(w, l, newznabCapabilities())
+		case "search", "book":
+			if !caller.Scopes.Has(auth.ScopeSearch) {
+				writeNewznabError(w, l, 102, "Insufficient privileges")
+				return
+			}
+			term := strings.TrimSpace(strings.Join([]string{query.Get("q"), query.Get("title"), query.Get("author")}, " "))
+			if term == "" {
+				writeNewznabError(w, l, 200, "Missing parameter (q)")
+				return
+			}
+			if err := chargeUsage(auth.WithCaller(r.Context(), caller), usage.KindSearch); err != nil {
+				writeNewznabError(w, l, 500, err.Error())
+				return
+			}
+
+			books, err := anna.FindBook(term)
+			if err != nil {
+				l.Error("Indexer search failed", zap.String("searchTerm", term), zap.Error(err))
+				writeNewznabError(w, l, 900, "Search failed")
+				return
+			}
+			metrics.Searches.Inc()
+
+			writeXML(w, l, newznabResults(r, books))
+		case "get":
+			if !caller.Scopes.Has(auth.ScopeDownload) {
+				writeNewznabError(w, l, 102, "Insufficient privileges")
+				return
+			}
+			env, err := LoadEnv(r)
+			if env != nil && env.ReadOnly {
+				writeNewznabError(w, l, 910, "Downloads are disabled in read-only mode")
+				return
+			}
+			if err != nil {
+				writeNewznabError(w, l, 100, "Secret key is not configured")
+				return
+			}
+
+			hash, err := anna.NormalizeHash(query.Get("id"))
+			if err != nil {
+				writeNewznabError(w, l, 201, err.Error())
+				return
+			}
+
+			ctx := auth.WithCaller(r.Context(), caller)
+			if _, err := takeDownload(ctx, env); err != nil {
+				writeNewznabError(w, l, 500, err.Error())
+				return
+			}
+
+			book := &anna.Book{Hash: hash}
+			info, err := resolveLink(env, book)
+			auditDownload(ctx, env, auditSourceIndexer, book, "", err)
+			if err != nil {
+				l.Error("Indexer download failed", zap.String("bookHash", book.Hash), zap.Error(err))
+				writeNewznabError(w, l, 300, err.Error())
+				return
+			}
+
+			http.Redirect(w, r, info.URL, http.StatusFound)
+		default:
+			writeNewznabError(w, l, 202, "No such function")
+		}
+	}
+}
+
+func newznabCapabilities() newznabCaps {
+	return newznabCaps{
+		Server: newznabServer{Title: "annas-mcp", Version: version.GetVersion()},
+		Limits: newznabLimits{Max: 100, Default: 100},
+		Searching: newznabSearching{
+			Search:     newznabSearchType{Available: "yes", SupportedParams: "q"},
+			BookSearch: newznabSearchType{Available: "yes", SupportedParams: "q,title,author"},
+		},
+		Categories: newznabCategories{Categories: []newznabCategory{{
+			ID:   newznabCategoryBooks,
+			Name: "Books",
+			Subcats: []newznabSubcategory{
+				{ID: newznabCategoryMagazines, Name: "Mags"},
+				{ID: newznabCategoryEbooks, Name: "EBook"},
+				{ID: newznabCategoryComics, Name: "Comics"},
+			},
+		}}},
+	}
+}
+
+func newznabResults(r *http.Request, books []*anna.Book) newznabRSS {
+	base := requestOrigin(r, "") + r.URL.Path
+	pubDate := time.Now().UTC().Format(time.RFC1123Z)
+
+	items := make([]newznabItem, 0, len(books))
+	for _, book := range books {
+		params := url.Values{"t": {"get"}, "id": {book.Hash}}
+		if apiKey := r.URL.Query().Get("apikey"); apiKey != "" {
+			params.Set("apikey", apiKey)
+		}
+		getURL := base + "?" + params.Encode()
+
+		size := book.SizeBytes()
+		items = append(items, newznabItem{
+			Title:    newznabTitle(book),
+			GUID:     book.Hash,
+			Link:     getURL,
+			Comments: book.URL,
+			PubDate:  pubDate,
+			Size:     size,
+			Enclosure: newznabEnclosure{
+				URL:    getURL,
+				Length: size,
+				Type:   "application/octet-stream",
+			},
+			Attrs: []newznabAttr{
+				{Name: "category", Value: fmt.Sprint(newznabCategoryEbooks)},
+				{Name: "size", Value: fmt.Sprint(size)},
+				{Name: "author", Value: book.AuthorLine()},
+				{Name: "booktitle", Value: book.Title},
+				{Name: "publisher", Value: book.Publisher},
+			},
+		})
+	}
+
+	return newznabRSS{
+		Version:      "2.0",
+		NewznabSpace: "http://www.newznab.com/DTD/2010/feeds/attributes/",
+		Channel: newznabChannel{
+			Title:       "annas-mcp",
+			Description: "Anna's Archive search results",
+			Items:       items,
+		},
+	}
+}
+
+// newznabTitle renders a release-style title ("Author - Title [EPUB]") that
+// Readarr's parser can match against its book list.
+func newznabTitle(book *anna.Book) string {
+	title := book.Title
+	if len(book.Authors) > 0 {
+		title = book.AuthorLine() + " - " + title
+	}
+	if book.Format != "" {
+		title += " [" + strings.ToUpper(book.Format) + "]"
+	}
+
+	return title
+}
+
+func writeNewznabError(w http.ResponseWriter, l *zap.Logger, code int, description string) {
+	writeXML(w, l, newznabError{Code: code, Description: description})
+}
+
+func writeXML(w http.ResponseWriter, l *zap.Logger, v any) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		l.Error("Failed to encode indexer response", zap.Error(err))
+	}
+}
@@ -0,0 +1,38 @@
+package modes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+)
+
+func TestNewznabResults(t *testing.T) {
+	key := "reader+key&t=caps"
+	r := httptest.NewRequest(http.MethodGet, "/api?t=search&q=dune&apikey="+url.QueryEscape(key), nil)
+	book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Title: "Dune", Format: "epub"}
+
+	rss := newznabResults(r, []*anna.Book{book})
+	if len(rss.Channel.Items) != 1 {
+		t.Fatalf("Expected 1 item, got %d", len(rss.Channel.Items))
+	}
+	item := rss.Channel.Items[0]
+	if item.Enclosure.URL != item.Link {
+		t.Errorf("Expected the enclosure to point at the link, got %s and %s", item.Enclosure.URL, item.Link)
+	}
+
+	// The grab request carries the key as sent, whatever characters it holds
+	link, err := url.Parse(item.Link)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if link.Path != "/api" {
+		t.Errorf("Expected the path of the indexer, got %s", link.Path)
+	}
+	query := link.Query()
+	if query.Get("t") != "get" || query.Get("id") != book.Hash || query.Get("apikey") != key {
+		t.Errorf("Expected a grab of %s with the key, got %v", book.Hash, query)
+	}
+}
@@ -0,0 +1,30 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/version"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// ServerInfoToolHandler returns the build information of the server, like
+// the version --json command.
+func ServerInfoToolHandler(ctx context.Context, req *mcp.CallToolRequest, params ServerInfoParams) (*mcp.CallToolResult, any, error) {
+	l := logger.GetLogger()
+	l.Info("Get server info command called")
+
+	info := version.GetInfo()
+	text := fmt.Sprintf("annas-mcp %s (%s, %s)", info.Version, info.GoVersion, info.Platform)
+	if info.Commit != "" {
+		text += fmt.Sprintf("\nCommit: %s", info.Commit)
+	}
+	if info.BuildDate != "" {
+		text += fmt.Sprintf("\nBuilt: %s", info.BuildDate)
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text}},
+	}, info, nil
+}
@@ -0,0 +1,120 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/kindle"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/notify"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// sendToKindle downloads the book into the download path, converts it if a
+// Kindle format is configured, and emails it to the configured Kindle address.
+// It returns the path of the saved book; a conversion is only kept until it
+// is sent, so the library, its quota, and GC only see saved books.
+func sendToKindle(env *Env, book *anna.Book) (string, error) {
+	if env.KindleEmail == "" {
+		return "", withCode(codeNotConfigured, "Kindle delivery is not configured. Please set ANNAS_KINDLE_EMAIL and the ANNAS_SMTP_* variables")
+	}
+
+	path, err := saveBook(env, book)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "annas-mcp-kindle-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	attachment, err := kindle.Convert(path, env.KindleFormat, dir)
+	if err != nil {
+		return "", err
+	}
+
+	smtpConfig := kindle.SMTPConfig{
+		Host:     env.SMTPHost,
+		Port:     env.SMTPPort,
+		Username: env.SMTPUsername,
+		Password: env.SMTPPassword,
+		From:     env.SMTPFrom,
+	}
+	if err := kindle.Send(smtpConfig, env.KindleEmail, attachment); err != nil {
+		return "", fmt.Errorf("failed to email book to Kindle: %w", err)
+	}
+
+	return path, nil
+}
+
+// NewSendToKindleToolHandler creates a handler for the send_to_kindle tool that uses the provided environment.
+func NewSendToKindleToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, DownloadParams) (*mcp.CallToolResult, any, error) {
+	dispatcher := newDispatcher(env)
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, params DownloadParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Send to Kindle command called",
+			zap.String("bookHash", params.BookHash),
+			zap.String("title", params.Title),
+			zap.String("format", params.Format),
+		)
+
+		hash, err := validateHash(params.BookHash)
+		if err != nil {
+			l.Error("Send to Kindle command failed", zap.Error(err))
+			return nil, nil, err
+		}
+		params.BookHash = hash
+
+		if len(env.Keys()) == 0 {
+			err := errSecretKeyMissing
+			l.Error("Send to Kindle command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		book := &anna.Book{
+			Hash:   params.BookHash,
+			Title:  params.Title,
+			Format: params.Format,
+		}
+
+		left, err := takeDownload(ctx, env)
+		if err != nil {
+			l.Error("Send to Kindle command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		dispatcher.Publish(downloadEvent(notify.EventDownloadQueued, book))
+
+		path, err := sendToKindle(env, book)
+		auditDownload(ctx, env, auditSourceKindle, book, path, err)
+		if err != nil {
+			l.Error("Send to Kindle command failed",
+				zap.String("bookHash", params.BookHash),
+				zap.Error(err),
+			)
+			event := downloadEvent(notify.EventDownloadFailed, book)
+			event.Error = err.Error()
+			dispatcher.Publish(event)
+			return nil, nil, err
+		}
+
+		l.Info("Send to Kindle command completed successfully",
+			zap.String("bookHash", params.BookHash),
+			zap.String("path", path),
+		)
+
+		dispatcher.Publish(downloadEvent(notify.EventDownloadCompleted, book))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{
+				Text: withAllowance(fmt.Sprintf("Sent %s to %s", params.Title, env.KindleEmail), left),
+			}},
+		}, nil, nil
+	}
+}
@@ -0,0 +1,56 @@
+package modes
+
+import (
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// collectLibrary runs a library garbage collection of the download path of
+// env, logging what was removed.
+func collectLibrary(env *Env, maxAge time.Duration) (*library.Collected, error) {
+	l := logger.GetLogger()
+
+	collected, err := library.GC(env.DownloadPath, maxAge, time.Now())
+	if err != nil {
+		l.Warn("Library garbage collection failed", zap.String("path", env.DownloadPath), zap.Error(err))
+	}
+	if len(collected.Files) > 0 || len(collected.Entries) > 0 {
+		l.Info("Collected library garbage",
+			zap.String("path", env.DownloadPath),
+			zap.Strings("files", collected.Files),
+			zap.Int64("bytes", collected.Bytes),
+			zap.Int("entriesCount", len(collected.Entries)),
+		)
+	}
+
+	return collected, err
+}
+
+// startLibraryGC collects the library garbage of the active download path
+// at startup, where a crash may have left some, and then every
+// ANNAS_LIBRARY_GC_INTERVAL_HOURS for the lifetime of the HTTP server, unless
+// disabled.
+func startLibraryGC(env *Env) {
+	if env.LibraryGCIntervalHours == 0 {
+		return
+	}
+
+	interval := time.Duration(env.LibraryGCIntervalHours) * time.Hour
+	logger.GetLogger().Info("Starting library garbage collection", zap.Duration("interval", interval))
+	go func() {
+		collectLibrary(env, library.DefaultGCAge)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			current := env
+			if active := activeConfig.Load(); active != nil {
+				current = active
+			}
+			collectLibrary(current, library.DefaultGCAge)
+		}
+	}()
+}
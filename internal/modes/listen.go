@@ -0,0 +1,84 @@
+package modes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Fallbacks for a busy port.
+const (
+	portFallbackNext   = "next"
+	portFallbackRandom = "random"
+)
+
+// maxPortAttempts is how many consecutive ports the next fallback tries.
+const maxPortAttempts = 10
+
+// listen binds host:port. When the port is in use, the next fallback tries
+// the following ports and the random fallback lets the system pick a free
+// one.
+func listen(host string, port int, fallback string) (net.Listener, error) {
+	l := logger.GetLogger()
+
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+	listener, err := net.Listen("tcp", addr)
+	if err == nil || fallback == "" || !errors.Is(err, syscall.EADDRINUSE) {
+		return listener, err
+	}
+	l.Warn("Port is in use, trying a fallback", zap.String("address", addr), zap.String("fallback", fallback))
+
+	switch fallback {
+	case portFallbackNext:
+		for next := port + 1; next < port+maxPortAttempts && next <= 65535; next++ {
+			listener, nextErr := net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(next)))
+			if nextErr == nil {
+				return listener, nil
+			}
+			if !errors.Is(nextErr, syscall.EADDRINUSE) {
+				return nil, nextErr
+			}
+		}
+		return nil, fmt.Errorf("ports %d to %d are in use: %w", port, port+maxPortAttempts-1, err)
+	case portFallbackRandom:
+		return net.Listen("tcp", net.JoinHostPort(host, "0"))
+	}
+
+	return nil, err
+}
+
+// listeningEvent is the line printed once the server is bound, for launchers
+// that start the server on a port they do not know in advance.
+type listeningEvent struct {
+	Event   string `json:"event"`
+	Address string `json:"address"`
+	Port    int    `json:"port"`
+	URL     string `json:"url"`
+}
+
+// announceListener prints the bound address of listener as a JSON line on
+// stdout, which carries no logs in HTTP mode.
+func announceListener(listener net.Listener) {
+	addr := listener.Addr().(*net.TCPAddr)
+
+	host := addr.IP.String()
+	if addr.IP.IsUnspecified() {
+		host = "localhost"
+	}
+	event := listeningEvent{
+		Event:   "listening",
+		Address: addr.String(),
+		Port:    addr.Port,
+		URL:     fmt.Sprintf("http://%s/mcp", net.JoinHostPort(host, strconv.Itoa(addr.Port))),
+	}
+
+	// Failing to announce must not stop the server
+	_ = json.NewEncoder(os.Stdout).Encode(event)
+}
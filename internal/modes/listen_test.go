@@ -0,0 +1,40 @@
+package modes
+
+import (
+	"net"
+	"testing"
+)
+
+func TestListenFallback(t *testing.T) {
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer busy.Close()
+	port := busy.Addr().(*net.TCPAddr).Port
+
+	t.Run("None", func(t *testing.T) {
+		if listener, err := listen("127.0.0.1", port, ""); err == nil {
+			listener.Close()
+			t.Error("Expected error for a busy port, got nil")
+		}
+	})
+
+	for _, fallback := range []string{portFallbackNext, portFallbackRandom} {
+		t.Run(fallback, func(t *testing.T) {
+			listener, err := listen("127.0.0.1", port, fallback)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer listener.Close()
+
+			got := listener.Addr().(*net.TCPAddr).Port
+			if got == port {
+				t.Errorf("Expected a port other than %d", port)
+			}
+			if fallback == portFallbackNext && (got <= port || got >= port+maxPortAttempts) {
+				t.Errorf("Expected one of the ports after %d, got %d", port, got)
+			}
+		})
+	}
+}
@@ -3,9 +3,15 @@ package modes
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/config"
 	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"github.com/iosifache/annas-mcp/internal/notify"
+	"github.com/iosifache/annas-mcp/internal/usage"
 	"github.com/iosifache/annas-mcp/internal/version"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"go.uber.org/zap"
@@ -14,13 +20,76 @@ import (
 // SearchToolHandler performs a search on Anna's Archive.
 // It does not require any specific environment configuration.
 func SearchToolHandler(ctx context.Context, req *mcp.CallToolRequest, params SearchParams) (*mcp.CallToolResult, any, error) {
+	return NewSearchToolHandler(nil)(ctx, req, params)
+}
+
+// NewSearchToolHandler creates a search handler that, when env enables it,
+// prefetches the download links of the top results in the background.
+func NewSearchToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, SearchParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params SearchParams) (*mcp.CallToolResult, any, error) {
+		return searchBooks(ctx, req, params, env)
+	}
+}
+
+func searchBooks(ctx context.Context, req *mcp.CallToolRequest, params SearchParams, env *Env) (*mcp.CallToolResult, any, error) {
 	l := logger.GetLogger()
 
 	l.Info("Search command called",
 		zap.String("searchTerm", params.SearchTerm),
+		zap.Bool("transliterate", params.Transliterate),
+		zap.Int("limit", params.Limit),
+		zap.Bool("rank", params.Rank),
+		zap.Strings("languages", params.Languages),
+		zap.Strings("formats", params.Formats),
 	)
 
-	books, err := anna.FindBook(params.SearchTerm)
+	if err := validateLimit(params.Limit); err != nil {
+		l.Error("Search command failed", zap.Error(err))
+		return nil, nil, err
+	}
+	languages, err := parseLanguages(params.Languages)
+	if err != nil {
+		l.Error("Search command failed", zap.Error(err))
+		return nil, nil, err
+	}
+	formats, err := parseFormats(params.Formats)
+	if err != nil {
+		l.Error("Search command failed", zap.Error(err))
+		return nil, nil, err
+	}
+	filters := anna.Filters{Languages: languages, Formats: formats}
+	if err := chargeUsage(ctx, usage.KindSearch); err != nil {
+		l.Error("Search command failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	// Clients that pass a progress token get every result as soon as it is
+	// parsed, before the complete list is returned
+	token := req.Params.GetProgressToken()
+	books := make([]*anna.Book, 0)
+	seen := make(map[string]bool)
+	collect := func(book *anna.Book) bool {
+		if seen[book.Hash] {
+			return ctx.Err() == nil
+		}
+		seen[book.Hash] = true
+		books = append(books, book)
+		if token != nil {
+			// Progress is best effort, the final result carries every book
+			_ = req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+				ProgressToken: token,
+				Progress:      float64(len(books)),
+				Message:       fmt.Sprintf("%s (%s, %s) [%s]", book.Title, book.Format, book.Size, book.Hash),
+			})
+		}
+		return ctx.Err() == nil && (params.Limit == 0 || len(books) < params.Limit)
+	}
+	class, err := anna.StreamBooksFiltered(params.SearchTerm, filters, params.Limit, collect)
+	if err == nil && params.Transliterate && (params.Limit == 0 || len(books) < params.Limit) {
+		if variant := anna.Transliterate(params.SearchTerm); variant != params.SearchTerm {
+			_, err = anna.StreamBooksFiltered(variant, filters, params.Limit, collect)
+		}
+	}
 	if err != nil {
 		l.Error("Search command failed",
 			zap.String("searchTerm", params.SearchTerm),
@@ -29,6 +98,13 @@ func SearchToolHandler(ctx context.Context, req *mcp.CallToolRequest, params Sea
 		return nil, nil, err
 	}
 
+	metrics.Searches.Inc()
+
+	// Progress notifications keep the upstream order, the final list is ranked
+	if prefs := preferences(env); params.Rank || (env != nil && env.RankResults) || !prefs.IsZero() {
+		anna.RankBooks(params.SearchTerm, books, prefs)
+	}
+
 	bookList := ""
 	for _, book := range books {
 		bookList += book.String() + "\n\n"
@@ -39,13 +115,59 @@ func SearchToolHandler(ctx context.Context, req *mcp.CallToolRequest, params Sea
 		zap.Int("resultsCount", len(books)),
 	)
 
+	// Relaxed variants keep agents from giving up on a slightly wrong query
+	if len(books) == 0 {
+		l.Info("Search returned no results", zap.String("searchTerm", params.SearchTerm), zap.String("class", class))
+		suggestions := anna.Suggest(params.SearchTerm, filters)
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: noResultsText(params.SearchTerm, class, suggestions)}},
+		}, map[string]interface{}{"books": books, "suggestions": suggestions, "classification": class}, nil
+	}
+
+	if env != nil {
+		prefetchDownloads(ctx, env, books)
+	}
+
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{&mcp.TextContent{Text: bookList}},
 	}, map[string]interface{}{"books": books}, nil
 }
 
+// preferences returns the preferred formats and languages of env, if any.
+func preferences(env *Env) anna.Preferences {
+	if env == nil {
+		return anna.Preferences{}
+	}
+
+	return anna.Preferences{Formats: env.PreferredFormats, Languages: env.PreferredLangs}
+}
+
+// noResultsText describes an empty search, warning when the page of class
+// did not look like a real empty result, and the relaxed queries that would
+// return results.
+func noResultsText(query, class string, suggestions []anna.Suggestion) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "No books found for %q.", query)
+	if class == anna.PageLayoutChanged {
+		text.WriteString(" Warning: the search page held no recognizable results, so its layout may have changed and this may not be a real empty result.")
+	}
+	if len(suggestions) == 0 {
+		return text.String()
+	}
+
+	text.WriteString(" Did you mean:\n")
+	for _, suggestion := range suggestions {
+		top := suggestion.Books[0]
+		fmt.Fprintf(&text, "- %q, e.g. %s by %s [%s]\n", suggestion.Query, top.Title, top.AuthorLine(), top.Hash)
+	}
+
+	return text.String()
+}
+
 // NewDownloadToolHandler creates a handler for the download tool that uses the provided environment.
 func NewDownloadToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, DownloadParams) (*mcp.CallToolResult, any, error) {
+	dispatcher := newDispatcher(env)
+
 	return func(ctx context.Context, req *mcp.CallToolRequest, params DownloadParams) (*mcp.CallToolResult, any, error) {
 		l := logger.GetLogger()
 
@@ -53,13 +175,20 @@ func NewDownloadToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest
 			zap.String("bookHash", params.BookHash),
 			zap.String("title", params.Title),
 			zap.String("format", params.Format),
+			zap.String("server", params.Server),
 		)
 
-		// Use the injected environment instead of global GetEnv()
-		secretKey := env.SecretKey
+		hash, err := validateHash(params.BookHash)
+		if err != nil {
+			l.Error("Download command failed", zap.Error(err))
+			return nil, nil, err
+		}
+		params.BookHash = hash
+		env := withServer(env, params.Server)
 
-		if secretKey == "" {
-			err := fmt.Errorf("secret key is not configured. Please set ANNAS_SECRET_KEY, secretKey, or pass it via query parameters")
+		// Use the injected environment instead of global GetEnv()
+		if len(env.Keys()) == 0 {
+			err := errSecretKeyMissing
 			l.Error("Download command failed", zap.Error(err))
 			return nil, nil, err
 		}
@@ -72,12 +201,55 @@ func NewDownloadToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest
 			Format: format,
 		}
 
-		url, err := book.GetDownloadURL(secretKey)
+		left, err := takeDownload(ctx, env)
+		if err != nil {
+			l.Error("Download command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		dispatcher.Publish(downloadEvent(notify.EventDownloadQueued, book))
+
+		if params.Save {
+			path, err := saveBook(env, book)
+			auditDownload(ctx, env, auditSourceDownload, book, path, err)
+			if err != nil {
+				l.Error("Download command failed",
+					zap.String("bookHash", params.BookHash),
+					zap.Error(err),
+				)
+				event := downloadEvent(notify.EventDownloadFailed, book)
+				event.Error = err.Error()
+				dispatcher.Publish(event)
+				return nil, nil, err
+			}
+
+			l.Info("Download command completed successfully",
+				zap.String("bookHash", params.BookHash),
+				zap.String("path", path),
+			)
+
+			dispatcher.Publish(downloadEvent(notify.EventDownloadCompleted, book))
+
+			note, result := savedDetails(env, book)
+			result["path"] = path
+
+			return &mcp.CallToolResult{
+				Content: []mcp.Content{&mcp.TextContent{
+					Text: withAllowance(fmt.Sprintf("Book saved to %s%s", path, note), left),
+				}},
+			}, result, nil
+		}
+
+		info, err := resolveLink(env, book)
+		auditDownload(ctx, env, auditSourceDownload, book, "", err)
 		if err != nil {
 			l.Error("Download command failed",
 				zap.String("bookHash", params.BookHash),
 				zap.Error(err),
 			)
+			event := downloadEvent(notify.EventDownloadFailed, book)
+			event.Error = err.Error()
+			dispatcher.Publish(event)
 			return nil, nil, err
 		}
 
@@ -85,11 +257,15 @@ func NewDownloadToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest
 			zap.String("bookHash", params.BookHash),
 		)
 
+		event := downloadEvent(notify.EventDownloadCompleted, book)
+		event.URL = info.URL
+		dispatcher.Publish(event)
+
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{&mcp.TextContent{
-				Text: fmt.Sprintf("[%s](%s)", title, url),
+				Text: withAllowance(linkText(title, info), left),
 			}},
-		}, nil, nil
+		}, map[string]interface{}{"download": info}, nil
 	}
 }
 
@@ -106,26 +282,232 @@ func DownloadToolHandler(ctx context.Context, req *mcp.CallToolRequest, params D
 	return NewDownloadToolHandler(env)(ctx, req, params)
 }
 
+// perCall builds the handler from the environment returned by env on every
+// call, so that configuration reloads reach sessions that are already running.
+func perCall[P any](env func() *Env, newHandler func(*Env) func(context.Context, *mcp.CallToolRequest, P) (*mcp.CallToolResult, any, error)) func(context.Context, *mcp.CallToolRequest, P) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params P) (*mcp.CallToolResult, any, error) {
+		return newHandler(env())(ctx, req, params)
+	}
+}
 
-// createMCPServer creates and configures an MCP server instance using the provided environment.
-func createMCPServer(env *Env) *mcp.Server {
+// createMCPServer creates and configures an MCP server instance using the
+// environment returned by env. Tools check the scopes of the caller that
+// caller returns for each request before running.
+func createMCPServer(env func() *Env, caller callerLookup) *mcp.Server {
 	serverVersion := version.GetVersion()
 	server := mcp.NewServer(&mcp.Implementation{
 		Name:    "annas-mcp",
 		Version: serverVersion,
 	}, nil)
+	withCallers(server, caller)
 
 	// Add search tool
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "search",
 		Description: "Search books on Anna's Archive",
-	}, SearchToolHandler)
+	}, wrapTool(auth.ScopeSearch, perCall(env, NewSearchToolHandler)))
+
+	// Add magazine search tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "search_magazines",
+		Description: "Search magazine issues on Anna's Archive, with the volume, issue, and year of each result",
+	}, wrapTool(auth.ScopeSearch, SearchMagazinesToolHandler))
+
+	// Add comic search tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "search_comics",
+		Description: "Search comic issues on Anna's Archive, with the volume, issue, and year of each result",
+	}, wrapTool(auth.ScopeSearch, SearchComicsToolHandler))
+
+	// Add metadata tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_metadata",
+		Description: "Get the detailed record of a book by its MD5 hash, optionally enriched with OpenLibrary data",
+	}, wrapTool(auth.ScopeSearch, MetadataToolHandler))
+
+	// Add similar works tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "recommend_similar",
+		Description: "Recommend records related to a book, given by MD5 hash or title, through its series, authors, and OpenLibrary subjects, with the availability of each",
+	}, wrapTool(auth.ScopeSearch, perCall(env, NewRecommendSimilarToolHandler)))
+
+	// Add mirror status tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "mirror_status",
+		Description: "Show the availability, latency, and circuit breaker state of the Anna's Archive mirrors and partner servers",
+	}, wrapTool(auth.ScopeSearch, func(ctx context.Context, req *mcp.CallToolRequest, params MirrorStatusParams) (*mcp.CallToolResult, any, error) {
+		// Reporting only reads the statistics, refreshing probes every mirror
+		if params.Refresh {
+			if err := checkScope(auth.CallerFrom(ctx).Scopes, auth.ScopeAdmin); err != nil {
+				return nil, nil, err
+			}
+		}
+		return MirrorStatusToolHandler(ctx, req, params)
+	}))
+
+	// Add format and language listing tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_formats_and_languages",
+		Description: "List the common format and language values (with labels) accepted by the formats and languages arguments of search, search_magazines, and search_comics",
+	}, wrapTool(auth.ScopeSearch, ListFiltersToolHandler))
+
+	// Add torrent catalog tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "list_torrents",
+		Description: "List the dataset torrents released by Anna's Archive, with their collection, size, and seeders",
+	}, wrapTool(auth.ScopeSearch, ListTorrentsToolHandler))
+
+	// Add offline search tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "offline_search",
+		Description: "Search the local index of imported Anna's Archive metadata dumps, without contacting the mirrors. Requires ANNAS_OFFLINE_INDEX.",
+	}, wrapTool(auth.ScopeSearch, perCall(env, NewOfflineSearchToolHandler)))
+
+	// Add server info tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "get_server_info",
+		Description: "Get the version, git commit, build date, Go version, and platform of the server",
+	}, wrapTool(auth.ScopeSearch, ServerInfoToolHandler))
+
+	// Add server statistics tool. The statistics cover every caller, so
+	// they are reserved to operators.
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "server_stats",
+		Description: "Get the runtime statistics of the server: uptime, searches served, downloads completed, link cache hit rate, remaining quota, and mirror health",
+	}, wrapTool(auth.ScopeAdmin, perCall(env, NewServerStatsToolHandler)))
+
+	// Add usage tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "usage",
+		Description: "Get the searches and downloads of your API token today, this month, and overall, along with its quota. Admins can pass all to list every token.",
+	}, wrapTool(auth.ScopeSearch, UsageToolHandler))
+
+	// Read-only deployments only offer lookups
+	if env().ReadOnly {
+		return server
+	}
 
 	// Add download tool
 	mcp.AddTool(server, &mcp.Tool{
 		Name:        "download",
 		Description: "Download a book by its MD5 hash. Requires ANNAS_SECRET_KEY/secretKey environment variable.",
-	}, NewDownloadToolHandler(env))
+	}, wrapTool(auth.ScopeDownload, perCall(env, NewDownloadToolHandler)))
+
+	// Add best match download tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "download_best_match",
+		Description: "Search a book by title and author or ISBN, save its best edition to the download path, and return the path and record. Requires ANNAS_SECRET_KEY/secretKey environment variable.",
+	}, wrapTool(auth.ScopeDownload, perCall(env, NewDownloadBestMatchToolHandler)))
+
+	// Add paper download tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "download_paper",
+		Description: "Download a scientific paper by its DOI through SciDB. Papers with a direct SciDB link need no secret key.",
+	}, wrapTool(auth.ScopeDownload, perCall(env, NewDownloadPaperToolHandler)))
+
+	// Add text extraction tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "extract_text",
+		Description: "Extract the text of a book saved to the download path by its MD5 hash. Scanned PDFs without a text layer are recognized with tesseract for a bounded page range when ANNAS_OCR is enabled, and the result is marked as OCR.",
+	}, wrapTool(auth.ScopeDownload, perCall(env, NewExtractTextToolHandler)))
+
+	// Add book text search tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "search_inside_book",
+		Description: "Search the text of a book saved to the download path for a phrase, returning the matching passages with their chapter or page and the text resource page holding them",
+	}, wrapTool(auth.ScopeDownload, perCall(env, NewSearchInsideBookToolHandler)))
+
+	// Add text resources, for reading long books one page at a time
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		Name:        "book_text",
+		Title:       "Book text",
+		Description: "The text of a book saved to the download path in pages of 20000 characters. The _meta of every page holds the number of pages and the URI of the next one.",
+		MIMEType:    "text/plain",
+		URITemplate: textURITemplate,
+	}, NewTextResourceHandler(env))
+
+	// Add download link refresh tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "refresh_download_url",
+		Description: "Resolve a fresh fast download link for a book by its MD5 hash, for when a link returned by download has expired",
+	}, wrapTool(auth.ScopeDownload, perCall(env, NewRefreshDownloadURLToolHandler)))
+
+	// Add quota tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "quota",
+		Description: "Show the remaining fast downloads and usage of each configured secret key",
+	}, wrapTool(auth.ScopeDownload, perCall(env, NewQuotaToolHandler)))
+
+	// Add partner server speed test tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "speedtest",
+		Description: "Measure the throughput of every fast partner server offering a record, to pick the fastest server for large downloads",
+	}, wrapTool(auth.ScopeDownload, perCall(env, NewSpeedTestToolHandler)))
+
+	// Add Send-to-Kindle tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "send_to_kindle",
+		Description: "Download a book by its MD5 hash and email it to the configured Kindle address. Requires ANNAS_KINDLE_EMAIL and ANNAS_SMTP_* environment variables.",
+	}, wrapTool(auth.ScopeDownload, perCall(env, NewSendToKindleToolHandler)))
+
+	// Add want-to-read sync tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "sync_want_to_read",
+		Description: "Match a Goodreads or Hardcover want-to-read shelf against Anna's Archive and optionally download the best matches",
+	}, wrapTool(auth.ScopeSearch, func(ctx context.Context, req *mcp.CallToolRequest, params WantToReadParams) (*mcp.CallToolResult, any, error) {
+		// Matching only searches, saving the matches consumes downloads
+		if params.Download {
+			if err := checkScope(auth.CallerFrom(ctx).Scopes, auth.ScopeDownload); err != nil {
+				return nil, nil, err
+			}
+		}
+		return perCall(env, NewWantToReadToolHandler)(ctx, req, params)
+	}))
+
+	// Add reading list import tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "import_reading_list",
+		Description: "Match every entry of a CSV or Markdown reading list against Anna's Archive, reporting the matched MD5 and confidence of each, and optionally queue the confident matches for download",
+	}, wrapTool(auth.ScopeSearch, func(ctx context.Context, req *mcp.CallToolRequest, params ReadingListParams) (*mcp.CallToolResult, any, error) {
+		// Matching only searches, queueing the matches consumes downloads
+		if params.Download {
+			if err := checkScope(auth.CallerFrom(ctx).Scopes, auth.ScopeDownload); err != nil {
+				return nil, nil, err
+			}
+		}
+		return perCall(env, NewReadingListToolHandler)(ctx, req, params)
+	}))
+
+	// Add series completion tool
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "complete_series",
+		Description: "Identify the series of a book, given by MD5 hash or title, from OpenLibrary, list its volumes in order, mark those already in the library, and optionally queue the missing ones for download",
+	}, wrapTool(auth.ScopeSearch, func(ctx context.Context, req *mcp.CallToolRequest, params CompleteSeriesParams) (*mcp.CallToolResult, any, error) {
+		// Listing only searches, queueing the missing volumes consumes downloads
+		if params.Download {
+			if err := checkScope(auth.CallerFrom(ctx).Scopes, auth.ScopeDownload); err != nil {
+				return nil, nil, err
+			}
+		}
+		return perCall(env, NewCompleteSeriesToolHandler)(ctx, req, params)
+	}))
+
+	// Add scheduled search tools. Schedules are shared by all callers, so
+	// managing them is reserved to admins
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "schedule_add",
+		Description: "Save a search that the HTTP server runs on a cron expression, notifying the configured webhook and push backends of new results. Requires ANNAS_SCHEDULES_FILE.",
+	}, wrapTool(auth.ScopeAdmin, perCall(env, NewScheduleAddToolHandler)))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "schedule_list",
+		Description: "List the scheduled searches with their last run",
+	}, wrapTool(auth.ScopeAdmin, perCall(env, NewScheduleListToolHandler)))
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "schedule_remove",
+		Description: "Remove a scheduled search by its ID",
+	}, wrapTool(auth.ScopeAdmin, perCall(env, NewScheduleRemoveToolHandler)))
 
 	return server
 }
@@ -146,14 +528,33 @@ func StartMCPServer() {
 	if err != nil {
 		// Log error but proceed to allow search tool to work
 		l.Warn("Failed to load environment variables, download tool may not work", zap.Error(err))
-		env = &Env{}
+	}
+	if env == nil {
+		env = config.Defaults()
 	}
 
-	server := createMCPServer(env)
+	if env.ReadOnly {
+		l.Info("Read-only mode, only lookup tools are available")
+	} else if err := checkDownloadPath(env); err != nil {
+		l.Fatal("Invalid download path", zap.String("path", env.DownloadPath), zap.Error(err))
+	}
+
+	logStartup("stdio", env)
+	watchReload(env)
+	startProber(env)
+	stopStats := startStats(env, "mcp/stdio")
+	defer stopStats()
+	server := createMCPServer(func() *Env {
+		if current, err := baseConfig(); err == nil {
+			return current
+		}
+		return env
+	}, fixedCaller(auth.Local))
 
 	l.Info("MCP server started successfully")
 
 	if err := server.Run(context.Background(), &mcp.StdioTransport{}); err != nil {
+		stopStats()
 		l.Fatal("MCP server failed", zap.Error(err))
 	}
 }
@@ -0,0 +1,139 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/openlibrary"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// maxSubjects caps the OpenLibrary subject list, which can run into the hundreds.
+const maxSubjects = 20
+
+// defaultDescriptionLength keeps descriptions long enough to recognize a book
+// without flooding the context of agents.
+const defaultDescriptionLength = 1000
+
+// fetchMetadata retrieves a record and optionally fills its gaps from
+// OpenLibrary. The description is truncated to descriptionLength characters,
+// 0 selecting the default and a negative length keeping it whole.
+func fetchMetadata(ctx context.Context, hash string, enrich bool, descriptionLength int) (*anna.Metadata, error) {
+	metadata, err := anna.GetMetadata(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	if enrich {
+		enrichMetadata(ctx, metadata)
+	}
+
+	if descriptionLength == 0 {
+		descriptionLength = defaultDescriptionLength
+	}
+	if descriptionLength > 0 {
+		metadata.Description = truncateText(metadata.Description, descriptionLength)
+	}
+
+	return metadata, nil
+}
+
+// truncateText cuts text to at most limit characters, at a word boundary
+// when there is one, and marks the cut with an ellipsis.
+func truncateText(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	// Back up to the last word boundary unless the cut falls on one
+	cut := string(runes[:limit])
+	if !unicode.IsSpace(runes[limit]) {
+		if i := strings.LastIndexAny(cut, " \n"); i > len(cut)/2 {
+			cut = cut[:i]
+		}
+	}
+
+	return strings.TrimRight(cut, " \n.,;:") + "…"
+}
+
+// enrichMetadata fills empty fields using the first ISBN OpenLibrary knows about.
+// Lookup failures are logged and leave the record untouched.
+func enrichMetadata(ctx context.Context, metadata *anna.Metadata) {
+	l := logger.GetLogger()
+
+	for _, isbn := range metadata.ISBNs {
+		work, err := openlibrary.LookupISBN(ctx, isbn)
+		if errors.Is(err, openlibrary.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			l.Warn("OpenLibrary enrichment failed", zap.String("isbn", isbn), zap.Error(err))
+			return
+		}
+
+		if metadata.Title == "" {
+			metadata.Title = work.Title
+		}
+		if metadata.Description == "" {
+			metadata.Description = work.Description
+		}
+		if len(metadata.Subjects) == 0 {
+			metadata.Subjects = work.Subjects
+			if len(metadata.Subjects) > maxSubjects {
+				metadata.Subjects = metadata.Subjects[:maxSubjects]
+			}
+		}
+		if len(metadata.Series) == 0 {
+			metadata.Series = work.Series
+		}
+		return
+	}
+}
+
+// MetadataToolHandler returns the detailed record of a book.
+// It does not require any specific environment configuration.
+func MetadataToolHandler(ctx context.Context, req *mcp.CallToolRequest, params MetadataParams) (*mcp.CallToolResult, any, error) {
+	l := logger.GetLogger()
+
+	l.Info("Metadata command called",
+		zap.String("bookHash", params.BookHash),
+		zap.Bool("enrich", params.Enrich),
+		zap.Int("descriptionLength", params.DescriptionLength),
+	)
+
+	hash, err := validateHash(params.BookHash)
+	if err != nil {
+		l.Error("Metadata command failed", zap.Error(err))
+		return nil, nil, err
+	}
+	params.BookHash = hash
+
+	metadata, err := fetchMetadata(ctx, params.BookHash, params.Enrich, params.DescriptionLength)
+	if err != nil {
+		l.Error("Metadata command failed",
+			zap.String("bookHash", params.BookHash),
+			zap.Error(err),
+		)
+		return nil, nil, err
+	}
+
+	data, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	l.Info("Metadata command completed successfully",
+		zap.String("bookHash", params.BookHash),
+	)
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: string(data)}},
+	}, metadata, nil
+}
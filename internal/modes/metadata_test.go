@@ -0,0 +1,20 @@
+package modes
+
+import "testing"
+
+func TestTruncateText(t *testing.T) {
+	cases := []struct {
+		text     string
+		limit    int
+		expected string
+	}{
+		{"Short text", 100, "Short text"},
+		{"The spice must flow, said the Baron.", 20, "The spice must flow…"},
+		{"Дюна — роман Фрэнка Герберта", 10, "Дюна —…"},
+	}
+	for _, c := range cases {
+		if got := truncateText(c.text, c.limit); got != c.expected {
+			t.Errorf("Expected '%s' truncated to %d to be '%s', got '%s'", c.text, c.limit, c.expected, got)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// startProber starts the background mirror health checks of long-running
+// modes, unless they are disabled.
+func startProber(env *Env) {
+	l := logger.GetLogger()
+
+	if env.ProbeIntervalSeconds == 0 {
+		l.Info("Mirror health checks disabled")
+		return
+	}
+
+	interval := time.Duration(env.ProbeIntervalSeconds) * time.Second
+	l.Info("Starting mirror health checks", zap.Duration("interval", interval))
+	anna.StartProber(context.Background(), interval)
+}
+
+// mirrorReport combines the probe statistics and circuit breaker states of
+// the mirrors.
+func mirrorReport() map[string]interface{} {
+	return map[string]interface{}{
+		"probes":   anna.HealthStatuses(),
+		"breakers": anna.MirrorStatuses(),
+	}
+}
+
+// MirrorStatusToolHandler reports the health of the configured mirrors and
+// partner servers.
+func MirrorStatusToolHandler(ctx context.Context, req *mcp.CallToolRequest, params MirrorStatusParams) (*mcp.CallToolResult, any, error) {
+	l := logger.GetLogger()
+	l.Info("Mirror status command called", zap.Bool("refresh", params.Refresh))
+
+	if params.Refresh {
+		anna.Probe(ctx)
+	}
+
+	breakers := make(map[string]anna.MirrorStatus)
+	for _, status := range anna.MirrorStatuses() {
+		breakers[status.Mirror] = status
+	}
+
+	var text strings.Builder
+	for _, health := range anna.HealthStatuses() {
+		fmt.Fprintf(&text, "%s (%s): ", health.Target, health.Kind)
+		switch {
+		case health.Probes == 0:
+			text.WriteString("not probed yet")
+		case health.Available:
+			fmt.Fprintf(&text, "available, %d ms average, %.0f%% of the last %d probes succeeded", health.LatencyMS, health.SuccessRate*100, health.Probes)
+		default:
+			fmt.Fprintf(&text, "unavailable (%s), %.0f%% of the last %d probes succeeded", health.LastError, health.SuccessRate*100, health.Probes)
+		}
+		if breaker, ok := breakers[health.Target]; ok && breaker.State != anna.CircuitClosed {
+			fmt.Fprintf(&text, ", circuit %s", breaker.State)
+		}
+		text.WriteString("\n")
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text.String()}},
+	}, mirrorReport(), nil
+}
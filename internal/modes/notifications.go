@@ -0,0 +1,43 @@
+package modes
+
+import (
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/notify"
+)
+
+// newDispatcher builds a notification dispatcher from the backends configured in env.
+func newDispatcher(env *Env) *notify.Dispatcher {
+	notifiers := []notify.Notifier{eventBroker}
+	if env.WebhookURL != "" {
+		notifiers = append(notifiers, notify.NewWebhook(env.WebhookURL, env.WebhookSecret))
+	}
+	if env.NtfyURL != "" {
+		notifiers = append(notifiers, notify.NewNtfy(env.NtfyURL, env.NtfyToken))
+	}
+	if env.PushoverToken != "" && env.PushoverUser != "" {
+		notifiers = append(notifiers, notify.NewPushover(env.PushoverToken, env.PushoverUser))
+	}
+
+	return notify.NewDispatcher(notifiers...)
+}
+
+// downloadEvent builds a lifecycle event describing the given book.
+func downloadEvent(eventType string, book *anna.Book) notify.Event {
+	return notify.Event{
+		Type:   eventType,
+		Hash:   book.Hash,
+		Title:  book.Title,
+		Format: book.Format,
+	}
+}
+
+// batchEvent builds the event closing a batch of downloads queued by source,
+// done of which were saved and failed not.
+func batchEvent(source string, done, failed int) notify.Event {
+	return notify.Event{
+		Type:   notify.EventBatchCompleted,
+		Source: source,
+		Done:   done,
+		Failed: failed,
+	}
+}
@@ -0,0 +1,92 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"go.uber.org/zap"
+)
+
+// protectedResourcePath serves the OAuth 2.0 protected resource metadata
+// (RFC 9728) that the MCP authorization spec has clients discover.
+const protectedResourcePath = "/.well-known/oauth-protected-resource"
+
+// requestOrigin returns the scheme and host clients used to reach r, unless
+// a public URL is configured.
+func requestOrigin(r *http.Request, publicURL string) string {
+	if publicURL != "" {
+		return strings.TrimRight(publicURL, "/")
+	}
+
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+// protectedResource is the metadata of the MCP endpoint as a protected
+// resource.
+type protectedResource struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	ScopesSupported        []string `json:"scopes_supported"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+	ResourceName           string   `json:"resource_name"`
+}
+
+// protectedResourceHandler serves the protected resource metadata of the MCP
+// endpoint. Deployments without authorization servers have none.
+func protectedResourceHandler(l *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, MCP-Protocol-Version")
+
+		if r.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		env, err := baseConfig()
+		if err != nil || len(env.OAuthAuthorizationServers) == 0 {
+			http.NotFound(w, r)
+			return
+		}
+
+		metadata := protectedResource{
+			Resource:               requestOrigin(r, env.PublicURL) + "/mcp",
+			AuthorizationServers:   env.OAuthAuthorizationServers,
+			ScopesSupported:        auth.AllScopes,
+			BearerMethodsSupported: []string{"header"},
+			ResourceName:           "Anna's Archive MCP Server",
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(metadata); err != nil {
+			l.Error("Failed to encode protected resource metadata", zap.Error(err))
+		}
+	}
+}
+
+// challenge sets the WWW-Authenticate header of a 401 response for provider,
+// pointing bearer clients at the protected resource metadata when
+// authorization servers are configured.
+func challenge(w http.ResponseWriter, r *http.Request, provider auth.Provider) {
+	if provider.Name() == auth.ProviderBasic {
+		w.Header().Set("WWW-Authenticate", `Basic realm="annas-mcp"`)
+		return
+	}
+
+	env, err := baseConfig()
+	if err != nil || len(env.OAuthAuthorizationServers) == 0 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		return
+	}
+
+	metadata := requestOrigin(r, env.PublicURL) + protectedResourcePath
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer resource_metadata="%s"`, metadata))
+}
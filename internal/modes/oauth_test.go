@@ -0,0 +1,54 @@
+package modes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"go.uber.org/zap"
+)
+
+func TestProtectedResource(t *testing.T) {
+	handler := protectedResourceHandler(zap.NewNop())
+
+	t.Run("Not Configured", func(t *testing.T) {
+		activeConfig.Store(&Env{})
+		defer activeConfig.Store(nil)
+
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, protectedResourcePath, nil))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		activeConfig.Store(&Env{OAuthAuthorizationServers: []string{"https://auth.example.com"}})
+		defer activeConfig.Store(nil)
+
+		r := httptest.NewRequest(http.MethodGet, "http://books.example.com"+protectedResourcePath, nil)
+		r.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		handler(w, r)
+
+		var metadata protectedResource
+		if err := json.NewDecoder(w.Body).Decode(&metadata); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if metadata.Resource != "https://books.example.com/mcp" {
+			t.Errorf("Expected resource 'https://books.example.com/mcp', got '%s'", metadata.Resource)
+		}
+		if len(metadata.AuthorizationServers) != 1 || metadata.AuthorizationServers[0] != "https://auth.example.com" {
+			t.Errorf("Expected the configured authorization server, got %v", metadata.AuthorizationServers)
+		}
+
+		w = httptest.NewRecorder()
+		challenge(w, r, auth.NewKeyProvider("", nil))
+		want := `Bearer resource_metadata="https://books.example.com/.well-known/oauth-protected-resource"`
+		if got := w.Header().Get("WWW-Authenticate"); got != want {
+			t.Errorf("Expected WWW-Authenticate '%s', got '%s'", want, got)
+		}
+	})
+}
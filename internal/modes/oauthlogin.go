@@ -0,0 +1,320 @@
+package modes
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// oauthLoginTTL bounds how long a browser may take between /oauth/login and
+// the callback.
+const oauthLoginTTL = 10 * time.Minute
+
+// sessionCookie carries the session secret for browser clients.
+const sessionCookie = "annas_session"
+
+// oauthSessions holds the sessions of the OAuth login of the HTTP mode.
+var oauthSessions atomic.Pointer[auth.SessionStore]
+
+// oauthLogin is a login waiting for its callback, keyed by its state.
+type oauthLogin struct {
+	verifier string
+	redirect string
+	expires  time.Time
+}
+
+var oauthLogins = struct {
+	sync.Mutex
+	pending map[string]oauthLogin
+}{pending: make(map[string]oauthLogin)}
+
+// oauthTokens is the token response of the authorization server.
+type oauthTokens struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	IDToken      string `json:"id_token"`
+}
+
+var oauthClient = &http.Client{Timeout: 15 * time.Second}
+
+// oauthEnabled reports whether env configures the OAuth login.
+func oauthEnabled(env *Env) bool {
+	return env.OAuthAuthorizeURL != "" && env.OAuthTokenURL != ""
+}
+
+// loadOAuthSessions opens the sessions file of env when the OAuth login is
+// configured.
+func loadOAuthSessions(env *Env) error {
+	if !oauthEnabled(env) {
+		return nil
+	}
+
+	store, err := auth.OpenSessions(env.OAuthSessionsFile)
+	if err != nil {
+		return err
+	}
+
+	oauthSessions.Store(store)
+	return nil
+}
+
+// randomString returns n random bytes encoded for URLs.
+func randomString(n int) (string, error) {
+	raw := make([]byte, n)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// oauthLoginHandler redirects browsers to the authorization server, with a
+// PKCE challenge and a state that the callback checks.
+func oauthLoginHandler(l *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		env, err := baseConfig()
+		if err != nil || !oauthEnabled(env) {
+			http.NotFound(w, r)
+			return
+		}
+
+		state, err := randomString(24)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		verifier, err := randomString(32)
+		if err != nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		redirect := requestOrigin(r, env.PublicURL) + "/oauth/callback"
+
+		now := time.Now()
+		oauthLogins.Lock()
+		for key, login := range oauthLogins.pending {
+			if now.After(login.expires) {
+				delete(oauthLogins.pending, key)
+			}
+		}
+		oauthLogins.pending[state] = oauthLogin{verifier: verifier, redirect: redirect, expires: now.Add(oauthLoginTTL)}
+		oauthLogins.Unlock()
+
+		challenge := sha256.Sum256([]byte(verifier))
+		query := url.Values{
+			"response_type":         {"code"},
+			"client_id":             {env.OAuthClientID},
+			"redirect_uri":          {redirect},
+			"scope":                 {strings.Join(env.OAuthScopes, " ")},
+			"state":                 {state},
+			"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+			"code_challenge_method": {"S256"},
+		}
+
+		target, err := url.Parse(env.OAuthAuthorizeURL)
+		if err != nil {
+			l.Error("Invalid OAuth authorize URL", zap.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		values := target.Query()
+		for key, value := range query {
+			values[key] = value
+		}
+		target.RawQuery = values.Encode()
+
+		http.Redirect(w, r, target.String(), http.StatusFound)
+	}
+}
+
+// oauthCallbackHandler exchanges the authorization code for tokens and
+// starts a session. Its secret is shown to the user and set as a cookie, and
+// authenticates /mcp like an API token.
+func oauthCallbackHandler(l *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		env, err := baseConfig()
+		store := oauthSessions.Load()
+		if err != nil || !oauthEnabled(env) || store == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		query := r.URL.Query()
+		if reason := query.Get("error"); reason != "" {
+			l.Warn("OAuth login was denied", zap.String("error", reason), zap.String("description", query.Get("error_description")))
+			http.Error(w, "Login failed: "+reason, http.StatusUnauthorized)
+			return
+		}
+
+		oauthLogins.Lock()
+		login, ok := oauthLogins.pending[query.Get("state")]
+		delete(oauthLogins.pending, query.Get("state"))
+		oauthLogins.Unlock()
+		if !ok || time.Now().After(login.expires) {
+			http.Error(w, "Login failed: unknown or expired state, please sign in again", http.StatusBadRequest)
+			return
+		}
+		code := query.Get("code")
+		if code == "" {
+			http.Error(w, "Login failed: missing authorization code", http.StatusBadRequest)
+			return
+		}
+
+		tokens, err := exchangeToken(r.Context(), env, url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {login.redirect},
+			"code_verifier": {login.verifier},
+		})
+		if err != nil {
+			l.Error("OAuth token exchange failed", zap.Error(err))
+			http.Error(w, "Login failed: the authorization server rejected the code", http.StatusBadGateway)
+			return
+		}
+
+		session := auth.Session{Name: tokenName(tokens.IDToken)}
+		applyTokens(env, &session, tokens)
+		secret, err := store.Create(session)
+		if err != nil {
+			l.Error("Failed to save OAuth session", zap.Error(err))
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		l.Info("OAuth login completed successfully", zap.String("caller", session.Name), zap.Strings("scopes", session.Scopes))
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     sessionCookie,
+			Value:    secret,
+			Path:     "/",
+			HttpOnly: true,
+			Secure:   strings.HasPrefix(login.redirect, "https://"),
+			SameSite: http.SameSiteLaxMode,
+		})
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		fmt.Fprintf(w, "Signed in as %s with the %s scopes.\n\nConfigure your MCP client with this API key, sent as \"Authorization: Bearer <key>\":\n\n%s\n",
+			session.Name, strings.Join(session.Scopes, ", "), secret)
+	}
+}
+
+// exchangeToken posts form to the token URL of env, authenticating as the
+// configured client.
+func exchangeToken(ctx context.Context, env *Env, form url.Values) (*oauthTokens, error) {
+	form.Set("client_id", env.OAuthClientID)
+	if env.OAuthClientSecret != "" {
+		form.Set("client_secret", env.OAuthClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, env.OAuthTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oauthClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach the token URL: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var tokens oauthTokens
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		return nil, fmt.Errorf("token URL answered %s: %s %s", resp.Status, failure.Error, failure.Description)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return nil, errors.New("token response has no access token")
+	}
+
+	return &tokens, nil
+}
+
+// applyTokens stores tokens in session. The session gets the configured
+// scopes, narrowed to the granted ones when the server reports them.
+func applyTokens(env *Env, session *auth.Session, tokens *oauthTokens) {
+	session.AccessToken = tokens.AccessToken
+	if tokens.RefreshToken != "" {
+		session.RefreshToken = tokens.RefreshToken
+	}
+	session.Expiry = time.Time{}
+	if tokens.ExpiresIn > 0 {
+		session.Expiry = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second).UTC()
+	}
+
+	granted := strings.Fields(tokens.Scope)
+	session.Scopes = nil
+	for _, scope := range env.OAuthScopes {
+		if auth.ValidScope(scope) && (len(granted) == 0 || slices.Contains(granted, scope)) {
+			session.Scopes = append(session.Scopes, scope)
+		}
+	}
+}
+
+// refreshSession renews the upstream tokens of an expired session with its
+// refresh token.
+func refreshSession(ctx context.Context, session *auth.Session) error {
+	env, err := baseConfig()
+	if err != nil {
+		return err
+	}
+
+	tokens, err := exchangeToken(ctx, env, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {session.RefreshToken},
+	})
+	if err != nil {
+		return err
+	}
+
+	applyTokens(env, session, tokens)
+	logger.GetLogger().Info("Refreshed OAuth session", zap.String("caller", session.Name))
+	return nil
+}
+
+// tokenName names the caller of an ID token. The token comes straight from
+// the token URL over the client's own connection, so its claims are read
+// without checking the signature.
+func tokenName(idToken string) string {
+	parts := strings.Split(idToken, ".")
+	if len(parts) == 3 {
+		var claims struct {
+			Subject           string `json:"sub"`
+			PreferredUsername string `json:"preferred_username"`
+			Email             string `json:"email"`
+		}
+		if data, err := base64.RawURLEncoding.DecodeString(parts[1]); err == nil && json.Unmarshal(data, &claims) == nil {
+			for _, name := range []string{claims.PreferredUsername, claims.Email, claims.Subject} {
+				if name != "" {
+					return name
+				}
+			}
+		}
+	}
+
+	return "oauth"
+}
@@ -0,0 +1,103 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"go.uber.org/zap"
+)
+
+func TestOAuthLogin(t *testing.T) {
+	var form url.Values
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		form = r.PostForm
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "upstream-access",
+			"refresh_token": "upstream-refresh",
+			"expires_in":    3600,
+			"scope":         "search openid",
+			"id_token":      "e30.eyJwcmVmZXJyZWRfdXNlcm5hbWUiOiJhbGljZSJ9.c2ln",
+		})
+	}))
+	defer tokenServer.Close()
+
+	activeConfig.Store(&Env{
+		OAuthAuthorizeURL: "https://auth.example.com/authorize",
+		OAuthTokenURL:     tokenServer.URL,
+		OAuthClientID:     "annas",
+		OAuthScopes:       []string{"search", "download"},
+		OAuthSessionsFile: filepath.Join(t.TempDir(), "sessions.json"),
+	})
+	defer activeConfig.Store(nil)
+	env, _ := baseConfig()
+	if err := loadOAuthSessions(env); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer oauthSessions.Store(nil)
+
+	w := httptest.NewRecorder()
+	oauthLoginHandler(zap.NewNop())(w, httptest.NewRequest(http.MethodGet, "http://books.example.com/oauth/login", nil))
+	if w.Code != http.StatusFound {
+		t.Fatalf("Expected status 302, got %d", w.Code)
+	}
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := location.Query().Get("redirect_uri"); got != "http://books.example.com/oauth/callback" {
+		t.Errorf("Expected redirect URI 'http://books.example.com/oauth/callback', got '%s'", got)
+	}
+	if location.Query().Get("code_challenge_method") != "S256" {
+		t.Errorf("Expected a S256 code challenge, got '%s'", location.Query().Get("code_challenge_method"))
+	}
+
+	callback := oauthCallbackHandler(zap.NewNop())
+
+	t.Run("Unknown State", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		callback(w, httptest.NewRequest(http.MethodGet, "/oauth/callback?code=abc&state=unknown", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", w.Code)
+		}
+	})
+
+	t.Run("Exchange", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		callback(w, httptest.NewRequest(http.MethodGet, "/oauth/callback?code=abc&state="+location.Query().Get("state"), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if form.Get("grant_type") != "authorization_code" || form.Get("code") != "abc" || form.Get("code_verifier") == "" {
+			t.Errorf("Expected an authorization code grant with a verifier, got %v", form)
+		}
+
+		cookies := w.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != sessionCookie {
+			t.Fatalf("Expected the session cookie, got %v", cookies)
+		}
+		if !strings.Contains(w.Body.String(), cookies[0].Value) {
+			t.Errorf("Expected the session key in the response, got '%s'", w.Body.String())
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+		r.AddCookie(cookies[0])
+		caller, err := newAuthProvider(env).Authenticate(context.Background(), requestCredentials(r, false))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if caller.Name != "alice" {
+			t.Errorf("Expected caller 'alice', got '%s'", caller.Name)
+		}
+		if !caller.Scopes.Has(auth.ScopeSearch) || caller.Scopes.Has(auth.ScopeDownload) {
+			t.Errorf("Expected only the granted search scope, got %v", caller.Scopes)
+		}
+	})
+}
@@ -0,0 +1,110 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"github.com/iosifache/annas-mcp/internal/offline"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// errOfflineIndexMissing is returned without a configured offline index.
+var errOfflineIndexMissing = withCode(codeNotConfigured, "offline index is not configured. Please set ANNAS_OFFLINE_INDEX and run 'annas-mcp index import'")
+
+// The opened offline index is kept until its file changes, since rebuilding
+// the terms of a large index takes a while.
+var (
+	offlineMu      sync.Mutex
+	offlinePath    string
+	offlineModTime time.Time
+	offlineCached  *offline.Index
+)
+
+// openOfflineIndex returns the offline index of env.
+func openOfflineIndex(env *Env) (*offline.Index, error) {
+	if env.OfflineIndex == "" {
+		return nil, errOfflineIndexMissing
+	}
+
+	info, err := os.Stat(env.OfflineIndex)
+	if err != nil {
+		return nil, withCode(codeNotConfigured, "offline index %s is not readable, run 'annas-mcp index import' first: %v", env.OfflineIndex, err)
+	}
+
+	offlineMu.Lock()
+	defer offlineMu.Unlock()
+
+	if offlineCached != nil && offlinePath == env.OfflineIndex && offlineModTime.Equal(info.ModTime()) {
+		return offlineCached, nil
+	}
+
+	index, err := offline.Open(env.OfflineIndex)
+	if err != nil {
+		return nil, err
+	}
+	offlinePath, offlineModTime, offlineCached = env.OfflineIndex, info.ModTime(), index
+
+	return index, nil
+}
+
+// searchOffline queries the offline index of env.
+func searchOffline(env *Env, term string, limit int) ([]*anna.Book, error) {
+	index, err := openOfflineIndex(env)
+	if err != nil {
+		return nil, err
+	}
+
+	records := index.Search(term, limit)
+	metrics.Searches.Inc()
+	books := make([]*anna.Book, 0, len(records))
+	for i := range records {
+		books = append(books, records[i].Book())
+	}
+
+	return books, nil
+}
+
+// NewOfflineSearchToolHandler creates a handler for the offline_search tool that uses the provided environment.
+func NewOfflineSearchToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, OfflineSearchParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params OfflineSearchParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Offline search command called", zap.String("searchTerm", params.SearchTerm))
+
+		if err := chargeUsage(ctx, usage.KindSearch); err != nil {
+			l.Error("Offline search command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		books, err := searchOffline(env, params.SearchTerm, params.Limit)
+		if err != nil {
+			l.Error("Offline search command failed", zap.String("searchTerm", params.SearchTerm), zap.Error(err))
+			return nil, nil, err
+		}
+
+		l.Info("Offline search command completed successfully",
+			zap.String("searchTerm", params.SearchTerm),
+			zap.Int("resultsCount", len(books)),
+		)
+
+		text := fmt.Sprintf("No books found for %q in the offline index.", params.SearchTerm)
+		if len(books) > 0 {
+			text = ""
+			for _, book := range books {
+				text += book.String() + "\n\n"
+			}
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: text}},
+		}, map[string]interface{}{"books": books}, nil
+	}
+}
@@ -0,0 +1,127 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"maps"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/notify"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// resolvePaper returns a download URL for a paper, preferring the direct
+// SciDB link over spending a fast download on its record.
+func resolvePaper(env *Env, paper *anna.Paper) (string, error) {
+	if paper.DownloadURL != "" {
+		return paper.DownloadURL, nil
+	}
+	if len(env.Keys()) == 0 {
+		return "", errSecretKeyMissing
+	}
+
+	info, err := resolveDownload(env, paper.Book())
+	if err != nil {
+		return "", fmt.Errorf("failed to get download URL: %w", err)
+	}
+
+	return info.URL, nil
+}
+
+// savePaper downloads a paper into the configured download path like saveBook
+// does for books. It returns the local path of the saved file.
+func savePaper(env *Env, paper *anna.Paper) (string, error) {
+	book := paper.Book()
+
+	if err := checkDownloadPath(env); err != nil {
+		return "", err
+	}
+	if err := checkFormat(env, book); err != nil {
+		return "", err
+	}
+	if err := checkFileSize(env, book); err != nil {
+		return "", err
+	}
+	if err := reserveSpace(env, book.SizeBytes()); err != nil {
+		return "", err
+	}
+
+	downloadURL, err := resolvePaper(env, paper)
+	if err != nil {
+		return "", err
+	}
+
+	return storeBook(env, book, downloadURL)
+}
+
+// NewDownloadPaperToolHandler creates a handler for the download_paper tool that uses the provided environment.
+func NewDownloadPaperToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, DownloadPaperParams) (*mcp.CallToolResult, any, error) {
+	dispatcher := newDispatcher(env)
+
+	return func(ctx context.Context, req *mcp.CallToolRequest, params DownloadPaperParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Download paper command called",
+			zap.String("doi", params.DOI),
+			zap.Bool("save", params.Save),
+		)
+
+		doi, err := anna.NormalizeDOI(params.DOI)
+		if err != nil {
+			l.Error("Download paper command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		left, err := takeDownload(ctx, env)
+		if err != nil {
+			l.Error("Download paper command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		paper, err := anna.GetPaper(doi)
+		if err != nil {
+			l.Error("Download paper command failed", zap.String("doi", doi), zap.Error(err))
+			return nil, nil, err
+		}
+		book := paper.Book()
+
+		dispatcher.Publish(downloadEvent(notify.EventDownloadQueued, book))
+
+		var text string
+		result := map[string]interface{}{"paper": paper}
+		if params.Save {
+			path, err := savePaper(env, paper)
+			auditDownload(ctx, env, auditSourcePaper, book, path, err)
+			if err != nil {
+				l.Error("Download paper command failed", zap.String("doi", doi), zap.Error(err))
+				event := downloadEvent(notify.EventDownloadFailed, book)
+				event.Error = err.Error()
+				dispatcher.Publish(event)
+				return nil, nil, err
+			}
+			note, details := savedDetails(env, book)
+			maps.Copy(result, details)
+			text = fmt.Sprintf("Paper saved to %s%s", path, note)
+		} else {
+			downloadURL, err := resolvePaper(env, paper)
+			auditDownload(ctx, env, auditSourcePaper, book, "", err)
+			if err != nil {
+				l.Error("Download paper command failed", zap.String("doi", doi), zap.Error(err))
+				event := downloadEvent(notify.EventDownloadFailed, book)
+				event.Error = err.Error()
+				dispatcher.Publish(event)
+				return nil, nil, err
+			}
+			text = fmt.Sprintf("[%s](%s)", book.Title, downloadURL)
+		}
+
+		l.Info("Download paper command completed successfully", zap.String("doi", doi))
+		dispatcher.Publish(downloadEvent(notify.EventDownloadCompleted, book))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: withAllowance(text, left)}},
+		}, result, nil
+	}
+}
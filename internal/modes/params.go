@@ -1,11 +1,183 @@
 package modes
 
+import (
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+)
+
+// validateHash normalizes an MD5 hash parameter, so malformed values are
+// reported with the INVALID_HASH code instead of reaching upstream.
+func validateHash(hash string) (string, error) {
+	return anna.NormalizeHash(hash)
+}
+
+// maxSearchLimit bounds the results of one search, since every page of
+// results costs a request to the mirrors.
+const maxSearchLimit = 500
+
+// validateLimit rejects search limits that are negative or too large.
+func validateLimit(limit int) error {
+	if limit < 0 || limit > maxSearchLimit {
+		return withCode(codeInvalidArgument, "invalid limit %d: expected 0 to %d", limit, maxSearchLimit)
+	}
+
+	return nil
+}
+
+// parseLanguages returns the language codes of a list of ISO 639-1 codes or
+// language names, such as "en" and "German".
+func parseLanguages(list []string) ([]string, error) {
+	var codes []string
+	for _, value := range list {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		code, ok := anna.LanguageCode(value)
+		if !ok {
+			return nil, withCode(codeInvalidArgument, "invalid language %q: expected an ISO 639-1 code such as en", strings.TrimSpace(value))
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// parseFormats returns the file extensions of a list of formats, such as
+// "epub" and "PDF".
+func parseFormats(list []string) ([]string, error) {
+	var codes []string
+	for _, value := range list {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		code, ok := anna.FormatCode(value)
+		if !ok {
+			return nil, withCode(codeInvalidArgument, "invalid format %q: expected a file extension such as epub", strings.TrimSpace(value))
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
 type SearchParams struct {
-	SearchTerm string `json:"term" jsonschema:"Term to search for"`
+	SearchTerm    string   `json:"term" jsonschema:"Term to search for"`
+	Transliterate bool     `json:"transliterate,omitempty" jsonschema:"Also search the term with diacritics removed and Cyrillic or Greek romanized, to find records listed under a romanized title"`
+	Limit         int      `json:"limit,omitempty" jsonschema:"Maximum number of results, fetched from as many result pages as needed (default: the first page, at most 500)"`
+	Rank          bool     `json:"rank,omitempty" jsonschema:"Reorder the results by how closely their title and authors match the term, best match first (default: the upstream order, or ANNAS_RANK_RESULTS)"`
+	Languages     []string `json:"languages,omitempty" jsonschema:"Only return results in any of these languages, as ISO 639-1 codes such as en (default: every language)"`
+	Formats       []string `json:"formats,omitempty" jsonschema:"Only return results in any of these file formats, as extensions such as epub (default: every format)"`
 }
 
 type DownloadParams struct {
 	BookHash string `json:"hash" jsonschema:"MD5 hash of the book to download"`
 	Title    string `json:"title" jsonschema:"Book title, used for filename"`
 	Format   string `json:"format" jsonschema:"Book format, for example pdf or epub"`
+	Save     bool   `json:"save,omitempty" jsonschema:"Save the file to the server's download path instead of returning a link"`
+	Server   string `json:"server,omitempty" jsonschema:"Fast partner server to download from, by index as reported by speedtest or by domain; picked by the fast download API by default"`
+}
+
+type BestMatchParams struct {
+	Title    string `json:"title,omitempty" jsonschema:"Title of the wanted book"`
+	Author   string `json:"author,omitempty" jsonschema:"Author of the wanted book"`
+	ISBN     string `json:"isbn,omitempty" jsonschema:"ISBN of the wanted book, searched before the title and author"`
+	Format   string `json:"format,omitempty" jsonschema:"Wanted format, for example epub; other formats are only picked when no result has it (default: ANNAS_PREFERRED_FORMATS)"`
+	Language string `json:"language,omitempty" jsonschema:"Wanted language as ISO 639-1 code or name; other languages are only picked when no result has it (default: ANNAS_PREFERRED_LANGS)"`
+}
+
+type RefreshDownloadURLParams struct {
+	BookHash string `json:"hash" jsonschema:"MD5 hash of the book whose download link expired"`
+	Title    string `json:"title,omitempty" jsonschema:"Book title, used for the link text"`
+}
+
+type DownloadPaperParams struct {
+	DOI  string `json:"doi" jsonschema:"DOI of the paper, for example 10.1038/nature12373"`
+	Save bool   `json:"save,omitempty" jsonschema:"Save the file to the server's download path instead of returning a link"`
+}
+
+type ExtractTextParams struct {
+	BookHash  string `json:"hash" jsonschema:"MD5 hash of a book saved to the download path"`
+	Limit     int    `json:"limit,omitempty" jsonschema:"Maximum number of characters returned (default: 100000)"`
+	FirstPage int    `json:"first_page,omitempty" jsonschema:"First page to OCR when the book is a scanned PDF without a text layer (default: 1)"`
+	Pages     int    `json:"pages,omitempty" jsonschema:"Number of pages to OCR (default and maximum: ANNAS_OCR_MAX_PAGES)"`
+}
+
+type SearchInsideBookParams struct {
+	BookHash string `json:"hash" jsonschema:"MD5 hash of a book saved to the download path"`
+	Query    string `json:"query" jsonschema:"Phrase to search for, ignoring case and line breaks"`
+	Limit    int    `json:"limit,omitempty" jsonschema:"Maximum number of passages returned (default: 20)"`
+}
+
+type RecommendSimilarParams struct {
+	BookHash string `json:"hash,omitempty" jsonschema:"MD5 hash of the book to recommend from"`
+	Title    string `json:"title,omitempty" jsonschema:"Title of the book to recommend from, when no hash is given"`
+	Author   string `json:"author,omitempty" jsonschema:"Author of the book to recommend from, narrowing the title search"`
+	Limit    int    `json:"limit,omitempty" jsonschema:"Maximum number of recommendations (default: 10)"`
+}
+
+type MetadataParams struct {
+	BookHash          string `json:"hash" jsonschema:"MD5 hash of the book"`
+	Enrich            bool   `json:"enrich,omitempty" jsonschema:"Augment the record with OpenLibrary data (description, subjects, series) looked up by ISBN"`
+	DescriptionLength int    `json:"description_length,omitempty" jsonschema:"Truncate the description to this many characters (default 1000, -1 for the whole text)"`
+}
+
+type WantToReadParams struct {
+	Source   string `json:"source" jsonschema:"Shelf source: goodreads or hardcover"`
+	CSV      string `json:"csv,omitempty" jsonschema:"Contents of a Goodreads library export CSV, required for the goodreads source"`
+	Download bool   `json:"download,omitempty" jsonschema:"Save the best match of every entry to the download path"`
+}
+
+type ReadingListParams struct {
+	List          string  `json:"list" jsonschema:"Contents of the reading list: a CSV file or Markdown table with title and optional author and ISBN columns, or a Markdown list of 'Title by Author' items"`
+	Download      bool    `json:"download,omitempty" jsonschema:"Queue the confident matches for download to the download path"`
+	MinConfidence float64 `json:"min_confidence,omitempty" jsonschema:"Confidence between 0 and 1 a match needs to be downloaded (default: 0.6)"`
+}
+
+type CompleteSeriesParams struct {
+	BookHash string `json:"hash,omitempty" jsonschema:"MD5 hash of a book of the series"`
+	Title    string `json:"title,omitempty" jsonschema:"Title of a book of the series, when no hash is given"`
+	Author   string `json:"author,omitempty" jsonschema:"Author of the book, narrowing the title search"`
+	Download bool   `json:"download,omitempty" jsonschema:"Queue the volumes missing from the library for download to the download path"`
+}
+
+type QuotaParams struct{}
+
+type SpeedTestParams struct {
+	BookHash string `json:"hash" jsonschema:"MD5 hash of a record to download the samples from"`
+	SampleKB int    `json:"sample_kb,omitempty" jsonschema:"Kilobytes downloaded from every server, 1024 by default and at most 16384"`
+}
+
+type ListFiltersParams struct{}
+
+type ServerInfoParams struct{}
+
+type ServerStatsParams struct{}
+
+type UsageParams struct {
+	All bool `json:"all,omitempty" jsonschema:"List the usage of every token instead of your own, for admins"`
+}
+
+type MirrorStatusParams struct {
+	Refresh bool `json:"refresh,omitempty" jsonschema:"Probe all mirrors now instead of reporting the last background probes; requires the admin scope"`
+}
+
+type ListTorrentsParams struct {
+	Group string `json:"group,omitempty" jsonschema:"Only list the torrents of collections whose name contains this text, for example libgen or zlib"`
+}
+
+type ScheduleAddParams struct {
+	Query string `json:"query" jsonschema:"Search term run on every activation"`
+	Cron  string `json:"cron" jsonschema:"Five-field cron expression such as '0 8 * * *', or @hourly, @daily, @weekly, @monthly"`
+}
+
+type ScheduleListParams struct{}
+
+type ScheduleRemoveParams struct {
+	ID string `json:"id" jsonschema:"ID of the schedule, as returned by schedule_add or schedule_list"`
+}
+
+type OfflineSearchParams struct {
+	SearchTerm string `json:"term" jsonschema:"Term to search for in the title, authors, and publisher"`
+	Limit      int    `json:"limit,omitempty" jsonschema:"Maximum number of results, 20 by default"`
 }
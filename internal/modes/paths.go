@@ -0,0 +1,123 @@
+package modes
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/config"
+	"github.com/iosifache/annas-mcp/internal/i18n"
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/paths"
+)
+
+// pathEntry is a file or folder used by annas-mcp, as reported by the paths
+// command.
+type pathEntry struct {
+	Name string `json:"name"`
+	// Path is empty when the file is not used, and Setting names where it
+	// is configured
+	Path    string `json:"path"`
+	Setting string `json:"setting,omitempty"`
+	Exists  bool   `json:"exists"`
+}
+
+func newPathEntry(name, path, setting string) pathEntry {
+	entry := pathEntry{Name: name, Path: path, Setting: setting}
+	if path != "" {
+		_, err := os.Stat(path)
+		entry.Exists = err == nil
+	}
+
+	return entry
+}
+
+// pathEntries lists the base directories of annas-mcp, then every file of
+// env along with the config and dotenv files it was loaded from. Without
+// them, the files looked up in the config directory are listed instead.
+func pathEntries(env *Env, configFile, envFile string) []pathEntry {
+	if configFile == "" {
+		configFile = paths.File(paths.Config, "config.json")
+	}
+	if envFile == "" {
+		envFile = paths.File(paths.Config, ".env")
+	}
+
+	entries := make([]pathEntry, 0, 20)
+	for _, kind := range paths.Kinds {
+		entries = append(entries, newPathEntry(kind.String()+" directory", paths.Dir(kind), "XDG_"+strings.ToUpper(kind.String())+"_HOME"))
+	}
+
+	stats := ""
+	if env.StatsFile != "off" {
+		stats = statsStore(env).Path()
+	}
+	libraryIndex, downloadQueue := "", ""
+	if env.DownloadPath != "" {
+		libraryIndex = filepath.Join(env.DownloadPath, library.IndexFile)
+		downloadQueue = filepath.Join(env.DownloadPath, library.QueueFile)
+	}
+
+	return append(entries,
+		newPathEntry("config file", configFile, "--config, "+config.ConfigFileEnv),
+		newPathEntry("env file", envFile, "--env-file"),
+		newPathEntry("download path", env.DownloadPath, "ANNAS_DOWNLOAD_PATH"),
+		newPathEntry("library index", libraryIndex, "ANNAS_DOWNLOAD_PATH"),
+		newPathEntry("download queue", downloadQueue, "ANNAS_DOWNLOAD_PATH"),
+		newPathEntry("offline index", env.OfflineIndex, "ANNAS_OFFLINE_INDEX"),
+		newPathEntry("usage stats", stats, "ANNAS_STATS_FILE"),
+		newPathEntry("release cache", paths.File(paths.Cache, releaseCache), ""),
+		newPathEntry("audit log", env.AuditLog, "ANNAS_AUDIT_LOG"),
+		newPathEntry("cookie file", env.CookieFile, "ANNAS_COOKIE_FILE"),
+		newPathEntry("schedules file", env.SchedulesFile, "ANNAS_SCHEDULES_FILE"),
+		newPathEntry("usage counters", env.UsageFile, "ANNAS_USAGE_FILE"),
+		newPathEntry("tokens file", env.TokensFile, "ANNAS_TOKENS_FILE"),
+		newPathEntry("OAuth sessions", env.OAuthSessionsFile, "ANNAS_OAUTH_SESSIONS_FILE"),
+	)
+}
+
+// pathsText renders entries as aligned lines, marking files that do not exist
+// yet and the ones that are not used.
+func pathsText(entries []pathEntry) string {
+	width := 0
+	for _, entry := range entries {
+		width = max(width, displayWidth(i18n.T(entry.Name)))
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		name := i18n.T(entry.Name)
+		fmt.Fprintf(&b, "%s:%s ", name, strings.Repeat(" ", width-displayWidth(name)))
+		switch {
+		case entry.Path == "" && entry.Setting == "":
+			fmt.Fprint(&b, i18n.T("unknown"))
+		case entry.Path == "":
+			fmt.Fprint(&b, i18n.Sprintf("not used, set %s", entry.Setting))
+		case entry.Exists:
+			fmt.Fprint(&b, entry.Path)
+		default:
+			fmt.Fprint(&b, entry.Path, " ", i18n.T("(not created yet)"))
+		}
+		fmt.Fprintln(&b)
+	}
+
+	return b.String()
+}
+
+// displayWidth returns the columns s takes in a terminal, where the CJK
+// characters of the translations take two.
+func displayWidth(s string) int {
+	width := 0
+	for _, r := range s {
+		switch {
+		case r >= 0x1100 && r <= 0x115f, r >= 0x2e80 && r <= 0xa4cf, r >= 0xac00 && r <= 0xd7a3,
+			r >= 0xf900 && r <= 0xfaff, r >= 0xfe30 && r <= 0xfe4f, r >= 0xff00 && r <= 0xff60, r >= 0xffe0 && r <= 0xffe6:
+			width += 2
+		default:
+			width++
+		}
+	}
+
+	return width
+}
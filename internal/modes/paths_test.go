@@ -0,0 +1,55 @@
+package modes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/config"
+	"github.com/iosifache/annas-mcp/internal/paths"
+	"github.com/iosifache/annas-mcp/internal/version"
+)
+
+func TestReleaseCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	if release := loadCachedRelease(); release != nil {
+		t.Fatalf("Expected no cached release, got %+v", release)
+	}
+	saveCachedRelease(&version.Release{Version: "v9.9.9", URL: "https://example.com/v9.9.9"})
+	if release := loadCachedRelease(); release == nil || release.Version != "v9.9.9" {
+		t.Errorf("Expected the cached release, got %+v", release)
+	}
+
+	expired := `{"checked": "2020-01-01T00:00:00Z", "release": {"version": "v9.9.9"}}`
+	os.WriteFile(paths.File(paths.Cache, releaseCache), []byte(expired), 0o644)
+	if release := loadCachedRelease(); release != nil {
+		t.Errorf("Expected an expired release to be looked up again, got %+v", release)
+	}
+}
+
+func TestPathEntries(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	env := config.Defaults()
+	env.DownloadPath = t.TempDir()
+	env.StatsFile = "off"
+	entries := make(map[string]pathEntry)
+	for _, entry := range pathEntries(env, "", "") {
+		entries[entry.Name] = entry
+	}
+
+	if entry := entries["config file"]; entry.Path != filepath.Join(dir, "annas-mcp", "config.json") || entry.Exists {
+		t.Errorf("Expected the config file looked up in the config directory, got %+v", entry)
+	}
+	if entry := entries["download path"]; !entry.Exists {
+		t.Errorf("Expected the download path to exist, got %+v", entry)
+	}
+	if entry := entries["usage stats"]; entry.Path != "" {
+		t.Errorf("Expected no stats file with ANNAS_STATS_FILE=off, got %+v", entry)
+	}
+	if entry := entries["audit log"]; entry.Path != "" || entry.Setting != "ANNAS_AUDIT_LOG" {
+		t.Errorf("Expected the audit log to be unused, got %+v", entry)
+	}
+}
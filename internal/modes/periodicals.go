@@ -0,0 +1,82 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// SearchMagazinesToolHandler searches magazine issues on Anna's Archive.
+func SearchMagazinesToolHandler(ctx context.Context, req *mcp.CallToolRequest, params SearchParams) (*mcp.CallToolResult, any, error) {
+	return searchPeriodicals(ctx, params, anna.ContentMagazine, "magazines")
+}
+
+// SearchComicsToolHandler searches comic issues on Anna's Archive.
+func SearchComicsToolHandler(ctx context.Context, req *mcp.CallToolRequest, params SearchParams) (*mcp.CallToolResult, any, error) {
+	return searchPeriodicals(ctx, params, anna.ContentComic, "comics")
+}
+
+func searchPeriodicals(ctx context.Context, params SearchParams, content, kind string) (*mcp.CallToolResult, any, error) {
+	l := logger.GetLogger()
+
+	l.Info("Search periodicals command called",
+		zap.String("searchTerm", params.SearchTerm),
+		zap.String("content", content),
+		zap.Strings("languages", params.Languages),
+		zap.Strings("formats", params.Formats),
+	)
+
+	languages, err := parseLanguages(params.Languages)
+	if err != nil {
+		l.Error("Search periodicals command failed", zap.Error(err))
+		return nil, nil, err
+	}
+	formats, err := parseFormats(params.Formats)
+	if err != nil {
+		l.Error("Search periodicals command failed", zap.Error(err))
+		return nil, nil, err
+	}
+	if err := chargeUsage(ctx, usage.KindSearch); err != nil {
+		l.Error("Search periodicals command failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	issues := make([]*anna.Periodical, 0)
+	err = anna.StreamPeriodicalsFiltered(params.SearchTerm, anna.Filters{Content: content, Languages: languages, Formats: formats}, func(issue *anna.Periodical) bool {
+		issues = append(issues, issue)
+		return ctx.Err() == nil
+	})
+	if err != nil {
+		l.Error("Search periodicals command failed",
+			zap.String("searchTerm", params.SearchTerm),
+			zap.String("content", content),
+			zap.Error(err),
+		)
+		return nil, nil, err
+	}
+
+	metrics.Searches.Inc()
+	l.Info("Search periodicals command completed successfully",
+		zap.String("searchTerm", params.SearchTerm),
+		zap.String("content", content),
+		zap.Int("resultsCount", len(issues)),
+	)
+
+	text := fmt.Sprintf("No %s found for %q.", kind, params.SearchTerm)
+	if len(issues) > 0 {
+		text = ""
+		for _, issue := range issues {
+			text += issue.String() + "\n\n"
+		}
+	}
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text}},
+	}, map[string]interface{}{kind: issues}, nil
+}
@@ -0,0 +1,169 @@
+package modes
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// prefetchTTL bounds how long a prefetched link is served from the cache,
+// well below the lifetime of fast download links.
+const prefetchTTL = 15 * time.Minute
+
+// maxPrefetches bounds the links resolved at once across all searches. Books
+// found while every slot is taken are not prefetched.
+const maxPrefetches = 4
+
+type prefetchEntry struct {
+	info    *anna.DownloadInfo
+	expires time.Time
+}
+
+// Prefetched links are keyed by hash and the keys that resolved them, so a
+// link paid for by one tenant's key is never handed to another tenant.
+var (
+	prefetchMu    sync.Mutex
+	prefetchCache = make(map[string]prefetchEntry)
+	// prefetching holds the keys being resolved, so that repeated searches
+	// do not resolve the same link twice
+	prefetching   = make(map[string]bool)
+	prefetchSlots = make(chan struct{}, maxPrefetches)
+)
+
+func prefetchKey(env *Env, hash string) string {
+	return hash + "\x00" + strings.Join(env.Keys(), "\x00")
+}
+
+// cachedDownload returns a prefetched link for the book, if one is still fresh.
+// Stateless deployments keep no links.
+func cachedDownload(env *Env, book *anna.Book) *anna.DownloadInfo {
+	if env.Stateless {
+		return nil
+	}
+
+	prefetchMu.Lock()
+	defer prefetchMu.Unlock()
+
+	key := prefetchKey(env, book.Hash)
+	entry, ok := prefetchCache[key]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(entry.expires) {
+		delete(prefetchCache, key)
+		return nil
+	}
+
+	return entry.info
+}
+
+// prefetchDownloads resolves the fast download links of the first
+// env.PrefetchCount books in the background, for the caller of ctx. Fast
+// download API calls may be counted as downloads, so nothing is prefetched
+// for callers that may not download or whose downloads are limited, since
+// the prefetches could use up their allowance on books they never fetch.
+func prefetchDownloads(ctx context.Context, env *Env, books []*anna.Book) {
+	l := logger.GetLogger()
+
+	if env.PrefetchCount <= 0 || env.Stateless || len(env.Keys()) == 0 {
+		return
+	}
+	if !mayPrefetch(ctx, env) {
+		return
+	}
+	if len(books) > env.PrefetchCount {
+		books = books[:env.PrefetchCount]
+	}
+
+	for _, book := range books {
+		if cachedDownload(env, book) != nil {
+			continue
+		}
+
+		key := prefetchKey(env, book.Hash)
+		prefetchMu.Lock()
+		if prefetching[key] {
+			prefetchMu.Unlock()
+			continue
+		}
+		select {
+		case prefetchSlots <- struct{}{}:
+			prefetching[key] = true
+		default:
+			prefetchMu.Unlock()
+			return
+		}
+		prefetchMu.Unlock()
+
+		go func(book *anna.Book) {
+			defer func() {
+				prefetchMu.Lock()
+				delete(prefetching, key)
+				prefetchMu.Unlock()
+				<-prefetchSlots
+			}()
+
+			info, err := resolveUncached(env, book)
+			if err != nil {
+				l.Debug("Failed to prefetch download link", zap.String("bookHash", book.Hash), zap.Error(err))
+				return
+			}
+
+			cacheDownload(env, book, info)
+		}(book)
+	}
+}
+
+// mayPrefetch reports whether links may be prefetched for the caller of ctx:
+// callers with the download scope, when neither download rate limits nor a
+// download quota of their token apply.
+func mayPrefetch(ctx context.Context, env *Env) bool {
+	if env.DownloadsPerHour > 0 || env.DownloadsPerDay > 0 {
+		return false
+	}
+
+	caller := auth.CallerFrom(ctx)
+	if !caller.Scopes.Has(auth.ScopeDownload) {
+		return false
+	}
+	if quota := caller.Quota; quota != nil && (quota.DownloadsPerDay > 0 || quota.DownloadsPerMonth > 0) {
+		return false
+	}
+
+	return true
+}
+
+// linkExpiryMargin is how long before its stated expiry a link stops being
+// served from the cache, leaving the client time to fetch it.
+const linkExpiryMargin = 2 * time.Minute
+
+// cacheDownload keeps a resolved link for the book, dropping expired ones.
+// Links that state an expiry are kept until shortly before it at most.
+func cacheDownload(env *Env, book *anna.Book, info *anna.DownloadInfo) {
+	if env.Stateless {
+		return
+	}
+
+	prefetchMu.Lock()
+	defer prefetchMu.Unlock()
+
+	now := time.Now()
+	for key, entry := range prefetchCache {
+		if now.After(entry.expires) {
+			delete(prefetchCache, key)
+		}
+	}
+	expires := now.Add(prefetchTTL)
+	if info.ExpiresAt != nil {
+		if linkExpires := info.ExpiresAt.Add(-linkExpiryMargin); linkExpires.Before(expires) {
+			expires = linkExpires
+		}
+	}
+	prefetchCache[prefetchKey(env, book.Hash)] = prefetchEntry{info: info, expires: expires}
+}
@@ -0,0 +1,131 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+)
+
+func TestPrefetchCache(t *testing.T) {
+	book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45"}
+	tenant := &Env{SecretKey: "tenant-key"}
+	other := &Env{SecretKey: "other-key"}
+
+	t.Run("Keys", func(t *testing.T) {
+		cacheDownload(tenant, book, &anna.DownloadInfo{URL: "https://example.org/tenant"})
+		if info := cachedDownload(tenant, book); info == nil || info.URL != "https://example.org/tenant" {
+			t.Errorf("Expected the link of the tenant, got %v", info)
+		}
+		if info := cachedDownload(other, book); info != nil {
+			t.Errorf("Expected no link for another key, got %v", info)
+		}
+		if info := cachedDownload(tenant, &anna.Book{Hash: "00000000000000000000000000000000"}); info != nil {
+			t.Errorf("Expected no link for another book, got %v", info)
+		}
+	})
+
+	t.Run("TTL", func(t *testing.T) {
+		cacheDownload(tenant, book, &anna.DownloadInfo{URL: "https://example.org/tenant"})
+		prefetchMu.Lock()
+		entry := prefetchCache[prefetchKey(tenant, book.Hash)]
+		entry.expires = time.Now().Add(-time.Second)
+		prefetchCache[prefetchKey(tenant, book.Hash)] = entry
+		prefetchMu.Unlock()
+
+		if info := cachedDownload(tenant, book); info != nil {
+			t.Errorf("Expected the expired link to be dropped, got %v", info)
+		}
+	})
+
+	t.Run("LinkExpiry", func(t *testing.T) {
+		// Links expiring within the margin are never served
+		expires := time.Now().Add(time.Minute)
+		cacheDownload(tenant, book, &anna.DownloadInfo{URL: "https://example.org/tenant", ExpiresAt: &expires})
+		if info := cachedDownload(tenant, book); info != nil {
+			t.Errorf("Expected the link expiring soon not to be served, got %v", info)
+		}
+	})
+}
+
+func TestPrefetchDownloads(t *testing.T) {
+	var resolving, peak atomic.Int32
+	release := make(chan struct{})
+	var upstream *httptest.Server
+	upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := resolving.Add(1)
+		defer resolving.Add(-1)
+		for {
+			previous := peak.Load()
+			if current <= previous || peak.CompareAndSwap(previous, current) {
+				break
+			}
+		}
+		<-release
+		fmt.Fprintf(w, `{"download_url": "%s/file.pdf"}`, upstream.URL)
+	}))
+	defer upstream.Close()
+
+	if err := anna.Configure(anna.Options{Mirrors: []string{upstream.URL}}); err != nil {
+		t.Fatalf("Failed to configure client: %v", err)
+	}
+	defer anna.Configure(anna.Options{})
+
+	books := make([]*anna.Book, 10)
+	for i := range books {
+		books[i] = &anna.Book{Hash: fmt.Sprintf("%032x", i+1)}
+	}
+	env := &Env{SecretKey: "prefetch-key", PrefetchCount: len(books)}
+	for _, book := range books {
+		prefetchMu.Lock()
+		delete(prefetchCache, prefetchKey(env, book.Hash))
+		prefetchMu.Unlock()
+	}
+
+	t.Run("Limits", func(t *testing.T) {
+		limited := *env
+		limited.DownloadsPerDay = 10
+		prefetchDownloads(context.Background(), &limited, books)
+		searchOnly := auth.WithCaller(context.Background(), auth.Caller{Name: "reader", Scopes: auth.Scopes{auth.ScopeSearch}})
+		prefetchDownloads(searchOnly, env, books)
+		quota := auth.WithCaller(context.Background(), auth.Caller{Name: "club", Scopes: auth.AllScopes, Quota: &auth.Quota{DownloadsPerMonth: 5}})
+		prefetchDownloads(quota, env, books)
+
+		if len(prefetchSlots) != 0 {
+			t.Errorf("Expected no prefetch under download limits, got %d", len(prefetchSlots))
+		}
+	})
+
+	t.Run("Concurrency", func(t *testing.T) {
+		// Repeated searches share the slots
+		prefetchDownloads(context.Background(), env, books)
+		prefetchDownloads(context.Background(), env, books)
+		if got := len(prefetchSlots); got != maxPrefetches {
+			t.Errorf("Expected %d prefetches in progress, got %d", maxPrefetches, got)
+		}
+
+		close(release)
+		for deadline := time.Now().Add(5 * time.Second); len(prefetchSlots) > 0 && time.Now().Before(deadline); {
+			time.Sleep(time.Millisecond)
+		}
+
+		if got := peak.Load(); got > maxPrefetches {
+			t.Errorf("Expected at most %d links resolved at once, got %d", maxPrefetches, got)
+		}
+		cached := 0
+		for _, book := range books {
+			if cachedDownload(env, book) != nil {
+				cached++
+			}
+		}
+		if cached != maxPrefetches {
+			t.Errorf("Expected %d prefetched links, got %d", maxPrefetches, cached)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+)
+
+// Progress modes of the CLI download commands.
+const (
+	progressNone = "none"
+	progressJSON = "json"
+)
+
+// Types of the progress events.
+const (
+	progressEventProgress  = "progress"
+	progressEventCompleted = "completed"
+	progressEventFailed    = "failed"
+)
+
+// fetchProgress is called while the built-in downloader receives a file. It is
+// only set by the CLI, for which downloads run one at a time.
+var fetchProgress func(book *anna.Book, progress anna.Progress)
+
+// progressEvent is one line of the NDJSON progress output. Percent and ETA
+// are left out while the size of the file is unknown.
+type progressEvent struct {
+	Event          string   `json:"event"`
+	Hash           string   `json:"hash"`
+	Bytes          int64    `json:"bytes,omitempty"`
+	Total          int64    `json:"total,omitempty"`
+	Percent        *float64 `json:"percent,omitempty"`
+	BytesPerSecond float64  `json:"bytes_per_second,omitempty"`
+	ETASeconds     *float64 `json:"eta_seconds,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// progressWriter writes progress events as NDJSON.
+type progressWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+func newProgressWriter(w io.Writer) *progressWriter {
+	return &progressWriter{enc: json.NewEncoder(w)}
+}
+
+func (p *progressWriter) write(event progressEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Progress is best effort, a closed pipe must not fail the download
+	_ = p.enc.Encode(event)
+}
+
+// progress reports how far the download of book got.
+func (p *progressWriter) progress(book *anna.Book, progress anna.Progress) {
+	event := progressEvent{
+		Event:          progressEventProgress,
+		Hash:           book.Hash,
+		Bytes:          progress.Bytes,
+		Total:          progress.Total,
+		BytesPerSecond: progress.BytesPerSecond(),
+	}
+	if percent := progress.Percent(); percent >= 0 {
+		event.Percent = &percent
+	}
+	if eta := progress.ETA(); eta >= 0 {
+		seconds := eta.Seconds()
+		event.ETASeconds = &seconds
+	}
+
+	p.write(event)
+}
+
+// result reports the outcome of the download of book.
+func (p *progressWriter) result(book *anna.Book, path string, err error) {
+	if err != nil {
+		p.write(progressEvent{Event: progressEventFailed, Hash: book.Hash, Error: err.Error()})
+		return
+	}
+
+	p.write(progressEvent{Event: progressEventCompleted, Hash: book.Hash, Path: path})
+}
+
+// startProgress enables the progress output selected by mode. Progress goes
+// to stderr, so the result printed on stdout stays parseable. The returned
+// writer is nil when no progress is reported.
+func startProgress(mode string) (*progressWriter, error) {
+	switch mode {
+	case "", progressNone:
+		return nil, nil
+	case progressJSON:
+		writer := newProgressWriter(os.Stderr)
+		fetchProgress = writer.progress
+		return writer, nil
+	}
+
+	return nil, fmt.Errorf("invalid progress mode %q: expected %s or %s", mode, progressNone, progressJSON)
+}
@@ -0,0 +1,265 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/fsutil"
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/state"
+	"go.uber.org/zap"
+)
+
+// queuedDownload is a book waiting in saveQueued, as kept in
+// library.QueueFile.
+type queuedDownload struct {
+	Book   *anna.Book `json:"book"`
+	Source string     `json:"source"`
+	Caller string     `json:"caller,omitempty"`
+	// DownloadPath is the download path the book was queued for, which may
+	// be a per-request path inside the operator's
+	DownloadPath string `json:"download_path,omitempty"`
+	// Key is the hash of the secret key of the download, never the key
+	// itself, so downloads of callers bringing their own key are not resumed
+	// with the operator's
+	Key string `json:"key,omitempty"`
+}
+
+// downloadQueue tracks the downloads saveQueued runs, so a shutdown can stop
+// it from starting new ones, wait for the ones in progress, and keep the
+// others for the next start.
+type downloadQueue struct {
+	mu sync.Mutex
+	// pending are in the order they were queued
+	pending  []*queuedDownload
+	stopping bool
+	running  sync.WaitGroup
+}
+
+var queue = &downloadQueue{}
+
+// add records books queued by the caller of ctx from source, to be saved
+// with env. Every call must be followed by done.
+func (q *downloadQueue) add(ctx context.Context, env *Env, books []*anna.Book, source string) []*queuedDownload {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := ""
+	if env.SecretKey != "" {
+		key = auth.Hash(env.SecretKey)
+	}
+
+	q.running.Add(1)
+	entries := make([]*queuedDownload, len(books))
+	for i, book := range books {
+		entries[i] = &queuedDownload{
+			Book:         book,
+			Source:       source,
+			Caller:       auth.CallerFrom(ctx).Name,
+			DownloadPath: env.DownloadPath,
+			Key:          key,
+		}
+	}
+	q.pending = append(q.pending, entries...)
+
+	return entries
+}
+
+// finish forgets entry once it was saved or failed.
+func (q *downloadQueue) finish(entry *queuedDownload) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if i := slices.Index(q.pending, entry); i >= 0 {
+		q.pending = slices.Delete(q.pending, i, i+1)
+	}
+}
+
+// done ends a call of add.
+func (q *downloadQueue) done() {
+	q.running.Done()
+}
+
+// stopped reports whether the queue is draining, in which case no further
+// download may start.
+func (q *downloadQueue) stopped() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.stopping
+}
+
+// drain stops new downloads from starting and waits until the ones in
+// progress end or ctx is done. It returns the downloads left, in the order
+// they were queued, including the ones still in progress.
+func (q *downloadQueue) drain(ctx context.Context) []*queuedDownload {
+	q.mu.Lock()
+	q.stopping = true
+	q.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return slices.Clone(q.pending)
+}
+
+// flushQueue writes the downloads left by drain to the queue file of the
+// download path, or removes the file when none are left.
+func flushQueue(downloadPath string, left []*queuedDownload) error {
+	file := filepath.Join(downloadPath, library.QueueFile)
+	if len(left) == 0 {
+		if err := os.Remove(file); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(left, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return state.WriteFile(file, data)
+}
+
+// queuedBatch is a group of downloads left by a shutdown that saveQueued
+// resumes together.
+type queuedBatch struct {
+	caller string
+	source string
+	env    *Env
+	books  []*anna.Book
+}
+
+// resumableBatches groups the downloads of left that may be resumed with env
+// by caller, source, and download path, in the order they were queued.
+// Downloads are dropped when they used a key of their caller rather than one
+// of env, when their download path is no longer inside the one of env, or
+// when their caller is neither the operator nor a token of the token store
+// that may still download. It returns how many were dropped.
+func resumableBatches(env *Env, left []*queuedDownload) ([]*queuedBatch, int) {
+	keys := make(map[string]bool)
+	for _, key := range env.Keys() {
+		keys[auth.Hash(key)] = true
+	}
+
+	batches := make([]*queuedBatch, 0)
+	dropped := 0
+	for _, entry := range left {
+		if entry.Book == nil {
+			continue
+		}
+		if (entry.Key != "" && !keys[entry.Key]) || !mayResume(entry.Caller) {
+			dropped++
+			continue
+		}
+		downloadPath := env.DownloadPath
+		if entry.DownloadPath != "" {
+			confined, err := fsutil.Confine(env.DownloadPath, entry.DownloadPath)
+			if err != nil {
+				dropped++
+				continue
+			}
+			downloadPath = confined
+		}
+
+		i := slices.IndexFunc(batches, func(b *queuedBatch) bool {
+			return b.caller == entry.Caller && b.source == entry.Source && b.env.DownloadPath == downloadPath
+		})
+		if i < 0 {
+			batchEnv := *env
+			batchEnv.DownloadPath = downloadPath
+			batches = append(batches, &queuedBatch{caller: entry.Caller, source: entry.Source, env: &batchEnv})
+			i = len(batches) - 1
+		}
+		batches[i].books = append(batches[i].books, entry.Book)
+	}
+
+	return batches, dropped
+}
+
+// mayResume reports whether downloads queued by the caller named name may
+// be resumed: only the operator and local callers, and tokens of the token
+// store with the download scope, can be checked without their credentials.
+func mayResume(name string) bool {
+	if name == "" || name == auth.Operator.Name || name == auth.Local.Name {
+		return true
+	}
+	store := tokenStore.Load()
+	if store == nil {
+		return false
+	}
+	for _, token := range store.Tokens() {
+		if token.Name == name {
+			return token.Scopes.Has(auth.ScopeDownload)
+		}
+	}
+
+	return false
+}
+
+// resumeQueue downloads the books flushQueue kept in the download path of env
+// in the background, with the configuration of env, and removes the file.
+// Only the downloads resumableBatches accepts are resumed.
+func resumeQueue(env *Env) error {
+	l := logger.GetLogger()
+
+	file := filepath.Join(env.DownloadPath, library.QueueFile)
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var left []*queuedDownload
+	if err := json.Unmarshal(data, &left); err != nil {
+		return fmt.Errorf("failed to read the download queue %s: %w", file, err)
+	}
+	if err := os.Remove(file); err != nil {
+		return err
+	}
+
+	// Books keep the caller and source they were queued by, for the audit log
+	batches, dropped := resumableBatches(env, left)
+	if dropped > 0 {
+		l.Warn("Dropping queued downloads that cannot be resumed with the server configuration", zap.Int("count", dropped))
+	}
+
+	l.Info("Resuming queued downloads", zap.String("file", file), zap.Int("count", len(left)-dropped))
+	for _, batch := range batches {
+		ctx := context.Background()
+		caller := auth.Caller{Name: batch.caller}
+		switch batch.caller {
+		case auth.Operator.Name:
+			caller = auth.Operator
+		case auth.Local.Name:
+			caller = auth.Local
+		}
+		if batch.caller != "" {
+			ctx = auth.WithCaller(ctx, caller)
+		}
+		go saveQueued(ctx, batch.env, batch.books, batch.source)
+	}
+
+	return nil
+}
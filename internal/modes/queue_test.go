@@ -0,0 +1,124 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/library"
+)
+
+func TestDownloadQueue(t *testing.T) {
+	q := &downloadQueue{}
+	ctx := auth.WithCaller(context.Background(), auth.Caller{Name: "reader"})
+	books := []*anna.Book{{Hash: "1", Title: "Dune"}, {Hash: "2", Title: "Emma"}, {Hash: "3", Title: "Ulysses"}}
+
+	entries := q.add(ctx, &Env{SecretKey: "operator-key", DownloadPath: t.TempDir()}, books, auditSourceSeries)
+	q.finish(entries[0])
+	if q.stopped() {
+		t.Fatal("Expected the queue to run until drained")
+	}
+
+	// The download of the second book is still in progress
+	drained, cancel := context.WithCancel(context.Background())
+	cancel()
+	left := q.drain(drained)
+	if !q.stopped() {
+		t.Error("Expected the drained queue to stop")
+	}
+	if len(left) != 2 || left[0].Book.Hash != "2" || left[1].Book.Hash != "3" {
+		t.Fatalf("Expected the second and third books to be left, got %v", left)
+	}
+	q.done()
+
+	dir := t.TempDir()
+	if err := flushQueue(dir, left); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, library.QueueFile))
+	if err != nil {
+		t.Fatalf("Expected the queue file, got %v", err)
+	}
+	var kept []queuedDownload
+	if err := json.Unmarshal(data, &kept); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(kept) != 2 || kept[0].Caller != "reader" || kept[0].Source != auditSourceSeries || kept[1].Book.Title != "Ulysses" {
+		t.Errorf("Expected the left downloads with their caller and source, got %+v", kept)
+	}
+
+	if err := flushQueue(dir, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, library.QueueFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected an empty queue to remove the file, got %v", err)
+	}
+}
+
+func TestResumableBatches(t *testing.T) {
+	dir := t.TempDir()
+	tokens := filepath.Join(t.TempDir(), "tokens.json")
+	if err := os.WriteFile(tokens, []byte(`{"tokens":[{"name":"reader","token":"secret","scopes":["download"]},{"name":"searcher","token":"other","scopes":["search"]}]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store, err := auth.Load(tokens)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tokenStore.Store(store)
+	t.Cleanup(func() { tokenStore.Store(nil) })
+
+	operator := &Env{SecretKey: "operator-key", DownloadPath: dir}
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set("X-Annas-Secret-Key", "session-key")
+	session := operator.WithRequest(req)
+	req = httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	req.Header.Set("X-Annas-Download-Path", "reader")
+	confined := operator.WithRequest(req)
+
+	q := &downloadQueue{}
+	reader := auth.WithCaller(context.Background(), auth.Caller{Name: "reader"})
+	q.add(reader, session, []*anna.Book{{Hash: "1", Title: "Dune"}}, auditSourceSeries)
+	q.add(reader, confined, []*anna.Book{{Hash: "2", Title: "Emma"}}, auditSourceSeries)
+	q.add(context.Background(), operator, []*anna.Book{{Hash: "3", Title: "Ulysses"}}, auditSourceReadingList)
+	q.add(auth.WithCaller(context.Background(), auth.Caller{Name: "revoked"}), operator, []*anna.Book{{Hash: "4"}}, auditSourceSeries)
+	q.add(auth.WithCaller(context.Background(), auth.Caller{Name: "searcher"}), operator, []*anna.Book{{Hash: "5"}}, auditSourceSeries)
+
+	drained, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := flushQueue(dir, q.drain(drained)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, library.QueueFile))
+	if err != nil {
+		t.Fatalf("Expected the queue file, got %v", err)
+	}
+	if strings.Contains(string(data), "session-key") || strings.Contains(string(data), "operator-key") {
+		t.Error("Expected the queue file to keep no secret key")
+	}
+	var left []*queuedDownload
+	if err := json.Unmarshal(data, &left); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	batches, dropped := resumableBatches(operator, left)
+	if dropped != 3 {
+		t.Errorf("Expected the session-keyed, revoked, and search-only downloads to be dropped, got %d", dropped)
+	}
+	if len(batches) != 2 {
+		t.Fatalf("Expected two batches, got %d", len(batches))
+	}
+	if batches[0].caller != "reader" || batches[0].books[0].Hash != "2" || batches[0].env.DownloadPath != filepath.Join(dir, "reader") {
+		t.Errorf("Expected the confined download to resume into its own path, got %+v", batches[0])
+	}
+	if batches[1].caller != auth.Local.Name || batches[1].books[0].Hash != "3" || batches[1].env.DownloadPath != dir || batches[1].env.SecretKey != "operator-key" {
+		t.Errorf("Expected the operator download to resume with the operator configuration, got %+v", batches[1])
+	}
+}
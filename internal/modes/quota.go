@@ -0,0 +1,43 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/keyring"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// NewQuotaToolHandler creates a handler for the quota tool that reports the
+// usage of the secret keys configured in the provided environment.
+func NewQuotaToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, QuotaParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params QuotaParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+		l.Info("Quota command called")
+
+		usage := keyring.New(env.Keys()).Usage()
+		if len(usage) == 0 {
+			return nil, nil, errSecretKeyMissing
+		}
+
+		var text strings.Builder
+		for i, u := range usage {
+			fmt.Fprintf(&text, "Key %d (%s): %d downloads through this server", i+1, u.Key, u.Downloads)
+			if u.DownloadsLeft != nil && u.DownloadsPerDay != nil {
+				fmt.Fprintf(&text, ", %d of %d fast downloads left", *u.DownloadsLeft, *u.DownloadsPerDay)
+			} else {
+				text.WriteString(", allowance unknown until the first download")
+			}
+			if u.LastError != "" {
+				fmt.Fprintf(&text, ", last error: %s", u.LastError)
+			}
+			text.WriteString("\n")
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: text.String()}},
+		}, map[string]interface{}{"keys": usage}, nil
+	}
+}
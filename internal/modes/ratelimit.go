@@ -0,0 +1,183 @@
+package modes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/usage"
+)
+
+// errRateLimited is returned once a token or session used up its downloads.
+var errRateLimited = errors.New("download rate limit reached")
+
+// allowance is the number of downloads a token or session has left, with -1
+// meaning unlimited.
+type allowance struct {
+	Hour int `json:"hour"`
+	Day  int `json:"day"`
+}
+
+func (a allowance) String() string {
+	switch {
+	case a.Hour >= 0 && a.Day >= 0:
+		return fmt.Sprintf("%d this hour, %d today", a.Hour, a.Day)
+	case a.Hour >= 0:
+		return fmt.Sprintf("%d this hour", a.Hour)
+	default:
+		return fmt.Sprintf("%d today", a.Day)
+	}
+}
+
+// rateLimiter counts the downloads of every token or session over sliding
+// one hour and one day windows.
+type rateLimiter struct {
+	mu   sync.Mutex
+	hits map[string][]time.Time
+	// swept is when keys without downloads in the daily window were last
+	// dropped
+	swept time.Time
+}
+
+var downloadLimiter = &rateLimiter{hits: make(map[string][]time.Time)}
+
+// take records a download for key, unless it would exceed the limits. Limits
+// of 0 are unlimited. Failed downloads are counted too, since the fast
+// download may have been spent already.
+func (r *rateLimiter) take(key string, perHour, perDay int, now time.Time) (allowance, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sweep(now)
+
+	// Drop downloads that left the daily window
+	hits := r.hits[key]
+	for len(hits) > 0 && now.Sub(hits[0]) >= 24*time.Hour {
+		hits = hits[1:]
+	}
+	lastHour := 0
+	for _, hit := range hits {
+		if now.Sub(hit) < time.Hour {
+			lastHour++
+		}
+	}
+
+	if perHour > 0 && lastHour >= perHour {
+		r.hits[key] = hits
+		return allowance{}, fmt.Errorf("%w: %d downloads per hour (ANNAS_DOWNLOADS_PER_HOUR), try again later", errRateLimited, perHour)
+	}
+	if perDay > 0 && len(hits) >= perDay {
+		r.hits[key] = hits
+		return allowance{}, fmt.Errorf("%w: %d downloads per day (ANNAS_DOWNLOADS_PER_DAY), try again later", errRateLimited, perDay)
+	}
+
+	r.hits[key] = append(hits, now)
+
+	left := allowance{Hour: -1, Day: -1}
+	if perHour > 0 {
+		left.Hour = perHour - lastHour - 1
+	}
+	if perDay > 0 {
+		left.Day = perDay - len(hits) - 1
+	}
+	return left, nil
+}
+
+// sweep drops the keys whose downloads all left the daily window, at most
+// once an hour, so sessions that ended do not accumulate.
+func (r *rateLimiter) sweep(now time.Time) {
+	if now.Sub(r.swept) < time.Hour {
+		return
+	}
+	r.swept = now
+
+	for key, hits := range r.hits {
+		if len(hits) == 0 || now.Sub(hits[len(hits)-1]) >= 24*time.Hour {
+			delete(r.hits, key)
+		}
+	}
+}
+
+// peek returns what key has left under the limits without recording a
+// download.
+func (r *rateLimiter) peek(key string, perHour, perDay int, now time.Time) allowance {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	lastHour, lastDay := 0, 0
+	for _, hit := range r.hits[key] {
+		if now.Sub(hit) < 24*time.Hour {
+			lastDay++
+		}
+		if now.Sub(hit) < time.Hour {
+			lastHour++
+		}
+	}
+
+	left := allowance{Hour: -1, Day: -1}
+	if perHour > 0 {
+		left.Hour = max(perHour-lastHour, 0)
+	}
+	if perDay > 0 {
+		left.Day = max(perDay-lastDay, 0)
+	}
+	return left
+}
+
+// rateKey identifies the caller of ctx for rate limiting. Named tokens share
+// their limits across sessions, other callers are limited per session. Calls
+// without a session, such as the CLI and the indexer, and every call of a
+// stateless server, where each request is a new session, share the limits of
+// the operator or local caller.
+func rateKey(ctx context.Context, env *Env) string {
+	caller := auth.CallerFrom(ctx)
+	if caller.Name != auth.Operator.Name && caller.Name != auth.Local.Name {
+		return "token:" + caller.Name
+	}
+	if caller.Session == "" || env.Stateless {
+		return "caller:" + caller.Name
+	}
+
+	return "session:" + caller.Session
+}
+
+// takeDownload counts a download against the usage quota and the limits of
+// the caller of ctx. The returned allowance is nil when no limits are
+// configured.
+func takeDownload(ctx context.Context, env *Env) (*allowance, error) {
+	if err := chargeUsage(ctx, usage.KindDownload); err != nil {
+		return nil, err
+	}
+	if env.DownloadsPerHour == 0 && env.DownloadsPerDay == 0 {
+		return nil, nil
+	}
+
+	left, err := downloadLimiter.take(rateKey(ctx, env), env.DownloadsPerHour, env.DownloadsPerDay, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &left, nil
+}
+
+// remainingDownloads returns what the caller of ctx has left under the
+// limits, or nil when no limits are configured.
+func remainingDownloads(ctx context.Context, env *Env) *allowance {
+	if env.DownloadsPerHour == 0 && env.DownloadsPerDay == 0 {
+		return nil
+	}
+
+	left := downloadLimiter.peek(rateKey(ctx, env), env.DownloadsPerHour, env.DownloadsPerDay, time.Now())
+	return &left
+}
+
+// withAllowance appends the remaining allowance to a tool result text.
+func withAllowance(text string, left *allowance) string {
+	if left == nil {
+		return text
+	}
+
+	return fmt.Sprintf("%s\n\nRemaining downloads: %s", text, left)
+}
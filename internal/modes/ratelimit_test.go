@@ -0,0 +1,109 @@
+package modes
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := &rateLimiter{hits: make(map[string][]time.Time)}
+	now := time.Now()
+
+	for i := 0; i < 2; i++ {
+		left, err := limiter.take("session:a", 2, 3, now)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if left.Hour != 1-i || left.Day != 2-i {
+			t.Errorf("Expected %d left this hour and %d today, got %+v", 1-i, 2-i, left)
+		}
+	}
+
+	if left := limiter.peek("session:a", 2, 3, now); left.Hour != 0 || left.Day != 1 {
+		t.Errorf("Expected peek to report 0 left this hour and 1 today, got %+v", left)
+	}
+
+	if _, err := limiter.take("session:a", 2, 3, now); !errors.Is(err, errRateLimited) {
+		t.Errorf("Expected errRateLimited above the hourly limit, got %v", err)
+	}
+	if _, err := limiter.take("session:b", 2, 3, now); err != nil {
+		t.Errorf("Expected other sessions to be unaffected, got %v", err)
+	}
+
+	// An hour later only the daily limit applies
+	if _, err := limiter.take("session:a", 2, 3, now.Add(time.Hour)); err != nil {
+		t.Errorf("Unexpected error after an hour: %v", err)
+	}
+	if _, err := limiter.take("session:a", 2, 3, now.Add(time.Hour)); !errors.Is(err, errRateLimited) {
+		t.Errorf("Expected errRateLimited above the daily limit, got %v", err)
+	}
+	if _, err := limiter.take("session:a", 2, 3, now.Add(25*time.Hour)); err != nil {
+		t.Errorf("Expected the daily window to have passed, got %v", err)
+	}
+}
+
+func TestRateLimiterSweep(t *testing.T) {
+	limiter := &rateLimiter{hits: make(map[string][]time.Time)}
+	now := time.Now()
+
+	if _, err := limiter.take("session:a", 2, 3, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := limiter.take("session:b", 2, 3, now.Add(23*time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := limiter.take("session:c", 2, 3, now.Add(25*time.Hour)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The session without downloads in the daily window is forgotten
+	if _, ok := limiter.hits["session:a"]; ok {
+		t.Error("Expected the expired session to be dropped")
+	}
+	if len(limiter.hits) != 2 {
+		t.Errorf("Expected 2 sessions left, got %d", len(limiter.hits))
+	}
+}
+
+func TestRateKey(t *testing.T) {
+	env := &Env{}
+	stateless := &Env{Stateless: true}
+	session := func(caller auth.Caller, id string) context.Context {
+		caller.Session = id
+		return auth.WithCaller(context.Background(), caller)
+	}
+
+	cases := []struct {
+		name     string
+		ctx      context.Context
+		env      *Env
+		expected string
+	}{
+		{"Token", session(auth.Caller{Name: "reader"}, "1"), stateless, "token:reader"},
+		{"Session", session(auth.Operator, "1"), env, "session:1"},
+		{"NoSession", context.Background(), env, "caller:" + auth.Local.Name},
+		{"OperatorNoSession", session(auth.Operator, ""), env, "caller:" + auth.Operator.Name},
+		{"Stateless", session(auth.Operator, "1"), stateless, "caller:" + auth.Operator.Name},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := rateKey(c.ctx, c.env); got != c.expected {
+				t.Errorf("Expected key '%s', got '%s'", c.expected, got)
+			}
+		})
+	}
+
+	// Stateless requests share one bucket although each is a new session
+	limiter := &rateLimiter{hits: make(map[string][]time.Time)}
+	now := time.Now()
+	if _, err := limiter.take(rateKey(session(auth.Operator, "1"), stateless), 1, 0, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := limiter.take(rateKey(session(auth.Operator, "2"), stateless), 1, 0, now); !errors.Is(err, errRateLimited) {
+		t.Errorf("Expected errRateLimited for a new stateless session, got %v", err)
+	}
+}
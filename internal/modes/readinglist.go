@@ -0,0 +1,203 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/notify"
+	"github.com/iosifache/annas-mcp/internal/shelves"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// defaultMinConfidence is the confidence a reading list match needs to be
+// downloaded when no threshold is given.
+const defaultMinConfidence = 0.6
+
+// listMatch reports how a single reading list entry was resolved.
+type listMatch struct {
+	Entry shelves.Entry `json:"entry"`
+	Match *anna.Book    `json:"match,omitempty"`
+	// Confidence is how closely the match fits the title and author of the
+	// entry, between 0 and 1. ISBN matches of entries without a title are
+	// trusted fully.
+	Confidence float64 `json:"confidence"`
+	Queued     bool    `json:"queued,omitempty"`
+	Path       string  `json:"path,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// matchReadingList picks the best edition of every entry like
+// download_best_match does, and scores the confidence of each match.
+func matchReadingList(env *Env, entries []shelves.Entry) []listMatch {
+	results := make([]listMatch, 0, len(entries))
+	for _, entry := range entries {
+		result := listMatch{Entry: entry}
+
+		book, err := findBestMatch(env, BestMatchParams{Title: entry.Title, Author: entry.Author, ISBN: entry.ISBN})
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.Match = book
+
+		result.Confidence = 1
+		if query := strings.TrimSpace(entry.Title + " " + entry.Author); query != "" {
+			result.Confidence = anna.MatchScore(query, book)
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// queueMatches reserves a download for every match at least as confident as
+// minConfidence and marks it queued. It returns the indexes of the queued
+// results.
+func queueMatches(ctx context.Context, env *Env, results []listMatch, minConfidence float64) []int {
+	queued := make([]int, 0)
+	for i := range results {
+		result := &results[i]
+		if result.Match == nil || result.Confidence < minConfidence {
+			continue
+		}
+		if _, err := takeDownload(ctx, env); err != nil {
+			result.Error = err.Error()
+			continue
+		}
+
+		result.Queued = true
+		queued = append(queued, i)
+	}
+
+	return queued
+}
+
+// queuedBooks returns copies of the matches of the queued results, so saving
+// them does not touch the reported matches.
+func queuedBooks(results []listMatch, queued []int) []*anna.Book {
+	books := make([]*anna.Book, 0, len(queued))
+	for _, index := range queued {
+		book := *results[index].Match
+		books = append(books, &book)
+	}
+
+	return books
+}
+
+// saveQueued saves the queued books one after the other, notifying of every
+// download and of the end of the batch, and auditing every download under
+// source. It returns the local path of each book, empty for those that
+// failed.
+func saveQueued(ctx context.Context, env *Env, books []*anna.Book, source string) []string {
+	l := logger.GetLogger()
+	dispatcher := newDispatcher(env)
+	defer dispatcher.Wait()
+
+	for _, book := range books {
+		dispatcher.Publish(downloadEvent(notify.EventDownloadQueued, book))
+	}
+
+	// A shutdown leaves the books not started yet to the next start
+	entries := queue.add(ctx, env, books, source)
+	defer queue.done()
+
+	paths := make([]string, len(books))
+	failed := 0
+	for i, book := range books {
+		if queue.stopped() {
+			// The batch ends once resumed
+			l.Info("Leaving queued downloads for the next start", zap.Int("count", len(books)-i))
+			return paths
+		}
+		path, err := saveBook(env, book)
+		queue.finish(entries[i])
+		auditDownload(ctx, env, source, book, path, err)
+		if err != nil {
+			l.Warn("Failed to download queued book",
+				zap.String("bookHash", book.Hash),
+				zap.Error(err),
+			)
+			event := downloadEvent(notify.EventDownloadFailed, book)
+			event.Error = err.Error()
+			dispatcher.Publish(event)
+			failed++
+			continue
+		}
+
+		paths[i] = path
+		dispatcher.Publish(downloadEvent(notify.EventDownloadCompleted, book))
+	}
+	dispatcher.Publish(batchEvent(source, len(books)-failed, failed))
+
+	return paths
+}
+
+// NewReadingListToolHandler creates a handler for the import_reading_list tool that uses the provided environment.
+func NewReadingListToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, ReadingListParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params ReadingListParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Import reading list command called", zap.Bool("download", params.Download))
+
+		minConfidence := params.MinConfidence
+		if minConfidence == 0 {
+			minConfidence = defaultMinConfidence
+		}
+		if minConfidence < 0 || minConfidence > 1 {
+			err := withCode(codeInvalidArgument, "invalid min_confidence %v (must be between 0 and 1)", params.MinConfidence)
+			l.Error("Import reading list command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		if params.Download {
+			if len(env.Keys()) == 0 {
+				err := errSecretKeyMissing
+				l.Error("Import reading list command failed", zap.Error(err))
+				return nil, nil, err
+			}
+			if err := checkDownloadPath(env); err != nil {
+				l.Error("Import reading list command failed", zap.Error(err))
+				return nil, nil, err
+			}
+		}
+
+		entries, err := shelves.ParseReadingList(strings.NewReader(params.List))
+		if err != nil {
+			err = withCode(codeInvalidArgument, "%s", err)
+			l.Error("Import reading list command failed", zap.Error(err))
+			return nil, nil, err
+		}
+		if err := chargeUsage(ctx, usage.KindSearch); err != nil {
+			l.Error("Import reading list command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		results := matchReadingList(env, entries)
+
+		// Queued downloads run after the report is returned, and outlive the
+		// call that queued them
+		if params.Download {
+			if queued := queueMatches(ctx, env, results, minConfidence); len(queued) > 0 {
+				go saveQueued(context.WithoutCancel(ctx), env, queuedBooks(results, queued), auditSourceReadingList)
+			}
+		}
+
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		l.Info("Import reading list command completed successfully", zap.Int("entriesCount", len(entries)))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: string(data)}},
+		}, map[string]interface{}{"results": results}, nil
+	}
+}
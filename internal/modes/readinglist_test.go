@@ -0,0 +1,36 @@
+package modes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/config"
+	"github.com/iosifache/annas-mcp/internal/shelves"
+)
+
+func TestQueueMatches(t *testing.T) {
+	results := []listMatch{
+		{Entry: shelves.Entry{Title: "Dune"}, Match: &anna.Book{Hash: "dune"}, Confidence: 1},
+		{Entry: shelves.Entry{Title: "Solaris"}, Error: "no book matching \"Solaris\" was found"},
+		{Entry: shelves.Entry{Title: "Emma"}, Match: &anna.Book{Hash: "emma"}, Confidence: 0.4},
+		{Entry: shelves.Entry{Title: "Ubik"}, Match: &anna.Book{Hash: "ubik"}, Confidence: 0.6},
+	}
+
+	queued := queueMatches(context.Background(), config.Defaults(), results, defaultMinConfidence)
+	if len(queued) != 2 || queued[0] != 0 || queued[1] != 3 {
+		t.Fatalf("Expected results 0 and 3 to be queued, got %v", queued)
+	}
+	for i, result := range results {
+		expected := i == 0 || i == 3
+		if result.Queued != expected {
+			t.Errorf("Expected result %d queued %v, got %v", i, expected, result.Queued)
+		}
+	}
+
+	books := queuedBooks(results, queued)
+	books[0].Format = "epub"
+	if len(books) != 2 || books[1].Hash != "ubik" || results[0].Match.Format != "" {
+		t.Errorf("Expected copies of the queued matches, got %+v", books)
+	}
+}
@@ -0,0 +1,78 @@
+package modes
+
+import (
+	"context"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// refreshDownload resolves a new link for the book, bypassing the link cache
+// that may still hold the expired one, and caches the new link instead.
+func refreshDownload(env *Env, book *anna.Book) (*anna.DownloadInfo, error) {
+	if err := checkFormat(env, book); err != nil {
+		return nil, err
+	}
+
+	info, err := resolveUncached(env, book)
+	if err != nil {
+		return nil, err
+	}
+	if env.DownloadServer == "" {
+		cacheDownload(env, book, info)
+	}
+
+	return info, nil
+}
+
+// NewRefreshDownloadURLToolHandler creates a handler for the refresh_download_url tool that uses the provided environment.
+func NewRefreshDownloadURLToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, RefreshDownloadURLParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params RefreshDownloadURLParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Refresh download URL command called", zap.String("bookHash", params.BookHash))
+
+		hash, err := validateHash(params.BookHash)
+		if err != nil {
+			l.Error("Refresh download URL command failed", zap.Error(err))
+			return nil, nil, err
+		}
+		if len(env.Keys()) == 0 {
+			err := errSecretKeyMissing
+			l.Error("Refresh download URL command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		title := params.Title
+		if title == "" {
+			title = hash
+		}
+		book := &anna.Book{Hash: hash, Title: title}
+
+		left, err := takeDownload(ctx, env)
+		if err != nil {
+			l.Error("Refresh download URL command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		info, err := refreshDownload(env, book)
+		auditDownload(ctx, env, auditSourceRefresh, book, "", err)
+		if err != nil {
+			l.Error("Refresh download URL command failed",
+				zap.String("bookHash", hash),
+				zap.Error(err),
+			)
+			return nil, nil, err
+		}
+
+		l.Info("Refresh download URL command completed successfully", zap.String("bookHash", hash))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{
+				Text: withAllowance(linkText(title, info), left),
+			}},
+		}, map[string]interface{}{"download": info}, nil
+	}
+}
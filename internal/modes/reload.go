@@ -0,0 +1,85 @@
+package modes
+
+import (
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/config"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// activeConfig holds the configuration of a long-running mode once
+// watchReload has been called. LoadEnv then layers requests on top of it
+// instead of re-reading the config file for every session.
+var activeConfig atomic.Pointer[Env]
+
+// watchReload makes cfg the active configuration and re-reads it whenever the
+// process receives SIGHUP.
+func watchReload(cfg *Env) {
+	l := logger.GetLogger()
+
+	activeConfig.Store(cfg)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			l.Info("Received SIGHUP, reloading configuration")
+			reloadConfig()
+		}
+	}()
+}
+
+// reloadConfig replaces the active configuration with a freshly loaded one.
+// The listener settings are kept since changing them requires a rebind, and
+// an invalid configuration leaves the previous one in place.
+func reloadConfig() error {
+	l := logger.GetLogger()
+
+	next, err := config.Load(loadOptions)
+	if err != nil {
+		l.Error("Failed to reload configuration, keeping the previous one", zap.Error(err))
+		return err
+	}
+
+	if prev := activeConfig.Load(); prev != nil {
+		if next.Host != prev.Host || next.Port != prev.Port || next.Transport != prev.Transport || next.APIKey != prev.APIKey || next.ReadOnly != prev.ReadOnly {
+			l.Warn("Host, port, transport, API key, and read-only changes require a restart and were ignored")
+		}
+		next.Host, next.Port, next.Transport = prev.Host, prev.Port, prev.Transport
+		next.APIKey, next.ReadOnly = prev.APIKey, prev.ReadOnly
+	}
+
+	tokens, err := auth.Load(next.TokensFile)
+	if err != nil {
+		l.Error("Failed to reload configuration, keeping the previous one", zap.Error(err))
+		return err
+	}
+
+	if err := configureClient(next); err != nil {
+		l.Error("Failed to reload configuration, keeping the previous one", zap.Error(err))
+		return err
+	}
+
+	tokenStore.Store(tokens)
+
+	activeConfig.Store(next)
+	l.Info("Configuration reloaded", zap.Strings("mirrors", next.Mirrors))
+
+	return nil
+}
+
+// baseConfig returns a copy of the active configuration, or loads it when no
+// long-running mode is watching for reloads.
+func baseConfig() (*Env, error) {
+	if cfg := activeConfig.Load(); cfg != nil {
+		clone := *cfg
+		return &clone, nil
+	}
+
+	return config.Load(loadOptions)
+}
@@ -0,0 +1,177 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/notify"
+	"github.com/iosifache/annas-mcp/internal/scheduler"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// errSchedulesMissing is returned by the schedule tools and commands without
+// a schedules file.
+var errSchedulesMissing = withCode(codeNotConfigured, "schedules file is not configured. Please set ANNAS_SCHEDULES_FILE")
+
+// startScheduler runs the saved searches of the schedules file in the
+// background. New results are published to the notification backends of the
+// active configuration, so SIGHUP changes apply to the next run.
+func startScheduler(env *Env) {
+	l := logger.GetLogger()
+
+	if env.SchedulesFile == "" {
+		l.Info("Scheduled searches disabled")
+		return
+	}
+
+	runner := &scheduler.Runner{
+		Path:   env.SchedulesFile,
+		Search: anna.FindBook,
+		Notify: func(schedule scheduler.Schedule, books []*anna.Book) {
+			current, err := baseConfig()
+			if err != nil {
+				l.Error("Failed to load configuration for notification", zap.Error(err))
+				return
+			}
+			dispatcher := newDispatcher(current)
+			for _, book := range books {
+				event := downloadEvent(notify.EventSearchNewResult, book)
+				event.URL = book.URL
+				event.Query = schedule.Query
+				dispatcher.Publish(event)
+			}
+			dispatcher.Publish(notify.Event{Type: notify.EventSearchCompleted, Query: schedule.Query, Results: len(books)})
+		},
+	}
+
+	l.Info("Starting scheduled searches", zap.String("path", env.SchedulesFile))
+	go runner.Run(context.Background())
+}
+
+// addSchedule saves a new schedule to the schedules file of env.
+func addSchedule(env *Env, query, cron string) (scheduler.Schedule, error) {
+	if env.SchedulesFile == "" {
+		return scheduler.Schedule{}, errSchedulesMissing
+	}
+	if strings.TrimSpace(query) == "" {
+		return scheduler.Schedule{}, withCode(codeInvalidArgument, "query must not be empty")
+	}
+
+	var schedule scheduler.Schedule
+	err := scheduler.Update(env.SchedulesFile, func(store *scheduler.Store) error {
+		var err error
+		schedule, err = store.Add(query, cron)
+		return err
+	})
+
+	return schedule, err
+}
+
+// listSchedules returns the schedules of the schedules file of env.
+func listSchedules(env *Env) ([]scheduler.Schedule, error) {
+	if env.SchedulesFile == "" {
+		return nil, errSchedulesMissing
+	}
+
+	store, err := scheduler.Load(env.SchedulesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.Schedules, nil
+}
+
+// removeSchedule deletes a schedule from the schedules file of env.
+func removeSchedule(env *Env, id string) error {
+	if env.SchedulesFile == "" {
+		return errSchedulesMissing
+	}
+
+	return scheduler.Update(env.SchedulesFile, func(store *scheduler.Store) error {
+		return store.Remove(strings.TrimSpace(id))
+	})
+}
+
+// schedulesText lists schedules with their state, formatted by sprintf:
+// i18n.Sprintf for the CLI, and fmt.Sprintf for tool results, which agents
+// read in English.
+func schedulesText(schedules []scheduler.Schedule, sprintf func(format string, args ...any) string) string {
+	if len(schedules) == 0 {
+		return sprintf("No scheduled searches.")
+	}
+
+	var text strings.Builder
+	for _, schedule := range schedules {
+		text.WriteString(sprintf("%s: %q on %q", schedule.ID, schedule.Query, schedule.Cron))
+		if !schedule.LastRun.IsZero() {
+			text.WriteString(sprintf(", last run %s", schedule.LastRun.Format("2006-01-02 15:04 MST")))
+		}
+		if schedule.LastError != "" {
+			text.WriteString(sprintf(", last error: %s", schedule.LastError))
+		}
+		text.WriteString("\n")
+	}
+
+	return text.String()
+}
+
+// NewScheduleAddToolHandler creates a handler for the schedule_add tool that uses the provided environment.
+func NewScheduleAddToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, ScheduleAddParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params ScheduleAddParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+		l.Info("Schedule add command called", zap.String("query", params.Query), zap.String("cron", params.Cron))
+
+		schedule, err := addSchedule(env, params.Query, params.Cron)
+		if err != nil {
+			l.Error("Schedule add command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		l.Info("Schedule add command completed successfully", zap.String("id", schedule.ID))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Scheduled %q on %q with ID %s, new results are listed in the feed /feeds/%s.xml", schedule.Query, schedule.Cron, schedule.ID, schedule.ID)}},
+		}, map[string]interface{}{"schedule": schedule}, nil
+	}
+}
+
+// NewScheduleListToolHandler creates a handler for the schedule_list tool that uses the provided environment.
+func NewScheduleListToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, ScheduleListParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params ScheduleListParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+		l.Info("Schedule list command called")
+
+		schedules, err := listSchedules(env)
+		if err != nil {
+			l.Error("Schedule list command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: schedulesText(schedules, fmt.Sprintf)}},
+		}, map[string]interface{}{"schedules": schedules}, nil
+	}
+}
+
+// NewScheduleRemoveToolHandler creates a handler for the schedule_remove tool that uses the provided environment.
+func NewScheduleRemoveToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, ScheduleRemoveParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params ScheduleRemoveParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+		l.Info("Schedule remove command called", zap.String("id", params.ID))
+
+		if err := removeSchedule(env, params.ID); err != nil {
+			l.Error("Schedule remove command failed", zap.String("id", params.ID), zap.Error(err))
+			return nil, nil, err
+		}
+
+		l.Info("Schedule remove command completed successfully", zap.String("id", params.ID))
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Removed schedule %s", params.ID)}},
+		}, nil, nil
+	}
+}
@@ -0,0 +1,212 @@
+package modes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/iosifache/annas-mcp/internal/booktext"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// defaultPassageLimit bounds the passages returned by search_inside_book.
+const defaultPassageLimit = 20
+
+// passageContext is the number of characters of a passage on each side of
+// the match.
+const passageContext = 200
+
+// passage is a match of search_inside_book with its surrounding text.
+type passage struct {
+	Text    string `json:"text"`
+	Chapter string `json:"chapter,omitempty"`
+	Page    int    `json:"page,omitempty"`
+	// Resource is the page of the text resource of the book holding the
+	// match, to continue reading from there.
+	Resource string `json:"resource"`
+}
+
+// insideMatches are the passages of a book matching a phrase.
+type insideMatches struct {
+	Hash     string    `json:"md5"`
+	File     string    `json:"file"`
+	Query    string    `json:"query"`
+	Matches  int       `json:"matches"`
+	Passages []passage `json:"passages"`
+}
+
+// searchInsideBook finds a phrase in the text of a book saved to the download
+// path, ignoring case and line breaks.
+func searchInsideBook(env *Env, params SearchInsideBookParams) (*insideMatches, error) {
+	hash, err := validateHash(params.BookHash)
+	if err != nil {
+		return nil, err
+	}
+	query := strings.Join(strings.Fields(params.Query), " ")
+	if query == "" {
+		return nil, withCode(codeInvalidArgument, "query must not be empty")
+	}
+	if params.Limit < 0 {
+		return nil, withCode(codeInvalidArgument, "invalid limit %d: expected 0 or more", params.Limit)
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultPassageLimit
+	}
+
+	entry, path, err := savedBook(env, hash)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := booktext.ExtractDocument(path, 0)
+	if errors.Is(err, booktext.ErrUnsupported) {
+		return nil, withCode(codeInvalidArgument, "cannot extract the text of %s", entry.File)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text of %s: %w", entry.File, err)
+	}
+	if doc.Text == "" {
+		return nil, withCode(codeInvalidArgument, "%s has no text layer, use extract_text to recognize it", entry.File)
+	}
+
+	matches := &insideMatches{Hash: hash, File: entry.File, Query: query, Passages: make([]passage, 0)}
+	text := []rune(doc.Text)
+	for _, offset := range findPhrase(text, fold(query)) {
+		matches.Matches++
+		if len(matches.Passages) == limit {
+			continue
+		}
+
+		found := passage{Text: excerpt(text, offset, len([]rune(query))), Resource: textURI(hash, offset/textPageSize+1)}
+		for _, location := range doc.Locations {
+			if location.Offset > offset {
+				break
+			}
+			if location.Chapter != "" {
+				found.Chapter = location.Chapter
+			}
+			if location.Page != 0 {
+				found.Page = location.Page
+			}
+		}
+		matches.Passages = append(matches.Passages, found)
+	}
+
+	return matches, nil
+}
+
+// fold lowercases s and turns line breaks into spaces, keeping one rune per
+// rune so offsets are preserved.
+func fold(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' {
+			return ' '
+		}
+		return unicode.ToLower(r)
+	}, s)
+}
+
+// findPhrase returns the offsets, in characters, of the non-overlapping
+// occurrences of the folded phrase in text.
+func findPhrase(text []rune, phrase string) []int {
+	folded := fold(string(text))
+	offsets := make([]int, 0)
+	// runes counts the characters of folded before from
+	runes, from := 0, 0
+	for {
+		i := strings.Index(folded[from:], phrase)
+		if i < 0 {
+			return offsets
+		}
+		runes += utf8.RuneCountInString(folded[from : from+i])
+		offsets = append(offsets, runes)
+		runes += utf8.RuneCountInString(phrase)
+		from += i + len(phrase)
+	}
+}
+
+// excerpt returns the match of length characters at offset with up to
+// passageContext characters on each side, cut at whole words.
+func excerpt(text []rune, offset, length int) string {
+	start := max(0, offset-passageContext)
+	end := min(len(text), offset+length+passageContext)
+	for start > 0 && start < offset && !unicode.IsSpace(text[start-1]) {
+		start++
+	}
+	for end < len(text) && end > offset+length && !unicode.IsSpace(text[end]) {
+		end--
+	}
+
+	passage := strings.Join(strings.Fields(string(text[start:end])), " ")
+	if start > 0 {
+		passage = "…" + passage
+	}
+	if end < len(text) {
+		passage += "…"
+	}
+
+	return passage
+}
+
+// String renders the passages for tool results, each with its location.
+func (m *insideMatches) String() string {
+	if m.Matches == 0 {
+		return fmt.Sprintf("No passages of %s match %q.", m.File, m.Query)
+	}
+
+	var text strings.Builder
+	if m.Matches == 1 {
+		fmt.Fprintf(&text, "1 passage of %s matches %q", m.File, m.Query)
+	} else {
+		fmt.Fprintf(&text, "%d passages of %s match %q", m.Matches, m.File, m.Query)
+	}
+	if len(m.Passages) < m.Matches {
+		fmt.Fprintf(&text, ", showing the first %d", len(m.Passages))
+	}
+	text.WriteString(":")
+	for _, found := range m.Passages {
+		var location []string
+		if found.Chapter != "" {
+			location = append(location, found.Chapter)
+		}
+		if found.Page != 0 {
+			location = append(location, fmt.Sprintf("page %d", found.Page))
+		}
+		location = append(location, found.Resource)
+		fmt.Fprintf(&text, "\n\n[%s]\n%s", strings.Join(location, ", "), found.Text)
+	}
+
+	return text.String()
+}
+
+// NewSearchInsideBookToolHandler creates a handler for the search_inside_book tool that uses the provided environment.
+func NewSearchInsideBookToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, SearchInsideBookParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params SearchInsideBookParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Search inside book command called",
+			zap.String("bookHash", params.BookHash),
+			zap.String("query", params.Query),
+		)
+
+		matches, err := searchInsideBook(env, params)
+		if err != nil {
+			l.Error("Search inside book command failed", zap.String("bookHash", params.BookHash), zap.Error(err))
+			return nil, nil, err
+		}
+
+		l.Info("Search inside book command completed successfully",
+			zap.String("bookHash", matches.Hash),
+			zap.Int("matchesCount", matches.Matches),
+		)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: matches.String()}},
+		}, matches, nil
+	}
+}
@@ -0,0 +1,50 @@
+package modes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/config"
+)
+
+func TestSearchInsideBook(t *testing.T) {
+	env := config.Defaults()
+	env.DownloadPath = t.TempDir()
+	env.DetectLanguage = false
+
+	path := filepath.Join(env.DownloadPath, "Dune.fb2")
+	filler := strings.Repeat("sand ", 100)
+	os.WriteFile(path, []byte(`<FictionBook><body><section><title><p>Book One</p></title><p>`+filler+`Fear is the mind-killer.</p></section>`+
+		`<section><title><p>Book Two</p></title><p>I must not FEAR.</p><p>Fear is the little-death.</p></section></body></FictionBook>`), 0o644)
+	book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Format: "fb2"}
+	if err := indexBook(env, book, path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	matches, err := searchInsideBook(env, SearchInsideBookParams{BookHash: book.Hash, Query: "fear", Limit: 2})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if matches.Matches != 3 || len(matches.Passages) != 2 {
+		t.Fatalf("Expected 3 matches and 2 passages, got %d and %d", matches.Matches, len(matches.Passages))
+	}
+	if first := matches.Passages[0]; first.Chapter != "Book One" || !strings.HasPrefix(first.Text, "…sand") || !strings.Contains(first.Text, "Fear is the mind-killer.") {
+		t.Errorf("Unexpected first passage %+v", first)
+	}
+	if second := matches.Passages[1]; second.Chapter != "Book Two" || second.Resource != textURI(book.Hash, 1) {
+		t.Errorf("Unexpected second passage %+v", second)
+	}
+
+	t.Run("Across line breaks", func(t *testing.T) {
+		matches, err := searchInsideBook(env, SearchInsideBookParams{BookHash: book.Hash, Query: "not fear.  fear is"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if matches.Matches != 1 {
+			t.Errorf("Expected 1 match, got %d", matches.Matches)
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// volumeNumber matches the volume number in a title, such as "Book 2",
+// "Vol. 3", or "(Dune #4)".
+var volumeNumber = regexp.MustCompile(`(?i)(?:\b(?:book|vol(?:ume)?|part|tome|band|no)\.?\s*|#)(\d{1,3})\b`)
+
+// seriesVolume is a volume of a series, as its most available edition.
+type seriesVolume struct {
+	*anna.Book
+	// Volume is the number of the volume in the series, 0 when the titles of
+	// its editions do not tell.
+	Volume    int    `json:"volume,omitempty"`
+	InLibrary bool   `json:"in_library,omitempty"`
+	Queued    bool   `json:"queued,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// seriesReport lists the volumes of the series of a seed record.
+type seriesReport struct {
+	Series  string          `json:"series"`
+	Seed    string          `json:"md5"`
+	Volumes []*seriesVolume `json:"volumes"`
+	// Missing counts the volumes not saved to the download path.
+	Missing int `json:"missing"`
+}
+
+// parseVolume returns the volume number in title, 0 when there is none.
+func parseVolume(title string) int {
+	match := volumeNumber.FindStringSubmatch(title)
+	if match == nil {
+		return 0
+	}
+	volume, _ := strconv.Atoi(match[1])
+
+	return volume
+}
+
+// collectVolumes merges the records found for a series into one volume per
+// work, in series order: numbered volumes first, then the others by title.
+// Records count as volumes when they share an author with the seed, or name
+// the series along with a volume number.
+func collectVolumes(seed *anna.Metadata, series string, books []*anna.Book, prefs anna.Preferences) []*seriesVolume {
+	byWork := make(map[string]*seriesVolume)
+	volumes := make([]*seriesVolume, 0)
+	for _, book := range append([]*anna.Book{&seed.Book}, books...) {
+		volume := parseVolume(book.Title)
+		if book != &seed.Book && !sharesAuthor(seed.Authors, book.Authors) &&
+			(volume == 0 || anna.MatchScore(series, book) < minSeriesMatch) {
+			continue
+		}
+
+		key := workKey(book)
+		found, ok := byWork[key]
+		if !ok {
+			found = &seriesVolume{Book: book}
+			byWork[key] = found
+			volumes = append(volumes, found)
+		} else if moreAvailable(book, found.Book, prefs) {
+			found.Book = book
+		}
+		if found.Volume == 0 {
+			found.Volume = volume
+		}
+	}
+
+	sort.SliceStable(volumes, func(i, j int) bool {
+		a, b := volumes[i], volumes[j]
+		if (a.Volume == 0) != (b.Volume == 0) {
+			return a.Volume != 0
+		}
+		if a.Volume != b.Volume {
+			return a.Volume < b.Volume
+		}
+		return normalizeName(a.Title) < normalizeName(b.Title)
+	})
+
+	return volumes
+}
+
+// markSaved marks the volumes saved to the download path, under the hash of
+// their edition or, for other editions, under their title.
+func markSaved(env *Env, volumes []*seriesVolume) {
+	index, err := library.Load(env.DownloadPath)
+	if err != nil {
+		return
+	}
+	titles := make(map[string]bool, len(index.Entries))
+	for _, entry := range index.Entries {
+		if entry.Title != "" {
+			titles[normalizeName(entry.Title)] = true
+		}
+	}
+
+	for _, volume := range volumes {
+		_, saved := index.Entries[volume.Hash]
+		volume.InLibrary = saved || titles[normalizeName(volume.Title)]
+	}
+}
+
+// findSeries identifies the series of a seed record from its OpenLibrary
+// enrichment and lists its volumes, marking those already saved.
+func findSeries(ctx context.Context, env *Env, params CompleteSeriesParams) (*seriesReport, error) {
+	seed, err := seedRecord(ctx, env, params.BookHash, params.Title, params.Author)
+	if err != nil {
+		return nil, err
+	}
+	if len(seed.Series) == 0 {
+		return nil, withCode(codeInvalidArgument, "no series is known for %s: OpenLibrary lists none for its ISBNs", seed.Title)
+	}
+	series := seed.Series[0]
+
+	// The series name alone misses volumes listed without it, the name with
+	// the author finds those
+	queries := []string{series}
+	if len(seed.Authors) > 0 {
+		queries = append(queries, series+" "+seed.Authors[0])
+	}
+	found := make([]*anna.Book, 0)
+	for _, query := range queries {
+		books, err := anna.FindBook(query)
+		if err != nil {
+			logger.GetLogger().Warn("Series search failed", zap.String("query", query), zap.Error(err))
+			continue
+		}
+		found = append(found, books...)
+	}
+
+	report := &seriesReport{Series: series, Seed: seed.Hash, Volumes: collectVolumes(seed, series, found, preferences(env))}
+	markSaved(env, report.Volumes)
+	for _, volume := range report.Volumes {
+		if !volume.InLibrary {
+			report.Missing++
+		}
+	}
+
+	return report, nil
+}
+
+// queueMissing reserves a download for every volume not saved yet and marks
+// it queued. It returns copies of the queued editions, so saving them does
+// not touch the report.
+func queueMissing(ctx context.Context, env *Env, report *seriesReport) []*anna.Book {
+	books := make([]*anna.Book, 0, report.Missing)
+	for _, volume := range report.Volumes {
+		if volume.InLibrary {
+			continue
+		}
+		if _, err := takeDownload(ctx, env); err != nil {
+			volume.Error = err.Error()
+			continue
+		}
+
+		volume.Queued = true
+		book := *volume.Book
+		books = append(books, &book)
+	}
+
+	return books
+}
+
+// String lists the volumes in series order with their state.
+func (r *seriesReport) String() string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "%s: %d volumes found, %d missing from the library:", r.Series, len(r.Volumes), r.Missing)
+	for _, volume := range r.Volumes {
+		number := "-"
+		if volume.Volume != 0 {
+			number = strconv.Itoa(volume.Volume)
+		}
+		state := "missing"
+		switch {
+		case volume.InLibrary:
+			state = "in library"
+		case volume.Queued:
+			state = "queued"
+		case volume.Error != "":
+			state = "not queued: " + volume.Error
+		}
+
+		fmt.Fprintf(&text, "\n%s. %s by %s (%s, %s) [%s]\nHash: %s", number, volume.Title, volume.AuthorLine(), volume.Format, volume.Size, state, volume.Hash)
+	}
+
+	return text.String()
+}
+
+// NewCompleteSeriesToolHandler creates a handler for the complete_series tool that uses the provided environment.
+func NewCompleteSeriesToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, CompleteSeriesParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params CompleteSeriesParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Complete series command called",
+			zap.String("bookHash", params.BookHash),
+			zap.String("title", params.Title),
+			zap.Bool("download", params.Download),
+		)
+
+		if params.Download {
+			if len(env.Keys()) == 0 {
+				err := errSecretKeyMissing
+				l.Error("Complete series command failed", zap.Error(err))
+				return nil, nil, err
+			}
+			if err := checkDownloadPath(env); err != nil {
+				l.Error("Complete series command failed", zap.Error(err))
+				return nil, nil, err
+			}
+		}
+		if err := chargeUsage(ctx, usage.KindSearch); err != nil {
+			l.Error("Complete series command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		report, err := findSeries(ctx, env, params)
+		if err != nil {
+			l.Error("Complete series command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		// Queued downloads run after the report is returned, and outlive the
+		// call that queued them
+		if params.Download {
+			if books := queueMissing(ctx, env, report); len(books) > 0 {
+				go saveQueued(context.WithoutCancel(ctx), env, books, auditSourceSeries)
+			}
+		}
+
+		l.Info("Complete series command completed successfully",
+			zap.String("series", report.Series),
+			zap.Int("volumesCount", len(report.Volumes)),
+			zap.Int("missingCount", report.Missing),
+		)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: report.String()}},
+		}, report, nil
+	}
+}
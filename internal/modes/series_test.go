@@ -0,0 +1,58 @@
+package modes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/config"
+)
+
+func TestParseVolume(t *testing.T) {
+	for title, want := range map[string]int{
+		"The Name of the Wind (Kingkiller Chronicle, Book 1)": 1,
+		"The Way of Kings: Vol. 2":                            2,
+		"Children of Dune (Dune #3)":                          3,
+		"Dune Messiah":                                        0,
+	} {
+		if got := parseVolume(title); got != want {
+			t.Errorf("Expected volume %d for '%s', got %d", want, title, got)
+		}
+	}
+}
+
+func TestCollectVolumes(t *testing.T) {
+	env := config.Defaults()
+	env.DownloadPath = t.TempDir()
+	env.DetectLanguage = false
+
+	seed := &anna.Metadata{
+		Book:   anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Title: "Dune (Dune #1)", Authors: []string{"Frank Herbert"}, Format: "txt"},
+		Series: []string{"Dune"},
+	}
+	children := &anna.Book{Hash: "11111111111111111111111111111111", Title: "Children of Dune (Dune #3)", Authors: []string{"Herbert, Frank"}}
+	messiah := &anna.Book{Hash: "22222222222222222222222222222222", Title: "Dune Messiah (Dune #2)", Authors: []string{"Frank Herbert"}}
+	messiahFast := &anna.Book{Hash: "33333333333333333333333333333333", Title: "Dune Messiah (Dune #2)", Authors: []string{"Frank Herbert"}, FastDownload: true}
+	unrelated := &anna.Book{Hash: "44444444444444444444444444444444", Title: "The Science of Dune", Authors: []string{"Someone Else"}}
+
+	volumes := collectVolumes(seed, "Dune", []*anna.Book{children, messiah, unrelated, messiahFast}, anna.Preferences{})
+	if len(volumes) != 3 {
+		t.Fatalf("Expected 3 volumes, got %d", len(volumes))
+	}
+	for i, want := range []string{seed.Hash, messiahFast.Hash, children.Hash} {
+		if volumes[i].Hash != want || volumes[i].Volume != i+1 {
+			t.Errorf("Expected volume %d to be %s, got %s numbered %d", i+1, want, volumes[i].Hash, volumes[i].Volume)
+		}
+	}
+
+	path := filepath.Join(env.DownloadPath, "Dune.txt")
+	os.WriteFile(path, []byte("A beginning is the time for taking the most delicate care."), 0o644)
+	if err := indexBook(env, &seed.Book, path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	markSaved(env, volumes)
+	if !volumes[0].InLibrary || volumes[1].InLibrary || volumes[2].InLibrary {
+		t.Errorf("Expected only the first volume in the library, got %v, %v, %v", volumes[0].InLibrary, volumes[1].InLibrary, volumes[2].InLibrary)
+	}
+}
@@ -0,0 +1,99 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/version"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// registeredTools lists the tools that createMCPServer registers for env, by
+// asking a server connected over an in-memory transport, so that the server
+// card cannot drift from the tools clients actually get.
+func registeredTools(ctx context.Context, env *Env) ([]*mcp.Tool, error) {
+	server := createMCPServer(func() *Env { return env }, fixedCaller(auth.Local))
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	defer serverSession.Close()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "annas-mcp-server-card", Version: version.GetVersion()}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tools: %w", err)
+	}
+	defer clientSession.Close()
+
+	var tools []*mcp.Tool
+	for tool, err := range clientSession.Tools(ctx, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tools: %w", err)
+		}
+		tools = append(tools, tool)
+	}
+
+	return tools, nil
+}
+
+// serverCard describes the server, its transport, and its authentication,
+// with the tools registered for env.
+func serverCard(ctx context.Context, config HTTPServerConfig, env *Env) (map[string]interface{}, error) {
+	tools, err := registeredTools(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+
+	cardTools := make([]map[string]interface{}, 0, len(tools))
+	for _, tool := range tools {
+		cardTools = append(cardTools, map[string]interface{}{
+			"name":        tool.Name,
+			"description": tool.Description,
+			"inputSchema": tool.InputSchema,
+		})
+	}
+
+	serverInfo := map[string]interface{}{
+		"name":    "annas-mcp",
+		"title":   "Anna's Archive MCP Server",
+		"version": version.GetVersion(),
+	}
+	if release := version.UpdateAvailable(); release != nil {
+		serverInfo["updateAvailable"] = release
+	}
+
+	mode := authMode(env)
+	authentication := map[string]interface{}{
+		"mode":     mode,
+		"required": mode != authModeNone,
+	}
+	switch mode {
+	case authModeNone:
+	case auth.ProviderBasic:
+		authentication["schemes"] = []string{"basic", "bearer", "x-api-key"}
+	case auth.ProviderOIDC:
+		authentication["schemes"] = []string{"bearer"}
+	default:
+		authentication["schemes"] = []string{"bearer", "x-api-key"}
+	}
+
+	return map[string]interface{}{
+		"$schema":         "https://static.modelcontextprotocol.io/schemas/mcp-server-card/v1.json",
+		"version":         "1.0",
+		"protocolVersion": "2024-11-05",
+		"serverInfo":      serverInfo,
+		"description":     "Search and download documents from Anna's Archive",
+		"transport": map[string]interface{}{
+			"type":     config.TransportType,
+			"endpoint": "/mcp",
+		},
+		"authentication": authentication,
+		"capabilities": map[string]interface{}{
+			"tools": cardTools,
+		},
+	}, nil
+}
@@ -0,0 +1,46 @@
+package modes
+
+import (
+	"context"
+	"testing"
+)
+
+func TestServerCard(t *testing.T) {
+	tools := func(readOnly bool) map[string]bool {
+		card, err := serverCard(context.Background(), HTTPServerConfig{TransportType: "streamable"}, &Env{ReadOnly: readOnly})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		names := make(map[string]bool)
+		for _, tool := range card["capabilities"].(map[string]interface{})["tools"].([]map[string]interface{}) {
+			names[tool["name"].(string)] = true
+		}
+		return names
+	}
+
+	t.Run("All Tools", func(t *testing.T) {
+		names := tools(false)
+		for _, name := range []string{"search", "usage", "download", "schedule_remove"} {
+			if !names[name] {
+				t.Errorf("Expected tool '%s' on the server card", name)
+			}
+		}
+	})
+
+	t.Run("Read Only", func(t *testing.T) {
+		names := tools(true)
+		lookups := []string{
+			"get_metadata", "get_server_info", "list_formats_and_languages", "list_torrents", "mirror_status", "offline_search",
+			"recommend_similar", "search", "search_comics", "search_magazines", "server_stats", "usage",
+		}
+		for _, name := range lookups {
+			if !names[name] {
+				t.Errorf("Expected lookup tool '%s' in read-only mode", name)
+			}
+		}
+		if len(names) != len(lookups) {
+			t.Errorf("Expected only the %d lookup tools in read-only mode, got %v", len(lookups), names)
+		}
+	})
+}
@@ -0,0 +1,178 @@
+package modes
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/config"
+	"github.com/iosifache/annas-mcp/internal/i18n"
+	"github.com/iosifache/annas-mcp/internal/paths"
+	"github.com/iosifache/annas-mcp/internal/service"
+	"github.com/iosifache/annas-mcp/internal/state"
+)
+
+// serviceLog is the file in the state directory receiving the output of the
+// service where the service manager keeps no log.
+const serviceLog = "service.log"
+
+// serviceEnv is the file in the config directory holding the settings
+// exported in the shell of the install command, which the service manager
+// does not pass on.
+const serviceEnv = "service-env.json"
+
+// exportedEnv returns the settings set in the environment, read before any
+// dotenv file is loaded so only the ones exported in the shell remain.
+func exportedEnv() map[string]string {
+	exported := make(map[string]string)
+	for _, name := range config.EnvNames() {
+		if value := os.Getenv(name); value != "" {
+			exported[name] = value
+		}
+	}
+
+	return exported
+}
+
+// writeServiceEnv writes exported to the service env file, readable by its
+// owner only since it may hold API keys, and returns its path. Without
+// settings to pass on, a previous file is removed and the path is empty.
+func writeServiceEnv(exported map[string]string) (string, error) {
+	file := paths.File(paths.Config, serviceEnv)
+	if len(exported) == 0 {
+		if file == "" {
+			return "", nil
+		}
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		return "", nil
+	}
+	if file == "" {
+		return "", fmt.Errorf("no config directory to save %s", strings.Join(exportedNames(exported), ", "))
+	}
+
+	data, err := json.MarshalIndent(exported, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := state.WriteFile(file, data); err != nil {
+		return "", err
+	}
+
+	return file, nil
+}
+
+// loadServiceEnv sets the settings saved by writeServiceEnv in file, leaving
+// the variables already set by the service manager alone.
+func loadServiceEnv(file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to load env file %s: %w", file, err)
+	}
+	var exported map[string]string
+	if err := json.Unmarshal(data, &exported); err != nil {
+		return fmt.Errorf("failed to parse env file %s: %w", file, err)
+	}
+
+	for name, value := range exported {
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exportedNames returns the sorted names of exported, as listed after
+// installing the service.
+func exportedNames(exported map[string]string) []string {
+	names := make([]string, 0, len(exported))
+	for name := range exported {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// serviceSpec returns the service running the http command with the current
+// configuration: the config and dotenv files in use, the profile, read-only
+// mode, and the service env file holding the settings exported in the shell,
+// followed by httpArgs. Paths are made absolute, and the service runs in the
+// current working directory, so it loads the same files as the current
+// invocation.
+func serviceSpec(configFile, envFile, exportedFile, profile string, readOnly bool, httpArgs []string) (service.Spec, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return service.Spec{}, fmt.Errorf("failed to locate the annas-mcp binary: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return service.Spec{}, err
+	}
+
+	args := []string{"http"}
+	if file := config.ResolveFile(configFile); file != "" {
+		if file, err = filepath.Abs(file); err != nil {
+			return service.Spec{}, err
+		}
+		args = append(args, "--config", file)
+	}
+	if envFile == "" {
+		envFile = findDotEnv()
+	}
+	if envFile != "" {
+		if envFile, err = filepath.Abs(envFile); err != nil {
+			return service.Spec{}, err
+		}
+		args = append(args, "--env-file", envFile)
+	}
+	if exportedFile != "" {
+		args = append(args, "--"+service.EnvFileFlag, exportedFile)
+	}
+	if profile == "" {
+		profile = os.Getenv(config.ProfileEnv)
+	}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	if readOnly {
+		args = append(args, "--read-only")
+	}
+
+	return service.Spec{
+		Executable: executable,
+		Args:       append(args, httpArgs...),
+		Dir:        dir,
+		LogFile:    paths.File(paths.State, serviceLog),
+	}, nil
+}
+
+// serviceText describes status, as printed by the service status command.
+func serviceText(status service.Status) string {
+	if !status.Installed {
+		return i18n.Sprintf("The %s service is not installed\n", status.Manager)
+	}
+
+	var b strings.Builder
+	if status.File != "" {
+		b.WriteString(i18n.Sprintf("The %s service is installed from %s\n", status.Manager, status.File))
+	} else {
+		b.WriteString(i18n.Sprintf("The %s service is installed\n", status.Manager))
+	}
+	if status.State != "" {
+		b.WriteString(i18n.Sprintf("State: %s\n", status.State))
+	}
+
+	return b.String()
+}
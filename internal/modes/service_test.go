@@ -0,0 +1,60 @@
+package modes
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/service"
+)
+
+func TestServiceEnv(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("ANNAS_SECRET_KEY", `shell "key"`)
+	t.Setenv("ANNAS_DOWNLOAD_PATH", "/srv/books")
+
+	exported := exportedEnv()
+	if exported["ANNAS_SECRET_KEY"] != `shell "key"` || exported["ANNAS_DOWNLOAD_PATH"] != "/srv/books" {
+		t.Fatalf("Expected the exported settings, got %v", exported)
+	}
+	if _, ok := exported["HOME"]; ok {
+		t.Error("Expected only the settings of annas-mcp to be exported")
+	}
+
+	file, err := writeServiceEnv(exported)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if info, err := os.Stat(file); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the file to be readable by its owner only, got %v", info)
+	}
+
+	// The service manager passes none of them, but its own settings win
+	os.Unsetenv("ANNAS_SECRET_KEY")
+	t.Setenv("ANNAS_DOWNLOAD_PATH", "/var/lib/books")
+	if err := loadServiceEnv(file); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := os.Getenv("ANNAS_SECRET_KEY"); got != `shell "key"` {
+		t.Errorf("Expected the saved key, got %q", got)
+	}
+	if got := os.Getenv("ANNAS_DOWNLOAD_PATH"); got != "/var/lib/books" {
+		t.Errorf("Expected the path set by the service manager, got %q", got)
+	}
+
+	spec, err := serviceSpec("", "", file, "", false, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if i := slices.Index(spec.Args, "--"+service.EnvFileFlag); i < 0 || i+1 >= len(spec.Args) || spec.Args[i+1] != file {
+		t.Errorf("Expected the service to load %s, got %v", file, spec.Args)
+	}
+
+	// Without settings to pass on, the file of a previous install goes
+	if removed, err := writeServiceEnv(nil); err != nil || removed != "" {
+		t.Errorf("Expected no file, got %q and %v", removed, err)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got %v", file, err)
+	}
+}
@@ -0,0 +1,169 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// errSessionNotFound is returned when closing a session that is not open.
+var errSessionNotFound = errors.New("session not found")
+
+// sessionInfo describes an open MCP session of the HTTP server for
+// operators.
+type sessionInfo struct {
+	ID         string    `json:"id"`
+	Caller     string    `json:"caller"`
+	Client     string    `json:"client,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	AgeSeconds int64     `json:"age_seconds"`
+	LastSeen   time.Time `json:"last_seen"`
+	Requests   int64     `json:"requests"`
+	ToolCalls  int64     `json:"tool_calls"`
+}
+
+// trackedSession is an open session along with its counters.
+type trackedSession struct {
+	session *mcp.ServerSession
+	info    sessionInfo
+}
+
+// mcpSessions holds the open sessions of the HTTP server by ID.
+var mcpSessions = struct {
+	sync.Mutex
+	byID      map[string]*trackedSession
+	bySession map[*mcp.ServerSession]*trackedSession
+}{byID: make(map[string]*trackedSession), bySession: make(map[*mcp.ServerSession]*trackedSession)}
+
+// trackSessions records the sessions of server, opened by caller, and counts
+// the requests they receive.
+func trackSessions(server *mcp.Server, caller auth.Caller) {
+	server.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if session, ok := req.GetSession().(*mcp.ServerSession); ok {
+				touchSession(session, caller, method)
+			}
+			return next(ctx, method, req)
+		}
+	})
+}
+
+// touchSession counts a request of session, tracking it on its first one
+// until it is closed.
+func touchSession(session *mcp.ServerSession, caller auth.Caller, method string) {
+	now := time.Now().UTC()
+
+	mcpSessions.Lock()
+	defer mcpSessions.Unlock()
+
+	tracked, ok := mcpSessions.bySession[session]
+	if !ok {
+		// WebSocket sessions have no transport ID, so they get one here
+		id := session.ID()
+		if id == "" {
+			id, _ = randomString(12)
+		}
+		tracked = &trackedSession{session: session, info: sessionInfo{ID: id, Caller: caller.Name, CreatedAt: now}}
+		mcpSessions.byID[id] = tracked
+		mcpSessions.bySession[session] = tracked
+
+		go func() {
+			_ = session.Wait()
+			untrackSession(tracked)
+		}()
+	}
+
+	if tracked.info.Client == "" {
+		if params := session.InitializeParams(); params != nil && params.ClientInfo != nil {
+			tracked.info.Client = params.ClientInfo.Name
+		}
+	}
+	tracked.info.LastSeen = now
+	tracked.info.Requests++
+	if method == "tools/call" {
+		tracked.info.ToolCalls++
+	}
+}
+
+// untrackSession forgets a closed session.
+func untrackSession(tracked *trackedSession) {
+	mcpSessions.Lock()
+	defer mcpSessions.Unlock()
+
+	delete(mcpSessions.byID, tracked.info.ID)
+	delete(mcpSessions.bySession, tracked.session)
+}
+
+// openSessions returns the open sessions, oldest first.
+func openSessions(now time.Time) []sessionInfo {
+	mcpSessions.Lock()
+	defer mcpSessions.Unlock()
+
+	sessions := make([]sessionInfo, 0, len(mcpSessions.byID))
+	for _, tracked := range mcpSessions.byID {
+		info := tracked.info
+		info.AgeSeconds = int64(now.Sub(info.CreatedAt).Seconds())
+		sessions = append(sessions, info)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+
+	return sessions
+}
+
+// closeSession force-closes the session with the given ID.
+func closeSession(id string) error {
+	mcpSessions.Lock()
+	tracked, ok := mcpSessions.byID[id]
+	mcpSessions.Unlock()
+	if !ok {
+		return errSessionNotFound
+	}
+
+	err := tracked.session.Close()
+	untrackSession(tracked)
+	return err
+}
+
+// sessionsHandler lists the open MCP sessions as JSON, and force-closes one
+// on DELETE /admin/sessions/{id}. Sessions cover every caller, so they are
+// reserved to operators.
+func sessionsHandler(l *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		caller := auth.CallerFrom(r.Context())
+		if err := checkScope(caller.Scopes, auth.ScopeAdmin); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		id := r.PathValue("id")
+		switch {
+		case r.Method == http.MethodGet && id == "":
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(map[string]interface{}{"sessions": openSessions(time.Now())}); err != nil {
+				l.Error("Failed to encode sessions", zap.Error(err))
+			}
+		case r.Method == http.MethodDelete && id != "":
+			if err := closeSession(id); err != nil {
+				if errors.Is(err, errSessionNotFound) {
+					http.Error(w, err.Error(), http.StatusNotFound)
+					return
+				}
+				l.Error("Failed to close session", zap.String("session", id), zap.Error(err))
+			}
+			l.Info("Closed MCP session", zap.String("session", id), zap.String("caller", caller.Name))
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+		}
+	}
+}
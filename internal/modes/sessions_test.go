@@ -0,0 +1,93 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "annas-mcp"}, nil)
+	trackSessions(server, auth.Caller{Name: "alice"})
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "reader"}, nil)
+	clientSession, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer clientSession.Close()
+	if err := clientSession.Ping(ctx, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	handler := sessionsHandler(zap.NewNop())
+	admin := auth.WithCaller(ctx, auth.Caller{Name: "ops", Scopes: auth.Scopes{auth.ScopeAdmin}})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/admin/sessions", nil)
+		handler(w, r.WithContext(auth.WithCaller(ctx, auth.Caller{Name: "alice", Scopes: auth.Scopes{auth.ScopeSearch}})))
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", w.Code)
+		}
+	})
+
+	var id string
+	t.Run("List", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, "/admin/sessions", nil).WithContext(admin))
+
+		var listing struct {
+			Sessions []sessionInfo `json:"sessions"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&listing); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(listing.Sessions) != 1 {
+			t.Fatalf("Expected 1 session, got %d", len(listing.Sessions))
+		}
+		session := listing.Sessions[0]
+		if session.Caller != "alice" || session.Client != "reader" {
+			t.Errorf("Expected caller 'alice' with client 'reader', got '%s' with '%s'", session.Caller, session.Client)
+		}
+		if session.Requests < 2 {
+			t.Errorf("Expected at least 2 requests, got %d", session.Requests)
+		}
+		id = session.ID
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodDelete, "/admin/sessions/"+id, nil)
+		r.SetPathValue("id", id)
+		handler(w, r.WithContext(admin))
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status 204, got %d", w.Code)
+		}
+		if err := serverSession.Wait(); err != nil {
+			t.Logf("Session closed with %v", err)
+		}
+		if sessions := openSessions(time.Now()); len(sessions) != 0 {
+			t.Errorf("Expected no open sessions, got %d", len(sessions))
+		}
+
+		w = httptest.NewRecorder()
+		handler(w, r.WithContext(admin))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", w.Code)
+		}
+	})
+}
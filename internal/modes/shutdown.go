@@ -0,0 +1,92 @@
+package modes
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// draining is set once the HTTP server received a stop signal, so the health
+// check takes the instance out of load balancing while it drains.
+var draining atomic.Bool
+
+// stopRequests receives the signals stopping the HTTP server, and the stop
+// requests of the Windows service control manager.
+var stopRequests = make(chan os.Signal, 2)
+
+// serve runs server on listener until it fails, SIGTERM or SIGINT is
+// received, or the Windows service is stopped. The server then stops
+// accepting connections and drains for up to grace: requests in progress and
+// queued downloads may finish, while queued downloads not started yet are
+// kept in the queue file of queueDir, if any, for the next start. Connections
+// still open after grace, such as SSE streams, are closed. A second signal
+// ends the grace period early.
+func serve(server *http.Server, listener net.Listener, grace time.Duration, queueDir string) error {
+	l := logger.GetLogger()
+
+	signals := stopRequests
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-served:
+		return err
+	case sig := <-signals:
+		l.Info("Draining MCP HTTP server",
+			zap.String("signal", sig.String()),
+			zap.Duration("grace", grace),
+		)
+	}
+	draining.Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	go func() {
+		select {
+		case <-signals:
+			l.Warn("Received a second signal, ending the shutdown grace early")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// Background downloads are waited for alongside the requests
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		queue.drain(ctx)
+	}()
+	if err := server.Shutdown(ctx); err != nil {
+		l.Warn("Closing the connections still open after the shutdown grace", zap.Error(err))
+		server.Close()
+	}
+	wg.Wait()
+
+	// Requests may have queued downloads until they ended
+	left := queue.drain(ctx)
+	if queueDir != "" {
+		if err := flushQueue(queueDir, left); err != nil {
+			l.Error("Failed to keep the queued downloads for the next start", zap.Error(err))
+		} else if len(left) > 0 {
+			l.Info("Kept the queued downloads for the next start", zap.Int("count", len(left)))
+		}
+	}
+
+	l.Info("MCP HTTP server stopped")
+	return http.ErrServerClosed
+}
@@ -0,0 +1,347 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// defaultSimilarLimit bounds the records suggested by recommend_similar.
+const defaultSimilarLimit = 10
+
+// Searches run for a seed record, each costing a request to the mirrors.
+const (
+	maxSimilarAuthors  = 2
+	maxSimilarSubjects = 3
+)
+
+// minSeriesMatch is the score of a series search result against the series
+// name below which it is not taken for a volume of the series.
+const minSeriesMatch = 0.6
+
+// Weights of the reasons a record is related to the seed.
+const (
+	weightSeries  = 3
+	weightAuthor  = 2
+	weightSubject = 1
+)
+
+// similarQuery is a search for records related to a seed by one reason.
+type similarQuery struct {
+	Query  string
+	Reason string
+	Weight int
+	// Keep filters the results, nil keeping them all
+	Keep func(*anna.Book) bool
+}
+
+// similarResults are the results of a similarQuery.
+type similarResults struct {
+	similarQuery
+	Books []*anna.Book
+}
+
+// recommendation is a record related to the seed, with its availability.
+type recommendation struct {
+	*anna.Book
+	Reasons []string `json:"reasons"`
+	Score   int      `json:"score"`
+	// InLibrary reports whether the record is already saved to the
+	// download path.
+	InLibrary bool `json:"in_library,omitempty"`
+}
+
+// similarQueries returns the searches for records sharing the series,
+// authors, and subjects of seed, most telling first.
+func similarQueries(seed *anna.Metadata) []similarQuery {
+	queries := make([]similarQuery, 0)
+	if len(seed.Series) > 0 {
+		series := seed.Series[0]
+		queries = append(queries, similarQuery{
+			Query:  series,
+			Reason: fmt.Sprintf("same series %q", series),
+			Weight: weightSeries,
+			Keep: func(book *anna.Book) bool {
+				return anna.MatchScore(series, book) >= minSeriesMatch || sharesAuthor(seed.Authors, book.Authors)
+			},
+		})
+	}
+	for _, author := range seed.Authors[:min(len(seed.Authors), maxSimilarAuthors)] {
+		queries = append(queries, similarQuery{
+			Query:  author,
+			Reason: fmt.Sprintf("same author %s", author),
+			Weight: weightAuthor,
+			Keep: func(book *anna.Book) bool {
+				return sharesAuthor([]string{author}, book.Authors)
+			},
+		})
+	}
+	for _, subject := range seed.Subjects[:min(len(seed.Subjects), maxSimilarSubjects)] {
+		queries = append(queries, similarQuery{
+			Query:  subject,
+			Reason: fmt.Sprintf("subject %q", subject),
+			Weight: weightSubject,
+		})
+	}
+
+	return queries
+}
+
+// rankSimilar merges the results of the similar queries into one
+// recommendation per work, best first: the one related to seed for the most
+// telling reasons, then the most available. The seed itself and its other
+// editions are left out.
+func rankSimilar(seed *anna.Metadata, results []similarResults, prefs anna.Preferences, limit int) []*recommendation {
+	seedWork := workKey(&seed.Book)
+	byWork := make(map[string]*recommendation)
+	order := make([]string, 0)
+	for _, result := range results {
+		for _, book := range result.Books {
+			key := workKey(book)
+			if book.Hash == seed.Hash || key == seedWork || (result.Keep != nil && !result.Keep(book)) {
+				continue
+			}
+
+			found, ok := byWork[key]
+			if !ok {
+				found = &recommendation{Book: book}
+				byWork[key] = found
+				order = append(order, key)
+			} else if moreAvailable(book, found.Book, prefs) {
+				found.Book = book
+			}
+			if !hasReason(found.Reasons, result.Reason) {
+				found.Reasons = append(found.Reasons, result.Reason)
+				found.Score += result.Weight
+			}
+		}
+	}
+
+	recommendations := make([]*recommendation, 0, len(order))
+	for _, key := range order {
+		recommendations = append(recommendations, byWork[key])
+	}
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return moreAvailable(recommendations[i].Book, recommendations[j].Book, prefs)
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+
+	return recommendations
+}
+
+// workKey identifies the editions of a work by their title and first author.
+func workKey(book *anna.Book) string {
+	author := ""
+	if len(book.Authors) > 0 {
+		author = normalizeName(book.Authors[0])
+	}
+
+	return normalizeName(book.Title) + "|" + author
+}
+
+// normalizeName lowercases a title or name and keeps only its words.
+func normalizeName(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(anna.Transliterate(name)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
+
+// sharesAuthor reports whether one of authors wrote the book, ignoring case,
+// punctuation, and the order of first and last names.
+func sharesAuthor(authors, bookAuthors []string) bool {
+	for _, author := range authors {
+		wanted := strings.Fields(normalizeName(author))
+		sort.Strings(wanted)
+		for _, bookAuthor := range bookAuthors {
+			names := strings.Fields(normalizeName(bookAuthor))
+			sort.Strings(names)
+			if len(wanted) > 0 && strings.Join(wanted, " ") == strings.Join(names, " ") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// moreAvailable reports whether edition a is easier to get than b: offered
+// through the fast download servers, held by more collections, or in a
+// preferred format.
+func moreAvailable(a, b *anna.Book, prefs anna.Preferences) bool {
+	if a.FastDownload != b.FastDownload {
+		return a.FastDownload
+	}
+	if len(a.Sources) != len(b.Sources) {
+		return len(a.Sources) > len(b.Sources)
+	}
+
+	return preferredFormat(a, prefs) < preferredFormat(b, prefs)
+}
+
+// preferredFormat returns the rank of the format of book among the preferred
+// formats, past the last one when it is not preferred.
+func preferredFormat(book *anna.Book, prefs anna.Preferences) int {
+	for i, format := range prefs.Formats {
+		if strings.EqualFold(strings.TrimSpace(format), book.Format) {
+			return i
+		}
+	}
+
+	return len(prefs.Formats)
+}
+
+func hasReason(reasons []string, reason string) bool {
+	for _, r := range reasons {
+		if r == reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+// seedRecord returns the record to start from, by hash or as the best match
+// of title and author, enriched with OpenLibrary subjects and series.
+func seedRecord(ctx context.Context, env *Env, hash, title, author string) (*anna.Metadata, error) {
+	hash = strings.TrimSpace(hash)
+	if hash == "" {
+		query := strings.TrimSpace(title + " " + author)
+		if query == "" {
+			return nil, withCode(codeInvalidArgument, "a hash or a title is required")
+		}
+		books, err := anna.FindBook(query)
+		if err != nil {
+			return nil, err
+		}
+		if len(books) == 0 {
+			return nil, withCode(codeInvalidArgument, "no book matching %q was found", query)
+		}
+		anna.RankBooks(query, books, preferences(env))
+		hash = books[0].Hash
+	}
+
+	hash, err := validateHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return fetchMetadata(ctx, hash, true, 0)
+}
+
+// recommendSimilar suggests records related to a seed record by series,
+// author, and subject, marking those already saved to the download path.
+func recommendSimilar(ctx context.Context, env *Env, params RecommendSimilarParams) (*anna.Metadata, []*recommendation, error) {
+	if params.Limit < 0 {
+		return nil, nil, withCode(codeInvalidArgument, "invalid limit %d: expected 0 or more", params.Limit)
+	}
+	limit := params.Limit
+	if limit == 0 {
+		limit = defaultSimilarLimit
+	}
+
+	seed, err := seedRecord(ctx, env, params.BookHash, params.Title, params.Author)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	queries := similarQueries(seed)
+	if len(queries) == 0 {
+		return nil, nil, withCode(codeInvalidArgument, "%s has no author, series, or subjects to recommend from", seed.Hash)
+	}
+	results := make([]similarResults, 0, len(queries))
+	for _, query := range queries {
+		books, err := anna.FindBook(query.Query)
+		if err != nil {
+			// One failed search still leaves the others
+			logger.GetLogger().Warn("Similar works search failed", zap.String("query", query.Query), zap.Error(err))
+			continue
+		}
+		results = append(results, similarResults{similarQuery: query, Books: books})
+	}
+
+	recommendations := rankSimilar(seed, results, preferences(env), limit)
+	if index, err := library.Load(env.DownloadPath); err == nil {
+		for _, found := range recommendations {
+			_, found.InLibrary = index.Entries[found.Hash]
+		}
+	}
+
+	return seed, recommendations, nil
+}
+
+// similarText lists recommendations with their reasons and availability.
+func similarText(seed *anna.Metadata, recommendations []*recommendation) string {
+	if len(recommendations) == 0 {
+		return fmt.Sprintf("No works similar to %s were found.", seed.Title)
+	}
+
+	var text strings.Builder
+	fmt.Fprintf(&text, "Works similar to %s:", seed.Title)
+	for i, found := range recommendations {
+		availability := make([]string, 0, 3)
+		if found.FastDownload {
+			availability = append(availability, "fast download")
+		}
+		if len(found.Sources) > 0 {
+			availability = append(availability, strings.Join(found.Sources, ", "))
+		}
+		if found.InLibrary {
+			availability = append(availability, "in library")
+		}
+
+		fmt.Fprintf(&text, "\n\n%d. %s by %s (%s, %s, %s)\nWhy: %s\nHash: %s",
+			i+1, found.Title, found.AuthorLine(), found.Format, found.Language, found.Size, strings.Join(found.Reasons, "; "), found.Hash)
+		if len(availability) > 0 {
+			fmt.Fprintf(&text, "\nAvailability: %s", strings.Join(availability, "; "))
+		}
+	}
+
+	return text.String()
+}
+
+// NewRecommendSimilarToolHandler creates a handler for the recommend_similar tool that uses the provided environment.
+func NewRecommendSimilarToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, RecommendSimilarParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params RecommendSimilarParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Recommend similar command called",
+			zap.String("bookHash", params.BookHash),
+			zap.String("title", params.Title),
+			zap.String("author", params.Author),
+		)
+
+		if err := chargeUsage(ctx, usage.KindSearch); err != nil {
+			l.Error("Recommend similar command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		seed, recommendations, err := recommendSimilar(ctx, env, params)
+		if err != nil {
+			l.Error("Recommend similar command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		l.Info("Recommend similar command completed successfully",
+			zap.String("bookHash", seed.Hash),
+			zap.Int("recommendationsCount", len(recommendations)),
+		)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: similarText(seed, recommendations)}},
+		}, map[string]interface{}{"seed": seed, "recommendations": recommendations}, nil
+	}
+}
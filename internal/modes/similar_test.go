@@ -0,0 +1,49 @@
+package modes
+
+import (
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+)
+
+func TestRankSimilar(t *testing.T) {
+	seed := &anna.Metadata{
+		Book:     anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Title: "Dune", Authors: []string{"Frank Herbert"}},
+		Series:   []string{"Dune Chronicles"},
+		Subjects: []string{"Science fiction"},
+	}
+	messiah := &anna.Book{Hash: "11111111111111111111111111111111", Title: "Dune Messiah", Authors: []string{"Herbert, Frank"}}
+	messiahFast := &anna.Book{Hash: "22222222222222222222222222222222", Title: "Dune Messiah", Authors: []string{"Herbert, Frank"}, FastDownload: true}
+	otherEdition := &anna.Book{Hash: "33333333333333333333333333333333", Title: "Dune", Authors: []string{"Frank Herbert"}}
+	foundation := &anna.Book{Hash: "44444444444444444444444444444444", Title: "Foundation", Authors: []string{"Isaac Asimov"}}
+	guide := &anna.Book{Hash: "55555555555555555555555555555555", Title: "The Dune Chronicles Guide", Authors: []string{"Someone Else"}}
+	unrelated := &anna.Book{Hash: "66666666666666666666666666666666", Title: "Cooking", Authors: []string{"Someone Else"}}
+
+	queries := similarQueries(seed)
+	if len(queries) != 3 {
+		t.Fatalf("Expected 3 queries, got %d", len(queries))
+	}
+	results := []similarResults{
+		{similarQuery: queries[0], Books: []*anna.Book{messiah, guide, unrelated}},
+		{similarQuery: queries[1], Books: []*anna.Book{otherEdition, messiahFast, unrelated}},
+		{similarQuery: queries[2], Books: []*anna.Book{foundation, messiah}},
+	}
+
+	recommendations := rankSimilar(seed, results, anna.Preferences{}, 10)
+	if len(recommendations) != 3 {
+		t.Fatalf("Expected 3 recommendations, got %d", len(recommendations))
+	}
+	if first := recommendations[0]; first.Hash != messiahFast.Hash || first.Score != weightSeries+weightAuthor+weightSubject || len(first.Reasons) != 3 {
+		t.Errorf("Expected the fast edition of Dune Messiah for every reason first, got %s scoring %d for %v", first.Hash, first.Score, first.Reasons)
+	}
+	if second := recommendations[1]; second.Hash != guide.Hash {
+		t.Errorf("Expected the series guide second, got %s", second.Hash)
+	}
+	if third := recommendations[2]; third.Hash != foundation.Hash {
+		t.Errorf("Expected Foundation third, got %s", third.Hash)
+	}
+
+	if limited := rankSimilar(seed, results, anna.Preferences{}, 1); len(limited) != 1 {
+		t.Errorf("Expected 1 recommendation, got %d", len(limited))
+	}
+}
@@ -0,0 +1,121 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/keyring"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultSpeedTestSampleKB is downloaded from every server, enough to
+	// get past the connection setup without spending long on slow ones.
+	defaultSpeedTestSampleKB = 1024
+	maxSpeedTestSampleKB     = 16 * 1024
+	// maxPartnerServers bounds the servers tried when the API keeps
+	// accepting higher indexes.
+	maxPartnerServers = 10
+)
+
+// speedTest measures the throughput of every fast partner server offering
+// the book, stopping at the first server index the API rejects.
+func speedTest(ctx context.Context, env *Env, book *anna.Book, sampleKB int) ([]anna.ServerSpeed, error) {
+	if len(env.Keys()) == 0 {
+		return nil, errSecretKeyMissing
+	}
+	if sampleKB <= 0 {
+		sampleKB = defaultSpeedTestSampleKB
+	}
+	sampleKB = min(sampleKB, maxSpeedTestSampleKB)
+
+	ring := keyring.New(env.Keys())
+	speeds := make([]anna.ServerSpeed, 0)
+	for server := 0; server < maxPartnerServers && ctx.Err() == nil; server++ {
+		info, err := ring.ResolveFrom(book, server)
+		if err != nil {
+			if server == 0 {
+				return nil, fmt.Errorf("failed to get download URL: %w", err)
+			}
+			// Past the last server
+			break
+		}
+
+		speeds = append(speeds, anna.MeasureSpeed(ctx, server, info.URL, int64(sampleKB)*1024))
+	}
+
+	return speeds, nil
+}
+
+// fastestServer returns the index of the fastest server that answered, or -1.
+func fastestServer(speeds []anna.ServerSpeed) int {
+	fastest := -1
+	for i := range speeds {
+		if speeds[i].Error == "" && (fastest == -1 || speeds[i].BytesPerSecond > speeds[fastest].BytesPerSecond) {
+			fastest = i
+		}
+	}
+	if fastest == -1 {
+		return -1
+	}
+
+	return speeds[fastest].Server
+}
+
+// speedTestText formats the measured servers for tools and the CLI.
+func speedTestText(speeds []anna.ServerSpeed) string {
+	var text strings.Builder
+	for i := range speeds {
+		text.WriteString(speeds[i].String() + "\n")
+	}
+	if fastest := fastestServer(speeds); fastest >= 0 {
+		fmt.Fprintf(&text, "\nFastest: server %d\n", fastest)
+	} else {
+		text.WriteString("\nNo server could be measured\n")
+	}
+
+	return text.String()
+}
+
+// NewSpeedTestToolHandler creates a handler for the speedtest tool that uses the provided environment.
+func NewSpeedTestToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, SpeedTestParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params SpeedTestParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Speed test command called",
+			zap.String("bookHash", params.BookHash),
+			zap.Int("sampleKB", params.SampleKB),
+		)
+
+		hash, err := validateHash(params.BookHash)
+		if err != nil {
+			l.Error("Speed test command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		// Every server hands out a fast download link for the record
+		if _, err := takeDownload(ctx, env); err != nil {
+			l.Error("Speed test command failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		speeds, err := speedTest(ctx, env, &anna.Book{Hash: hash}, params.SampleKB)
+		if err != nil {
+			l.Error("Speed test command failed", zap.String("bookHash", hash), zap.Error(err))
+			return nil, nil, err
+		}
+
+		l.Info("Speed test command completed successfully",
+			zap.String("bookHash", hash),
+			zap.Int("servers", len(speeds)),
+		)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: speedTestText(speeds)}},
+		}, map[string]interface{}{"servers": speeds, "fastest": fastestServer(speeds)}, nil
+	}
+}
@@ -0,0 +1,71 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/config"
+)
+
+func TestSpeedTest(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/file" {
+			fmt.Fprint(w, strings.Repeat("x", 4096))
+			return
+		}
+
+		switch r.URL.Query().Get("domain_index") {
+		case "0", "1":
+			fmt.Fprintf(w, `{"download_url": "%s/file"}`, server.URL)
+		default:
+			fmt.Fprint(w, `{"error": "Invalid domain_index or path_index"}`)
+		}
+	}))
+	defer server.Close()
+
+	if err := anna.Configure(anna.Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Failed to configure client: %v", err)
+	}
+	defer anna.Configure(anna.Options{})
+
+	env := config.Defaults()
+	env.SecretKey = "speedtest-key"
+
+	speeds, err := speedTest(context.Background(), env, &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45"}, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(speeds) != 2 {
+		t.Fatalf("Expected 2 servers, got %d", len(speeds))
+	}
+	for _, speed := range speeds {
+		if speed.Error != "" || speed.Bytes != 1024 {
+			t.Errorf("Expected a 1024 byte sample, got %+v", speed)
+		}
+	}
+	if fastest := fastestServer(speeds); fastest != 0 && fastest != 1 {
+		t.Errorf("Expected the fastest server to be 0 or 1, got %d", fastest)
+	}
+
+	t.Run("Pinned server", func(t *testing.T) {
+		book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45"}
+		if _, err := resolveUncached(withServer(env, "1"), book); err != nil {
+			t.Errorf("Expected server 1 to resolve, got %v", err)
+		}
+		if _, err := resolveUncached(withServer(env, "127.0.0.1"), book); err != nil {
+			t.Errorf("Expected the server domain to resolve, got %v", err)
+		}
+		if _, err := resolveUncached(withServer(env, "example.org"), book); errorCode(err) != codeInvalidArgument {
+			t.Errorf("Expected an unknown domain to be an invalid argument, got %v", err)
+		}
+		if env.DownloadServer != "" {
+			t.Errorf("Expected withServer to leave the environment untouched, got '%s'", env.DownloadServer)
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package modes
+
+import (
+	"path/filepath"
+
+	"github.com/iosifache/annas-mcp/internal/library"
+	"github.com/iosifache/annas-mcp/internal/state"
+)
+
+// stateItems lists the state files of env, under the names they take in a
+// state bundle. The search history is the audit log, which records every
+// search and download.
+func stateItems(env *Env) []state.Item {
+	items := []state.Item{
+		{Name: "library/" + library.IndexFile},
+		{Name: "audit.log", Path: env.AuditLog},
+		{Name: "schedules.json", Path: env.SchedulesFile},
+		{Name: "usage.json", Path: env.UsageFile},
+		{Name: "tokens.json", Path: env.TokensFile, Secret: true},
+		{Name: "oauth-sessions.json", Path: env.OAuthSessionsFile, Secret: true},
+		{Name: "cookies.txt", Path: env.CookieFile, Secret: true},
+	}
+	if env.DownloadPath != "" {
+		items[0].Path = filepath.Join(env.DownloadPath, library.IndexFile)
+	}
+
+	return items
+}
@@ -0,0 +1,93 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/keyring"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// NewServerStatsToolHandler creates a handler for the server_stats tool that
+// reports the runtime statistics of the server and the quota left in the
+// provided environment.
+func NewServerStatsToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, ServerStatsParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params ServerStatsParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+		l.Info("Server stats command called")
+
+		snapshot := metrics.Take()
+		usage := keyring.New(env.Keys()).Usage()
+		left := remainingDownloads(ctx, env)
+
+		var text strings.Builder
+		fmt.Fprintf(&text, "Uptime: %s\n", (time.Duration(snapshot.UptimeSeconds) * time.Second).String())
+		fmt.Fprintf(&text, "Searches served: %d\n", snapshot.Searches)
+		fmt.Fprintf(&text, "Downloads completed: %d\n", snapshot.DownloadsCompleted)
+		fmt.Fprintf(&text, "Link cache: %d hits, %d misses (%.0f%% hit rate)\n", snapshot.CacheHits, snapshot.CacheMisses, snapshot.CacheHitRate*100)
+		if fastLeft, known := fastDownloadsLeft(usage); known {
+			fmt.Fprintf(&text, "Fast downloads left: %d across %d keys\n", fastLeft, len(usage))
+		} else if len(usage) > 0 {
+			fmt.Fprintf(&text, "Fast downloads left: unknown until the first download\n")
+		}
+		if left != nil {
+			fmt.Fprintf(&text, "Remaining downloads for this caller: %s\n", left)
+		}
+		text.WriteString(breakerText(anna.MirrorStatuses()))
+
+		output := map[string]interface{}{
+			"stats":   snapshot,
+			"keys":    usage,
+			"mirrors": mirrorReport(),
+		}
+		if left != nil {
+			output["allowance"] = left
+		}
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: text.String()}},
+		}, output, nil
+	}
+}
+
+// breakerText describes the circuit breakers of the mirrors, listing those
+// that are not closed.
+func breakerText(breakers []anna.MirrorStatus) string {
+	var text strings.Builder
+	closed := 0
+	for _, breaker := range breakers {
+		if breaker.State == anna.CircuitClosed {
+			closed++
+			continue
+		}
+		fmt.Fprintf(&text, "Mirror %s: circuit %s after %d failures", breaker.Mirror, breaker.State, breaker.Failures)
+		if breaker.LastError != "" {
+			fmt.Fprintf(&text, " (%s)", breaker.LastError)
+		}
+		text.WriteString("\n")
+	}
+	if len(breakers) > 0 {
+		fmt.Fprintf(&text, "Mirror circuits closed: %d of %d\n", closed, len(breakers))
+	}
+
+	return text.String()
+}
+
+// fastDownloadsLeft sums the fast downloads left on the keys whose allowance
+// is known.
+func fastDownloadsLeft(usage []keyring.Usage) (int, bool) {
+	total, known := 0, false
+	for _, u := range usage {
+		if u.DownloadsLeft != nil {
+			total += *u.DownloadsLeft
+			known = true
+		}
+	}
+
+	return total, known
+}
@@ -0,0 +1,22 @@
+package modes
+
+import (
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+)
+
+func TestBreakerText(t *testing.T) {
+	text := breakerText([]anna.MirrorStatus{
+		{Mirror: "https://annas-archive.org", State: anna.CircuitClosed},
+		{Mirror: "https://annas-archive.se", State: anna.CircuitOpen, Failures: 5, LastError: "timeout"},
+	})
+
+	expected := "Mirror https://annas-archive.se: circuit open after 5 failures (timeout)\nMirror circuits closed: 1 of 2\n"
+	if text != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, text)
+	}
+	if text := breakerText(nil); text != "" {
+		t.Errorf("Expected nothing without mirrors, got '%s'", text)
+	}
+}
@@ -0,0 +1,95 @@
+package modes
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"go.uber.org/zap"
+)
+
+// auditSourceStream marks streamed files in the audit log.
+const auditSourceStream = "stream"
+
+// streamHeaders are copied from the upstream response so clients can seek.
+var streamHeaders = []string{"Content-Type", "Content-Length", "Content-Range", "Accept-Ranges", "Last-Modified", "ETag"}
+
+// streamHandler serves /stream/{md5} by proxying the upstream file, Range
+// requests included, without writing it to disk. The fast download link is
+// cached like prefetched ones, so only the first request of a reading session
+// counts against the download limits.
+func streamHandler(l *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if err := checkScope(auth.CallerFrom(ctx).Scopes, auth.ScopeDownload); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		env, err := LoadEnv(r)
+		if env != nil && env.ReadOnly {
+			http.Error(w, errReadOnly.Error(), http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, errSecretKeyMissing.Error(), http.StatusServiceUnavailable)
+			return
+		}
+
+		hash, err := anna.NormalizeHash(r.PathValue("md5"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		book := &anna.Book{Hash: hash}
+
+		var info *anna.DownloadInfo
+		if env.DownloadServer == "" {
+			info = cachedDownload(env, book)
+		}
+		if info != nil {
+			metrics.CacheHits.Inc()
+		} else {
+			if _, err := takeDownload(ctx, env); err != nil {
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+				return
+			}
+
+			info, err = resolveLink(env, book)
+			auditDownload(ctx, env, auditSourceStream, book, "", err)
+			if err != nil {
+				l.Error("Stream failed", zap.String("bookHash", hash), zap.Error(err))
+				status := http.StatusBadGateway
+				if errorCode(err) == codeFormatNotAllowed {
+					status = http.StatusForbidden
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+			cacheDownload(env, book, info)
+		}
+
+		resp, err := anna.OpenStream(ctx, info.URL, r.Header.Get("Range"))
+		if err != nil {
+			l.Error("Stream failed", zap.String("bookHash", hash), zap.Error(err))
+			http.Error(w, "failed to open upstream file", http.StatusBadGateway)
+			return
+		}
+		defer resp.Body.Close()
+
+		for _, header := range streamHeaders {
+			if value := resp.Header.Get(header); value != "" {
+				w.Header().Set(header, value)
+			}
+		}
+		w.Header().Set("Content-Disposition", "inline")
+		w.WriteHeader(resp.StatusCode)
+
+		// Readers abort requests when seeking, which is not worth logging
+		if _, err := io.Copy(w, resp.Body); err != nil && ctx.Err() == nil {
+			l.Debug("Stream interrupted", zap.String("bookHash", hash), zap.Error(err))
+		}
+	}
+}
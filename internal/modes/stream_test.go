@@ -0,0 +1,93 @@
+package modes
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+)
+
+func TestStreamHandler(t *testing.T) {
+	const hash = "d6e1dc51a50726f00ec438af21952a45"
+	content := strings.Repeat("0123456789", 100)
+
+	resolved := 0
+	var upstream *httptest.Server
+	upstream = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/dyn/api/fast_download.json":
+			resolved++
+			fmt.Fprintf(w, `{"download_url": "%s/file.pdf"}`, upstream.URL)
+		case "/md5/" + hash:
+			fmt.Fprint(w, `<html><body><main><div class="text-3xl">Dune</div><div class="text-gray-800">✅ English [en] · PDF · 1MB · 1965</div></main></body></html>`)
+		case "/file.pdf":
+			http.ServeContent(w, r, "file.pdf", time.Time{}, strings.NewReader(content))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	if err := anna.Configure(anna.Options{Mirrors: []string{upstream.URL}}); err != nil {
+		t.Fatalf("Failed to configure client: %v", err)
+	}
+	defer anna.Configure(anna.Options{})
+	t.Setenv("ANNAS_SECRET_KEY", "stream-test-key")
+
+	mux := http.NewServeMux()
+	mux.Handle("/stream/{md5}", streamHandler(logger.GetLogger()))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	get := func(t *testing.T, path, rangeHeader string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	t.Run("Range", func(t *testing.T) {
+		for _, rangeHeader := range []string{"bytes=0-9", "bytes=990-"} {
+			resp := get(t, "/stream/"+strings.ToUpper(hash), rangeHeader)
+			if resp.StatusCode != http.StatusPartialContent {
+				t.Fatalf("Expected status 206, got %d", resp.StatusCode)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if string(body) != "0123456789" {
+				t.Errorf("Expected the requested range, got '%s'", body)
+			}
+			if resp.Header.Get("Content-Range") == "" {
+				t.Errorf("Expected a Content-Range header")
+			}
+		}
+		// The link is reused while a client seeks through the file
+		if resolved != 1 {
+			t.Errorf("Expected 1 fast download, got %d", resolved)
+		}
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		t.Setenv("ANNAS_READ_ONLY", "true")
+		if resp := get(t, "/stream/"+hash, ""); resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("InvalidHash", func(t *testing.T) {
+		if resp := get(t, "/stream/nothex", ""); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("Expected status 400, got %d", resp.StatusCode)
+		}
+	})
+}
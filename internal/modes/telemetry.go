@@ -0,0 +1,168 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/i18n"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/metrics"
+	"github.com/iosifache/annas-mcp/internal/paths"
+	"github.com/iosifache/annas-mcp/internal/telemetry"
+	"github.com/iosifache/annas-mcp/internal/version"
+	"go.uber.org/zap"
+)
+
+const (
+	// telemetryInterval is the minimum time between two pings.
+	telemetryInterval = 24 * time.Hour
+	// statsFlushInterval is how often the servers add their counts to the
+	// stats file.
+	statsFlushInterval = time.Hour
+	// telemetryTimeout bounds the sending of a ping.
+	telemetryTimeout = 3 * time.Second
+)
+
+// statsStore returns the store of the local stats selected by
+// ANNAS_STATS_FILE.
+func statsStore(env *Env) *telemetry.Store {
+	switch env.StatsFile {
+	case "off":
+		return telemetry.NewStore("")
+	case "":
+		return telemetry.NewStore(paths.File(paths.State, "stats.json"))
+	default:
+		return telemetry.NewStore(env.StatsFile)
+	}
+}
+
+// run counts a single run of a mode into the local stats.
+type run struct {
+	mu    sync.Mutex
+	env   *Env
+	store *telemetry.Store
+	mode  string
+	// last is the snapshot of the counters already added to the stats, and
+	// counted whether the run itself was
+	last    metrics.Snapshot
+	counted bool
+}
+
+func newRun(env *Env, mode string) *run {
+	return &run{env: env, store: statsStore(env), mode: mode}
+}
+
+// flush adds the searches and downloads since the last flush to the stats,
+// then sends the pending counts if telemetry is enabled and a ping is due.
+// Failures are only logged, stats must never affect the command being run.
+func (r *run) flush() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l := logger.GetLogger()
+
+	snapshot := metrics.Take()
+	counts := telemetry.Counts{
+		Searches:  snapshot.Searches - r.last.Searches,
+		Downloads: snapshot.DownloadsCompleted - r.last.DownloadsCompleted,
+	}
+	if !r.counted {
+		counts.Runs = 1
+	}
+
+	now := time.Now()
+	stats, err := r.store.Add(r.mode, counts, now)
+	if err != nil {
+		l.Debug("Failed to record stats", zap.Error(err))
+		return
+	}
+	r.last, r.counted = snapshot, true
+
+	if !r.env.Telemetry || !stats.Due(now, telemetryInterval) {
+		return
+	}
+
+	ping := stats.Ping(version.GetVersion())
+	ctx, cancel := context.WithTimeout(context.Background(), telemetryTimeout)
+	defer cancel()
+	if err := telemetry.Send(ctx, r.env.TelemetryURL, ping); err != nil {
+		l.Debug("Telemetry ping failed", zap.Error(err))
+		return
+	}
+	if err := r.store.Sent(ping, now); err != nil {
+		l.Debug("Failed to record stats", zap.Error(err))
+	}
+}
+
+// startStats counts a server run of mode, flushing its counts every
+// statsFlushInterval. The returned function flushes them a last time, and
+// may be called more than once.
+func startStats(env *Env, mode string) func() {
+	r := newRun(env, mode)
+	r.flush()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(statsFlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.flush()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			r.flush()
+		})
+	}
+}
+
+// statsText renders the stats recorded in path, and whether they are sent.
+func statsText(env *Env, path string, stats *telemetry.Stats) string {
+	var b strings.Builder
+
+	if path == "" {
+		fmt.Fprintln(&b, i18n.T("Stats are kept in memory only (ANNAS_STATS_FILE=off)"))
+	} else {
+		fmt.Fprint(&b, i18n.Sprintf("Stats file: %s\n", path))
+	}
+	fmt.Fprint(&b, i18n.Sprintf("Since: %s\n", stats.Since.Local().Format(time.DateOnly)))
+
+	if len(stats.Modes) == 0 {
+		fmt.Fprint(&b, "\n", i18n.T("No runs recorded yet"), "\n")
+	} else {
+		modes := make([]string, 0, len(stats.Modes))
+		for mode := range stats.Modes {
+			modes = append(modes, mode)
+		}
+		slices.Sort(modes)
+
+		fmt.Fprintf(&b, "\n%-18s %8s %10s %10s\n", i18n.T("MODE"), i18n.T("RUNS"), i18n.T("SEARCHES"), i18n.T("DOWNLOADS"))
+		for _, mode := range modes {
+			counts := stats.Modes[mode]
+			fmt.Fprintf(&b, "%-18s %8d %10d %10d\n", mode, counts.Runs, counts.Searches, counts.Downloads)
+		}
+	}
+
+	fmt.Fprintln(&b)
+	if !env.Telemetry {
+		fmt.Fprint(&b, i18n.T("Telemetry: off, nothing is sent"))
+		return b.String()
+	}
+	fmt.Fprint(&b, i18n.Sprintf("Telemetry: on, sending the version and the counts per mode to %s at most once a day", env.TelemetryURL))
+	if !stats.LastPing.IsZero() {
+		fmt.Fprint(&b, "\n", i18n.Sprintf("Last sent: %s", stats.LastPing.Local().Format(time.DateTime)))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,105 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/booktext"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// textURITemplate addresses the pages of the text of saved books.
+const textURITemplate = "annas://text/{md5}{?page}"
+
+// textPageSize is the number of characters of a page of a text resource.
+const textPageSize = 20000
+
+// textURI returns the URI of a page of the text of a saved book.
+func textURI(hash string, page int) string {
+	return fmt.Sprintf("annas://text/%s?page=%d", hash, page)
+}
+
+// readTextPage returns page (1-based) of the text of the saved book addressed
+// by uri, with the number of pages and the URI of the next one in its _meta.
+// Pages are fixed-size chunks, so long books can be read one at a time.
+func readTextPage(env *Env, uri string) (*mcp.ReadResourceResult, error) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Host != "text" {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	hash, err := anna.NormalizeHash(strings.TrimPrefix(parsed.Path, "/"))
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	page := 1
+	if value := parsed.Query().Get("page"); value != "" {
+		if page, err = strconv.Atoi(value); err != nil || page < 1 {
+			return nil, withCode(codeInvalidArgument, "invalid page %q: expected a number from 1", value)
+		}
+	}
+
+	entry, path, err := savedBook(env, hash)
+	if err != nil {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	text, err := booktext.Extract(path, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract text of %s: %w", entry.File, err)
+	}
+	if text == "" {
+		return nil, withCode(codeInvalidArgument, "%s has no text layer, use extract_text to recognize it", entry.File)
+	}
+
+	runes := []rune(text)
+	pages := (len(runes) + textPageSize - 1) / textPageSize
+	if page > pages {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+
+	meta := mcp.Meta{"page": page, "pages": pages}
+	if page < pages {
+		meta["next"] = textURI(hash, page+1)
+	}
+
+	return &mcp.ReadResourceResult{
+		Contents: []*mcp.ResourceContents{{
+			URI:      uri,
+			MIMEType: "text/plain",
+			Text:     string(runes[(page-1)*textPageSize : min(page*textPageSize, len(runes))]),
+			Meta:     meta,
+		}},
+	}, nil
+}
+
+// NewTextResourceHandler creates a handler for the text resources of saved
+// books, reserved to callers whose token has the download scope.
+func NewTextResourceHandler(env func() *Env) mcp.ResourceHandler {
+	return func(ctx context.Context, req *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+		l := logger.GetLogger()
+
+		uri := req.Params.URI
+		l.Info("Read text resource command called", zap.String("uri", uri))
+
+		if err := checkScope(auth.CallerFrom(ctx).Scopes, auth.ScopeDownload); err != nil {
+			l.Error("Read text resource command failed", zap.String("uri", uri), zap.Error(err))
+			return nil, err
+		}
+
+		result, err := readTextPage(env(), uri)
+		if err != nil {
+			l.Error("Read text resource command failed", zap.String("uri", uri), zap.Error(err))
+			return nil, err
+		}
+
+		l.Info("Read text resource command completed successfully", zap.String("uri", uri))
+
+		return result, nil
+	}
+}
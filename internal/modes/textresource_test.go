@@ -0,0 +1,74 @@
+package modes
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestTextResource(t *testing.T) {
+	ctx := context.Background()
+	env := config.Defaults()
+	env.DownloadPath = t.TempDir()
+	env.DetectLanguage = false
+
+	path := filepath.Join(env.DownloadPath, "Dune.txt")
+	os.WriteFile(path, []byte(strings.Repeat("a", textPageSize)+strings.Repeat("b", 10)), 0o644)
+	book := &anna.Book{Hash: "d6e1dc51a50726f00ec438af21952a45", Format: "txt"}
+	if err := indexBook(env, book, path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	connect := func(t *testing.T, caller auth.Caller) *mcp.ClientSession {
+		server := createMCPServer(func() *Env { return env }, fixedCaller(caller))
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		session, err := mcp.NewClient(&mcp.Implementation{Name: "reader"}, nil).Connect(ctx, clientTransport, nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		t.Cleanup(func() { session.Close() })
+		return session
+	}
+
+	t.Run("Pages", func(t *testing.T) {
+		session := connect(t, auth.Local)
+
+		first, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "annas://text/" + book.Hash})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		content := first.Contents[0]
+		if len(content.Text) != textPageSize || content.Meta["next"] != textURI(book.Hash, 2) {
+			t.Errorf("Expected a full first page linking to the second, got %d characters and %v", len(content.Text), content.Meta)
+		}
+
+		second, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: textURI(book.Hash, 2)})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if content := second.Contents[0]; content.Text != strings.Repeat("b", 10) || content.Meta["next"] != nil {
+			t.Errorf("Expected the last page, got '%s' and %v", content.Text, content.Meta)
+		}
+
+		if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: textURI(book.Hash, 3)}); err == nil {
+			t.Errorf("Expected an error past the last page")
+		}
+	})
+
+	t.Run("Forbidden", func(t *testing.T) {
+		session := connect(t, auth.Caller{Scopes: auth.Scopes{auth.ScopeSearch}})
+		if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: textURI(book.Hash, 1)}); err == nil {
+			t.Errorf("Expected an error without the download scope")
+		}
+	})
+}
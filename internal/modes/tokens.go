@@ -0,0 +1,189 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/i18n"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// tokenStore holds the scoped API tokens of the HTTP mode.
+var tokenStore atomic.Pointer[auth.Store]
+
+// loadTokens loads the tokens file of env into the token store.
+func loadTokens(env *Env) error {
+	l := logger.GetLogger()
+
+	store, err := auth.Load(env.TokensFile)
+	if err != nil {
+		return err
+	}
+	if env.TokensFile != "" {
+		l.Info("Loaded API tokens", zap.String("path", env.TokensFile), zap.Int("count", store.Len()))
+	}
+
+	tokenStore.Store(store)
+	return nil
+}
+
+// newAuthProvider returns the authentication provider selected by env. The
+// key and basic providers check the token store as it is reloaded, and the
+// sessions of the OAuth login are accepted by every provider.
+func newAuthProvider(env *Env) auth.Provider {
+	var provider auth.Provider
+	switch env.AuthProvider {
+	case auth.ProviderNone:
+		provider = auth.NoneProvider{}
+	case auth.ProviderBasic:
+		provider = auth.NewBasicProvider(env.APIKey, tokenStore.Load)
+	case auth.ProviderOIDC:
+		provider = auth.NewOIDCProvider(env.OIDCIssuer, env.OIDCAudience, env.APIKey)
+	default:
+		provider = auth.NewKeyProvider(env.APIKey, tokenStore.Load)
+	}
+
+	if sessions := oauthSessions.Load(); sessions != nil {
+		provider = auth.NewSessionProvider(provider, sessions, refreshSession)
+	}
+	return provider
+}
+
+// requestCredentials returns the credentials of r. Clients that cannot set
+// headers, such as feed readers and indexer clients, may pass their key as
+// the apikey query parameter when query is set, and browsers signed in
+// through the OAuth login send their session cookie.
+func requestCredentials(r *http.Request, query bool) auth.Credentials {
+	credentials := auth.Credentials{Key: headerKey(r)}
+	if credentials.Key == "" && query {
+		credentials.Key = r.URL.Query().Get("apikey")
+	}
+	if cookie, err := r.Cookie(sessionCookie); err == nil && credentials.Key == "" {
+		credentials.Key = cookie.Value
+	}
+	if username, password, ok := r.BasicAuth(); ok {
+		credentials.Username, credentials.Password = username, password
+	}
+
+	return credentials
+}
+
+// callerLookup returns the caller of an MCP request.
+type callerLookup func(ctx context.Context, req mcp.Request) (auth.Caller, error)
+
+// fixedCaller returns the lookup of servers whose requests all come from
+// caller, such as in stdio mode.
+func fixedCaller(caller auth.Caller) callerLookup {
+	return func(context.Context, mcp.Request) (auth.Caller, error) {
+		return caller, nil
+	}
+}
+
+// requestCaller returns the lookup of a session opened by r, authenticating
+// every request of the session with provider: with the headers of its own
+// HTTP request where the transport passes them, as Streamable HTTP does, and
+// with the credentials of r otherwise. Tokens revoked or re-scoped while the
+// session runs thus take effect on its next request.
+func requestCaller(provider auth.Provider, r *http.Request) callerLookup {
+	opened := requestCredentials(r, false)
+	return func(ctx context.Context, req mcp.Request) (auth.Caller, error) {
+		credentials := opened
+		if extra := req.GetExtra(); extra != nil && extra.Header != nil {
+			credentials = requestCredentials(&http.Request{Header: extra.Header}, false)
+		}
+
+		caller, err := provider.Authenticate(ctx, credentials)
+		if err != nil {
+			logger.GetLogger().Warn("Rejected request of a session whose credentials are no longer valid", zap.Error(err))
+			return auth.Caller{}, withCode(codeForbidden, "%w", err)
+		}
+		return caller, nil
+	}
+}
+
+// withCallers looks up the caller of every request to server, and stores it
+// in the context of the handlers.
+func withCallers(server *mcp.Server, caller callerLookup) {
+	server.AddReceivingMiddleware(func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			current, err := caller(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			return next(auth.WithCaller(ctx, current), method, req)
+		}
+	})
+}
+
+// wrapTool wraps a tool handler so that it rejects callers whose token lacks
+// scope and reports errors as results with an error code. The handler finds
+// the caller in its context.
+func wrapTool[P any](scope string, handler func(context.Context, *mcp.CallToolRequest, P) (*mcp.CallToolResult, any, error)) func(context.Context, *mcp.CallToolRequest, P) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params P) (*mcp.CallToolResult, any, error) {
+		caller := auth.CallerFrom(ctx)
+		if err := checkScope(caller.Scopes, scope); err != nil {
+			return errorResult(err), nil, nil
+		}
+		if req != nil && req.Session != nil {
+			caller.Session = req.Session.ID()
+		}
+
+		result, out, err := handler(auth.WithCaller(ctx, caller), req, params)
+		if err != nil {
+			return errorResult(err), nil, nil
+		}
+		return result, out, nil
+	}
+}
+
+// tokensText lists tokens with their scopes and quotas, never their secrets.
+func tokensText(tokens []auth.Token) string {
+	if len(tokens) == 0 {
+		return i18n.T("No API tokens.")
+	}
+
+	var text strings.Builder
+	for _, token := range tokens {
+		fmt.Fprintf(&text, "%s: %s", token.Name, strings.Join(token.Scopes, ", "))
+		if quota := token.Quota; quota != nil {
+			var limits []string
+			for _, limit := range []struct {
+				max    int
+				format string
+			}{
+				{quota.SearchesPerDay, "%d searches per day"},
+				{quota.SearchesPerMonth, "%d searches per month"},
+				{quota.DownloadsPerDay, "%d downloads per day"},
+				{quota.DownloadsPerMonth, "%d downloads per month"},
+			} {
+				if limit.max > 0 {
+					limits = append(limits, i18n.Sprintf(limit.format, limit.max))
+				}
+			}
+			if len(limits) > 0 {
+				text.WriteString(i18n.Sprintf(", quota of %s", strings.Join(limits, ", ")))
+			}
+		}
+		if !token.CreatedAt.IsZero() {
+			text.WriteString(i18n.Sprintf(", created %s", token.CreatedAt.Format("2006-01-02 15:04 MST")))
+		}
+		text.WriteString("\n")
+	}
+
+	return text.String()
+}
+
+func checkScope(scopes auth.Scopes, scope string) error {
+	if !scopes.Has(scope) {
+		logger.GetLogger().Warn("Rejected call without the required scope", zap.String("scope", scope))
+		return withCode(codeForbidden, "this API token lacks the %q scope", scope)
+	}
+
+	return nil
+}
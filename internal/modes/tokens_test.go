@@ -0,0 +1,108 @@
+package modes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/config"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestToolScopes(t *testing.T) {
+	ctx := context.Background()
+	env := config.Defaults()
+	env.DownloadPath = t.TempDir()
+
+	tokens := filepath.Join(t.TempDir(), "tokens.json")
+	setTokens := func(t *testing.T, content string) {
+		if err := os.WriteFile(tokens, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		store, err := auth.Load(tokens)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		tokenStore.Store(store)
+	}
+	setTokens(t, `{"tokens":[{"name":"reader","token":"reader-secret","scopes":["search"]}]}`)
+	t.Cleanup(func() { tokenStore.Store(nil) })
+
+	// The session is opened by a request carrying the token
+	r := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+	r.Header.Set("Authorization", "Bearer reader-secret")
+	provider := auth.NewKeyProvider("operator-key", tokenStore.Load)
+	server := createMCPServer(func() *Env { return env }, requestCaller(provider, r))
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "reader"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer session.Close()
+
+	download := func() (*mcp.CallToolResult, error) {
+		return session.CallTool(ctx, &mcp.CallToolParams{Name: "download", Arguments: map[string]any{"hash": "invalid", "title": "Dune", "format": "epub"}})
+	}
+
+	result, err := download()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.HasPrefix(result.Content[0].(*mcp.TextContent).Text, codeForbidden) {
+		t.Errorf("Expected a search-only token to be refused downloads with %s, got %v", codeForbidden, result.Content)
+	}
+
+	// Scopes granted while the session runs apply to its next call
+	setTokens(t, `{"tokens":[{"name":"reader","token":"reader-secret","scopes":["search","download"]}]}`)
+	result, err = download()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.HasPrefix(result.Content[0].(*mcp.TextContent).Text, codeForbidden) {
+		t.Errorf("Expected the new download scope to apply, got %v", result.Content)
+	}
+
+	// So does revoking the token
+	setTokens(t, `{"tokens":[]}`)
+	if _, err := download(); err == nil || !strings.Contains(err.Error(), "invalid") {
+		t.Errorf("Expected the revoked token to be refused, got %v", err)
+	}
+}
+
+func TestMirrorRefreshScope(t *testing.T) {
+	ctx := context.Background()
+	server := createMCPServer(func() *Env { return &Env{} }, fixedCaller(auth.Caller{Name: "reader", Scopes: auth.Scopes{auth.ScopeSearch}}))
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "reader"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer session.Close()
+
+	result, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "mirror_status", Arguments: map[string]any{"refresh": true}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.IsError || !strings.HasPrefix(result.Content[0].(*mcp.TextContent).Text, codeForbidden) {
+		t.Errorf("Expected refreshing without the admin scope to be refused with %s, got %v", codeForbidden, result.Content)
+	}
+
+	result, err = session.CallTool(ctx, &mcp.CallToolParams{Name: "mirror_status", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Errorf("Expected the last probes to be reported, got %v", result.Content)
+	}
+}
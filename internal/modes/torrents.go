@@ -0,0 +1,56 @@
+package modes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// ListTorrentsToolHandler lists the dataset torrents released by Anna's
+// Archive, for mirroring whole collections.
+func ListTorrentsToolHandler(ctx context.Context, req *mcp.CallToolRequest, params ListTorrentsParams) (*mcp.CallToolResult, any, error) {
+	l := logger.GetLogger()
+	l.Info("List torrents command called", zap.String("group", params.Group))
+
+	torrents, err := anna.ListTorrents(params.Group)
+	if err != nil {
+		l.Error("List torrents command failed", zap.String("group", params.Group), zap.Error(err))
+		return nil, nil, err
+	}
+
+	l.Info("List torrents command completed successfully", zap.Int("torrentsCount", len(torrents)))
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: torrentsText(torrents)}},
+	}, map[string]interface{}{"torrents": torrents}, nil
+}
+
+// torrentsText lists torrents under the summary of their group.
+func torrentsText(torrents []*anna.Torrent) string {
+	if len(torrents) == 0 {
+		return "No torrents found."
+	}
+
+	var text strings.Builder
+	group := ""
+	for i, torrent := range torrents {
+		if i == 0 || torrent.Group != group {
+			group = torrent.Group
+			if i > 0 {
+				text.WriteString("\n")
+			}
+			fmt.Fprintf(&text, "## %s\n", group)
+			if torrent.Summary != "" {
+				fmt.Fprintf(&text, "%s\n", torrent.Summary)
+			}
+		}
+		fmt.Fprintf(&text, "- %s (%s, %d seeders): %s\n", torrent.Name, torrent.Size, torrent.Seeders, torrent.URL)
+	}
+
+	return text.String()
+}
@@ -0,0 +1,145 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/i18n"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/paths"
+	"github.com/iosifache/annas-mcp/internal/version"
+	"go.uber.org/zap"
+)
+
+const (
+	// updateCheckTimeout bounds a single lookup of the latest release.
+	updateCheckTimeout = 5 * time.Second
+	// updateCheckWait is how long a CLI command waits at exit for a pending
+	// check before giving up on the warning.
+	updateCheckWait = 500 * time.Millisecond
+	// updateCheckInterval is how often the HTTP server checks again, and
+	// how long the release found is reused by the next commands.
+	updateCheckInterval = 24 * time.Hour
+)
+
+// releaseCache is the file in the cache directory remembering the latest
+// release, so that not every CLI command asks GitHub.
+const releaseCache = "latest-release.json"
+
+// cachedRelease is the content of the release cache.
+type cachedRelease struct {
+	Checked time.Time       `json:"checked"`
+	Release version.Release `json:"release"`
+}
+
+// loadCachedRelease returns the release found by a check less than
+// updateCheckInterval ago, if any.
+func loadCachedRelease() *version.Release {
+	data, err := os.ReadFile(paths.File(paths.Cache, releaseCache))
+	if err != nil {
+		return nil
+	}
+	var cached cachedRelease
+	if err := json.Unmarshal(data, &cached); err != nil || time.Since(cached.Checked) >= updateCheckInterval {
+		return nil
+	}
+
+	return &cached.Release
+}
+
+// saveCachedRelease remembers release in the release cache. The cache is
+// only an optimization, so failures are ignored.
+func saveCachedRelease(release *version.Release) {
+	file := paths.File(paths.Cache, releaseCache)
+	if file == "" {
+		return
+	}
+	data, err := json.Marshal(cachedRelease{Checked: time.Now().UTC(), Release: *release})
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return
+	}
+	_ = os.WriteFile(file, data, 0o644)
+}
+
+// updateCheckDone is closed once the background check started by
+// startUpdateCheck has finished.
+var updateCheckDone chan struct{}
+
+// checkForUpdate looks up the latest release, logging failures only since
+// an unreachable GitHub must never affect the command being run.
+func checkForUpdate() {
+	ctx, cancel := context.WithTimeout(context.Background(), updateCheckTimeout)
+	defer cancel()
+
+	if release := loadCachedRelease(); release != nil {
+		version.Remember(release)
+	} else if release, err := version.CheckForUpdate(ctx); err != nil {
+		logger.GetLogger().Debug("Update check failed", zap.Error(err))
+		return
+	} else {
+		saveCachedRelease(release)
+	}
+	if release := version.UpdateAvailable(); release != nil {
+		logger.GetLogger().Warn("A newer version is available",
+			zap.String("version", release.Version),
+			zap.String("url", release.URL),
+		)
+	}
+}
+
+// startUpdateCheck checks for a newer release in the background, unless
+// disabled through ANNAS_UPDATE_CHECK.
+func startUpdateCheck(env *Env) {
+	if !env.UpdateCheck || updateCheckDone != nil {
+		return
+	}
+
+	done := make(chan struct{})
+	updateCheckDone = done
+	go func() {
+		defer close(done)
+		checkForUpdate()
+	}()
+}
+
+// startUpdateChecks keeps checking for newer releases for the lifetime of
+// the HTTP server, so the server card stays current.
+func startUpdateChecks(env *Env) {
+	if !env.UpdateCheck {
+		return
+	}
+
+	startUpdateCheck(env)
+	go func() {
+		ticker := time.NewTicker(updateCheckInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			checkForUpdate()
+		}
+	}()
+}
+
+// warnIfOutdated prints a notice to stderr when the background check found
+// a newer release. It waits briefly for a check still in flight.
+func warnIfOutdated() {
+	if updateCheckDone == nil {
+		return
+	}
+
+	select {
+	case <-updateCheckDone:
+	case <-time.After(updateCheckWait):
+		return
+	}
+
+	if release := version.UpdateAvailable(); release != nil {
+		fmt.Fprint(os.Stderr, i18n.Sprintf("A newer version of annas-mcp is available: %s (running %s)\n%s\n", release.Version, version.GetVersion(), release.URL))
+	}
+}
@@ -0,0 +1,133 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/usage"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// usageTracker counts the searches and downloads of every token of the HTTP
+// mode. Other modes have a single tenant and track nothing.
+var usageTracker atomic.Pointer[usage.Tracker]
+
+// loadUsage opens the usage file of env into the usage tracker. Without a
+// file, usage is counted in memory until the server stops.
+func loadUsage(env *Env) error {
+	tracker, err := usage.Open(env.UsageFile)
+	if err != nil {
+		return err
+	}
+	if env.UsageFile != "" {
+		logger.GetLogger().Info("Loaded usage", zap.String("path", env.UsageFile))
+	}
+
+	usageTracker.Store(tracker)
+	return nil
+}
+
+// chargeUsage counts a request of kind against the quota of the caller of
+// ctx, failing once the quota is used up.
+func chargeUsage(ctx context.Context, kind string) error {
+	tracker := usageTracker.Load()
+	if tracker == nil {
+		return nil
+	}
+
+	caller := auth.CallerFrom(ctx)
+	var quota auth.Quota
+	if caller.Quota != nil {
+		quota = *caller.Quota
+	}
+
+	_, err := tracker.Record(caller.Name, kind, quota, time.Now())
+	if err != nil {
+		logger.GetLogger().Warn("Rejected request over the usage quota",
+			zap.String("caller", caller.Name),
+			zap.String("kind", kind),
+			zap.Error(err),
+		)
+	}
+	return err
+}
+
+// usageReport is the usage of a caller along with its quota.
+type usageReport struct {
+	usage.Usage
+	Quota *auth.Quota `json:"quota,omitempty"`
+}
+
+// callerUsage returns the usage of the caller of ctx, or of every tenant for
+// admins that ask for it.
+func callerUsage(ctx context.Context, all bool) (any, error) {
+	caller := auth.CallerFrom(ctx)
+	if all {
+		if err := checkScope(caller.Scopes, auth.ScopeAdmin); err != nil {
+			return nil, err
+		}
+	}
+
+	tracker := usageTracker.Load()
+	if tracker == nil {
+		return nil, withCode(codeNotConfigured, "usage is only tracked in HTTP mode")
+	}
+
+	now := time.Now()
+	if all {
+		return map[string]interface{}{"tenants": tracker.All(now)}, nil
+	}
+	return usageReport{Usage: tracker.Get(caller.Name, now), Quota: caller.Quota}, nil
+}
+
+// UsageToolHandler reports what the caller used of its quota.
+func UsageToolHandler(ctx context.Context, req *mcp.CallToolRequest, params UsageParams) (*mcp.CallToolResult, any, error) {
+	l := logger.GetLogger()
+
+	l.Info("Usage command called", zap.Bool("all", params.All))
+
+	report, err := callerUsage(ctx, params.All)
+	if err != nil {
+		l.Error("Usage command failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		l.Error("Usage command failed", zap.Error(err))
+		return nil, nil, err
+	}
+
+	l.Info("Usage command completed successfully")
+
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: string(data)}},
+	}, map[string]interface{}{"usage": report}, nil
+}
+
+// usageHandler serves the usage of the caller as JSON, or of every tenant
+// with ?all for admins.
+func usageHandler(l *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := callerUsage(r.Context(), r.URL.Query().Has("all"))
+		if err != nil {
+			status := http.StatusForbidden
+			if errorCode(err) == codeNotConfigured {
+				status = http.StatusServiceUnavailable
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			l.Error("Failed to encode usage", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package modes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"go.uber.org/zap"
+)
+
+// maxValidateBody bounds the candidate configurations read by /config/validate.
+const maxValidateBody = 64 << 10
+
+// checkResult is the outcome of one check of a candidate configuration.
+type checkResult struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message"`
+}
+
+// validation reports whether a candidate configuration is usable.
+type validation struct {
+	Valid        bool        `json:"valid"`
+	SecretKey    checkResult `json:"secret_key"`
+	DownloadPath checkResult `json:"download_path"`
+}
+
+// candidateRequest returns a copy of r carrying the settings of a candidate
+// configuration as query parameters, so they are applied, confined, and
+// restricted exactly like those of a session. Values are the ones of the
+// session config schema: strings, numbers, booleans, or lists of strings.
+func candidateRequest(r *http.Request, settings map[string]interface{}) (*http.Request, error) {
+	query := make(url.Values, len(settings))
+	for name, value := range settings {
+		switch value := value.(type) {
+		case string:
+			query.Set(name, value)
+		case bool, float64:
+			query.Set(name, fmt.Sprint(value))
+		case []interface{}:
+			parts := make([]string, 0, len(value))
+			for _, part := range value {
+				parts = append(parts, fmt.Sprint(part))
+			}
+			query.Set(name, strings.Join(parts, ","))
+		case nil:
+		default:
+			return nil, fmt.Errorf("invalid value for %s", name)
+		}
+	}
+
+	candidate := r.Clone(r.Context())
+	candidate.URL.RawQuery = query.Encode()
+
+	return candidate, nil
+}
+
+// validateEnv checks that the fast download API accepts the secret key of env
+// and that its download path is writable. The path is not needed, and not
+// checked, in read-only mode.
+func validateEnv(env *Env) *validation {
+	result := &validation{}
+
+	var apiErr *anna.APIError
+	if keys := env.Keys(); len(keys) == 0 {
+		result.SecretKey.Message = "no secret key is set"
+	} else if err := anna.CheckKey(keys[0]); err == nil {
+		result.SecretKey = checkResult{OK: true, Message: "the fast download API accepts the key"}
+	} else if errors.As(err, &apiErr) {
+		result.SecretKey.Message = "the fast download API rejects the key: " + apiErr.Message
+	} else {
+		result.SecretKey.Message = "the fast download API could not be reached: " + err.Error()
+	}
+
+	if env.ReadOnly {
+		result.DownloadPath = checkResult{OK: true, Message: "not needed in read-only mode"}
+	} else if err := checkDownloadPath(env); err != nil {
+		result.DownloadPath.Message = err.Error()
+	} else {
+		result.DownloadPath = checkResult{OK: true, Message: env.DownloadPath + " is writable"}
+	}
+
+	result.Valid = result.SecretKey.OK && result.DownloadPath.OK
+
+	return result
+}
+
+// validateHandler checks a candidate configuration posted as a JSON object of
+// session settings, the properties of /.well-known/mcp-config, without
+// establishing an MCP session. Settings left out fall back to the request
+// headers and the server configuration, like for sessions.
+func validateHandler(l *zap.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		settings := make(map[string]interface{})
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxValidateBody)).Decode(&settings); err != nil {
+			http.Error(w, "invalid candidate configuration: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		candidate, err := candidateRequest(r, settings)
+		if err != nil {
+			http.Error(w, "invalid candidate configuration: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// A missing key is reported in the validation rather than failing it
+		env, _ := LoadEnv(candidate)
+		if env == nil {
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		result := validateEnv(env)
+		l.Info("Configuration validated",
+			zap.Bool("valid", result.Valid),
+			zap.Bool("secretKey", result.SecretKey.OK),
+			zap.Bool("downloadPath", result.DownloadPath.OK),
+		)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			l.Error("Failed to encode validation", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package modes
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/config"
+)
+
+func TestValidateEnv(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") == "good" {
+			fmt.Fprint(w, `{"download_url": null, "error": "Record not found"}`)
+			return
+		}
+		fmt.Fprint(w, `{"download_url": null, "error": "Invalid secret key"}`)
+	}))
+	defer server.Close()
+	if err := anna.Configure(anna.Options{Mirrors: []string{server.URL}}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer anna.Configure(anna.Options{})
+
+	base := config.Defaults()
+	base.DownloadPath = t.TempDir()
+	base.MinFreeSpaceMB = 0
+
+	t.Run("valid", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "http://example.com/config/validate", nil)
+		candidate, err := candidateRequest(req, map[string]interface{}{"secretKey": "good", "downloadPath": "books"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		env := base.WithRequest(candidate)
+		if env.DownloadPath != filepath.Join(base.DownloadPath, "books") {
+			t.Errorf("Expected the download path to be confined, got '%s'", env.DownloadPath)
+		}
+		if result := validateEnv(env); !result.Valid {
+			t.Errorf("Expected a valid configuration, got %+v", result)
+		}
+	})
+
+	t.Run("rejected key", func(t *testing.T) {
+		env := *base
+		env.SecretKey = "bad"
+		if result := validateEnv(&env); result.Valid || result.SecretKey.OK || !result.DownloadPath.OK {
+			t.Errorf("Expected only the key to be rejected, got %+v", result)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		if result := validateEnv(base); result.SecretKey.OK {
+			t.Errorf("Expected a missing key to fail, got %+v", result)
+		}
+	})
+
+	t.Run("invalid value", func(t *testing.T) {
+		req, _ := http.NewRequest("POST", "http://example.com/config/validate", nil)
+		if _, err := candidateRequest(req, map[string]interface{}{"secretKey": map[string]interface{}{}}); err == nil {
+			t.Errorf("Expected an error for an object value")
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package modes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/iosifache/annas-mcp/internal/shelves"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+)
+
+// shelfMatch reports how a single want-to-read entry was resolved.
+type shelfMatch struct {
+	Entry shelves.Entry `json:"entry"`
+	Match *anna.Book    `json:"match,omitempty"`
+	Path  string        `json:"path,omitempty"`
+	Error string        `json:"error,omitempty"`
+}
+
+// loadShelf reads the want-to-read entries from the requested source.
+func loadShelf(ctx context.Context, env *Env, source string, csvData io.Reader) ([]shelves.Entry, error) {
+	switch strings.ToLower(source) {
+	case "goodreads":
+		if csvData == nil {
+			return nil, withCode(codeInvalidArgument, "a Goodreads CSV export is required")
+		}
+		return shelves.ParseGoodreadsCSV(csvData)
+	case "hardcover":
+		if env.HardcoverToken == "" {
+			return nil, withCode(codeNotConfigured, "Hardcover is not configured. Please set ANNAS_HARDCOVER_TOKEN")
+		}
+		return shelves.FetchHardcover(ctx, env.HardcoverToken)
+	default:
+		return nil, withCode(codeInvalidArgument, "unknown source %q (must be 'goodreads' or 'hardcover')", source)
+	}
+}
+
+// matchEntry searches Anna's Archive for the entry, trying the ISBN first and
+// falling back to title and author.
+func matchEntry(entry shelves.Entry) (*anna.Book, error) {
+	queries := make([]string, 0, 2)
+	if entry.ISBN != "" {
+		queries = append(queries, entry.ISBN)
+	}
+	queries = append(queries, strings.TrimSpace(entry.Title+" "+entry.Author))
+
+	for _, query := range queries {
+		books, err := anna.FindBook(query)
+		if err != nil {
+			return nil, err
+		}
+		if book := bestMatch(books, entry.Title); book != nil {
+			return book, nil
+		}
+	}
+
+	return nil, errors.New("no match found")
+}
+
+// bestMatch prefers the first result whose title contains the wanted title.
+func bestMatch(books []*anna.Book, title string) *anna.Book {
+	if len(books) == 0 {
+		return nil
+	}
+
+	wanted := strings.ToLower(strings.TrimSpace(title))
+	for _, book := range books {
+		if wanted != "" && strings.Contains(strings.ToLower(book.Title), wanted) {
+			return book
+		}
+	}
+
+	return books[0]
+}
+
+// syncShelf matches every entry and, if requested, saves the matches,
+// notifying of the end of the batch.
+func syncShelf(ctx context.Context, env *Env, entries []shelves.Entry, download bool) []shelfMatch {
+	l := logger.GetLogger()
+	results := make([]shelfMatch, 0, len(entries))
+	done, failed := 0, 0
+
+	for _, entry := range entries {
+		result := shelfMatch{Entry: entry}
+
+		book, err := matchEntry(entry)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+		result.Match = book
+
+		if download {
+			if _, err := takeDownload(ctx, env); err != nil {
+				result.Error = err.Error()
+				results = append(results, result)
+				continue
+			}
+
+			path, err := saveBook(env, book)
+			auditDownload(ctx, env, auditSourceWantToRead, book, path, err)
+			if err != nil {
+				l.Warn("Failed to download want-to-read match",
+					zap.String("title", entry.Title),
+					zap.Error(err),
+				)
+				result.Error = err.Error()
+				failed++
+			} else {
+				done++
+			}
+			result.Path = path
+		}
+
+		results = append(results, result)
+	}
+
+	if done+failed > 0 {
+		dispatcher := newDispatcher(env)
+		dispatcher.Publish(batchEvent(auditSourceWantToRead, done, failed))
+		dispatcher.Wait()
+	}
+
+	return results
+}
+
+// NewWantToReadToolHandler creates a handler for the sync_want_to_read tool that uses the provided environment.
+func NewWantToReadToolHandler(env *Env) func(context.Context, *mcp.CallToolRequest, WantToReadParams) (*mcp.CallToolResult, any, error) {
+	return func(ctx context.Context, req *mcp.CallToolRequest, params WantToReadParams) (*mcp.CallToolResult, any, error) {
+		l := logger.GetLogger()
+
+		l.Info("Want to read sync called",
+			zap.String("source", params.Source),
+			zap.Bool("download", params.Download),
+		)
+
+		if params.Download && len(env.Keys()) == 0 {
+			err := errSecretKeyMissing
+			l.Error("Want to read sync failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		if params.Download {
+			if err := checkDownloadPath(env); err != nil {
+				l.Error("Want to read sync failed", zap.Error(err))
+				return nil, nil, err
+			}
+		}
+
+		var csvData io.Reader
+		if params.CSV != "" {
+			csvData = strings.NewReader(params.CSV)
+		}
+
+		entries, err := loadShelf(ctx, env, params.Source, csvData)
+		if err != nil {
+			l.Error("Want to read sync failed", zap.Error(err))
+			return nil, nil, err
+		}
+
+		results := syncShelf(ctx, env, entries, params.Download)
+
+		data, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		l.Info("Want to read sync completed successfully",
+			zap.Int("entriesCount", len(entries)),
+		)
+
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: string(data)}},
+		}, map[string]interface{}{"results": results}, nil
+	}
+}
@@ -0,0 +1,116 @@
+package modes
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/modelcontextprotocol/go-sdk/jsonrpc"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+)
+
+// websocketSubprotocol is offered by clients that speak MCP over WebSocket.
+const websocketSubprotocol = "mcp"
+
+// maxWebSocketMessageBytes bounds a single JSON-RPC message from a client.
+const maxWebSocketMessageBytes = 4 << 20
+
+// websocketConn carries JSON-RPC messages as WebSocket text messages, one
+// message per frame.
+type websocketConn struct {
+	ws        *websocket.Conn
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// websocketTransport connects an MCP server or client over an established
+// WebSocket.
+type websocketTransport struct {
+	conn *websocketConn
+}
+
+func newWebSocketTransport(ws *websocket.Conn) *websocketTransport {
+	ws.MaxPayloadBytes = maxWebSocketMessageBytes
+	return &websocketTransport{conn: &websocketConn{ws: ws}}
+}
+
+func (t *websocketTransport) Connect(context.Context) (mcp.Connection, error) {
+	return t.conn, nil
+}
+
+// Read blocks until the next message arrives. Closing the connection
+// unblocks it.
+func (c *websocketConn) Read(ctx context.Context) (jsonrpc.Message, error) {
+	var data []byte
+	if err := websocket.Message.Receive(c.ws, &data); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	return jsonrpc.DecodeMessage(data)
+}
+
+func (c *websocketConn) Write(ctx context.Context, msg jsonrpc.Message) error {
+	data, err := jsonrpc.EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return websocket.Message.Send(c.ws, string(data))
+}
+
+func (c *websocketConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.ws.Close()
+	})
+	return c.closeErr
+}
+
+// SessionID is empty, a WebSocket is a session of its own.
+func (c *websocketConn) SessionID() string {
+	return ""
+}
+
+// websocketHandler serves one MCP session per WebSocket, with the server
+// returned by serverFactory for the upgrade request. Clients authenticate on
+// the upgrade request like on the other transports, so any origin is
+// accepted.
+func websocketHandler(serverFactory func(*http.Request) *mcp.Server, l *zap.Logger) http.Handler {
+	return websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			for _, protocol := range config.Protocol {
+				if protocol == websocketSubprotocol {
+					config.Protocol = []string{websocketSubprotocol}
+					return nil
+				}
+			}
+			config.Protocol = nil
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			r := ws.Request()
+			server := serverFactory(r)
+
+			// The socket is closed when the handler returns, so it waits for
+			// the session to end
+			session, err := server.Connect(r.Context(), newWebSocketTransport(ws), nil)
+			if err != nil {
+				l.Error("Failed to start WebSocket session", zap.Error(err))
+				return
+			}
+			if err := session.Wait(); err != nil {
+				l.Debug("WebSocket session ended", zap.Error(err))
+			}
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package modes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"golang.org/x/net/websocket"
+)
+
+func TestWebSocketTransport(t *testing.T) {
+	serverFactory := func(r *http.Request) *mcp.Server {
+		return createMCPServer(func() *Env { return &Env{} }, fixedCaller(auth.Caller{}))
+	}
+	server := httptest.NewServer(websocketHandler(serverFactory, logger.GetLogger()))
+	defer server.Close()
+
+	config, err := websocket.NewConfig("ws"+strings.TrimPrefix(server.URL, "http"), server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	config.Protocol = []string{websocketSubprotocol}
+	ws, err := websocket.DialConfig(config)
+	if err != nil {
+		t.Fatalf("Unexpected error while dialing: %v", err)
+	}
+	if ws.Config().Protocol[0] != websocketSubprotocol {
+		t.Errorf("Expected subprotocol '%s', got %v", websocketSubprotocol, ws.Config().Protocol)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := mcp.NewClient(&mcp.Implementation{Name: "test"}, nil)
+	session, err := client.Connect(ctx, newWebSocketTransport(ws), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error while initializing: %v", err)
+	}
+	defer session.Close()
+
+	tools, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error while listing tools: %v", err)
+	}
+	found := false
+	for _, tool := range tools.Tools {
+		found = found || tool.Name == "search"
+	}
+	if !found {
+		t.Errorf("Expected the search tool over WebSocket, got %d tools", len(tools.Tools))
+	}
+}
@@ -0,0 +1,53 @@
+package notify
+
+import (
+	"context"
+	"sync"
+)
+
+// Broker hands events to in-process subscribers, such as the clients of the
+// /events endpoint. Subscribers that fall behind miss events instead of
+// slowing down the downloads publishing them.
+type Broker struct {
+	mu   sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[chan Event]struct{})}
+}
+
+// Subscribe returns a channel receiving every event published from now on,
+// buffering up to buffer of them, and a function ending the subscription.
+func (b *Broker) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Notify passes the event to every subscriber with room for it.
+func (b *Broker) Notify(ctx context.Context, event Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+
+	return nil
+}
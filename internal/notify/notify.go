@@ -0,0 +1,101 @@
+package notify
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	EventDownloadQueued    = "download.queued"
+	EventDownloadProgress  = "download.progress"
+	EventDownloadCompleted = "download.completed"
+	EventDownloadFailed    = "download.failed"
+	EventSearchNewResult   = "search.new_result"
+	// EventBatchCompleted closes a batch of queued downloads, and
+	// EventSearchCompleted a scheduled search run with new results. They are
+	// the only events pushed to phones.
+	EventBatchCompleted  = "batch.completed"
+	EventSearchCompleted = "search.completed"
+)
+
+// deliveryTimeout bounds how long a single backend may take to accept an event.
+const deliveryTimeout = 10 * time.Second
+
+type Event struct {
+	Type      string    `json:"event"`
+	Timestamp time.Time `json:"timestamp"`
+	Hash      string    `json:"hash,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Format    string    `json:"format,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	// Bytes and Total report how far a download got, Total being 0 while
+	// the size is unknown.
+	Bytes int64 `json:"bytes,omitempty"`
+	Total int64 `json:"total,omitempty"`
+	// Query is the saved search that found a new result.
+	Query string `json:"query,omitempty"`
+	// Source is the tool that queued a batch, Done and Failed count its
+	// downloads.
+	Source string `json:"source,omitempty"`
+	Done   int    `json:"done,omitempty"`
+	Failed int    `json:"failed,omitempty"`
+	// Results counts the new results of a scheduled search run.
+	Results int `json:"results,omitempty"`
+}
+
+// Notifier is implemented by every notification backend.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Dispatcher fans events out to the configured backends without blocking the caller.
+type Dispatcher struct {
+	notifiers []Notifier
+	wg        sync.WaitGroup
+}
+
+func NewDispatcher(notifiers ...Notifier) *Dispatcher {
+	return &Dispatcher{notifiers: notifiers}
+}
+
+// Publish delivers the event to all backends in the background. Delivery
+// failures are logged and otherwise ignored.
+func (d *Dispatcher) Publish(event Event) {
+	if d == nil || len(d.notifiers) == 0 {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		l := logger.GetLogger()
+
+		for _, n := range d.notifiers {
+			ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+			if err := n.Notify(ctx, event); err != nil {
+				l.Warn("Failed to deliver notification",
+					zap.String("event", event.Type),
+					zap.Error(err),
+				)
+			}
+			cancel()
+		}
+	}()
+}
+
+// Wait blocks until all published events have been delivered. Short-lived
+// processes such as CLI commands should call it before exiting.
+func (d *Dispatcher) Wait() {
+	if d == nil {
+		return
+	}
+	d.wg.Wait()
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const PushoverEndpoint = "https://api.pushover.net/1/messages.json"
+
+// Ntfy publishes human-readable alerts to an ntfy topic URL such as
+// https://ntfy.sh/my-topic.
+type Ntfy struct {
+	TopicURL string
+	Token    string
+	Client   *http.Client
+}
+
+func NewNtfy(topicURL, token string) *Ntfy {
+	return &Ntfy{
+		TopicURL: topicURL,
+		Token:    token,
+		Client:   &http.Client{Timeout: deliveryTimeout},
+	}
+}
+
+func (n *Ntfy) Notify(ctx context.Context, event Event) error {
+	title, message, ok := pushMessage(event)
+	if !ok {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.TopicURL, strings.NewReader(message))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Title", title)
+	if event.Failed > 0 {
+		req.Header.Set("Tags", "warning")
+	} else {
+		req.Header.Set("Tags", "books")
+	}
+	if n.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.Token)
+	}
+
+	return send(n.Client, req, "ntfy")
+}
+
+// Pushover delivers alerts through the Pushover messages API.
+type Pushover struct {
+	Token string
+	User  string
+	// Endpoint is the messages API, PushoverEndpoint unless testing
+	Endpoint string
+	Client   *http.Client
+}
+
+func NewPushover(token, user string) *Pushover {
+	return &Pushover{
+		Token:    token,
+		User:     user,
+		Endpoint: PushoverEndpoint,
+		Client:   &http.Client{Timeout: deliveryTimeout},
+	}
+}
+
+func (p *Pushover) Notify(ctx context.Context, event Event) error {
+	title, message, ok := pushMessage(event)
+	if !ok {
+		return nil
+	}
+
+	form := url.Values{}
+	form.Set("token", p.Token)
+	form.Set("user", p.User)
+	form.Set("title", title)
+	form.Set("message", message)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	return send(p.Client, req, "pushover")
+}
+
+// pushMessage renders an event for push backends. Only the ends of batches
+// and of scheduled search runs are pushed, since a phone alert for every
+// book of a batch is noise; the webhook and /events get every event.
+func pushMessage(event Event) (title, message string, ok bool) {
+	switch event.Type {
+	case EventBatchCompleted:
+		title = "Downloads completed"
+		if event.Failed > 0 {
+			title = "Downloads completed with failures"
+		}
+		message = fmt.Sprintf("%d downloaded, %d failed", event.Done, event.Failed)
+		if event.Source != "" {
+			message += " (" + event.Source + ")"
+		}
+		return title, message, true
+	case EventSearchCompleted:
+		if event.Results == 1 {
+			return fmt.Sprintf("New result for %q", event.Query), "1 new result", true
+		}
+		return fmt.Sprintf("New results for %q", event.Query), fmt.Sprintf("%d new results", event.Results), true
+	default:
+		return "", "", false
+	}
+}
+
+func send(client *http.Client, req *http.Request, backend string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", backend, resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestNtfyNotify(t *testing.T) {
+	var gotPath, gotTitle, gotTags, gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotPath, gotBody = r.URL.Path, string(body)
+		gotTitle, gotTags, gotAuth = r.Header.Get("Title"), r.Header.Get("Tags"), r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	ntfy := NewNtfy(server.URL+"/my-books", "tk_secret")
+	err := ntfy.Notify(context.Background(), Event{Type: EventBatchCompleted, Source: "import_reading_list", Done: 49, Failed: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotPath != "/my-books" {
+		t.Errorf("Expected the topic URL, got %s", gotPath)
+	}
+	if gotTitle != "Downloads completed with failures" || gotTags != "warning" {
+		t.Errorf("Unexpected title '%s' and tags '%s'", gotTitle, gotTags)
+	}
+	if gotAuth != "Bearer tk_secret" {
+		t.Errorf("Expected the access token, got '%s'", gotAuth)
+	}
+	if gotBody != "49 downloaded, 1 failed (import_reading_list)" {
+		t.Errorf("Unexpected message '%s'", gotBody)
+	}
+
+	// Single downloads reach the webhook only
+	gotPath = ""
+	if err := ntfy.Notify(context.Background(), Event{Type: EventDownloadCompleted, Hash: "abc"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotPath != "" {
+		t.Errorf("Expected no push for a single download")
+	}
+}
+
+func TestPushoverNotify(t *testing.T) {
+	var form url.Values
+	var gotType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotType = r.Header.Get("Content-Type")
+		r.ParseForm()
+		form = r.PostForm
+	}))
+	defer server.Close()
+
+	pushover := NewPushover("app-token", "user-key")
+	if pushover.Endpoint != PushoverEndpoint {
+		t.Errorf("Expected the Pushover API by default, got %s", pushover.Endpoint)
+	}
+	pushover.Endpoint = server.URL
+	err := pushover.Notify(context.Background(), Event{Type: EventSearchCompleted, Query: "dune", Results: 3})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if gotType != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected a form, got %s", gotType)
+	}
+	if form.Get("token") != "app-token" || form.Get("user") != "user-key" {
+		t.Errorf("Expected the token and user key, got %v", form)
+	}
+	if form.Get("title") != `New results for "dune"` || form.Get("message") != "3 new results" {
+		t.Errorf("Unexpected title and message, got %v", form)
+	}
+}
+
+func TestSend(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, failing.URL, nil)
+	err := send(failing.Client(), req, "ntfy")
+	if err == nil || err.Error() != "ntfy returned status 429" {
+		t.Errorf("Expected the status to be reported, got %v", err)
+	}
+}
+
+func TestPushMessage(t *testing.T) {
+	cases := []struct {
+		event   Event
+		pushed  bool
+		title   string
+		message string
+	}{
+		{Event{Type: EventDownloadQueued}, false, "", ""},
+		{Event{Type: EventDownloadProgress}, false, "", ""},
+		{Event{Type: EventDownloadCompleted, Title: "Dune"}, false, "", ""},
+		{Event{Type: EventDownloadFailed, Title: "Dune", Error: "not found"}, false, "", ""},
+		{Event{Type: EventSearchNewResult, Query: "dune"}, false, "", ""},
+		{Event{Type: EventBatchCompleted, Source: "complete_series", Done: 3}, true, "Downloads completed", "3 downloaded, 0 failed (complete_series)"},
+		{Event{Type: EventSearchCompleted, Query: "dune", Results: 1}, true, `New result for "dune"`, "1 new result"},
+	}
+	for _, c := range cases {
+		t.Run(c.event.Type, func(t *testing.T) {
+			title, message, pushed := pushMessage(c.event)
+			if pushed != c.pushed || title != c.title || message != c.message {
+				t.Errorf("Expected %v '%s' '%s', got %v '%s' '%s'", c.pushed, c.title, c.message, pushed, title, message)
+			}
+		})
+	}
+}
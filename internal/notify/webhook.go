@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+)
+
+// SignatureHeader carries the hex-encoded HMAC-SHA256 of the request body
+// when a webhook secret is configured.
+const SignatureHeader = "X-Annas-Signature"
+
+// Webhook posts events as JSON to a user-provided URL.
+type Webhook struct {
+	URL    string
+	Secret string
+	Client *http.Client
+}
+
+func NewWebhook(url, secret string) *Webhook {
+	return &Webhook{
+		URL:    url,
+		Secret: secret,
+		Client: &http.Client{Timeout: deliveryTimeout},
+	}
+}
+
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(w.Secret, body))
+	}
+
+	return send(w.Client, req, "webhook")
+}
+
+// Sign computes the signature receivers should compare against SignatureHeader.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookNotify(t *testing.T) {
+	var gotBody []byte
+	var gotSignature string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(SignatureHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	t.Run("Signed payload", func(t *testing.T) {
+		hook := NewWebhook(server.URL, "topsecret")
+		err := hook.Notify(context.Background(), Event{Type: EventDownloadCompleted, Hash: "abc"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		var event Event
+		if err := json.Unmarshal(gotBody, &event); err != nil {
+			t.Fatalf("Failed to decode payload: %v", err)
+		}
+		if event.Type != EventDownloadCompleted || event.Hash != "abc" {
+			t.Errorf("Unexpected payload: %s", gotBody)
+		}
+
+		expected := "sha256=" + Sign("topsecret", gotBody)
+		if gotSignature != expected {
+			t.Errorf("Expected signature '%s', got '%s'", expected, gotSignature)
+		}
+	})
+
+	t.Run("Unsigned payload", func(t *testing.T) {
+		hook := NewWebhook(server.URL, "")
+		if err := hook.Notify(context.Background(), Event{Type: EventDownloadQueued}); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if gotSignature != "" {
+			t.Errorf("Expected no signature, got '%s'", gotSignature)
+		}
+	})
+
+	t.Run("Non-2xx status", func(t *testing.T) {
+		failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer failing.Close()
+
+		hook := NewWebhook(failing.URL, "")
+		if err := hook.Notify(context.Background(), Event{Type: EventDownloadFailed}); err == nil {
+			t.Error("Expected error for failing webhook, got nil")
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package ocr
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"regexp"
+	"strconv"
+)
+
+// minPageSide is the smallest width and height, in pixels, of an image taken
+// for a scanned page rather than a logo or a figure.
+const minPageSide = 400
+
+// maxImageSize caps the decompressed size of a single image.
+const maxImageSize = 256 << 20
+
+var (
+	// imageStream matches the dictionary of an image XObject and the start
+	// of its data.
+	imageStream = regexp.MustCompile(`<<((?:[^<>]|<<[^<>]*>>)*?/Subtype\s*/Image(?:[^<>]|<<[^<>]*>>)*?)>>\s*stream\r?\n`)
+	// filterName matches a single filter, alone or in an array
+	filterName = regexp.MustCompile(`/Filter\s*(?:/(\w+)|\[\s*/(\w+)\s*\])`)
+	directLen  = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	endStream  = regexp.MustCompile(`\r?\n?endstream`)
+	dictInts   = map[string]*regexp.Regexp{
+		"Width":            regexp.MustCompile(`/Width\s+(\d+)`),
+		"Height":           regexp.MustCompile(`/Height\s+(\d+)`),
+		"BitsPerComponent": regexp.MustCompile(`/BitsPerComponent\s+(\d+)`),
+	}
+)
+
+// pageImage is a page image in a file format tesseract reads.
+type pageImage struct {
+	ext  string
+	data []byte
+}
+
+// pageImages returns the full-page images of a PDF in file order. Pages
+// whose image cannot be converted are nil, so the others keep their number.
+func pageImages(data []byte) []*pageImage {
+	images := make([]*pageImage, 0)
+	for _, match := range imageStream.FindAllSubmatchIndex(data, -1) {
+		dict := data[match[2]:match[3]]
+		width, height := dictInt(dict, "Width"), dictInt(dict, "Height")
+		if width < minPageSide || height < minPageSide {
+			continue
+		}
+
+		images = append(images, convert(dict, streamData(dict, data[match[1]:]), width, height))
+	}
+
+	return images
+}
+
+// streamData returns the data of a stream starting at rest, using its
+// /Length when it is direct and the endstream keyword otherwise.
+func streamData(dict, rest []byte) []byte {
+	if match := directLen.FindSubmatch(dict); match != nil && len(match[2]) == 0 {
+		if n, err := strconv.Atoi(string(match[1])); err == nil && n <= len(rest) {
+			return rest[:n]
+		}
+	}
+	if loc := endStream.FindIndex(rest); loc != nil {
+		return rest[:loc[0]]
+	}
+
+	return rest
+}
+
+// convert turns the data of an image XObject into a file tesseract reads, or
+// nil for unsupported encodings.
+func convert(dict, data []byte, width, height int) *pageImage {
+	filter := ""
+	if match := filterName.FindSubmatch(dict); match != nil {
+		filter = string(match[1]) + string(match[2])
+	} else if bytes.Contains(dict, []byte("/Filter")) {
+		// Chained filters
+		return nil
+	}
+
+	switch filter {
+	case "DCTDecode":
+		return &pageImage{ext: ".jpg", data: data}
+	case "JPXDecode":
+		return &pageImage{ext: ".jp2", data: data}
+	case "FlateDecode", "":
+		// PNG predictors are not worth the complexity
+		if bytes.Contains(dict, []byte("/DecodeParms")) {
+			return nil
+		}
+		if filter == "FlateDecode" {
+			reader, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil
+			}
+			defer reader.Close()
+			if data, err = io.ReadAll(io.LimitReader(reader, maxImageSize)); err != nil && len(data) == 0 {
+				return nil
+			}
+		}
+		return encodePNG(dict, data, width, height)
+	default:
+		return nil
+	}
+}
+
+// encodePNG encodes raw samples of a gray, RGB, or bilevel image as PNG.
+func encodePNG(dict, samples []byte, width, height int) *pageImage {
+	bits := dictInt(dict, "BitsPerComponent")
+	if bytes.Contains(dict, []byte("/ImageMask true")) {
+		bits = 1
+	}
+
+	var img image.Image
+	switch {
+	case bits == 8 && bytes.Contains(dict, []byte("/DeviceRGB")):
+		if len(samples) < width*height*3 {
+			return nil
+		}
+		rgb := image.NewRGBA(image.Rect(0, 0, width, height))
+		for i := 0; i < width*height; i++ {
+			rgb.Pix[i*4], rgb.Pix[i*4+1], rgb.Pix[i*4+2], rgb.Pix[i*4+3] = samples[i*3], samples[i*3+1], samples[i*3+2], 0xff
+		}
+		img = rgb
+	case bits == 8 && bytes.Contains(dict, []byte("/DeviceGray")):
+		if len(samples) < width*height {
+			return nil
+		}
+		img = &image.Gray{Pix: samples[:width*height], Stride: width, Rect: image.Rect(0, 0, width, height)}
+	case bits == 1:
+		// Rows of bilevel images are padded to whole bytes, and 0 is black
+		stride := (width + 7) / 8
+		if len(samples) < stride*height {
+			return nil
+		}
+		gray := image.NewGray(image.Rect(0, 0, width, height))
+		for y := 0; y < height; y++ {
+			for x := 0; x < width; x++ {
+				if samples[y*stride+x/8]&(0x80>>(x%8)) != 0 {
+					gray.SetGray(x, y, color.Gray{Y: 0xff})
+				}
+			}
+		}
+		img = gray
+	default:
+		return nil
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil
+	}
+
+	return &pageImage{ext: ".png", data: buf.Bytes()}
+}
+
+// dictInt returns the direct integer value of key, one of dictInts, in a PDF
+// dictionary, 0 when it is missing.
+func dictInt(dict []byte, key string) int {
+	match := dictInts[key].FindSubmatch(dict)
+	if match == nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(string(match[1]))
+
+	return n
+}
@@ -0,0 +1,135 @@
+// Package ocr recognizes the text of scanned PDFs, whose pages are images
+// without a text layer, with the tesseract binary.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultBinary is used when no explicit tesseract binary is configured.
+const DefaultBinary = "tesseract"
+
+// maxFileSize caps the PDFs read into memory.
+const maxFileSize = 512 << 20
+
+// ErrNoImages is returned when no page of the requested range is an image
+// tesseract can read.
+var ErrNoImages = errors.New("no scanned page images found")
+
+// Options configures PDF.
+type Options struct {
+	// Binary runs OCR, DefaultBinary when empty.
+	Binary string
+	// Languages are the tesseract language codes to recognize, joined with
+	// "+" such as "eng+deu", tesseract's default when empty.
+	Languages string
+}
+
+// Page is the recognized text of a page.
+type Page struct {
+	Number int    `json:"page"`
+	Text   string `json:"text"`
+}
+
+// PDF recognizes the text of count pages of the scanned PDF at path,
+// starting at page first (1-based). Pages are the full-page images of the
+// file in the order they are stored, which is page order for the PDFs
+// scanners produce. JPEG, JPEG 2000, and uncompressed or Flate-encoded
+// images are read; CCITT and JBIG2 images are not.
+func PDF(ctx context.Context, path string, first, count int, opts Options) ([]Page, error) {
+	binary := opts.Binary
+	if binary == "" {
+		binary = DefaultBinary
+	}
+	resolved, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("tesseract binary %q not found: %w", binary, err)
+	}
+
+	data, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	images := pageImages(data)
+	if first < 1 {
+		first = 1
+	}
+	if first > len(images) {
+		return nil, ErrNoImages
+	}
+	images = images[first-1 : min(first-1+count, len(images))]
+
+	dir, err := os.MkdirTemp("", "annas-mcp-ocr-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	pages := make([]Page, 0, len(images))
+	for i, image := range images {
+		number := first + i
+		if image == nil {
+			continue
+		}
+
+		file := filepath.Join(dir, fmt.Sprintf("page-%d%s", number, image.ext))
+		if err := os.WriteFile(file, image.data, 0o600); err != nil {
+			return nil, err
+		}
+		text, err := recognize(ctx, resolved, file, opts.Languages)
+		if err != nil {
+			return nil, fmt.Errorf("failed to OCR page %d: %w", number, err)
+		}
+		pages = append(pages, Page{Number: number, Text: text})
+	}
+	if len(pages) == 0 {
+		return nil, ErrNoImages
+	}
+
+	return pages, nil
+}
+
+// recognize runs tesseract on the image file and returns the text it prints.
+func recognize(ctx context.Context, binary, file, languages string) (string, error) {
+	args := []string{file, "stdout"}
+	if languages != "" {
+		args = append(args, "-l", languages)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("tesseract failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// readFile reads the PDF at path, refusing files larger than maxFileSize.
+func readFile(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("PDF file larger than %d MB", maxFileSize>>20)
+	}
+
+	return data, nil
+}
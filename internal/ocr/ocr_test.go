@@ -0,0 +1,72 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// scannedPDF returns a PDF whose pages are bilevel images, with a logo too
+// small to be a page in between.
+func scannedPDF(pages int) []byte {
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	page := bytes.Repeat([]byte{0xff}, 50*400)
+	for i := 0; i < pages; i++ {
+		fmt.Fprintf(&pdf, "%d 0 obj << /Type /XObject /Subtype /Image /Width 400 /Height 400 /BitsPerComponent 1 /ColorSpace /DeviceGray /Length %d >>\nstream\n", i+10, len(page))
+		pdf.Write(page)
+		pdf.WriteString("\nendstream\nendobj\n")
+		if i == 0 {
+			pdf.WriteString("5 0 obj << /Subtype /Image /Width 40 /Height 40 /Filter /DCTDecode /Length 4 >>\nstream\nlogo\nendstream\nendobj\n")
+		}
+	}
+	pdf.WriteString("%%EOF\n")
+
+	return pdf.Bytes()
+}
+
+func TestPageImages(t *testing.T) {
+	images := pageImages(scannedPDF(3))
+	if len(images) != 3 {
+		t.Fatalf("Expected 3 page images, got %d", len(images))
+	}
+	for _, image := range images {
+		if image == nil || image.ext != ".png" || !bytes.HasPrefix(image.data, []byte("\x89PNG")) {
+			t.Errorf("Expected a PNG page image, got %+v", image)
+		}
+	}
+
+	unsupported := pageImages([]byte("<< /Subtype /Image /Width 800 /Height 800 /Filter /JBIG2Decode /Length 4 >>\nstream\njbig\nendstream"))
+	if len(unsupported) != 1 || unsupported[0] != nil {
+		t.Errorf("Expected an unsupported page, got %+v", unsupported)
+	}
+}
+
+func TestPDF(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, "Scan.pdf")
+	os.WriteFile(path, scannedPDF(5), 0o644)
+
+	// The fake tesseract prints the image name and the languages
+	binary := filepath.Join(root, "tesseract")
+	os.WriteFile(binary, []byte("#!/bin/sh\necho \"$(basename \"$1\") $4\"\n"), 0o755)
+
+	pages, err := PDF(context.Background(), path, 2, 2, Options{Binary: binary, Languages: "eng+deu"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(pages) != 2 || pages[0].Number != 2 || pages[1].Number != 3 {
+		t.Fatalf("Expected pages 2 and 3, got %+v", pages)
+	}
+	if pages[0].Text != "page-2.png eng+deu" {
+		t.Errorf("Expected text 'page-2.png eng+deu', got '%s'", pages[0].Text)
+	}
+
+	if _, err := PDF(context.Background(), path, 6, 1, Options{Binary: binary}); !errors.Is(err, ErrNoImages) {
+		t.Errorf("Expected ErrNoImages past the last page, got %v", err)
+	}
+}
@@ -0,0 +1,397 @@
+package offline
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/fsutil"
+)
+
+// DefaultLimit is the number of results returned when no limit is given.
+const DefaultLimit = 20
+
+// Record is a metadata record stored in the offline index.
+type Record struct {
+	Hash      string
+	Title     string
+	Authors   string
+	Publisher string
+	Language  string
+	Format    string
+	Bytes     int64
+	Year      string
+}
+
+// Book returns the record as a search result.
+func (r *Record) Book() *anna.Book {
+	language := r.Language
+	for _, option := range anna.Languages {
+		if strings.EqualFold(option.Value, language) {
+			language = option.Label
+		}
+	}
+
+	book := &anna.Book{
+		Language:  language,
+		Format:    r.Format,
+		Title:     r.Title,
+		Publisher: r.Publisher,
+		Authors:   anna.ParseAuthors(r.Authors),
+		URL:       fmt.Sprintf(anna.AnnasRecordEndpoint, anna.Mirrors()[0], r.Hash),
+		Hash:      r.Hash,
+	}
+	if r.Bytes > 0 {
+		book.Size = fsutil.FormatSize(r.Bytes)
+		book.Bytes = r.Bytes
+	}
+
+	return book
+}
+
+// Filter selects the records of a dump that are imported. Empty fields do not
+// filter.
+type Filter struct {
+	Languages []string
+	Formats   []string
+	// Limit caps the number of imported records, 0 for all.
+	Limit int
+}
+
+func (f Filter) matches(r *Record) bool {
+	return matchesAny(f.Languages, r.Language) && matchesAny(f.Formats, r.Format)
+}
+
+func matchesAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Index is a full-text index of metadata records. Only the records are
+// stored; the terms are rebuilt when the index is opened.
+type Index struct {
+	Records []Record
+	byHash  map[string]int
+	terms   map[string][]int
+}
+
+// New returns an empty index.
+func New() *Index {
+	index := &Index{}
+	index.build()
+
+	return index
+}
+
+// Open reads an index written by Save. A missing file is an empty index.
+func Open(path string) (*Index, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open offline index: %w", err)
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read offline index: %w", err)
+	}
+	defer gz.Close()
+
+	index := &Index{}
+	if err := gob.NewDecoder(gz).Decode(&index.Records); err != nil {
+		return nil, fmt.Errorf("failed to read offline index: %w", err)
+	}
+	index.build()
+
+	return index, nil
+}
+
+// Save writes the index to path through a temporary file.
+func (i *Index) Save(path string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to write offline index: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write offline index: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	if err := gob.NewEncoder(gz).Encode(i.Records); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write offline index: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write offline index: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write offline index: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write offline index: %w", err)
+	}
+
+	return nil
+}
+
+func (i *Index) build() {
+	i.byHash = make(map[string]int, len(i.Records))
+	i.terms = make(map[string][]int)
+	for n := range i.Records {
+		i.byHash[i.Records[n].Hash] = n
+		i.addTerms(n)
+	}
+}
+
+func (i *Index) addTerms(n int) {
+	r := &i.Records[n]
+	seen := make(map[string]bool)
+	for _, term := range tokenize(r.Title + " " + r.Authors + " " + r.Publisher + " " + r.Hash) {
+		if !seen[term] {
+			seen[term] = true
+			i.terms[term] = append(i.terms[term], n)
+		}
+	}
+}
+
+// add stores a record, replacing an earlier one with the same hash. Terms of
+// the replaced record stay in the index until it is reopened; they only
+// cause extra candidates that Search filters out.
+func (i *Index) add(r Record) {
+	if n, ok := i.byHash[r.Hash]; ok {
+		i.Records[n] = r
+		i.addTerms(n)
+		return
+	}
+
+	i.Records = append(i.Records, r)
+	n := len(i.Records) - 1
+	i.byHash[r.Hash] = n
+	i.addTerms(n)
+}
+
+// Len returns the number of indexed records.
+func (i *Index) Len() int {
+	return len(i.Records)
+}
+
+// tokenize splits text into lowercase words of letters and digits. Words are
+// transliterated, so romanized queries match Cyrillic or accented records
+// and the other way around.
+func tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(anna.Transliterate(text)), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// Search returns the records containing every word of query, those matching
+// in the title first.
+func (i *Index) Search(query string, limit int) []Record {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	words := tokenize(query)
+	if len(words) == 0 {
+		return nil
+	}
+
+	// Intersect starting from the rarest word
+	sort.Slice(words, func(a, b int) bool { return len(i.terms[words[a]]) < len(i.terms[words[b]]) })
+	candidates := i.terms[words[0]]
+
+	type match struct {
+		record Record
+		score  int
+	}
+	var matches []match
+	checked := make(map[int]bool, len(candidates))
+	for _, n := range candidates {
+		if checked[n] {
+			continue
+		}
+		checked[n] = true
+
+		r := i.Records[n]
+		text := tokenize(r.Title + " " + r.Authors + " " + r.Publisher + " " + r.Hash)
+		title := tokenize(r.Title)
+		if !containsAll(text, words) {
+			continue
+		}
+
+		score := 0
+		for _, word := range words {
+			if contains(title, word) {
+				score++
+			}
+		}
+		matches = append(matches, match{record: r, score: score})
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool { return matches[a].score > matches[b].score })
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	records := make([]Record, 0, len(matches))
+	for _, m := range matches {
+		records = append(records, m.record)
+	}
+
+	return records
+}
+
+func contains(words []string, word string) bool {
+	for _, w := range words {
+		if w == word {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsAll(words, wanted []string) bool {
+	for _, word := range wanted {
+		if !contains(words, word) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dumpRecord decodes both the Elasticsearch dumps of Anna's Archive, where
+// the record is nested in _source.file_unified_data, and flat JSON Lines.
+type dumpRecord struct {
+	ID     string `json:"_id"`
+	Source struct {
+		ID   string      `json:"id"`
+		File unifiedData `json:"file_unified_data"`
+	} `json:"_source"`
+
+	MD5       string          `json:"md5"`
+	Title     string          `json:"title"`
+	Author    string          `json:"author"`
+	Publisher string          `json:"publisher"`
+	Extension string          `json:"extension"`
+	Filesize  int64           `json:"filesize"`
+	Language  string          `json:"language"`
+	Year      json.RawMessage `json:"year"`
+}
+
+type unifiedData struct {
+	Title     string          `json:"title_best"`
+	Author    string          `json:"author_best"`
+	Publisher string          `json:"publisher_best"`
+	Extension string          `json:"extension_best"`
+	Filesize  int64           `json:"filesize_best"`
+	Languages []string        `json:"language_codes"`
+	Year      json.RawMessage `json:"year_best"`
+}
+
+func (d *dumpRecord) record() (Record, bool) {
+	id := d.MD5
+	if id == "" {
+		id = d.Source.ID
+	}
+	if id == "" {
+		id = d.ID
+	}
+	hash, err := anna.NormalizeHash(strings.TrimPrefix(id, "md5:"))
+	if err != nil {
+		return Record{}, false
+	}
+
+	r := Record{
+		Hash:      hash,
+		Title:     d.Title,
+		Authors:   d.Author,
+		Publisher: d.Publisher,
+		Format:    d.Extension,
+		Bytes:     d.Filesize,
+		Language:  d.Language,
+		Year:      rawString(d.Year),
+	}
+	if file := d.Source.File; file.Title != "" {
+		r.Title, r.Authors, r.Publisher = file.Title, file.Author, file.Publisher
+		r.Format, r.Bytes, r.Year = file.Extension, file.Filesize, rawString(file.Year)
+		if len(file.Languages) > 0 {
+			r.Language = file.Languages[0]
+		}
+	}
+	r.Format = strings.ToLower(r.Format)
+
+	return r, r.Title != ""
+}
+
+// rawString returns a JSON string or number as text.
+func rawString(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+
+	return strings.Trim(string(raw), `"`)
+}
+
+// Import adds the records of a JSON Lines dump read from r that pass filter,
+// and returns how many were added. Gzip-compressed dumps are detected and
+// decompressed. Lines that are not records are skipped.
+func (i *Index) Import(r io.Reader, filter Filter) (int, error) {
+	buffered := bufio.NewReaderSize(r, 1<<20)
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decompress dump: %w", err)
+		}
+		defer gz.Close()
+		buffered = bufio.NewReaderSize(gz, 1<<20)
+	}
+
+	added := 0
+	for filter.Limit == 0 || added < filter.Limit {
+		line, err := buffered.ReadBytes('\n')
+		if len(line) > 0 {
+			var d dumpRecord
+			if json.Unmarshal(line, &d) == nil {
+				if record, ok := d.record(); ok && filter.matches(&record) {
+					i.add(record)
+					added++
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return added, fmt.Errorf("failed to read dump: %w", err)
+		}
+	}
+
+	return added, nil
+}
@@ -0,0 +1,87 @@
+package offline
+
+import (
+	"bytes"
+	"compress/gzip"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const dump = `{"_id":"md5:D6E1DC51A50726F00EC438AF21952A45","_source":{"file_unified_data":{"title_best":"Dune","author_best":"Frank Herbert","extension_best":"EPUB","filesize_best":734003,"language_codes":["en"],"year_best":"1965"}}}
+{"md5":"0123456789abcdef0123456789abcdef","title":"Dune Messiah","author":"Frank Herbert","extension":"pdf","language":"en","year":1969}
+{"md5":"fedcba9876543210fedcba9876543210","title":"Der Wüstenplanet","author":"Frank Herbert","extension":"epub","language":"de"}
+not json
+{"md5":"short","title":"Broken"}
+`
+
+func TestImport(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(dump))
+	gz.Close()
+
+	index := New()
+	added, err := index.Import(&compressed, Filter{Languages: []string{"en"}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if added != 2 {
+		t.Fatalf("Expected 2 records, got %d", added)
+	}
+
+	path := filepath.Join(t.TempDir(), "index.gob.gz")
+	if err := index.Save(path); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	index, err = Open(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Search", func(t *testing.T) {
+		records := index.Search("herbert DUNE", 0)
+		if len(records) != 2 {
+			t.Fatalf("Expected 2 results, got %d", len(records))
+		}
+		if records[0].Hash != "d6e1dc51a50726f00ec438af21952a45" || records[0].Format != "epub" || records[0].Year != "1965" {
+			t.Errorf("Unexpected first result %+v", records[0])
+		}
+		if records[1].Year != "1969" {
+			t.Errorf("Expected numeric year '1969', got '%s'", records[1].Year)
+		}
+		if len(index.Search("messiah", 0)) != 1 || len(index.Search("wüstenplanet", 0)) != 0 {
+			t.Errorf("Expected only imported records to match")
+		}
+	})
+
+	t.Run("Book", func(t *testing.T) {
+		book := index.Search("dune", 1)[0]
+		if got := (&book).Book(); got.Language != "English" || got.Size != "716.8KB" {
+			t.Errorf("Expected language 'English' and size '716.8KB', got '%s' and '%s'", got.Language, got.Size)
+		}
+	})
+
+	t.Run("Transliteration", func(t *testing.T) {
+		added, err := index.Import(strings.NewReader(`{"md5":"00000000000000000000000000000001","title":"Преступление и наказание","author":"Фёдор Достоевский","extension":"epub"}`), Filter{})
+		if err != nil || added != 1 {
+			t.Fatalf("Expected 1 record, got %d (%v)", added, err)
+		}
+		if len(index.Search("prestuplenie dostoevskiy", 0)) != 1 || len(index.Search("наказание", 0)) != 1 {
+			t.Errorf("Expected romanized and Cyrillic queries to match the Cyrillic record")
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		added, err := index.Import(strings.NewReader(`{"md5":"0123456789abcdef0123456789abcdef","title":"Dune Messiah (Revised)","extension":"epub"}`), Filter{})
+		if err != nil || added != 1 {
+			t.Fatalf("Expected 1 record, got %d (%v)", added, err)
+		}
+		if index.Len() != 3 {
+			t.Errorf("Expected the record to be replaced, got %d records", index.Len())
+		}
+		if records := index.Search("messiah", 0); len(records) != 1 || records[0].Title != "Dune Messiah (Revised)" {
+			t.Errorf("Expected the revised record once, got %v", records)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package openlibrary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	OpenLibraryISBNEndpoint = "https://openlibrary.org/isbn/%s.json"
+	OpenLibraryKeyEndpoint  = "https://openlibrary.org%s.json"
+)
+
+var ErrNotFound = errors.New("not found on OpenLibrary")
+
+var client = &http.Client{Timeout: 15 * time.Second}
+
+// Work is the subset of OpenLibrary edition and work data used for enrichment.
+type Work struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Subjects    []string `json:"subjects,omitempty"`
+	Series      []string `json:"series,omitempty"`
+	PublishDate string   `json:"publish_date,omitempty"`
+	Pages       int      `json:"pages,omitempty"`
+}
+
+type edition struct {
+	Title         string   `json:"title"`
+	Series        []string `json:"series"`
+	PublishDate   string   `json:"publish_date"`
+	NumberOfPages int      `json:"number_of_pages"`
+	Works         []struct {
+		Key string `json:"key"`
+	} `json:"works"`
+}
+
+type work struct {
+	Description description `json:"description"`
+	Subjects    []string    `json:"subjects"`
+}
+
+// description handles OpenLibrary returning either a plain string or a
+// {"type": ..., "value": ...} object.
+type description string
+
+func (d *description) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*d = description(text)
+		return nil
+	}
+
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(data, &typed); err != nil {
+		return err
+	}
+	*d = description(typed.Value)
+	return nil
+}
+
+// LookupISBN resolves an ISBN to its edition and parent work.
+func LookupISBN(ctx context.Context, isbn string) (*Work, error) {
+	var ed edition
+	if err := getJSON(ctx, fmt.Sprintf(OpenLibraryISBNEndpoint, isbn), &ed); err != nil {
+		return nil, err
+	}
+
+	result := &Work{
+		Title:       ed.Title,
+		Series:      ed.Series,
+		PublishDate: ed.PublishDate,
+		Pages:       ed.NumberOfPages,
+	}
+
+	if len(ed.Works) > 0 {
+		var w work
+		if err := getJSON(ctx, fmt.Sprintf(OpenLibraryKeyEndpoint, ed.Works[0].Key), &w); err == nil {
+			result.Description = string(w.Description)
+			result.Subjects = w.Subjects
+		}
+	}
+
+	return result, nil
+}
+
+func getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("OpenLibrary returned status %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,120 @@
+// Package paths locates the default directories of annas-mcp following the
+// XDG base directory specification, with the usual locations of macOS and
+// Windows when the XDG variables are not set.
+package paths
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// App is the name of the folder of annas-mcp in every base directory.
+const App = "annas-mcp"
+
+// Kind is a base directory.
+type Kind int
+
+const (
+	// Config holds the settings written by the user, such as config.json.
+	Config Kind = iota
+	// Cache holds data that can be fetched again at any time.
+	Cache
+	// Data holds data built by the user, such as the offline index.
+	Data
+	// State holds data kept across runs that is not worth backing up, such
+	// as the usage stats.
+	State
+)
+
+// Kinds are all the base directories, in the order they are reported.
+var Kinds = []Kind{Config, Cache, Data, State}
+
+func (k Kind) String() string {
+	switch k {
+	case Config:
+		return "config"
+	case Cache:
+		return "cache"
+	case Data:
+		return "data"
+	default:
+		return "state"
+	}
+}
+
+// env is the XDG variable of every base directory.
+var env = map[Kind]string{
+	Config: "XDG_CONFIG_HOME",
+	Cache:  "XDG_CACHE_HOME",
+	Data:   "XDG_DATA_HOME",
+	State:  "XDG_STATE_HOME",
+}
+
+// base returns the base directory of kind on goos. Relative XDG values are
+// ignored, as the specification requires. It is empty when no directory is
+// known, for example without a home directory.
+func base(kind Kind, goos string, getenv func(string) string, home string) string {
+	if dir := getenv(env[kind]); filepath.IsAbs(dir) {
+		return dir
+	}
+
+	switch goos {
+	case "windows":
+		if kind == Config {
+			return getenv("APPDATA")
+		}
+		return getenv("LOCALAPPDATA")
+	case "darwin", "ios":
+		if home == "" {
+			return ""
+		}
+		if kind == Cache {
+			return filepath.Join(home, "Library", "Caches")
+		}
+		return filepath.Join(home, "Library", "Application Support")
+	}
+
+	if home == "" {
+		return ""
+	}
+	switch kind {
+	case Config:
+		return filepath.Join(home, ".config")
+	case Cache:
+		return filepath.Join(home, ".cache")
+	case Data:
+		return filepath.Join(home, ".local", "share")
+	default:
+		return filepath.Join(home, ".local", "state")
+	}
+}
+
+// Base returns the base directory of kind shared by every program, such as
+// ~/.config, or an empty path when it is unknown.
+func Base(kind Kind) string {
+	home, _ := os.UserHomeDir()
+	return base(kind, runtime.GOOS, os.Getenv, home)
+}
+
+// Dir returns the folder of annas-mcp in the base directory of kind, or an
+// empty path when it is unknown.
+func Dir(kind Kind) string {
+	dir := Base(kind)
+	if dir == "" {
+		return ""
+	}
+
+	return filepath.Join(dir, App)
+}
+
+// File returns the path of name in the folder of annas-mcp in the base
+// directory of kind, or an empty path when it is unknown.
+func File(kind Kind, name string) string {
+	dir := Dir(kind)
+	if dir == "" {
+		return ""
+	}
+
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,41 @@
+package paths
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBase(t *testing.T) {
+	env := map[string]string{
+		"XDG_CACHE_HOME": "/var/cache/reader",
+		"XDG_DATA_HOME":  "relative/data",
+		"APPDATA":        `C:\Users\reader\AppData\Roaming`,
+		"LOCALAPPDATA":   `C:\Users\reader\AppData\Local`,
+	}
+	getenv := func(name string) string { return env[name] }
+
+	cases := []struct {
+		kind     Kind
+		goos     string
+		expected string
+	}{
+		{Config, "linux", filepath.Join("/home/reader", ".config")},
+		{Cache, "linux", "/var/cache/reader"},
+		// Relative XDG values are ignored
+		{Data, "linux", filepath.Join("/home/reader", ".local", "share")},
+		{State, "linux", filepath.Join("/home/reader", ".local", "state")},
+		{Config, "darwin", filepath.Join("/home/reader", "Library", "Application Support")},
+		{Cache, "darwin", "/var/cache/reader"},
+		{Config, "windows", `C:\Users\reader\AppData\Roaming`},
+		{State, "windows", `C:\Users\reader\AppData\Local`},
+	}
+	for _, c := range cases {
+		if got := base(c.kind, c.goos, getenv, "/home/reader"); got != c.expected {
+			t.Errorf("Expected the %s directory '%s' on %s, got '%s'", c.kind, c.expected, c.goos, got)
+		}
+	}
+
+	if got := base(State, "linux", getenv, ""); got != "" {
+		t.Errorf("Expected no state directory without a home, got '%s'", got)
+	}
+}
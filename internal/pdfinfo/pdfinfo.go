@@ -0,0 +1,151 @@
+// Package pdfinfo reads the page count and page size of a PDF file without a
+// full PDF parser, so samples and previews can be told from whole books.
+package pdfinfo
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// maxFileSize caps the files read by Probe, which holds them in memory.
+const maxFileSize = 512 << 20
+
+// maxStreamSize caps the decompressed size of a single object stream.
+const maxStreamSize = 64 << 20
+
+// ErrNotPDF is returned for files that do not start with a PDF header.
+var ErrNotPDF = errors.New("not a PDF file")
+
+var (
+	// pagesCount matches the page tree nodes, whose /Count is the number of
+	// pages below them.
+	pagesCount = regexp.MustCompile(`/Type\s*/Pages\b[^>]*?/Count\s+(\d+)|/Count\s+(\d+)[^>]*?/Type\s*/Pages\b`)
+	// pageObject matches the leaves of the page tree.
+	pageObject = regexp.MustCompile(`/Type\s*/Page\b`)
+	mediaBox   = regexp.MustCompile(`/MediaBox\s*\[\s*(-?[\d.]+)\s+(-?[\d.]+)\s+(-?[\d.]+)\s+(-?[\d.]+)\s*\]`)
+	// objectStream matches the dictionary of a compressed object stream and
+	// the start of its data. PDF 1.5 files store the page objects there.
+	objectStream = regexp.MustCompile(`<<((?:[^<>]|<<[^<>]*>>)*?/Type\s*/ObjStm(?:[^<>]|<<[^<>]*>>)*?)>>\s*stream\r?\n`)
+)
+
+// Info describes the pages of a PDF file.
+type Info struct {
+	Pages int `json:"pages"`
+	// Width and Height are the most common page size, in points (1/72 inch).
+	Width  float64 `json:"width_pt,omitempty"`
+	Height float64 `json:"height_pt,omitempty"`
+}
+
+// Probe reads the page count and the most common page size of the PDF file
+// at path. Page objects in compressed object streams are found too;
+// encrypted files may only report their page count.
+func Probe(path string) (*Info, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxFileSize {
+		return nil, fmt.Errorf("PDF file larger than %d MB", maxFileSize>>20)
+	}
+
+	return Parse(data)
+}
+
+// Parse is like Probe for a PDF file held in memory.
+func Parse(data []byte) (*Info, error) {
+	if !bytes.HasPrefix(bytes.TrimLeft(data, "\x00\t\r\n "), []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+
+	sections := append([][]byte{data}, objectStreams(data)...)
+
+	info := &Info{}
+	leaves := 0
+	sizes := make(map[[2]float64]int)
+	for _, section := range sections {
+		for _, match := range pagesCount.FindAllSubmatch(section, -1) {
+			count := match[1]
+			if len(count) == 0 {
+				count = match[2]
+			}
+			// The root of the page tree counts every page
+			if n, err := strconv.Atoi(string(count)); err == nil && n > info.Pages {
+				info.Pages = n
+			}
+		}
+		leaves += len(pageObject.FindAllIndex(section, -1))
+
+		for _, match := range mediaBox.FindAllSubmatch(section, -1) {
+			var box [4]float64
+			for i := range box {
+				box[i], _ = strconv.ParseFloat(string(match[i+1]), 64)
+			}
+			size := [2]float64{round(math.Abs(box[2] - box[0])), round(math.Abs(box[3] - box[1]))}
+			if size[0] > 0 && size[1] > 0 {
+				sizes[size]++
+			}
+		}
+	}
+	if info.Pages == 0 {
+		info.Pages = leaves
+	}
+	if info.Pages == 0 {
+		return nil, errors.New("no pages found")
+	}
+
+	best := 0
+	for size, count := range sizes {
+		// Ties go to the wider size, so the result does not depend on the
+		// order of the map
+		if count > best || (count == best && (size[0] > info.Width || (size[0] == info.Width && size[1] > info.Height))) {
+			best = count
+			info.Width, info.Height = size[0], size[1]
+		}
+	}
+
+	return info, nil
+}
+
+// objectStreams returns the decompressed content of the Flate-encoded object
+// streams of data. Streams that fail to decompress are skipped.
+func objectStreams(data []byte) [][]byte {
+	streams := make([][]byte, 0)
+	for _, match := range objectStream.FindAllSubmatchIndex(data, -1) {
+		dict := data[match[2]:match[3]]
+		if !bytes.Contains(dict, []byte("/FlateDecode")) {
+			continue
+		}
+
+		reader, err := zlib.NewReader(bytes.NewReader(data[match[1]:]))
+		if err != nil {
+			continue
+		}
+		content, err := io.ReadAll(io.LimitReader(reader, maxStreamSize))
+		reader.Close()
+		// Truncated streams still hold the objects read so far
+		if len(content) > 0 || err == nil {
+			streams = append(streams, content)
+		}
+	}
+
+	return streams
+}
+
+// round keeps page sizes to a tenth of a point, since producers write them
+// with varying precision.
+func round(x float64) float64 {
+	return math.Round(x*10) / 10
+}
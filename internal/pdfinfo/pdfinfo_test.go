@@ -0,0 +1,57 @@
+package pdfinfo
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("plain objects", func(t *testing.T) {
+		data := []byte("%PDF-1.4\n" +
+			"1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n" +
+			"2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R 5 0 R] /Count 3 /MediaBox [0 0 612 792] >> endobj\n" +
+			"3 0 obj << /Type /Page /Parent 2 0 R >> endobj\n" +
+			"4 0 obj << /Type /Page /Parent 2 0 R >> endobj\n" +
+			"5 0 obj << /Type /Page /Parent 2 0 R /MediaBox [0 0 792 612] >> endobj\n" +
+			"6 0 obj << /Type /Outlines /Count 12 >> endobj\n%%EOF\n")
+
+		info, err := Parse(data)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Pages != 3 {
+			t.Errorf("Expected 3 pages, got %d", info.Pages)
+		}
+		if info.Width != 792 || info.Height != 612 {
+			t.Errorf("Expected the wider of two equally common sizes 792x612, got %vx%v", info.Width, info.Height)
+		}
+	})
+
+	t.Run("object streams", func(t *testing.T) {
+		var stream bytes.Buffer
+		writer := zlib.NewWriter(&stream)
+		writer.Write([]byte("<< /Type /Pages /Kids [3 0 R] /Count 120 >> << /Type /Page /MediaBox [0 0 595.276 841.89] >>"))
+		writer.Close()
+
+		var data bytes.Buffer
+		data.WriteString("%PDF-1.7\n7 0 obj\n<< /Type /ObjStm /N 2 /First 10 /Filter /FlateDecode /Length 99 >>\nstream\n")
+		data.Write(stream.Bytes())
+		data.WriteString("\nendstream\nendobj\n%%EOF\n")
+
+		info, err := Parse(data.Bytes())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if info.Pages != 120 || info.Width != 595.3 || info.Height != 841.9 {
+			t.Errorf("Expected 120 A4 pages, got %d of %vx%v", info.Pages, info.Width, info.Height)
+		}
+	})
+
+	t.Run("not a PDF", func(t *testing.T) {
+		if _, err := Parse([]byte("PK\x03\x04")); !errors.Is(err, ErrNotPDF) {
+			t.Errorf("Expected ErrNotPDF, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package rclone
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DefaultBinary is used when no explicit rclone binary is configured.
+const DefaultBinary = "rclone"
+
+// Copy uploads the local file into the given rclone remote path, for example
+// "gdrive:Books". Remotes are resolved from the user's rclone configuration.
+func Copy(ctx context.Context, binary, path, remote string) error {
+	if binary == "" {
+		binary = DefaultBinary
+	}
+
+	resolved, err := exec.LookPath(binary)
+	if err != nil {
+		return fmt.Errorf("rclone binary %q not found: %w", binary, err)
+	}
+
+	output, err := exec.CommandContext(ctx, resolved, "copy", path, remote).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rclone copy failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
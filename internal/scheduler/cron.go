@@ -0,0 +1,151 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxLookahead bounds the search for the next activation, so expressions that
+// never match, such as "0 0 30 2 *", do not loop forever.
+const maxLookahead = 5 * 366 * 24 * time.Hour
+
+// ErrInvalidCron is returned for expressions ParseCron cannot parse.
+var ErrInvalidCron = errors.New("invalid cron expression")
+
+// shortcuts are the named expressions accepted besides five fields.
+var shortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// Cron is a parsed five-field cron expression: minute, hour, day of month,
+// month, and day of week. Each field is a bit set of the matching values.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// Like in cron, a restricted day of month and day of week match if
+	// either does
+	domAny, dowAny bool
+}
+
+// ParseCron parses expressions such as "*/15 * * * *", "0 8 * * 1-5", or
+// "@daily". Lists, ranges, and steps are supported; names are not.
+func ParseCron(expr string) (*Cron, error) {
+	if shortcut, ok := shortcuts[strings.TrimSpace(expr)]; ok {
+		expr = shortcut
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w %q: expected 5 fields", ErrInvalidCron, expr)
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %w", ErrInvalidCron, expr, err)
+		}
+		sets[i] = set
+	}
+
+	// Sunday is both 0 and 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	return &Cron{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", first)
+			}
+			high = low
+			if isRange {
+				if high, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value %q", last)
+				}
+			} else if hasStep {
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("value %q out of range %d-%d", rangePart, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			set |= 1 << value
+		}
+	}
+
+	return set, nil
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<t.Weekday()) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next returns the first activation strictly after t, in t's location, or the
+// zero time if there is none within the next five years.
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.Add(maxLookahead)
+
+	for t.Before(end) {
+		switch {
+		case c.month&(1<<t.Month()) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+	"github.com/iosifache/annas-mcp/internal/logger"
+	"go.uber.org/zap"
+)
+
+// Runner runs the due schedules of a schedules file and reports their new
+// results, once per run that found any.
+type Runner struct {
+	Path   string
+	Search func(query string) ([]*anna.Book, error)
+	Notify func(schedule Schedule, books []*anna.Book)
+}
+
+// Run checks for due schedules at the start of every minute until ctx is
+// done.
+func (r *Runner) Run(ctx context.Context) {
+	for {
+		now := time.Now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case now = <-timer.C:
+		}
+
+		r.RunDue(now)
+	}
+}
+
+// RunDue runs the schedules due at now. Searches run without holding the
+// schedules file, so schedules can be managed meanwhile; results of schedules
+// removed in the meantime are dropped.
+func (r *Runner) RunDue(now time.Time) {
+	l := logger.GetLogger()
+
+	store, err := Load(r.Path)
+	if err != nil {
+		l.Error("Failed to load schedules", zap.String("path", r.Path), zap.Error(err))
+		return
+	}
+
+	for _, schedule := range store.Schedules {
+		if !schedule.Due(now) {
+			continue
+		}
+
+		books, searchErr := r.Search(schedule.Query)
+		if searchErr != nil {
+			// The schedule is retried at its next activation
+			l.Error("Scheduled search failed", zap.String("id", schedule.ID), zap.String("query", schedule.Query), zap.Error(searchErr))
+		}
+
+		hashes := make([]string, 0, len(books))
+		byHash := make(map[string]*anna.Book, len(books))
+		for _, book := range books {
+			hashes = append(hashes, book.Hash)
+			byHash[book.Hash] = book
+		}
+
+		var fresh []string
+		err = Update(r.Path, func(current *Store) error {
+			for i := range current.Schedules {
+				s := &current.Schedules[i]
+				if s.ID != schedule.ID {
+					continue
+				}
+
+				s.LastAttempt = now.UTC()
+				if searchErr != nil {
+					s.LastError = searchErr.Error()
+				} else {
+					fresh = s.Record(hashes)
+					for _, hash := range fresh {
+						book := byHash[hash]
+						s.AddResults(Result{
+							Hash:    book.Hash,
+							Title:   book.Title,
+							Authors: book.AuthorLine(),
+							Format:  book.Format,
+							URL:     book.URL,
+							FoundAt: now.UTC(),
+						})
+					}
+					s.LastRun, s.LastError = now.UTC(), ""
+				}
+				schedule = *s
+			}
+			return nil
+		})
+		if err != nil {
+			l.Error("Failed to save schedules", zap.String("path", r.Path), zap.Error(err))
+			return
+		}
+		if searchErr != nil {
+			continue
+		}
+
+		l.Info("Scheduled search completed",
+			zap.String("id", schedule.ID),
+			zap.String("query", schedule.Query),
+			zap.Int("resultsCount", len(books)),
+			zap.Int("newCount", len(fresh)),
+		)
+		if len(fresh) == 0 {
+			continue
+		}
+		found := make([]*anna.Book, 0, len(fresh))
+		for _, hash := range fresh {
+			found = append(found, byHash[hash])
+		}
+		r.Notify(schedule, found)
+	}
+}
@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2026, time.March, 6, 10, 7, 30, 0, time.UTC) // a Friday
+
+	cases := map[string]time.Time{
+		"*/15 * * * *":  time.Date(2026, time.March, 6, 10, 15, 0, 0, time.UTC),
+		"0 8 * * 1-5":   time.Date(2026, time.March, 9, 8, 0, 0, 0, time.UTC),
+		"30 9 1,15 * *": time.Date(2026, time.March, 15, 9, 30, 0, 0, time.UTC),
+		"@daily":        time.Date(2026, time.March, 7, 0, 0, 0, 0, time.UTC),
+		"0 0 * * 7":     time.Date(2026, time.March, 8, 0, 0, 0, 0, time.UTC),
+	}
+	for expr, expected := range cases {
+		t.Run(expr, func(t *testing.T) {
+			cron, err := ParseCron(expr)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if next := cron.Next(from); !next.Equal(expected) {
+				t.Errorf("Expected next activation '%s', got '%s'", expected, next)
+			}
+		})
+	}
+
+	for _, expr := range []string{"* * * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *", "@sometimes"} {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("Expected %q to be rejected", expr)
+		}
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	created := time.Date(2026, time.March, 6, 10, 0, 0, 0, time.UTC)
+
+	var schedule Schedule
+	err := Update(path, func(store *Store) error {
+		var err error
+		schedule, err = store.Add("dune", "@hourly")
+		store.Schedules[0].CreatedAt = created
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	results := []*anna.Book{{Hash: "a", Title: "Dune"}}
+	var searchErr error
+	var notified []string
+	runs := 0
+	runner := &Runner{
+		Path:   path,
+		Search: func(string) ([]*anna.Book, error) { return results, searchErr },
+		Notify: func(_ Schedule, books []*anna.Book) {
+			runs++
+			for _, book := range books {
+				notified = append(notified, book.Hash)
+			}
+		},
+	}
+
+	t.Run("NotDue", func(t *testing.T) {
+		runner.RunDue(created.Add(30 * time.Minute))
+		store, _ := Load(path)
+		if !store.Schedules[0].LastAttempt.IsZero() {
+			t.Errorf("Expected no run before the first activation")
+		}
+	})
+
+	t.Run("FailedBaseline", func(t *testing.T) {
+		searchErr = errors.New("mirrors down")
+		runner.RunDue(created.Add(time.Hour))
+		searchErr = nil
+
+		store, _ := Load(path)
+		if store.Schedules[0].LastError != "mirrors down" || !store.Schedules[0].LastRun.IsZero() {
+			t.Errorf("Expected a failed attempt to be recorded, got %+v", store.Schedules[0])
+		}
+	})
+
+	t.Run("Baseline", func(t *testing.T) {
+		runner.RunDue(created.Add(2 * time.Hour))
+		if len(notified) != 0 || runs != 0 {
+			t.Errorf("Expected the first results not to be reported, got %v", notified)
+		}
+	})
+
+	t.Run("NewResults", func(t *testing.T) {
+		results = append(results, &anna.Book{Hash: "b", Title: "Dune Messiah"}, &anna.Book{Hash: "c", Title: "Children of Dune"})
+		runner.RunDue(created.Add(3 * time.Hour))
+		if len(notified) != 2 || notified[0] != "b" || notified[1] != "c" {
+			t.Errorf("Expected only 'b' and 'c' to be reported, got %v", notified)
+		}
+		if runs != 1 {
+			t.Errorf("Expected the run to be reported once, got %d", runs)
+		}
+
+		store, _ := Load(path)
+		if results := store.Schedules[0].Results; len(results) != 2 || results[0].Title != "Dune Messiah" {
+			t.Errorf("Expected 'Dune Messiah' to be kept for the feed, got %v", results)
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		err := Update(path, func(store *Store) error { return store.Remove(schedule.ID) })
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		err = Update(path, func(store *Store) error { return store.Remove(schedule.ID) })
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound, got %v", err)
+		}
+	})
+}
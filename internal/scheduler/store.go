@@ -0,0 +1,209 @@
+package scheduler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/state"
+)
+
+// maxSeen bounds the remembered results of a schedule. Older hashes are
+// forgotten first, so only results that drop out of a search for a long time
+// and come back are reported twice.
+const maxSeen = 500
+
+// maxResults is the number of new results kept for the feed of a schedule.
+const maxResults = 50
+
+// ErrNotFound is returned for schedule IDs that do not exist.
+var ErrNotFound = errors.New("schedule not found")
+
+// Result is a new result found by a schedule.
+type Result struct {
+	Hash    string    `json:"md5"`
+	Title   string    `json:"title,omitempty"`
+	Authors string    `json:"authors,omitempty"`
+	Format  string    `json:"format,omitempty"`
+	URL     string    `json:"url,omitempty"`
+	FoundAt time.Time `json:"found_at"`
+}
+
+// Schedule is a saved search run on a cron expression.
+type Schedule struct {
+	ID    string `json:"id"`
+	Query string `json:"query"`
+	Cron  string `json:"cron"`
+	// Seen lists the hashes of the results that were already reported.
+	Seen []string `json:"seen,omitempty"`
+	// Results lists the most recent new results, oldest first.
+	Results   []Result  `json:"results,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// LastRun is the time of the last successful search, LastAttempt and
+	// LastError those of the last search.
+	LastRun     time.Time `json:"last_run,omitempty"`
+	LastAttempt time.Time `json:"last_attempt,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Due reports whether the schedule should run at now: an activation passed
+// since its last run. Activations missed while the server was down result in
+// a single run.
+func (s *Schedule) Due(now time.Time) bool {
+	cron, err := ParseCron(s.Cron)
+	if err != nil {
+		return false
+	}
+
+	last := s.LastAttempt
+	if last.IsZero() {
+		last = s.CreatedAt
+	}
+	next := cron.Next(last.In(now.Location()))
+
+	return !next.IsZero() && !next.After(now)
+}
+
+// Record marks hashes as seen and returns those that were not. The first
+// successful run only records the current results, since every one of them
+// would be new.
+func (s *Schedule) Record(hashes []string) []string {
+	seen := make(map[string]bool, len(s.Seen))
+	for _, hash := range s.Seen {
+		seen[hash] = true
+	}
+
+	var fresh []string
+	for _, hash := range hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+		s.Seen = append(s.Seen, hash)
+		fresh = append(fresh, hash)
+	}
+	if len(s.Seen) > maxSeen {
+		s.Seen = s.Seen[len(s.Seen)-maxSeen:]
+	}
+
+	if s.LastRun.IsZero() {
+		return nil
+	}
+	return fresh
+}
+
+// AddResults appends new results, keeping only the most recent ones.
+func (s *Schedule) AddResults(results ...Result) {
+	s.Results = append(s.Results, results...)
+	if len(s.Results) > maxResults {
+		s.Results = s.Results[len(s.Results)-maxResults:]
+	}
+}
+
+// Store holds the schedules of a schedules file.
+type Store struct {
+	path      string
+	Schedules []Schedule `json:"schedules"`
+}
+
+// mu serializes updates of the schedules file between the scheduler and the
+// commands managing it.
+var mu sync.Mutex
+
+// Update loads the schedules file, calls fn with it, and saves it if fn
+// succeeds.
+func Update(path string, fn func(*Store) error) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	store, err := load(path)
+	if err != nil {
+		return err
+	}
+	if err := fn(store); err != nil {
+		return err
+	}
+
+	return store.save()
+}
+
+// Load returns a snapshot of the schedules file. A missing file has no
+// schedules.
+func Load(path string) (*Store, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return load(path)
+}
+
+func load(path string) (*Store, error) {
+	store := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schedules: %w", err)
+	}
+	if err := json.Unmarshal(data, store); err != nil {
+		return nil, fmt.Errorf("failed to parse schedules: %w", err)
+	}
+
+	return store, nil
+}
+
+// save writes the schedules through a temporary file so readers never see a
+// partial file.
+func (s *Store) save() error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode schedules: %w", err)
+	}
+
+	return state.WriteFile(s.path, data)
+}
+
+// Add validates the cron expression and saves a new schedule for query.
+func (s *Store) Add(query, cron string) (Schedule, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return Schedule{}, errors.New("query must not be empty")
+	}
+	if _, err := ParseCron(cron); err != nil {
+		return Schedule{}, err
+	}
+
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return Schedule{}, err
+	}
+
+	schedule := Schedule{
+		ID:        hex.EncodeToString(id),
+		Query:     query,
+		Cron:      strings.TrimSpace(cron),
+		CreatedAt: time.Now().UTC(),
+	}
+	s.Schedules = append(s.Schedules, schedule)
+
+	return schedule, nil
+}
+
+// Remove deletes the schedule with the given ID.
+func (s *Store) Remove(id string) error {
+	for i, schedule := range s.Schedules {
+		if schedule.ID == id {
+			s.Schedules = append(s.Schedules[:i], s.Schedules[i+1:]...)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrNotFound, id)
+}
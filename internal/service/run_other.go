@@ -0,0 +1,11 @@
+//go:build !windows
+
+package service
+
+// Run runs start as a service when the process was started by a service
+// manager that expects it to answer its requests, calling stop when asked to
+// stop. It reports false, without running start, when the process can run it
+// on its own, as on every system but Windows.
+func Run(start func() error, stop func()) (bool, error) {
+	return false, nil
+}
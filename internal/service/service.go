@@ -0,0 +1,156 @@
+// Package service registers annas-mcp with the service manager of the
+// operating system, so the HTTP server starts at boot or login and restarts
+// when it fails: a systemd unit on Linux, a launchd agent on macOS, and a
+// service of the service control manager on Windows.
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const (
+	// Name is the name of the systemd unit and of the Windows service.
+	Name = "annas-mcp"
+	// Label is the name of the launchd agent.
+	Label = "io.github.iosifache.annas-mcp"
+	// Description is shown by the service managers.
+	Description = "Anna's Archive MCP server"
+	// WorkDirFlag is the flag of the http command changing the working
+	// directory to Spec.Dir, for the service managers that cannot.
+	WorkDirFlag = "workdir"
+	// EnvFileFlag is the flag naming the file of settings loaded ahead of
+	// the dotenv file, holding the ones exported in the shell of the
+	// install command.
+	EnvFileFlag = "service-env"
+)
+
+var (
+	// ErrUnsupported is returned on systems without a supported service
+	// manager.
+	ErrUnsupported = errors.New("services are not supported on " + runtime.GOOS)
+	// ErrNotInstalled is returned when removing a service that is not
+	// installed.
+	ErrNotInstalled = errors.New("service is not installed")
+)
+
+// Spec describes the command run by the service.
+type Spec struct {
+	// Executable is the absolute path of the annas-mcp binary
+	Executable string
+	// Args are passed to Executable, starting with the http command
+	Args []string
+	// Dir is the working directory, so that relative paths of the
+	// configuration resolve as they did when the service was installed
+	Dir string
+	// LogFile receives the output of the server where the service manager
+	// keeps no log of its own, that is on macOS
+	LogFile string
+}
+
+// Status is the state of the installed service.
+type Status struct {
+	Manager   string `json:"manager"`
+	File      string `json:"file,omitempty"`
+	Installed bool   `json:"installed"`
+	Running   bool   `json:"running"`
+	// State is the state as reported by the service manager, such as
+	// "active" or "failed"
+	State string `json:"state,omitempty"`
+}
+
+// run executes a command of the service manager and returns its combined
+// output.
+var run = func(name string, args ...string) (string, error) {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	output := strings.TrimSpace(string(out))
+	if err != nil && output != "" {
+		return output, fmt.Errorf("%s %s: %w: %s", name, strings.Join(args, " "), err, output)
+	}
+	if err != nil {
+		return output, fmt.Errorf("%s %s: %w", name, strings.Join(args, " "), err)
+	}
+
+	return output, nil
+}
+
+// systemdUnit renders the unit running spec, wanted by target.
+func systemdUnit(spec Spec, target string) string {
+	command := make([]string, 0, len(spec.Args)+1)
+	for _, arg := range append([]string{spec.Executable}, spec.Args...) {
+		command = append(command, systemdQuote(arg))
+	}
+
+	var b strings.Builder
+	b.WriteString("[Unit]\n")
+	fmt.Fprintf(&b, "Description=%s\n", Description)
+	b.WriteString("Wants=network-online.target\n")
+	b.WriteString("After=network-online.target\n\n")
+	b.WriteString("[Service]\n")
+	fmt.Fprintf(&b, "ExecStart=%s\n", strings.Join(command, " "))
+	if spec.Dir != "" {
+		fmt.Fprintf(&b, "WorkingDirectory=%s\n", systemdEscape(spec.Dir))
+	}
+	b.WriteString("Restart=on-failure\n")
+	b.WriteString("RestartSec=5\n\n")
+	b.WriteString("[Install]\n")
+	fmt.Fprintf(&b, "WantedBy=%s\n", target)
+
+	return b.String()
+}
+
+// systemdEscape escapes the specifiers and variables systemd expands.
+func systemdEscape(value string) string {
+	return strings.NewReplacer("%", "%%", "$", "$$").Replace(value)
+}
+
+// systemdQuote quotes an argument of ExecStart when it holds characters
+// systemd would split or unescape.
+func systemdQuote(arg string) string {
+	arg = systemdEscape(arg)
+	if arg != "" && !strings.ContainsAny(arg, " \t\"'\\;") {
+		return arg
+	}
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg) + `"`
+}
+
+// launchdPlist renders the property list of the agent running spec.
+func launchdPlist(spec Spec) string {
+	var b bytes.Buffer
+	value := func(key, text string) {
+		fmt.Fprintf(&b, "\t<key>%s</key>\n\t<string>", key)
+		_ = xml.EscapeText(&b, []byte(text))
+		b.WriteString("</string>\n")
+	}
+
+	b.WriteString(xml.Header)
+	b.WriteString(`<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">` + "\n")
+	b.WriteString("<plist version=\"1.0\">\n<dict>\n")
+	value("Label", Label)
+	b.WriteString("\t<key>ProgramArguments</key>\n\t<array>\n")
+	for _, arg := range append([]string{spec.Executable}, spec.Args...) {
+		b.WriteString("\t\t<string>")
+		_ = xml.EscapeText(&b, []byte(arg))
+		b.WriteString("</string>\n")
+	}
+	b.WriteString("\t</array>\n")
+	if spec.Dir != "" {
+		value("WorkingDirectory", spec.Dir)
+	}
+	b.WriteString("\t<key>RunAtLoad</key>\n\t<true/>\n")
+	// Restarted when it fails, but not when it exits cleanly
+	b.WriteString("\t<key>KeepAlive</key>\n\t<dict>\n\t\t<key>SuccessfulExit</key>\n\t\t<false/>\n\t</dict>\n")
+	if spec.LogFile != "" {
+		value("StandardOutPath", spec.LogFile)
+		value("StandardErrorPath", spec.LogFile)
+	}
+	b.WriteString("</dict>\n</plist>\n")
+
+	return b.String()
+}
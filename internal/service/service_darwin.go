@@ -0,0 +1,102 @@
+//go:build darwin
+
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Manager is the service manager used on this system.
+const Manager = "launchd"
+
+// launchd returns the launchctl domain and the property list of the service
+// for the current user: a daemon for root, an agent otherwise.
+func launchd() (domain, file string, err error) {
+	if os.Geteuid() == 0 {
+		return "system", filepath.Join("/Library/LaunchDaemons", Label+".plist"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", err
+	}
+	return "gui/" + strconv.Itoa(os.Getuid()), filepath.Join(home, "Library", "LaunchAgents", Label+".plist"), nil
+}
+
+// Install writes the property list of spec and (re)loads it, which starts
+// the service. It returns the path of the property list.
+func Install(spec Spec) (string, error) {
+	domain, file, err := launchd()
+	if err != nil {
+		return "", err
+	}
+	if spec.LogFile != "" {
+		if err := os.MkdirAll(filepath.Dir(spec.LogFile), 0o755); err != nil {
+			return "", err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, []byte(launchdPlist(spec)), 0o644); err != nil {
+		return "", err
+	}
+
+	// A previous version of the service may still be loaded
+	_, _ = run("launchctl", "bootout", domain+"/"+Label)
+	if _, err := run("launchctl", "bootstrap", domain, file); err != nil {
+		return file, err
+	}
+	_, err = run("launchctl", "enable", domain+"/"+Label)
+	return file, err
+}
+
+// Uninstall unloads the service, which stops it, then removes its property
+// list.
+func Uninstall() error {
+	domain, file, err := launchd()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return ErrNotInstalled
+	}
+
+	_, _ = run("launchctl", "bootout", domain+"/"+Label)
+	return os.Remove(file)
+}
+
+// Query returns the status of the service.
+func Query() (Status, error) {
+	domain, file, err := launchd()
+	status := Status{Manager: Manager, File: file}
+	if err != nil {
+		return status, err
+	}
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return status, nil
+	} else if err != nil {
+		return status, err
+	}
+	status.Installed = true
+
+	// print fails for services that are not loaded
+	output, err := run("launchctl", "print", domain+"/"+Label)
+	if err != nil {
+		status.State = "not loaded"
+		return status, nil
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if state, ok := strings.CutPrefix(strings.TrimSpace(line), "state = "); ok {
+			status.State = state
+			break
+		}
+	}
+	status.Running = status.State == "running"
+
+	return status, nil
+}
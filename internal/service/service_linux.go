@@ -0,0 +1,84 @@
+//go:build linux
+
+package service
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/paths"
+)
+
+// Manager is the service manager used on this system.
+const Manager = "systemd"
+
+// systemd returns the systemctl flags and the unit file for the current
+// user: a system unit for root, a user unit otherwise.
+func systemd() (flags []string, file, target string) {
+	if os.Geteuid() == 0 {
+		return nil, filepath.Join("/etc/systemd/system", Name+".service"), "multi-user.target"
+	}
+
+	return []string{"--user"}, filepath.Join(paths.Base(paths.Config), "systemd", "user", Name+".service"), "default.target"
+}
+
+// Install writes the unit of spec, then enables and (re)starts it. It
+// returns the path of the unit.
+func Install(spec Spec) (string, error) {
+	flags, file, target := systemd()
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(file, []byte(systemdUnit(spec, target)), 0o644); err != nil {
+		return "", err
+	}
+
+	for _, command := range [][]string{{"daemon-reload"}, {"enable", Name + ".service"}, {"restart", Name + ".service"}} {
+		if _, err := run("systemctl", append(flags, command...)...); err != nil {
+			return file, err
+		}
+	}
+
+	return file, nil
+}
+
+// Uninstall stops and disables the service, then removes its unit.
+func Uninstall() error {
+	flags, file, _ := systemd()
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return ErrNotInstalled
+	}
+
+	if _, err := run("systemctl", append(flags, "disable", "--now", Name+".service")...); err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil {
+		return err
+	}
+	_, err := run("systemctl", append(flags, "daemon-reload")...)
+	return err
+}
+
+// Query returns the status of the service.
+func Query() (Status, error) {
+	flags, file, _ := systemd()
+	status := Status{Manager: Manager, File: file}
+	if _, err := os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return status, nil
+	} else if err != nil {
+		return status, err
+	}
+	status.Installed = true
+
+	// is-active fails for every state but active, and still prints it
+	state, err := run("systemctl", append(flags, "is-active", Name+".service")...)
+	if state == "" && err != nil {
+		return status, err
+	}
+	status.State = strings.TrimSpace(state)
+	status.Running = status.State == "active"
+
+	return status, nil
+}
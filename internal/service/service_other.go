@@ -0,0 +1,21 @@
+//go:build !linux && !darwin && !windows
+
+package service
+
+// Manager is the service manager used on this system, none is supported.
+const Manager = ""
+
+// Install fails, as no service manager is supported on this system.
+func Install(spec Spec) (string, error) {
+	return "", ErrUnsupported
+}
+
+// Uninstall fails, as no service manager is supported on this system.
+func Uninstall() error {
+	return ErrUnsupported
+}
+
+// Query fails, as no service manager is supported on this system.
+func Query() (Status, error) {
+	return Status{}, ErrUnsupported
+}
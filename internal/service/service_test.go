@@ -0,0 +1,50 @@
+package service
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+var spec = Spec{
+	Executable: "/opt/annas mcp/annas-mcp",
+	Args:       []string{"http", "--env-file", `/home/reader/My "Books"/.env`, "--port", "9000", "--profile", "50%$off"},
+	Dir:        "/home/reader/books 50%",
+	LogFile:    "/home/reader/.local/state/annas-mcp/service.log",
+}
+
+func TestSystemdUnit(t *testing.T) {
+	unit := systemdUnit(spec, "default.target")
+
+	expected := []string{
+		`ExecStart="/opt/annas mcp/annas-mcp" http --env-file "/home/reader/My \"Books\"/.env" --port 9000 --profile 50%%$$off` + "\n",
+		"WorkingDirectory=/home/reader/books 50%%\n",
+		"Restart=on-failure\n",
+		"WantedBy=default.target\n",
+	}
+	for _, line := range expected {
+		if !strings.Contains(unit, line) {
+			t.Errorf("Expected the unit to hold '%s', got:\n%s", strings.TrimSpace(line), unit)
+		}
+	}
+}
+
+func TestLaunchdPlist(t *testing.T) {
+	plist := launchdPlist(spec)
+
+	var parsed struct {
+		Keys    []string `xml:"dict>key"`
+		Strings []string `xml:"dict>array>string"`
+	}
+	if err := xml.Unmarshal([]byte(plist), &parsed); err != nil {
+		t.Fatalf("Expected a valid property list, got %v:\n%s", err, plist)
+	}
+	if len(parsed.Strings) != len(spec.Args)+1 || parsed.Strings[3] != spec.Args[2] {
+		t.Errorf("Expected the program arguments to be kept as is, got %q", parsed.Strings)
+	}
+	for _, key := range []string{"Label", "WorkingDirectory", "RunAtLoad", "KeepAlive", "StandardErrorPath"} {
+		if !strings.Contains(plist, "<key>"+key+"</key>") {
+			t.Errorf("Expected the key '%s' in:\n%s", key, plist)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+//go:build windows
+
+package service
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Manager is the service manager used on this system.
+const Manager = "windows"
+
+// stopTimeout bounds the wait for the service to stop.
+const stopTimeout = 20 * time.Second
+
+// states names the states of a Windows service.
+var states = map[svc.State]string{
+	svc.Stopped:         "stopped",
+	svc.StartPending:    "start pending",
+	svc.StopPending:     "stop pending",
+	svc.Running:         "running",
+	svc.ContinuePending: "continue pending",
+	svc.PausePending:    "pause pending",
+	svc.Paused:          "paused",
+}
+
+// Install registers spec as a service starting with Windows and restarting
+// when it fails, replacing the command of an installed one, and starts it.
+// The service control manager starts services in the system folder, so the
+// working directory is passed with WorkDirFlag. It returns no path, as
+// Windows keeps services in the registry.
+func Install(spec Spec) (string, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to the service control manager, run as an administrator: %w", err)
+	}
+	defer m.Disconnect()
+
+	args := spec.Args
+	if spec.Dir != "" {
+		args = append(args[:len(args):len(args)], "--"+WorkDirFlag, spec.Dir)
+	}
+
+	s, err := m.OpenService(Name)
+	if err == nil {
+		defer s.Close()
+		if err := stop(s); err != nil {
+			return "", err
+		}
+		config, err := s.Config()
+		if err != nil {
+			return "", err
+		}
+		config.BinaryPathName = commandLine(spec.Executable, args)
+		if err := s.UpdateConfig(config); err != nil {
+			return "", err
+		}
+	} else {
+		s, err = m.CreateService(Name, spec.Executable, mgr.Config{
+			DisplayName: Description,
+			Description: Description,
+			StartType:   mgr.StartAutomatic,
+		}, args...)
+		if err != nil {
+			return "", err
+		}
+		defer s.Close()
+	}
+
+	err = s.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+	}, uint32((24 * time.Hour).Seconds()))
+	if err != nil {
+		return "", err
+	}
+
+	return "", s.Start()
+}
+
+// Uninstall stops the service and removes it.
+func Uninstall() error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("failed to connect to the service control manager, run as an administrator: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(Name)
+	if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+		return ErrNotInstalled
+	}
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	if err := stop(s); err != nil {
+		return err
+	}
+	return s.Delete()
+}
+
+// Query returns the status of the service.
+func Query() (Status, error) {
+	status := Status{Manager: Manager}
+	m, err := mgr.Connect()
+	if err != nil {
+		return status, err
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(Name)
+	if errors.Is(err, windows.ERROR_SERVICE_DOES_NOT_EXIST) {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+	defer s.Close()
+	status.Installed = true
+
+	current, err := s.Query()
+	if err != nil {
+		return status, err
+	}
+	status.State = states[current.State]
+	status.Running = current.State == svc.Running
+
+	return status, nil
+}
+
+// stop stops s and waits until it stopped.
+func stop(s *mgr.Service) error {
+	current, err := s.Query()
+	if err != nil {
+		return err
+	}
+	if current.State == svc.Stopped {
+		return nil
+	}
+	if current.State != svc.StopPending {
+		if current, err = s.Control(svc.Stop); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(stopTimeout)
+	for current.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service did not stop within %s", stopTimeout)
+		}
+		time.Sleep(300 * time.Millisecond)
+		if current, err = s.Query(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// commandLine joins executable and args the way CreateService does.
+func commandLine(executable string, args []string) string {
+	line := syscall.EscapeArg(executable)
+	for _, arg := range args {
+		line += " " + syscall.EscapeArg(arg)
+	}
+
+	return line
+}
+
+// handler answers the requests of the service control manager while start
+// runs the server, which stop asks to drain and return.
+type handler struct {
+	start func() error
+	stop  func()
+	err   error
+}
+
+func (h *handler) Execute(args []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	done := make(chan error, 1)
+	go func() {
+		done <- h.start()
+	}()
+	changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+
+	for {
+		select {
+		case err := <-done:
+			h.err = err
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+		case request := <-requests:
+			switch request.Cmd {
+			case svc.Interrogate:
+				changes <- request.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending, WaitHint: uint32(stopTimeout / time.Millisecond)}
+				h.stop()
+				h.err = <-done
+				return false, 0
+			}
+		}
+	}
+}
+
+// Run runs start as a service when the process was started by the service
+// control manager, calling stop when the service is stopped and waiting for
+// start to return. It reports false, without running start, when the process
+// was started otherwise.
+func Run(start func() error, stop func()) (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return false, err
+	}
+
+	h := &handler{start: start, stop: stop}
+	if err := svc.Run(Name, h); err != nil {
+		return true, err
+	}
+	return true, h.err
+}
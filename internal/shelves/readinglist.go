@@ -0,0 +1,188 @@
+package shelves
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// listItem matches a Markdown list item, bulleted, numbered, or a task.
+var listItem = regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s+)?(.+)$`)
+
+// authorSeparators split "Title by Author" style list items, the first one
+// found wins.
+var authorSeparators = []string{" by ", " — ", " – ", " - "}
+
+// ParseReadingList reads a reading list, either a CSV file with a title
+// column and optional author and ISBN columns, a Markdown table with the same
+// columns, or a Markdown list of "Title by Author" items.
+func ParseReadingList(r io.Reader) ([]Entry, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reading list: %w", err)
+	}
+
+	var entries []Entry
+	switch first := firstLine(data); {
+	case bytes.HasPrefix(first, []byte("|")):
+		entries, err = parseMarkdownTable(data)
+	case listItem.Match(first):
+		entries = parseMarkdownList(data)
+	default:
+		entries, err = parseListCSV(bytes.TrimSpace(data))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("the reading list holds no entries")
+	}
+
+	return entries, nil
+}
+
+// firstLine returns the first line of data that is neither blank nor a
+// Markdown heading.
+func firstLine(data []byte) []byte {
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && !bytes.HasPrefix(line, []byte("#")) {
+			return line
+		}
+	}
+
+	return nil
+}
+
+// listColumns finds the title, author, and ISBN columns of a header row,
+// -1 for those that are missing.
+func listColumns(header []string) (title, author, isbn int, err error) {
+	title, author, isbn = -1, -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "title", "book":
+			title = i
+		case "author", "authors":
+			author = i
+		case "isbn", "isbn13", "isbn 13":
+			isbn = i
+		}
+	}
+	if title < 0 {
+		return 0, 0, 0, errors.New("the reading list has no title column")
+	}
+
+	return title, author, isbn, nil
+}
+
+// listEntry builds the entry of a row from its title, author, and ISBN
+// columns.
+func listEntry(record []string, title, author, isbn int) Entry {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.Trim(strings.TrimSpace(record[i]), `*_="`)
+	}
+
+	return Entry{Title: field(title), Author: field(author), ISBN: field(isbn)}
+}
+
+// parseListCSV reads a CSV reading list with a header row.
+func parseListCSV(data []byte) ([]Entry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	title, author, isbn, err := listColumns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if entry := listEntry(record, title, author, isbn); entry.Title != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// parseMarkdownTable reads a Markdown table whose header row names the
+// columns.
+func parseMarkdownTable(data []byte) ([]Entry, error) {
+	var title, author, isbn int
+	header := true
+
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		cells := strings.Split(strings.Trim(line, "|"), "|")
+
+		if header {
+			var err error
+			if title, author, isbn, err = listColumns(cells); err != nil {
+				return nil, err
+			}
+			header = false
+			continue
+		}
+		// Skip the delimiter row below the header
+		if strings.Trim(strings.Join(cells, ""), " :-") == "" {
+			continue
+		}
+		if entry := listEntry(cells, title, author, isbn); entry.Title != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// parseMarkdownList reads the items of a Markdown list, splitting "Title by
+// Author" items into their title and author.
+func parseMarkdownList(data []byte) []Entry {
+	entries := make([]Entry, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		match := listItem.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+
+		entry := Entry{Title: strings.TrimSpace(match[1])}
+		for _, separator := range authorSeparators {
+			if title, author, ok := strings.Cut(entry.Title, separator); ok {
+				entry.Title, entry.Author = strings.TrimSpace(title), strings.TrimSpace(author)
+				break
+			}
+		}
+		entry.Title = strings.Trim(entry.Title, `*_"`)
+		entry.Author = strings.Trim(entry.Author, `*_"`)
+		if entry.Title != "" {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
@@ -0,0 +1,161 @@
+package shelves
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const HardcoverEndpoint = "https://api.hardcover.app/v1/graphql"
+
+// hardcoverWantToRead is Hardcover's status_id for the "Want to Read" shelf.
+const hardcoverWantToRead = 1
+
+// Entry is a single book on a want-to-read shelf.
+type Entry struct {
+	Title  string `json:"title"`
+	Author string `json:"author,omitempty"`
+	ISBN   string `json:"isbn,omitempty"`
+}
+
+// ParseGoodreadsCSV reads a Goodreads library export and returns the books on
+// the "to-read" shelf.
+func ParseGoodreadsCSV(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["Title"]; !ok {
+		return nil, errors.New("not a Goodreads export: missing Title column")
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		// Goodreads wraps ISBNs as ="0140328726" to stop spreadsheets mangling them
+		return strings.Trim(strings.TrimSpace(record[i]), `="`)
+	}
+
+	entries := make([]Entry, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if shelf := field(record, "Exclusive Shelf"); shelf != "" && shelf != "to-read" {
+			continue
+		}
+
+		isbn := field(record, "ISBN13")
+		if isbn == "" {
+			isbn = field(record, "ISBN")
+		}
+
+		entries = append(entries, Entry{
+			Title:  field(record, "Title"),
+			Author: field(record, "Author"),
+			ISBN:   isbn,
+		})
+	}
+
+	return entries, nil
+}
+
+type hardcoverResponse struct {
+	Data struct {
+		Me []struct {
+			UserBooks []struct {
+				Book struct {
+					Title         string `json:"title"`
+					Contributions []struct {
+						Author struct {
+							Name string `json:"name"`
+						} `json:"author"`
+					} `json:"contributions"`
+				} `json:"book"`
+				Edition *struct {
+					ISBN13 string `json:"isbn_13"`
+				} `json:"edition"`
+			} `json:"user_books"`
+		} `json:"me"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// FetchHardcover retrieves the "Want to Read" shelf of the Hardcover account
+// that owns the API token.
+func FetchHardcover(ctx context.Context, token string) ([]Entry, error) {
+	query := fmt.Sprintf(`{ me { user_books(where: {status_id: {_eq: %d}}) { book { title contributions { author { name } } } edition { isbn_13 } } } }`, hardcoverWantToRead)
+	body, err := json.Marshal(map[string]string{"query": query})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, HardcoverEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if !strings.HasPrefix(token, "Bearer ") {
+		token = "Bearer " + token
+	}
+	req.Header.Set("Authorization", token)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Hardcover returned status %d", resp.StatusCode)
+	}
+
+	var apiResp hardcoverResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, err
+	}
+	if len(apiResp.Errors) > 0 {
+		return nil, errors.New(apiResp.Errors[0].Message)
+	}
+
+	entries := make([]Entry, 0)
+	for _, me := range apiResp.Data.Me {
+		for _, userBook := range me.UserBooks {
+			entry := Entry{Title: userBook.Book.Title}
+			if len(userBook.Book.Contributions) > 0 {
+				entry.Author = userBook.Book.Contributions[0].Author.Name
+			}
+			if userBook.Edition != nil {
+				entry.ISBN = userBook.Edition.ISBN13
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
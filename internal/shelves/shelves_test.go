@@ -0,0 +1,93 @@
+package shelves
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGoodreadsCSV(t *testing.T) {
+	export := `Book Id,Title,Author,ISBN,ISBN13,Exclusive Shelf
+1,Dune,Frank Herbert,"=""0441172717""","=""9780441172719""",to-read
+2,Emma,Jane Austen,"=""""","=""""",read
+3,Solaris,Stanislaw Lem,"=""0156027607""","=""""",to-read
+`
+
+	entries, err := ParseGoodreadsCSV(strings.NewReader(export))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 to-read entries, got %d", len(entries))
+	}
+	if entries[0].Title != "Dune" || entries[0].Author != "Frank Herbert" || entries[0].ISBN != "9780441172719" {
+		t.Errorf("Unexpected first entry: %+v", entries[0])
+	}
+	if entries[1].ISBN != "0156027607" {
+		t.Errorf("Expected ISBN-10 fallback '0156027607', got '%s'", entries[1].ISBN)
+	}
+
+	t.Run("Missing Title column", func(t *testing.T) {
+		if _, err := ParseGoodreadsCSV(strings.NewReader("a,b\n1,2\n")); err == nil {
+			t.Error("Expected error for non-Goodreads CSV, got nil")
+		}
+	})
+}
+
+func TestParseReadingList(t *testing.T) {
+	expected := []Entry{
+		{Title: "Dune", Author: "Frank Herbert", ISBN: "9780441172719"},
+		{Title: "Solaris", Author: "Stanislaw Lem"},
+	}
+
+	cases := map[string]string{
+		"CSV": "Title,Author,ISBN\nDune,Frank Herbert,9780441172719\nSolaris,Stanislaw Lem,\n",
+		"Markdown table": `# To read
+
+| Title | Author | ISBN |
+| ----- | :----- | ---- |
+| **Dune** | Frank Herbert | 9780441172719 |
+| Solaris | Stanislaw Lem | |
+`,
+	}
+	for name, list := range cases {
+		t.Run(name, func(t *testing.T) {
+			entries, err := ParseReadingList(strings.NewReader(list))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(entries) != len(expected) {
+				t.Fatalf("Expected %d entries, got %+v", len(expected), entries)
+			}
+			for i := range expected {
+				if entries[i] != expected[i] {
+					t.Errorf("Expected entry %+v, got %+v", expected[i], entries[i])
+				}
+			}
+		})
+	}
+
+	t.Run("Markdown list", func(t *testing.T) {
+		entries, err := ParseReadingList(strings.NewReader("## Sci-fi\n\n- [ ] *Dune* by Frank Herbert\n2. Solaris — Stanislaw Lem\n* Roadside Picnic\n"))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if len(entries) != 3 {
+			t.Fatalf("Expected 3 entries, got %+v", entries)
+		}
+		if entries[0].Title != "Dune" || entries[0].Author != "Frank Herbert" {
+			t.Errorf("Unexpected first entry: %+v", entries[0])
+		}
+		if entries[1] != expected[1] {
+			t.Errorf("Expected entry %+v, got %+v", expected[1], entries[1])
+		}
+		if entries[2].Title != "Roadside Picnic" || entries[2].Author != "" {
+			t.Errorf("Unexpected entry without author: %+v", entries[2])
+		}
+	})
+
+	t.Run("Missing title column", func(t *testing.T) {
+		if _, err := ParseReadingList(strings.NewReader("name,year\nDune,1965\n")); err == nil {
+			t.Error("Expected error for a list without a title column, got nil")
+		}
+	})
+}
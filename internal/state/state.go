@@ -0,0 +1,203 @@
+// Package state bundles the files holding the state of a server into a
+// tarball, so it can be moved to another machine.
+package state
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestName is the entry of a bundle describing its contents. It is
+// written first, so imports can check the bundle before extracting it.
+const ManifestName = "manifest.json"
+
+// ConfigName is the entry of a bundle holding the exported configuration.
+const ConfigName = "config.json"
+
+// ErrExists is returned when an import would overwrite an existing file.
+var ErrExists = errors.New("file already exists")
+
+// Item is a state file, stored in bundles under Name.
+type Item struct {
+	Name string `json:"name"`
+	// Path is where the file lives on this machine. Items without a path are
+	// not configured and are skipped.
+	Path string `json:"-"`
+	// Secret items hold credentials and are only bundled on request.
+	Secret bool `json:"secret,omitempty"`
+}
+
+// Manifest describes the contents of a bundle.
+type Manifest struct {
+	CreatedAt time.Time `json:"created_at"`
+	Version   string    `json:"version,omitempty"`
+	Items     []Item    `json:"items"`
+	// Secrets reports whether secret items and settings were bundled.
+	Secrets bool `json:"secrets"`
+}
+
+// Export writes a gzip-compressed tarball of config and the items whose
+// file exists to w. Secret items are skipped unless secrets is set. It
+// returns the manifest of the bundle.
+func Export(w io.Writer, items []Item, config []byte, version string, secrets bool) (*Manifest, error) {
+	manifest := &Manifest{CreatedAt: time.Now().UTC(), Version: version, Items: make([]Item, 0), Secrets: secrets}
+	for _, item := range items {
+		if item.Path == "" || (item.Secret && !secrets) {
+			continue
+		}
+		if _, err := os.Stat(item.Path); errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		manifest.Items = append(manifest.Items, item)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeEntry(tw, ManifestName, data, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := writeEntry(tw, ConfigName, config, manifest.CreatedAt); err != nil {
+		return nil, err
+	}
+	for _, item := range manifest.Items {
+		data, err := os.ReadFile(item.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", item.Path, err)
+		}
+		if err := writeEntry(tw, item.Name, data, manifest.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return manifest, nil
+}
+
+func writeEntry(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(data)), ModTime: modTime}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return nil
+}
+
+// Bundle is the contents of a bundle read into memory. State files are small,
+// so they are checked in full before anything is written.
+type Bundle struct {
+	Manifest Manifest
+	Config   []byte
+	Files    map[string][]byte
+}
+
+// Read reads a bundle written by Export from r.
+func Read(r io.Reader) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	defer gz.Close()
+
+	bundle := &Bundle{Files: make(map[string][]byte)}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bundle: %w", err)
+		}
+		bundle.Files[header.Name] = data
+	}
+
+	manifest, ok := bundle.Files[ManifestName]
+	if !ok {
+		return nil, fmt.Errorf("not a state bundle: missing %s", ManifestName)
+	}
+	if err := json.Unmarshal(manifest, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle manifest: %w", err)
+	}
+	bundle.Config = bundle.Files[ConfigName]
+	delete(bundle.Files, ManifestName)
+	delete(bundle.Files, ConfigName)
+
+	return bundle, nil
+}
+
+// Restore writes the bundled files of items to their paths on this machine.
+// Items that are not configured here, or not in the bundle, are skipped.
+// Existing files are only replaced with overwrite; otherwise ErrExists is
+// returned before anything is written. It returns the restored items.
+func (b *Bundle) Restore(items []Item, overwrite bool) ([]Item, error) {
+	restore := make([]Item, 0, len(items))
+	for _, item := range items {
+		if _, ok := b.Files[item.Name]; !ok || item.Path == "" {
+			continue
+		}
+		if _, err := os.Stat(item.Path); err == nil && !overwrite {
+			return nil, fmt.Errorf("%w: %s (pass --force to replace it)", ErrExists, item.Path)
+		}
+		restore = append(restore, item)
+	}
+
+	for i, item := range restore {
+		if err := WriteFile(item.Path, b.Files[item.Name]); err != nil {
+			return restore[:i], err
+		}
+	}
+
+	return restore, nil
+}
+
+// WriteFile writes data to path through a temporary file, creating the
+// parent directory if needed. Files are only readable by their owner, since
+// state files may hold credentials.
+func WriteFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package state
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExportRestore(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	if err := os.WriteFile(filepath.Join(source, "audit.log"), []byte("history\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "tokens.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	items := []Item{
+		{Name: "audit.log", Path: filepath.Join(source, "audit.log")},
+		{Name: "schedules.json", Path: filepath.Join(source, "schedules.json")},
+		{Name: "tokens.json", Path: filepath.Join(source, "tokens.json"), Secret: true},
+	}
+
+	var buf bytes.Buffer
+	manifest, err := Export(&buf, items, []byte(`{"audit_log":"x"}`), "v1.0.0", false)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if len(manifest.Items) != 1 || manifest.Items[0].Name != "audit.log" {
+		t.Errorf("Expected only the existing, non-secret audit.log to be bundled, got %v", manifest.Items)
+	}
+
+	bundle, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if string(bundle.Config) != `{"audit_log":"x"}` {
+		t.Errorf("Expected the bundled config, got '%s'", bundle.Config)
+	}
+	if bundle.Manifest.Version != "v1.0.0" {
+		t.Errorf("Expected Version 'v1.0.0', got '%s'", bundle.Manifest.Version)
+	}
+
+	targets := []Item{
+		{Name: "audit.log", Path: filepath.Join(target, "logs", "audit.log")},
+		{Name: "tokens.json"},
+	}
+	restored, err := bundle.Restore(targets, false)
+	if err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if len(restored) != 1 {
+		t.Errorf("Expected 1 restored file, got %d", len(restored))
+	}
+	if data, _ := os.ReadFile(targets[0].Path); string(data) != "history\n" {
+		t.Errorf("Expected the restored audit log 'history', got '%s'", data)
+	}
+
+	t.Run("existing files are kept", func(t *testing.T) {
+		if _, err := bundle.Restore(targets, false); !errors.Is(err, ErrExists) {
+			t.Errorf("Expected ErrExists, got %v", err)
+		}
+		if _, err := bundle.Restore(targets, true); err != nil {
+			t.Errorf("Expected an overwriting restore to succeed, got %v", err)
+		}
+	})
+
+	t.Run("not a bundle", func(t *testing.T) {
+		if _, err := Read(bytes.NewReader([]byte("plain text"))); err == nil {
+			t.Error("Expected an error for a file that is not a bundle")
+		}
+	})
+}
@@ -0,0 +1,231 @@
+// Package telemetry keeps local counters of how the server and CLI are run,
+// and turns them into an anonymous usage ping for operators who opt in.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/state"
+)
+
+// Counts are the runs, searches, and downloads of a mode.
+type Counts struct {
+	Runs      int64 `json:"runs"`
+	Searches  int64 `json:"searches"`
+	Downloads int64 `json:"downloads"`
+}
+
+func (c *Counts) add(other Counts) {
+	c.Runs += other.Runs
+	c.Searches += other.Searches
+	c.Downloads += other.Downloads
+}
+
+func (c Counts) isZero() bool {
+	return c == Counts{}
+}
+
+// Stats are the counts of every mode, such as "cli", "mcp/stdio", or
+// "http/streamable", since the stats were started.
+type Stats struct {
+	Since time.Time          `json:"since"`
+	Modes map[string]*Counts `json:"modes"`
+	// Pending are the counts not sent in a ping yet, and LastPing the time
+	// of the last one.
+	Pending  map[string]*Counts `json:"pending,omitempty"`
+	LastPing time.Time          `json:"last_ping,omitempty"`
+}
+
+// Due reports whether a ping should be sent at now: there are pending counts
+// and the last ping is at least interval old.
+func (s *Stats) Due(now time.Time, interval time.Duration) bool {
+	if now.Sub(s.LastPing) < interval {
+		return false
+	}
+	for _, counts := range s.Pending {
+		if !counts.isZero() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Ping is the anonymous usage report: the version and the pending counts of
+// every mode, nothing else.
+type Ping struct {
+	Version string            `json:"version"`
+	Modes   map[string]Counts `json:"modes"`
+}
+
+// Ping returns the ping reporting the pending counts.
+func (s *Stats) Ping(version string) Ping {
+	ping := Ping{Version: version, Modes: make(map[string]Counts, len(s.Pending))}
+	for mode, counts := range s.Pending {
+		if !counts.isZero() {
+			ping.Modes[mode] = *counts
+		}
+	}
+
+	return ping
+}
+
+// Store persists Stats to a file shared by every process of the user. Each
+// update re-reads the file, so concurrent processes add up their counts
+// instead of overwriting each other's.
+type Store struct {
+	mu   sync.Mutex
+	path string
+	// memory holds the stats when there is no file
+	memory *Stats
+}
+
+// NewStore returns a store writing to path, or keeping the stats in memory
+// when path is empty.
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Path returns the stats file, empty when the stats are kept in memory.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Load returns the recorded stats. A missing file holds none.
+func (s *Store) Load() (*Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.load(time.Now())
+}
+
+// Add records counts for mode at now, and returns the updated stats.
+func (s *Store) Add(mode string, counts Counts, now time.Time) (*Stats, error) {
+	return s.update(now, func(stats *Stats) {
+		for _, modes := range []map[string]*Counts{stats.Modes, stats.Pending} {
+			if modes[mode] == nil {
+				modes[mode] = &Counts{}
+			}
+			modes[mode].add(counts)
+		}
+	})
+}
+
+// Sent records that ping was sent at now, removing its counts from the
+// pending ones. Counts added since the ping was built stay pending.
+func (s *Store) Sent(ping Ping, now time.Time) error {
+	_, err := s.update(now, func(stats *Stats) {
+		for mode, counts := range ping.Modes {
+			pending, ok := stats.Pending[mode]
+			if !ok {
+				continue
+			}
+			pending.add(Counts{Runs: -counts.Runs, Searches: -counts.Searches, Downloads: -counts.Downloads})
+			if pending.isZero() {
+				delete(stats.Pending, mode)
+			}
+		}
+		stats.LastPing = now.UTC()
+	})
+
+	return err
+}
+
+func (s *Store) update(now time.Time, change func(*Stats)) (*Stats, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, err := s.load(now)
+	if err != nil {
+		return nil, err
+	}
+	change(stats)
+	if err := s.save(stats); err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+func (s *Store) load(now time.Time) (*Stats, error) {
+	if s.path == "" {
+		if s.memory == nil {
+			s.memory = newStats(now)
+		}
+		return s.memory, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return newStats(now), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stats file: %w", err)
+	}
+	stats := &Stats{}
+	if err := json.Unmarshal(data, stats); err != nil {
+		return nil, fmt.Errorf("failed to parse stats file %s: %w", s.path, err)
+	}
+	if stats.Modes == nil {
+		stats.Modes = make(map[string]*Counts)
+	}
+	if stats.Pending == nil {
+		stats.Pending = make(map[string]*Counts)
+	}
+
+	return stats, nil
+}
+
+func newStats(now time.Time) *Stats {
+	return &Stats{Since: now.UTC(), Modes: make(map[string]*Counts), Pending: make(map[string]*Counts)}
+}
+
+// save writes the stats through a temporary file so readers never see a
+// partial file.
+func (s *Store) save(stats *Stats) error {
+	if s.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode stats: %w", err)
+	}
+
+	return state.WriteFile(s.path, data)
+}
+
+// Send posts ping as JSON to endpoint.
+func Send(ctx context.Context, endpoint string, ping Ping) error {
+	body, err := json.Marshal(ping)
+	if err != nil {
+		return fmt.Errorf("failed to encode ping: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "annas-mcp/"+ping.Version)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send ping: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to send ping: status %d", resp.StatusCode)
+	}
+
+	return nil
+}
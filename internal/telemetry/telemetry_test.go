@@ -0,0 +1,86 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "annas-mcp", "stats.json")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	if _, err := NewStore(path).Add("cli", Counts{Runs: 1, Searches: 2}, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	// A second process adds to the same file
+	stats, err := NewStore(path).Add("mcp/stdio", Counts{Runs: 1, Downloads: 3}, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !stats.Since.Equal(now) || stats.Modes["cli"].Searches != 2 || stats.Modes["mcp/stdio"].Downloads != 3 {
+		t.Fatalf("Expected the counts of both processes, got %+v", stats)
+	}
+	if !stats.Due(now, 24*time.Hour) {
+		t.Errorf("Expected a ping to be due")
+	}
+
+	store := NewStore(path)
+	ping := stats.Ping("v1.2.3")
+	if _, err := store.Add("cli", Counts{Runs: 1}, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Sent(ping, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	stats, err = store.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats.Modes["cli"].Runs != 2 {
+		t.Errorf("Expected the totals to be kept, got %+v", stats.Modes["cli"])
+	}
+	if len(stats.Pending) != 1 || *stats.Pending["cli"] != (Counts{Runs: 1}) {
+		t.Errorf("Expected only the run added after the ping to be pending, got %+v", stats.Pending)
+	}
+	if stats.Due(now.Add(time.Hour), 24*time.Hour) || !stats.Due(now.Add(25*time.Hour), 24*time.Hour) {
+		t.Errorf("Expected the next ping to be due a day after the last one")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewStore("")
+	if _, err := store.Add("cli", Counts{Runs: 1}, time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	stats, err := store.Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if stats.Modes["cli"].Runs != 1 {
+		t.Errorf("Expected the run to be kept in memory, got %+v", stats.Modes)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var received Ping
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	ping := Ping{Version: "v1.2.3", Modes: map[string]Counts{"http/streamable": {Runs: 1, Searches: 4}}}
+	if err := Send(context.Background(), server.URL, ping); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if received.Version != "v1.2.3" || received.Modes["http/streamable"].Searches != 4 {
+		t.Errorf("Expected the ping to be received, got %+v", received)
+	}
+}
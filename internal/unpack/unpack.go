@@ -0,0 +1,232 @@
+// Package unpack extracts downloaded ZIP and RAR containers, such as CBZ and
+// CBR comics, into a folder.
+package unpack
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/iosifache/annas-mcp/internal/fsutil"
+)
+
+// DefaultUnrarBinary is used when no explicit unrar binary is configured.
+const DefaultUnrarBinary = "unrar"
+
+// TempPrefix names the folders archives are extracted to before they are
+// moved into place.
+const TempPrefix = ".annas-mcp-extract-"
+
+// Kinds of containers recognized by Detect.
+const (
+	KindZip = "zip"
+	KindRar = "rar"
+)
+
+// ErrTooLarge is returned when the extracted content exceeds the size limit.
+var ErrTooLarge = errors.New("extracted content exceeds the size limit")
+
+var (
+	zipMagic = []byte("PK\x03\x04")
+	rarMagic = []byte("Rar!\x1a\x07")
+)
+
+// Options configures Extract.
+type Options struct {
+	// UnrarBinary extracts RAR containers, DefaultUnrarBinary when empty.
+	UnrarBinary string
+	// MaxBytes caps the total size of the extracted files, 0 for no limit.
+	MaxBytes int64
+}
+
+// Result reports what Extract wrote.
+type Result struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// Detect returns the kind of container of the file at path from its leading
+// bytes, since the format of a record does not always match its content. It
+// returns an empty kind for other files.
+func Detect(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header := make([]byte, len(rarMagic))
+	n, err := io.ReadFull(file, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	switch header = header[:n]; {
+	case bytes.HasPrefix(header, zipMagic):
+		return KindZip, nil
+	case bytes.HasPrefix(header, rarMagic):
+		return KindRar, nil
+	default:
+		return "", nil
+	}
+}
+
+// Extract unpacks the container of kind at path into the folder dest,
+// replacing it if it exists. Content is extracted to a temporary folder next
+// to dest first, so dest never holds a partial extraction. Entries that would
+// land outside of dest, symlinks, and special files are skipped.
+func Extract(ctx context.Context, kind, path, dest string, opts Options) (*Result, error) {
+	tmp, err := os.MkdirTemp(filepath.Dir(dest), TempPrefix+"*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", path, err)
+	}
+	defer os.RemoveAll(tmp)
+
+	var result *Result
+	switch kind {
+	case KindZip:
+		result, err = extractZip(path, tmp, opts.MaxBytes)
+	case KindRar:
+		result, err = extractRar(ctx, opts.UnrarBinary, path, tmp, opts.MaxBytes)
+	default:
+		err = fmt.Errorf("unsupported container %q", kind)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", path, err)
+	}
+
+	if err := os.RemoveAll(dest); err != nil {
+		return nil, fmt.Errorf("failed to replace %s: %w", dest, err)
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %w", path, err)
+	}
+
+	return result, nil
+}
+
+// extractZip writes the regular files of the ZIP archive at path below dir.
+func extractZip(path, dir string, maxBytes int64) (*Result, error) {
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	result := &Result{}
+	for _, file := range reader.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		// Names come from the archive, so they must not escape dir (zip slip)
+		name := filepath.FromSlash(file.Name)
+		if !filepath.IsLocal(name) {
+			continue
+		}
+		target, err := fsutil.Confine(dir, name)
+		if err != nil {
+			continue
+		}
+
+		limit := int64(-1)
+		if maxBytes > 0 {
+			limit = maxBytes - result.Bytes
+		}
+		written, err := writeEntry(file, target, limit)
+		if err != nil {
+			return nil, err
+		}
+		result.Files++
+		result.Bytes += written
+	}
+
+	return result, nil
+}
+
+// writeEntry writes a file of a ZIP archive to target, failing with
+// ErrTooLarge past limit bytes unless limit is negative. Sizes in the
+// archive headers can lie, so the limit is enforced while writing.
+func writeEntry(file *zip.File, target string, limit int64) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return 0, err
+	}
+
+	in, err := file.Open()
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+
+	body := io.Reader(in)
+	if limit >= 0 {
+		// Read one byte past the limit to detect oversized content
+		body = io.LimitReader(in, limit+1)
+	}
+	written, err := io.Copy(out, body)
+	if err != nil {
+		return written, err
+	}
+	if limit >= 0 && written > limit {
+		return written, ErrTooLarge
+	}
+
+	return written, out.Close()
+}
+
+// extractRar lets unrar extract the archive at path into dir, then drops the
+// symlinks and special files it created.
+func extractRar(ctx context.Context, binary, path, dir string, maxBytes int64) (*Result, error) {
+	if binary == "" {
+		binary = DefaultUnrarBinary
+	}
+	resolved, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("unrar binary %q not found: %w", binary, err)
+	}
+
+	// -p- never prompts for a password, -o+ overwrites duplicate entries
+	output, err := exec.CommandContext(ctx, resolved, "x", "-y", "-p-", "-o+", "--", path, dir+string(filepath.Separator)).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("unrar failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	result := &Result{}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return os.Remove(path)
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		result.Files++
+		result.Bytes += info.Size()
+		if maxBytes > 0 && result.Bytes > maxBytes {
+			return ErrTooLarge
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package unpack
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	writer := zip.NewWriter(out)
+	for name, content := range files {
+		w, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	root := t.TempDir()
+	archive := filepath.Join(root, "Comic.cbz")
+	writeZip(t, archive, map[string]string{
+		"001.jpg":           "page one",
+		"extra/002.jpg":     "page two",
+		"../evil.txt":       "escaped",
+		"/tmp/absolute.txt": "escaped",
+	})
+
+	kind, err := Detect(archive)
+	if err != nil || kind != KindZip {
+		t.Fatalf("Expected kind 'zip', got '%s' (%v)", kind, err)
+	}
+
+	dest := filepath.Join(root, "Comic")
+	result, err := Extract(context.Background(), kind, archive, dest, Options{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.Files != 2 || result.Bytes != 16 {
+		t.Errorf("Expected 2 files of 16 bytes, got %d files of %d bytes", result.Files, result.Bytes)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dest, "extra", "002.jpg")); string(data) != "page two" {
+		t.Errorf("Expected extracted content 'page two', got '%s'", data)
+	}
+	if _, err := os.Stat(filepath.Join(root, "evil.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected entries escaping the folder to be skipped, got %v", err)
+	}
+
+	t.Run("size limit", func(t *testing.T) {
+		_, err := Extract(context.Background(), kind, archive, filepath.Join(root, "Limited"), Options{MaxBytes: 10})
+		if !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Expected ErrTooLarge, got %v", err)
+		}
+		if _, err := os.Stat(filepath.Join(root, "Limited")); !os.IsNotExist(err) {
+			t.Errorf("Expected no partial extraction, got %v", err)
+		}
+	})
+
+	t.Run("other files", func(t *testing.T) {
+		book := filepath.Join(root, "notes.txt")
+		os.WriteFile(book, []byte("plain"), 0o644)
+		if kind, err := Detect(book); err != nil || kind != "" {
+			t.Errorf("Expected no kind for a plain file, got '%s' (%v)", kind, err)
+		}
+	})
+}
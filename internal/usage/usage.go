@@ -0,0 +1,195 @@
+// Package usage accounts the searches and downloads of every tenant of a
+// shared deployment against their quotas.
+package usage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+	"github.com/iosifache/annas-mcp/internal/state"
+)
+
+// Kinds of accounted requests.
+const (
+	KindSearch   = "search"
+	KindDownload = "download"
+)
+
+// ErrQuotaExceeded is returned once a tenant used up a quota.
+var ErrQuotaExceeded = errors.New("usage quota exceeded")
+
+// Counts are the requests of a tenant over a period.
+type Counts struct {
+	Searches  int `json:"searches"`
+	Downloads int `json:"downloads"`
+}
+
+func (c *Counts) add(kind string) {
+	if kind == KindDownload {
+		c.Downloads++
+	} else {
+		c.Searches++
+	}
+}
+
+// Usage is what a tenant did in the current UTC day and month, and overall.
+type Usage struct {
+	Tenant    string    `json:"tenant"`
+	Day       string    `json:"day"`
+	Month     string    `json:"month"`
+	Today     Counts    `json:"today"`
+	ThisMonth Counts    `json:"this_month"`
+	Total     Counts    `json:"total"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// roll starts new periods once the day or month of now differs from the
+// recorded one.
+func (u *Usage) roll(now time.Time) {
+	now = now.UTC()
+	if day := now.Format(time.DateOnly); u.Day != day {
+		u.Day = day
+		u.Today = Counts{}
+	}
+	if month := now.Format("2006-01"); u.Month != month {
+		u.Month = month
+		u.ThisMonth = Counts{}
+	}
+}
+
+// check returns an error when one more request of kind would exceed quota.
+func (u *Usage) check(kind string, quota auth.Quota) error {
+	type limit struct {
+		max    int
+		used   int
+		period string
+	}
+	limits := []limit{
+		{quota.SearchesPerDay, u.Today.Searches, "searches per day"},
+		{quota.SearchesPerMonth, u.ThisMonth.Searches, "searches per month"},
+	}
+	if kind == KindDownload {
+		limits = []limit{
+			{quota.DownloadsPerDay, u.Today.Downloads, "downloads per day"},
+			{quota.DownloadsPerMonth, u.ThisMonth.Downloads, "downloads per month"},
+		}
+	}
+
+	for _, l := range limits {
+		if l.max > 0 && l.used >= l.max {
+			return fmt.Errorf("%w: %d %s for %s", ErrQuotaExceeded, l.max, l.period, u.Tenant)
+		}
+	}
+	return nil
+}
+
+// Tracker counts the requests of every tenant, persisting them to a file
+// when it has one.
+type Tracker struct {
+	mu      sync.Mutex
+	path    string
+	Tenants map[string]*Usage `json:"tenants"`
+}
+
+// Open loads the usage file at path. A missing file starts empty, and an
+// empty path keeps the counts in memory only.
+func Open(path string) (*Tracker, error) {
+	t := &Tracker{path: path, Tenants: make(map[string]*Usage)}
+	if path == "" {
+		return t, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read usage file: %w", err)
+	}
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("failed to parse usage file %s: %w", path, err)
+	}
+	if t.Tenants == nil {
+		t.Tenants = make(map[string]*Usage)
+	}
+
+	return t, nil
+}
+
+// Record counts a request of kind for tenant, unless it would exceed quota.
+// It returns the usage including the request.
+func (t *Tracker) Record(tenant, kind string, quota auth.Quota, now time.Time) (Usage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	u, ok := t.Tenants[tenant]
+	if !ok {
+		u = &Usage{Tenant: tenant}
+		t.Tenants[tenant] = u
+	}
+	u.roll(now)
+
+	if err := u.check(kind, quota); err != nil {
+		return *u, err
+	}
+
+	u.Today.add(kind)
+	u.ThisMonth.add(kind)
+	u.Total.add(kind)
+	u.LastSeen = now.UTC()
+
+	return *u, t.save()
+}
+
+// Get returns the usage of tenant as of now.
+func (t *Tracker) Get(tenant string, now time.Time) Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	u := Usage{Tenant: tenant}
+	if recorded, ok := t.Tenants[tenant]; ok {
+		u = *recorded
+	}
+	u.roll(now)
+
+	return u
+}
+
+// All returns the usage of every tenant as of now, sorted by tenant.
+func (t *Tracker) All(now time.Time) []Usage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	all := make([]Usage, 0, len(t.Tenants))
+	for _, recorded := range t.Tenants {
+		u := *recorded
+		u.roll(now)
+		all = append(all, u)
+	}
+	sort.Slice(all, func(a, b int) bool {
+		return all[a].Tenant < all[b].Tenant
+	})
+
+	return all
+}
+
+// save writes the counts through a temporary file so readers never see a
+// partial file.
+func (t *Tracker) save() error {
+	if t.path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode usage: %w", err)
+	}
+
+	return state.WriteFile(t.path, data)
+}
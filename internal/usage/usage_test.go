@@ -0,0 +1,66 @@
+package usage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iosifache/annas-mcp/internal/auth"
+)
+
+func TestRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.json")
+	quota := auth.Quota{SearchesPerDay: 2, DownloadsPerMonth: 1}
+	day := time.Date(2025, 3, 31, 12, 0, 0, 0, time.UTC)
+
+	tracker, err := Open(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	t.Run("Daily", func(t *testing.T) {
+		for range 2 {
+			if _, err := tracker.Record("family", KindSearch, quota, day); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		if _, err := tracker.Record("family", KindSearch, quota, day); !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+		}
+		if _, err := tracker.Record("guests", KindSearch, quota, day); err != nil {
+			t.Errorf("Expected other tenants to have their own quota, got %v", err)
+		}
+
+		got, err := tracker.Record("family", KindSearch, quota, day.Add(24*time.Hour))
+		if err != nil {
+			t.Fatalf("Expected a new day to reset the quota, got %v", err)
+		}
+		if got.Today.Searches != 1 || got.ThisMonth.Searches != 1 || got.Total.Searches != 3 {
+			t.Errorf("Expected 1 search today and this month and 3 overall, got %+v", got)
+		}
+	})
+
+	t.Run("Monthly", func(t *testing.T) {
+		if _, err := tracker.Record("family", KindDownload, quota, day); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := tracker.Record("family", KindDownload, quota, day.Add(-24*time.Hour)); !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("Expected ErrQuotaExceeded within the month, got %v", err)
+		}
+	})
+
+	t.Run("Persisted", func(t *testing.T) {
+		reopened, err := Open(path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		all := reopened.All(day)
+		if len(all) != 2 || all[0].Tenant != "family" || all[0].Total.Searches != 3 || all[0].Total.Downloads != 1 {
+			t.Errorf("Expected the recorded usage of both tenants, got %+v", all)
+		}
+		if got := reopened.Get("nobody", day); got.Total.Searches != 0 || got.Day != "2025-03-31" {
+			t.Errorf("Expected empty usage for today, got %+v", got)
+		}
+	})
+}
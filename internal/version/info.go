@@ -0,0 +1,47 @@
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, set by release builds with
+// -ldflags "-X github.com/iosifache/annas-mcp/internal/version.commit=<sha> -X github.com/iosifache/annas-mcp/internal/version.date=<date>".
+// Builds from a git checkout fall back to the VCS stamp of the Go toolchain.
+var (
+	commit string
+	date   string
+)
+
+// Info describes the running build.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	BuildDate string `json:"build_date,omitempty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// GetInfo returns the version and build metadata of the running binary.
+func GetInfo() Info {
+	info := Info{
+		Version:   GetVersion(),
+		Commit:    commit,
+		BuildDate: date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+	}
+
+	return info
+}
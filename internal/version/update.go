@@ -0,0 +1,118 @@
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// releasesEndpoint returns the latest release of the project on GitHub.
+var releasesEndpoint = "https://api.github.com/repos/iosifache/annas-mcp/releases/latest"
+
+// Release is a published release.
+type Release struct {
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+// latest is the release found by the last successful check.
+var latest atomic.Pointer[Release]
+
+// CheckForUpdate looks up the latest release and remembers it for
+// UpdateAvailable.
+func CheckForUpdate(ctx context.Context) (*Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, releasesEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "annas-mcp/"+GetVersion())
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to check for updates: status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode the latest release: %w", err)
+	}
+
+	release := &Release{Version: body.TagName, URL: body.HTMLURL}
+	latest.Store(release)
+
+	return release, nil
+}
+
+// Remember records release as the latest one, as if CheckForUpdate had found
+// it, for releases looked up earlier.
+func Remember(release *Release) {
+	latest.Store(release)
+}
+
+// UpdateAvailable returns the latest release found by CheckForUpdate if it is
+// newer than the running version.
+func UpdateAvailable() *Release {
+	release := latest.Load()
+	if release == nil || !IsNewer(release.Version, GetVersion()) {
+		return nil
+	}
+
+	return release
+}
+
+// IsNewer reports whether the semantic version candidate is above current.
+// Versions that do not parse are never newer.
+func IsNewer(candidate, current string) bool {
+	a, ok := parseSemver(candidate)
+	if !ok {
+		return false
+	}
+	b, ok := parseSemver(current)
+	if !ok {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] > b[i]
+		}
+	}
+
+	return false
+}
+
+// parseSemver parses "v1.2.3", ignoring pre-release and build suffixes.
+func parseSemver(v string) ([3]int, bool) {
+	var parts [3]int
+
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) != 3 {
+		return parts, false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, false
+		}
+		parts[i] = n
+	}
+
+	return parts, true
+}
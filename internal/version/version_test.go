@@ -0,0 +1,47 @@
+package version
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIsNewer(t *testing.T) {
+	cases := []struct {
+		candidate, current string
+		expected           bool
+	}{
+		{"v0.0.4", "v0.0.3", true},
+		{"v0.1.0", "v0.0.9", true},
+		{"v1.0.0-rc.1", "v0.9.0", true},
+		{"v0.0.3", "v0.0.3", false},
+		{"v0.0.2", "v0.0.3", false},
+		{"nightly", "v0.0.3", false},
+	}
+	for _, c := range cases {
+		if got := IsNewer(c.candidate, c.current); got != c.expected {
+			t.Errorf("Expected IsNewer(%q, %q) to be %t, got %t", c.candidate, c.current, c.expected, got)
+		}
+	}
+}
+
+func TestCheckForUpdate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name": "v99.0.0", "html_url": "https://github.com/iosifache/annas-mcp/releases/tag/v99.0.0"}`)
+	}))
+	defer server.Close()
+
+	previous := releasesEndpoint
+	releasesEndpoint = server.URL
+	defer func() { releasesEndpoint = previous }()
+
+	if _, err := CheckForUpdate(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	release := UpdateAvailable()
+	if release == nil || release.Version != "v99.0.0" {
+		t.Errorf("Expected v99.0.0 to be available, got %v", release)
+	}
+}
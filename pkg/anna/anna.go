@@ -0,0 +1,229 @@
+// Package anna is a client for Anna's Archive, so other Go programs can
+// search and download from it without the MCP server.
+//
+//	client, err := anna.NewClient(anna.Options{SecretKey: os.Getenv("ANNAS_SECRET_KEY")})
+//	if err != nil {
+//		return err
+//	}
+//	records, err := client.Search("The Go Programming Language", anna.SearchOptions{Limit: 5})
+//	if err != nil {
+//		return err
+//	}
+//	path, err := client.Download(records[0], anna.DownloadOptions{Dir: "/tmp/books"})
+//
+// The mirrors, proxy, connection pool, and circuit breakers are shared by the
+// whole process, so creating a Client reconfigures them for every other
+// Client.
+package anna
+
+import (
+	"errors"
+
+	"github.com/iosifache/annas-mcp/internal/anna"
+)
+
+// Content types accepted by SearchOptions.Content.
+const (
+	ContentBook     = ""
+	ContentMagazine = anna.ContentMagazine
+	ContentComic    = anna.ContentComic
+)
+
+// Errors that can be matched with errors.Is.
+var (
+	// ErrInvalidHash is returned for identifiers that are not MD5 hashes.
+	ErrInvalidHash = anna.ErrInvalidHash
+	// ErrFileTooLarge is returned when a download exceeds DownloadOptions.MaxBytes.
+	ErrFileTooLarge = anna.ErrFileTooLarge
+	// ErrCircuitOpen is returned when every mirror is skipped after failing.
+	ErrCircuitOpen = anna.ErrCircuitOpen
+	// ErrNoSecretKey is returned by downloads when no secret key is configured.
+	ErrNoSecretKey = errors.New("no secret key configured")
+)
+
+// Options configures a Client. Zero values select the defaults.
+type Options struct {
+	// Mirrors lists the domains or base URLs to try, in order. It defaults
+	// to annas-archive.org.
+	Mirrors []string
+	// Proxy is an http://, https:// or socks5:// proxy URL. When empty the
+	// standard HTTP_PROXY/HTTPS_PROXY environment variables apply.
+	Proxy string
+	// SecretKey is the membership key used by fast downloads.
+	SecretKey string
+}
+
+// SearchOptions narrows a search.
+type SearchOptions struct {
+	// Content selects books (ContentBook), magazines, or comics.
+	Content string
+	// Limit stops the search after that many records, 0 keeping every
+	// record of the first result page. Book searches above one page fetch
+	// the following pages concurrently.
+	Limit int
+}
+
+// DownloadOptions controls where and how a record is downloaded.
+type DownloadOptions struct {
+	// Dir is the directory the file is written to.
+	Dir string
+	// MaxBytes refuses larger files with ErrFileTooLarge, 0 disabling the
+	// limit.
+	MaxBytes int64
+}
+
+// Record is a file listed by Anna's Archive.
+type Record struct {
+	Hash      string   `json:"hash"`
+	Title     string   `json:"title"`
+	Authors   []string `json:"authors"`
+	Publisher string   `json:"publisher"`
+	Language  string   `json:"language"`
+	Format    string   `json:"format"`
+	Size      string   `json:"size"`
+	URL       string   `json:"url"`
+
+	// Bytes is Size in bytes, 0 when unknown.
+	Bytes int64 `json:"bytes,omitempty"`
+	// Sources are the upstream collections holding the file, such as lgli,
+	// zlib, or ia.
+	Sources []string `json:"sources,omitempty"`
+	// FastDownload reports whether the file is offered through fast
+	// downloads.
+	FastDownload bool `json:"fast_download,omitempty"`
+}
+
+// Details is the full view of a record, as shown on its page.
+type Details struct {
+	Record
+	ISBNs       []string `json:"isbns,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Subjects    []string `json:"subjects,omitempty"`
+	Series      []string `json:"series,omitempty"`
+	// AlternativeTitles are other titles of the record, such as the title
+	// in its original language.
+	AlternativeTitles []string `json:"alternative_titles,omitempty"`
+}
+
+// Client searches and downloads from Anna's Archive.
+type Client struct {
+	secretKey string
+}
+
+// NewClient configures the connection to Anna's Archive and returns a
+// client using it.
+func NewClient(opts Options) (*Client, error) {
+	if err := anna.Configure(anna.Options{Mirrors: opts.Mirrors, Proxy: opts.Proxy}); err != nil {
+		return nil, err
+	}
+
+	return &Client{secretKey: opts.SecretKey}, nil
+}
+
+// Search returns the records matching query, in the order of the results
+// page.
+func (c *Client) Search(query string, opts SearchOptions) ([]Record, error) {
+	records := make([]Record, 0)
+	keep := func(book *anna.Book) bool {
+		records = append(records, recordFrom(book))
+		return opts.Limit <= 0 || len(records) < opts.Limit
+	}
+
+	var err error
+	if opts.Content == ContentBook {
+		err = anna.StreamBooksLimit(query, opts.Limit, keep)
+	} else {
+		err = anna.StreamPeriodicals(query, opts.Content, func(periodical *anna.Periodical) bool {
+			return keep(&periodical.Book)
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// Lookup returns the details of the record with the given MD5 hash.
+func (c *Client) Lookup(hash string) (*Details, error) {
+	hash, err := anna.NormalizeHash(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := anna.GetMetadata(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Details{
+		Record:      recordFrom(&metadata.Book),
+		ISBNs:       metadata.ISBNs,
+		Description: metadata.Description,
+		Subjects:    metadata.Subjects,
+		Series:      metadata.Series,
+
+		AlternativeTitles: metadata.AlternativeTitles,
+	}, nil
+}
+
+// DownloadURL resolves a fast download link for the record with the given
+// MD5 hash, spending one fast download of the secret key.
+func (c *Client) DownloadURL(hash string) (string, error) {
+	if c.secretKey == "" {
+		return "", ErrNoSecretKey
+	}
+	hash, err := anna.NormalizeHash(hash)
+	if err != nil {
+		return "", err
+	}
+
+	book := &anna.Book{Hash: hash}
+	return book.GetDownloadURL(c.secretKey)
+}
+
+// Download saves the file of a record into opts.Dir and returns its path.
+// The file is named after the title and format of the record.
+func (c *Client) Download(record Record, opts DownloadOptions) (string, error) {
+	downloadURL, err := c.DownloadURL(record.Hash)
+	if err != nil {
+		return "", err
+	}
+
+	book := record.book()
+	return book.FetchLimited(downloadURL, opts.Dir, opts.MaxBytes)
+}
+
+func recordFrom(book *anna.Book) Record {
+	return Record{
+		Hash:      book.Hash,
+		Title:     book.Title,
+		Authors:   book.Authors,
+		Publisher: book.Publisher,
+		Language:  book.Language,
+		Format:    book.Format,
+		Size:      book.Size,
+		URL:       book.URL,
+
+		Bytes:        book.Bytes,
+		Sources:      book.Sources,
+		FastDownload: book.FastDownload,
+	}
+}
+
+func (r Record) book() *anna.Book {
+	return &anna.Book{
+		Hash:      r.Hash,
+		Title:     r.Title,
+		Authors:   r.Authors,
+		Publisher: r.Publisher,
+		Language:  r.Language,
+		Format:    r.Format,
+		Size:      r.Size,
+		URL:       r.URL,
+
+		Bytes:        r.Bytes,
+		Sources:      r.Sources,
+		FastDownload: r.FastDownload,
+	}
+}
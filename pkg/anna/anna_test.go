@@ -0,0 +1,70 @@
+package anna
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func newArchive(t *testing.T) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search":
+			fmt.Fprintf(w, `<html><body><div>
+  <a href="/md5/%[1]s" class="custom-a block mr-2 sm:mr-4 hover:opacity-80"></a>
+  <div class="max-w-full">
+    <a href="/md5/%[1]s">Dune</a>
+    <div class="text-gray-800">✅ English [en] · EPUB · 0.7MB · 1965</div>
+  </div>
+</div></body></html>`, "0123456789abcdef0123456789abcdef")
+		case "/dyn/api/fast_download.json":
+			fmt.Fprintf(w, `{"download_url": "%s/file"}`, server.URL)
+		case "/file":
+			fmt.Fprint(w, "content")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestClient(t *testing.T) {
+	server := newArchive(t)
+
+	client, err := NewClient(Options{Mirrors: []string{server.URL}, SecretKey: "key"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	records, err := client.Search("dune", SearchOptions{Limit: 1})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(records) != 1 || records[0].Title != "Dune" {
+		t.Fatalf("Expected the record 'Dune', got %v", records)
+	}
+
+	path, err := client.Download(records[0], DownloadOptions{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "content" {
+		t.Errorf("Expected saved content 'content', got '%s'", data)
+	}
+
+	t.Run("no secret key", func(t *testing.T) {
+		client, err := NewClient(Options{Mirrors: []string{server.URL}})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if _, err := client.DownloadURL(records[0].Hash); !errors.Is(err, ErrNoSecretKey) {
+			t.Errorf("Expected ErrNoSecretKey, got %v", err)
+		}
+	})
+}